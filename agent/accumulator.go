@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
 	"github.com/influxdata/telegraf/selfstat"
 )
 
@@ -41,6 +42,10 @@ type accumulator struct {
 	maker MetricMaker
 
 	precision time.Duration
+
+	// backpressure, if set, pauses the input while the output buffers are
+	// full.
+	backpressure *backpressure
 }
 
 func (ac *accumulator) AddFields(
@@ -50,7 +55,7 @@ func (ac *accumulator) AddFields(
 	t ...time.Time,
 ) {
 	if m := ac.maker.MakeMetric(measurement, fields, tags, telegraf.Untyped, ac.getTime(t)); m != nil {
-		ac.metrics <- m
+		ac.send(m)
 	}
 }
 
@@ -61,7 +66,7 @@ func (ac *accumulator) AddGauge(
 	t ...time.Time,
 ) {
 	if m := ac.maker.MakeMetric(measurement, fields, tags, telegraf.Gauge, ac.getTime(t)); m != nil {
-		ac.metrics <- m
+		ac.send(m)
 	}
 }
 
@@ -72,8 +77,16 @@ func (ac *accumulator) AddCounter(
 	t ...time.Time,
 ) {
 	if m := ac.maker.MakeMetric(measurement, fields, tags, telegraf.Counter, ac.getTime(t)); m != nil {
-		ac.metrics <- m
+		ac.send(m)
+	}
+}
+
+// send passes the metric on, once the backpressure is released.
+func (ac *accumulator) send(m telegraf.Metric) {
+	if ac.backpressure != nil {
+		ac.backpressure.wait()
 	}
+	ac.metrics <- m
 }
 
 // AddError passes a runtime error to the accumulator.
@@ -84,6 +97,11 @@ func (ac *accumulator) AddError(err error) {
 	}
 	NErrors.Incr(1)
 	//TODO suppress/throttle consecutive duplicate errors?
+	if input, ok := ac.maker.(*models.RunningInput); ok {
+		input.SetError(err)
+		input.Log.Errorf("Error in plugin: %s", err)
+		return
+	}
 	log.Printf("E! Error in plugin [%s]: %s", ac.maker.Name(), err)
 }
 
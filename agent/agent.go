@@ -5,25 +5,48 @@ import (
 	"log"
 	"os"
 	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/config"
 	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/internal/systemd"
 	"github.com/influxdata/telegraf/selfstat"
 )
 
 // Agent runs telegraf and collects data based on the given config
 type Agent struct {
 	Config *config.Config
+
+	// ready is set to 1 once the agent is running.
+	ready int32
+
+	failoverGroups []*failoverGroup
+
+	// backpressure pauses the service inputs while the output buffers are
+	// full, with the "backpressure" buffer full policy.
+	backpressure *backpressure
+
+	// mu guards the inputs and outputs of Config while running, they are
+	// replaced when reloading. flushMu is held by the flushes, reloading
+	// waits for the ones in progress before closing the removed outputs.
+	mu      sync.RWMutex
+	flushMu sync.RWMutex
+	// reloads are handled by Run until stopping is closed.
+	reloads  chan reloadRequest
+	stopping chan struct{}
 }
 
 // NewAgent returns an Agent struct based off the given Config
 func NewAgent(config *config.Config) (*Agent, error) {
 	a := &Agent{
-		Config: config,
+		Config:   config,
+		reloads:  make(chan reloadRequest),
+		stopping: make(chan struct{}),
 	}
 
 	if !a.Config.Agent.OmitHostname {
@@ -39,50 +62,159 @@ func NewAgent(config *config.Config) (*Agent, error) {
 		config.Tags["host"] = a.Config.Agent.Hostname
 	}
 
+	hostname := a.Config.Agent.Hostname
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+	}
+	if err := config.ExpandTagTemplates(hostname); err != nil {
+		return nil, err
+	}
+
+	switch a.Config.Agent.MetricBufferFullPolicy {
+	case "", "drop_oldest":
+	case "backpressure":
+		a.backpressure = newBackpressure(a.buffersFull)
+	default:
+		return nil, fmt.Errorf("invalid metric_buffer_full_policy %q",
+			a.Config.Agent.MetricBufferFullPolicy)
+	}
+
+	switch a.Config.Agent.LogFormat {
+	case "", "text", "json":
+	default:
+		return nil, fmt.Errorf("invalid log_format %q", a.Config.Agent.LogFormat)
+	}
+
 	return a, nil
 }
 
-// Connect connects to all configured outputs
+// Connect initializes the plugins and connects to all configured outputs
 func (a *Agent) Connect() error {
+	if err := a.initPlugins(); err != nil {
+		return err
+	}
+	a.setupFailover()
 	for _, o := range a.Config.Outputs {
-		switch ot := o.Output.(type) {
-		case telegraf.ServiceOutput:
-			if err := ot.Start(); err != nil {
-				log.Printf("E! Service for output %s failed to start, exiting\n%s\n",
-					o.Name, err.Error())
-				return err
-			}
+		a.setupDeadLetter(o)
+		if err := a.connectOutput(o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// connectOutput opens the buffer of the output, starts it if it is a
+// service output and connects it.
+func (a *Agent) connectOutput(o *models.RunningOutput) error {
+	if err := o.OpenBuffer(); err != nil {
+		log.Printf("E! Unable to open buffer of output %s: %s\n", o.Name, err)
+		return err
+	}
+
+	switch ot := o.Output.(type) {
+	case telegraf.ServiceOutput:
+		if err := ot.Start(); err != nil {
+			log.Printf("E! Service for output %s failed to start, exiting\n%s\n",
+				o.Name, err.Error())
+			return err
 		}
+	}
 
-		log.Printf("D! Attempting connection to output: %s\n", o.Name)
-		err := o.Output.Connect()
+	log.Printf("D! Attempting connection to output: %s\n", o.Name)
+	err := o.Output.Connect()
+	if err != nil {
+		log.Printf("E! Failed to connect to output %s, retrying in 15s, "+
+			"error was '%s' \n", o.Name, err)
+		time.Sleep(15 * time.Second)
+		err = o.Output.Connect()
 		if err != nil {
-			log.Printf("E! Failed to connect to output %s, retrying in 15s, "+
-				"error was '%s' \n", o.Name, err)
-			time.Sleep(15 * time.Second)
-			err = o.Output.Connect()
-			if err != nil {
-				return err
-			}
+			return err
 		}
-		log.Printf("D! Successfully connected to output: %s\n", o.Name)
 	}
+	log.Printf("D! Successfully connected to output: %s\n", o.Name)
 	return nil
 }
 
+// setupDeadLetter passes the metrics rejected by the output to the dead
+// letter outputs, tagged with the name of the output and with the error in
+// the rejection_reason field.
+func (a *Agent) setupDeadLetter(o *models.RunningOutput) {
+	if o.Config.DeadLetter {
+		return
+	}
+	var deadLetters []*models.RunningOutput
+	for _, dl := range a.Config.Outputs {
+		if dl.Config.DeadLetter {
+			deadLetters = append(deadLetters, dl)
+		}
+	}
+	if len(deadLetters) == 0 {
+		return
+	}
+
+	name := o.Name
+	o.Reject = func(metrics []telegraf.Metric, err error) {
+		for _, m := range metrics {
+			for _, dl := range deadLetters {
+				rejected := m.Copy()
+				rejected.AddTag("rejected_by", name)
+				rejected.AddField("rejection_reason", err.Error())
+				dl.AddMetric(rejected)
+			}
+		}
+	}
+}
+
 // Close closes the connection to all configured outputs
 func (a *Agent) Close() error {
+	return a.closeOutputs(a.Config.Outputs, nil)
+}
+
+// closeOutputs closes the outputs and their buffers, except the busy ones
+// still in a write.
+func (a *Agent) closeOutputs(
+	outputs []*models.RunningOutput,
+	busy map[*models.RunningOutput]bool,
+) error {
 	var err error
-	for _, o := range a.Config.Outputs {
+	for _, o := range outputs {
+		if busy[o] {
+			log.Printf("E! Output [%s] is still writing, not closing it\n", o.Name)
+			continue
+		}
 		err = o.Output.Close()
 		switch ot := o.Output.(type) {
 		case telegraf.ServiceOutput:
 			ot.Stop()
 		}
+		if berr := o.CloseBuffer(); berr != nil {
+			log.Printf("E! Unable to close buffer of output %s: %s\n", o.Name, berr)
+		}
 	}
 	return err
 }
 
+// TakeOverBuffers moves the metrics still buffered by the outputs of a
+// previous agent to the outputs of this agent configured identically, so
+// they are not lost when reloading the configuration.
+func (a *Agent) TakeOverBuffers(prev *Agent) {
+	taken := make(map[*models.RunningOutput]bool)
+	for _, o := range a.Config.Outputs {
+		for _, p := range prev.Config.Outputs {
+			if taken[p] || p.Name != o.Name || p.Config.Source != o.Config.Source {
+				continue
+			}
+			taken[p] = true
+			if metrics := p.TakeMetrics(); len(metrics) > 0 {
+				log.Printf("I! Output [%s] kept %d buffered metrics\n",
+					o.Name, len(metrics))
+				o.RestoreMetrics(metrics)
+			}
+			break
+		}
+	}
+}
+
 func panicRecover(input *models.RunningInput) {
 	if err := recover(); err != nil {
 		trace := make([]byte, 2048)
@@ -95,6 +227,112 @@ func panicRecover(input *models.RunningInput) {
 	}
 }
 
+// precision returns the precision of the metrics of the input, its own
+// precision overrides the one of the agent.
+func (a *Agent) precision(input *models.RunningInput) time.Duration {
+	if input.Config.Precision > 0 {
+		return input.Config.Precision
+	}
+	return a.Config.Agent.Precision.Duration
+}
+
+// servicePrecision returns the precision of the metrics of a service input,
+// which keep their timestamps unless the input sets its own precision.
+func (a *Agent) servicePrecision(input *models.RunningInput) time.Duration {
+	if input.Config.Precision > 0 {
+		return input.Config.Precision
+	}
+	return time.Nanosecond
+}
+
+// routine is a goroutine run until it is halted.
+type routine struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+// startRoutine runs the function in a goroutine, stop is closed when the
+// routine is halted.
+func startRoutine(run func(stop chan struct{})) *routine {
+	r := &routine{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go func() {
+		defer close(r.done)
+		run(r.stop)
+	}()
+	return r
+}
+
+// halt stops the routines and waits for them to return.
+func halt(routines ...*routine) {
+	for _, r := range routines {
+		close(r.stop)
+	}
+	for _, r := range routines {
+		<-r.done
+	}
+}
+
+// startService starts the input if it is a service input.
+func (a *Agent) startService(
+	input *models.RunningInput,
+	metricC chan telegraf.Metric,
+) error {
+	p, ok := input.Input.(telegraf.ServiceInput)
+	if !ok {
+		return nil
+	}
+	acc := NewAccumulator(input, metricC)
+	// Service input plugins should set their own precision of their
+	// metrics, unless it is set for the input.
+	acc.SetPrecision(a.servicePrecision(input), 0)
+	acc.backpressure = a.backpressure
+	if err := p.Start(acc); err != nil {
+		log.Printf("E! Service for input %s failed to start, exiting\n%s\n",
+			input.Name(), err.Error())
+		return err
+	}
+	return nil
+}
+
+// stopServices stops the service inputs.
+func stopServices(inputs []*models.RunningInput) {
+	for _, input := range inputs {
+		if p, ok := input.Input.(telegraf.ServiceInput); ok {
+			p.Stop()
+		}
+	}
+}
+
+// startGatherer gathers the input at its interval until the returned
+// routine is halted.
+func (a *Agent) startGatherer(
+	input *models.RunningInput,
+	metricC chan telegraf.Metric,
+) *routine {
+	interval := a.Config.Agent.Interval.Duration
+	// overwrite global interval if this plugin has it's own.
+	if input.Config.Interval != 0 {
+		interval = input.Config.Interval
+	}
+	return startRoutine(func(stop chan struct{}) {
+		a.gatherer(stop, input, interval, metricC)
+	})
+}
+
+// startOutputFlusher flushes the output at its own interval until the
+// returned routine is halted.
+func (a *Agent) startOutputFlusher(
+	shutdown chan struct{},
+	output *models.RunningOutput,
+) *routine {
+	return startRoutine(func(stop chan struct{}) {
+		a.outputFlusher(shutdown, stop, output)
+	})
+}
+
 // gatherer runs the inputs that have been configured with their own
 // reporting interval.
 func (a *Agent) gatherer(
@@ -109,22 +347,54 @@ func (a *Agent) gatherer(
 		"gather_time_ns",
 		map[string]string{"input": input.Config.Name},
 	)
+	GatherDuration := selfstat.RegisterHistogram("gather",
+		"gather_time",
+		map[string]string{"input": input.Config.Name},
+		selfstat.DefaultDurationBounds,
+	)
 
 	acc := NewAccumulator(input, metricC)
-	acc.SetPrecision(a.Config.Agent.Precision.Duration,
-		a.Config.Agent.Interval.Duration)
+	acc.SetPrecision(a.precision(input), a.Config.Agent.Interval.Duration)
+
+	roundInterval := a.Config.Agent.RoundInterval
+	if input.Config.RoundInterval != nil {
+		roundInterval = *input.Config.RoundInterval
+	}
+	// Round collection to nearest interval by sleeping
+	if roundInterval {
+		i := int64(interval)
+		select {
+		case <-shutdown:
+			return
+		case <-time.After(time.Duration(i - (time.Now().UnixNano() % i))):
+		}
+	}
+
+	jitter := a.Config.Agent.CollectionJitter.Duration
+	// overwrite global jitter if this plugin has it's own.
+	if input.Config.CollectionJitter != 0 {
+		jitter = input.Config.CollectionJitter
+	}
 
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
-		internal.RandomSleep(a.Config.Agent.CollectionJitter.Duration, shutdown)
+		internal.RandomSleep(jitter, shutdown)
 
 		start := time.Now()
 		gatherWithTimeout(shutdown, input, acc, interval)
 		elapsed := time.Since(start)
+		input.SetGathered(start)
 
 		GatherTime.Incr(elapsed.Nanoseconds())
+		GatherDuration.Incr(elapsed.Nanoseconds())
+
+		if limit := a.Config.Agent.PanicLimit; limit > 0 && input.Panics() >= limit {
+			log.Printf("E! Input [%s] quarantined after %d consecutive panics\n",
+				input.Name(), limit)
+			return
+		}
 
 		select {
 		case <-shutdown:
@@ -150,7 +420,7 @@ func gatherWithTimeout(
 	defer ticker.Stop()
 	done := make(chan error)
 	go func() {
-		done <- input.Input.Gather(acc)
+		done <- input.Gather(acc)
 	}()
 
 	for {
@@ -172,23 +442,57 @@ func gatherWithTimeout(
 }
 
 // Test verifies that we can 'Gather' from all inputs with their configured
-// Config struct
+// Config struct. The metrics are passed through the processors and the
+// aggregators and printed to stdout in line protocol.
 func (a *Agent) Test() error {
+	if err := a.initPlugins(); err != nil {
+		return err
+	}
+
 	shutdown := make(chan struct{})
 	defer close(shutdown)
 	metricC := make(chan telegraf.Metric)
+	collectC := make(chan chan []telegraf.Metric)
 
-	// dummy receiver for the point channel
+	// receiver for the point channel, the metrics received so far are
+	// returned on collectC.
 	go func() {
+		var metrics []telegraf.Metric
 		for {
 			select {
-			case <-metricC:
-				// do nothing
+			case m := <-metricC:
+				metrics = append(metrics, m)
+			case reply := <-collectC:
+				reply <- metrics
+				metrics = nil
 			case <-shutdown:
 				return
 			}
 		}
 	}()
+	// printMetrics passes the metrics gathered through the processors and the
+	// aggregators and prints them.
+	printMetrics := func(aggregate bool) {
+		reply := make(chan []telegraf.Metric)
+		collectC <- reply
+		metrics := <-reply
+		for _, processor := range a.Config.Processors {
+			metrics = processor.Apply(metrics...)
+		}
+		for _, m := range metrics {
+			var dropOriginal bool
+			if aggregate && !m.IsAggregate() {
+				for _, agg := range a.Config.Aggregators {
+					if ok := agg.Aggregate(m.Copy()); ok {
+						dropOriginal = true
+					}
+				}
+			}
+			if !dropOriginal {
+				fmt.Print("> " + m.String())
+			}
+		}
+	}
 
 	for _, input := range a.Config.Inputs {
 		if _, ok := input.Input.(telegraf.ServiceInput); ok {
@@ -198,19 +502,18 @@ func (a *Agent) Test() error {
 		}
 
 		acc := NewAccumulator(input, metricC)
-		acc.SetPrecision(a.Config.Agent.Precision.Duration,
-			a.Config.Agent.Interval.Duration)
-		input.SetTrace(true)
+		acc.SetPrecision(a.precision(input), a.Config.Agent.Interval.Duration)
 		input.SetDefaultTags(a.Config.Tags)
 
 		fmt.Printf("* Plugin: %s, Collection 1\n", input.Name())
 		if input.Config.Interval != 0 {
-			fmt.Printf("* Internal: %s\n", input.Config.Interval)
+			fmt.Printf("* Interval: %s\n", input.Config.Interval)
 		}
 
-		if err := input.Input.Gather(acc); err != nil {
+		if err := input.Gather(acc); err != nil {
 			return err
 		}
+		printMetrics(true)
 
 		// Special instructions for some inputs. cpu, for example, needs to be
 		// run twice in order to return cpu usage percentages.
@@ -218,21 +521,166 @@ func (a *Agent) Test() error {
 		case "inputs.cpu", "inputs.mongodb", "inputs.procstat":
 			time.Sleep(500 * time.Millisecond)
 			fmt.Printf("* Plugin: %s, Collection 2\n", input.Name())
-			if err := input.Input.Gather(acc); err != nil {
+			if err := input.Gather(acc); err != nil {
 				return err
 			}
+			printMetrics(true)
+		}
+
+	}
+
+	for _, agg := range a.Config.Aggregators {
+		fmt.Printf("* Aggregator: %s\n", agg.Name())
+		acc := NewAccumulator(agg, metricC)
+		acc.SetPrecision(a.Config.Agent.Precision.Duration,
+			a.Config.Agent.Interval.Duration)
+		agg.Push(acc)
+		printMetrics(false)
+	}
+	return nil
+}
+
+// Once gathers from all inputs a single time, passes the metrics through the
+// processors and writes them to the outputs. It returns an error if any
+// input or output failed. Aggregators are not run as their period would
+// never elapse.
+func (a *Agent) Once() error {
+	start := time.Now()
+	if len(a.Config.Aggregators) > 0 {
+		log.Println("W! Aggregators are not run in --once mode")
+	}
+
+	if err := a.loadState(); err != nil {
+		log.Printf("E! Unable to load state: %s\n", err)
+	}
+
+	metricC := make(chan telegraf.Metric, 100)
+	var metrics []telegraf.Metric
+	stopped := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case m := <-metricC:
+				metrics = append(metrics, m)
+			case <-stopped:
+				// collect what is left once the inputs are stopped.
+				for {
+					select {
+					case m := <-metricC:
+						metrics = append(metrics, m)
+					default:
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	var services []telegraf.ServiceInput
+	for _, input := range a.Config.Inputs {
+		input.SetDefaultTags(a.Config.Tags)
+		if p, ok := input.Input.(telegraf.ServiceInput); ok {
+			acc := NewAccumulator(input, metricC)
+			acc.SetPrecision(a.servicePrecision(input), 0)
+			if err := p.Start(acc); err != nil {
+				log.Printf("E! Service for input %s failed to start\n%s\n",
+					input.Name(), err.Error())
+				input.SetError(err)
+				continue
+			}
+			services = append(services, p)
+		}
+	}
+
+	shutdown := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(len(a.Config.Inputs))
+	for _, input := range a.Config.Inputs {
+		interval := a.Config.Agent.Interval.Duration
+		if input.Config.Interval != 0 {
+			interval = input.Config.Interval
+		}
+		go func(in *models.RunningInput, interv time.Duration) {
+			defer wg.Done()
+			defer panicRecover(in)
+			acc := NewAccumulator(in, metricC)
+			acc.SetPrecision(a.precision(in), a.Config.Agent.Interval.Duration)
+			gatherWithTimeout(shutdown, in, acc, interv)
+			in.SetGathered(time.Now())
+		}(input, interval)
+	}
+	wg.Wait()
+	close(shutdown)
+
+	for _, p := range services {
+		p.Stop()
+	}
+	close(stopped)
+	<-done
+
+	var failed []string
+	for _, input := range a.Config.Inputs {
+		if _, _, errorTime := input.Status(); !errorTime.Before(start) {
+			failed = append(failed, input.Name())
+		}
+	}
+
+	for _, processor := range a.Config.Processors {
+		metrics = processor.Apply(metrics...)
+	}
+	for _, m := range metrics {
+		a.addMetric(m)
+	}
+	// The dead letter outputs are written last, with the metrics rejected by
+	// the other outputs.
+	for _, deadLetter := range []bool{false, true} {
+		for _, o := range a.Config.Outputs {
+			if o.Config.DeadLetter != deadLetter {
+				continue
+			}
+			// Write sends a single batch, write until the buffer is empty.
+			for o.BufferLen() > 0 {
+				if err := o.Write(); err != nil {
+					log.Printf("E! Error writing to output [%s]: %s\n", o.Name, err)
+					failed = append(failed, "outputs."+o.Name)
+					break
+				}
+			}
 		}
+	}
 
+	if err := a.saveState(); err != nil {
+		log.Printf("E! Unable to save state: %s\n", err)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("errors in plugins: %s", strings.Join(failed, ", "))
 	}
 	return nil
 }
 
 // flush writes a list of metrics to all configured outputs
 func (a *Agent) flush() {
-	var wg sync.WaitGroup
+	a.flushOutputs(a.outputs())
+}
 
-	wg.Add(len(a.Config.Outputs))
-	for _, o := range a.Config.Outputs {
+// flushOutputs writes the buffered metrics to the outputs, skipping the
+// ones removed by a reload.
+func (a *Agent) flushOutputs(outputs []*models.RunningOutput) {
+	a.flushMu.RLock()
+	defer a.flushMu.RUnlock()
+	running := make(map[*models.RunningOutput]bool)
+	for _, o := range a.outputs() {
+		running[o] = true
+	}
+
+	var wg sync.WaitGroup
+	for _, o := range outputs {
+		if a.quarantined(o) || !running[o] {
+			continue
+		}
+		wg.Add(1)
 		go func(output *models.RunningOutput) {
 			defer wg.Done()
 			err := output.Write()
@@ -240,14 +688,59 @@ func (a *Agent) flush() {
 				log.Printf("E! Error writing to output [%s]: %s\n",
 					output.Name, err.Error())
 			}
+			if a.quarantined(output) {
+				log.Printf("E! Output [%s] quarantined after %d consecutive panics\n",
+					output.Name, output.Panics())
+			}
 		}(o)
 	}
 
 	wg.Wait()
+	a.updateFailover()
+	if a.backpressure != nil {
+		a.backpressure.release()
+	}
+}
+
+// quarantined returns true if the output panicked too many times in a row
+// to be written to anymore.
+func (a *Agent) quarantined(output *models.RunningOutput) bool {
+	limit := a.Config.Agent.PanicLimit
+	return limit > 0 && output.Panics() >= limit
+}
+
+// addMetric adds the metric to the outputs, except the dead letter outputs
+// only receiving rejected metrics and the outputs on standby in a failover
+// group.
+func (a *Agent) addMetric(m telegraf.Metric) {
+	// Held while adding, so reloading does not close an output removed
+	// before the metric is added to it.
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	var outputs []*models.RunningOutput
+	for _, o := range a.Config.Outputs {
+		if !o.Config.DeadLetter && !a.standby(o) {
+			outputs = append(outputs, o)
+		}
+	}
+	for i, o := range outputs {
+		if i == len(outputs)-1 {
+			o.AddMetric(m)
+		} else {
+			o.AddMetric(m.Copy())
+		}
+	}
 }
 
-// flusher monitors the metrics input channel and flushes on the minimum interval
-func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric) error {
+// flusher monitors the metrics input channel and flushes on the minimum
+// interval, until the inputs are stopped and their metrics are passed on to
+// the aggregators and the outputs.
+func (a *Agent) flusher(
+	shutdown chan struct{},
+	stopped chan struct{},
+	metricC chan telegraf.Metric,
+) error {
 	// Inelegant, but this sleep is to allow the Gather threads to run, so that
 	// the flusher will flush after metrics are collected.
 	time.Sleep(time.Millisecond * 300)
@@ -259,54 +752,64 @@ func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric) er
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		for {
-			select {
-			case <-shutdown:
-				if len(outMetricC) > 0 {
-					// keep going until outMetricC is flushed
-					continue
-				}
-				return
-			case m := <-outMetricC:
-				// if dropOriginal is set to true, then we will only send this
-				// metric to the aggregators, not the outputs.
-				var dropOriginal bool
-				if !m.IsAggregate() {
-					for _, agg := range a.Config.Aggregators {
-						if ok := agg.Add(m.Copy()); ok {
-							dropOriginal = true
-						}
-					}
-				}
-				if !dropOriginal {
-					for i, o := range a.Config.Outputs {
-						if i == len(a.Config.Outputs)-1 {
-							o.AddMetric(m)
-						} else {
-							o.AddMetric(m.Copy())
-						}
+		for m := range outMetricC {
+			// if dropOriginal is set to true, then we will only send this
+			// metric to the aggregators, not the outputs.
+			var dropOriginal bool
+			if !m.IsAggregate() {
+				for _, agg := range a.Config.Aggregators {
+					if ok := agg.Add(m.Copy()); ok {
+						dropOriginal = true
 					}
 				}
 			}
+			if !dropOriginal {
+				a.addMetric(m)
+			}
 		}
 	}()
 
+	// The keepalives are sent from this loop, so systemd restarts telegraf
+	// if it hangs.
+	var watchdog <-chan time.Time
+	if interval := systemd.WatchdogInterval(); interval > 0 {
+		watchdogTicker := time.NewTicker(interval)
+		defer watchdogTicker.Stop()
+		watchdog = watchdogTicker.C
+	}
+
 	ticker := time.NewTicker(a.Config.Agent.FlushInterval.Duration)
 	semaphore := make(chan struct{}, 1)
 	for {
 		select {
-		case <-shutdown:
-			log.Println("I! Hang on, flushing any cached metrics before shutdown")
-			// wait for outMetricC to get flushed before flushing outputs
+		case <-watchdog:
+			notify(systemd.Watchdog)
+		case <-stopped:
+			ticker.Stop()
+			for len(metricC) > 0 {
+				for _, m := range a.process(<-metricC) {
+					outMetricC <- m
+				}
+			}
+			close(outMetricC)
 			wg.Wait()
-			a.flush()
+			// wait for the scheduled flush in progress.
+			semaphore <- struct{}{}
 			return nil
 		case <-ticker.C:
 			go func() {
 				select {
 				case semaphore <- struct{}{}:
 					internal.RandomSleep(a.Config.Agent.FlushJitter.Duration, shutdown)
-					a.flush()
+					// The outputs with their own flush interval or jitter
+					// are flushed on their own, by outputFlusher.
+					var outputs []*models.RunningOutput
+					for _, o := range a.outputs() {
+						if !ownFlush(o) {
+							outputs = append(outputs, o)
+						}
+					}
+					a.flushOutputs(outputs)
 					<-semaphore
 				default:
 					// skipping this flush because one is already happening
@@ -317,20 +820,159 @@ func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric) er
 		case metric := <-metricC:
 			// NOTE potential bottleneck here as we put each metric through the
 			// processors serially.
-			mS := []telegraf.Metric{metric}
-			for _, processor := range a.Config.Processors {
-				mS = processor.Apply(mS...)
-			}
-			for _, m := range mS {
+			for _, m := range a.process(metric) {
 				outMetricC <- m
 			}
 		}
 	}
 }
 
+// ownFlush returns true if the output has its own flush interval or jitter.
+func ownFlush(output *models.RunningOutput) bool {
+	return output.Config.FlushInterval != 0 || output.Config.FlushJitter != 0
+}
+
+// outputFlusher flushes an output at its own interval, with its own jitter,
+// until stop is closed.
+func (a *Agent) outputFlusher(
+	shutdown chan struct{},
+	stop chan struct{},
+	output *models.RunningOutput,
+) {
+	interval := a.Config.Agent.FlushInterval.Duration
+	if output.Config.FlushInterval != 0 {
+		interval = output.Config.FlushInterval
+	}
+	jitter := a.Config.Agent.FlushJitter.Duration
+	if output.Config.FlushJitter != 0 {
+		jitter = output.Config.FlushJitter
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			internal.RandomSleep(jitter, shutdown)
+			a.flushOutputs([]*models.RunningOutput{output})
+		}
+	}
+}
+
+// notify sends the state to systemd, if telegraf is run by it.
+func notify(state string) {
+	if _, err := systemd.Notify(state); err != nil {
+		log.Printf("E! Unable to notify systemd: %s\n", err)
+	}
+}
+
+// process applies the processors to the metric.
+func (a *Agent) process(metric telegraf.Metric) []telegraf.Metric {
+	mS := []telegraf.Metric{metric}
+	for _, processor := range a.Config.Processors {
+		mS = processor.Apply(mS...)
+	}
+	return mS
+}
+
+// shutdownFlush writes the metrics left to the outputs, the dead letter
+// outputs last, within the shutdown flush timeout, and logs the number of
+// metrics each output could not write. The writes stop at the timeout, it
+// returns the outputs still in a write then, which must not be closed.
+func (a *Agent) shutdownFlush(
+	outputs []*models.RunningOutput,
+) map[*models.RunningOutput]bool {
+	// cancel stops the writes between two batches, canceled and writing
+	// are guarded by mu.
+	cancel := make(chan struct{})
+	var mu sync.Mutex
+	canceled := false
+	writing := make(map[*models.RunningOutput]bool)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, deadLetter := range []bool{false, true} {
+			var wg sync.WaitGroup
+			mu.Lock()
+			if canceled {
+				mu.Unlock()
+				return
+			}
+			for _, o := range outputs {
+				if o.Config.DeadLetter != deadLetter || a.quarantined(o) {
+					continue
+				}
+				writing[o] = true
+				wg.Add(1)
+				go func(output *models.RunningOutput) {
+					defer wg.Done()
+					defer func() {
+						mu.Lock()
+						delete(writing, output)
+						mu.Unlock()
+					}()
+					// Write sends a single batch, write until the buffer is
+					// empty.
+					for output.BufferLen() > 0 {
+						select {
+						case <-cancel:
+							return
+						default:
+						}
+						if err := output.Write(); err != nil {
+							log.Printf("E! Error writing to output [%s]: %s\n",
+								output.Name, err.Error())
+							return
+						}
+					}
+				}(o)
+			}
+			mu.Unlock()
+			wg.Wait()
+		}
+	}()
+
+	var timeout <-chan time.Time
+	if d := a.Config.Agent.ShutdownFlushTimeout.Duration; d > 0 {
+		timeout = time.After(d)
+	}
+	busy := make(map[*models.RunningOutput]bool)
+	select {
+	case <-done:
+	case <-timeout:
+		log.Printf("E! Outputs did not finish writing within %s\n",
+			a.Config.Agent.ShutdownFlushTimeout.Duration)
+		mu.Lock()
+		canceled = true
+		close(cancel)
+		for o := range writing {
+			busy[o] = true
+		}
+		mu.Unlock()
+	}
+
+	for _, o := range outputs {
+		n := o.BufferLen()
+		if n == 0 {
+			continue
+		}
+		if o.Config.BufferPath != "" {
+			log.Printf("I! Output [%s] keeps %d metrics not written in %s\n",
+				o.Name, n, o.Config.BufferPath)
+		} else {
+			log.Printf("E! Output [%s] could not write %d metrics before shutdown\n",
+				o.Name, n)
+		}
+	}
+	return busy
+}
+
 // Run runs the agent daemon, gathering every Interval
 func (a *Agent) Run(shutdown chan struct{}) error {
-	var wg sync.WaitGroup
+	defer close(a.stopping)
 
 	log.Printf("I! Agent Config: Interval:%s, Quiet:%#v, Hostname:%#v, "+
 		"Flush Interval:%s \n",
@@ -340,64 +982,149 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 	// channel shared between all input threads for accumulating metrics
 	metricC := make(chan telegraf.Metric, 100)
 
+	if err := a.startHealthServer(shutdown); err != nil {
+		log.Printf("E! Unable to serve health endpoints: %s\n", err)
+		return err
+	}
+
+	if err := a.loadState(); err != nil {
+		log.Printf("E! Unable to load state: %s\n", err)
+	}
+	// Saved once all plugins are stopped, after the other deferred calls.
+	defer func() {
+		if err := a.saveState(); err != nil {
+			log.Printf("E! Unable to save state: %s\n", err)
+		}
+	}()
+
 	// Start all ServicePlugins
-	for _, input := range a.Config.Inputs {
+	for i, input := range a.Config.Inputs {
 		input.SetDefaultTags(a.Config.Tags)
-		switch p := input.Input.(type) {
-		case telegraf.ServiceInput:
-			acc := NewAccumulator(input, metricC)
-			// Service input plugins should set their own precision of their
-			// metrics.
-			acc.SetPrecision(time.Nanosecond, 0)
-			if err := p.Start(acc); err != nil {
-				log.Printf("E! Service for input %s failed to start, exiting\n%s\n",
-					input.Name(), err.Error())
-				return err
-			}
-			defer p.Stop()
+		if err := a.startService(input, metricC); err != nil {
+			stopServices(a.Config.Inputs[:i])
+			return err
 		}
 	}
 
-	// Round collection to nearest interval by sleeping
+	atomic.StoreInt32(&a.ready, 1)
+	defer atomic.StoreInt32(&a.ready, 0)
+	notify(systemd.Ready)
+
+	// The gatherers round their collection to their own interval.
+	r := &runner{
+		shutdown:  shutdown,
+		metricC:   metricC,
+		gatherers: make(map[*models.RunningInput]*routine),
+		flushers:  make(map[*models.RunningOutput]*routine),
+	}
+	for _, input := range a.Config.Inputs {
+		r.gatherers[input] = a.startGatherer(input, metricC)
+	}
+
+	// Round flushes and aggregations to nearest interval by sleeping
 	if a.Config.Agent.RoundInterval {
 		i := int64(a.Config.Agent.Interval.Duration)
 		time.Sleep(time.Duration(i - (time.Now().UnixNano() % i)))
 	}
 
-	wg.Add(1)
+	// On shutdown the inputs are stopped first, the flusher then passes
+	// their last metrics on to the aggregators and the outputs before the
+	// aggregators are stopped.
+	stopped := make(chan struct{})
+	flushed := make(chan struct{})
 	go func() {
-		defer wg.Done()
-		if err := a.flusher(shutdown, metricC); err != nil {
+		defer close(flushed)
+		if err := a.flusher(shutdown, stopped, metricC); err != nil {
 			log.Printf("E! Flusher routine failed, exiting: %s\n", err.Error())
 			close(shutdown)
 		}
 	}()
 
-	wg.Add(len(a.Config.Aggregators))
+	var aggWg sync.WaitGroup
+	aggShutdown := make(chan struct{})
+	aggWg.Add(len(a.Config.Aggregators))
 	for _, aggregator := range a.Config.Aggregators {
 		go func(agg *models.RunningAggregator) {
-			defer wg.Done()
+			defer aggWg.Done()
 			acc := NewAccumulator(agg, metricC)
 			acc.SetPrecision(a.Config.Agent.Precision.Duration,
 				a.Config.Agent.Interval.Duration)
-			agg.Run(acc, shutdown)
+			agg.Run(acc, aggShutdown)
 		}(aggregator)
 	}
 
-	wg.Add(len(a.Config.Inputs))
-	for _, input := range a.Config.Inputs {
-		interval := a.Config.Agent.Interval.Duration
-		// overwrite global interval if this plugin has it's own.
-		if input.Config.Interval != 0 {
-			interval = input.Config.Interval
+	// The outputs with their own flush interval or jitter are flushed on
+	// their own, the others together by the flusher.
+	for _, o := range a.Config.Outputs {
+		if ownFlush(o) {
+			r.flushers[o] = a.startOutputFlusher(shutdown, o)
 		}
-		go func(in *models.RunningInput, interv time.Duration) {
-			defer wg.Done()
-			a.gatherer(shutdown, in, interv, metricC)
-		}(input, interval)
 	}
 
-	wg.Wait()
-	a.Close()
+	// The inputs and outputs are replaced when reloading, until shutdown.
+	for running := true; running; {
+		select {
+		case <-shutdown:
+			running = false
+		case req := <-a.reloads:
+			req.err <- a.reload(req.next, r)
+		}
+	}
+
+	var gatherers []*routine
+	for _, g := range r.gatherers {
+		gatherers = append(gatherers, g)
+	}
+	halt(gatherers...)
+	// The service inputs waiting for the buffers to drain would block their
+	// Stop.
+	if a.backpressure != nil {
+		a.backpressure.stop()
+	}
+	// The last metrics of the service inputs are sent when they are stopped.
+	stopServices(a.Config.Inputs)
+	close(stopped)
+	<-flushed
+	// wait for the flushes of the outputs flushed on their own.
+	var flushers []*routine
+	for _, f := range r.flushers {
+		flushers = append(flushers, f)
+	}
+	halt(flushers...)
+
+	// The aggregates pushed from now on only go through the processors.
+	pushed := make(chan struct{})
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		for {
+			select {
+			case m := <-metricC:
+				for _, m := range a.process(m) {
+					a.addMetric(m)
+				}
+			case <-pushed:
+				for len(metricC) > 0 {
+					for _, m := range a.process(<-metricC) {
+						a.addMetric(m)
+					}
+				}
+				return
+			}
+		}
+	}()
+	close(aggShutdown)
+	aggWg.Wait()
+	for _, agg := range a.Config.Aggregators {
+		acc := NewAccumulator(agg, metricC)
+		acc.SetPrecision(a.Config.Agent.Precision.Duration,
+			a.Config.Agent.Interval.Duration)
+		agg.Push(acc)
+	}
+	close(pushed)
+	<-drained
+
+	log.Println("I! Hang on, flushing any cached metrics before shutdown")
+	a.closeOutputs(a.Config.Outputs, a.shutdownFlush(a.Config.Outputs))
 	return nil
 }
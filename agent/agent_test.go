@@ -1,9 +1,18 @@
 package agent
 
 import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/metric"
 
 	// needing to load the plugins
 	_ "github.com/influxdata/telegraf/plugins/inputs/all"
@@ -11,6 +20,7 @@ import (
 	_ "github.com/influxdata/telegraf/plugins/outputs/all"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestAgent_OmitHostname(t *testing.T) {
@@ -109,3 +119,462 @@ func TestAgent_LoadOutput(t *testing.T) {
 	a, _ = NewAgent(c)
 	assert.Equal(t, 3, len(a.Config.Outputs))
 }
+
+type statefulInput struct {
+	offsets map[string]int64
+}
+
+func (s *statefulInput) SampleConfig() string                  { return "" }
+func (s *statefulInput) Description() string                   { return "" }
+func (s *statefulInput) Gather(acc telegraf.Accumulator) error { return nil }
+func (s *statefulInput) GetState() interface{}                 { return s.offsets }
+func (s *statefulInput) SetState(state interface{}) error {
+	offsets, ok := state.(map[string]int64)
+	if !ok {
+		return fmt.Errorf("invalid state of type %T", state)
+	}
+	s.offsets = offsets
+	return nil
+}
+
+func TestAgent_State(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	newAgent := func(inputs ...*statefulInput) *Agent {
+		c := config.NewConfig()
+		c.Agent.Statefile = filepath.Join(dir, "telegraf.state")
+		for _, input := range inputs {
+			ri := models.NewRunningInput(input, &models.InputConfig{Name: "stateful"})
+			ri.ID = "input.stateful.0"
+			c.Inputs = append(c.Inputs, ri)
+		}
+		a, err := NewAgent(c)
+		require.NoError(t, err)
+		return a
+	}
+
+	// A missing statefile is not an error.
+	require.NoError(t, newAgent(&statefulInput{}).loadState())
+
+	first := &statefulInput{offsets: map[string]int64{"a.log": 42}}
+	second := &statefulInput{offsets: map[string]int64{"b.log": 7}}
+	require.NoError(t, newAgent(first, second).saveState())
+
+	first, second = &statefulInput{}, &statefulInput{}
+	require.NoError(t, newAgent(first, second).loadState())
+	assert.Equal(t, map[string]int64{"a.log": 42}, first.offsets)
+	assert.Equal(t, map[string]int64{"b.log": 7}, second.offsets)
+
+	// The state is not restored to a plugin returning a nil state.
+	c := config.NewConfig()
+	c.Agent.Statefile = filepath.Join(dir, "telegraf.state")
+	ri := models.NewRunningInput(&nilStateInput{}, &models.InputConfig{Name: "stateful"})
+	ri.ID = "input.stateful.0"
+	c.Inputs = append(c.Inputs, ri)
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+	require.NoError(t, a.loadState())
+}
+
+// nilStateInput returns a nil state, it fails the test if a state is set.
+type nilStateInput struct {
+	statefulInput
+}
+
+func (s *nilStateInput) GetState() interface{} { return nil }
+func (s *nilStateInput) SetState(state interface{}) error {
+	panic("unexpected state")
+}
+
+type onceInput struct {
+	err error
+}
+
+func (i *onceInput) SampleConfig() string { return "" }
+func (i *onceInput) Description() string  { return "" }
+func (i *onceInput) Gather(acc telegraf.Accumulator) error {
+	acc.AddFields("once", map[string]interface{}{"value": 1}, nil)
+	return i.err
+}
+
+type onceOutput struct {
+	metrics []telegraf.Metric
+}
+
+func (o *onceOutput) Connect() error       { return nil }
+func (o *onceOutput) Close() error         { return nil }
+func (o *onceOutput) Description() string  { return "" }
+func (o *onceOutput) SampleConfig() string { return "" }
+func (o *onceOutput) Write(metrics []telegraf.Metric) error {
+	o.metrics = append(o.metrics, metrics...)
+	return nil
+}
+
+func TestAgent_Once(t *testing.T) {
+	newAgent := func(inputs ...*onceInput) (*Agent, *onceOutput) {
+		c := config.NewConfig()
+		c.Agent.OmitHostname = true
+		for _, input := range inputs {
+			c.Inputs = append(c.Inputs,
+				models.NewRunningInput(input, &models.InputConfig{Name: "once"}))
+		}
+		output := &onceOutput{}
+		c.Outputs = append(c.Outputs, models.NewRunningOutput("once", output,
+			&models.OutputConfig{Name: "once"}, 1, 10))
+		a, err := NewAgent(c)
+		require.NoError(t, err)
+		return a, output
+	}
+
+	a, output := newAgent(&onceInput{}, &onceInput{})
+	require.NoError(t, a.Once())
+	assert.Len(t, output.metrics, 2)
+
+	a, output = newAgent(&onceInput{}, &onceInput{err: fmt.Errorf("failed")})
+	err := a.Once()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "inputs.once")
+	assert.Len(t, output.metrics, 2)
+}
+
+// rejectOutput fails to write the batches containing a metric with a
+// negative value.
+type rejectOutput struct {
+	onceOutput
+}
+
+func (o *rejectOutput) Write(metrics []telegraf.Metric) error {
+	for _, m := range metrics {
+		if v, ok := m.Fields()["value"].(int64); ok && v < 0 {
+			return fmt.Errorf("negative value")
+		}
+	}
+	return o.onceOutput.Write(metrics)
+}
+
+func TestAgent_DeadLetter(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.OmitHostname = true
+	c.Inputs = append(c.Inputs,
+		models.NewRunningInput(&onceInput{}, &models.InputConfig{Name: "once"}))
+
+	output := &rejectOutput{}
+	c.Outputs = append(c.Outputs, models.NewRunningOutput("reject", output,
+		&models.OutputConfig{Name: "reject", DeadLetterRetries: 1}, 10, 10))
+	deadLetter := &onceOutput{}
+	c.Outputs = append(c.Outputs, models.NewRunningOutput("dead_letter", deadLetter,
+		&models.OutputConfig{Name: "dead_letter", DeadLetter: true}, 10, 10))
+
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+	require.NoError(t, a.Connect())
+
+	m, err := metric.New("bad", nil, map[string]interface{}{"value": -1}, time.Now())
+	require.NoError(t, err)
+	a.addMetric(m)
+	require.NoError(t, a.Once())
+
+	require.Len(t, output.metrics, 1)
+	assert.Equal(t, "once", output.metrics[0].Name())
+	require.Len(t, deadLetter.metrics, 1)
+	rejected := deadLetter.metrics[0]
+	assert.Equal(t, "bad", rejected.Name())
+	assert.Equal(t, map[string]string{"rejected_by": "reject"}, rejected.Tags())
+	assert.Equal(t, "negative value", rejected.Fields()["rejection_reason"])
+}
+
+// stopInput sends a metric when it is stopped.
+type stopInput struct {
+	acc telegraf.Accumulator
+}
+
+func (i *stopInput) SampleConfig() string                  { return "" }
+func (i *stopInput) Description() string                   { return "" }
+func (i *stopInput) Gather(acc telegraf.Accumulator) error { return nil }
+func (i *stopInput) Start(acc telegraf.Accumulator) error {
+	i.acc = acc
+	return nil
+}
+func (i *stopInput) Stop() {
+	i.acc.AddFields("stopped", map[string]interface{}{"value": 1}, nil)
+}
+
+// blockingOutput blocks the writes until unblock is closed.
+type blockingOutput struct {
+	onceOutput
+	unblock chan struct{}
+	closed  bool
+}
+
+func (o *blockingOutput) Close() error {
+	o.closed = true
+	return nil
+}
+
+func (o *blockingOutput) Write(metrics []telegraf.Metric) error {
+	<-o.unblock
+	return o.onceOutput.Write(metrics)
+}
+
+func TestAgent_ShutdownFlush(t *testing.T) {
+	newAgent := func(output telegraf.Output) *Agent {
+		c := config.NewConfig()
+		c.Agent.OmitHostname = true
+		c.Agent.RoundInterval = false
+		c.Agent.Interval.Duration = time.Hour
+		c.Agent.FlushInterval.Duration = time.Hour
+		c.Agent.ShutdownFlushTimeout.Duration = 100 * time.Millisecond
+		c.Inputs = append(c.Inputs,
+			models.NewRunningInput(&onceInput{}, &models.InputConfig{Name: "once"}),
+			models.NewRunningInput(&stopInput{}, &models.InputConfig{Name: "stop"}))
+		c.Outputs = append(c.Outputs, models.NewRunningOutput("test", output,
+			&models.OutputConfig{Name: "test"}, 10, 10))
+		a, err := NewAgent(c)
+		require.NoError(t, err)
+		return a
+	}
+	run := func(a *Agent) {
+		shutdown := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			a.Run(shutdown)
+		}()
+		time.Sleep(500 * time.Millisecond)
+		close(shutdown)
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("agent did not stop")
+		}
+	}
+
+	// The metrics of the inputs, including the ones sent when stopping,
+	// are written before the agent stops.
+	output := &onceOutput{}
+	run(newAgent(output))
+	var names []string
+	for _, m := range output.metrics {
+		names = append(names, m.Name())
+	}
+	assert.Equal(t, []string{"once", "stopped"}, names)
+
+	// The agent stops after the timeout when the output does not finish,
+	// without closing the output still writing.
+	blocking := &blockingOutput{unblock: make(chan struct{})}
+	run(newAgent(blocking))
+	assert.False(t, blocking.closed)
+	close(blocking.unblock)
+}
+
+// closeOutput records whether it is closed.
+type closeOutput struct {
+	onceOutput
+	closed bool
+}
+
+func (o *closeOutput) Close() error {
+	o.closed = true
+	return nil
+}
+
+func TestAgent_Reload(t *testing.T) {
+	newAgent := func(
+		inputs []*models.RunningInput,
+		outputs []*models.RunningOutput,
+	) *Agent {
+		c := config.NewConfig()
+		c.Agent.OmitHostname = true
+		c.Agent.RoundInterval = false
+		c.Agent.Interval.Duration = 10 * time.Millisecond
+		c.Agent.FlushInterval.Duration = time.Hour
+		c.Inputs = inputs
+		c.Outputs = outputs
+		a, err := NewAgent(c)
+		require.NoError(t, err)
+		return a
+	}
+	input := func(source string) *models.RunningInput {
+		ri := models.NewRunningInput(&onceInput{},
+			&models.InputConfig{Name: "once", Source: source})
+		ri.ID = "inputs.once." + source
+		return ri
+	}
+	output := func(source string, o telegraf.Output) *models.RunningOutput {
+		ro := models.NewRunningOutput("test", o,
+			&models.OutputConfig{Name: "test", Source: source}, 100, 100)
+		ro.ID = "outputs.test." + source
+		return ro
+	}
+
+	kept := &closeOutput{}
+	a := newAgent([]*models.RunningInput{input("a")},
+		[]*models.RunningOutput{output("1", kept)})
+	require.NoError(t, a.Connect())
+	keptInput, keptOutput := a.Config.Inputs[0], a.Config.Outputs[0]
+	shutdown := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.Run(shutdown)
+	}()
+
+	// The added input and output are started, the unchanged ones keep
+	// running.
+	added := &closeOutput{}
+	require.NoError(t, a.Reload(newAgent(
+		[]*models.RunningInput{input("a"), input("b")},
+		[]*models.RunningOutput{output("1", &closeOutput{}), output("2", added)})))
+	inputs, outputs := a.inputs(), a.outputs()
+	require.Len(t, inputs, 2)
+	assert.True(t, inputs[0] == keptInput)
+	require.Len(t, outputs, 2)
+	assert.True(t, outputs[0] == keptOutput)
+	// The flusher passes the metrics on after 300ms.
+	time.Sleep(500 * time.Millisecond)
+
+	// The metrics left are written to the removed output before it is
+	// closed.
+	require.NoError(t, a.Reload(newAgent(
+		[]*models.RunningInput{input("a")},
+		[]*models.RunningOutput{output("1", &closeOutput{})})))
+	assert.Len(t, a.inputs(), 1)
+	assert.Len(t, a.outputs(), 1)
+	assert.True(t, added.closed)
+	assert.NotEmpty(t, added.metrics)
+
+	// Changing the other settings requires a restart.
+	next := newAgent([]*models.RunningInput{input("a")},
+		[]*models.RunningOutput{output("1", &closeOutput{})})
+	next.Config.Agent.Interval.Duration = time.Second
+	assert.Equal(t, ErrRestart, a.Reload(next))
+
+	close(shutdown)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("agent did not stop")
+	}
+	assert.True(t, kept.closed)
+	assert.NotEmpty(t, kept.metrics)
+
+	// A stopped agent is not reloaded.
+	assert.Error(t, a.Reload(next))
+}
+
+func TestAgent_InputPrecision(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.OmitHostname = true
+	c.Agent.Precision.Duration = time.Second
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	input := models.NewRunningInput(&onceInput{}, &models.InputConfig{Name: "once"})
+	assert.Equal(t, time.Second, a.precision(input))
+	assert.Equal(t, time.Nanosecond, a.servicePrecision(input))
+
+	input.Config.Precision = time.Millisecond
+	assert.Equal(t, time.Millisecond, a.precision(input))
+	assert.Equal(t, time.Millisecond, a.servicePrecision(input))
+}
+
+func TestAgent_OutputFlushInterval(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.OmitHostname = true
+	c.Agent.FlushInterval.Duration = time.Hour
+	output := &onceOutput{}
+	ro := models.NewRunningOutput("once", output,
+		&models.OutputConfig{Name: "once", FlushInterval: 10 * time.Millisecond}, 0, 0)
+	c.Outputs = append(c.Outputs, ro)
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	m, err := metric.New("cpu", nil, map[string]interface{}{"value": 1}, time.Now())
+	require.NoError(t, err)
+	a.addMetric(m)
+
+	shutdown := make(chan struct{})
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.outputFlusher(shutdown, stop, ro)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	close(stop)
+	<-done
+
+	// Flushed at the interval of the output, not of the agent.
+	assert.Len(t, output.metrics, 1)
+}
+
+// initInput fails to initialize when err is set.
+type initInput struct {
+	onceInput
+	initErr error
+}
+
+func (i *initInput) Init() error {
+	return i.initErr
+}
+
+func TestAgent_Check(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.OmitHostname = true
+	c.Inputs = append(c.Inputs,
+		models.NewRunningInput(&initInput{}, &models.InputConfig{Name: "ok"}),
+		models.NewRunningInput(&initInput{initErr: fmt.Errorf("no servers")},
+			&models.InputConfig{Name: "bad"}))
+	c.Outputs = append(c.Outputs, models.NewRunningOutput("dlq", &onceOutput{},
+		&models.OutputConfig{Name: "dlq", DeadLetter: true}, 1, 10))
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	assert.Equal(t, []Problem{
+		{Level: "error", Plugin: "inputs.bad", Msg: "no servers"},
+		{
+			Level:  "warning",
+			Plugin: "outputs.dlq",
+			Msg:    "dead letter output never receives metrics, no output sets dead_letter_retries",
+		},
+	}, a.Check())
+
+	err = a.Test()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "inputs.bad")
+}
+
+// serverOutput sets the addresses of its servers like the outputs.
+type serverOutput struct {
+	onceOutput
+	URLs    []string
+	Address string
+}
+
+func TestAgent_CheckConnections(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closed.Close()
+
+	c := config.NewConfig()
+	c.Agent.OmitHostname = true
+	o := &serverOutput{
+		URLs:    []string{"http://" + l.Addr().String(), "udp://localhost:8089"},
+		Address: closed.Addr().String(),
+	}
+	c.Outputs = append(c.Outputs, models.NewRunningOutput("servers", o,
+		&models.OutputConfig{Name: "servers"}, 1, 10))
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	// only the closed server is reported, the UDP one is not dialed.
+	problems := a.CheckConnections(time.Second)
+	require.Len(t, problems, 1)
+	assert.Equal(t, "outputs.servers", problems[0].Plugin)
+	assert.Contains(t, problems[0].Msg, closed.Addr().String())
+}
@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"log"
+	"sync"
+
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+var (
+	NBackpressurePauses = selfstat.Register("agent", "backpressure_pauses",
+		map[string]string{})
+)
+
+// backpressure pauses the service inputs while the buffer of an output is
+// above its high water mark, instead of letting the output drop the oldest
+// metrics. The inputs wait in their accumulator until a flush drains the
+// buffers or the agent stops.
+type backpressure struct {
+	full func() bool
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	paused  bool
+	stopped bool
+}
+
+func newBackpressure(full func() bool) *backpressure {
+	b := &backpressure{full: full}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// wait blocks while the buffers are full.
+func (b *backpressure) wait() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for !b.stopped && b.full() {
+		if !b.paused {
+			b.paused = true
+			NBackpressurePauses.Incr(1)
+			log.Printf("W! Output buffer full, pausing the service inputs\n")
+		}
+		b.cond.Wait()
+	}
+	if b.paused {
+		b.paused = false
+		log.Printf("I! Output buffers drained, resuming the service inputs\n")
+	}
+}
+
+// release wakes up the inputs waiting so they check the buffers again.
+func (b *backpressure) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.cond.Broadcast()
+}
+
+// stop releases the inputs waiting for good, so they can be stopped.
+func (b *backpressure) stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stopped = true
+	b.cond.Broadcast()
+}
+
+// buffersFull returns true if the buffer of an output is above the high
+// water mark. The outputs buffering on disk, the dead letter outputs and the
+// outputs on standby do not apply backpressure.
+func (a *Agent) buffersFull() bool {
+	for _, o := range a.outputs() {
+		if o.Config.DeadLetter || o.Config.BufferPath != "" || a.standby(o) {
+			continue
+		}
+		if float64(o.BufferLen()) >= highWater(o, a.Config.Agent.MetricBufferHighWater) {
+			return true
+		}
+	}
+	return false
+}
+
+func highWater(output *models.RunningOutput, fraction float64) float64 {
+	if fraction <= 0 || fraction > 1 {
+		fraction = 1
+	}
+	return fraction * float64(output.MetricBufferLimit)
+}
@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgent_Backpressure(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.OmitHostname = true
+	c.Agent.MetricBufferFullPolicy = "backpressure"
+	c.Agent.MetricBufferHighWater = 0.5
+
+	output := &failingOutput{fail: true}
+	c.Outputs = append(c.Outputs,
+		models.NewRunningOutput("failing", output,
+			&models.OutputConfig{Name: "failing"}, 10, 20))
+	input := models.NewRunningInput(&stopInput{}, &models.InputConfig{Name: "stop"})
+
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+	require.NoError(t, a.Connect())
+
+	for i := 0; i < 10; i++ {
+		m, err := metric.New("cpu", nil, map[string]interface{}{"value": i}, time.Now())
+		require.NoError(t, err)
+		a.addMetric(m)
+	}
+	a.flush()
+	assert.True(t, a.buffersFull())
+
+	pauses := NBackpressurePauses.Get()
+	metricC := make(chan telegraf.Metric, 10)
+	acc := NewAccumulator(input, metricC)
+	acc.backpressure = a.backpressure
+	added := make(chan struct{})
+	go func() {
+		defer close(added)
+		acc.AddFields("cpu", map[string]interface{}{"value": 10}, nil)
+	}()
+
+	select {
+	case <-added:
+		t.Fatal("metric added while the buffer is full")
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.Equal(t, pauses+1, NBackpressurePauses.Get())
+
+	// The input resumes once a flush drained the buffer.
+	output.fail = false
+	a.flush()
+	select {
+	case <-added:
+	case <-time.After(time.Second):
+		t.Fatal("metric not added after the buffer drained")
+	}
+	assert.Len(t, metricC, 1)
+	assert.Len(t, output.metrics, 10)
+}
+
+func TestAgent_BackpressureStop(t *testing.T) {
+	b := newBackpressure(func() bool { return true })
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		b.wait()
+	}()
+
+	b.stop()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("wait not released by stop")
+	}
+}
+
+func TestAgent_InvalidBufferFullPolicy(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.OmitHostname = true
+	c.Agent.MetricBufferFullPolicy = "block"
+
+	_, err := NewAgent(c)
+	assert.Error(t, err)
+}
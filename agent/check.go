@@ -0,0 +1,205 @@
+package agent
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// addressFields are the options of the outputs setting the addresses of the
+// servers they connect to.
+var addressFields = []string{
+	"URL", "URLs", "Server", "Servers", "Address", "Brokers", "Endpoint",
+}
+
+// defaultPorts are the ports of the URLs without a port, by scheme.
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"amqp":  "5672",
+	"amqps": "5671",
+}
+
+// Problem is an error or a warning found checking the configuration.
+type Problem struct {
+	// Level is "error" or "warning".
+	Level string `json:"level"`
+	// Plugin is the plugin the problem is about, ie "inputs.cpu", empty for
+	// the agent.
+	Plugin string `json:"plugin,omitempty"`
+	Msg    string `json:"msg"`
+}
+
+// namedPlugin is a configured plugin along with its name, ie "inputs.cpu".
+type namedPlugin struct {
+	name   string
+	plugin interface{}
+}
+
+// plugins returns the configured plugins in the order they are started.
+func (a *Agent) plugins() []namedPlugin {
+	var plugins []namedPlugin
+	for _, o := range a.Config.Outputs {
+		plugins = append(plugins, namedPlugin{"outputs." + o.Name, o.Output})
+	}
+	for _, p := range a.Config.Processors {
+		plugins = append(plugins, namedPlugin{"processors." + p.Name, p.Processor})
+	}
+	for _, agg := range a.Config.Aggregators {
+		plugins = append(plugins, namedPlugin{agg.Name(), agg.Aggregator()})
+	}
+	for _, i := range a.Config.Inputs {
+		plugins = append(plugins, namedPlugin{i.Name(), i.Input})
+	}
+	return plugins
+}
+
+// initPlugins initializes the configured plugins.
+func (a *Agent) initPlugins() error {
+	return initialize(a.plugins())
+}
+
+// initialize initializes the plugins implementing telegraf.Initializer, it
+// returns the error of the first plugin failing to.
+func initialize(plugins []namedPlugin) error {
+	for _, p := range plugins {
+		if i, ok := p.plugin.(telegraf.Initializer); ok {
+			if err := i.Init(); err != nil {
+				return fmt.Errorf("could not initialize %s: %s", p.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Check initializes the plugins without starting them or connecting to
+// anything, and returns the problems found in the configuration.
+func (a *Agent) Check() []Problem {
+	var problems []Problem
+	for _, p := range a.plugins() {
+		if i, ok := p.plugin.(telegraf.Initializer); ok {
+			if err := i.Init(); err != nil {
+				problems = append(problems, Problem{
+					Level:  "error",
+					Plugin: p.name,
+					Msg:    err.Error(),
+				})
+			}
+		}
+	}
+
+	// Dead letter outputs only receive the metrics rejected after
+	// dead_letter_retries failed writes.
+	retries := false
+	for _, o := range a.Config.Outputs {
+		if !o.Config.DeadLetter && o.Config.DeadLetterRetries > 0 {
+			retries = true
+		}
+	}
+	groups := make(map[string]int)
+	for _, o := range a.Config.Outputs {
+		if o.Config.DeadLetter && !retries {
+			problems = append(problems, Problem{
+				Level:  "warning",
+				Plugin: "outputs." + o.Name,
+				Msg:    "dead letter output never receives metrics, no output sets dead_letter_retries",
+			})
+		}
+		if o.Config.FailoverGroup != "" {
+			groups[o.Config.FailoverGroup]++
+		}
+	}
+	for _, o := range a.Config.Outputs {
+		if g := o.Config.FailoverGroup; g != "" && groups[g] == 1 {
+			problems = append(problems, Problem{
+				Level:  "warning",
+				Plugin: "outputs." + o.Name,
+				Msg:    fmt.Sprintf("failover group %q has no other output", g),
+			})
+		}
+	}
+	return problems
+}
+
+// CheckConnections dials the TCP servers of the outputs, set by their
+// address options, and returns the ones that cannot be reached within the
+// timeout. Nothing is sent to the servers.
+func (a *Agent) CheckConnections(timeout time.Duration) []Problem {
+	var problems []Problem
+	for _, o := range a.Config.Outputs {
+		for _, addr := range serverAddresses(o.Output) {
+			conn, err := net.DialTimeout("tcp", addr, timeout)
+			if err != nil {
+				problems = append(problems, Problem{
+					Level:  "error",
+					Plugin: "outputs." + o.Name,
+					Msg:    fmt.Sprintf("unable to connect to %s: %s", addr, err),
+				})
+				continue
+			}
+			conn.Close()
+		}
+	}
+	return problems
+}
+
+// serverAddresses returns the host:port of the TCP servers set by the
+// address options of the plugin.
+func serverAddresses(plugin interface{}) []string {
+	v := reflect.ValueOf(plugin)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var addrs []string
+	for _, name := range addressFields {
+		var values []string
+		f := v.FieldByName(name)
+		switch {
+		case !f.IsValid():
+			continue
+		case f.Kind() == reflect.String:
+			values = append(values, f.String())
+		case f.Kind() == reflect.Slice && f.Type().Elem().Kind() == reflect.String:
+			for i := 0; i < f.Len(); i++ {
+				values = append(values, f.Index(i).String())
+			}
+		}
+		for _, value := range values {
+			if addr := tcpAddress(value); addr != "" {
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+	return addrs
+}
+
+// tcpAddress returns the host:port of an URL or of a host:port address, ie
+// "http://localhost:8086" or "localhost:9092", empty if it is not the
+// address of a TCP server.
+func tcpAddress(s string) string {
+	if u, err := url.Parse(s); err == nil && u.Host != "" {
+		switch u.Scheme {
+		case "udp", "udp4", "udp6", "unix", "unixgram":
+			return ""
+		}
+		if u.Port() != "" {
+			return u.Host
+		}
+		if port, ok := defaultPorts[u.Scheme]; ok {
+			return net.JoinHostPort(u.Hostname(), port)
+		}
+		return ""
+	}
+	if _, _, err := net.SplitHostPort(s); err == nil {
+		return s
+	}
+	return ""
+}
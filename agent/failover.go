@@ -0,0 +1,130 @@
+package agent
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// failoverGroup writes the metrics to one of its outputs at a time, in the
+// order of the configuration. It fails over to the next output when the
+// active one failed threshold writes in a row, and tries to fail back to the
+// first output at the retry interval.
+type failoverGroup struct {
+	name          string
+	outputs       []*models.RunningOutput
+	threshold     int
+	retryInterval time.Duration
+
+	mu       sync.Mutex
+	active   int
+	failedAt time.Time
+	// activeFailures is the number of failures of the active output when
+	// it became active, a failed back output fails over again only if it
+	// fails again.
+	activeFailures int
+
+	Active    selfstat.Stat
+	Failovers selfstat.Stat
+}
+
+func newFailoverGroup(
+	name string,
+	threshold int,
+	retryInterval time.Duration,
+) *failoverGroup {
+	tags := map[string]string{"group": name}
+	return &failoverGroup{
+		name:          name,
+		threshold:     threshold,
+		retryInterval: retryInterval,
+		Active:        selfstat.Register("failover", "active", tags),
+		Failovers:     selfstat.Register("failover", "failovers", tags),
+	}
+}
+
+// isActive returns true if the output is the one written to.
+func (g *failoverGroup) isActive(output *models.RunningOutput) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.outputs[g.active] == output
+}
+
+// update fails over to the next output if the active one keeps failing, or
+// back to the first output once the retry interval elapsed. The metrics
+// buffered by the active output are moved to the new one.
+func (g *failoverGroup) update() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	next := g.active
+	failures := g.outputs[g.active].Failures()
+	switch {
+	case failures >= g.threshold && failures != g.activeFailures &&
+		g.active < len(g.outputs)-1:
+		next = g.active + 1
+		g.failedAt = time.Now()
+		g.Failovers.Incr(1)
+		log.Printf("W! Output [%s] failed %d writes in a row, failing over to output [%s] in group %s\n",
+			g.outputs[g.active].Name, failures, g.outputs[next].Name, g.name)
+	case g.active > 0 && time.Since(g.failedAt) >= g.retryInterval:
+		next = 0
+		log.Printf("I! Trying to fail back to output [%s] in group %s\n",
+			g.outputs[next].Name, g.name)
+	default:
+		return
+	}
+
+	if metrics := g.outputs[g.active].TakeMetrics(); len(metrics) > 0 {
+		g.outputs[next].RestoreMetrics(metrics)
+	}
+	g.active = next
+	g.activeFailures = g.outputs[next].Failures()
+	g.Active.Set(int64(next))
+}
+
+// setupFailover creates the failover groups of the outputs.
+func (a *Agent) setupFailover() {
+	a.failoverGroups = nil
+	groups := make(map[string]*failoverGroup)
+	for _, o := range a.Config.Outputs {
+		name := o.Config.FailoverGroup
+		if name == "" || o.Config.DeadLetter {
+			continue
+		}
+		g, ok := groups[name]
+		if !ok {
+			g = newFailoverGroup(name, a.Config.Agent.FailoverThreshold,
+				a.Config.Agent.FailoverRetryInterval.Duration)
+			groups[name] = g
+			a.failoverGroups = append(a.failoverGroups, g)
+		}
+		g.outputs = append(g.outputs, o)
+	}
+}
+
+// standby returns true if the output belongs to a failover group and is not
+// the one written to.
+func (a *Agent) standby(output *models.RunningOutput) bool {
+	for _, g := range a.failoverGroups {
+		for _, o := range g.outputs {
+			if o == output {
+				return !g.isActive(output)
+			}
+		}
+	}
+	return false
+}
+
+// updateFailover updates the active output of the failover groups.
+func (a *Agent) updateFailover() {
+	if a.Config.Agent.FailoverThreshold <= 0 {
+		return
+	}
+	for _, g := range a.failoverGroups {
+		g.update()
+	}
+}
@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingOutput struct {
+	onceOutput
+	fail bool
+}
+
+func (o *failingOutput) Write(metrics []telegraf.Metric) error {
+	if o.fail {
+		return fmt.Errorf("failed")
+	}
+	return o.onceOutput.Write(metrics)
+}
+
+func TestAgent_Failover(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.OmitHostname = true
+	c.Agent.FailoverThreshold = 2
+	c.Agent.FailoverRetryInterval.Duration = time.Hour
+
+	primary := &failingOutput{}
+	secondary := &failingOutput{}
+	c.Outputs = append(c.Outputs,
+		models.NewRunningOutput("primary", primary,
+			&models.OutputConfig{Name: "primary", FailoverGroup: "influx"}, 10, 100),
+		models.NewRunningOutput("secondary", secondary,
+			&models.OutputConfig{Name: "secondary", FailoverGroup: "influx"}, 10, 100))
+
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+	require.NoError(t, a.Connect())
+	group := a.failoverGroups[0]
+
+	add := func() {
+		m, err := metric.New("cpu", nil, map[string]interface{}{"value": 1}, time.Now())
+		require.NoError(t, err)
+		a.addMetric(m)
+	}
+
+	add()
+	a.flush()
+	assert.Len(t, primary.metrics, 1)
+	assert.Len(t, secondary.metrics, 0)
+
+	// The secondary output receives the metrics after the primary failed
+	// twice in a row, with the metrics the primary could not write.
+	primary.fail = true
+	add()
+	a.flush()
+	add()
+	a.flush()
+	assert.Equal(t, int64(1), group.Failovers.Get())
+	assert.Equal(t, int64(1), group.Active.Get())
+	add()
+	a.flush()
+	assert.Len(t, primary.metrics, 1)
+	assert.Len(t, secondary.metrics, 3)
+
+	// Failing back to the primary output when it still fails fails over
+	// again.
+	group.failedAt = time.Now().Add(-2 * time.Hour)
+	a.flush()
+	assert.Equal(t, int64(0), group.Active.Get())
+	add()
+	a.flush()
+	assert.Equal(t, int64(1), group.Active.Get())
+	assert.Equal(t, int64(2), group.Failovers.Get())
+
+	// and stays on the primary output once it recovered, the metric moved
+	// to the secondary output is written by it before failing back.
+	primary.fail = false
+	group.failedAt = time.Now().Add(-2 * time.Hour)
+	a.flush()
+	add()
+	a.flush()
+	assert.Equal(t, int64(0), group.Active.Get())
+	assert.Len(t, primary.metrics, 2)
+	assert.Len(t, secondary.metrics, 4)
+}
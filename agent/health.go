@@ -0,0 +1,137 @@
+package agent
+
+import (
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+type inputHealth struct {
+	Name       string `json:"name"`
+	ID         string `json:"id"`
+	LastGather string `json:"last_gather,omitempty"`
+	LastError  string `json:"last_error,omitempty"`
+	ErrorTime  string `json:"last_error_time,omitempty"`
+}
+
+type outputHealth struct {
+	Name           string  `json:"name"`
+	ID             string  `json:"id"`
+	BufferSize     int     `json:"buffer_size"`
+	BufferLimit    int     `json:"buffer_limit"`
+	BufferFullness float64 `json:"buffer_fullness"`
+	LastWrite      string  `json:"last_write,omitempty"`
+	LastError      string  `json:"last_error,omitempty"`
+	ErrorTime      string  `json:"last_error_time,omitempty"`
+}
+
+type health struct {
+	Status  string         `json:"status"`
+	Inputs  []inputHealth  `json:"inputs"`
+	Outputs []outputHealth `json:"outputs"`
+}
+
+// formatTime formats t as RFC3339, the zero time as the empty string.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// health returns the status of the plugins, the agent is failing when the
+// buffer of an output is full as new metrics are dropped.
+func (a *Agent) health() *health {
+	h := &health{
+		Status:  "ok",
+		Inputs:  []inputHealth{},
+		Outputs: []outputHealth{},
+	}
+	for _, input := range a.inputs() {
+		lastGather, lastError, errorTime := input.Status()
+		h.Inputs = append(h.Inputs, inputHealth{
+			Name:       input.Name(),
+			ID:         input.ID,
+			LastGather: formatTime(lastGather),
+			LastError:  errorString(lastError),
+			ErrorTime:  formatTime(errorTime),
+		})
+	}
+	for _, output := range a.outputs() {
+		lastWrite, lastError, errorTime := output.Status()
+		size := output.BufferLen()
+		if size >= output.MetricBufferLimit {
+			h.Status = "failing"
+		}
+		h.Outputs = append(h.Outputs, outputHealth{
+			Name:           output.Name,
+			ID:             output.ID,
+			BufferSize:     size,
+			BufferLimit:    output.MetricBufferLimit,
+			BufferFullness: float64(size) / float64(output.MetricBufferLimit),
+			LastWrite:      formatTime(lastWrite),
+			LastError:      errorString(lastError),
+			ErrorTime:      formatTime(errorTime),
+		})
+	}
+	return h
+}
+
+func (a *Agent) serveHealth(w http.ResponseWriter, r *http.Request) {
+	h := a.health()
+	w.Header().Set("Content-Type", "application/json")
+	if h.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(h)
+}
+
+func (a *Agent) serveReady(w http.ResponseWriter, r *http.Request) {
+	if atomic.LoadInt32(&a.ready) == 0 {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte("ok\n"))
+}
+
+// startHealthServer serves the health endpoints on the configured address
+// until shutdown is closed.
+func (a *Agent) startHealthServer(shutdown chan struct{}) error {
+	address := a.Config.Agent.HealthAddress
+	if address == "" {
+		return nil
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", a.serveHealth)
+	mux.HandleFunc("/readyz", a.serveReady)
+
+	go func() {
+		<-shutdown
+		listener.Close()
+	}()
+	go func() {
+		err := http.Serve(listener, mux)
+		select {
+		case <-shutdown:
+		default:
+			log.Printf("E! Health endpoint stopped: %s\n", err)
+		}
+	}()
+	log.Printf("I! Serving health endpoints on %s\n", listener.Addr())
+	return nil
+}
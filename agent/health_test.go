@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type healthOutput struct{}
+
+func (o *healthOutput) Connect() error                        { return nil }
+func (o *healthOutput) Close() error                          { return nil }
+func (o *healthOutput) Description() string                   { return "" }
+func (o *healthOutput) SampleConfig() string                  { return "" }
+func (o *healthOutput) Write(metrics []telegraf.Metric) error { return fmt.Errorf("unreachable") }
+
+func TestAgent_Health(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.OmitHostname = true
+	input := models.NewRunningInput(&statefulInput{}, &models.InputConfig{Name: "stateful"})
+	c.Inputs = append(c.Inputs, input)
+	output := models.NewRunningOutput("health", &healthOutput{},
+		&models.OutputConfig{Name: "health"}, 1, 2)
+	c.Outputs = append(c.Outputs, output)
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	acc := NewAccumulator(input, make(chan telegraf.Metric, 10))
+	acc.AddError(fmt.Errorf("connection refused"))
+
+	get := func(handler http.HandlerFunc) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest("GET", "/", nil))
+		return w
+	}
+
+	w := get(a.serveReady)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	a.ready = 1
+	w = get(a.serveReady)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var h health
+	w = get(a.serveHealth)
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &h))
+	assert.Equal(t, "ok", h.Status)
+	require.Len(t, h.Inputs, 1)
+	assert.Equal(t, "inputs.stateful", h.Inputs[0].Name)
+	assert.Equal(t, "connection refused", h.Inputs[0].LastError)
+	assert.NotEmpty(t, h.Inputs[0].ErrorTime)
+	assert.Empty(t, h.Inputs[0].LastGather)
+
+	// Fill the buffer of the output with metrics failing to be written.
+	for i := 0; i < 2; i++ {
+		m, err := metric.New("cpu", nil, map[string]interface{}{"value": 1}, time.Now())
+		require.NoError(t, err)
+		output.AddMetric(m)
+	}
+	output.Write()
+
+	w = get(a.serveHealth)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &h))
+	assert.Equal(t, "failing", h.Status)
+	require.Len(t, h.Outputs, 1)
+	assert.Equal(t, 2, h.Outputs[0].BufferSize)
+	assert.Equal(t, float64(1), h.Outputs[0].BufferFullness)
+	assert.Equal(t, "unreachable", h.Outputs[0].LastError)
+}
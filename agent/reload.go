@@ -0,0 +1,242 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
+)
+
+// ErrRestart is returned by Reload when settings other than the inputs and
+// the outputs changed, the agent must be restarted to apply them.
+var ErrRestart = errors.New("the configuration can only be applied by restarting the agent")
+
+type reloadRequest struct {
+	next *Agent
+	err  chan error
+}
+
+// runner holds the routines started by Run for the inputs and the outputs.
+type runner struct {
+	shutdown  chan struct{}
+	metricC   chan telegraf.Metric
+	gatherers map[*models.RunningInput]*routine
+	flushers  map[*models.RunningOutput]*routine
+}
+
+// inputs returns the running inputs.
+func (a *Agent) inputs() []*models.RunningInput {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.Config.Inputs
+}
+
+// outputs returns the running outputs.
+func (a *Agent) outputs() []*models.RunningOutput {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.Config.Outputs
+}
+
+// Reload applies the configuration of the next agent, not connected, to the
+// running agent. The inputs and outputs removed from the configuration are
+// stopped, the added ones are started and the ones configured identically
+// keep running with their buffers. It returns ErrRestart if other settings
+// changed or if outputs of a failover group or dead letter outputs changed.
+// The running configuration is kept if an added plugin fails to start.
+func (a *Agent) Reload(next *Agent) error {
+	req := reloadRequest{next: next, err: make(chan error, 1)}
+	select {
+	case a.reloads <- req:
+		return <-req.err
+	case <-a.stopping:
+		return fmt.Errorf("the agent is stopping")
+	}
+}
+
+// reload applies the configuration of the next agent, from Run.
+func (a *Agent) reload(next *Agent, r *runner) error {
+	if changed := a.restartReason(next); changed != "" {
+		log.Printf("I! The %s changed, restarting the agent\n", changed)
+		return ErrRestart
+	}
+
+	inputs, addedInputs, removedInputs := matchInputs(a.Config.Inputs,
+		next.Config.Inputs)
+	outputs, addedOutputs, removedOutputs := matchOutputs(a.Config.Outputs,
+		next.Config.Outputs)
+	for _, list := range [][]*models.RunningOutput{addedOutputs, removedOutputs} {
+		for _, o := range list {
+			if o.Config.DeadLetter || o.Config.FailoverGroup != "" {
+				log.Printf("I! Output [%s] of a failover group or dead letter "+
+					"output changed, restarting the agent\n", o.Name)
+				return ErrRestart
+			}
+		}
+	}
+
+	// The added plugins are started before replacing the running ones,
+	// which are kept if one fails.
+	var plugins []namedPlugin
+	for _, o := range addedOutputs {
+		plugins = append(plugins, namedPlugin{"outputs." + o.Name, o.Output})
+	}
+	for _, i := range addedInputs {
+		plugins = append(plugins, namedPlugin{i.Name(), i.Input})
+	}
+	if err := initialize(plugins); err != nil {
+		return err
+	}
+	for i, o := range addedOutputs {
+		a.setupDeadLetter(o)
+		if err := a.connectOutput(o); err != nil {
+			a.closeOutputs(addedOutputs[:i], nil)
+			return err
+		}
+	}
+	for i, input := range addedInputs {
+		input.SetDefaultTags(a.Config.Tags)
+		if err := a.startService(input, r.metricC); err != nil {
+			stopServices(addedInputs[:i])
+			a.closeOutputs(addedOutputs, nil)
+			return err
+		}
+	}
+
+	a.mu.Lock()
+	a.Config.Inputs, a.Config.Outputs = inputs, outputs
+	a.mu.Unlock()
+
+	for _, input := range addedInputs {
+		r.gatherers[input] = a.startGatherer(input, r.metricC)
+	}
+	for _, o := range addedOutputs {
+		if ownFlush(o) {
+			r.flushers[o] = a.startOutputFlusher(r.shutdown, o)
+		}
+	}
+
+	var stopped []*routine
+	for _, input := range removedInputs {
+		stopped = append(stopped, r.gatherers[input])
+		delete(r.gatherers, input)
+	}
+	halt(stopped...)
+	stopServices(removedInputs)
+
+	// The metrics left are written to the removed outputs once the flushes
+	// in progress are done, no flush writes to them afterwards.
+	stopped = nil
+	for _, o := range removedOutputs {
+		if f, ok := r.flushers[o]; ok {
+			stopped = append(stopped, f)
+			delete(r.flushers, o)
+		}
+	}
+	halt(stopped...)
+	if len(removedOutputs) > 0 {
+		a.flushMu.Lock()
+		a.flushMu.Unlock()
+		a.closeOutputs(removedOutputs, a.shutdownFlush(removedOutputs))
+	}
+
+	log.Printf("I! Reloaded config: %d inputs and %d outputs added, "+
+		"%d inputs and %d outputs removed\n", len(addedInputs),
+		len(addedOutputs), len(removedInputs), len(removedOutputs))
+	return nil
+}
+
+// restartReason returns the settings changed by the next agent that are
+// only applied by restarting the agent, empty if there are none.
+func (a *Agent) restartReason(next *Agent) string {
+	if !reflect.DeepEqual(a.Config.Agent, next.Config.Agent) {
+		return "agent settings"
+	}
+	if !reflect.DeepEqual(a.Config.Tags, next.Config.Tags) {
+		return "global tags"
+	}
+	if len(a.Config.Processors) != len(next.Config.Processors) {
+		return "processors"
+	}
+	for i, p := range a.Config.Processors {
+		n := next.Config.Processors[i]
+		if p.ID != n.ID || p.Config.Source != n.Config.Source {
+			return "processors"
+		}
+	}
+	if len(a.Config.Aggregators) != len(next.Config.Aggregators) {
+		return "aggregators"
+	}
+	for i, agg := range a.Config.Aggregators {
+		n := next.Config.Aggregators[i]
+		if agg.Config.Name != n.Config.Name || agg.Config.Source != n.Config.Source {
+			return "aggregators"
+		}
+	}
+	return ""
+}
+
+// matchInputs returns the inputs of the next configuration, with the ones
+// configured identically to a running input replaced by it, along with the
+// added and removed inputs.
+func matchInputs(running, next []*models.RunningInput) (
+	inputs, added, removed []*models.RunningInput,
+) {
+	kept := make(map[*models.RunningInput]bool)
+	for _, n := range next {
+		var match *models.RunningInput
+		for _, r := range running {
+			if !kept[r] && r.ID == n.ID && r.Config.Source == n.Config.Source {
+				match = r
+				break
+			}
+		}
+		if match == nil {
+			added = append(added, n)
+			inputs = append(inputs, n)
+			continue
+		}
+		kept[match] = true
+		inputs = append(inputs, match)
+	}
+	for _, r := range running {
+		if !kept[r] {
+			removed = append(removed, r)
+		}
+	}
+	return inputs, added, removed
+}
+
+// matchOutputs returns the outputs of the next configuration, with the ones
+// configured identically to a running output replaced by it, along with the
+// added and removed outputs.
+func matchOutputs(running, next []*models.RunningOutput) (
+	outputs, added, removed []*models.RunningOutput,
+) {
+	kept := make(map[*models.RunningOutput]bool)
+	for _, n := range next {
+		var match *models.RunningOutput
+		for _, r := range running {
+			if !kept[r] && r.ID == n.ID && r.Config.Source == n.Config.Source {
+				match = r
+				break
+			}
+		}
+		if match == nil {
+			added = append(added, n)
+			outputs = append(outputs, n)
+			continue
+		}
+		kept[match] = true
+		outputs = append(outputs, match)
+	}
+	for _, r := range running {
+		if !kept[r] {
+			removed = append(removed, r)
+		}
+	}
+	return outputs, added, removed
+}
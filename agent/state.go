@@ -0,0 +1,120 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/influxdata/telegraf"
+)
+
+// statefulPlugins returns the plugins implementing telegraf.StatefulPlugin
+// by a unique id.
+func (a *Agent) statefulPlugins() map[string]telegraf.StatefulPlugin {
+	plugins := make(map[string]telegraf.StatefulPlugin)
+	add := func(id string, plugin interface{}) {
+		p, ok := plugin.(telegraf.StatefulPlugin)
+		if !ok {
+			return
+		}
+		// Identically configured plugins share the same id, number them
+		// in the order of the configuration.
+		unique := id
+		for i := 2; plugins[unique] != nil; i++ {
+			unique = fmt.Sprintf("%s-%d", id, i)
+		}
+		plugins[unique] = p
+	}
+
+	for _, i := range a.Config.Inputs {
+		add(i.ID, i.Input)
+	}
+	for _, p := range a.Config.Processors {
+		add(p.ID, p.Processor)
+	}
+	for _, o := range a.Config.Outputs {
+		add(o.ID, o.Output)
+	}
+	return plugins
+}
+
+// loadState restores the states of the plugins from the statefile.
+func (a *Agent) loadState() error {
+	path := a.Config.Agent.Statefile
+	if path == "" {
+		return nil
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var states map[string]json.RawMessage
+	if err := json.Unmarshal(contents, &states); err != nil {
+		return fmt.Errorf("unable to parse statefile %s, %s", path, err)
+	}
+
+	for id, p := range a.statefulPlugins() {
+		data, ok := states[id]
+		if !ok {
+			continue
+		}
+		// Decode the state to the type used by the plugin, which can not be
+		// known from a nil state.
+		current := p.GetState()
+		if current == nil {
+			log.Printf("E! Unable to restore state of %s: no state type\n", id)
+			continue
+		}
+		state := reflect.New(reflect.TypeOf(current))
+		if err := json.Unmarshal(data, state.Interface()); err != nil {
+			log.Printf("E! Unable to restore state of %s: %s\n", id, err)
+			continue
+		}
+		if err := p.SetState(state.Elem().Interface()); err != nil {
+			log.Printf("E! Unable to restore state of %s: %s\n", id, err)
+		}
+	}
+	return nil
+}
+
+// saveState writes the states of the plugins to the statefile.
+func (a *Agent) saveState() error {
+	path := a.Config.Agent.Statefile
+	if path == "" {
+		return nil
+	}
+
+	states := make(map[string]interface{})
+	for id, p := range a.statefulPlugins() {
+		states[id] = p.GetState()
+	}
+	contents, err := json.Marshal(states)
+	if err != nil {
+		return err
+	}
+
+	// Replace the file at once to not lose the states if interrupted.
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(contents); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
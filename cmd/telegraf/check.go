@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf/agent"
+)
+
+var problemRegex = regexp.MustCompile(`^([WE])! (?:\[([^\]]+)\] )?`)
+
+// problemLog collects the warnings and errors logged while checking the
+// configuration, ie the options that could not be parsed.
+type problemLog struct {
+	problems []agent.Problem
+}
+
+func (p *problemLog) Write(b []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		match := problemRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		level := "warning"
+		if match[1] == "E" {
+			level = "error"
+		}
+		p.problems = append(p.problems, agent.Problem{
+			Level:  level,
+			Plugin: match[2],
+			Msg:    line[len(match[0]):],
+		})
+	}
+	return len(b), nil
+}
+
+// checkResult is the result of `telegraf config check`, printed as JSON.
+type checkResult struct {
+	Valid    bool            `json:"valid"`
+	Problems []agent.Problem `json:"problems"`
+}
+
+// checkConfig loads the configuration and initializes its plugins without
+// starting them, and with --check-connections dials the servers of the
+// outputs. It prints the problems found as JSON and returns the exit code, 1
+// if there are errors.
+func checkConfig(inputFilters, outputFilters []string) int {
+	plog := &problemLog{}
+	log.SetFlags(0)
+	log.SetOutput(plog)
+
+	result := checkResult{Valid: true}
+	c, err := loadConfig(inputFilters, outputFilters)
+	if err == nil {
+		var ag *agent.Agent
+		if ag, err = agent.NewAgent(c); err == nil {
+			result.Problems = append(result.Problems, ag.Check()...)
+			if *fCheckConnections {
+				result.Problems = append(result.Problems,
+					ag.CheckConnections(5*time.Second)...)
+			}
+		}
+	}
+	log.SetOutput(os.Stderr)
+	result.Problems = append(plog.problems, result.Problems...)
+	if err != nil {
+		result.Problems = append(result.Problems, agent.Problem{
+			Level: "error",
+			Msg:   strings.TrimPrefix(err.Error(), "Error: "),
+		})
+	}
+	if result.Problems == nil {
+		result.Problems = []agent.Problem{}
+	}
+	for _, p := range result.Problems {
+		if p.Level == "error" {
+			result.Valid = false
+		}
+	}
+
+	out, err := json.MarshalIndent(&result, "", "  ")
+	if err != nil {
+		log.Fatal("E! " + err.Error())
+	}
+	fmt.Println(string(out))
+
+	if !result.Valid {
+		return 1
+	}
+	return 0
+}
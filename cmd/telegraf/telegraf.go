@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
 	"log"
@@ -8,12 +9,15 @@ import (
 	_ "net/http/pprof" // Comment this line to disable pprof endpoint.
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/influxdata/telegraf/agent"
 	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/systemd"
 	"github.com/influxdata/telegraf/logger"
 	_ "github.com/influxdata/telegraf/plugins/aggregators/all"
 	"github.com/influxdata/telegraf/plugins/inputs"
@@ -21,7 +25,9 @@ import (
 	"github.com/influxdata/telegraf/plugins/outputs"
 	_ "github.com/influxdata/telegraf/plugins/outputs/all"
 	_ "github.com/influxdata/telegraf/plugins/processors/all"
+	_ "github.com/influxdata/telegraf/plugins/secretstores/all"
 	"github.com/kardianos/service"
+	"gopkg.in/fsnotify.v1"
 )
 
 var fDebug = flag.Bool("debug", false,
@@ -31,9 +37,18 @@ var pprofAddr = flag.String("pprof-addr", "",
 var fQuiet = flag.Bool("quiet", false,
 	"run in quiet mode")
 var fTest = flag.Bool("test", false, "gather metrics, print them out, and exit")
+var fOnce = flag.Bool("once", false,
+	"gather metrics once, write them to the outputs, and exit")
 var fConfig = flag.String("config", "", "configuration file to load")
+var fCheckConnections = flag.Bool("check-connections", false,
+	"with config check, connect to the servers of the outputs")
 var fConfigDirectory = flag.String("config-directory", "",
 	"directory containing additional *.conf files")
+var fWatchConfigDirectory = flag.Bool("watch-config-directory", false,
+	"reload the configuration when *.conf files change in the config directory")
+var fConfigHeaders headerFlags
+var fConfigURLInterval = flag.Duration("config-url-interval", 0,
+	"interval to check a remote configuration for changes, disabled if 0")
 var fVersion = flag.Bool("version", false, "display the version")
 var fSampleConfig = flag.Bool("sample-config", false,
 	"print out full sample configuration")
@@ -63,7 +78,27 @@ var (
 	branch  string
 )
 
+// headerFlags collects the headers given with repeated --config-header flags.
+type headerFlags map[string]string
+
+func (h headerFlags) String() string {
+	return fmt.Sprintf("%v", map[string]string(h))
+}
+
+func (h headerFlags) Set(value string) error {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid header %q, expected name: value", value)
+	}
+	h[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	return nil
+}
+
 func init() {
+	fConfigHeaders = make(headerFlags)
+	flag.Var(fConfigHeaders, "config-header",
+		"header sent when loading the configuration from an HTTP(S) URL or etcd, ie 'Authorization: Bearer token'")
+
 	// If commit or branch are not set, make that clear.
 	if commit == "" {
 		commit = "unknown"
@@ -82,11 +117,30 @@ Usage:
 The commands & flags are:
 
   config             print out full sample configuration to stdout
+  config check       load the configuration and initialize its plugins
+                     without starting them, print the errors and warnings as
+                     JSON and exit with a non-zero code if there are errors
   version            print the version to stdout
 
-  --config <file>     configuration file to load
-  --test              gather metrics once, print them to stdout, and exit
+  --config <file>     configuration file to load, or the URL of a remote
+                      configuration: http(s)://, consul://, etcd:// or s3://
+  --config-header     header sent when loading the configuration from an
+                      HTTP(S) URL or etcd, ie 'Authorization: Bearer token',
+                      can be repeated
+  --config-url-interval
+                      interval to check the remote configuration for changes
+                      and reload it, ie 5m
+  --test              gather metrics once, pass them through the processors
+                      and aggregators, print them to stdout, and exit
+  --once              gather metrics once, write them to the outputs, and
+                      exit with a non-zero code if a plugin failed
+  --check-connections
+                      with config check, connect to the servers of the
+                      outputs and report the ones not reachable
   --config-directory  directory containing additional *.conf files
+  --watch-config-directory
+                      reload the configuration when *.conf files are added,
+                      changed or removed in the config directory
   --input-filter      filter the input plugins to enable, separator is :
   --output-filter     filter the output plugins to enable, separator is :
   --usage             print usage for a plugin, ie, 'telegraf --usage mysql'
@@ -105,6 +159,19 @@ Examples:
   # run a single telegraf collection, outputing metrics to stdout
   telegraf --config telegraf.conf -test
 
+  # run a single collection of the cpu input, through the processors and
+  # aggregators, outputing metrics to stdout
+  telegraf --config telegraf.conf --test --input-filter cpu
+
+  # run a single telegraf collection, writing metrics to the outputs
+  telegraf --config telegraf.conf --once
+
+  # check a configuration before deploying it
+  telegraf --config telegraf.conf config check
+
+  # check a configuration and that the servers of its outputs are reachable
+  telegraf --config telegraf.conf --check-connections config check
+
   # run telegraf with all plugins defined in config file
   telegraf --config telegraf.conf
 
@@ -113,10 +180,44 @@ Examples:
 
   # run telegraf with pprof
   telegraf --config telegraf.conf --pprof-addr localhost:6060
+
+  # run telegraf with a configuration served by a web server, reloading it
+  # when it changes
+  telegraf --config https://config.example.com/telegraf.conf --config-url-interval 5m
+
+  # run telegraf with the plugins configured in /etc/telegraf/telegraf.d,
+  # reloading them when a file is added or removed
+  telegraf --config-directory /etc/telegraf/telegraf.d --watch-config-directory
 `
 
 var stop chan struct{}
 
+// loadConfig loads the configuration file and directory.
+func loadConfig(inputFilters, outputFilters []string) (*config.Config, error) {
+	c := config.NewConfig()
+	c.OutputFilters = outputFilters
+	c.InputFilters = inputFilters
+	c.ConfigHeaders = fConfigHeaders
+	err := c.LoadConfig(*fConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if *fConfigDirectory != "" {
+		err = c.LoadDirectory(*fConfigDirectory)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !*fTest && len(c.Outputs) == 0 {
+		return nil, fmt.Errorf("Error: no outputs found, did you provide a valid config file?")
+	}
+	if len(c.Inputs) == 0 {
+		return nil, fmt.Errorf("Error: no inputs found, did you provide a valid config file?")
+	}
+	return c, nil
+}
+
 func reloadLoop(
 	stop chan struct{},
 	inputFilters []string,
@@ -124,43 +225,25 @@ func reloadLoop(
 	aggregatorFilters []string,
 	processorFilters []string,
 ) {
-	reload := make(chan bool, 1)
-	reload <- true
-	for <-reload {
-		reload <- false
-
-		// If no other options are specified, load the config file and run.
-		c := config.NewConfig()
-		c.OutputFilters = outputFilters
-		c.InputFilters = inputFilters
-		err := c.LoadConfig(*fConfig)
-		if err != nil {
-			log.Fatal("E! " + err.Error())
-		}
-
-		if *fConfigDirectory != "" {
-			err = c.LoadDirectory(*fConfigDirectory)
-			if err != nil {
-				log.Fatal("E! " + err.Error())
-			}
-		}
-		if !*fTest && len(c.Outputs) == 0 {
-			log.Fatalf("E! Error: no outputs found, did you provide a valid config file?")
-		}
-		if len(c.Inputs) == 0 {
-			log.Fatalf("E! Error: no inputs found, did you provide a valid config file?")
-		}
+	// If no other options are specified, load the config file and run.
+	c, err := loadConfig(inputFilters, outputFilters)
+	if err != nil {
+		log.Fatal("E! " + err.Error())
+	}
 
-		ag, err := agent.NewAgent(c)
-		if err != nil {
-			log.Fatal("E! " + err.Error())
-		}
+	ag, err := agent.NewAgent(c)
+	if err != nil {
+		log.Fatal("E! " + err.Error())
+	}
 
+	var prev *agent.Agent
+	for ag != nil {
 		// Setup logging
 		logger.SetupLogging(
 			ag.Config.Agent.Debug || *fDebug,
 			ag.Config.Agent.Quiet || *fQuiet,
 			ag.Config.Agent.Logfile,
+			ag.Config.Agent.LogFormat,
 		)
 
 		if *fTest {
@@ -171,35 +254,109 @@ func reloadLoop(
 			os.Exit(0)
 		}
 
+		if *fOnce {
+			if err := ag.Connect(); err != nil {
+				log.Fatal("E! " + err.Error())
+			}
+			err = ag.Once()
+			ag.Close()
+			if err != nil {
+				log.Fatal("E! " + err.Error())
+			}
+			os.Exit(0)
+		}
+
+		// Keep the metrics the outputs of the previous configuration could
+		// not write yet.
+		if prev != nil {
+			ag.TakeOverBuffers(prev)
+		}
+
 		err = ag.Connect()
 		if err != nil {
 			log.Fatal("E! " + err.Error())
 		}
 
+		// The agent to run next, set before shutdown is closed when the
+		// reloaded configuration requires a restart.
+		var next *agent.Agent
+
 		shutdown := make(chan struct{})
-		signals := make(chan os.Signal)
-		signal.Notify(signals, os.Interrupt, syscall.SIGHUP)
-		go func() {
-			select {
-			case sig := <-signals:
-				if sig == os.Interrupt {
-					close(shutdown)
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+		var changed <-chan struct{}
+		if config.IsRemote(*fConfig) && *fConfigURLInterval > 0 {
+			changed = watchConfigURL(*fConfig, *fConfigURLInterval, shutdown)
+		}
+		var dirChanged <-chan struct{}
+		if *fConfigDirectory != "" && *fWatchConfigDirectory {
+			dirChanged = watchConfigDirectory(*fConfigDirectory, shutdown)
+		}
+		go func(ag *agent.Agent) {
+			// reload loads the new configuration and applies it to the
+			// running agent, the inputs and outputs changed are restarted.
+			// It returns true if the agent must be restarted to apply other
+			// changes, false once applied or if the new configuration
+			// cannot be loaded and the running configuration is kept.
+			reload := func() bool {
+				log.Printf("I! Reloading Telegraf config\n")
+				nc, err := loadConfig(inputFilters, outputFilters)
+				if err != nil {
+					log.Printf("E! Error reloading config, keeping the running config: %s\n", err)
+					return false
 				}
-				if sig == syscall.SIGHUP {
-					log.Printf("I! Reloading Telegraf config\n")
-					<-reload
-					reload <- true
+				na, err := agent.NewAgent(nc)
+				if err != nil {
+					log.Printf("E! Error reloading config, keeping the running config: %s\n", err)
+					return false
+				}
+				notifySystemd(systemd.Reloading)
+				err = ag.Reload(na)
+				switch {
+				case err == agent.ErrRestart:
+					next = na
+					return true
+				case err != nil:
+					log.Printf("E! Error reloading config, keeping the running config: %s\n", err)
+				}
+				notifySystemd(systemd.Ready)
+				return false
+			}
+
+			for {
+				select {
+				case sig := <-signals:
+					if sig == os.Interrupt || sig == syscall.SIGTERM {
+						notifySystemd(systemd.Stopping)
+						close(shutdown)
+						return
+					}
+					if sig == syscall.SIGHUP && reload() {
+						close(shutdown)
+						return
+					}
+				case <-changed:
+					if reload() {
+						close(shutdown)
+						return
+					}
+				case <-dirChanged:
+					if reload() {
+						close(shutdown)
+						return
+					}
+				case <-stop:
+					notifySystemd(systemd.Stopping)
 					close(shutdown)
+					return
 				}
-			case <-stop:
-				close(shutdown)
 			}
-		}()
+		}(ag)
 
 		log.Printf("I! Starting Telegraf (version %s)\n", version)
-		log.Printf("I! Loaded outputs: %s", strings.Join(c.OutputNames(), " "))
-		log.Printf("I! Loaded inputs: %s", strings.Join(c.InputNames(), " "))
-		log.Printf("I! Tags enabled: %s", c.ListTags())
+		log.Printf("I! Loaded outputs: %s", strings.Join(ag.Config.OutputNames(), " "))
+		log.Printf("I! Loaded inputs: %s", strings.Join(ag.Config.InputNames(), " "))
+		log.Printf("I! Tags enabled: %s", ag.Config.ListTags())
 
 		if *fPidfile != "" {
 			f, err := os.OpenFile(*fPidfile, os.O_CREATE|os.O_WRONLY, 0644)
@@ -220,6 +377,123 @@ func reloadLoop(
 		}
 
 		ag.Run(shutdown)
+		signal.Stop(signals)
+
+		prev = ag
+		ag = next
+	}
+}
+
+// watchConfigURL loads the configuration from a remote source at every
+// interval and signals on the returned channel when it changed, until
+// shutdown is closed.
+func watchConfigURL(
+	u string,
+	interval time.Duration,
+	shutdown chan struct{},
+) <-chan struct{} {
+	changed := make(chan struct{}, 1)
+	go func() {
+		source, err := config.NewSource(u, fConfigHeaders)
+		if err != nil {
+			log.Printf("E! Error watching config %s: %s\n", u, err)
+			return
+		}
+
+		last, err := source.Load()
+		if err != nil {
+			log.Printf("E! Error fetching config from %s: %s\n", u, err)
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-shutdown:
+				return
+			case <-ticker.C:
+			}
+
+			contents, err := source.Load()
+			if err != nil {
+				log.Printf("E! Error fetching config from %s: %s\n", u, err)
+				continue
+			}
+			if last != nil && !bytes.Equal(contents, last) {
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			}
+			last = contents
+		}
+	}()
+	return changed
+}
+
+// watchConfigDirectory signals on the returned channel when *.conf files
+// are added, changed or removed in the directory or its subdirectories,
+// until shutdown is closed. The changes are signaled once no other change
+// happened for a second, as editors and tools often write a file in several
+// steps.
+func watchConfigDirectory(dir string, shutdown chan struct{}) <-chan struct{} {
+	changed := make(chan struct{}, 1)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("E! Error watching config directory %s: %s\n", dir, err)
+		return changed
+	}
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.IsDir() {
+			return watcher.Add(path)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("E! Error watching config directory %s: %s\n", dir, err)
+		watcher.Close()
+		return changed
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		var settle <-chan time.Time
+		for {
+			select {
+			case <-shutdown:
+				return
+			case event := <-watcher.Events:
+				if event.Op&fsnotify.Create != 0 {
+					if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+						watcher.Add(event.Name)
+						continue
+					}
+				}
+				if filepath.Ext(event.Name) != ".conf" || event.Op == fsnotify.Chmod {
+					continue
+				}
+				log.Printf("D! Config file %s changed\n", event.Name)
+				settle = time.After(time.Second)
+			case err := <-watcher.Errors:
+				log.Printf("E! Error watching config directory %s: %s\n", dir, err)
+			case <-settle:
+				settle = nil
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return changed
+}
+
+// notifySystemd sends the state to systemd, if telegraf is run by it.
+func notifySystemd(state string) {
+	if _, err := systemd.Notify(state); err != nil {
+		log.Printf("E! Unable to notify systemd: %s\n", err)
 	}
 }
 
@@ -298,6 +572,9 @@ func main() {
 			fmt.Printf("Telegraf v%s (git: %s %s)\n", version, branch, commit)
 			return
 		case "config":
+			if len(args) > 1 && args[1] == "check" {
+				os.Exit(checkConfig(inputFilters, outputFilters))
+			}
 			config.PrintSampleConfig(
 				inputFilters,
 				outputFilters,
@@ -370,6 +647,9 @@ func main() {
 			if *fConfigDirectory != "" {
 				(*svcConfig).Arguments = append((*svcConfig).Arguments, "-config-directory", *fConfigDirectory)
 			}
+			if *fWatchConfigDirectory {
+				(*svcConfig).Arguments = append((*svcConfig).Arguments, "-watch-config-directory")
+			}
 			err := service.Control(s, *fService)
 			if err != nil {
 				log.Fatal("E! " + err.Error())
@@ -0,0 +1,506 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Expression is a boolean expression over the name, tags, fields and time of
+// a metric, ie:
+//
+//   e, _ := NewExpression(`fields.temperature > 90 && tags.site_id == "123"`)
+//   e.Eval("sensor", tags, fields, t)
+//
+// The operands are the metric name, time in seconds since the epoch, tags.key
+// and fields.key, or tags["key"] and fields["key"] for keys with other
+// characters than letters, digits and underscores, and now() in seconds since
+// the epoch. Literals are numbers, strings quoted with " or ' and true or
+// false. The operators, by increasing precedence, are ||, &&, the
+// comparisons == != < <= > >= and the regular expression matches =~ !~,
+// + -, * / and the unary ! -.
+//
+// A comparison with a missing tag or field is false, except for !=.
+type Expression struct {
+	source string
+	root   node
+}
+
+// NewExpression parses the expression.
+func NewExpression(source string) (*Expression, error) {
+	p := &parser{source: source}
+	if err := p.tokenize(); err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %s", source, err)
+	}
+	root, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %s", source, err)
+	}
+	return &Expression{source: source, root: root}, nil
+}
+
+// String returns the source of the expression.
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Eval returns true if the expression is true for the metric.
+func (e *Expression) Eval(
+	name string,
+	tags map[string]string,
+	fields map[string]interface{},
+	t time.Time,
+) bool {
+	return truthy(e.root.eval(&env{name: name, tags: tags, fields: fields, t: t}))
+}
+
+type env struct {
+	name   string
+	tags   map[string]string
+	fields map[string]interface{}
+	t      time.Time
+}
+
+// node evaluates to a float64, string, bool or nil if undefined.
+type node interface {
+	eval(e *env) interface{}
+}
+
+type literal struct {
+	value interface{}
+}
+
+func (l *literal) eval(e *env) interface{} {
+	return l.value
+}
+
+type reference struct {
+	kind string
+	key  string
+}
+
+func (r *reference) eval(e *env) interface{} {
+	switch r.kind {
+	case "name":
+		return e.name
+	case "time":
+		if e.t.IsZero() {
+			return nil
+		}
+		return float64(e.t.UnixNano()) / float64(time.Second)
+	case "now":
+		return float64(time.Now().UnixNano()) / float64(time.Second)
+	case "tags":
+		if v, ok := e.tags[r.key]; ok {
+			return v
+		}
+	case "fields":
+		if v, ok := e.fields[r.key]; ok {
+			return normalize(v)
+		}
+	}
+	return nil
+}
+
+type unary struct {
+	op string
+	x  node
+}
+
+func (u *unary) eval(e *env) interface{} {
+	v := u.x.eval(e)
+	if u.op == "!" {
+		return !truthy(v)
+	}
+	if f, ok := v.(float64); ok {
+		return -f
+	}
+	return nil
+}
+
+type match struct {
+	negate bool
+	x      node
+	re     *regexp.Regexp
+}
+
+func (m *match) eval(e *env) interface{} {
+	s, ok := m.x.eval(e).(string)
+	if !ok {
+		return m.negate
+	}
+	return m.re.MatchString(s) != m.negate
+}
+
+type binary struct {
+	op   string
+	l, r node
+}
+
+func (b *binary) eval(e *env) interface{} {
+	switch b.op {
+	case "&&":
+		return truthy(b.l.eval(e)) && truthy(b.r.eval(e))
+	case "||":
+		return truthy(b.l.eval(e)) || truthy(b.r.eval(e))
+	}
+
+	l, r := b.l.eval(e), b.r.eval(e)
+	switch b.op {
+	case "==":
+		return l != nil && r != nil && l == r
+	case "!=":
+		return l == nil || r == nil || l != r
+	}
+
+	switch lv := l.(type) {
+	case float64:
+		rv, ok := r.(float64)
+		if !ok {
+			return nil
+		}
+		switch b.op {
+		case "<":
+			return lv < rv
+		case "<=":
+			return lv <= rv
+		case ">":
+			return lv > rv
+		case ">=":
+			return lv >= rv
+		case "+":
+			return lv + rv
+		case "-":
+			return lv - rv
+		case "*":
+			return lv * rv
+		case "/":
+			if rv == 0 {
+				return nil
+			}
+			return lv / rv
+		}
+	case string:
+		rv, ok := r.(string)
+		if !ok {
+			return nil
+		}
+		switch b.op {
+		case "<":
+			return lv < rv
+		case "<=":
+			return lv <= rv
+		case ">":
+			return lv > rv
+		case ">=":
+			return lv >= rv
+		case "+":
+			return lv + rv
+		}
+	}
+	return nil
+}
+
+// normalize converts the numbers of fields to float64.
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case int:
+		return float64(v)
+	case float64, string, bool:
+		return v
+	}
+	return nil
+}
+
+func truthy(v interface{}) bool {
+	b, ok := v.(bool)
+	return ok && b
+}
+
+type token struct {
+	// kind is one of "ident", "number", "string" or "op".
+	kind string
+	text string
+}
+
+type parser struct {
+	source string
+	tokens []token
+	pos    int
+}
+
+var operators = []string{
+	"==", "!=", "<=", ">=", "=~", "!~", "&&", "||",
+	"<", ">", "!", "(", ")", "[", "]", "+", "-", "*", "/",
+}
+
+func (p *parser) tokenize() error {
+	s := p.source
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '_' || unicode.IsLetter(c):
+			j := i + 1
+			for j < len(s) && (s[j] == '_' || s[j] == '.' ||
+				unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j]))) {
+				j++
+			}
+			p.tokens = append(p.tokens, token{"ident", s[i:j]})
+			i = j
+		case unicode.IsDigit(c) || (c == '.' && i+1 < len(s) && unicode.IsDigit(rune(s[i+1]))):
+			j := i + 1
+			for j < len(s) && (unicode.IsDigit(rune(s[j])) || s[j] == '.' ||
+				s[j] == 'e' || s[j] == 'E' ||
+				((s[j] == '+' || s[j] == '-') && (s[j-1] == 'e' || s[j-1] == 'E'))) {
+				j++
+			}
+			p.tokens = append(p.tokens, token{"number", s[i:j]})
+			i = j
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(s) && s[j] != s[i] {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) {
+				return fmt.Errorf("unterminated string")
+			}
+			text := s[i+1 : j]
+			if c == '"' {
+				unquoted, err := strconv.Unquote(s[i : j+1])
+				if err != nil {
+					return fmt.Errorf("invalid string %s", s[i:j+1])
+				}
+				text = unquoted
+			} else {
+				text = strings.Replace(text, `\'`, `'`, -1)
+			}
+			p.tokens = append(p.tokens, token{"string", text})
+			i = j + 1
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return fmt.Errorf("unexpected character %q", c)
+			}
+			p.tokens = append(p.tokens, token{"op", op})
+			i += len(op)
+		}
+	}
+	return nil
+}
+
+// accept consumes the next token if it is one of the operators.
+func (p *parser) accept(ops ...string) (string, bool) {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != "op" {
+		return "", false
+	}
+	for _, op := range ops {
+		if p.tokens[p.pos].text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) expect(op string) error {
+	if _, ok := p.accept(op); !ok {
+		if p.pos >= len(p.tokens) {
+			return fmt.Errorf("expected %q at end", op)
+		}
+		return fmt.Errorf("expected %q, found %q", op, p.tokens[p.pos].text)
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("||"); !ok {
+			return l, nil
+		}
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = &binary{op: "||", l: l, r: r}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	l, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("&&"); !ok {
+			return l, nil
+		}
+		r, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		l = &binary{op: "&&", l: l, r: r}
+	}
+}
+
+func (p *parser) parseComparison() (node, error) {
+	l, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+
+	if op, ok := p.accept("=~", "!~"); ok {
+		if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != "string" {
+			return nil, fmt.Errorf("expected a regular expression string after %s", op)
+		}
+		re, err := regexp.Compile(p.tokens[p.pos].text)
+		if err != nil {
+			return nil, err
+		}
+		p.pos++
+		return &match{negate: op == "!~", x: l, re: re}, nil
+	}
+
+	op, ok := p.accept("==", "!=", "<=", ">=", "<", ">")
+	if !ok {
+		return l, nil
+	}
+	r, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	return &binary{op: op, l: l, r: r}, nil
+}
+
+func (p *parser) parseSum() (node, error) {
+	l, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			return l, nil
+		}
+		r, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		l = &binary{op: op, l: l, r: r}
+	}
+}
+
+func (p *parser) parseProduct() (node, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("*", "/")
+		if !ok {
+			return l, nil
+		}
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l = &binary{op: op, l: l, r: r}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if op, ok := p.accept("!", "-"); ok {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unary{op: op, x: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end")
+	}
+	if _, ok := p.accept("("); ok {
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return x, p.expect(")")
+	}
+
+	tok := p.tokens[p.pos]
+	p.pos++
+	switch tok.kind {
+	case "number":
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s", tok.text)
+		}
+		return &literal{f}, nil
+	case "string":
+		return &literal{tok.text}, nil
+	case "ident":
+		return p.parseIdent(tok.text)
+	}
+	return nil, fmt.Errorf("unexpected %q", tok.text)
+}
+
+func (p *parser) parseIdent(ident string) (node, error) {
+	switch ident {
+	case "true":
+		return &literal{true}, nil
+	case "false":
+		return &literal{false}, nil
+	case "name", "time":
+		return &reference{kind: ident}, nil
+	case "now":
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		return &reference{kind: ident}, p.expect(")")
+	case "tags", "fields":
+		// tags["key"]
+		if err := p.expect("["); err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != "string" {
+			return nil, fmt.Errorf("expected a string key in %s[]", ident)
+		}
+		key := p.tokens[p.pos].text
+		p.pos++
+		return &reference{kind: ident, key: key}, p.expect("]")
+	}
+
+	parts := strings.SplitN(ident, ".", 2)
+	if len(parts) == 2 && (parts[0] == "tags" || parts[0] == "fields") && parts[1] != "" {
+		return &reference{kind: parts[0], key: parts[1]}, nil
+	}
+	return nil, fmt.Errorf("unknown identifier %q", ident)
+}
@@ -0,0 +1,71 @@
+package filter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpression(t *testing.T) {
+	tags := map[string]string{"site_id": "123", "inverter name": "main"}
+	fields := map[string]interface{}{
+		"temperature": float64(95.5),
+		"power":       int64(1200),
+		"status":      "ok",
+		"alarm":       false,
+	}
+	now := time.Unix(1500000000, 0)
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`fields.temperature > 90 && tags.site_id == "123"`, true},
+		{`fields.temperature > 90 && tags.site_id == '456'`, false},
+		{`fields.temperature > 100 || fields.power >= 1200`, true},
+		{`name == "solaredge" && !fields.alarm`, true},
+		{`fields.alarm`, false},
+		{`fields.power / 1000 == 1.2`, true},
+		{`fields.power - 200 * 2 == 800`, true},
+		{`-fields.temperature < -95`, true},
+		{`(fields.power > 2000 || fields.status == "ok") && tags.site_id != "1"`, true},
+		{`tags["inverter name"] == "main"`, true},
+		{`fields["power"] == 1200`, true},
+		{`name =~ "^solar"`, true},
+		{`tags.site_id !~ "^[0-9]+$"`, false},
+		{`tags.missing == "x"`, false},
+		{`tags.missing != "x"`, true},
+		{`fields.missing > 1`, false},
+		{`fields.status > 1`, false},
+		{`time == 1500000000`, true},
+		{`time < now() - 3600`, true},
+		{`1e3 < fields.power`, true},
+		{`tags.site_id + "-" + fields.status == "123-ok"`, true},
+	}
+	for _, tt := range tests {
+		e, err := NewExpression(tt.expr)
+		require.NoError(t, err, tt.expr)
+		assert.Equal(t, tt.want, e.Eval("solaredge", tags, fields, now), tt.expr)
+	}
+}
+
+func TestExpressionInvalid(t *testing.T) {
+	for _, expr := range []string{
+		``,
+		`fields.temperature >`,
+		`(fields.temperature > 1`,
+		`temperature > 1`,
+		`tags.`,
+		`tags[site_id] == "1"`,
+		`name =~ fields.status`,
+		`name =~ "("`,
+		`"unterminated`,
+		`fields.temperature # 1`,
+		`1 2`,
+	} {
+		_, err := NewExpression(expr)
+		assert.Error(t, err, expr)
+	}
+}
@@ -0,0 +1,10 @@
+package telegraf
+
+// Initializer is implemented by plugins checking their configuration and
+// preparing themselves once it is loaded. The agent calls Init before
+// starting the plugins, and `telegraf config check` reports its errors.
+type Initializer interface {
+	// Init returns an error if the plugin cannot run with its
+	// configuration. It must not connect to remote services.
+	Init() error
+}
@@ -2,6 +2,7 @@ package buffer
 
 import (
 	"sync"
+	"sync/atomic"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/selfstat"
@@ -15,6 +16,8 @@ var (
 // Buffer is an object for storing metrics in a circular buffer.
 type Buffer struct {
 	buf chan telegraf.Metric
+	// dropped counts the metrics dropped when the buffer was full.
+	dropped int64
 
 	mu sync.Mutex
 }
@@ -38,6 +41,11 @@ func (b *Buffer) Len() int {
 	return len(b.buf)
 }
 
+// Dropped returns the number of metrics dropped since the buffer was created.
+func (b *Buffer) Dropped() int64 {
+	return atomic.LoadInt64(&b.dropped)
+}
+
 // Add adds metrics to the buffer.
 func (b *Buffer) Add(metrics ...telegraf.Metric) {
 	for i, _ := range metrics {
@@ -47,6 +55,7 @@ func (b *Buffer) Add(metrics ...telegraf.Metric) {
 		default:
 			b.mu.Lock()
 			MetricsDropped.Incr(1)
+			atomic.AddInt64(&b.dropped, 1)
 			<-b.buf
 			b.buf <- metrics[i]
 			b.mu.Unlock()
@@ -67,6 +67,7 @@ func TestDroppingMetrics(t *testing.T) {
 	assert.False(t, b.IsEmpty())
 	assert.Equal(t, b.Len(), 10)
 	assert.Equal(t, int64(5), MetricsDropped.Get())
+	assert.Equal(t, int64(5), b.Dropped())
 	assert.Equal(t, int64(15), MetricsWritten.Get())
 }
 
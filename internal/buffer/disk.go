@@ -0,0 +1,288 @@
+package buffer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// compactSize is the number of bytes of consumed metrics from which the
+// file of a DiskBuffer is compacted, once they make up half of it.
+const compactSize = 1 << 20
+
+// DiskBuffer is a buffer of metrics stored in a file, so they survive
+// restarts of telegraf. The metrics are appended to the file in line
+// protocol, each preceded by its length, and the offset of the oldest metric
+// is stored in a second file with the ".offset" suffix.
+type DiskBuffer struct {
+	path    string
+	maxSize int64
+
+	mu   sync.Mutex
+	file *os.File
+	// head is the offset of the oldest metric, size the size of the file.
+	head  int64
+	size  int64
+	count int
+	// dropped counts the metrics dropped when the buffer was full or could
+	// not be written or read.
+	dropped int64
+}
+
+// NewDiskBuffer returns a DiskBuffer storing the metrics in the file at path,
+// the metrics already stored in the file are kept.
+//   maxSize is the maximum size in bytes of the metrics stored, if Add is
+//   called when the buffer is full, then the oldest metric(s) will be
+//   dropped. 0 means no limit.
+func NewDiskBuffer(path string, maxSize int64) (*DiskBuffer, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0640)
+	if err != nil {
+		return nil, err
+	}
+	b := &DiskBuffer{
+		path:    path,
+		maxSize: maxSize,
+		file:    file,
+	}
+	if err := b.load(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("unable to load buffer %s: %s", path, err)
+	}
+	return b, nil
+}
+
+// load reads the offset of the oldest metric and counts the metrics, a
+// metric partially written is removed.
+func (b *DiskBuffer) load() error {
+	info, err := b.file.Stat()
+	if err != nil {
+		return err
+	}
+	b.size = info.Size()
+
+	contents, err := ioutil.ReadFile(b.offsetPath())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(contents) > 0 {
+		head, err := strconv.ParseInt(strings.TrimSpace(string(contents)), 10, 64)
+		if err != nil || head < 0 || head > b.size {
+			log.Printf("E! Invalid offset in %s, reading %s from the start\n",
+				b.offsetPath(), b.path)
+			head = 0
+		}
+		b.head = head
+	}
+
+	offset := b.head
+	for offset < b.size {
+		n, err := b.recordLen(offset)
+		if err != nil || offset+n > b.size {
+			log.Printf("E! Removing metric partially written at the end of %s\n", b.path)
+			if err := b.file.Truncate(offset); err != nil {
+				return err
+			}
+			b.size = offset
+			break
+		}
+		offset += n
+		b.count++
+	}
+	return nil
+}
+
+func (b *DiskBuffer) offsetPath() string {
+	return b.path + ".offset"
+}
+
+// recordLen returns the length of the record at offset, with its header.
+func (b *DiskBuffer) recordLen(offset int64) (int64, error) {
+	var header [4]byte
+	if _, err := b.file.ReadAt(header[:], offset); err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint32(header[:])) + 4, nil
+}
+
+// IsEmpty returns true if DiskBuffer is empty.
+func (b *DiskBuffer) IsEmpty() bool {
+	return b.Len() == 0
+}
+
+// Len returns the current length of the buffer.
+func (b *DiskBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.count
+}
+
+// Dropped returns the number of metrics dropped since the buffer was opened.
+func (b *DiskBuffer) Dropped() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dropped
+}
+
+// Add adds metrics to the buffer.
+func (b *DiskBuffer) Add(metrics ...telegraf.Metric) {
+	MetricsWritten.Incr(int64(len(metrics)))
+
+	var buf []byte
+	for _, m := range metrics {
+		line := m.Serialize()
+		var header [4]byte
+		binary.BigEndian.PutUint32(header[:], uint32(len(line)))
+		buf = append(buf, header[:]...)
+		buf = append(buf, line...)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.file == nil {
+		MetricsDropped.Incr(int64(len(metrics)))
+		b.dropped += int64(len(metrics))
+		return
+	}
+	if _, err := b.file.WriteAt(buf, b.size); err != nil {
+		log.Printf("E! Unable to write metrics to buffer %s: %s\n", b.path, err)
+		MetricsDropped.Incr(int64(len(metrics)))
+		b.dropped += int64(len(metrics))
+		b.file.Truncate(b.size)
+		return
+	}
+	b.size += int64(len(buf))
+	b.count += len(metrics)
+
+	if b.maxSize <= 0 || b.size-b.head <= b.maxSize {
+		return
+	}
+	for b.count > 0 && b.size-b.head > b.maxSize {
+		n, err := b.recordLen(b.head)
+		if err != nil {
+			log.Printf("E! Unable to read buffer %s: %s\n", b.path, err)
+			return
+		}
+		MetricsDropped.Incr(1)
+		b.dropped++
+		b.head += n
+		b.count--
+	}
+	b.consumed()
+}
+
+// Batch returns a batch of metrics of size batchSize.
+// the batch will be of maximum length batchSize. It can be less than batchSize,
+// if the length of DiskBuffer is less than batchSize.
+func (b *DiskBuffer) Batch(batchSize int) []telegraf.Metric {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.file == nil {
+		return nil
+	}
+
+	n := min(b.count, batchSize)
+	out := make([]telegraf.Metric, 0, n)
+	for i := 0; i < n; i++ {
+		length, err := b.recordLen(b.head)
+		if err != nil {
+			log.Printf("E! Unable to read buffer %s: %s\n", b.path, err)
+			break
+		}
+		line := make([]byte, length-4)
+		if _, err := b.file.ReadAt(line, b.head+4); err != nil && err != io.EOF {
+			log.Printf("E! Unable to read buffer %s: %s\n", b.path, err)
+			break
+		}
+		b.head += length
+		b.count--
+
+		metrics, err := metric.Parse(line)
+		if err != nil {
+			log.Printf("E! Dropping invalid metric in buffer %s: %s\n", b.path, err)
+			MetricsDropped.Incr(1)
+			b.dropped++
+			continue
+		}
+		out = append(out, metrics...)
+	}
+	b.consumed()
+	return out
+}
+
+// consumed saves the offset of the oldest metric and compacts the file once
+// the consumed metrics make up half of it.
+func (b *DiskBuffer) consumed() {
+	if b.count == 0 {
+		if err := b.file.Truncate(0); err == nil {
+			b.head, b.size = 0, 0
+		}
+	} else if b.head >= compactSize && b.head > b.size/2 {
+		if err := b.compact(); err != nil {
+			log.Printf("E! Unable to compact buffer %s: %s\n", b.path, err)
+		}
+	}
+
+	err := ioutil.WriteFile(b.offsetPath(),
+		[]byte(strconv.FormatInt(b.head, 10)), 0640)
+	if err != nil {
+		log.Printf("E! Unable to save offset of buffer %s: %s\n", b.path, err)
+	}
+}
+
+// compact rewrites the file without the consumed metrics.
+func (b *DiskBuffer) compact() error {
+	tmp, err := os.OpenFile(b.path+".tmp", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0640)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tmp, io.NewSectionReader(b.file, b.head, b.size-b.head))
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	// Reset the offset before replacing the file: if telegraf stops in
+	// between the consumed metrics are written again instead of being lost.
+	err = ioutil.WriteFile(b.offsetPath(), []byte("0"), 0640)
+	if err == nil {
+		err = os.Rename(tmp.Name(), b.path)
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	b.file.Close()
+	b.file = tmp
+	b.size -= b.head
+	b.head = 0
+	return nil
+}
+
+// Close closes the file of the buffer, the metrics it contains are kept in
+// the file.
+func (b *DiskBuffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.file == nil {
+		return nil
+	}
+	err := b.file.Close()
+	b.file = nil
+	b.count = 0
+	return err
+}
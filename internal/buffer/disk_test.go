@@ -0,0 +1,145 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tempBufferPath(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "telegraf-buffer")
+	require.NoError(t, err)
+	return filepath.Join(dir, "output.buffer"), func() { os.RemoveAll(dir) }
+}
+
+func names(metrics []telegraf.Metric) []string {
+	var out []string
+	for _, m := range metrics {
+		out = append(out, m.Name())
+	}
+	return out
+}
+
+func TestDiskBufferBatches(t *testing.T) {
+	path, cleanup := tempBufferPath(t)
+	defer cleanup()
+
+	b, err := NewDiskBuffer(path, 0)
+	require.NoError(t, err)
+	defer b.Close()
+	assert.True(t, b.IsEmpty())
+
+	b.Add(metricList...)
+	assert.False(t, b.IsEmpty())
+	assert.Equal(t, 5, b.Len())
+
+	batch := b.Batch(2)
+	assert.Equal(t, []string{"mymetric1", "mymetric2"}, names(batch))
+	assert.Equal(t, metricList[0].Fields(), batch[0].Fields())
+	assert.Equal(t, metricList[0].Tags(), batch[0].Tags())
+	assert.Equal(t, metricList[0].Time().UnixNano(), batch[0].Time().UnixNano())
+	assert.Equal(t, 3, b.Len())
+
+	batch = b.Batch(10)
+	assert.Equal(t, []string{"mymetric3", "mymetric4", "mymetric5"}, names(batch))
+	assert.True(t, b.IsEmpty())
+	assert.Len(t, b.Batch(10), 0)
+}
+
+// Verify that the metrics are kept when the buffer is opened again.
+func TestDiskBufferReopen(t *testing.T) {
+	path, cleanup := tempBufferPath(t)
+	defer cleanup()
+
+	b, err := NewDiskBuffer(path, 0)
+	require.NoError(t, err)
+	b.Add(metricList...)
+	b.Batch(2)
+	require.NoError(t, b.Close())
+
+	b, err = NewDiskBuffer(path, 0)
+	require.NoError(t, err)
+	defer b.Close()
+	assert.Equal(t, 3, b.Len())
+	assert.Equal(t, []string{"mymetric3", "mymetric4", "mymetric5"}, names(b.Batch(10)))
+}
+
+// Verify that a metric partially written is removed when the buffer is
+// opened again.
+func TestDiskBufferPartialWrite(t *testing.T) {
+	path, cleanup := tempBufferPath(t)
+	defer cleanup()
+
+	b, err := NewDiskBuffer(path, 0)
+	require.NoError(t, err)
+	b.Add(metricList[:2]...)
+	require.NoError(t, b.Close())
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0640)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{0, 0, 1, 0, 'c', 'p', 'u'})
+	require.NoError(t, err)
+	f.Close()
+
+	b, err = NewDiskBuffer(path, 0)
+	require.NoError(t, err)
+	defer b.Close()
+	assert.Equal(t, 2, b.Len())
+
+	b.Add(metricList[2])
+	assert.Equal(t, []string{"mymetric1", "mymetric2", "mymetric3"}, names(b.Batch(10)))
+}
+
+func TestDiskBufferMaxSize(t *testing.T) {
+	path, cleanup := tempBufferPath(t)
+	defer cleanup()
+
+	m := testutil.TestMetric(1, "mymetric1")
+	size := int64(len(m.Serialize()) + 4)
+
+	b, err := NewDiskBuffer(path, 3*size)
+	require.NoError(t, err)
+	defer b.Close()
+	MetricsDropped.Set(0)
+
+	b.Add(testutil.TestMetric(1, "mymetric1"), testutil.TestMetric(1, "mymetric2"))
+	b.Add(testutil.TestMetric(1, "mymetric3"), testutil.TestMetric(1, "mymetric4"))
+	assert.Equal(t, 3, b.Len())
+	assert.Equal(t, int64(1), MetricsDropped.Get())
+	assert.Equal(t, int64(1), b.Dropped())
+	assert.Equal(t, []string{"mymetric2", "mymetric3", "mymetric4"}, names(b.Batch(10)))
+}
+
+// Verify that the consumed metrics are removed from the file.
+func TestDiskBufferCompaction(t *testing.T) {
+	path, cleanup := tempBufferPath(t)
+	defer cleanup()
+
+	b, err := NewDiskBuffer(path, 0)
+	require.NoError(t, err)
+	defer b.Close()
+
+	m := testutil.TestMetric(1, "mymetric")
+	n := 2*compactSize/(len(m.Serialize())+4) + 1
+	for i := 0; i < n; i++ {
+		b.Add(m)
+	}
+	b.Batch(n - 10)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.True(t, info.Size() < compactSize)
+	assert.Equal(t, 10, b.Len())
+	assert.Len(t, b.Batch(n), 10)
+
+	info, err = os.Stat(path)
+	require.NoError(t, err)
+	assert.Zero(t, info.Size())
+}
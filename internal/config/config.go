@@ -2,9 +2,9 @@ package config
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"math"
 	"os"
@@ -19,11 +19,14 @@ import (
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/logger"
 	"github.com/influxdata/telegraf/plugins/aggregators"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/plugins/parsers/binary"
 	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/plugins/secretstores"
 	"github.com/influxdata/telegraf/plugins/serializers"
 
 	"github.com/influxdata/toml"
@@ -33,13 +36,20 @@ import (
 var (
 	// Default input plugins
 	inputDefaults = []string{"cpu", "mem", "swap", "system", "kernel",
-		"processes", "disk", "diskio"}
+		"processes", "disk", "diskio", "internal"}
 
 	// Default output plugins
 	outputDefaults = []string{"influxdb"}
 
-	// envVarRe is a regex to find environment variables in the config file
-	envVarRe = regexp.MustCompile(`\$\w+`)
+	// envVarRe is a regex to find environment variables in the config file,
+	// ie $VAR or ${VAR}
+	envVarRe = regexp.MustCompile(`\$\{\w+\}|\$\w+`)
+
+	// secretRe is a regex to find references to secrets, @{store:key}
+	secretRe = regexp.MustCompile(`@\{([\w-]+):([^}]+)\}`)
+
+	// secretStoreIDRe matches valid ids of secret stores
+	secretStoreIDRe = regexp.MustCompile(`^[\w-]+$`)
 )
 
 // Config specifies the URL/user/password for the database that telegraf
@@ -50,12 +60,22 @@ type Config struct {
 	InputFilters  []string
 	OutputFilters []string
 
+	// ConfigHeaders are sent with the request when the configuration file
+	// is loaded from an HTTP(S) URL or etcd.
+	ConfigHeaders map[string]string
+
 	Agent       *AgentConfig
 	Inputs      []*models.RunningInput
 	Outputs     []*models.RunningOutput
 	Aggregators []*models.RunningAggregator
 	// Processors have a slice wrapper type because they need to be sorted
 	Processors models.RunningProcessors
+	// SecretStores by id
+	SecretStores map[string]telegraf.SecretStore
+
+	// unresolved are the sources of the tables before the secrets are
+	// resolved, the ids of the plugins are derived from them.
+	unresolved map[*ast.Table]string
 }
 
 func NewConfig() *Config {
@@ -65,12 +85,22 @@ func NewConfig() *Config {
 			Interval:      internal.Duration{Duration: 10 * time.Second},
 			RoundInterval: true,
 			FlushInterval: internal.Duration{Duration: 10 * time.Second},
+			PanicLimit:    3,
+
+			ShutdownFlushTimeout:  internal.Duration{Duration: 30 * time.Second},
+			FailoverThreshold:     3,
+			FailoverRetryInterval: internal.Duration{Duration: 5 * time.Minute},
+
+			MetricBufferFullPolicy: "drop_oldest",
+			MetricBufferHighWater:  0.9,
 		},
 
 		Tags:          make(map[string]string),
 		Inputs:        make([]*models.RunningInput, 0),
 		Outputs:       make([]*models.RunningOutput, 0),
 		Processors:    make([]*models.RunningProcessor, 0),
+		SecretStores:  make(map[string]telegraf.SecretStore),
+		unresolved:    make(map[*ast.Table]string),
 		InputFilters:  make([]string, 0),
 		OutputFilters: make([]string, 0),
 	}
@@ -108,6 +138,10 @@ type AgentConfig struct {
 	// ie, a jitter of 5s and interval 10s means flushes will happen every 10-15s
 	FlushJitter internal.Duration
 
+	// ShutdownFlushTimeout is the time given to the outputs to write the
+	// metrics left when shutting down, 0 waits for them indefinitely.
+	ShutdownFlushTimeout internal.Duration
+
 	// MetricBatchSize is the maximum number of metrics that is wrote to an
 	// output plugin in one call.
 	MetricBatchSize int
@@ -119,6 +153,15 @@ type AgentConfig struct {
 	// not be less than 2 times MetricBatchSize.
 	MetricBufferLimit int
 
+	// MetricBufferFullPolicy is what happens when the buffer of an output
+	// fills up: "drop_oldest" drops the oldest metrics, "backpressure" pauses
+	// the service inputs until the buffers are below the high water mark.
+	MetricBufferFullPolicy string
+
+	// MetricBufferHighWater is the fraction of MetricBufferLimit from which
+	// the service inputs are paused with the "backpressure" policy.
+	MetricBufferHighWater float64
+
 	// FlushBufferWhenFull tells Telegraf to flush the metric buffer whenever
 	// it fills up, regardless of FlushInterval. Setting this option to true
 	// does _not_ deactivate FlushInterval.
@@ -135,6 +178,27 @@ type AgentConfig struct {
 	// Logfile specifies the file to send logs to
 	Logfile string
 
+	// LogFormat is the format of the log lines, "text" or "json"
+	LogFormat string
+
+	// Statefile specifies the file the states of the plugins are saved to
+	Statefile string
+
+	// HealthAddress specifies the address of the health endpoints
+	HealthAddress string
+
+	// PanicLimit is the number of consecutive panics after which a plugin
+	// is quarantined
+	PanicLimit int
+
+	// FailoverThreshold is the number of consecutive failed writes after
+	// which an output of a failover group fails over to the next output
+	FailoverThreshold int
+
+	// FailoverRetryInterval is the interval after which a failover group
+	// tries to fail back to its first output
+	FailoverRetryInterval internal.Duration
+
 	// Quiet is the option for running in quiet mode
 	Quiet        bool
 	Hostname     string
@@ -185,8 +249,9 @@ var header = `# Telegraf Configuration
 # file would generate.
 #
 # Environment variables can be used anywhere in this config file, simply prepend
-# them with $. For strings the variable must be within quotes (ie, "$STR_VAR"),
-# for numbers and booleans they should be plain (ie, $INT_VAR, $BOOL_VAR)
+# them with $, or write them as ${VAR}. For strings the variable must be within
+# quotes (ie, "$STR_VAR"), for numbers and booleans they should be plain
+# (ie, $INT_VAR, $BOOL_VAR)
 
 
 # Global tags can be specified here in key="value" format.
@@ -195,6 +260,10 @@ var header = `# Telegraf Configuration
   # rack = "1a"
   ## Environment variables can be used as tags, and throughout the config file
   # user = "$USER"
+  ## Tag values can be templates evaluated at startup, with the .Hostname and
+  ## .ShortHostname of the agent and the env, lower and upper functions
+  # region = '{{env "REGION" | lower}}'
+  # cluster = "{{.ShortHostname}}"
 
 
 # Configuration for telegraf agent
@@ -216,6 +285,13 @@ var header = `# Telegraf Configuration
   ## This buffer only fills when writes fail to output plugin(s).
   metric_buffer_limit = 10000
 
+  ## Policy when the buffer of an output fills up, "drop_oldest" drops the
+  ## oldest metrics, "backpressure" pauses the service inputs (ie. mqtt or
+  ## socket listeners) while a buffer is above metric_buffer_high_water times
+  ## metric_buffer_limit metrics, until writes succeed again.
+  metric_buffer_full_policy = "drop_oldest"
+  metric_buffer_high_water = 0.9
+
   ## Collection jitter is used to jitter the collection by a random amount.
   ## Each plugin will sleep for a random time within jitter before collecting.
   ## This can be used to avoid many plugins querying things like sysfs at the
@@ -229,6 +305,9 @@ var header = `# Telegraf Configuration
   ## large write spikes for users running a large number of telegraf instances.
   ## ie, a jitter of 5s and interval 10s means flushes will happen every 10-15s
   flush_jitter = "0s"
+  ## Time given to the outputs to write the metrics left when shutting down,
+  ## "0s" waits for them indefinitely.
+  shutdown_flush_timeout = "30s"
 
   ## By default or when set to "0s", precision will be set to the same
   ## timestamp order as the collection interval, with the maximum being 1s.
@@ -246,6 +325,30 @@ var header = `# Telegraf Configuration
   quiet = false
   ## Specify the log file name. The empty string means to log to stderr.
   logfile = ""
+  ## Format of the log lines, "text" or "json". The JSON lines have the time,
+  ## level and msg fields, along with the category and plugin fields for the
+  ## messages of the plugins, ie "inputs" and "cpu".
+  log_format = "text"
+
+  ## Specify the file the state of plugins, ie the offsets of tailed files,
+  ## is saved to when stopping and restored from when starting. The empty
+  ## string disables saving the state.
+  statefile = ""
+
+  ## Address to serve the health of the plugins on, as JSON at /healthz,
+  ## and the readiness of the agent at /readyz, ie ":8080". The empty
+  ## string disables the endpoints.
+  health_address = ""
+
+  ## Stop gathering from an input or writing to an output after it panicked
+  ## this many times in a row, 0 to never stop.
+  panic_limit = 3
+
+  ## Outputs of the same failover_group fail over to the next output after
+  ## failing this many writes in a row, and try to fail back to the first
+  ## output at this interval.
+  failover_threshold = 3
+  failover_retry_interval = "5m"
 
   ## Override default hostname, if empty use os.Hostname()
   hostname = ""
@@ -487,6 +590,82 @@ func sliceContains(name string, list []string) bool {
 	return false
 }
 
+// pluginID returns an id for a plugin derived from its configuration, as
+// written before the secrets are resolved so rotating a secret keeps the id.
+// Any other change of the settings of the plugin changes its id.
+func (c *Config) pluginID(kind, name string, tbl *ast.Table) string {
+	source, ok := c.unresolved[tbl]
+	if !ok {
+		source = tableSource(tbl)
+	}
+	sum := sha256.Sum256([]byte(source))
+	return fmt.Sprintf("%s.%s.%x", kind, name, sum[:8])
+}
+
+// keepUnresolved keeps the sources of the tables of the plugins, before
+// their secrets are resolved.
+func (c *Config) keepUnresolved(tbl *ast.Table) {
+	for name, val := range tbl.Fields {
+		subTable, ok := val.(*ast.Table)
+		if !ok {
+			continue
+		}
+		switch name {
+		case "agent", "global_tags", "tags", "secretstores":
+			continue
+		case "outputs", "inputs", "plugins", "processors", "aggregators":
+		default:
+			// legacy input tables
+			c.unresolved[subTable] = tableSource(subTable)
+			continue
+		}
+		for _, pluginVal := range subTable.Fields {
+			switch pluginSubTable := pluginVal.(type) {
+			case *ast.Table:
+				c.unresolved[pluginSubTable] = tableSource(pluginSubTable)
+			case []*ast.Table:
+				for _, t := range pluginSubTable {
+					c.unresolved[t] = tableSource(t)
+				}
+			}
+		}
+	}
+}
+
+// tableSource returns a canonical representation of the settings of a table,
+// which is identical for plugins configured the same way regardless of the
+// order of the settings and of comments.
+func tableSource(tbl *ast.Table) string {
+	var buf bytes.Buffer
+	writeTableSource(&buf, tbl)
+	return buf.String()
+}
+
+func writeTableSource(buf *bytes.Buffer, tbl *ast.Table) {
+	keys := make([]string, 0, len(tbl.Fields))
+	for k := range tbl.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		switch v := tbl.Fields[k].(type) {
+		case *ast.KeyValue:
+			fmt.Fprintf(buf, "%s = %s\n", k, v.Value.Source())
+		case *ast.Table:
+			fmt.Fprintf(buf, "[%s]\n", k)
+			writeTableSource(buf, v)
+			fmt.Fprintf(buf, "[/%s]\n", k)
+		case []*ast.Table:
+			for _, t := range v {
+				fmt.Fprintf(buf, "[[%s]]\n", k)
+				writeTableSource(buf, t)
+				fmt.Fprintf(buf, "[[/%s]]\n", k)
+			}
+		}
+	}
+}
+
 // PrintInputConfig prints the config usage of a single input.
 func PrintInputConfig(name string) error {
 	if creator, ok := inputs.Inputs[name]; ok {
@@ -561,11 +740,45 @@ func (c *Config) LoadConfig(path string) error {
 			return err
 		}
 	}
-	tbl, err := parseFile(path)
+	source, err := NewSource(path, c.ConfigHeaders)
+	if err != nil {
+		return fmt.Errorf("Error loading %s, %s", path, err)
+	}
+	contents, err := source.Load()
+	if err != nil {
+		return fmt.Errorf("Error loading %s, %s", path, err)
+	}
+	tbl, err := parseConfig(contents)
 	if err != nil {
 		return fmt.Errorf("Error parsing %s, %s", path, err)
 	}
 
+	// Parse secret stores first, their secrets can be referenced in all
+	// other tables:
+	if val, ok := tbl.Fields["secretstores"]; ok {
+		subTable, ok := val.(*ast.Table)
+		if !ok {
+			return fmt.Errorf("%s: invalid configuration", path)
+		}
+		for pluginName, pluginVal := range subTable.Fields {
+			switch pluginSubTable := pluginVal.(type) {
+			case []*ast.Table:
+				for _, t := range pluginSubTable {
+					if err = c.addSecretStore(pluginName, t); err != nil {
+						return fmt.Errorf("Error parsing %s, %s", path, err)
+					}
+				}
+			default:
+				return fmt.Errorf("Unsupported config format: %s, file %s",
+					pluginName, path)
+			}
+		}
+	}
+	c.keepUnresolved(tbl)
+	if err = c.resolveSecrets(tbl); err != nil {
+		return fmt.Errorf("Error parsing %s, %s", path, err)
+	}
+
 	// Parse tags tables first:
 	for _, tableName := range []string{"tags", "global_tags"} {
 		if val, ok := tbl.Fields[tableName]; ok {
@@ -600,7 +813,7 @@ func (c *Config) LoadConfig(path string) error {
 		}
 
 		switch name {
-		case "agent", "global_tags", "tags":
+		case "agent", "global_tags", "tags", "secretstores":
 		case "outputs":
 			for pluginName, pluginVal := range subTable.Fields {
 				switch pluginSubTable := pluginVal.(type) {
@@ -689,20 +902,16 @@ func trimBOM(f []byte) []byte {
 	return bytes.TrimPrefix(f, []byte("\xef\xbb\xbf"))
 }
 
-// parseFile loads a TOML configuration from a provided path and
-// returns the AST produced from the TOML parser. When loading the file, it
-// will find environment variables and replace them.
-func parseFile(fpath string) (*ast.Table, error) {
-	contents, err := ioutil.ReadFile(fpath)
-	if err != nil {
-		return nil, err
-	}
+// parseConfig returns the AST produced from the TOML parser for the contents
+// of a configuration file. It will find environment variables and replace
+// them.
+func parseConfig(contents []byte) (*ast.Table, error) {
 	// ugh windows why
 	contents = trimBOM(contents)
 
 	env_vars := envVarRe.FindAll(contents, -1)
 	for _, env_var := range env_vars {
-		env_val := os.Getenv(strings.TrimPrefix(string(env_var), "$"))
+		env_val := os.Getenv(strings.Trim(string(env_var), "${}"))
 		if env_val != "" {
 			contents = bytes.Replace(contents, env_var, []byte(env_val), 1)
 		}
@@ -717,11 +926,13 @@ func (c *Config) addAggregator(name string, table *ast.Table) error {
 		return fmt.Errorf("Undefined but requested aggregator: %s", name)
 	}
 	aggregator := creator()
+	source := tableSource(table)
 
 	conf, err := buildAggregator(name, table)
 	if err != nil {
 		return err
 	}
+	conf.Source = source
 
 	if err := toml.UnmarshalTable(table, aggregator); err != nil {
 		return err
@@ -731,17 +942,107 @@ func (c *Config) addAggregator(name string, table *ast.Table) error {
 	return nil
 }
 
+func (c *Config) addSecretStore(name string, table *ast.Table) error {
+	creator, ok := secretstores.SecretStores[name]
+	if !ok {
+		return fmt.Errorf("Undefined but requested secret store: %s", name)
+	}
+	store := creator()
+
+	var id string
+	if node, ok := table.Fields["id"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				id = str.Value
+			}
+		}
+	}
+	if !secretStoreIDRe.MatchString(id) {
+		return fmt.Errorf("secret store %s needs an id made of letters, digits, '-' and '_'", name)
+	}
+	if _, ok := c.SecretStores[id]; ok {
+		return fmt.Errorf("duplicate secret store id: %s", id)
+	}
+	delete(table.Fields, "id")
+
+	if err := c.resolveSecrets(table); err != nil {
+		return err
+	}
+	if err := toml.UnmarshalTable(table, store); err != nil {
+		return err
+	}
+
+	c.SecretStores[id] = store
+	return nil
+}
+
+// resolveSecrets replaces the @{store:key} references in the strings of a
+// table with the secrets they refer to.
+func (c *Config) resolveSecrets(tbl *ast.Table) error {
+	for name, val := range tbl.Fields {
+		switch v := val.(type) {
+		case *ast.KeyValue:
+			if err := c.resolveValueSecrets(v.Value); err != nil {
+				return fmt.Errorf("%s: %s", name, err)
+			}
+		case *ast.Table:
+			if err := c.resolveSecrets(v); err != nil {
+				return err
+			}
+		case []*ast.Table:
+			for _, t := range v {
+				if err := c.resolveSecrets(t); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Config) resolveValueSecrets(val ast.Value) error {
+	switch v := val.(type) {
+	case *ast.String:
+		var err error
+		v.Value = secretRe.ReplaceAllStringFunc(v.Value, func(ref string) string {
+			m := secretRe.FindStringSubmatch(ref)
+			store, ok := c.SecretStores[m[1]]
+			if !ok {
+				err = fmt.Errorf("unknown secret store %s", m[1])
+				return ref
+			}
+			secret, serr := store.Get(m[2])
+			if serr != nil {
+				err = serr
+				return ref
+			}
+			return secret
+		})
+		return err
+	case *ast.Array:
+		for _, e := range v.Value {
+			if err := c.resolveValueSecrets(e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (c *Config) addProcessor(name string, table *ast.Table) error {
 	creator, ok := processors.Processors[name]
 	if !ok {
 		return fmt.Errorf("Undefined but requested processor: %s", name)
 	}
 	processor := creator()
+	source := tableSource(table)
+	id := c.pluginID("processors", name, table)
 
 	processorConfig, err := buildProcessor(name, table)
 	if err != nil {
 		return err
 	}
+	processorConfig.Source = source
 
 	if err := toml.UnmarshalTable(table, processor); err != nil {
 		return err
@@ -751,7 +1052,10 @@ func (c *Config) addProcessor(name string, table *ast.Table) error {
 		Name:      name,
 		Processor: processor,
 		Config:    processorConfig,
+		ID:        id,
 	}
+	models.SetLoggerOnPlugin(processor,
+		models.NewLogger("processors", name, processorConfig.LogLevel))
 
 	c.Processors = append(c.Processors, rf)
 	return nil
@@ -766,6 +1070,8 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 		return fmt.Errorf("Undefined but requested output: %s", name)
 	}
 	output := creator()
+	source := tableSource(table)
+	id := c.pluginID("outputs", name, table)
 
 	// If the output has a SetSerializer function, then this means it can write
 	// arbitrary types of output, so build the serializer and set it.
@@ -777,18 +1083,27 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 		}
 		t.SetSerializer(serializer)
 	}
+	if t, ok := output.(outputs.GlobalTagsOutput); ok {
+		t.SetGlobalTags(c.Tags)
+	}
 
 	outputConfig, err := buildOutput(name, table)
 	if err != nil {
 		return err
 	}
+	outputConfig.Source = source
 
 	if err := toml.UnmarshalTable(table, output); err != nil {
 		return err
 	}
 
+	batchSize := c.Agent.MetricBatchSize
+	if outputConfig.MetricBatchSize > 0 {
+		batchSize = outputConfig.MetricBatchSize
+	}
 	ro := models.NewRunningOutput(name, output, outputConfig,
-		c.Agent.MetricBatchSize, c.Agent.MetricBufferLimit)
+		batchSize, c.Agent.MetricBufferLimit)
+	ro.ID = id
 	c.Outputs = append(c.Outputs, ro)
 	return nil
 }
@@ -807,6 +1122,8 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 		return fmt.Errorf("Undefined but requested input: %s", name)
 	}
 	input := creator()
+	source := tableSource(table)
+	id := c.pluginID("inputs", name, table)
 
 	// If the input has a SetParser function, then this means it can accept
 	// arbitrary types of input, so build the parser and set it.
@@ -823,12 +1140,14 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 	if err != nil {
 		return err
 	}
+	pluginConfig.Source = source
 
 	if err := toml.UnmarshalTable(table, input); err != nil {
 		return err
 	}
 
 	rp := models.NewRunningInput(input, pluginConfig)
+	rp.ID = id
 	c.Inputs = append(c.Inputs, rp)
 	return nil
 }
@@ -922,6 +1241,12 @@ func buildAggregator(name string, tbl *ast.Table) (*models.AggregatorConfig, err
 		}
 	}
 
+	var err error
+	conf.LogLevel, err = buildLogLevel(name, tbl)
+	if err != nil {
+		return nil, err
+	}
+
 	delete(tbl.Fields, "period")
 	delete(tbl.Fields, "delay")
 	delete(tbl.Fields, "drop_original")
@@ -929,7 +1254,6 @@ func buildAggregator(name string, tbl *ast.Table) (*models.AggregatorConfig, err
 	delete(tbl.Fields, "name_suffix")
 	delete(tbl.Fields, "name_override")
 	delete(tbl.Fields, "tags")
-	var err error
 	conf.Filter, err = buildFilter(tbl)
 	if err != nil {
 		return conf, err
@@ -937,6 +1261,23 @@ func buildAggregator(name string, tbl *ast.Table) (*models.AggregatorConfig, err
 	return conf, nil
 }
 
+// buildLogLevel returns the log_level of the plugin, empty if not set.
+func buildLogLevel(name string, tbl *ast.Table) (string, error) {
+	var level string
+	if node, ok := tbl.Fields["log_level"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				if _, err := logger.ParseLevel(str.Value); err != nil {
+					return "", fmt.Errorf("%s for %s", err, name)
+				}
+				level = str.Value
+			}
+		}
+	}
+	delete(tbl.Fields, "log_level")
+	return level, nil
+}
+
 // buildProcessor parses Processor specific items from the ast.Table,
 // builds the filter and returns a
 // models.ProcessorConfig to be inserted into models.RunningProcessor
@@ -962,8 +1303,13 @@ func buildProcessor(name string, tbl *ast.Table) (*models.ProcessorConfig, error
 		}
 	}
 
-	delete(tbl.Fields, "order")
 	var err error
+	conf.LogLevel, err = buildLogLevel(name, tbl)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(tbl.Fields, "order")
 	conf.Filter, err = buildFilter(tbl)
 	if err != nil {
 		return conf, err
@@ -1091,6 +1437,13 @@ func buildFilter(tbl *ast.Table) (models.Filter, error) {
 			}
 		}
 	}
+	if node, ok := tbl.Fields["metricpass"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				f.MetricPass = str.Value
+			}
+		}
+	}
 	if err := f.Compile(); err != nil {
 		return f, err
 	}
@@ -1105,6 +1458,7 @@ func buildFilter(tbl *ast.Table) (models.Filter, error) {
 	delete(tbl.Fields, "tagpass")
 	delete(tbl.Fields, "tagexclude")
 	delete(tbl.Fields, "taginclude")
+	delete(tbl.Fields, "metricpass")
 	return f, nil
 }
 
@@ -1126,6 +1480,45 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 		}
 	}
 
+	if node, ok := tbl.Fields["collection_jitter"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				cp.CollectionJitter = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["precision"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				cp.Precision = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["round_interval"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				roundInterval, err := strconv.ParseBool(b.Value)
+				if err != nil {
+					log.Printf("Error parsing boolean value for %s: %s\n", name, err)
+				} else {
+					cp.RoundInterval = &roundInterval
+				}
+			}
+		}
+	}
+
 	if node, ok := tbl.Fields["name_prefix"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if str, ok := kv.Value.(*ast.String); ok {
@@ -1159,12 +1552,20 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 		}
 	}
 
+	var err error
+	cp.LogLevel, err = buildLogLevel(name, tbl)
+	if err != nil {
+		return nil, err
+	}
+
 	delete(tbl.Fields, "name_prefix")
 	delete(tbl.Fields, "name_suffix")
 	delete(tbl.Fields, "name_override")
 	delete(tbl.Fields, "interval")
+	delete(tbl.Fields, "collection_jitter")
+	delete(tbl.Fields, "precision")
+	delete(tbl.Fields, "round_interval")
 	delete(tbl.Fields, "tags")
-	var err error
 	cp.Filter, err = buildFilter(tbl)
 	if err != nil {
 		return cp, err
@@ -1261,12 +1662,155 @@ func buildParser(name string, tbl *ast.Table) (parsers.Parser, error) {
 		}
 	}
 
+	if node, ok := tbl.Fields["json_strings_as_numbers"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				var err error
+				c.JSONStringsAsNumbers, err = strconv.ParseBool(b.Value)
+				if err != nil {
+					log.Printf("Error parsing boolean value for %s: %s\n", name, err)
+				}
+			}
+		}
+	}
+
+	for key, value := range map[string]*string{
+		"json_time_key":       &c.JSONTimeKey,
+		"json_time_format":    &c.JSONTimeFormat,
+		"json_v2_query":       &c.JSONV2Query,
+		"json_v2_name_path":   &c.JSONV2NamePath,
+		"json_v2_time_path":   &c.JSONV2TimePath,
+		"json_v2_time_format": &c.JSONV2TimeFormat,
+		"xml_query":           &c.XMLQuery,
+		"xml_name_path":       &c.XMLNamePath,
+		"xml_time_path":       &c.XMLTimePath,
+		"xml_time_format":     &c.XMLTimeFormat,
+		"csv_delimiter":       &c.CSVDelimiter,
+		"csv_comment":         &c.CSVComment,
+
+		"csv_measurement_column": &c.CSVMeasurementColumn,
+		"csv_timestamp_column":   &c.CSVTimestampColumn,
+		"csv_timestamp_format":   &c.CSVTimestampFormat,
+		"csv_timezone":           &c.CSVTimezone,
+
+		"avro_schema_registry":  &c.AvroSchemaRegistry,
+		"avro_schema":           &c.AvroSchema,
+		"avro_measurement":      &c.AvroMeasurement,
+		"avro_timestamp":        &c.AvroTimestamp,
+		"avro_timestamp_format": &c.AvroTimestampFormat,
+		"avro_field_separator":  &c.AvroFieldSeparator,
+
+		"protobuf_descriptor_set":   &c.ProtobufDescriptorSet,
+		"protobuf_message_type":     &c.ProtobufMessageType,
+		"protobuf_measurement":      &c.ProtobufMeasurement,
+		"protobuf_timestamp":        &c.ProtobufTimestamp,
+		"protobuf_timestamp_format": &c.ProtobufTimestampFormat,
+		"protobuf_field_separator":  &c.ProtobufFieldSeparator,
+
+		"syslog_format":            &c.SyslogFormat,
+		"syslog_sdparam_separator": &c.SyslogSdparamSeparator,
+		"syslog_timezone":          &c.SyslogTimezone,
+
+		"value_separator": &c.ValueSeparator,
+
+		"binary_endianness":       &c.BinaryEndianness,
+		"binary_timestamp":        &c.BinaryTimestamp,
+		"binary_timestamp_format": &c.BinaryTimestampFormat,
+	} {
+		if node, ok := tbl.Fields[key]; ok {
+			if kv, ok := node.(*ast.KeyValue); ok {
+				if str, ok := kv.Value.(*ast.String); ok {
+					*value = str.Value
+				}
+			}
+		}
+		delete(tbl.Fields, key)
+	}
+
+	for key, paths := range map[string]*map[string]string{
+		"json_v2_tag_paths":   &c.JSONV2TagPaths,
+		"json_v2_field_paths": &c.JSONV2FieldPaths,
+		"json_v2_field_types": &c.JSONV2FieldTypes,
+		"xml_tag_paths":       &c.XMLTagPaths,
+		"xml_field_paths":     &c.XMLFieldPaths,
+		"xml_field_types":     &c.XMLFieldTypes,
+	} {
+		if node, ok := tbl.Fields[key]; ok {
+			if subtbl, ok := node.(*ast.Table); ok {
+				*paths = make(map[string]string)
+				if err := toml.UnmarshalTable(subtbl, *paths); err != nil {
+					return nil, fmt.Errorf("Could not parse %s for %s: %s", key, name, err)
+				}
+			}
+		}
+		delete(tbl.Fields, key)
+	}
+
+	for key, value := range map[string]*int{
+		"csv_header_row_count": &c.CSVHeaderRowCount,
+		"csv_skip_rows":        &c.CSVSkipRows,
+		"csv_skip_columns":     &c.CSVSkipColumns,
+		"binary_record_length": &c.BinaryRecordLength,
+	} {
+		if node, ok := tbl.Fields[key]; ok {
+			if kv, ok := node.(*ast.KeyValue); ok {
+				if integer, ok := kv.Value.(*ast.Integer); ok {
+					var err error
+					*value, err = strconv.Atoi(integer.Value)
+					if err != nil {
+						log.Printf("Error parsing int value for %s: %s\n", name, err)
+					}
+				}
+			}
+		}
+		delete(tbl.Fields, key)
+	}
+
+	for key, values := range map[string]*[]string{
+		"csv_column_names":  &c.CSVColumnNames,
+		"csv_column_types":  &c.CSVColumnTypes,
+		"csv_tag_columns":   &c.CSVTagColumns,
+		"avro_tags":         &c.AvroTags,
+		"avro_fields":       &c.AvroFields,
+		"protobuf_tags":     &c.ProtobufTags,
+		"protobuf_fields":   &c.ProtobufFields,
+		"binary_tags":       &c.BinaryTags,
+		"value_field_names": &c.ValueFieldNames,
+	} {
+		if node, ok := tbl.Fields[key]; ok {
+			if kv, ok := node.(*ast.KeyValue); ok {
+				if ary, ok := kv.Value.(*ast.Array); ok {
+					for _, elem := range ary.Value {
+						if str, ok := elem.(*ast.String); ok {
+							*values = append(*values, str.Value)
+						}
+					}
+				}
+			}
+		}
+		delete(tbl.Fields, key)
+	}
+
+	if node, ok := tbl.Fields["binary_fields"]; ok {
+		if subtbls, ok := node.([]*ast.Table); ok {
+			for _, subtbl := range subtbls {
+				var field binary.Field
+				if err := toml.UnmarshalTable(subtbl, &field); err != nil {
+					return nil, fmt.Errorf("Could not parse binary_fields for %s: %s", name, err)
+				}
+				c.BinaryFields = append(c.BinaryFields, field)
+			}
+		}
+	}
+	delete(tbl.Fields, "binary_fields")
+
 	c.MetricName = name
 
 	delete(tbl.Fields, "data_format")
 	delete(tbl.Fields, "separator")
 	delete(tbl.Fields, "templates")
 	delete(tbl.Fields, "tag_keys")
+	delete(tbl.Fields, "json_strings_as_numbers")
 	delete(tbl.Fields, "data_type")
 	delete(tbl.Fields, "collectd_auth_file")
 	delete(tbl.Fields, "collectd_security_level")
@@ -1325,10 +1869,123 @@ func buildSerializer(name string, tbl *ast.Table) (serializers.Serializer, error
 		}
 	}
 
+	for key, value := range map[string]*string{
+		"json_timestamp_format":   &c.JSONTimestampFormat,
+		"json_nest_separator":     &c.JSONNestSeparator,
+		"cloudevents_source":      &c.CloudEventsSource,
+		"cloudevents_type":        &c.CloudEventsType,
+		"opentelemetry_encoding":  &c.OpenTelemetryEncoding,
+		"splunkmetric_index":      &c.SplunkmetricIndex,
+		"splunkmetric_source":     &c.SplunkmetricSource,
+		"splunkmetric_sourcetype": &c.SplunkmetricSourcetype,
+		"csv_timestamp_format":    &c.CSVTimestampFormat,
+		"csv_separator":           &c.CSVSeparator,
+	} {
+		if node, ok := tbl.Fields[key]; ok {
+			if kv, ok := node.(*ast.KeyValue); ok {
+				if str, ok := kv.Value.(*ast.String); ok {
+					*value = str.Value
+				}
+			}
+		}
+		delete(tbl.Fields, key)
+	}
+
+	if node, ok := tbl.Fields["templates"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.Templates = append(c.Templates, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["graphite_tag_support"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				var err error
+				c.GraphiteTagSupport, err = strconv.ParseBool(b.Value)
+				if err != nil {
+					log.Printf("Error parsing boolean value for %s: %s\n", name, err)
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_header"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				var err error
+				c.CSVHeader, err = strconv.ParseBool(b.Value)
+				if err != nil {
+					log.Printf("Error parsing boolean value for %s: %s\n", name, err)
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_columns"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.CSVColumns = append(c.CSVColumns, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["cloudevents_batch"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				var err error
+				c.CloudEventsBatch, err = strconv.ParseBool(b.Value)
+				if err != nil {
+					log.Printf("Error parsing boolean value for %s: %s\n", name, err)
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["opentelemetry_resource_tags"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.OpenTelemetryResourceTags = append(c.OpenTelemetryResourceTags, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["wavefront_source_override"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.WavefrontSourceOverride = append(c.WavefrontSourceOverride, str.Value)
+					}
+				}
+			}
+		}
+	}
+
 	delete(tbl.Fields, "data_format")
 	delete(tbl.Fields, "prefix")
 	delete(tbl.Fields, "template")
+	delete(tbl.Fields, "templates")
+	delete(tbl.Fields, "graphite_tag_support")
 	delete(tbl.Fields, "json_timestamp_units")
+	delete(tbl.Fields, "cloudevents_batch")
+	delete(tbl.Fields, "opentelemetry_resource_tags")
+	delete(tbl.Fields, "wavefront_source_override")
+	delete(tbl.Fields, "csv_header")
+	delete(tbl.Fields, "csv_columns")
 	return serializers.NewSerializer(c)
 }
 
@@ -1352,5 +2009,142 @@ func buildOutput(name string, tbl *ast.Table) (*models.OutputConfig, error) {
 	if len(oc.Filter.FieldPass) > 0 {
 		oc.Filter.NamePass = oc.Filter.FieldPass
 	}
+
+	if node, ok := tbl.Fields["dead_letter"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				oc.DeadLetter, err = strconv.ParseBool(b.Value)
+				if err != nil {
+					log.Printf("Error parsing boolean value for %s: %s\n", name, err)
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["dead_letter_retries"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Integer); ok {
+				oc.DeadLetterRetries, err = strconv.Atoi(b.Value)
+				if err != nil {
+					log.Printf("Error parsing int value for %s: %s\n", name, err)
+				}
+			}
+		}
+	}
+
+	oc.BufferMaxSize = models.DEFAULT_BUFFER_MAX_SIZE
+	if node, ok := tbl.Fields["buffer_path"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.BufferPath = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["buffer_max_size"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Integer); ok {
+				oc.BufferMaxSize, err = strconv.ParseInt(b.Value, 10, 64)
+				if err != nil {
+					log.Printf("Error parsing int value for %s: %s\n", name, err)
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["max_write_rate"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Integer); ok {
+				oc.MaxWriteRate, err = strconv.Atoi(b.Value)
+				if err != nil {
+					log.Printf("Error parsing int value for %s: %s\n", name, err)
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["max_write_bytes_rate"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Integer); ok {
+				oc.MaxWriteBytesRate, err = strconv.Atoi(b.Value)
+				if err != nil {
+					log.Printf("Error parsing int value for %s: %s\n", name, err)
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["metric_batch_bytes"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Integer); ok {
+				oc.MetricBatchBytes, err = strconv.Atoi(b.Value)
+				if err != nil {
+					log.Printf("Error parsing int value for %s: %s\n", name, err)
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["failover_group"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.FailoverGroup = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["flush_interval"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				oc.FlushInterval = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["flush_jitter"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				oc.FlushJitter = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["metric_batch_size"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Integer); ok {
+				oc.MetricBatchSize, err = strconv.Atoi(b.Value)
+				if err != nil {
+					log.Printf("Error parsing int value for %s: %s\n", name, err)
+				}
+			}
+		}
+	}
+
+	oc.LogLevel, err = buildLogLevel(name, tbl)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(tbl.Fields, "dead_letter")
+	delete(tbl.Fields, "dead_letter_retries")
+	delete(tbl.Fields, "failover_group")
+	delete(tbl.Fields, "buffer_path")
+	delete(tbl.Fields, "buffer_max_size")
+	delete(tbl.Fields, "max_write_rate")
+	delete(tbl.Fields, "max_write_bytes_rate")
+	delete(tbl.Fields, "flush_interval")
+	delete(tbl.Fields, "flush_jitter")
+	delete(tbl.Fields, "metric_batch_size")
+	delete(tbl.Fields, "metric_batch_bytes")
 	return oc, nil
 }
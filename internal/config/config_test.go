@@ -1,6 +1,9 @@
 package config
 
 import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 	"time"
@@ -10,9 +13,15 @@ import (
 	"github.com/influxdata/telegraf/plugins/inputs/exec"
 	"github.com/influxdata/telegraf/plugins/inputs/memcached"
 	"github.com/influxdata/telegraf/plugins/inputs/procstat"
+	_ "github.com/influxdata/telegraf/plugins/outputs/file"
 	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/plugins/parsers/binary"
+	_ "github.com/influxdata/telegraf/plugins/secretstores/env"
 
+	"github.com/influxdata/toml"
+	"github.com/influxdata/toml/ast"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConfig_LoadSingleInputWithEnvVars(t *testing.T) {
@@ -52,6 +61,11 @@ func TestConfig_LoadSingleInputWithEnvVars(t *testing.T) {
 	}
 	mConfig.Tags = make(map[string]string)
 
+	// The sources of the tables are tested by TestTableSource.
+	for _, input := range c.Inputs {
+		input.Config.Source = ""
+	}
+
 	assert.Equal(t, memcached, c.Inputs[0].Input,
 		"Testdata did not produce a correct memcached struct.")
 	assert.Equal(t, mConfig, c.Inputs[0].Config,
@@ -85,12 +99,18 @@ func TestConfig_LoadSingleInput(t *testing.T) {
 	}
 	assert.NoError(t, filter.Compile())
 	mConfig := &models.InputConfig{
-		Name:     "memcached",
-		Filter:   filter,
-		Interval: 5 * time.Second,
+		Name:             "memcached",
+		Filter:           filter,
+		Interval:         5 * time.Second,
+		CollectionJitter: 2 * time.Second,
 	}
 	mConfig.Tags = make(map[string]string)
 
+	// The sources of the tables are tested by TestTableSource.
+	for _, input := range c.Inputs {
+		input.Config.Source = ""
+	}
+
 	assert.Equal(t, memcached, c.Inputs[0].Input,
 		"Testdata did not produce a correct memcached struct.")
 	assert.Equal(t, mConfig, c.Inputs[0].Config,
@@ -131,12 +151,18 @@ func TestConfig_LoadDirectory(t *testing.T) {
 	}
 	assert.NoError(t, filter.Compile())
 	mConfig := &models.InputConfig{
-		Name:     "memcached",
-		Filter:   filter,
-		Interval: 5 * time.Second,
+		Name:             "memcached",
+		Filter:           filter,
+		Interval:         5 * time.Second,
+		CollectionJitter: 2 * time.Second,
 	}
 	mConfig.Tags = make(map[string]string)
 
+	// The sources of the tables are tested by TestTableSource.
+	for _, input := range c.Inputs {
+		input.Config.Source = ""
+	}
+
 	assert.Equal(t, memcached, c.Inputs[0].Input,
 		"Testdata did not produce a correct memcached struct.")
 	assert.Equal(t, mConfig, c.Inputs[0].Config,
@@ -174,3 +200,326 @@ func TestConfig_LoadDirectory(t *testing.T) {
 	assert.Equal(t, pConfig, c.Inputs[3].Config,
 		"Merged Testdata did not produce correct procstat metadata.")
 }
+
+func TestTableSource(t *testing.T) {
+	parse := func(doc string) *ast.Table {
+		tbl, err := toml.Parse([]byte(doc))
+		require.NoError(t, err)
+		return tbl.Fields["outputs"].(*ast.Table).Fields["file"].([]*ast.Table)[0]
+	}
+
+	a := parse(`
+[[outputs.file]]
+  # comment
+  files = ["stdout"]
+  data_format = "influx"
+  [outputs.file.tagpass]
+    cpu = ["cpu0"]
+`)
+	b := parse(`
+[[outputs.file]]
+  data_format = "influx"
+  files = ["stdout"]
+  [outputs.file.tagpass]
+    cpu = ["cpu0"]
+`)
+	c := parse(`
+[[outputs.file]]
+  data_format = "json"
+  files = ["stdout"]
+`)
+	assert.Equal(t, tableSource(a), tableSource(b))
+	assert.NotEqual(t, tableSource(a), tableSource(c))
+}
+
+func TestConfig_LoadURL(t *testing.T) {
+	contents, err := ioutil.ReadFile("./testdata/single_plugin.toml")
+	require.NoError(t, err)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write(contents)
+	}))
+	defer ts.Close()
+
+	c := NewConfig()
+	assert.Error(t, c.LoadConfig(ts.URL+"/telegraf.conf"))
+
+	c = NewConfig()
+	c.ConfigHeaders = map[string]string{"Authorization": "Bearer secret"}
+	require.NoError(t, c.LoadConfig(ts.URL+"/telegraf.conf"))
+	require.Len(t, c.Inputs, 1)
+	assert.Equal(t, "memcached", c.Inputs[0].Config.Name)
+}
+
+func TestConfig_Secrets(t *testing.T) {
+	os.Setenv("TELEGRAF_TEST_SERVER", "192.168.1.1")
+	os.Setenv("TELEGRAF_TEST_TOKEN", "s3cr3t")
+	defer os.Unsetenv("TELEGRAF_TEST_SERVER")
+	defer os.Unsetenv("TELEGRAF_TEST_TOKEN")
+
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/secrets.toml"))
+	require.Len(t, c.Inputs, 1)
+
+	memcached := c.Inputs[0].Input.(*memcached.Memcached)
+	assert.Equal(t, []string{"192.168.1.1"}, memcached.Servers)
+	assert.Equal(t, map[string]string{"token": "Bearer s3cr3t"}, c.Inputs[0].Config.Tags)
+
+	// The id of the plugin, saving its state, does not change with the
+	// value of its secrets.
+	id := c.Inputs[0].ID
+	os.Setenv("TELEGRAF_TEST_TOKEN", "r0t4t3d")
+	c = NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/secrets.toml"))
+	require.Len(t, c.Inputs, 1)
+	assert.Equal(t, map[string]string{"token": "Bearer r0t4t3d"}, c.Inputs[0].Config.Tags)
+	assert.Equal(t, id, c.Inputs[0].ID)
+
+	c = NewConfig()
+	assert.Error(t, c.LoadConfig("./testdata/secrets_unknown_store.toml"))
+}
+
+func TestConfig_MetricPass(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/metricpass.toml"))
+	require.Len(t, c.Inputs, 1)
+
+	filter := c.Inputs[0].Config.Filter
+	assert.Equal(t, `fields.get_hits > 100 && tags.server == "localhost"`, filter.MetricPass)
+	assert.True(t, filter.IsActive())
+	assert.True(t, filter.Apply("memcached",
+		map[string]interface{}{"get_hits": int64(150)},
+		map[string]string{"server": "localhost"}))
+}
+
+func TestConfig_InputPrecision(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/precision.toml"))
+	require.Len(t, c.Inputs, 1)
+
+	conf := c.Inputs[0].Config
+	assert.Equal(t, time.Nanosecond, conf.Precision)
+	require.NotNil(t, conf.RoundInterval)
+	assert.False(t, *conf.RoundInterval)
+	assert.Equal(t, []string{"localhost"},
+		c.Inputs[0].Input.(*memcached.Memcached).Servers)
+}
+
+func TestConfig_OutputFlush(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/output_flush.toml"))
+	require.Len(t, c.Outputs, 1)
+
+	ro := c.Outputs[0]
+	assert.Equal(t, time.Minute, ro.Config.FlushInterval)
+	assert.Equal(t, 10*time.Second, ro.Config.FlushJitter)
+	assert.Equal(t, 5000, ro.MetricBatchSize)
+}
+
+func TestConfig_LogLevel(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/log_level.toml"))
+	require.Len(t, c.Inputs, 2)
+	require.Len(t, c.Outputs, 1)
+
+	assert.Equal(t, "debug", c.Inputs[0].Config.LogLevel)
+	assert.Equal(t, "", c.Inputs[1].Config.LogLevel)
+	assert.Equal(t, "error", c.Outputs[0].Config.LogLevel)
+
+	c = NewConfig()
+	assert.Error(t, c.LoadConfig("./testdata/log_level_invalid.toml"))
+}
+
+func TestConfig_JSONV2Parser(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/json_v2.toml"))
+	require.Len(t, c.Inputs, 1)
+
+	ex := inputs.Inputs["exec"]().(*exec.Exec)
+	p, err := parsers.NewParser(&parsers.Config{
+		DataFormat:       "json_v2",
+		MetricName:       "exec",
+		JSONV2Query:      "sitesEnergy.siteEnergyList",
+		JSONV2TimePath:   "date",
+		JSONV2TimeFormat: "2006-01-02 15:04:05",
+		JSONV2TagPaths:   map[string]string{"site": "siteId"},
+		JSONV2FieldPaths: map[string]string{"energy": "energyValues.values.0.value"},
+		JSONV2FieldTypes: map[string]string{"energy": "float"},
+	})
+	require.NoError(t, err)
+	ex.SetParser(p)
+	ex.Command = "/usr/bin/solaredge-energy"
+	assert.Equal(t, ex, c.Inputs[0].Input)
+}
+
+func TestConfig_XMLParser(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/xml.toml"))
+	require.Len(t, c.Inputs, 1)
+
+	ex := inputs.Inputs["exec"]().(*exec.Exec)
+	p, err := parsers.NewParser(&parsers.Config{
+		DataFormat:    "xml",
+		MetricName:    "exec",
+		XMLQuery:      "//device",
+		XMLTimePath:   "/plc/timestamp",
+		XMLTagPaths:   map[string]string{"device": "@id"},
+		XMLFieldPaths: map[string]string{"speed": "speed"},
+		XMLFieldTypes: map[string]string{"speed": "float"},
+	})
+	require.NoError(t, err)
+	ex.SetParser(p)
+	ex.Command = "/usr/bin/plc-status"
+	assert.Equal(t, ex, c.Inputs[0].Input)
+}
+
+func TestConfig_CSVParser(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/csv.toml"))
+	require.Len(t, c.Inputs, 1)
+
+	ex := inputs.Inputs["exec"]().(*exec.Exec)
+	p, err := parsers.NewParser(&parsers.Config{
+		DataFormat:         "csv",
+		MetricName:         "exec",
+		CSVSkipRows:        2,
+		CSVHeaderRowCount:  2,
+		CSVSkipColumns:     1,
+		CSVDelimiter:       ";",
+		CSVColumnTypes:     []string{"", "float"},
+		CSVTagColumns:      []string{"Inverter"},
+		CSVTimestampColumn: "Date",
+		CSVTimestampFormat: "2006-01-02 15:04:05",
+		CSVTimezone:        "Europe/Berlin",
+	})
+	require.NoError(t, err)
+	ex.SetParser(p)
+	ex.Command = "/usr/bin/inverter-export"
+	assert.Equal(t, ex, c.Inputs[0].Input)
+}
+
+func TestConfig_AvroParser(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/avro.toml"))
+	require.Len(t, c.Inputs, 1)
+
+	ex := inputs.Inputs["exec"]().(*exec.Exec)
+	p, err := parsers.NewParser(&parsers.Config{
+		DataFormat:          "avro",
+		MetricName:          "exec",
+		AvroSchema:          `{"type": "record", "name": "Reading", "fields": [{"name": "device", "type": "string"}, {"name": "power", "type": "double"}, {"name": "time", "type": "long"}]}`,
+		AvroMeasurement:     "device",
+		AvroTags:            []string{"line"},
+		AvroFields:          []string{"power"},
+		AvroTimestamp:       "time",
+		AvroTimestampFormat: "unix_ms",
+		AvroFieldSeparator:  ".",
+	})
+	require.NoError(t, err)
+	ex.SetParser(p)
+	ex.Command = "/usr/bin/avro-reading"
+	assert.Equal(t, ex, c.Inputs[0].Input)
+
+	c = NewConfig()
+	assert.Error(t, c.LoadConfig("./testdata/avro_invalid.toml"))
+}
+
+func TestConfig_ProtobufParser(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/protobuf.toml"))
+	require.Len(t, c.Inputs, 1)
+
+	ex := inputs.Inputs["exec"]().(*exec.Exec)
+	p, err := parsers.NewParser(&parsers.Config{
+		DataFormat:              "protobuf",
+		MetricName:              "exec",
+		ProtobufDescriptorSet:   "../../plugins/parsers/protobuf/testdata/reading.desc",
+		ProtobufMessageType:     "sensors.Reading",
+		ProtobufMeasurement:     "device",
+		ProtobufTags:            []string{"status"},
+		ProtobufFields:          []string{"power"},
+		ProtobufTimestamp:       "time",
+		ProtobufTimestampFormat: "unix_ms",
+		ProtobufFieldSeparator:  ".",
+	})
+	require.NoError(t, err)
+	ex.SetParser(p)
+	ex.Command = "/usr/bin/read-sensor"
+	assert.Equal(t, ex, c.Inputs[0].Input)
+}
+
+func TestConfig_SyslogParser(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/syslog.toml"))
+	require.Len(t, c.Inputs, 1)
+
+	ex := inputs.Inputs["exec"]().(*exec.Exec)
+	p, err := parsers.NewParser(&parsers.Config{
+		DataFormat:             "syslog",
+		MetricName:             "exec",
+		SyslogFormat:           "rfc3164",
+		SyslogSdparamSeparator: ".",
+		SyslogTimezone:         "Europe/Berlin",
+	})
+	require.NoError(t, err)
+	ex.SetParser(p)
+	ex.Command = "/usr/bin/tail-syslog"
+	assert.Equal(t, ex, c.Inputs[0].Input)
+
+	c = NewConfig()
+	assert.Error(t, c.LoadConfig("./testdata/syslog_invalid.toml"))
+}
+
+func TestConfig_BinaryParser(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/binary.toml"))
+	require.Len(t, c.Inputs, 1)
+
+	ex := inputs.Inputs["exec"]().(*exec.Exec)
+	p, err := parsers.NewParser(&parsers.Config{
+		DataFormat:         "binary",
+		MetricName:         "exec",
+		BinaryEndianness:   "le",
+		BinaryRecordLength: 8,
+		BinaryFields: []binary.Field{
+			{Name: "serial", Type: "uint16", Offset: 0},
+			{Name: "time", Type: "uint32", Offset: 2},
+			{Name: "voltage", Type: "uint16", Offset: 6, Endianness: "be"},
+		},
+		BinaryTags:            []string{"serial"},
+		BinaryTimestamp:       "time",
+		BinaryTimestampFormat: "unix",
+	})
+	require.NoError(t, err)
+	ex.SetParser(p)
+	ex.Command = "/usr/bin/read-meter"
+	assert.Equal(t, ex, c.Inputs[0].Input)
+}
+
+func TestConfig_ValueParserFieldNames(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/value.toml"))
+	require.Len(t, c.Inputs, 1)
+
+	ex := inputs.Inputs["exec"]().(*exec.Exec)
+	p, err := parsers.NewParser(&parsers.Config{
+		DataFormat:      "value",
+		MetricName:      "exec",
+		DataType:        "float",
+		ValueSeparator:  ",",
+		ValueFieldNames: []string{"voltage", "current", "power"},
+	})
+	require.NoError(t, err)
+	ex.SetParser(p)
+	ex.Command = "/usr/bin/read-sensor"
+	assert.Equal(t, ex, c.Inputs[0].Input)
+
+	_, err = parsers.NewParser(&parsers.Config{
+		DataFormat:     "value",
+		ValueSeparator: ",",
+	})
+	assert.Error(t, err)
+}
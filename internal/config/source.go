@@ -0,0 +1,219 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/hashicorp/consul/api"
+
+	internalaws "github.com/influxdata/telegraf/internal/config/aws"
+)
+
+// Source loads the contents of a configuration file.
+type Source interface {
+	Load() ([]byte, error)
+}
+
+// IsRemote returns true if the configuration path is the URL of a remote
+// source, an HTTP(S) server, a Consul or etcd key or an S3 object.
+func IsRemote(path string) bool {
+	u, err := url.Parse(path)
+	if err != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "http", "https", "consul", "etcd", "s3":
+		return true
+	}
+	return false
+}
+
+// NewSource returns the source of a configuration path, either a file or
+// one of the following URLs:
+//
+// http(s)://host/path loads the response of a GET request.
+// consul://host:port/key loads a Consul KV key, the address and the ACL
+// token default to the CONSUL_HTTP_ADDR and CONSUL_HTTP_TOKEN variables.
+// etcd://host:port/key loads a key of the etcd v3 API.
+// s3://bucket/key loads an S3 object, the region is set with the region
+// parameter or the AWS_REGION variable.
+//
+// The headers are sent with HTTP(S) and etcd requests, ie to authenticate.
+func NewSource(path string, headers map[string]string) (Source, error) {
+	if !IsRemote(path) {
+		return fileSource(path), nil
+	}
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+	key := strings.TrimPrefix(u.Path, "/")
+
+	switch u.Scheme {
+	case "consul":
+		if key == "" {
+			return nil, fmt.Errorf("missing key in %s", path)
+		}
+		return &consulSource{address: u.Host, key: key}, nil
+	case "etcd":
+		if u.Host == "" || key == "" {
+			return nil, fmt.Errorf("missing address or key in %s", path)
+		}
+		return &etcdSource{
+			endpoint: "http://" + u.Host + "/v3/kv/range",
+			key:      u.Path,
+			headers:  headers,
+		}, nil
+	case "s3":
+		if u.Host == "" || key == "" {
+			return nil, fmt.Errorf("missing bucket or key in %s", path)
+		}
+		region := u.Query().Get("region")
+		if region == "" {
+			region = os.Getenv("AWS_REGION")
+		}
+		return &s3Source{bucket: u.Host, key: key, region: region}, nil
+	}
+	return &httpSource{url: path, headers: headers}, nil
+}
+
+type fileSource string
+
+func (f fileSource) Load() ([]byte, error) {
+	return ioutil.ReadFile(string(f))
+}
+
+func newHTTPClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+		},
+		Timeout: 30 * time.Second,
+	}
+}
+
+// do sends a request with the headers and returns the body of the response.
+func do(req *http.Request, headers map[string]string) ([]byte, error) {
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := newHTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("received status code %d (%s), expected %d (%s)",
+			resp.StatusCode, http.StatusText(resp.StatusCode),
+			http.StatusOK, http.StatusText(http.StatusOK))
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+type httpSource struct {
+	url     string
+	headers map[string]string
+}
+
+func (h *httpSource) Load() ([]byte, error) {
+	req, err := http.NewRequest("GET", h.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return do(req, h.headers)
+}
+
+type consulSource struct {
+	address string
+	key     string
+}
+
+func (c *consulSource) Load() ([]byte, error) {
+	config := api.DefaultConfig()
+	if c.address != "" {
+		config.Address = c.address
+	}
+	client, err := api.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	pair, _, err := client.KV().Get(c.key, nil)
+	if err != nil {
+		return nil, err
+	}
+	if pair == nil {
+		return nil, fmt.Errorf("key %s not found", c.key)
+	}
+	return pair.Value, nil
+}
+
+type etcdSource struct {
+	endpoint string
+	key      string
+	headers  map[string]string
+}
+
+func (e *etcdSource) Load() ([]byte, error) {
+	body, err := json.Marshal(map[string]string{
+		"key": base64.StdEncoding.EncodeToString([]byte(e.key)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := do(req, e.headers)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Kvs []struct {
+			Value string `json:"value"`
+		} `json:"kvs"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("unable to parse etcd response, %s", err)
+	}
+	if len(result.Kvs) == 0 {
+		return nil, fmt.Errorf("key %s not found", e.key)
+	}
+	return base64.StdEncoding.DecodeString(result.Kvs[0].Value)
+}
+
+type s3Source struct {
+	bucket string
+	key    string
+	region string
+}
+
+func (s *s3Source) Load() ([]byte, error) {
+	credentialConfig := &internalaws.CredentialConfig{Region: s.region}
+	svc := s3.New(credentialConfig.Credentials())
+
+	out, err := svc.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+	return ioutil.ReadAll(out.Body)
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSource(t *testing.T) {
+	s, err := NewSource("/etc/telegraf/telegraf.conf", nil)
+	require.NoError(t, err)
+	assert.Equal(t, fileSource("/etc/telegraf/telegraf.conf"), s)
+
+	s, err = NewSource("consul://localhost:8500/telegraf/agent", nil)
+	require.NoError(t, err)
+	assert.Equal(t, &consulSource{address: "localhost:8500", key: "telegraf/agent"}, s)
+
+	s, err = NewSource("etcd://localhost:2379/telegraf/agent", nil)
+	require.NoError(t, err)
+	assert.Equal(t, &etcdSource{
+		endpoint: "http://localhost:2379/v3/kv/range",
+		key:      "/telegraf/agent",
+	}, s)
+
+	s, err = NewSource("s3://bucket/telegraf/agent.conf?region=eu-west-1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, &s3Source{bucket: "bucket", key: "telegraf/agent.conf", region: "eu-west-1"}, s)
+
+	_, err = NewSource("s3://bucket", nil)
+	assert.Error(t, err)
+	_, err = NewSource("consul://localhost:8500", nil)
+	assert.Error(t, err)
+}
+
+func TestEtcdSource(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v3/kv/range", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		var req map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		key, err := base64.StdEncoding.DecodeString(req["key"])
+		require.NoError(t, err)
+
+		if string(key) != "/telegraf/agent" {
+			w.Write([]byte(`{"header": {}}`))
+			return
+		}
+		value := base64.StdEncoding.EncodeToString([]byte("[[inputs.cpu]]\n"))
+		w.Write([]byte(`{"kvs": [{"value": "` + value + `"}]}`))
+	}))
+	defer ts.Close()
+
+	headers := map[string]string{"Authorization": "Bearer secret"}
+	u := strings.Replace(ts.URL, "http://", "etcd://", 1)
+
+	s, err := NewSource(u+"/telegraf/agent", headers)
+	require.NoError(t, err)
+	contents, err := s.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "[[inputs.cpu]]\n", string(contents))
+
+	s, err = NewSource(u+"/telegraf/other", headers)
+	require.NoError(t, err)
+	_, err = s.Load()
+	assert.Error(t, err)
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// tagTemplateData is the data available to the templates in tag values,
+// ie host = "{{.ShortHostname}}".
+type tagTemplateData struct {
+	Hostname      string
+	ShortHostname string
+}
+
+var tagTemplateFuncs = template.FuncMap{
+	"env":   os.Getenv,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// expandTagTemplate evaluates the value of a tag if it is a template.
+func expandTagTemplate(value string, data *tagTemplateData) (string, error) {
+	if !strings.Contains(value, "{{") {
+		return value, nil
+	}
+	tmpl, err := template.New("tag").Funcs(tagTemplateFuncs).Parse(value)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func expandTagTemplates(tags map[string]string, data *tagTemplateData) error {
+	for k, v := range tags {
+		value, err := expandTagTemplate(v, data)
+		if err != nil {
+			return fmt.Errorf("invalid template in tag %s: %s", k, err)
+		}
+		tags[k] = value
+	}
+	return nil
+}
+
+// ExpandTagTemplates evaluates the templates in the values of the global
+// tags and of the tags of the inputs and aggregators, with the hostname of
+// the agent.
+func (c *Config) ExpandTagTemplates(hostname string) error {
+	data := &tagTemplateData{
+		Hostname:      hostname,
+		ShortHostname: strings.SplitN(hostname, ".", 2)[0],
+	}
+
+	if err := expandTagTemplates(c.Tags, data); err != nil {
+		return fmt.Errorf("Error in global_tags: %s", err)
+	}
+	for _, input := range c.Inputs {
+		if err := expandTagTemplates(input.Config.Tags, data); err != nil {
+			return fmt.Errorf("Error in %s: %s", input.Name(), err)
+		}
+	}
+	for _, aggregator := range c.Aggregators {
+		if err := expandTagTemplates(aggregator.Config.Tags, data); err != nil {
+			return fmt.Errorf("Error in %s: %s", aggregator.Name(), err)
+		}
+	}
+	return nil
+}
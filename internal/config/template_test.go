@@ -0,0 +1,53 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/influxdata/telegraf/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandTagTemplates(t *testing.T) {
+	os.Setenv("TEST_REGION", "EU-West")
+	defer os.Unsetenv("TEST_REGION")
+
+	c := NewConfig()
+	c.Tags["region"] = `{{env "TEST_REGION" | lower}}`
+	c.Tags["host"] = "{{.Hostname}}"
+	c.Tags["dc"] = "dc1"
+	c.Inputs = append(c.Inputs, models.NewRunningInput(nil, &models.InputConfig{
+		Name: "test",
+		Tags: map[string]string{"node": "{{upper .ShortHostname}}"},
+	}))
+
+	require.NoError(t, c.ExpandTagTemplates("web-1.example.com"))
+	assert.Equal(t, map[string]string{
+		"region": "eu-west",
+		"host":   "web-1.example.com",
+		"dc":     "dc1",
+	}, c.Tags)
+	assert.Equal(t, map[string]string{"node": "WEB-1"}, c.Inputs[0].Config.Tags)
+
+	c.Tags["bad"] = "{{.Unknown}}"
+	assert.Error(t, c.ExpandTagTemplates("web-1"))
+}
+
+func TestConfig_BracedEnvVars(t *testing.T) {
+	os.Setenv("TEST_REGION", "eu-west")
+	defer os.Unsetenv("TEST_REGION")
+
+	f, err := ioutil.TempFile("", "telegraf")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("[global_tags]\n  region = \"${TEST_REGION}\"\n")
+	require.NoError(t, err)
+	f.Close()
+
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig(f.Name()))
+	assert.Equal(t, "eu-west", c.Tags["region"])
+}
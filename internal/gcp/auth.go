@@ -0,0 +1,179 @@
+// Package gcp authenticates the requests to the Google Cloud APIs.
+package gcp
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metadataTokenURL is the token endpoint of the service account of the GCE
+// instances.
+const metadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+// serviceAccount is the key file of a service account.
+type serviceAccount struct {
+	ClientEmail  string `json:"client_email"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// TokenSource returns the OAuth2 access tokens of the requests, those of a
+// service account if its key is set, those of the GCE instance otherwise.
+type TokenSource struct {
+	client  *http.Client
+	scope   string
+	account *serviceAccount
+	key     *rsa.PrivateKey
+
+	mu          sync.Mutex
+	accessToken string
+	expiry      time.Time
+}
+
+// NewTokenSource returns the source of the tokens of the scope, requested
+// with the client. The tokens are those of the service account of the
+// credentials file if set.
+func NewTokenSource(client *http.Client, credentialsFile, scope string) (*TokenSource, error) {
+	ts := &TokenSource{client: client, scope: scope}
+	if credentialsFile == "" {
+		return ts, nil
+	}
+
+	b, err := ioutil.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, err
+	}
+	var account serviceAccount
+	if err := json.Unmarshal(b, &account); err != nil {
+		return nil, fmt.Errorf("invalid credentials file %s: %s", credentialsFile, err)
+	}
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("invalid credentials file %s: no private key", credentialsFile)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key in %s: %s", credentialsFile, err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid private key in %s: not a RSA key", credentialsFile)
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+	ts.account = &account
+	ts.key = rsaKey
+	return ts, nil
+}
+
+// Token returns a valid access token, requesting a new one when needed.
+func (ts *TokenSource) Token() (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.accessToken != "" && time.Now().Before(ts.expiry) {
+		return ts.accessToken, nil
+	}
+
+	var req *http.Request
+	var err error
+	if ts.account != nil {
+		var assertion string
+		assertion, err = ts.assertion(time.Now())
+		if err != nil {
+			return "", err
+		}
+		form := url.Values{}
+		form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+		form.Set("assertion", assertion)
+		req, err = http.NewRequest("POST", ts.account.TokenURI, strings.NewReader(form.Encode()))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	} else {
+		req, err = http.NewRequest("GET", metadataTokenURL, nil)
+		if err == nil {
+			req.Header.Set("Metadata-Flavor", "Google")
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := ts.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request failed with status code %d (%s)",
+			resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	var t struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return "", fmt.Errorf("unable to parse token response, %s", err)
+	}
+	if t.AccessToken == "" {
+		return "", fmt.Errorf("no access token in token response")
+	}
+
+	ts.accessToken = t.AccessToken
+	// Renew the token a minute before it expires.
+	ts.expiry = time.Now().Add(time.Duration(t.ExpiresIn)*time.Second - time.Minute)
+	return ts.accessToken, nil
+}
+
+// assertion returns the JWT signed by the service account requesting an
+// access token of the scope.
+func (ts *TokenSource) assertion(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+		"kid": ts.account.PrivateKeyID,
+	})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   ts.account.ClientEmail,
+		"scope": ts.scope,
+		"aud":   ts.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	encoding := base64.RawURLEncoding
+	unsigned := encoding.EncodeToString(header) + "." + encoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, ts.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + encoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,74 @@
+package gcp
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenSource(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		parts := strings.Split(r.FormValue("assertion"), ".")
+		require.Len(t, parts, 3)
+		claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+		require.NoError(t, err)
+		assert.Contains(t, string(claims), `"scope":"https://www.googleapis.com/auth/monitoring"`)
+		w.Write([]byte(`{"access_token":"secret","expires_in":3600}`))
+	}))
+	defer ts.Close()
+
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	creds, err := json.Marshal(map[string]string{
+		"client_email": "telegraf@my-project.iam.gserviceaccount.com",
+		"private_key": string(pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(key),
+		})),
+		"token_uri": ts.URL,
+	})
+	require.NoError(t, err)
+	f, err := ioutil.TempFile("", "gcp-creds")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.Write(creds)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	tokens, err := NewTokenSource(http.DefaultClient, f.Name(),
+		"https://www.googleapis.com/auth/monitoring")
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		token, err := tokens.Token()
+		require.NoError(t, err)
+		assert.Equal(t, "secret", token)
+	}
+	// the token is requested once until it expires.
+	assert.Equal(t, 1, requests)
+}
+
+func TestTokenSource_InvalidCredentials(t *testing.T) {
+	f, err := ioutil.TempFile("", "gcp-creds")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.Write([]byte(`{"private_key":"none"}`))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	_, err = NewTokenSource(http.DefaultClient, f.Name(), "scope")
+	require.Error(t, err)
+}
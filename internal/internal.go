@@ -6,10 +6,12 @@ import (
 	"crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"math"
 	"math/big"
 	"os"
 	"os/exec"
@@ -67,6 +69,86 @@ func (d *Duration) UnmarshalTOML(b []byte) error {
 	return nil
 }
 
+// ParseTimestamp converts the timestamp according to format, "unix",
+// "unix_ms", "unix_us" or "unix_ns" for the numbers of seconds, milliseconds,
+// microseconds or nanoseconds since the epoch, or the Go reference time layout
+// of the string timestamps, RFC3339 if empty. The strings without a timezone
+// are in the location, UTC if nil.
+func ParseTimestamp(v interface{}, format string, loc *time.Location) (time.Time, error) {
+	var unit time.Duration
+	switch format {
+	case "unix":
+		unit = time.Second
+	case "unix_ms":
+		unit = time.Millisecond
+	case "unix_us":
+		unit = time.Microsecond
+	case "unix_ns":
+		unit = time.Nanosecond
+	}
+
+	var i int64
+	switch v := v.(type) {
+	case time.Time:
+		return v, nil
+	case int:
+		i = int64(v)
+	case int32:
+		i = int64(v)
+	case int64:
+		i = v
+	case uint32:
+		i = int64(v)
+	case uint64:
+		i = int64(v)
+	case float32:
+		return ParseTimestamp(float64(v), format, loc)
+	case float64:
+		// The integers are converted exactly.
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return ParseTimestamp(int64(v), format, loc)
+		}
+		if unit == 0 {
+			return time.Time{}, fmt.Errorf("numeric timestamp %v requires a unix time format", v)
+		}
+		return time.Unix(0, int64(v*float64(unit))).UTC(), nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return ParseTimestamp(i, format, loc)
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unable to parse timestamp %q, %s", v, err)
+		}
+		return ParseTimestamp(f, format, loc)
+	case string:
+		if unit != 0 {
+			return ParseTimestamp(json.Number(v), format, loc)
+		}
+		layout := format
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		if loc == nil {
+			loc = time.UTC
+		}
+		t, err := time.ParseInLocation(layout, v, loc)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unable to parse timestamp %q, %s", v, err)
+		}
+		return t, nil
+	case nil:
+		return time.Time{}, fmt.Errorf("timestamp not found")
+	default:
+		return time.Time{}, fmt.Errorf("unsupported timestamp value %v", v)
+	}
+
+	if unit == 0 {
+		return time.Time{}, fmt.Errorf("numeric timestamp %d requires a unix time format", i)
+	}
+	return time.Unix(0, i*int64(unit)).UTC(), nil
+}
+
 // ReadLines reads contents from a file and splits them by new lines.
 // A convenience wrapper to ReadLinesOffsetN(filename, 0, -1).
 func ReadLines(filename string) ([]string, error) {
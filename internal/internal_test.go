@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"encoding/json"
 	"os/exec"
 	"testing"
 	"time"
@@ -154,3 +155,51 @@ func TestDuration(t *testing.T) {
 	d.UnmarshalTOML([]byte(`1.5`))
 	assert.Equal(t, time.Second, d.Duration)
 }
+
+func TestParseTimestamp(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no timezone database")
+	}
+	ts := time.Unix(1500000000, 0).UTC()
+	tests := []struct {
+		value  interface{}
+		format string
+		loc    *time.Location
+		time   time.Time
+	}{
+		{int64(1500000000), "unix", nil, ts},
+		{uint64(1500000000000), "unix_ms", nil, ts},
+		{int(1500000000000000), "unix_us", nil, ts},
+		{float64(1500000000000000000), "unix_ns", nil, ts},
+		{1500000000.5, "unix", nil, ts.Add(500 * time.Millisecond)},
+		{json.Number("1500000000000"), "unix_ms", nil, ts},
+		{"1500000000", "unix", nil, ts},
+		{"1500000000.5", "unix", nil, ts.Add(500 * time.Millisecond)},
+		{"2017-07-14T02:40:00Z", "", nil, ts},
+		{"2017-07-14 02:40:00", "2006-01-02 15:04:05", nil, ts},
+		{"2017-07-14 04:40:00", "2006-01-02 15:04:05", berlin, ts},
+		{ts, "unix", nil, ts},
+	}
+	for _, tt := range tests {
+		parsed, err := ParseTimestamp(tt.value, tt.format, tt.loc)
+		assert.NoError(t, err, "%v %q", tt.value, tt.format)
+		assert.True(t, tt.time.Equal(parsed), "%v %q: %s", tt.value, tt.format, parsed)
+	}
+
+	for _, v := range []interface{}{
+		nil,
+		true,
+		"now",
+		int64(1500000000),
+		1500000000.5,
+	} {
+		_, err := ParseTimestamp(v, "2006-01-02", nil)
+		assert.Error(t, err, "%v", v)
+	}
+	// the numbers require a unix format.
+	_, err = ParseTimestamp(int64(1500000000), "", nil)
+	assert.Error(t, err)
+	_, err = ParseTimestamp("soon", "unix", nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,257 @@
+// Package modbus implements the parts of the Modbus RTU protocol shared by
+// the plugins reading registers from Modbus devices.
+package modbus
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// Modbus function codes for reading data.
+const (
+	FuncReadCoils            = 0x01
+	FuncReadDiscreteInputs   = 0x02
+	FuncReadHoldingRegisters = 0x03
+	FuncReadInputRegisters   = 0x04
+)
+
+var exceptions = map[byte]string{
+	0x01: "illegal function",
+	0x02: "illegal data address",
+	0x03: "illegal data value",
+	0x04: "slave device failure",
+	0x05: "acknowledge",
+	0x06: "slave device busy",
+	0x08: "memory parity error",
+	0x0A: "gateway path unavailable",
+	0x0B: "gateway target device failed to respond",
+}
+
+// CRC16 computes the Modbus RTU checksum of b.
+func CRC16(b []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, v := range b {
+		crc ^= uint16(v)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = (crc >> 1) ^ 0xA001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
+
+// Request builds an RTU read request frame for the given slave.
+func Request(slave byte, function byte, address, quantity uint16) []byte {
+	frame := make([]byte, 6, 8)
+	frame[0] = slave
+	frame[1] = function
+	binary.BigEndian.PutUint16(frame[2:], address)
+	binary.BigEndian.PutUint16(frame[4:], quantity)
+	crc := CRC16(frame)
+	return append(frame, byte(crc), byte(crc>>8))
+}
+
+// ParseResponse validates an RTU response frame to a read request and
+// returns its data bytes.
+func ParseResponse(frame []byte, slave byte, function byte) ([]byte, error) {
+	n := len(frame)
+	if n < 5 {
+		return nil, fmt.Errorf("response too short")
+	}
+	crc := CRC16(frame[:n-2])
+	if frame[n-2] != byte(crc) || frame[n-1] != byte(crc>>8) {
+		return nil, fmt.Errorf("invalid checksum in response")
+	}
+	if frame[0] != slave {
+		return nil, fmt.Errorf("response from slave %d, expected %d", frame[0], slave)
+	}
+	if frame[1] == function|0x80 {
+		msg, ok := exceptions[frame[2]]
+		if !ok {
+			msg = fmt.Sprintf("exception code %d", frame[2])
+		}
+		return nil, fmt.Errorf("modbus exception: %s", msg)
+	}
+	if frame[1] != function {
+		return nil, fmt.Errorf("response to function %d, expected %d", frame[1], function)
+	}
+	if int(frame[2]) != n-5 {
+		return nil, fmt.Errorf("response length %d does not match %d", n-5, frame[2])
+	}
+	return frame[3 : n-2], nil
+}
+
+// ReadRTU sends a read request over rw and returns the data bytes of the
+// response.
+func ReadRTU(
+	rw io.ReadWriter,
+	slave byte,
+	function byte,
+	address, quantity uint16,
+) ([]byte, error) {
+	if _, err := rw.Write(Request(slave, function, address, quantity)); err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 3)
+	if _, err := io.ReadFull(rw, header); err != nil {
+		return nil, err
+	}
+
+	var frame []byte
+	if header[1] == function|0x80 {
+		// Exception responses are slave, function, code and checksum.
+		frame = make([]byte, 5)
+	} else {
+		frame = make([]byte, 3+int(header[2])+2)
+	}
+	copy(frame, header)
+	if _, err := io.ReadFull(rw, frame[3:]); err != nil {
+		return nil, err
+	}
+	return ParseResponse(frame, slave, function)
+}
+
+// Register describes a value read from a device.
+type Register struct {
+	Name      string  `toml:"name"`
+	Type      string  `toml:"type"`
+	Address   int     `toml:"address"`
+	DataType  string  `toml:"data_type"`
+	ByteOrder string  `toml:"byte_order"`
+	Scale     float64 `toml:"scale"`
+}
+
+// registerWords returns the number of 16 bit registers used by a data type.
+func registerWords(dataType string) (uint16, error) {
+	switch dataType {
+	case "INT16", "UINT16":
+		return 1, nil
+	case "INT32", "UINT32", "FLOAT32":
+		return 2, nil
+	case "INT64", "UINT64", "FLOAT64":
+		return 4, nil
+	}
+	return 0, fmt.Errorf("unsupported data type %q", dataType)
+}
+
+// Validate checks the register definition and normalizes its settings.
+func (r *Register) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("register at address %d has no name", r.Address)
+	}
+	if r.Address < 0 || r.Address > math.MaxUint16 {
+		return fmt.Errorf("register %q: invalid address %d", r.Name, r.Address)
+	}
+	switch r.Type {
+	case "coil", "discrete_input":
+		return nil
+	case "holding", "input":
+	default:
+		return fmt.Errorf("register %q: unsupported type %q", r.Name, r.Type)
+	}
+	r.DataType = strings.ToUpper(r.DataType)
+	if _, err := registerWords(r.DataType); err != nil {
+		return fmt.Errorf("register %q: %s", r.Name, err)
+	}
+	r.ByteOrder = strings.ToUpper(r.ByteOrder)
+	switch r.ByteOrder {
+	case "":
+		r.ByteOrder = "ABCD"
+	case "ABCD", "CDAB", "BADC", "DCBA":
+	default:
+		return fmt.Errorf("register %q: unsupported byte order %q", r.Name, r.ByteOrder)
+	}
+	return nil
+}
+
+// Function returns the function code used to read the register.
+func (r *Register) Function() byte {
+	switch r.Type {
+	case "coil":
+		return FuncReadCoils
+	case "discrete_input":
+		return FuncReadDiscreteInputs
+	case "input":
+		return FuncReadInputRegisters
+	}
+	return FuncReadHoldingRegisters
+}
+
+// Quantity returns the number of coils or registers to read.
+func (r *Register) Quantity() uint16 {
+	if r.Type == "coil" || r.Type == "discrete_input" {
+		return 1
+	}
+	words, _ := registerWords(r.DataType)
+	return words
+}
+
+// Decode converts the data of a read response to a field value.
+func (r *Register) Decode(data []byte) (interface{}, error) {
+	if r.Type == "coil" || r.Type == "discrete_input" {
+		if len(data) != 1 {
+			return nil, fmt.Errorf("unexpected response length %d", len(data))
+		}
+		return data[0]&1 == 1, nil
+	}
+	if len(data) != int(r.Quantity())*2 {
+		return nil, fmt.Errorf("unexpected response length %d", len(data))
+	}
+	return decode(data, r.DataType, r.ByteOrder, r.Scale), nil
+}
+
+// decode converts the raw register data to a field value. The registers are
+// reordered to big endian according to the byte order first.
+func decode(data []byte, dataType, byteOrder string, scale float64) interface{} {
+	b := make([]byte, len(data))
+	copy(b, data)
+	if byteOrder == "BADC" || byteOrder == "DCBA" {
+		for i := 0; i+1 < len(b); i += 2 {
+			b[i], b[i+1] = b[i+1], b[i]
+		}
+	}
+	if byteOrder == "CDAB" || byteOrder == "DCBA" {
+		for i, j := 0, len(b)-2; i < j; i, j = i+2, j-2 {
+			b[i], b[i+1], b[j], b[j+1] = b[j], b[j+1], b[i], b[i+1]
+		}
+	}
+
+	var value interface{}
+	switch dataType {
+	case "INT16":
+		value = int64(int16(binary.BigEndian.Uint16(b)))
+	case "UINT16":
+		value = int64(binary.BigEndian.Uint16(b))
+	case "INT32":
+		value = int64(int32(binary.BigEndian.Uint32(b)))
+	case "UINT32":
+		value = int64(binary.BigEndian.Uint32(b))
+	case "INT64", "UINT64":
+		value = int64(binary.BigEndian.Uint64(b))
+	case "FLOAT32":
+		value = float64(math.Float32frombits(binary.BigEndian.Uint32(b)))
+	case "FLOAT64":
+		value = math.Float64frombits(binary.BigEndian.Uint64(b))
+	}
+
+	if scale == 0 {
+		return value
+	}
+	switch v := value.(type) {
+	case int64:
+		if dataType == "UINT64" {
+			return float64(uint64(v)) * scale
+		}
+		return float64(v) * scale
+	case float64:
+		return v * scale
+	}
+	return value
+}
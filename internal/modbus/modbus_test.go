@@ -0,0 +1,82 @@
+package modbus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequest(t *testing.T) {
+	// Read holding registers 0 and 1 of slave 1.
+	assert.Equal(t,
+		[]byte{0x01, 0x03, 0x00, 0x00, 0x00, 0x02, 0xC4, 0x0B},
+		Request(1, FuncReadHoldingRegisters, 0, 2))
+}
+
+func TestParseResponse(t *testing.T) {
+	frame := []byte{0x01, 0x03, 0x04, 0x43, 0x66, 0x66, 0x66}
+	crc := CRC16(frame)
+	frame = append(frame, byte(crc), byte(crc>>8))
+
+	data, err := ParseResponse(frame, 1, FuncReadHoldingRegisters)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x43, 0x66, 0x66, 0x66}, data)
+
+	_, err = ParseResponse(frame, 2, FuncReadHoldingRegisters)
+	assert.Error(t, err)
+
+	_, err = ParseResponse(frame, 1, FuncReadInputRegisters)
+	assert.Error(t, err)
+
+	frame[3] = 0x44
+	_, err = ParseResponse(frame, 1, FuncReadHoldingRegisters)
+	assert.Error(t, err)
+
+	exception := []byte{0x01, 0x83, 0x02}
+	crc = CRC16(exception)
+	exception = append(exception, byte(crc), byte(crc>>8))
+	_, err = ParseResponse(exception, 1, FuncReadHoldingRegisters)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "illegal data address")
+}
+
+func TestDecodeByteOrder(t *testing.T) {
+	var tests = []struct {
+		data      []byte
+		byteOrder string
+	}{
+		{[]byte{0x01, 0x02, 0x03, 0x04}, "ABCD"},
+		{[]byte{0x03, 0x04, 0x01, 0x02}, "CDAB"},
+		{[]byte{0x02, 0x01, 0x04, 0x03}, "BADC"},
+		{[]byte{0x04, 0x03, 0x02, 0x01}, "DCBA"},
+	}
+	for _, tt := range tests {
+		r := &Register{Name: "a", Type: "holding", DataType: "UINT32", ByteOrder: tt.byteOrder}
+		require.NoError(t, r.Validate())
+		v, err := r.Decode(tt.data)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0x01020304), v, tt.byteOrder)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	r := &Register{Name: "a", Type: "input", DataType: "int16", Scale: 0.1}
+	require.NoError(t, r.Validate())
+	assert.Equal(t, uint16(1), r.Quantity())
+	assert.Equal(t, byte(FuncReadInputRegisters), r.Function())
+
+	v, err := r.Decode([]byte{0xFF, 0x9C})
+	require.NoError(t, err)
+	assert.InDelta(t, -10.0, v, 1e-9)
+
+	_, err = r.Decode([]byte{0xFF, 0x9C, 0x00, 0x00})
+	assert.Error(t, err)
+
+	c := &Register{Name: "b", Type: "discrete_input"}
+	require.NoError(t, c.Validate())
+	assert.Equal(t, byte(FuncReadDiscreteInputs), c.Function())
+	v, err = c.Decode([]byte{0x01})
+	require.NoError(t, err)
+	assert.Equal(t, true, v)
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/influxdata/telegraf/filter"
 )
@@ -33,6 +34,9 @@ type Filter struct {
 	TagInclude []string
 	tagInclude filter.Filter
 
+	MetricPass string
+	metricPass *filter.Expression
+
 	isActive bool
 }
 
@@ -45,7 +49,8 @@ func (f *Filter) Compile() error {
 		len(f.TagInclude) == 0 &&
 		len(f.TagExclude) == 0 &&
 		len(f.TagPass) == 0 &&
-		len(f.TagDrop) == 0 {
+		len(f.TagDrop) == 0 &&
+		f.MetricPass == "" {
 		return nil
 	}
 
@@ -78,6 +83,13 @@ func (f *Filter) Compile() error {
 		return fmt.Errorf("Error compiling 'taginclude', %s", err)
 	}
 
+	if f.MetricPass != "" {
+		f.metricPass, err = filter.NewExpression(f.MetricPass)
+		if err != nil {
+			return fmt.Errorf("Error compiling 'metricpass', %s", err)
+		}
+	}
+
 	for i, _ := range f.TagDrop {
 		f.TagDrop[i].filter, err = filter.Compile(f.TagDrop[i].Filter)
 		if err != nil {
@@ -101,6 +113,7 @@ func (f *Filter) Apply(
 	measurement string,
 	fields map[string]interface{},
 	tags map[string]string,
+	t ...time.Time,
 ) bool {
 	if !f.isActive {
 		return true
@@ -116,6 +129,17 @@ func (f *Filter) Apply(
 		return false
 	}
 
+	// check if the metric matches the expression
+	if f.metricPass != nil {
+		var tm time.Time
+		if len(t) > 0 {
+			tm = t[0]
+		}
+		if !f.metricPass.Eval(measurement, tags, fields, tm) {
+			return false
+		}
+	}
+
 	// filter fields
 	for fieldkey, _ := range fields {
 		if !f.shouldFieldPass(fieldkey) {
@@ -2,6 +2,7 @@ package models
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -34,6 +35,29 @@ func TestFilter_ApplyTagsDontPass(t *testing.T) {
 		map[string]string{"cpu": "cpu-total"}))
 }
 
+func TestFilter_ApplyMetricPass(t *testing.T) {
+	f := Filter{
+		MetricPass: `fields.temperature > 90 && tags.site_id == "123" && time > 0`,
+	}
+	require.NoError(t, f.Compile())
+	assert.True(t, f.IsActive())
+
+	now := time.Now()
+	assert.True(t, f.Apply("m",
+		map[string]interface{}{"temperature": float64(95)},
+		map[string]string{"site_id": "123"}, now))
+	assert.False(t, f.Apply("m",
+		map[string]interface{}{"temperature": float64(85)},
+		map[string]string{"site_id": "123"}, now))
+	// the time is missing.
+	assert.False(t, f.Apply("m",
+		map[string]interface{}{"temperature": float64(95)},
+		map[string]string{"site_id": "123"}))
+
+	f = Filter{MetricPass: "fields.temperature >"}
+	assert.Error(t, f.Compile())
+}
+
 func TestFilter_ApplyDeleteFields(t *testing.T) {
 	f := Filter{
 		FieldDrop: []string{"value"},
@@ -259,6 +283,27 @@ func TestFilter_TagPass(t *testing.T) {
 	}
 }
 
+func TestFilter_TagPassAndDrop(t *testing.T) {
+	f := Filter{
+		TagPass: []TagFilter{
+			TagFilter{
+				Name:   "cpu",
+				Filter: []string{"cpu-*"},
+			}},
+		TagDrop: []TagFilter{
+			TagFilter{
+				Name:   "cpu",
+				Filter: []string{"cpu-total"},
+			}},
+	}
+	require.NoError(t, f.Compile())
+
+	// the metrics matching tagpass pass, even if they match tagdrop.
+	assert.True(t, f.shouldTagsPass(map[string]string{"cpu": "cpu-0"}))
+	assert.True(t, f.shouldTagsPass(map[string]string{"cpu": "cpu-total"}))
+	assert.False(t, f.shouldTagsPass(map[string]string{"cpu": "cpu0"}))
+}
+
 func TestFilter_TagDrop(t *testing.T) {
 	filters := []TagFilter{
 		TagFilter{
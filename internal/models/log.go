@@ -0,0 +1,96 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/logger"
+	"github.com/influxdata/wlog"
+)
+
+// Logger logs the messages of a plugin, tagged with its category and name.
+type Logger struct {
+	Category string
+	Name     string
+
+	// level is the log level of the plugin, 0 for the level of the agent.
+	level wlog.Level
+}
+
+// NewLogger returns the logger of the plugin of the category, ie "inputs",
+// logging at level, or at the level of the agent if empty or invalid.
+func NewLogger(category, name, level string) *Logger {
+	l := &Logger{Category: category, Name: name}
+	if level != "" {
+		l.level, _ = logger.ParseLevel(level)
+	}
+	return l
+}
+
+// SetLoggerOnPlugin sets the logger in the Log field of the plugin, if it
+// has one of type telegraf.Logger.
+func SetLoggerOnPlugin(plugin interface{}, l telegraf.Logger) {
+	v := reflect.ValueOf(plugin)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return
+	}
+	field := v.Elem().FieldByName("Log")
+	if !field.IsValid() || !field.CanSet() {
+		return
+	}
+	if field.Type() == reflect.TypeOf((*telegraf.Logger)(nil)).Elem() {
+		field.Set(reflect.ValueOf(l))
+	}
+}
+
+func (l *Logger) output(level wlog.Level, msg string) {
+	min := l.level
+	if min == 0 {
+		min = wlog.LogLevel()
+	}
+	if level < min {
+		return
+	}
+	logger.Output(level, l.Category, l.Name, msg)
+}
+
+// Errorf logs an error message, patterned after log.Printf.
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.output(wlog.ERROR, fmt.Sprintf(format, args...))
+}
+
+// Error logs an error message, patterned after log.Print.
+func (l *Logger) Error(args ...interface{}) {
+	l.output(wlog.ERROR, fmt.Sprint(args...))
+}
+
+// Warnf logs a warning message, patterned after log.Printf.
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.output(wlog.WARN, fmt.Sprintf(format, args...))
+}
+
+// Warn logs a warning message, patterned after log.Print.
+func (l *Logger) Warn(args ...interface{}) {
+	l.output(wlog.WARN, fmt.Sprint(args...))
+}
+
+// Infof logs an information message, patterned after log.Printf.
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.output(wlog.INFO, fmt.Sprintf(format, args...))
+}
+
+// Info logs an information message, patterned after log.Print.
+func (l *Logger) Info(args ...interface{}) {
+	l.output(wlog.INFO, fmt.Sprint(args...))
+}
+
+// Debugf logs a debug message, patterned after log.Printf.
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.output(wlog.DEBUG, fmt.Sprintf(format, args...))
+}
+
+// Debug logs a debug message, patterned after log.Print.
+func (l *Logger) Debug(args ...interface{}) {
+	l.output(wlog.DEBUG, fmt.Sprint(args...))
+}
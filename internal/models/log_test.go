@@ -0,0 +1,51 @@
+package models
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/logger"
+	"github.com/influxdata/wlog"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type logInput struct {
+	testInput
+	Log telegraf.Logger `toml:"-"`
+}
+
+func TestSetLoggerOnPlugin(t *testing.T) {
+	input := &logInput{}
+	ri := NewRunningInput(input, &InputConfig{Name: "test"})
+	assert.Equal(t, ri.Log, input.Log)
+
+	// Plugins without a Log field are left alone
+	SetLoggerOnPlugin(&testInput{}, ri.Log)
+}
+
+func TestLoggerLevel(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+
+	logger.SetupLogging(false, false, tmpfile.Name(), "")
+	defer logger.SetupLogging(false, false, "", "")
+	wlog.SetLevel(wlog.INFO)
+
+	NewLogger("inputs", "cpu", "").Debug("dropped")
+	NewLogger("inputs", "cpu", "debug").Debugf("kept %d", 1)
+	NewLogger("outputs", "file", "error").Warn("dropped")
+	NewLogger("outputs", "file", "error").Errorf("kept %d", 2)
+
+	f, err := ioutil.ReadFile(tmpfile.Name())
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(f)), "\n")
+	require.Len(t, lines, 2)
+	assert.True(t, strings.HasSuffix(lines[0], "D! [inputs.cpu] kept 1"))
+	assert.True(t, strings.HasSuffix(lines[1], "E! [outputs.file] kept 2"))
+}
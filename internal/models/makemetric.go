@@ -72,7 +72,7 @@ func makemetric(
 	// instead, the filter is applied to metric incoming into the plugin.
 	//   ie, it gets applied in the RunningAggregator.Apply function.
 	if applyFilter {
-		if ok := filter.Apply(measurement, fields, tags); !ok {
+		if ok := filter.Apply(measurement, fields, tags, t); !ok {
 			return nil
 		}
 	}
@@ -21,6 +21,7 @@ func NewRunningAggregator(
 	a telegraf.Aggregator,
 	conf *AggregatorConfig,
 ) *RunningAggregator {
+	SetLoggerOnPlugin(a, NewLogger("aggregators", conf.Name, conf.LogLevel))
 	return &RunningAggregator{
 		a:       a,
 		Config:  conf,
@@ -42,12 +43,24 @@ type AggregatorConfig struct {
 
 	Period time.Duration
 	Delay  time.Duration
+
+	// LogLevel overrides the log level of the agent when set.
+	LogLevel string
+
+	// Source is the canonical representation of the configuration table,
+	// used to find unchanged aggregators when reloading the configuration.
+	Source string
 }
 
 func (r *RunningAggregator) Name() string {
 	return "aggregators." + r.Config.Name
 }
 
+// Aggregator returns the aggregator plugin.
+func (r *RunningAggregator) Aggregator() telegraf.Aggregator {
+	return r.a
+}
+
 func (r *RunningAggregator) MakeMetric(
 	measurement string,
 	fields map[string]interface{},
@@ -81,23 +94,53 @@ func (r *RunningAggregator) MakeMetric(
 // Before applying to the plugin, it will run any defined filters on the metric.
 // Apply returns true if the original metric should be dropped.
 func (r *RunningAggregator) Add(in telegraf.Metric) bool {
+	in, ok := r.filter(in)
+	if !ok {
+		return false
+	}
+
+	r.metrics <- in
+	return r.Config.DropOriginal
+}
+
+// Aggregate adds the metric to the aggregator right away, instead of during
+// the current period when it is running. It returns true if the original
+// metric should be dropped.
+func (r *RunningAggregator) Aggregate(in telegraf.Metric) bool {
+	in, ok := r.filter(in)
+	if !ok {
+		return false
+	}
+
+	r.add(in)
+	return r.Config.DropOriginal
+}
+
+// Push pushes the aggregates to the accumulator and resets the aggregator.
+func (r *RunningAggregator) Push(acc telegraf.Accumulator) {
+	r.push(acc)
+	r.reset()
+}
+
+// filter returns the metric to apply and false if the aggregator should not
+// apply it.
+func (r *RunningAggregator) filter(in telegraf.Metric) (telegraf.Metric, bool) {
 	if r.Config.Filter.IsActive() {
 		// check if the aggregator should apply this metric
 		name := in.Name()
 		fields := in.Fields()
 		tags := in.Tags()
 		t := in.Time()
-		if ok := r.Config.Filter.Apply(name, fields, tags); !ok {
+		if ok := r.Config.Filter.Apply(name, fields, tags, t); !ok {
 			// aggregator should not apply this metric
-			return false
+			return nil, false
 		}
 
 		in, _ = metric.New(name, tags, fields, t)
 	}
-
-	r.metrics <- in
-	return r.Config.DropOriginal
+	return in, true
 }
+
 func (r *RunningAggregator) add(in telegraf.Metric) {
 	r.a.Add(in)
 }
@@ -167,6 +167,44 @@ func TestAddDropOriginal(t *testing.T) {
 	assert.False(t, ra.Add(m2))
 }
 
+func TestAggregateAndPush(t *testing.T) {
+	a := &TestAggregator{}
+	ra := NewRunningAggregator(a, &AggregatorConfig{
+		Name: "TestRunningAggregator",
+		Filter: Filter{
+			NamePass: []string{"RI*"},
+		},
+		DropOriginal: true,
+	})
+	assert.NoError(t, ra.Config.Filter.Compile())
+
+	m := ra.MakeMetric(
+		"RITest",
+		map[string]interface{}{"value": int(101)},
+		map[string]string{},
+		telegraf.Untyped,
+		time.Now(),
+	)
+	assert.True(t, ra.Aggregate(m))
+
+	// this metric name doesn't match the filter, so it is not aggregated
+	m2 := ra.MakeMetric(
+		"foobar",
+		map[string]interface{}{"value": int(101)},
+		map[string]string{},
+		telegraf.Untyped,
+		time.Now(),
+	)
+	assert.False(t, ra.Aggregate(m2))
+	assert.Equal(t, int64(101), atomic.LoadInt64(&a.sum))
+
+	acc := testutil.Accumulator{}
+	ra.Push(&acc)
+	acc.AssertContainsFields(t, "TestMetric",
+		map[string]interface{}{"sum": int64(101)})
+	assert.Equal(t, int64(0), atomic.LoadInt64(&a.sum))
+}
+
 // make an untyped, counter, & gauge metric
 func TestMakeMetricA(t *testing.T) {
 	now := time.Now()
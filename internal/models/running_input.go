@@ -2,6 +2,9 @@ package models
 
 import (
 	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -13,25 +16,48 @@ var GlobalMetricsGathered = selfstat.Register("agent", "metrics_gathered", map[s
 type RunningInput struct {
 	Input  telegraf.Input
 	Config *InputConfig
+	// ID identifies the input among the configured plugins, it is
+	// the same across restarts as long as its configuration is unchanged.
+	ID string
 
 	trace       bool
 	defaultTags map[string]string
 
+	// Log logs the messages of the input.
+	Log telegraf.Logger
+
 	MetricsGathered selfstat.Stat
+	GatherPanics    selfstat.Stat
+
+	// panics counts the consecutive gathers that panicked.
+	panics int32
+
+	mu         sync.Mutex
+	lastGather time.Time
+	lastError  error
+	errorTime  time.Time
 }
 
 func NewRunningInput(
 	input telegraf.Input,
 	config *InputConfig,
 ) *RunningInput {
+	logger := NewLogger("inputs", config.Name, config.LogLevel)
+	SetLoggerOnPlugin(input, logger)
 	return &RunningInput{
 		Input:  input,
 		Config: config,
+		Log:    logger,
 		MetricsGathered: selfstat.Register(
 			"gather",
 			"metrics_gathered",
 			map[string]string{"input": config.Name},
 		),
+		GatherPanics: selfstat.Register(
+			"gather",
+			"panics",
+			map[string]string{"input": config.Name},
+		),
 	}
 }
 
@@ -44,6 +70,17 @@ type InputConfig struct {
 	Tags              map[string]string
 	Filter            Filter
 	Interval          time.Duration
+	CollectionJitter  time.Duration
+	// Precision overrides the precision of the agent when non-zero.
+	Precision time.Duration
+	// RoundInterval overrides the round_interval of the agent when set.
+	RoundInterval *bool
+	// LogLevel overrides the log level of the agent when set.
+	LogLevel string
+
+	// Source is the canonical representation of the configuration table,
+	// used to find unchanged inputs when reloading the configuration.
+	Source string
 }
 
 func (r *RunningInput) Name() string {
@@ -94,3 +131,50 @@ func (r *RunningInput) SetTrace(trace bool) {
 func (r *RunningInput) SetDefaultTags(tags map[string]string) {
 	r.defaultTags = tags
 }
+
+// SetGathered records the time of the last completed gather.
+func (r *RunningInput) SetGathered(t time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastGather = t
+}
+
+// SetError records the last error of the input.
+func (r *RunningInput) SetError(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastError = err
+	r.errorTime = time.Now()
+}
+
+// Status returns the time of the last completed gather and the last error
+// with the time it occurred.
+func (r *RunningInput) Status() (lastGather time.Time, lastError error, errorTime time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastGather, r.lastError, r.errorTime
+}
+
+// Gather gathers from the input, a panic of the input is recovered and
+// returned as an error.
+func (r *RunningInput) Gather(acc telegraf.Accumulator) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			trace := make([]byte, 2048)
+			trace = trace[:runtime.Stack(trace, false)]
+			r.Log.Errorf("FATAL: panicked: %s, Stack:\n%s", p, trace)
+			r.GatherPanics.Incr(1)
+			atomic.AddInt32(&r.panics, 1)
+			err = fmt.Errorf("panicked: %v", p)
+		}
+	}()
+
+	err = r.Input.Gather(acc)
+	atomic.StoreInt32(&r.panics, 0)
+	return err
+}
+
+// Panics returns the number of consecutive gathers that panicked.
+func (r *RunningInput) Panics() int {
+	return int(atomic.LoadInt32(&r.panics))
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -332,6 +333,34 @@ func TestMakeMetricNameSuffix(t *testing.T) {
 	)
 }
 
+func TestRunningInputGatherPanic(t *testing.T) {
+	input := &panicInput{panic: true}
+	ri := NewRunningInput(input, &InputConfig{Name: "TestRunningInput"})
+
+	acc := testutil.Accumulator{}
+	assert.Error(t, ri.Gather(&acc))
+	assert.Error(t, ri.Gather(&acc))
+	assert.Equal(t, 2, ri.Panics())
+
+	// a gather that does not panic resets the count.
+	input.panic = false
+	assert.NoError(t, ri.Gather(&acc))
+	assert.Equal(t, 0, ri.Panics())
+}
+
+type panicInput struct {
+	panic bool
+}
+
+func (p *panicInput) Description() string  { return "" }
+func (p *panicInput) SampleConfig() string { return "" }
+func (p *panicInput) Gather(acc telegraf.Accumulator) error {
+	if p.panic {
+		panic("gather failed")
+	}
+	return nil
+}
+
 type testInput struct{}
 
 func (t *testInput) Description() string                   { return "" }
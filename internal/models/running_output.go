@@ -1,7 +1,12 @@
 package models
 
 import (
-	"log"
+	"fmt"
+	"net"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -16,8 +21,20 @@ const (
 
 	// Default number of metrics kept. It should be a multiple of batch size.
 	DEFAULT_METRIC_BUFFER_LIMIT = 10000
+
+	// Default maximum size in bytes of the metrics kept on disk.
+	DEFAULT_BUFFER_MAX_SIZE = 100 * 1024 * 1024
 )
 
+// metricBuffer stores the metrics of failed writes, in memory or on disk.
+type metricBuffer interface {
+	IsEmpty() bool
+	Len() int
+	Add(metrics ...telegraf.Metric)
+	Batch(batchSize int) []telegraf.Metric
+	Dropped() int64
+}
+
 // RunningOutput contains the output configuration
 type RunningOutput struct {
 	Name              string
@@ -25,15 +42,50 @@ type RunningOutput struct {
 	Config            *OutputConfig
 	MetricBufferLimit int
 	MetricBatchSize   int
+	// ID identifies the output among the configured plugins, it is
+	// the same across restarts as long as its configuration is unchanged.
+	ID string
 
 	MetricsFiltered selfstat.Stat
 	MetricsWritten  selfstat.Stat
+	MetricsDropped  selfstat.Stat
 	BufferSize      selfstat.Stat
 	BufferLimit     selfstat.Stat
+	BufferFullness  selfstat.Stat
 	WriteTime       selfstat.Stat
+	WriteDuration   selfstat.Stat
+	WritePanics     selfstat.Stat
+
+	// dropped is the number of metrics dropped by the buffer counted in
+	// MetricsDropped.
+	dropped int64
+
+	// panics counts the consecutive writes that panicked.
+	panics int32
+	// failures counts the consecutive writes that failed.
+	failures int32
+
+	// Reject is called with the metrics rejected after DeadLetterRetries
+	// failed writes and the error of the last write.
+	Reject func(metrics []telegraf.Metric, err error)
+
+	// Log logs the messages of the output.
+	Log telegraf.Logger
+
+	// metricRate and bytesRate limit the rate of writes.
+	metricRate *rateLimiter
+	bytesRate  *rateLimiter
 
 	metrics     *buffer.Buffer
-	failMetrics *buffer.Buffer
+	failMetrics metricBuffer
+	// attempts counts the failed writes of the metrics.
+	attempts   map[telegraf.Metric]int
+	attemptsMu sync.Mutex
+
+	mu        sync.Mutex
+	lastWrite time.Time
+	lastError error
+	errorTime time.Time
 }
 
 func NewRunningOutput(
@@ -49,8 +101,11 @@ func NewRunningOutput(
 	if batchSize == 0 {
 		batchSize = DEFAULT_METRIC_BATCH_SIZE
 	}
+	logger := NewLogger("outputs", name, conf.LogLevel)
+	SetLoggerOnPlugin(output, logger)
 	ro := &RunningOutput{
 		Name:              name,
+		Log:               logger,
 		metrics:           buffer.NewBuffer(batchSize),
 		failMetrics:       buffer.NewBuffer(bufferLimit),
 		Output:            output,
@@ -67,11 +122,21 @@ func NewRunningOutput(
 			"metrics_filtered",
 			map[string]string{"output": name},
 		),
+		MetricsDropped: selfstat.Register(
+			"write",
+			"metrics_dropped",
+			map[string]string{"output": name},
+		),
 		BufferSize: selfstat.Register(
 			"write",
 			"buffer_size",
 			map[string]string{"output": name},
 		),
+		BufferFullness: selfstat.Register(
+			"write",
+			"buffer_fullness_percent",
+			map[string]string{"output": name},
+		),
 		BufferLimit: selfstat.Register(
 			"write",
 			"buffer_limit",
@@ -82,6 +147,23 @@ func NewRunningOutput(
 			"write_time_ns",
 			map[string]string{"output": name},
 		),
+		WriteDuration: selfstat.RegisterHistogram(
+			"write",
+			"write_time",
+			map[string]string{"output": name},
+			selfstat.DefaultDurationBounds,
+		),
+		WritePanics: selfstat.Register(
+			"write",
+			"panics",
+			map[string]string{"output": name},
+		),
+	}
+	if conf.MaxWriteRate > 0 {
+		ro.metricRate = &rateLimiter{rate: float64(conf.MaxWriteRate)}
+	}
+	if conf.MaxWriteBytesRate > 0 {
+		ro.bytesRate = &rateLimiter{rate: float64(conf.MaxWriteBytesRate)}
 	}
 	ro.BufferLimit.Incr(int64(ro.MetricBufferLimit))
 	return ro
@@ -101,7 +183,7 @@ func (ro *RunningOutput) AddMetric(m telegraf.Metric) {
 		tags := m.Tags()
 		fields := m.Fields()
 		t := m.Time()
-		if ok := ro.Config.Filter.Apply(name, fields, tags); !ok {
+		if ok := ro.Config.Filter.Apply(name, fields, tags, t); !ok {
 			ro.MetricsFiltered.Incr(1)
 			return
 		}
@@ -112,9 +194,14 @@ func (ro *RunningOutput) AddMetric(m telegraf.Metric) {
 	ro.metrics.Add(m)
 	if ro.metrics.Len() == ro.MetricBatchSize {
 		batch := ro.metrics.Batch(ro.MetricBatchSize)
-		err := ro.write(batch)
-		if err != nil {
+		// The batch is written on the next flush when the rate of writes
+		// is exceeded, instead of blocking the metrics.
+		if ro.throttled() {
 			ro.failMetrics.Add(batch...)
+			return
+		}
+		if keep, err := ro.writeOrReject(batch); err != nil {
+			ro.failMetrics.Add(keep...)
 		}
 	}
 }
@@ -123,8 +210,17 @@ func (ro *RunningOutput) AddMetric(m telegraf.Metric) {
 func (ro *RunningOutput) Write() error {
 	nFails, nMetrics := ro.failMetrics.Len(), ro.metrics.Len()
 	ro.BufferSize.Set(int64(nFails + nMetrics))
-	log.Printf("D! Output [%s] buffer fullness: %d / %d metrics. ",
-		ro.Name, nFails+nMetrics, ro.MetricBufferLimit)
+	ro.BufferFullness.Set(int64(100 * (nFails + nMetrics) / ro.MetricBufferLimit))
+	ro.countDropped()
+	ro.Log.Debugf("buffer fullness: %d / %d metrics. ",
+		nFails+nMetrics, ro.MetricBufferLimit)
+	// held are the metrics buffered for the write, the failed writes of the
+	// others are forgotten.
+	var held map[telegraf.Metric]bool
+	if ro.Reject != nil && ro.Config.DeadLetterRetries > 0 {
+		held = make(map[telegraf.Metric]bool, nFails+nMetrics)
+		defer ro.forgetAttempts(held)
+	}
 	var err error
 	if !ro.failMetrics.IsEmpty() {
 		// how many batches of failed writes we need to write.
@@ -138,11 +234,16 @@ func (ro *RunningOutput) Write() error {
 				batchSize = nFails % ro.MetricBatchSize
 			}
 			batch := ro.failMetrics.Batch(batchSize)
+			hold(held, batch)
 			// If we've already failed previous writes, don't bother trying to
 			// write to this output again. We are not exiting the loop just so
 			// that we can rotate the metrics to preserve order.
 			if err == nil {
-				err = ro.write(batch)
+				var keep []telegraf.Metric
+				keep, err = ro.writeOrReject(batch)
+				if err != nil {
+					batch = keep
+				}
 			}
 			if err != nil {
 				ro.failMetrics.Add(batch...)
@@ -151,10 +252,15 @@ func (ro *RunningOutput) Write() error {
 	}
 
 	batch := ro.metrics.Batch(ro.MetricBatchSize)
+	hold(held, batch)
 	// see comment above about not trying to write to an already failed output.
 	// if ro.failMetrics is empty then err will always be nil at this point.
 	if err == nil {
-		err = ro.write(batch)
+		var keep []telegraf.Metric
+		keep, err = ro.writeOrReject(batch)
+		if err != nil {
+			batch = keep
+		}
 	}
 
 	if err != nil {
@@ -164,19 +270,317 @@ func (ro *RunningOutput) Write() error {
 	return nil
 }
 
+// writeOrReject writes the metrics, if the write fails it returns the
+// metrics to retry. The metrics that failed DeadLetterRetries writes are
+// written one by one to find the ones rejected by the output, which are
+// passed to Reject. The metrics are kept instead when a write fails to reach
+// the output, the output not rejecting them.
+func (ro *RunningOutput) writeOrReject(metrics []telegraf.Metric) ([]telegraf.Metric, error) {
+	failed, err := ro.writeSplit(metrics)
+	if ro.Reject == nil || ro.Config.DeadLetterRetries <= 0 {
+		return failed, err
+	}
+
+	ro.attemptsMu.Lock()
+	if err == nil {
+		for _, m := range metrics {
+			delete(ro.attempts, m)
+		}
+		ro.attemptsMu.Unlock()
+		return nil, nil
+	}
+	kept := make(map[telegraf.Metric]bool, len(failed))
+	for _, m := range failed {
+		kept[m] = true
+	}
+	for _, m := range metrics {
+		if !kept[m] {
+			delete(ro.attempts, m)
+		}
+	}
+	metrics = failed
+
+	if ro.attempts == nil {
+		ro.attempts = make(map[telegraf.Metric]int)
+	}
+	var keep, expired []telegraf.Metric
+	for _, m := range metrics {
+		ro.attempts[m]++
+		if ro.attempts[m] < ro.Config.DeadLetterRetries {
+			keep = append(keep, m)
+			continue
+		}
+		expired = append(expired, m)
+	}
+	ro.attemptsMu.Unlock()
+
+	for i, m := range expired {
+		werr := ro.write([]telegraf.Metric{m})
+		if werr != nil && connectionError(werr) {
+			// The remaining metrics are retried on the next write, their
+			// attempts still expired.
+			return append(keep, expired[i:]...), werr
+		}
+		ro.attemptsMu.Lock()
+		delete(ro.attempts, m)
+		ro.attemptsMu.Unlock()
+		if werr != nil {
+			ro.Log.Errorf("rejected metric after %d attempts: %s",
+				ro.Config.DeadLetterRetries, werr)
+			ro.Reject([]telegraf.Metric{m}, werr)
+		}
+	}
+	if len(keep) == 0 {
+		return nil, nil
+	}
+	return keep, err
+}
+
+// hold adds the metrics to held, if not nil.
+func hold(held map[telegraf.Metric]bool, metrics []telegraf.Metric) {
+	if held == nil {
+		return
+	}
+	for _, m := range metrics {
+		held[m] = true
+	}
+}
+
+// forgetAttempts removes the failed writes counted for the metrics not in
+// held, no longer buffered after being dropped when the buffer was full.
+func (ro *RunningOutput) forgetAttempts(held map[telegraf.Metric]bool) {
+	ro.attemptsMu.Lock()
+	defer ro.attemptsMu.Unlock()
+	for m := range ro.attempts {
+		if !held[m] {
+			delete(ro.attempts, m)
+		}
+	}
+}
+
+// connectionError returns true if the error is a failure to reach the
+// output rather than a rejection of the metrics by it. The outputs often
+// return the errors of the network as text, so the usual messages are
+// matched too.
+func connectionError(err error) bool {
+	if pe, ok := err.(*telegraf.PartialWriteError); ok {
+		err = pe.Err
+	}
+	if _, ok := err.(net.Error); ok {
+		return true
+	}
+	msg := err.Error()
+	for _, s := range []string{
+		"connection refused",
+		"connection reset",
+		"no such host",
+		"i/o timeout",
+		"broken pipe",
+		"network is unreachable",
+	} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeSplit writes the metrics in batches of at most MetricBatchBytes bytes
+// of line protocol, a metric larger than the limit being written alone. It
+// returns the metrics not written when a write failed, the metrics failed
+// by the output and the ones not written yet.
+func (ro *RunningOutput) writeSplit(metrics []telegraf.Metric) ([]telegraf.Metric, error) {
+	if ro.Config.MetricBatchBytes <= 0 {
+		if err := ro.write(metrics); err != nil {
+			return failed(metrics, err), err
+		}
+		return nil, nil
+	}
+
+	written := 0
+	for written < len(metrics) {
+		end, size := written, 0
+		for end < len(metrics) {
+			l := metrics[end].Len()
+			if end > written && size+l > ro.Config.MetricBatchBytes {
+				break
+			}
+			size += l
+			end++
+		}
+		if err := ro.write(metrics[written:end]); err != nil {
+			return append(failed(metrics[written:end], err), metrics[end:]...), err
+		}
+		written = end
+	}
+	return nil, nil
+}
+
+// failed returns the metrics of a failed write, only the ones failed by the
+// output if it wrote the others.
+func failed(metrics []telegraf.Metric, err error) []telegraf.Metric {
+	if pe, ok := err.(*telegraf.PartialWriteError); ok {
+		return pe.Failed
+	}
+	return metrics
+}
+
+// TakeMetrics removes and returns all metrics buffered for the output, the
+// metrics of failed writes first.
+func (ro *RunningOutput) TakeMetrics() []telegraf.Metric {
+	metrics := ro.failMetrics.Batch(ro.failMetrics.Len())
+	return append(metrics, ro.metrics.Batch(ro.metrics.Len())...)
+}
+
+// RestoreMetrics adds metrics taken from another output to the buffer, they
+// are written on the next flush.
+func (ro *RunningOutput) RestoreMetrics(metrics []telegraf.Metric) {
+	ro.failMetrics.Add(metrics...)
+}
+
+// OpenBuffer replaces the buffer of the metrics of failed writes by a buffer
+// on disk when BufferPath is set, the metrics already buffered are moved to
+// it.
+func (ro *RunningOutput) OpenBuffer() error {
+	if ro.Config.BufferPath == "" {
+		return nil
+	}
+	if _, ok := ro.failMetrics.(*buffer.DiskBuffer); ok {
+		return nil
+	}
+	db, err := buffer.NewDiskBuffer(ro.Config.BufferPath, ro.Config.BufferMaxSize)
+	if err != nil {
+		return err
+	}
+	if n := db.Len(); n > 0 {
+		ro.Log.Infof("loaded %d buffered metrics from %s",
+			n, ro.Config.BufferPath)
+	}
+	db.Add(ro.failMetrics.Batch(ro.failMetrics.Len())...)
+	ro.countDropped()
+	ro.failMetrics = db
+	atomic.StoreInt64(&ro.dropped, 0)
+	return nil
+}
+
+// CloseBuffer moves the metrics not written yet to the buffer on disk and
+// closes it, they are loaded again by the next OpenBuffer. The metrics of
+// writes failing afterwards are dropped.
+func (ro *RunningOutput) CloseBuffer() error {
+	db, ok := ro.failMetrics.(*buffer.DiskBuffer)
+	if !ok {
+		return nil
+	}
+	db.Add(ro.metrics.Batch(ro.metrics.Len())...)
+	return db.Close()
+}
+
+// countDropped adds the metrics dropped by the buffer since the last call to
+// the MetricsDropped stat.
+func (ro *RunningOutput) countDropped() {
+	dropped := ro.failMetrics.Dropped()
+	ro.MetricsDropped.Incr(dropped - atomic.SwapInt64(&ro.dropped, dropped))
+}
+
+// BufferLen returns the number of metrics waiting to be written.
+func (ro *RunningOutput) BufferLen() int {
+	return ro.failMetrics.Len() + ro.metrics.Len()
+}
+
+// Status returns the time of the last successful write and the last write
+// error with the time it occurred.
+func (ro *RunningOutput) Status() (lastWrite time.Time, lastError error, errorTime time.Time) {
+	ro.mu.Lock()
+	defer ro.mu.Unlock()
+	return ro.lastWrite, ro.lastError, ro.errorTime
+}
+
+// Failures returns the number of consecutive writes that failed.
+func (ro *RunningOutput) Failures() int {
+	return int(atomic.LoadInt32(&ro.failures))
+}
+
+// Panics returns the number of consecutive writes that panicked.
+func (ro *RunningOutput) Panics() int {
+	return int(atomic.LoadInt32(&ro.panics))
+}
+
+// writeRecover writes the metrics to the output, a panic of the output is
+// recovered and returned as an error.
+func (ro *RunningOutput) writeRecover(metrics []telegraf.Metric) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			trace := make([]byte, 2048)
+			trace = trace[:runtime.Stack(trace, false)]
+			ro.Log.Errorf("FATAL: panicked: %s, Stack:\n%s", p, trace)
+			ro.WritePanics.Incr(1)
+			atomic.AddInt32(&ro.panics, 1)
+			err = fmt.Errorf("panicked: %v", p)
+		}
+	}()
+
+	err = ro.Output.Write(metrics)
+	atomic.StoreInt32(&ro.panics, 0)
+	return err
+}
+
+// throttled returns true if a write would have to wait for the rate of
+// writes to drop below the limits.
+func (ro *RunningOutput) throttled() bool {
+	return (ro.metricRate != nil && !ro.metricRate.ready()) ||
+		(ro.bytesRate != nil && !ro.bytesRate.ready())
+}
+
+// waitRate waits until the metrics can be written within the limits of the
+// rate of writes.
+func (ro *RunningOutput) waitRate(metrics []telegraf.Metric) {
+	var wait time.Duration
+	if ro.metricRate != nil {
+		wait = ro.metricRate.reserve(len(metrics))
+	}
+	if ro.bytesRate != nil {
+		size := 0
+		for _, m := range metrics {
+			size += m.Len()
+		}
+		if w := ro.bytesRate.reserve(size); w > wait {
+			wait = w
+		}
+	}
+	if wait > 0 {
+		ro.Log.Debugf("waiting %s to stay within the rate of writes", wait)
+		time.Sleep(wait)
+	}
+}
+
 func (ro *RunningOutput) write(metrics []telegraf.Metric) error {
 	nMetrics := len(metrics)
 	if nMetrics == 0 {
 		return nil
 	}
+	ro.waitRate(metrics)
 	start := time.Now()
-	err := ro.Output.Write(metrics)
+	err := ro.writeRecover(metrics)
 	elapsed := time.Since(start)
+
+	ro.mu.Lock()
 	if err == nil {
-		log.Printf("D! Output [%s] wrote batch of %d metrics in %s\n",
-			ro.Name, nMetrics, elapsed)
+		ro.lastWrite = start
+		atomic.StoreInt32(&ro.failures, 0)
+	} else {
+		ro.lastError = err
+		ro.errorTime = start
+		atomic.AddInt32(&ro.failures, 1)
+	}
+	ro.mu.Unlock()
+
+	if err == nil {
+		ro.Log.Debugf("wrote batch of %d metrics in %s", nMetrics, elapsed)
 		ro.MetricsWritten.Incr(int64(nMetrics))
 		ro.WriteTime.Incr(elapsed.Nanoseconds())
+		ro.WriteDuration.Incr(elapsed.Nanoseconds())
+	} else if pe, ok := err.(*telegraf.PartialWriteError); ok {
+		ro.MetricsWritten.Incr(int64(nMetrics - len(pe.Failed)))
 	}
 	return err
 }
@@ -185,4 +589,75 @@ func (ro *RunningOutput) write(metrics []telegraf.Metric) error {
 type OutputConfig struct {
 	Name   string
 	Filter Filter
+
+	// Source is the canonical representation of the configuration table,
+	// used to find unchanged outputs when reloading the configuration.
+	Source string
+
+	// DeadLetter outputs only receive the metrics rejected by other outputs.
+	DeadLetter bool
+	// DeadLetterRetries is the number of failed writes after which a metric
+	// is rejected, 0 to retry forever.
+	DeadLetterRetries int
+
+	// FailoverGroup is the group of outputs written to one at a time, the
+	// next output of the group is written to when an output keeps failing.
+	FailoverGroup string
+
+	// BufferPath is the file the metrics of failed writes are buffered in,
+	// in memory if empty.
+	BufferPath string
+	// BufferMaxSize is the maximum size in bytes of the buffer on disk.
+	BufferMaxSize int64
+
+	// MaxWriteRate is the maximum number of metrics written per second,
+	// MaxWriteBytesRate the maximum number of bytes of line protocol, 0 for
+	// no limit.
+	MaxWriteRate      int
+	MaxWriteBytesRate int
+
+	// FlushInterval and FlushJitter override the ones of the agent when
+	// non-zero, the output is then flushed on its own.
+	FlushInterval time.Duration
+	FlushJitter   time.Duration
+	// MetricBatchSize overrides the metric_batch_size of the agent when
+	// non-zero.
+	MetricBatchSize int
+	// MetricBatchBytes is the maximum number of bytes of line protocol of
+	// the metrics of a write, the batches being split to stay within it, 0
+	// for no limit.
+	MetricBatchBytes int
+	// LogLevel overrides the log level of the agent when set.
+	LogLevel string
+}
+
+// rateLimiter spaces the writes so that on average at most rate units are
+// written per second.
+type rateLimiter struct {
+	rate float64
+
+	mu sync.Mutex
+	// next is the time from which the next write is allowed.
+	next time.Time
+}
+
+// reserve reserves the write of n units and returns how long to wait before
+// writing them.
+func (l *rateLimiter) reserve(n int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(float64(n) / l.rate * float64(time.Second)))
+	return wait
+}
+
+// ready returns true if a write is allowed without waiting.
+func (l *rateLimiter) ready() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return !l.next.After(time.Now())
 }
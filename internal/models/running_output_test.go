@@ -2,10 +2,16 @@ package models
 
 import (
 	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/testutil"
 
 	"github.com/stretchr/testify/assert"
@@ -142,6 +148,44 @@ func TestRunningOutput_PassFilter(t *testing.T) {
 	assert.Len(t, m.Metrics(), 10)
 }
 
+// Test that metrics are routed to the output by their tags.
+func TestRunningOutput_TagPassDropFilter(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{
+			NamePass: []string{"solaredge*"},
+			TagPass: []TagFilter{
+				{Name: "site_id", Filter: []string{"123", "456"}},
+			},
+		},
+	}
+	assert.NoError(t, conf.Filter.Compile())
+
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, conf, 1000, 10000)
+
+	metrics := []struct {
+		name string
+		tags map[string]string
+	}{
+		{"solaredge_site", map[string]string{"site_id": "123"}},
+		{"solaredge_site", map[string]string{"site_id": "789"}},
+		{"solaredge_inverter", map[string]string{"site_id": "456", "inverter": "main"}},
+		{"cpu", map[string]string{"site_id": "123"}},
+	}
+	for _, tm := range metrics {
+		m, err := metric.New(tm.name, tm.tags,
+			map[string]interface{}{"value": 1}, time.Now())
+		require.NoError(t, err)
+		ro.AddMetric(m)
+	}
+
+	err := ro.Write()
+	assert.NoError(t, err)
+	require.Len(t, m.Metrics(), 2)
+	assert.Equal(t, "solaredge_site", m.Metrics()[0].Name())
+	assert.Equal(t, "solaredge_inverter", m.Metrics()[1].Name())
+}
+
 // Test that tags are properly included
 func TestRunningOutput_TagIncludeNoMatch(t *testing.T) {
 	conf := &OutputConfig{
@@ -330,6 +374,181 @@ func TestRunningOutputWriteFail(t *testing.T) {
 	assert.Len(t, m.Metrics(), 10)
 }
 
+func TestRunningOutputStats(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("stats", m, conf, 4, 8)
+
+	// The third batch drops the first one from the buffer.
+	for _, metric := range append(first5, next5...) {
+		ro.AddMetric(metric)
+	}
+	ro.AddMetric(first5[0])
+	ro.AddMetric(first5[1])
+
+	require.Error(t, ro.Write())
+	assert.Equal(t, int64(4), ro.MetricsDropped.Get())
+	assert.Equal(t, int64(100), ro.BufferFullness.Get())
+	assert.Equal(t, int64(0), ro.WriteDuration.Get())
+
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	assert.Equal(t, int64(2), ro.WriteDuration.Get())
+	require.NoError(t, ro.Write())
+	assert.Equal(t, int64(0), ro.BufferFullness.Get())
+	assert.Equal(t, int64(4), ro.MetricsDropped.Get())
+}
+
+// Verify that the buffered metrics can be moved to another output, in order.
+func TestRunningOutputTakeRestoreMetrics(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 100, 1000)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	for _, metric := range next5 {
+		ro.AddMetric(metric)
+	}
+
+	metrics := ro.TakeMetrics()
+	assert.Equal(t, append(first5, next5...), metrics)
+	assert.Len(t, ro.TakeMetrics(), 0)
+
+	m2 := &mockOutput{}
+	ro2 := NewRunningOutput("test", m2, conf, 4, 1000)
+	ro2.RestoreMetrics(metrics)
+	require.NoError(t, ro2.Write())
+	assert.Equal(t, append(first5, next5...), m2.Metrics())
+}
+
+// Verify that the metrics not written are kept on disk across restarts.
+func TestRunningOutputDiskBuffer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-buffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	conf := &OutputConfig{
+		Filter:     Filter{},
+		BufferPath: filepath.Join(dir, "test.buffer"),
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 100, 1000)
+	require.NoError(t, ro.OpenBuffer())
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	for _, metric := range next5 {
+		ro.AddMetric(metric)
+	}
+	require.NoError(t, ro.CloseBuffer())
+	assert.Equal(t, 0, ro.BufferLen())
+
+	m2 := &mockOutput{}
+	ro2 := NewRunningOutput("test", m2, conf, 4, 1000)
+	require.NoError(t, ro2.OpenBuffer())
+	defer ro2.CloseBuffer()
+	assert.Equal(t, 10, ro2.BufferLen())
+	require.NoError(t, ro2.Write())
+
+	var names []string
+	for _, metric := range m2.Metrics() {
+		names = append(names, metric.Name())
+	}
+	assert.Equal(t, []string{"metric1", "metric2", "metric3", "metric4",
+		"metric5", "metric6", "metric7", "metric8", "metric9", "metric10"}, names)
+}
+
+// Verify that the writes are spaced to stay within the rate of writes, and
+// that the metrics are buffered meanwhile.
+func TestRunningOutputMaxWriteRate(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:       Filter{},
+		MaxWriteRate: 20,
+	}
+
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, conf, 5, 1000)
+
+	start := time.Now()
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	assert.Len(t, m.Metrics(), 5)
+
+	// the batch is full but the output was just written to.
+	for _, metric := range next5 {
+		ro.AddMetric(metric)
+	}
+	assert.Len(t, m.Metrics(), 5)
+	assert.Equal(t, 5, ro.BufferLen())
+
+	require.NoError(t, ro.Write())
+	assert.Len(t, m.Metrics(), 10)
+	assert.True(t, time.Since(start) >= 200*time.Millisecond)
+}
+
+// Verify that the batches are split to stay within the maximum size of the
+// writes, and that only the metrics not written are retried.
+func TestRunningOutputMetricBatchBytes(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:           Filter{},
+		MetricBatchBytes: 2 * first5[0].Len(),
+	}
+
+	m := &batchOutput{}
+	ro := NewRunningOutput("test", m, conf, 1000, 10000)
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.NoError(t, ro.Write())
+	assert.Equal(t, []int{2, 2, 1}, m.batches)
+	assert.Len(t, m.Metrics(), 5)
+
+	r := &rejectOutput{reject: "metric3"}
+	ro = NewRunningOutput("test", r, conf, 1000, 10000)
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	assert.Len(t, r.Metrics(), 2)
+	assert.Equal(t, 3, ro.BufferLen())
+}
+
+// batchOutput records the number of metrics of each write.
+type batchOutput struct {
+	mockOutput
+	batches []int
+}
+
+func (m *batchOutput) Write(metrics []telegraf.Metric) error {
+	m.batches = append(m.batches, len(metrics))
+	return m.mockOutput.Write(metrics)
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := &rateLimiter{rate: 100}
+	assert.True(t, l.ready())
+	assert.Zero(t, l.reserve(50))
+	assert.False(t, l.ready())
+	wait := l.reserve(10)
+	assert.True(t, wait > 400*time.Millisecond && wait <= 500*time.Millisecond)
+}
+
 // Verify that the order of points is preserved during a write failure.
 func TestRunningOutputWriteFailOrder(t *testing.T) {
 	conf := &OutputConfig{
@@ -479,6 +698,175 @@ func TestRunningOutputWriteFailOrder3(t *testing.T) {
 	assert.Equal(t, expected, m.Metrics())
 }
 
+func TestRunningOutputWritePanic(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+	}
+
+	m := &panicOutput{}
+	ro := NewRunningOutput("test", m, conf, 1000, 10000)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	err := ro.Write()
+	require.Error(t, err)
+	assert.Equal(t, 1, ro.Panics())
+	// the metrics are kept to be written again.
+	assert.Equal(t, 5, ro.BufferLen())
+}
+
+type panicOutput struct {
+	mockOutput
+}
+
+func (m *panicOutput) Write(metrics []telegraf.Metric) error {
+	panic("write failed")
+}
+
+func TestRunningOutputDeadLetter(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:            Filter{},
+		DeadLetterRetries: 2,
+	}
+
+	m := &rejectOutput{reject: "metric3"}
+	ro := NewRunningOutput("test", m, conf, 1000, 10000)
+	var rejected []telegraf.Metric
+	var reason error
+	ro.Reject = func(metrics []telegraf.Metric, err error) {
+		rejected = append(rejected, metrics...)
+		reason = err
+	}
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	assert.Len(t, m.Metrics(), 0)
+	assert.Len(t, rejected, 0)
+
+	// the second failed write rejects the metric failing on its own.
+	require.NoError(t, ro.Write())
+	assert.Len(t, m.Metrics(), 4)
+	require.Len(t, rejected, 1)
+	assert.Equal(t, "metric3", rejected[0].Name())
+	assert.EqualError(t, reason, "field type conflict")
+	assert.Equal(t, 0, ro.BufferLen())
+}
+
+func TestRunningOutputDeadLetterUnreachable(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:            Filter{},
+		DeadLetterRetries: 1,
+	}
+
+	m := &unreachableOutput{}
+	ro := NewRunningOutput("test", m, conf, 1000, 10000)
+	var rejected []telegraf.Metric
+	ro.Reject = func(metrics []telegraf.Metric, err error) {
+		rejected = append(rejected, metrics...)
+	}
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	// the metrics are not rejected while the output cannot be reached.
+	require.Error(t, ro.Write())
+	assert.Len(t, rejected, 0)
+	assert.Equal(t, 5, ro.BufferLen())
+	// the write of the batch and of the first metric alone.
+	assert.Equal(t, 2, m.writes)
+}
+
+func TestRunningOutputDeadLetterDropped(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:            Filter{},
+		DeadLetterRetries: 100,
+	}
+
+	m := &mockOutput{failWrite: true}
+	ro := NewRunningOutput("test", m, conf, 5, 5)
+	ro.Reject = func(metrics []telegraf.Metric, err error) {}
+
+	for i := 0; i < 3; i++ {
+		for _, metric := range first5 {
+			ro.AddMetric(metric)
+		}
+		for _, metric := range next5 {
+			ro.AddMetric(metric)
+		}
+		require.Error(t, ro.Write())
+	}
+	// the attempts of the metrics dropped by the full buffer are forgotten.
+	assert.Equal(t, 5, ro.BufferLen())
+	assert.Len(t, ro.attempts, 5)
+}
+
+// unreachableOutput fails to write the metrics with an error of the network.
+type unreachableOutput struct {
+	mockOutput
+	writes int
+}
+
+func (m *unreachableOutput) Write(metrics []telegraf.Metric) error {
+	m.writes++
+	return &net.OpError{Op: "dial", Net: "tcp", Err: fmt.Errorf("connection refused")}
+}
+
+func TestRunningOutputPartialWrite(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+	}
+
+	m := &partialOutput{rejectOutput{reject: "metric3"}}
+	ro := NewRunningOutput("test", m, conf, 1000, 10000)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	// only the metric failed by the output is written again.
+	require.Error(t, ro.Write())
+	assert.Len(t, m.Metrics(), 4)
+	assert.Equal(t, 1, ro.BufferLen())
+}
+
+// partialOutput writes the metrics except the reject metric.
+type partialOutput struct {
+	rejectOutput
+}
+
+func (m *partialOutput) Write(metrics []telegraf.Metric) error {
+	var written, failed []telegraf.Metric
+	for _, metric := range metrics {
+		if metric.Name() == m.reject {
+			failed = append(failed, metric)
+			continue
+		}
+		written = append(written, metric)
+	}
+	m.mockOutput.Write(written)
+	if len(failed) > 0 {
+		return &telegraf.PartialWriteError{Failed: failed, Err: fmt.Errorf("failed")}
+	}
+	return nil
+}
+
+// rejectOutput fails to write the batches containing the reject metric.
+type rejectOutput struct {
+	mockOutput
+	reject string
+}
+
+func (m *rejectOutput) Write(metrics []telegraf.Metric) error {
+	for _, metric := range metrics {
+		if metric.Name() == m.reject {
+			return fmt.Errorf("field type conflict")
+		}
+	}
+	return m.mockOutput.Write(metrics)
+}
+
 type mockOutput struct {
 	sync.Mutex
 
@@ -8,6 +8,9 @@ type RunningProcessor struct {
 	Name      string
 	Processor telegraf.Processor
 	Config    *ProcessorConfig
+	// ID identifies the processor among the configured plugins, it is
+	// the same across restarts as long as its configuration is unchanged.
+	ID string
 }
 
 type RunningProcessors []*RunningProcessor
@@ -21,6 +24,13 @@ type ProcessorConfig struct {
 	Name   string
 	Order  int64
 	Filter Filter
+
+	// LogLevel overrides the log level of the agent when set.
+	LogLevel string
+
+	// Source is the canonical representation of the configuration table,
+	// used to find unchanged processors when reloading the configuration.
+	Source string
 }
 
 func (rp *RunningProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
@@ -29,7 +39,7 @@ func (rp *RunningProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
 	for _, metric := range in {
 		if rp.Config.Filter.IsActive() {
 			// check if the filter should be applied to this metric
-			if ok := rp.Config.Filter.Apply(metric.Name(), metric.Fields(), metric.Tags()); !ok {
+			if ok := rp.Config.Filter.Apply(metric.Name(), metric.Fields(), metric.Tags(), metric.Time()); !ok {
 				// this means filter should not be applied
 				ret = append(ret, metric)
 				continue
@@ -0,0 +1,69 @@
+// Package serial provides access to serial ports, as used by plugins talking
+// to RS-232 and RS-485 devices.
+package serial
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Config holds the settings of a serial port.
+type Config struct {
+	// Port is the device path, ie /dev/ttyUSB0.
+	Port string
+	// BaudRate defaults to 9600 if unset.
+	BaudRate int
+	// DataBits is 5, 6, 7 or 8, the default is 8.
+	DataBits int
+	// Parity is "N" (none), "E" (even) or "O" (odd), the default is none.
+	Parity string
+	// StopBits is 1 or 2, the default is 1.
+	StopBits int
+	// Timeout is the amount of time to wait for data when reading.
+	Timeout time.Duration
+}
+
+// Port is an open serial port. Reads return an error once the timeout
+// elapsed without any data being received.
+type Port interface {
+	io.ReadWriteCloser
+	// Flush discards any data received but not read yet.
+	Flush() error
+}
+
+// Open opens and configures the serial port described by c.
+func Open(c *Config) (Port, error) {
+	cfg := *c
+	if cfg.Port == "" {
+		return nil, fmt.Errorf("serial port must be configured")
+	}
+	if cfg.BaudRate == 0 {
+		cfg.BaudRate = 9600
+	}
+	if cfg.DataBits == 0 {
+		cfg.DataBits = 8
+	}
+	if cfg.StopBits == 0 {
+		cfg.StopBits = 1
+	}
+	cfg.Parity = strings.ToUpper(cfg.Parity)
+	if cfg.Parity == "" {
+		cfg.Parity = "N"
+	}
+
+	if cfg.DataBits < 5 || cfg.DataBits > 8 {
+		return nil, fmt.Errorf("unsupported number of data bits: %d", cfg.DataBits)
+	}
+	if cfg.StopBits != 1 && cfg.StopBits != 2 {
+		return nil, fmt.Errorf("unsupported number of stop bits: %d", cfg.StopBits)
+	}
+	switch cfg.Parity {
+	case "N", "E", "O":
+	default:
+		return nil, fmt.Errorf("unsupported parity: %q", c.Parity)
+	}
+
+	return open(&cfg)
+}
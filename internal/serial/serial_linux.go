@@ -0,0 +1,131 @@
+// +build linux
+
+package serial
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+var baudRates = map[int]uint32{
+	1200:   syscall.B1200,
+	2400:   syscall.B2400,
+	4800:   syscall.B4800,
+	9600:   syscall.B9600,
+	19200:  syscall.B19200,
+	38400:  syscall.B38400,
+	57600:  syscall.B57600,
+	115200: syscall.B115200,
+	230400: syscall.B230400,
+}
+
+var dataBits = map[int]uint32{
+	5: syscall.CS5,
+	6: syscall.CS6,
+	7: syscall.CS7,
+	8: syscall.CS8,
+}
+
+type port struct {
+	f *os.File
+}
+
+func open(c *Config) (Port, error) {
+	speed, ok := baudRates[c.BaudRate]
+	if !ok {
+		return nil, fmt.Errorf("unsupported baud rate: %d", c.BaudRate)
+	}
+
+	// Open non-blocking so we do not wait for carrier detect, the port is
+	// switched back to blocking mode once configured.
+	fd, err := syscall.Open(c.Port,
+		syscall.O_RDWR|syscall.O_NOCTTY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open %s, %s", c.Port, err)
+	}
+
+	t := syscall.Termios{
+		Cflag: speed | dataBits[c.DataBits] | syscall.CREAD | syscall.CLOCAL,
+	}
+	if c.StopBits == 2 {
+		t.Cflag |= syscall.CSTOPB
+	}
+	switch c.Parity {
+	case "E":
+		t.Cflag |= syscall.PARENB
+	case "O":
+		t.Cflag |= syscall.PARENB | syscall.PARODD
+	}
+	if c.Parity != "N" {
+		t.Iflag |= syscall.INPCK
+	}
+
+	// Reads return as soon as data is available or after the timeout, which
+	// is counted in tenths of a second and limited to 25.5s by the kernel.
+	vtime := c.Timeout / (100 * time.Millisecond)
+	if vtime < 1 {
+		vtime = 1
+	} else if vtime > 255 {
+		vtime = 255
+	}
+	t.Cc[syscall.VMIN] = 0
+	t.Cc[syscall.VTIME] = uint8(vtime)
+
+	if err := ioctl(fd, syscall.TCSETS, uintptr(unsafe.Pointer(&t))); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("unable to configure %s, %s", c.Port, err)
+	}
+	if err := syscall.SetNonblock(fd, false); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("unable to configure %s, %s", c.Port, err)
+	}
+
+	return &port{f: os.NewFile(uintptr(fd), c.Port)}, nil
+}
+
+func (p *port) Read(b []byte) (int, error) {
+	n, err := p.f.Read(b)
+	if err == io.EOF {
+		// A read returning no data means the timeout elapsed.
+		return n, fmt.Errorf("timeout reading from %s", p.f.Name())
+	}
+	return n, err
+}
+
+func (p *port) Write(b []byte) (int, error) {
+	return p.f.Write(b)
+}
+
+func (p *port) Close() error {
+	return p.f.Close()
+}
+
+func (p *port) Flush() error {
+	// Drain the input queue in non-blocking mode, the TCFLSH ioctl number is
+	// not the same on all architectures.
+	fd := int(p.f.Fd())
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		return err
+	}
+	defer syscall.SetNonblock(fd, false)
+
+	buf := make([]byte, 256)
+	for {
+		n, err := syscall.Read(fd, buf)
+		if n <= 0 || err != nil {
+			return nil
+		}
+	}
+}
+
+func ioctl(fd int, req uint, arg uintptr) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), uintptr(req), arg)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+// +build !linux
+
+package serial
+
+import (
+	"fmt"
+)
+
+func open(c *Config) (Port, error) {
+	return nil, fmt.Errorf("serial ports are only supported on linux")
+}
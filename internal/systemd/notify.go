@@ -0,0 +1,62 @@
+// Package systemd implements the sd_notify protocol, telling systemd when
+// telegraf is ready or stopping and sending the watchdog keepalives.
+package systemd
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// Ready tells systemd that the startup is finished.
+	Ready = "READY=1"
+	// Reloading tells systemd that the configuration is being reloaded,
+	// until Ready is sent again.
+	Reloading = "RELOADING=1"
+	// Stopping tells systemd that the shutdown began.
+	Stopping = "STOPPING=1"
+	// Watchdog is the keepalive resetting the watchdog timer.
+	Watchdog = "WATCHDOG=1"
+)
+
+// Notify sends the state to the socket in $NOTIFY_SOCKET. It returns false
+// without an error if telegraf is not run by systemd with notifications.
+func Notify(state string) (bool, error) {
+	name := os.Getenv("NOTIFY_SOCKET")
+	if name == "" {
+		return false, nil
+	}
+	// Abstract socket
+	if name[0] == '@' {
+		name = "\x00" + name[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: name, Net: "unixgram"})
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// WatchdogInterval returns the interval at which the keepalives have to be
+// sent, half of the watchdog timeout set by systemd, or 0 if the watchdog
+// is not enabled for this process.
+func WatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" {
+		if p, err := strconv.Atoi(pid); err != nil || p != os.Getpid() {
+			return 0
+		}
+	}
+	return time.Duration(usec) * time.Microsecond / 2
+}
@@ -0,0 +1,29 @@
+package telegraf
+
+// Logger logs the messages of a plugin. The agent sets it in the field named
+// Log of type Logger of a plugin, if any, before starting the plugin:
+//
+//	type MyInput struct {
+//		Log telegraf.Logger `toml:"-"`
+//	}
+//
+// The messages are tagged with the plugin and logged at the log_level of the
+// plugin, or the level of the agent if not set.
+type Logger interface {
+	// Errorf logs an error message, patterned after log.Printf.
+	Errorf(format string, args ...interface{})
+	// Error logs an error message, patterned after log.Print.
+	Error(args ...interface{})
+	// Warnf logs a warning message, patterned after log.Printf.
+	Warnf(format string, args ...interface{})
+	// Warn logs a warning message, patterned after log.Print.
+	Warn(args ...interface{})
+	// Infof logs an information message, patterned after log.Printf.
+	Infof(format string, args ...interface{})
+	// Info logs an information message, patterned after log.Print.
+	Info(args ...interface{})
+	// Debugf logs a debug message, patterned after log.Printf.
+	Debugf(format string, args ...interface{})
+	// Debug logs a debug message, patterned after log.Print.
+	Debug(args ...interface{})
+}
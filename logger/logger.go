@@ -1,10 +1,15 @@
 package logger
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"os"
 	"regexp"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/influxdata/wlog"
@@ -12,6 +17,39 @@ import (
 
 var prefixRegex = regexp.MustCompile("^[DIWE]!")
 
+var (
+	levelNames = map[wlog.Level]string{
+		wlog.DEBUG: "debug",
+		wlog.INFO:  "info",
+		wlog.WARN:  "warn",
+		wlog.ERROR: "error",
+	}
+	prefixLevels = map[byte]wlog.Level{
+		'D': wlog.DEBUG,
+		'I': wlog.INFO,
+		'W': wlog.WARN,
+		'E': wlog.ERROR,
+	}
+)
+
+var (
+	// mu serializes the writes of the log package and of the plugins.
+	mu         sync.Mutex
+	output     io.Writer = os.Stderr
+	jsonFormat bool
+)
+
+// ParseLevel returns the level named s, one of "debug", "info", "warn" or
+// "error".
+func ParseLevel(s string) (wlog.Level, error) {
+	for level, name := range levelNames {
+		if strings.ToLower(s) == name {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid log level %q", s)
+}
+
 // newTelegrafWriter returns a logging-wrapped writer.
 func newTelegrafWriter(w io.Writer) io.Writer {
 	return &telegrafLog{
@@ -30,16 +68,79 @@ func (t *telegrafLog) Write(b []byte) (n int, err error) {
 	} else {
 		line = append([]byte(time.Now().UTC().Format(time.RFC3339)+" "), b...)
 	}
+	mu.Lock()
+	defer mu.Unlock()
 	return t.writer.Write(line)
 }
 
+// jsonLog writes the lines logged as JSON objects, with the time, level and
+// msg fields.
+type jsonLog struct {
+	writer io.Writer
+}
+
+func (j *jsonLog) Write(b []byte) (int, error) {
+	level, msg := wlog.INFO, b
+	if prefixRegex.Match(b) {
+		level, msg = prefixLevels[b[0]], bytes.TrimLeft(b[2:], " ")
+	}
+	if level < wlog.LogLevel() {
+		return len(b), nil
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if err := writeJSON(j.writer, level, "", "", string(msg)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+type entry struct {
+	Time     string `json:"time"`
+	Level    string `json:"level"`
+	Category string `json:"category,omitempty"`
+	Plugin   string `json:"plugin,omitempty"`
+	Msg      string `json:"msg"`
+}
+
+func writeJSON(w io.Writer, level wlog.Level, category, plugin, msg string) error {
+	line, err := json.Marshal(&entry{
+		Time:     time.Now().UTC().Format(time.RFC3339),
+		Level:    levelNames[level],
+		Category: category,
+		Plugin:   plugin,
+		Msg:      strings.TrimRight(msg, "\n"),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
+
+// Output writes the message of a plugin of the category, ie "inputs",
+// regardless of the log level: the plugins have their own log level.
+func Output(level wlog.Level, category, plugin, msg string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if jsonFormat {
+		writeJSON(output, level, category, plugin, msg)
+		return
+	}
+	fmt.Fprintf(output, "%s %s! [%s.%s] %s\n",
+		time.Now().UTC().Format(time.RFC3339),
+		strings.ToUpper(levelNames[level][:1]), category, plugin,
+		strings.TrimRight(msg, "\n"))
+}
+
 // SetupLogging configures the logging output.
 //   debug   will set the log level to DEBUG
 //   quiet   will set the log level to ERROR
 //   logfile will direct the logging output to a file. Empty string is
 //           interpreted as stderr. If there is an error opening the file the
 //           logger will fallback to stderr.
-func SetupLogging(debug, quiet bool, logfile string) {
+//   format  is "json" to write the lines as JSON objects, text otherwise.
+func SetupLogging(debug, quiet bool, logfile, format string) {
 	log.SetFlags(0)
 	if debug {
 		wlog.SetLevel(wlog.DEBUG)
@@ -65,5 +166,14 @@ func SetupLogging(debug, quiet bool, logfile string) {
 		oFile = os.Stderr
 	}
 
-	log.SetOutput(newTelegrafWriter(oFile))
+	mu.Lock()
+	output = oFile
+	jsonFormat = format == "json"
+	mu.Unlock()
+
+	if jsonFormat {
+		log.SetOutput(&jsonLog{writer: oFile})
+	} else {
+		log.SetOutput(newTelegrafWriter(oFile))
+	}
 }
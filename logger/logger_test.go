@@ -2,12 +2,15 @@ package logger
 
 import (
 	"bytes"
+	"encoding/json"
 	"io/ioutil"
 	"log"
 	"os"
 	"testing"
 
+	"github.com/influxdata/wlog"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestWriteLogToFile(t *testing.T) {
@@ -15,7 +18,7 @@ func TestWriteLogToFile(t *testing.T) {
 	assert.NoError(t, err)
 	defer func() { os.Remove(tmpfile.Name()) }()
 
-	SetupLogging(false, false, tmpfile.Name())
+	SetupLogging(false, false, tmpfile.Name(), "")
 	log.Printf("I! TEST")
 	log.Printf("D! TEST") // <- should be ignored
 
@@ -29,7 +32,7 @@ func TestDebugWriteLogToFile(t *testing.T) {
 	assert.NoError(t, err)
 	defer func() { os.Remove(tmpfile.Name()) }()
 
-	SetupLogging(true, false, tmpfile.Name())
+	SetupLogging(true, false, tmpfile.Name(), "")
 	log.Printf("D! TEST")
 
 	f, err := ioutil.ReadFile(tmpfile.Name())
@@ -42,7 +45,7 @@ func TestErrorWriteLogToFile(t *testing.T) {
 	assert.NoError(t, err)
 	defer func() { os.Remove(tmpfile.Name()) }()
 
-	SetupLogging(false, true, tmpfile.Name())
+	SetupLogging(false, true, tmpfile.Name(), "")
 	log.Printf("E! TEST")
 	log.Printf("I! TEST") // <- should be ignored
 
@@ -56,7 +59,7 @@ func TestAddDefaultLogLevel(t *testing.T) {
 	assert.NoError(t, err)
 	defer func() { os.Remove(tmpfile.Name()) }()
 
-	SetupLogging(true, false, tmpfile.Name())
+	SetupLogging(true, false, tmpfile.Name(), "")
 	log.Printf("TEST")
 
 	f, err := ioutil.ReadFile(tmpfile.Name())
@@ -64,6 +67,63 @@ func TestAddDefaultLogLevel(t *testing.T) {
 	assert.Equal(t, f[19:], []byte("Z I! TEST\n"))
 }
 
+func TestPluginWriteLogToFile(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "")
+	assert.NoError(t, err)
+	defer func() { os.Remove(tmpfile.Name()) }()
+
+	SetupLogging(false, false, tmpfile.Name(), "")
+	Output(wlog.DEBUG, "inputs", "cpu", "TEST")
+
+	f, err := ioutil.ReadFile(tmpfile.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, f[19:], []byte("Z D! [inputs.cpu] TEST\n"))
+}
+
+func TestJSONWriteLogToFile(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "")
+	assert.NoError(t, err)
+	defer func() { os.Remove(tmpfile.Name()) }()
+
+	wlog.SetLevel(wlog.INFO)
+	SetupLogging(false, false, tmpfile.Name(), "json")
+	defer SetupLogging(false, false, "", "")
+	log.Printf("W! TEST")
+	log.Printf("D! TEST") // <- should be ignored
+	Output(wlog.ERROR, "outputs", "influxdb", "TEST\n")
+
+	f, err := ioutil.ReadFile(tmpfile.Name())
+	assert.NoError(t, err)
+	lines := bytes.Split(bytes.TrimSpace(f), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	var e entry
+	require.NoError(t, json.Unmarshal(lines[0], &e))
+	assert.Equal(t, "warn", e.Level)
+	assert.Equal(t, "TEST", e.Msg)
+	assert.Empty(t, e.Plugin)
+	assert.NotEmpty(t, e.Time)
+
+	e = entry{}
+	require.NoError(t, json.Unmarshal(lines[1], &e))
+	assert.Equal(t, entry{
+		Time:     e.Time,
+		Level:    "error",
+		Category: "outputs",
+		Plugin:   "influxdb",
+		Msg:      "TEST",
+	}, e)
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("Debug")
+	assert.NoError(t, err)
+	assert.Equal(t, wlog.DEBUG, level)
+
+	_, err = ParseLevel("verbose")
+	assert.Error(t, err)
+}
+
 func BenchmarkTelegrafLogWrite(b *testing.B) {
 	var msg = []byte("test")
 	var buf bytes.Buffer
@@ -29,3 +29,14 @@ type ServiceOutput interface {
 	// Stop the "service" that will provide an Output
 	Stop()
 }
+
+// PartialWriteError is returned by the Write of an output that wrote only
+// some of the metrics, the Failed metrics are written again later.
+type PartialWriteError struct {
+	Failed []Metric
+	Err    error
+}
+
+func (e *PartialWriteError) Error() string {
+	return e.Err.Error()
+}
@@ -0,0 +1,277 @@
+package apsystems
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	ecuQuery      = "APS1100160001END\n"
+	inverterQuery = "APS1100280002%sEND\n"
+)
+
+type Apsystems struct {
+	Address string            `toml:"address"`
+	Timeout internal.Duration `toml:"timeout"`
+}
+
+// ecuData is the answer to the ECU query.
+type ecuData struct {
+	id              string
+	lifetimeEnergy  float64
+	currentPower    int64
+	todayEnergy     float64
+	inverters       int64
+	invertersOnline int64
+}
+
+// inverterData holds the realtime values of one microinverter.
+type inverterData struct {
+	id          string
+	online      bool
+	frequency   float64
+	temperature int64
+	power       []int64
+	voltage     []int64
+}
+
+var sampleConfig = `
+  ## Address of the ECU-R or ECU-C, the protocol listens on port 8899.
+  address = "192.168.1.20:8899"
+
+  ## Amount of time allowed to complete a query. The ECU is slow to
+  ## respond, especially while it reports to the cloud.
+  # timeout = "10s"
+
+  ## The ECU polls the inverters every 5 minutes.
+  interval = "5m"
+`
+
+func (a *Apsystems) SampleConfig() string {
+	return sampleConfig
+}
+
+func (a *Apsystems) Description() string {
+	return "Read power and temperature of APsystems microinverters from their ECU"
+}
+
+func (a *Apsystems) Gather(acc telegraf.Accumulator) error {
+	buf, err := a.query(ecuQuery)
+	if err != nil {
+		return fmt.Errorf("[address=%s]: %s", a.Address, err)
+	}
+	ecu, err := parseECU(buf)
+	if err != nil {
+		return fmt.Errorf("[address=%s]: %s", a.Address, err)
+	}
+
+	acc.AddFields("apsystems_ecu",
+		map[string]interface{}{
+			"current_power":    ecu.currentPower,
+			"today_energy":     ecu.todayEnergy,
+			"lifetime_energy":  ecu.lifetimeEnergy,
+			"inverters":        ecu.inverters,
+			"inverters_online": ecu.invertersOnline,
+		},
+		map[string]string{"ecu_id": ecu.id})
+
+	buf, err = a.query(fmt.Sprintf(inverterQuery, ecu.id))
+	if err != nil {
+		return fmt.Errorf("[address=%s]: %s", a.Address, err)
+	}
+	inverters, err := parseInverters(buf)
+	if err != nil {
+		return fmt.Errorf("[address=%s]: %s", a.Address, err)
+	}
+
+	for _, inv := range inverters {
+		tags := map[string]string{
+			"ecu_id":      ecu.id,
+			"inverter_id": inv.id,
+		}
+		fields := map[string]interface{}{
+			"online": inv.online,
+		}
+		if inv.online {
+			var total int64
+			for _, p := range inv.power {
+				total += p
+			}
+			fields["power"] = total
+			fields["frequency"] = inv.frequency
+			fields["temperature"] = inv.temperature
+			if len(inv.voltage) > 0 {
+				fields["voltage"] = inv.voltage[0]
+			}
+		}
+		acc.AddFields("apsystems_inverter", fields, tags)
+
+		if !inv.online {
+			continue
+		}
+		for i, p := range inv.power {
+			acc.AddFields("apsystems_panel",
+				map[string]interface{}{"power": p},
+				map[string]string{
+					"ecu_id":      ecu.id,
+					"inverter_id": inv.id,
+					"channel":     strconv.Itoa(i + 1),
+				})
+		}
+	}
+	return nil
+}
+
+// query sends a command to the ECU and returns its response. The ECU only
+// answers a single command per connection.
+func (a *Apsystems) query(cmd string) ([]byte, error) {
+	conn, err := net.DialTimeout("tcp", a.Address, a.Timeout.Duration)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(a.Timeout.Duration))
+
+	if _, err := conn.Write([]byte(cmd)); err != nil {
+		return nil, err
+	}
+
+	// Read until the length announced in the header was received, the
+	// binary payload may contain the "END" marker as well.
+	var resp []byte
+	buf := make([]byte, 4096)
+	for {
+		if len(resp) >= 9 {
+			length, err := strconv.Atoi(string(resp[5:9]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid response length %q", resp[5:9])
+			}
+			if len(resp) > length && bytes.HasSuffix(resp, []byte("END\n")) {
+				return resp, nil
+			}
+		}
+		n, err := conn.Read(buf)
+		resp = append(resp, buf[:n]...)
+		if err != nil {
+			return nil, fmt.Errorf("incomplete response, %s", err)
+		}
+	}
+}
+
+// checkFrame validates the header of a response, which starts with "APS",
+// a two digit version, the four digit length of the frame and the four digit
+// command, and ends with "END\n".
+func checkFrame(buf []byte, cmd string) error {
+	if len(buf) < 17 || string(buf[:3]) != "APS" {
+		return fmt.Errorf("invalid response")
+	}
+	length, err := strconv.Atoi(string(buf[5:9]))
+	if err != nil {
+		return fmt.Errorf("invalid response length %q", buf[5:9])
+	}
+	// The length does not include the trailing newline.
+	if length != len(buf)-1 {
+		return fmt.Errorf("response length %d does not match %d", len(buf)-1, length)
+	}
+	if string(buf[9:13]) != cmd {
+		return fmt.Errorf("response to command %q, expected %q", buf[9:13], cmd)
+	}
+	return nil
+}
+
+func parseECU(buf []byte) (*ecuData, error) {
+	if err := checkFrame(buf, "0001"); err != nil {
+		return nil, err
+	}
+	if len(buf) < 50 {
+		return nil, fmt.Errorf("ECU response too short")
+	}
+
+	return &ecuData{
+		id:              string(buf[13:25]),
+		lifetimeEnergy:  float64(binary.BigEndian.Uint32(buf[27:])) / 10,
+		currentPower:    int64(binary.BigEndian.Uint32(buf[31:])),
+		todayEnergy:     float64(binary.BigEndian.Uint32(buf[35:])) / 100,
+		inverters:       int64(binary.BigEndian.Uint16(buf[46:])),
+		invertersOnline: int64(binary.BigEndian.Uint16(buf[48:])),
+	}, nil
+}
+
+// channels returns the number of panels and of voltage values reported by
+// an inverter type.
+func channels(inverterType string) (int, int, error) {
+	switch inverterType {
+	case "01": // YC600, DS3
+		return 2, 2, nil
+	case "02": // YC1000
+		return 4, 3, nil
+	case "03": // QS1
+		return 4, 1, nil
+	}
+	return 0, 0, fmt.Errorf("unsupported inverter type %q", inverterType)
+}
+
+func parseInverters(buf []byte) ([]*inverterData, error) {
+	if err := checkFrame(buf, "0002"); err != nil {
+		return nil, err
+	}
+	if len(buf) < 26 {
+		return nil, fmt.Errorf("inverter response too short")
+	}
+
+	count := int(binary.BigEndian.Uint16(buf[17:]))
+	inverters := make([]*inverterData, 0, count)
+
+	pos := 26
+	for i := 0; i < count; i++ {
+		if pos+13 > len(buf) {
+			return nil, fmt.Errorf("inverter response too short")
+		}
+		inv := &inverterData{
+			id:          hex.EncodeToString(buf[pos : pos+6]),
+			online:      buf[pos+6] == 1,
+			frequency:   float64(binary.BigEndian.Uint16(buf[pos+9:])) / 10,
+			temperature: int64(binary.BigEndian.Uint16(buf[pos+11:])) - 100,
+		}
+		panels, voltages, err := channels(string(buf[pos+7 : pos+9]))
+		if err != nil {
+			return nil, fmt.Errorf("inverter %s: %s", inv.id, err)
+		}
+		pos += 13
+
+		// Values are stored as power, voltage pairs followed by the power of
+		// the remaining panels.
+		if pos+2*(panels+voltages) > len(buf) {
+			return nil, fmt.Errorf("inverter response too short")
+		}
+		for c := 0; c < panels; c++ {
+			if c < voltages {
+				inv.power = append(inv.power, int64(binary.BigEndian.Uint16(buf[pos:])))
+				inv.voltage = append(inv.voltage, int64(binary.BigEndian.Uint16(buf[pos+2:])))
+				pos += 4
+			} else {
+				inv.power = append(inv.power, int64(binary.BigEndian.Uint16(buf[pos:])))
+				pos += 2
+			}
+		}
+		inverters = append(inverters, inv)
+	}
+	return inverters, nil
+}
+
+func init() {
+	inputs.Add("apsystems", func() telegraf.Input {
+		return &Apsystems{
+			Timeout: internal.Duration{Duration: time.Second * 10},
+		}
+	})
+}
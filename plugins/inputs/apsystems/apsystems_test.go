@@ -0,0 +1,166 @@
+package apsystems
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ecuID = "216000012345"
+
+// frame wraps a payload in the header and trailer of an ECU response.
+func frame(cmd string, payload []byte) []byte {
+	length := 13 + len(payload) + 3
+	buf := []byte(fmt.Sprintf("APS11%04d%s", length, cmd))
+	buf = append(buf, payload...)
+	return append(buf, []byte("END\n")...)
+}
+
+func u16(v uint16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, v)
+	return b
+}
+
+func u32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
+
+func ecuResponse() []byte {
+	var p []byte
+	p = append(p, []byte(ecuID)...)   // 13
+	p = append(p, 0, 1)               // 25
+	p = append(p, u32(123456)...)     // 27 lifetime energy
+	p = append(p, u32(1530)...)       // 31 current power
+	p = append(p, u32(1234)...)       // 35 today energy
+	p = append(p, make([]byte, 7)...) // 39
+	p = append(p, u16(2)...)          // 46 inverters
+	p = append(p, u16(1)...)          // 48 inverters online
+	p = append(p, []byte("0010")...)  // 50
+	p = append(p, []byte("ECU_R_1.2.13")...)
+	return frame("0001", p)
+}
+
+func inverterResponse() []byte {
+	var p []byte
+	p = append(p, []byte("00")...)                          // 13 status
+	p = append(p, u16(0)...)                                // 15
+	p = append(p, u16(2)...)                                // 17 count
+	p = append(p, 0x20, 0x17, 0x06, 0x01, 0x10, 0x15, 0x00) // 19 time
+
+	// An online YC600 with two panels.
+	uid, _ := hex.DecodeString("408000012345")
+	p = append(p, uid...)
+	p = append(p, 1)
+	p = append(p, []byte("01")...)
+	p = append(p, u16(500)...) // frequency
+	p = append(p, u16(135)...) // temperature
+	p = append(p, u16(150)...) // panel 1 power
+	p = append(p, u16(231)...) // voltage
+	p = append(p, u16(145)...) // panel 2 power
+	p = append(p, u16(231)...) // voltage
+
+	// An offline QS1.
+	uid, _ = hex.DecodeString("802000054321")
+	p = append(p, uid...)
+	p = append(p, 0)
+	p = append(p, []byte("03")...)
+	p = append(p, make([]byte, 4+10)...)
+	return frame("0002", p)
+}
+
+func newServer(t *testing.T) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			cmd, _ := bufio.NewReader(conn).ReadString('\n')
+			switch cmd {
+			case ecuQuery:
+				conn.Write(ecuResponse())
+			case fmt.Sprintf(inverterQuery, ecuID):
+				// Send the response in parts like the ECU does.
+				resp := inverterResponse()
+				conn.Write(resp[:20])
+				time.Sleep(10 * time.Millisecond)
+				conn.Write(resp[20:])
+			}
+			conn.Close()
+		}
+	}()
+	return l
+}
+
+func TestGather(t *testing.T) {
+	l := newServer(t)
+	defer l.Close()
+
+	a := &Apsystems{
+		Address: l.Addr().String(),
+		Timeout: internal.Duration{Duration: time.Second},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, a.Gather(&acc))
+
+	acc.AssertContainsTaggedFields(t, "apsystems_ecu",
+		map[string]interface{}{
+			"current_power":    int64(1530),
+			"today_energy":     float64(12.34),
+			"lifetime_energy":  float64(12345.6),
+			"inverters":        int64(2),
+			"inverters_online": int64(1),
+		},
+		map[string]string{"ecu_id": ecuID})
+
+	acc.AssertContainsTaggedFields(t, "apsystems_inverter",
+		map[string]interface{}{
+			"online":      true,
+			"power":       int64(295),
+			"frequency":   float64(50),
+			"temperature": int64(35),
+			"voltage":     int64(231),
+		},
+		map[string]string{"ecu_id": ecuID, "inverter_id": "408000012345"})
+
+	acc.AssertContainsTaggedFields(t, "apsystems_inverter",
+		map[string]interface{}{"online": false},
+		map[string]string{"ecu_id": ecuID, "inverter_id": "802000054321"})
+
+	acc.AssertContainsTaggedFields(t, "apsystems_panel",
+		map[string]interface{}{"power": int64(145)},
+		map[string]string{"ecu_id": ecuID, "inverter_id": "408000012345", "channel": "2"})
+
+	// One ECU, two inverters and two panels of the online inverter.
+	assert.Len(t, acc.Metrics, 5)
+}
+
+func TestParseInvalid(t *testing.T) {
+	_, err := parseECU([]byte("garbage"))
+	assert.Error(t, err)
+
+	// The length in the header does not match.
+	buf := ecuResponse()
+	_, err = parseECU(buf[:len(buf)-5])
+	assert.Error(t, err)
+
+	// Wrong command.
+	_, err = parseInverters(ecuResponse())
+	assert.Error(t, err)
+}
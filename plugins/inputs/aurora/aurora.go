@@ -0,0 +1,254 @@
+package aurora
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/serial"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// Commands of the Aurora protocol.
+const (
+	cmdState        = 50
+	cmdMeasure      = 59
+	cmdSerialNumber = 63
+	cmdEnergy       = 78
+)
+
+// Transmission states returned when a variable is not available on the
+// inverter model, which is not an error worth reporting.
+const (
+	stateNotImplemented = 51
+	stateNoVariable     = 52
+)
+
+// Measurements read with the measure command, by DSP variable.
+var measures = []struct {
+	variable byte
+	field    string
+}{
+	{1, "grid_voltage"},
+	{2, "grid_current"},
+	{3, "grid_power"},
+	{4, "frequency"},
+	{23, "input1_voltage"},
+	{25, "input1_current"},
+	{26, "input2_voltage"},
+	{27, "input2_current"},
+	{8, "input1_power"},
+	{9, "input2_power"},
+	{21, "inverter_temperature"},
+	{22, "booster_temperature"},
+	{30, "isolation_resistance"},
+}
+
+// Cumulated energy counters in Wh, by period.
+var energies = []struct {
+	period byte
+	field  string
+}{
+	{0, "energy_daily"},
+	{1, "energy_weekly"},
+	{3, "energy_monthly"},
+	{4, "energy_yearly"},
+	{5, "energy_total"},
+	{6, "energy_partial"},
+}
+
+type Aurora struct {
+	Port      string            `toml:"port"`
+	BaudRate  int               `toml:"baud_rate"`
+	Timeout   internal.Duration `toml:"timeout"`
+	Addresses []int             `toml:"addresses"`
+
+	port serial.Port
+}
+
+var sampleConfig = `
+  ## Serial port the RS-485 adapter is connected to.
+  port = "/dev/ttyUSB0"
+  # baud_rate = 19200
+
+  ## Amount of time to wait for an inverter to respond.
+  # timeout = "1s"
+
+  ## RS-485 addresses of the inverters to poll, set in the display menu of
+  ## the inverter. The factory default is 2.
+  addresses = [2]
+`
+
+func (a *Aurora) SampleConfig() string {
+	return sampleConfig
+}
+
+func (a *Aurora) Description() string {
+	return "Read measurements from ABB, Power-One and Fimer inverters using the Aurora protocol"
+}
+
+func (a *Aurora) Gather(acc telegraf.Accumulator) error {
+	if a.port == nil {
+		if len(a.Addresses) == 0 {
+			return fmt.Errorf("at least one address must be configured")
+		}
+		port, err := serial.Open(&serial.Config{
+			Port:     a.Port,
+			BaudRate: a.BaudRate,
+			Timeout:  a.Timeout.Duration,
+		})
+		if err != nil {
+			return err
+		}
+		a.port = port
+	}
+
+	for _, address := range a.Addresses {
+		if err := a.gatherInverter(acc, byte(address)); err != nil {
+			acc.AddError(fmt.Errorf("[address=%d]: %s", address, err))
+		}
+	}
+	return nil
+}
+
+func (a *Aurora) gatherInverter(acc telegraf.Accumulator, address byte) error {
+	// Inverters shut down at night and stop responding, the first request
+	// failing stops the collection for this gather.
+	resp, err := a.request(address, cmdSerialNumber, nil)
+	if err != nil {
+		return err
+	}
+	tags := map[string]string{
+		"address":       strconv.Itoa(int(address)),
+		"serial_number": strings.TrimSpace(strings.Trim(string(resp[:6]), "\x00")),
+	}
+
+	fields := make(map[string]interface{})
+
+	resp, err = a.requestValue(address, cmdState, nil)
+	if err != nil {
+		return err
+	}
+	fields["global_state"] = int64(resp[1])
+	fields["inverter_state"] = int64(resp[2])
+	fields["dcdc1_state"] = int64(resp[3])
+	fields["dcdc2_state"] = int64(resp[4])
+	fields["alarm_state"] = int64(resp[5])
+
+	for _, m := range measures {
+		resp, err := a.requestValue(address, cmdMeasure, []byte{m.variable, 0})
+		if err == nil {
+			fields[m.field] = float64(math.Float32frombits(binary.BigEndian.Uint32(resp[2:6])))
+		} else if !isUnavailable(err) {
+			return err
+		}
+	}
+
+	for _, e := range energies {
+		resp, err := a.requestValue(address, cmdEnergy, []byte{e.period})
+		if err == nil {
+			fields[e.field] = int64(binary.BigEndian.Uint32(resp[2:6]))
+		} else if !isUnavailable(err) {
+			return err
+		}
+	}
+
+	acc.AddFields("aurora", fields, tags)
+	return nil
+}
+
+// transmissionError is returned when the inverter reports a non zero
+// transmission state.
+type transmissionError byte
+
+func (e transmissionError) Error() string {
+	return fmt.Sprintf("inverter returned transmission state %d", byte(e))
+}
+
+func isUnavailable(err error) bool {
+	e, ok := err.(transmissionError)
+	return ok && (e == stateNotImplemented || e == stateNoVariable)
+}
+
+// request sends a command with its parameters to the inverter and returns
+// the six data bytes of the response.
+func (a *Aurora) request(address, command byte, params []byte) ([]byte, error) {
+	// Discard the remains of any previous, timed out, response.
+	if err := a.port.Flush(); err != nil {
+		return nil, err
+	}
+	if _, err := a.port.Write(encodeRequest(address, command, params)); err != nil {
+		return nil, err
+	}
+
+	resp := make([]byte, 8)
+	if _, err := io.ReadFull(a.port, resp); err != nil {
+		return nil, err
+	}
+	return parseResponse(resp)
+}
+
+// encodeRequest builds a request frame, the address and command followed by
+// six parameter bytes and the checksum.
+func encodeRequest(address, command byte, params []byte) []byte {
+	frame := make([]byte, 10)
+	frame[0] = address
+	frame[1] = command
+	copy(frame[2:8], params)
+	binary.LittleEndian.PutUint16(frame[8:], crc16(frame[:8]))
+	return frame
+}
+
+// parseResponse checks the checksum of a response frame, six data bytes
+// followed by the checksum.
+func parseResponse(frame []byte) ([]byte, error) {
+	if binary.LittleEndian.Uint16(frame[6:]) != crc16(frame[:6]) {
+		return nil, fmt.Errorf("invalid checksum in response %x", frame)
+	}
+	return frame[:6], nil
+}
+
+// requestValue sends a command whose response starts with the transmission
+// and global states, followed by four bytes of data.
+func (a *Aurora) requestValue(address, command byte, params []byte) ([]byte, error) {
+	resp, err := a.request(address, command, params)
+	if err != nil {
+		return nil, err
+	}
+	if resp[0] != 0 {
+		return nil, transmissionError(resp[0])
+	}
+	return resp, nil
+}
+
+// crc16 computes the CRC-16/X.25 checksum used by the protocol.
+func crc16(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		for i := 0; i < 8; i++ {
+			if (crc^uint16(b))&1 != 0 {
+				crc = (crc >> 1) ^ 0x8408
+			} else {
+				crc >>= 1
+			}
+			b >>= 1
+		}
+	}
+	return ^crc
+}
+
+func init() {
+	inputs.Add("aurora", func() telegraf.Input {
+		return &Aurora{
+			BaudRate:  19200,
+			Timeout:   internal.Duration{Duration: time.Second},
+			Addresses: []int{2},
+		}
+	})
+}
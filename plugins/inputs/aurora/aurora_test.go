@@ -0,0 +1,135 @@
+package aurora
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeInverter answers Aurora requests of a single input inverter.
+type fakeInverter struct {
+	address  byte
+	response bytes.Buffer
+}
+
+func (f *fakeInverter) Write(b []byte) (int, error) {
+	if len(b) != 10 || crc16(b[:8]) != binary.LittleEndian.Uint16(b[8:]) {
+		return 0, fmt.Errorf("invalid request %x", b)
+	}
+	if b[0] != f.address {
+		return len(b), nil
+	}
+
+	frame := make([]byte, 6)
+	switch b[1] {
+	case cmdSerialNumber:
+		copy(frame, "123456")
+	case cmdState:
+		copy(frame, []byte{0, 6, 2, 2, 0, 0})
+	case cmdMeasure:
+		values := map[byte]float32{
+			1:  230.5,
+			2:  4.25,
+			3:  980,
+			4:  50.01,
+			23: 350.25,
+			25: 2.875,
+			21: 41.5,
+		}
+		v, ok := values[b[2]]
+		if !ok {
+			frame[0] = stateNoVariable
+			break
+		}
+		binary.BigEndian.PutUint32(frame[2:], math.Float32bits(v))
+	case cmdEnergy:
+		binary.BigEndian.PutUint32(frame[2:], 1000*uint32(b[2]+1))
+	default:
+		frame[0] = stateNotImplemented
+	}
+	crc := crc16(frame)
+	f.response.Write(append(frame, byte(crc), byte(crc>>8)))
+	return len(b), nil
+}
+
+func (f *fakeInverter) Read(b []byte) (int, error) {
+	if f.response.Len() == 0 {
+		return 0, fmt.Errorf("timeout")
+	}
+	return f.response.Read(b)
+}
+
+func (f *fakeInverter) Flush() error {
+	f.response.Reset()
+	return nil
+}
+
+func (f *fakeInverter) Close() error {
+	return nil
+}
+
+func TestGather(t *testing.T) {
+	a := &Aurora{
+		Addresses: []int{2, 3},
+		port:      &fakeInverter{address: 2},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, a.Gather(&acc))
+
+	// The inverter at address 3 does not respond.
+	require.Len(t, acc.Errors, 1)
+	assert.Contains(t, acc.Errors[0].Error(), "[address=3]")
+
+	require.Len(t, acc.Metrics, 1)
+	m := acc.Metrics[0]
+	assert.Equal(t, "aurora", m.Measurement)
+	assert.Equal(t, map[string]string{"address": "2", "serial_number": "123456"}, m.Tags)
+	assert.Equal(t, map[string]interface{}{
+		"global_state":         int64(6),
+		"inverter_state":       int64(2),
+		"dcdc1_state":          int64(2),
+		"dcdc2_state":          int64(0),
+		"alarm_state":          int64(0),
+		"grid_voltage":         float64(230.5),
+		"grid_current":         float64(4.25),
+		"grid_power":           float64(980),
+		"frequency":            float64(float32(50.01)),
+		"input1_voltage":       float64(350.25),
+		"input1_current":       float64(2.875),
+		"inverter_temperature": float64(41.5),
+		"energy_daily":         int64(1000),
+		"energy_weekly":        int64(2000),
+		"energy_monthly":       int64(4000),
+		"energy_yearly":        int64(5000),
+		"energy_total":         int64(6000),
+		"energy_partial":       int64(7000),
+	}, m.Fields)
+}
+
+func TestCRC16(t *testing.T) {
+	assert.Equal(t, uint16(0x906E), crc16([]byte("123456789")))
+}
+
+func TestParseResponse(t *testing.T) {
+	frame := []byte{0, 6, 0, 0, 0, 0}
+	crc := crc16(frame)
+	resp, err := parseResponse(append(frame, byte(crc), byte(crc>>8)))
+	require.NoError(t, err)
+	assert.Equal(t, frame, resp)
+
+	_, err = parseResponse(append(frame, byte(crc), byte(crc>>8)+1))
+	assert.Error(t, err)
+}
+
+func TestNoAddresses(t *testing.T) {
+	a := &Aurora{}
+	var acc testutil.Accumulator
+	assert.Error(t, a.Gather(&acc))
+}
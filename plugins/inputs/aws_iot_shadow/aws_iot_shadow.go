@@ -0,0 +1,174 @@
+package aws_iot_shadow
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iotdataplane"
+
+	"github.com/influxdata/telegraf"
+	internalaws "github.com/influxdata/telegraf/internal/config/aws"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	jsonparser "github.com/influxdata/telegraf/plugins/parsers/json"
+)
+
+type AwsIotShadow struct {
+	Region    string `toml:"region"`
+	AccessKey string `toml:"access_key"`
+	SecretKey string `toml:"secret_key"`
+	RoleARN   string `toml:"role_arn"`
+	Profile   string `toml:"profile"`
+	Filename  string `toml:"shared_credential_file"`
+	Token     string `toml:"token"`
+
+	Endpoint       string   `toml:"endpoint"`
+	Things         []string `toml:"things"`
+	IncludeDesired bool     `toml:"include_desired"`
+
+	client shadowClient
+}
+
+type shadowClient interface {
+	GetThingShadow(*iotdataplane.GetThingShadowInput) (*iotdataplane.GetThingShadowOutput, error)
+}
+
+// shadow is the subset of a device shadow document used by the plugin.
+type shadow struct {
+	State struct {
+		Reported map[string]interface{} `json:"reported"`
+		Desired  map[string]interface{} `json:"desired"`
+	} `json:"state"`
+	Version   int64 `json:"version"`
+	Timestamp int64 `json:"timestamp"`
+}
+
+var sampleConfig = `
+  ## Amazon Region
+  region = "us-east-1"
+
+  ## Amazon Credentials
+  ## Credentials are loaded in the following order
+  ## 1) Assumed credentials via STS if role_arn is specified
+  ## 2) explicit credentials from 'access_key' and 'secret_key'
+  ## 3) shared profile from 'profile'
+  ## 4) environment variables
+  ## 5) shared credentials file
+  ## 6) EC2 Instance Profile
+  #access_key = ""
+  #secret_key = ""
+  #token = ""
+  #role_arn = ""
+  #profile = ""
+  #shared_credential_file = ""
+
+  ## Account specific AWS IoT data endpoint (required), as shown by
+  ## "aws iot describe-endpoint".
+  endpoint = "https://a1b2c3d4e5f6g7.iot.us-east-1.amazonaws.com"
+
+  ## Names of the things whose shadows are read.
+  things = ["inverter01"]
+
+  ## Also create fields from the desired state, prefixed with "desired_".
+  # include_desired = false
+`
+
+func (a *AwsIotShadow) SampleConfig() string {
+	return sampleConfig
+}
+
+func (a *AwsIotShadow) Description() string {
+	return "Read the reported state of AWS IoT device shadows"
+}
+
+func (a *AwsIotShadow) initializeClient() error {
+	if a.Endpoint == "" {
+		return fmt.Errorf("endpoint must be configured")
+	}
+
+	credentialConfig := &internalaws.CredentialConfig{
+		Region:    a.Region,
+		AccessKey: a.AccessKey,
+		SecretKey: a.SecretKey,
+		RoleARN:   a.RoleARN,
+		Profile:   a.Profile,
+		Filename:  a.Filename,
+		Token:     a.Token,
+	}
+	configProvider := credentialConfig.Credentials()
+
+	a.client = iotdataplane.New(configProvider, &aws.Config{
+		Endpoint: aws.String(a.Endpoint),
+	})
+	return nil
+}
+
+func (a *AwsIotShadow) Gather(acc telegraf.Accumulator) error {
+	if a.client == nil {
+		if err := a.initializeClient(); err != nil {
+			return err
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, thing := range a.Things {
+		wg.Add(1)
+		go func(thing string) {
+			defer wg.Done()
+			if err := a.gatherThing(acc, thing); err != nil {
+				acc.AddError(fmt.Errorf("[thing=%s]: %s", thing, err))
+			}
+		}(thing)
+	}
+	wg.Wait()
+
+	return nil
+}
+
+func (a *AwsIotShadow) gatherThing(acc telegraf.Accumulator, thing string) error {
+	resp, err := a.client.GetThingShadow(&iotdataplane.GetThingShadowInput{
+		ThingName: aws.String(thing),
+	})
+	if err != nil {
+		return err
+	}
+
+	var doc shadow
+	if err := json.Unmarshal(resp.Payload, &doc); err != nil {
+		return fmt.Errorf("unable to parse shadow document, %s", err)
+	}
+
+	f := jsonparser.JSONFlattener{}
+	if err := f.FullFlattenJSON("", doc.State.Reported, true, true); err != nil {
+		return err
+	}
+	if a.IncludeDesired && doc.State.Desired != nil {
+		if err := f.FullFlattenJSON("desired", doc.State.Desired, true, true); err != nil {
+			return err
+		}
+	}
+	if len(f.Fields) == 0 {
+		return nil
+	}
+	f.Fields["version"] = doc.Version
+
+	tags := map[string]string{
+		"thing_name": thing,
+	}
+
+	t := time.Now()
+	if doc.Timestamp != 0 {
+		t = time.Unix(doc.Timestamp, 0)
+	}
+
+	acc.AddFields("aws_iot_shadow", f.Fields, tags, t)
+	return nil
+}
+
+func init() {
+	inputs.Add("aws_iot_shadow", func() telegraf.Input {
+		return &AwsIotShadow{}
+	})
+}
@@ -0,0 +1,89 @@
+package aws_iot_shadow
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/iotdataplane"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockShadowClient struct {
+	shadows map[string]string
+}
+
+func (m *mockShadowClient) GetThingShadow(params *iotdataplane.GetThingShadowInput) (*iotdataplane.GetThingShadowOutput, error) {
+	doc, ok := m.shadows[*params.ThingName]
+	if !ok {
+		return nil, fmt.Errorf("ResourceNotFoundException: No shadow exists with name: '%s'", *params.ThingName)
+	}
+	return &iotdataplane.GetThingShadowOutput{Payload: []byte(doc)}, nil
+}
+
+const inverterShadow = `
+{
+  "state": {
+    "desired": {"power_limit": 80},
+    "reported": {
+      "power": 1530.5,
+      "online": true,
+      "mode": "MPPT",
+      "battery": {"soc": 87, "temperature": 21.5}
+    }
+  },
+  "metadata": {"reported": {"power": {"timestamp": 1496311200}}},
+  "version": 42,
+  "timestamp": 1496311260
+}
+`
+
+func TestGather(t *testing.T) {
+	a := &AwsIotShadow{
+		Things: []string{"inverter01", "missing"},
+		client: &mockShadowClient{
+			shadows: map[string]string{"inverter01": inverterShadow},
+		},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, a.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Len(t, acc.Metrics, 1)
+
+	acc.AssertContainsTaggedFields(t, "aws_iot_shadow",
+		map[string]interface{}{
+			"power":               float64(1530.5),
+			"online":              true,
+			"mode":                "MPPT",
+			"battery_soc":         float64(87),
+			"battery_temperature": float64(21.5),
+			"version":             int64(42),
+		},
+		map[string]string{"thing_name": "inverter01"})
+	assert.Equal(t, time.Unix(1496311260, 0).UnixNano(), acc.Metrics[0].Time.UnixNano())
+}
+
+func TestGatherIncludeDesired(t *testing.T) {
+	a := &AwsIotShadow{
+		Things:         []string{"inverter01"},
+		IncludeDesired: true,
+		client: &mockShadowClient{
+			shadows: map[string]string{"inverter01": inverterShadow},
+		},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, a.Gather(&acc))
+	require.Len(t, acc.Metrics, 1)
+	assert.Equal(t, float64(80), acc.Metrics[0].Fields["desired_power_limit"])
+}
+
+func TestMissingEndpoint(t *testing.T) {
+	a := &AwsIotShadow{Things: []string{"inverter01"}}
+
+	var acc testutil.Accumulator
+	assert.Error(t, a.Gather(&acc))
+}
@@ -0,0 +1,250 @@
+package ecowitt
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// Maximum size of an upload, stations send a few hundred bytes.
+const maxBodySize = 64 * 1024
+
+type Ecowitt struct {
+	ServiceAddress string            `toml:"service_address"`
+	Path           string            `toml:"path"`
+	Passkeys       []string          `toml:"passkeys"`
+	Metric         bool              `toml:"metric"`
+	ReadTimeout    internal.Duration `toml:"read_timeout"`
+	WriteTimeout   internal.Duration `toml:"write_timeout"`
+
+	mu sync.Mutex
+	wg sync.WaitGroup
+
+	listener net.Listener
+	acc      telegraf.Accumulator
+}
+
+// Parameters identifying the station instead of carrying measurements.
+// PASSKEY is sent by the Ecowitt protocol, ID and PASSWORD by the
+// Wunderground protocol.
+var identityKeys = map[string]bool{
+	"PASSKEY":      true,
+	"ID":           true,
+	"PASSWORD":     true,
+	"stationtype":  true,
+	"model":        true,
+	"freq":         true,
+	"dateutc":      true,
+	"action":       true,
+	"realtime":     true,
+	"rtfreq":       true,
+	"softwaretype": true,
+}
+
+var sampleConfig = `
+  ## Address and port to listen on for uploads. Configure the station to
+  ## upload to this address with the "Customized" server option, using
+  ## either the Ecowitt or the Wunderground protocol.
+  service_address = ":8080"
+
+  ## Path to accept uploads on, by default all paths are accepted.
+  # path = "/data/report/"
+
+  ## Only accept uploads from these Ecowitt passkeys or Wunderground station
+  ## ids, by default all stations are accepted.
+  # passkeys = []
+
+  ## Convert values to metric units, ie tempf (°F) to temp_c (°C).
+  # metric = false
+
+  ## maximum duration before timing out read of the request
+  # read_timeout = "10s"
+  ## maximum duration before timing out write of the response
+  # write_timeout = "10s"
+`
+
+func (e *Ecowitt) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Ecowitt) Description() string {
+	return "Receive uploads of Ecowitt, Ambient and Wunderground compatible weather stations"
+}
+
+func (e *Ecowitt) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+// Start starts the listener service.
+func (e *Ecowitt) Start(acc telegraf.Accumulator) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.acc = acc
+
+	listener, err := net.Listen("tcp", e.ServiceAddress)
+	if err != nil {
+		return err
+	}
+	e.listener = listener
+
+	if e.ReadTimeout.Duration < time.Second {
+		e.ReadTimeout.Duration = time.Second * 10
+	}
+	if e.WriteTimeout.Duration < time.Second {
+		e.WriteTimeout.Duration = time.Second * 10
+	}
+
+	server := http.Server{
+		Handler:      e,
+		ReadTimeout:  e.ReadTimeout.Duration,
+		WriteTimeout: e.WriteTimeout.Duration,
+	}
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		server.Serve(e.listener)
+	}()
+
+	log.Printf("I! Started Ecowitt listener service on %s\n", e.ServiceAddress)
+	return nil
+}
+
+// Stop cleans up all resources
+func (e *Ecowitt) Stop() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.listener.Close()
+	e.wg.Wait()
+
+	log.Println("I! Stopped Ecowitt listener service on ", e.ServiceAddress)
+}
+
+func (e *Ecowitt) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	if e.Path != "" && req.URL.Path != e.Path {
+		http.NotFound(res, req)
+		return
+	}
+	if req.Method != "GET" && req.Method != "POST" {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	req.Body = http.MaxBytesReader(res, req.Body, maxBodySize)
+	if err := req.ParseForm(); err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	m := e.parse(req.Form)
+	if m == nil {
+		http.Error(res, "station not accepted", http.StatusForbidden)
+		return
+	}
+
+	if len(m.fields) > 0 {
+		e.acc.AddFields("ecowitt", m.fields, m.tags, m.t)
+	}
+
+	// Wunderground clients check for this answer.
+	res.Write([]byte("success\n"))
+}
+
+type upload struct {
+	fields map[string]interface{}
+	tags   map[string]string
+	t      time.Time
+}
+
+// parse converts the parameters of an upload, it returns nil if the station
+// is not accepted.
+func (e *Ecowitt) parse(values url.Values) *upload {
+	station := values.Get("PASSKEY")
+	if station == "" {
+		station = values.Get("ID")
+	}
+	if len(e.Passkeys) > 0 {
+		accepted := false
+		for _, k := range e.Passkeys {
+			if k == station {
+				accepted = true
+			}
+		}
+		if !accepted {
+			return nil
+		}
+	}
+
+	tags := make(map[string]string)
+	if station != "" {
+		tags["station"] = station
+	}
+	for _, k := range []string{"stationtype", "model", "softwaretype"} {
+		if v := values.Get(k); v != "" {
+			tags[k] = v
+		}
+	}
+
+	fields := make(map[string]interface{})
+	for k, v := range values {
+		if identityKeys[k] || len(v) == 0 {
+			continue
+		}
+		f, err := strconv.ParseFloat(v[0], 64)
+		if err != nil {
+			// Some firmwares send placeholders like "-" for missing sensors.
+			continue
+		}
+		name := strings.ToLower(k)
+		if e.Metric {
+			name, f = toMetric(name, f)
+		}
+		fields[name] = f
+	}
+	t := time.Now()
+	if v := values.Get("dateutc"); v != "" && v != "now" {
+		if ts, err := time.Parse("2006-01-02 15:04:05", v); err == nil {
+			t = ts
+		}
+	}
+	return &upload{fields: fields, tags: tags, t: t}
+}
+
+// toMetric converts a value in imperial units, as indicated by the suffix of
+// its name, to metric units and renames it accordingly.
+func toMetric(name string, v float64) (string, float64) {
+	switch {
+	case strings.HasPrefix(name, "barom") && strings.HasSuffix(name, "in"):
+		return strings.TrimSuffix(name, "in") + "_hpa", v * 33.8639
+	case strings.HasSuffix(name, "in") && strings.Contains(name, "rain"):
+		return strings.TrimSuffix(name, "in") + "_mm", v * 25.4
+	case strings.HasSuffix(name, "mph"):
+		return strings.TrimSuffix(name, "mph") + "_kmh", v * 1.609344
+	case name == "maxdailygust":
+		return name + "_kmh", v * 1.609344
+	case strings.HasSuffix(name, "f") && (strings.Contains(name, "temp") ||
+		strings.HasPrefix(name, "dewpt") || strings.HasPrefix(name, "windchill") ||
+		strings.HasPrefix(name, "feelslike") || strings.HasPrefix(name, "heatindex")):
+		return strings.TrimSuffix(name, "f") + "_c", (v - 32) * 5 / 9
+	}
+	return name, v
+}
+
+func init() {
+	inputs.Add("ecowitt", func() telegraf.Input {
+		return &Ecowitt{
+			ServiceAddress: ":8080",
+		}
+	})
+}
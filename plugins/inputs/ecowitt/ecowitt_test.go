@@ -0,0 +1,118 @@
+package ecowitt
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ecowittUpload = "PASSKEY=0123456789ABCDEF&stationtype=EasyWeatherV1.4.9" +
+	"&dateutc=2017-06-01+10:15:00&tempinf=72.3&humidityin=45&baromrelin=29.921" +
+	"&baromabsin=29.500&tempf=68.0&humidity=60&winddir=180&windspeedmph=2.24" +
+	"&windgustmph=4.47&maxdailygust=6.9&rainratein=0.000&dailyrainin=0.039" +
+	"&solarradiation=812.45&uv=6&wh65batt=0&freq=868M&model=WS2900_V2.01.10"
+
+func newListener(t *testing.T, e *Ecowitt) (*testutil.Accumulator, string) {
+	e.ServiceAddress = "127.0.0.1:0"
+	acc := &testutil.Accumulator{}
+	require.NoError(t, e.Start(acc))
+	return acc, "http://" + e.listener.Addr().String()
+}
+
+func TestEcowittUpload(t *testing.T) {
+	e := &Ecowitt{Path: "/data/report/"}
+	acc, addr := newListener(t, e)
+	defer e.Stop()
+
+	resp, err := http.Post(addr+"/data/report/", "application/x-www-form-urlencoded",
+		strings.NewReader(ecowittUpload))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	acc.Wait(1)
+	require.Len(t, acc.Metrics, 1)
+	m := acc.Metrics[0]
+	assert.Equal(t, "ecowitt", m.Measurement)
+	assert.Equal(t, map[string]string{
+		"station":     "0123456789ABCDEF",
+		"stationtype": "EasyWeatherV1.4.9",
+		"model":       "WS2900_V2.01.10",
+	}, m.Tags)
+	assert.Equal(t, float64(68), m.Fields["tempf"])
+	assert.Equal(t, float64(812.45), m.Fields["solarradiation"])
+	assert.NotContains(t, m.Fields, "freq")
+	assert.Equal(t, time.Date(2017, 6, 1, 10, 15, 0, 0, time.UTC).UnixNano(), m.Time.UnixNano())
+
+	// Other paths are not accepted.
+	resp, err = http.Post(addr+"/other", "application/x-www-form-urlencoded",
+		strings.NewReader(ecowittUpload))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestWundergroundUpload(t *testing.T) {
+	e := &Ecowitt{Metric: true, Passkeys: []string{"KCASANFR5"}}
+	acc, addr := newListener(t, e)
+	defer e.Stop()
+
+	params := url.Values{}
+	params.Set("ID", "KCASANFR5")
+	params.Set("PASSWORD", "secret")
+	params.Set("dateutc", "now")
+	params.Set("tempf", "50")
+	params.Set("windspeedmph", "10")
+	params.Set("baromin", "29.92")
+	params.Set("dailyrainin", "1")
+	params.Set("dewptf", "-")
+	params.Set("action", "updateraw")
+
+	resp, err := http.Get(addr + "/weatherstation/updateweatherstation.php?" + params.Encode())
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	acc.Wait(1)
+	require.Len(t, acc.Metrics, 1)
+	m := acc.Metrics[0]
+	assert.Equal(t, map[string]string{"station": "KCASANFR5"}, m.Tags)
+	assert.InDelta(t, 10, m.Fields["temp_c"], 1e-9)
+	assert.InDelta(t, 16.09344, m.Fields["windspeed_kmh"], 1e-9)
+	assert.InDelta(t, 1013.21, m.Fields["barom_hpa"], 0.01)
+	assert.InDelta(t, 25.4, m.Fields["dailyrain_mm"], 1e-9)
+	assert.Len(t, m.Fields, 4)
+
+	// Unknown stations are rejected.
+	params.Set("ID", "KCASANFR6")
+	resp, err = http.Get(addr + "/weatherstation/updateweatherstation.php?" + params.Encode())
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestToMetric(t *testing.T) {
+	var tests = []struct {
+		name     string
+		expected string
+	}{
+		{"tempinf", "tempin_c"},
+		{"temp1f", "temp1_c"},
+		{"humidityin", "humidityin"},
+		{"baromrelin", "baromrel_hpa"},
+		{"rainratein", "rainrate_mm"},
+		{"windgustmph", "windgust_kmh"},
+		{"maxdailygust", "maxdailygust_kmh"},
+		{"solarradiation", "solarradiation"},
+	}
+	for _, tt := range tests {
+		name, _ := toMetric(tt.name, 1)
+		assert.Equal(t, tt.expected, name)
+	}
+}
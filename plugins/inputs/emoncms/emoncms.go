@@ -0,0 +1,180 @@
+package emoncms
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+type Emoncms struct {
+	URL     string            `toml:"url"`
+	APIKey  string            `toml:"api_key"`
+	Feeds   []string          `toml:"feeds"`
+	Timeout internal.Duration `toml:"timeout"`
+
+	// Path to CA file
+	SSLCA string `toml:"ssl_ca"`
+	// Path to host cert file
+	SSLCert string `toml:"ssl_cert"`
+	// Path to cert key file
+	SSLKey string `toml:"ssl_key"`
+	// Use SSL but skip chain & host verification
+	InsecureSkipVerify bool
+
+	client *http.Client
+}
+
+// feed is an entry of the feed/list.json response. Depending on the emoncms
+// version numbers are encoded as JSON numbers or strings.
+type feed struct {
+	ID    json.Number `json:"id"`
+	Name  string      `json:"name"`
+	Tag   string      `json:"tag"`
+	Time  interface{} `json:"time"`
+	Value interface{} `json:"value"`
+}
+
+var sampleConfig = `
+  ## URL of the emoncms instance.
+  url = "https://emoncms.org"
+
+  ## Read API key, found on the "My Account" page.
+  api_key = ""
+
+  ## Names or ids of the feeds to read, by default all feeds are read.
+  # feeds = ["use", "use_kwh"]
+
+  ## Amount of time allowed to complete the HTTP request
+  # timeout = "5s"
+
+  ## Optional SSL Config
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  ## Use SSL but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+func (e *Emoncms) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Emoncms) Description() string {
+	return "Read feed values from emoncms"
+}
+
+func (e *Emoncms) Gather(acc telegraf.Accumulator) error {
+	if e.client == nil {
+		tlsCfg, err := internal.GetTLSConfig(
+			e.SSLCert, e.SSLKey, e.SSLCA, e.InsecureSkipVerify)
+		if err != nil {
+			return err
+		}
+		e.client = &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsCfg,
+			},
+			Timeout: e.Timeout.Duration,
+		}
+	}
+
+	feeds, err := e.fetchFeeds()
+	if err != nil {
+		return fmt.Errorf("[url=%s]: %s", e.URL, err)
+	}
+
+	selected := make(map[string]bool, len(e.Feeds))
+	for _, f := range e.Feeds {
+		selected[f] = true
+	}
+
+	for _, f := range feeds {
+		if len(selected) > 0 && !selected[f.Name] && !selected[f.ID.String()] {
+			continue
+		}
+
+		value, ok := toFloat(f.Value)
+		if !ok {
+			// Feeds that never received data have a null value.
+			continue
+		}
+
+		tags := map[string]string{
+			"feed_id":   f.ID.String(),
+			"feed_name": f.Name,
+		}
+		if f.Tag != "" {
+			tags["feed_tag"] = f.Tag
+		}
+
+		fields := map[string]interface{}{
+			"value": value,
+		}
+
+		t := time.Now()
+		if ts, ok := toFloat(f.Time); ok && ts > 0 {
+			t = time.Unix(int64(ts), 0)
+		}
+
+		acc.AddFields("emoncms", fields, tags, t)
+	}
+	return nil
+}
+
+func (e *Emoncms) fetchFeeds() ([]feed, error) {
+	u, err := url.Parse(strings.TrimRight(e.URL, "/") + "/feed/list.json")
+	if err != nil {
+		return nil, err
+	}
+	params := url.Values{}
+	params.Set("apikey", e.APIKey)
+	u.RawQuery = params.Encode()
+
+	resp, err := e.client.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("received status code %d (%s), expected %d (%s)",
+			resp.StatusCode, http.StatusText(resp.StatusCode),
+			http.StatusOK, http.StatusText(http.StatusOK))
+	}
+
+	var feeds []feed
+	if err := json.NewDecoder(resp.Body).Decode(&feeds); err != nil {
+		// An invalid API key returns an object instead of a list.
+		return nil, fmt.Errorf("unable to parse feed list, %s", err)
+	}
+	return feeds, nil
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func init() {
+	inputs.Add("emoncms", func() telegraf.Input {
+		return &Emoncms{
+			URL:     "https://emoncms.org",
+			Timeout: internal.Duration{Duration: time.Second * 5},
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package emoncms
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const feedList = `
+[
+  {"id":"1","userid":"1","name":"use","datatype":"1","tag":"House","public":"0",
+   "size":"1234","engine":"5","time":"1500000000","value":"1530.5"},
+  {"id":2,"userid":1,"name":"use_kwh","datatype":1,"tag":"","public":0,
+   "size":1234,"engine":5,"time":1500000010,"value":8723.25},
+  {"id":"3","userid":"1","name":"solar","datatype":"1","tag":"Solar","public":"0",
+   "size":"0","engine":"5","time":null,"value":null}
+]
+`
+
+func newServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/feed/list.json", r.URL.Path)
+		if r.URL.Query().Get("apikey") != "secret" {
+			w.Write([]byte(`{"success":false,"message":"Username or password empty"}`))
+			return
+		}
+		w.Write([]byte(feedList))
+	}))
+}
+
+func TestGather(t *testing.T) {
+	ts := newServer(t)
+	defer ts.Close()
+
+	e := &Emoncms{URL: ts.URL + "/", APIKey: "secret"}
+
+	var acc testutil.Accumulator
+	require.NoError(t, e.Gather(&acc))
+	require.Len(t, acc.Metrics, 2)
+
+	acc.AssertContainsTaggedFields(t, "emoncms",
+		map[string]interface{}{"value": float64(1530.5)},
+		map[string]string{"feed_id": "1", "feed_name": "use", "feed_tag": "House"})
+	acc.AssertContainsTaggedFields(t, "emoncms",
+		map[string]interface{}{"value": float64(8723.25)},
+		map[string]string{"feed_id": "2", "feed_name": "use_kwh"})
+	assert.Equal(t, time.Unix(1500000000, 0).UnixNano(), acc.Metrics[0].Time.UnixNano())
+}
+
+func TestGatherSelectedFeeds(t *testing.T) {
+	ts := newServer(t)
+	defer ts.Close()
+
+	e := &Emoncms{URL: ts.URL, APIKey: "secret", Feeds: []string{"2"}}
+
+	var acc testutil.Accumulator
+	require.NoError(t, e.Gather(&acc))
+	require.Len(t, acc.Metrics, 1)
+	assert.Equal(t, "use_kwh", acc.Metrics[0].Tags["feed_name"])
+}
+
+func TestGatherInvalidKey(t *testing.T) {
+	ts := newServer(t)
+	defer ts.Close()
+
+	e := &Emoncms{URL: ts.URL, APIKey: "wrong"}
+
+	var acc testutil.Accumulator
+	assert.Error(t, e.Gather(&acc))
+	assert.Len(t, acc.Metrics, 0)
+}
@@ -0,0 +1,132 @@
+package evcc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+type Evcc struct {
+	URL     string            `toml:"url"`
+	Timeout internal.Duration `toml:"timeout"`
+
+	client *http.Client
+}
+
+var sampleConfig = `
+  ## URL of the evcc web interface.
+  url = "http://localhost:7070"
+
+  ## Amount of time allowed to complete the HTTP request
+  # timeout = "5s"
+`
+
+func (e *Evcc) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Evcc) Description() string {
+	return "Read site and loadpoint state from evcc"
+}
+
+func (e *Evcc) Gather(acc telegraf.Accumulator) error {
+	if e.client == nil {
+		e.client = &http.Client{
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+			},
+			Timeout: e.Timeout.Duration,
+		}
+	}
+
+	state, err := e.fetchState()
+	if err != nil {
+		return fmt.Errorf("[url=%s]: %s", e.URL, err)
+	}
+	now := time.Now()
+
+	siteTags := map[string]string{"url": e.URL}
+	if title, ok := state["siteTitle"].(string); ok && title != "" {
+		siteTags["site"] = title
+	}
+	if fields := scalarFields(state); len(fields) > 0 {
+		acc.AddFields("evcc_site", fields, siteTags, now)
+	}
+
+	loadpoints, _ := state["loadpoints"].([]interface{})
+	for i, lp := range loadpoints {
+		values, ok := lp.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		tags := map[string]string{
+			"url":       e.URL,
+			"loadpoint": strconv.Itoa(i + 1),
+		}
+		if site, ok := siteTags["site"]; ok {
+			tags["site"] = site
+		}
+		for _, k := range []string{"title", "mode", "vehicleTitle"} {
+			if v, ok := values[k].(string); ok && v != "" {
+				tags[internal.SnakeCase(k)] = v
+			}
+		}
+		if fields := scalarFields(values); len(fields) > 0 {
+			acc.AddFields("evcc_loadpoint", fields, tags, now)
+		}
+	}
+	return nil
+}
+
+func (e *Evcc) fetchState() (map[string]interface{}, error) {
+	resp, err := e.client.Get(strings.TrimRight(e.URL, "/") + "/api/state")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("received status code %d (%s), expected %d (%s)",
+			resp.StatusCode, http.StatusText(resp.StatusCode),
+			http.StatusOK, http.StatusText(http.StatusOK))
+	}
+
+	var state map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		return nil, fmt.Errorf("unable to parse state, %s", err)
+	}
+	// Older versions wrap the state in a result object.
+	if result, ok := state["result"].(map[string]interface{}); ok {
+		state = result
+	}
+	return state, nil
+}
+
+// scalarFields returns the numbers and booleans of an object as fields
+// named in snake case, ie pvPower becomes pv_power.
+func scalarFields(values map[string]interface{}) map[string]interface{} {
+	fields := make(map[string]interface{})
+	for k, v := range values {
+		switch v := v.(type) {
+		case float64, bool:
+			fields[internal.SnakeCase(k)] = v
+		}
+	}
+	return fields
+}
+
+func init() {
+	inputs.Add("evcc", func() telegraf.Input {
+		return &Evcc{
+			URL:     "http://localhost:7070",
+			Timeout: internal.Duration{Duration: time.Second * 5},
+		}
+	})
+}
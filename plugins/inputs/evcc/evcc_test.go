@@ -0,0 +1,117 @@
+package evcc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const state = `
+{
+  "siteTitle": "Home",
+  "pvPower": 5230.5,
+  "gridPower": -2100,
+  "homePower": 730.5,
+  "batteryPower": -1400,
+  "batterySoc": 64,
+  "batteryConfigured": true,
+  "currency": "EUR",
+  "pv": [{"power": 5230.5}],
+  "loadpoints": [
+    {
+      "title": "Garage",
+      "mode": "pv",
+      "charging": true,
+      "connected": true,
+      "chargePower": 3680,
+      "chargedEnergy": 4210,
+      "vehicleTitle": "ID.3",
+      "vehicleSoc": 55,
+      "phasesActive": 1
+    },
+    {
+      "title": "Carport",
+      "mode": "off",
+      "charging": false,
+      "connected": false,
+      "chargePower": 0,
+      "vehicleTitle": ""
+    }
+  ]
+}
+`
+
+func TestGather(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/state", r.URL.Path)
+		w.Write([]byte(state))
+	}))
+	defer ts.Close()
+
+	e := &Evcc{URL: ts.URL}
+
+	var acc testutil.Accumulator
+	require.NoError(t, e.Gather(&acc))
+	require.Len(t, acc.Metrics, 3)
+
+	acc.AssertContainsTaggedFields(t, "evcc_site",
+		map[string]interface{}{
+			"pv_power":           float64(5230.5),
+			"grid_power":         float64(-2100),
+			"home_power":         float64(730.5),
+			"battery_power":      float64(-1400),
+			"battery_soc":        float64(64),
+			"battery_configured": true,
+		},
+		map[string]string{"url": ts.URL, "site": "Home"})
+
+	acc.AssertContainsTaggedFields(t, "evcc_loadpoint",
+		map[string]interface{}{
+			"charging":       true,
+			"connected":      true,
+			"charge_power":   float64(3680),
+			"charged_energy": float64(4210),
+			"vehicle_soc":    float64(55),
+			"phases_active":  float64(1),
+		},
+		map[string]string{
+			"url":           ts.URL,
+			"site":          "Home",
+			"loadpoint":     "1",
+			"title":         "Garage",
+			"mode":          "pv",
+			"vehicle_title": "ID.3",
+		})
+
+	acc.AssertContainsTaggedFields(t, "evcc_loadpoint",
+		map[string]interface{}{
+			"charging":     false,
+			"connected":    false,
+			"charge_power": float64(0),
+		},
+		map[string]string{
+			"url":       ts.URL,
+			"site":      "Home",
+			"loadpoint": "2",
+			"title":     "Carport",
+			"mode":      "off",
+		})
+}
+
+func TestGatherResultWrapper(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"result": {"pvPower": 100, "loadpoints": []}}`))
+	}))
+	defer ts.Close()
+
+	e := &Evcc{URL: ts.URL}
+
+	var acc testutil.Accumulator
+	require.NoError(t, e.Gather(&acc))
+	require.Len(t, acc.Metrics, 1)
+	assert.Equal(t, map[string]interface{}{"pv_power": float64(100)}, acc.Metrics[0].Fields)
+}
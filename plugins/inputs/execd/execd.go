@@ -0,0 +1,252 @@
+package execd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+const sampleConfig = `
+  ## Program to run as daemon, with its arguments
+  command = ["/usr/bin/mycollector", "--foo=bar"]
+
+  ## Define how the process is signaled on each collection interval.
+  ## Valid values are:
+  ##   "none"  : Do not signal anything. The process must output metrics by
+  ##             itself.
+  ##   "STDIN" : Send a newline on STDIN.
+  signal = "none"
+
+  ## Delay before the process is restarted after an unexpected termination
+  restart_delay = "10s"
+
+  ## Data format to consume, one metric, or one JSON document, per line.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
+  data_format = "influx"
+`
+
+var errStopped = errors.New("plugin stopped")
+
+type Execd struct {
+	Command      []string
+	Signal       string
+	RestartDelay internal.Duration
+
+	parser parsers.Parser
+	acc    telegraf.Accumulator
+
+	sync.Mutex
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	done  chan struct{}
+	wg    sync.WaitGroup
+	// readers waits for the output of the running process to be read.
+	readers sync.WaitGroup
+}
+
+func NewExecd() *Execd {
+	return &Execd{
+		Signal:       "none",
+		RestartDelay: internal.Duration{Duration: 10 * time.Second},
+	}
+}
+
+func (e *Execd) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Execd) Description() string {
+	return "Run executable as long-running input plugin"
+}
+
+func (e *Execd) SetParser(parser parsers.Parser) {
+	e.parser = parser
+}
+
+func (e *Execd) Start(acc telegraf.Accumulator) error {
+	if len(e.Command) == 0 {
+		return fmt.Errorf("no command defined")
+	}
+	switch e.Signal {
+	case "none", "STDIN":
+	default:
+		return fmt.Errorf("invalid signal %q, expected \"none\" or \"STDIN\"", e.Signal)
+	}
+
+	e.acc = acc
+	e.done = make(chan struct{})
+
+	if err := e.start(); err != nil {
+		return err
+	}
+
+	e.wg.Add(1)
+	go e.run()
+	return nil
+}
+
+// start starts the process and the readers of its output.
+func (e *Execd) start() error {
+	cmd := exec.Command(e.Command[0], e.Command[1:]...)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	// Stop kills the running process, do not start a new one once it did.
+	e.Lock()
+	defer e.Unlock()
+	select {
+	case <-e.done:
+		return errStopped
+	default:
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("error starting %s: %s", e.Command[0], err)
+	}
+	e.cmd = cmd
+	e.stdin = stdin
+
+	e.readers.Add(2)
+	go e.readOut(stdout)
+	go e.readErr(stderr)
+	return nil
+}
+
+// run waits for the process to exit and restarts it after the restart delay
+// until the plugin is stopped.
+func (e *Execd) run() {
+	defer e.wg.Done()
+
+	for {
+		e.Lock()
+		cmd := e.cmd
+		e.Unlock()
+
+		// The pipes are closed by Wait, read them until the end first.
+		e.readers.Wait()
+		err := cmd.Wait()
+		select {
+		case <-e.done:
+			return
+		default:
+		}
+		if err != nil {
+			e.acc.AddError(fmt.Errorf("process %s terminated: %s", e.Command[0], err))
+		} else {
+			e.acc.AddError(fmt.Errorf("process %s terminated", e.Command[0]))
+		}
+
+		for {
+			log.Printf("I! [inputs.execd] Restarting %s in %s\n",
+				e.Command[0], e.RestartDelay.Duration)
+			select {
+			case <-e.done:
+				return
+			case <-time.After(e.RestartDelay.Duration):
+			}
+
+			err := e.start()
+			if err == errStopped {
+				return
+			}
+			if err != nil {
+				e.acc.AddError(err)
+				continue
+			}
+			break
+		}
+	}
+}
+
+func (e *Execd) readOut(r io.Reader) {
+	defer e.readers.Done()
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		metrics, err := e.parser.Parse([]byte(line))
+		if err != nil {
+			e.acc.AddError(fmt.Errorf("unable to parse %q: %s", line, err))
+			continue
+		}
+		for _, m := range metrics {
+			e.acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		e.acc.AddError(fmt.Errorf("error reading output of %s: %s", e.Command[0], err))
+	}
+}
+
+func (e *Execd) readErr(r io.Reader) {
+	defer e.readers.Done()
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		log.Printf("E! [inputs.execd] stderr of %s: %s\n", e.Command[0], scanner.Text())
+	}
+}
+
+func (e *Execd) Gather(acc telegraf.Accumulator) error {
+	if e.Signal != "STDIN" {
+		return nil
+	}
+
+	e.Lock()
+	defer e.Unlock()
+	if e.stdin == nil {
+		return nil
+	}
+	if _, err := io.WriteString(e.stdin, "\n"); err != nil {
+		return fmt.Errorf("error writing to stdin of %s: %s", e.Command[0], err)
+	}
+	return nil
+}
+
+func (e *Execd) Stop() {
+	close(e.done)
+
+	e.Lock()
+	if e.stdin != nil {
+		e.stdin.Close()
+	}
+	if e.cmd != nil && e.cmd.Process != nil {
+		e.cmd.Process.Kill()
+	}
+	e.Unlock()
+
+	e.wg.Wait()
+}
+
+func init() {
+	inputs.Add("execd", func() telegraf.Input {
+		return NewExecd()
+	})
+}
@@ -0,0 +1,88 @@
+package execd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHelperProcess is run as the external program by the tests, it prints
+// a metric for every line on stdin, or once and exits with "once".
+func TestHelperProcess(t *testing.T) {
+	mode := os.Getenv("EXECD_TEST_MODE")
+	if mode == "" {
+		return
+	}
+	if mode == "once" {
+		fmt.Println("counter value=1i")
+		os.Exit(0)
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for i := 1; scanner.Scan(); i++ {
+		fmt.Printf("counter,source=stdin value=%di\n", i)
+	}
+	os.Exit(0)
+}
+
+func newTestExecd(t *testing.T, mode string) *Execd {
+	os.Setenv("EXECD_TEST_MODE", mode)
+	e := NewExecd()
+	e.Command = []string{os.Args[0], "-test.run=TestHelperProcess"}
+	p, err := parsers.NewInfluxParser()
+	require.NoError(t, err)
+	e.SetParser(p)
+	return e
+}
+
+func TestExecdSignalStdin(t *testing.T) {
+	e := newTestExecd(t, "stdin")
+	defer os.Unsetenv("EXECD_TEST_MODE")
+	e.Signal = "STDIN"
+
+	var acc testutil.Accumulator
+	require.NoError(t, e.Start(&acc))
+	require.NoError(t, e.Gather(&acc))
+	require.NoError(t, e.Gather(&acc))
+	acc.Wait(2)
+	e.Stop()
+
+	require.Len(t, acc.Metrics, 2)
+	for i, m := range acc.Metrics {
+		assert.Equal(t, "counter", m.Measurement)
+		assert.Equal(t, map[string]string{"source": "stdin"}, m.Tags)
+		assert.Equal(t, map[string]interface{}{"value": int64(i + 1)}, m.Fields)
+	}
+	assert.Empty(t, acc.Errors)
+}
+
+func TestExecdRestart(t *testing.T) {
+	e := newTestExecd(t, "once")
+	defer os.Unsetenv("EXECD_TEST_MODE")
+	e.RestartDelay = internal.Duration{Duration: 10 * time.Millisecond}
+
+	var acc testutil.Accumulator
+	require.NoError(t, e.Start(&acc))
+	acc.Wait(2)
+	e.Stop()
+
+	assert.True(t, acc.NMetrics() >= 2)
+	assert.NotEmpty(t, acc.Errors)
+}
+
+func TestExecdInvalidSignal(t *testing.T) {
+	e := newTestExecd(t, "")
+	e.Signal = "SIGHUP"
+
+	var acc testutil.Accumulator
+	assert.Error(t, e.Start(&acc))
+}
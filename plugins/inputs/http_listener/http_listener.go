@@ -1,7 +1,6 @@
 package http_listener
 
 import (
-	"bytes"
 	"compress/gzip"
 	"io"
 	"log"
@@ -42,9 +41,8 @@ type HTTPListener struct {
 
 	listener net.Listener
 
-	parser influx.InfluxParser
-	acc    telegraf.Accumulator
-	pool   *pool
+	acc  telegraf.Accumulator
+	pool *pool
 
 	BytesRecv       selfstat.Stat
 	RequestsServed  selfstat.Stat
@@ -223,94 +221,54 @@ func (h *HTTPListener) serveWrite(res http.ResponseWriter, req *http.Request) {
 	}
 	body = http.MaxBytesReader(res, body, h.MaxBodySize)
 
-	var return400 bool
-	var hangingBytes bool
+	// The body is parsed as it is read, the lines longer than the buffer
+	// being skipped.
 	buf := h.pool.get()
 	defer h.pool.put(buf)
-	bufStart := 0
-	for {
-		n, err := io.ReadFull(body, buf[bufStart:])
-		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
-			log.Println("E! " + err.Error())
-			// problem reading the request body
-			badRequest(res)
-			return
-		}
-		h.BytesRecv.Incr(int64(n))
+	parser := influx.NewStreamParser(&countingReader{r: body, stat: h.BytesRecv}, buf)
+	parser.DefaultTime = now
+	parser.Precision = precision
 
+	var return400 bool
+	for {
+		m, err := parser.Next()
 		if err == io.EOF {
-			if return400 {
-				badRequest(res)
-			} else {
-				res.WriteHeader(http.StatusNoContent)
-			}
-			return
+			break
 		}
-
-		if hangingBytes {
-			i := bytes.IndexByte(buf, '\n')
-			if i == -1 {
-				// still didn't find a newline, keep scanning
-				continue
-			}
-			// rotate the bit remaining after the first newline to the front of the buffer
-			i++ // start copying after the newline
-			bufStart = len(buf) - i
-			if bufStart > 0 {
-				copy(buf, buf[i:])
-			}
-			hangingBytes = false
-			continue
-		}
-
-		if err == io.ErrUnexpectedEOF {
-			// finished reading the request body
-			if err := h.parse(buf[:n+bufStart], now, precision); err != nil {
+		if err != nil {
+			if _, ok := err.(*influx.ParseError); !ok {
 				log.Println("E! " + err.Error())
-				return400 = true
-			}
-			if return400 {
+				// problem reading the request body
 				badRequest(res)
-			} else {
-				res.WriteHeader(http.StatusNoContent)
+				return
+			}
+			// Only the first error of the body is logged.
+			if !return400 {
+				log.Println("E! http_listener: " + err.Error())
 			}
-			return
-		}
-
-		// if we got down here it means that we filled our buffer, and there
-		// are still bytes remaining to be read. So we will parse up until the
-		// final newline, then push the rest of the bytes into the next buffer.
-		i := bytes.LastIndexByte(buf, '\n')
-		if i == -1 {
-			// drop any line longer than the max buffer size
-			log.Printf("E! http_listener received a single line longer than the maximum of %d bytes",
-				len(buf))
-			hangingBytes = true
 			return400 = true
-			bufStart = 0
 			continue
 		}
-		if err := h.parse(buf[:i+1], now, precision); err != nil {
-			log.Println("E! " + err.Error())
-			return400 = true
-		}
-		// rotate the bit remaining after the last newline to the front of the buffer
-		i++ // start copying after the newline
-		bufStart = len(buf) - i
-		if bufStart > 0 {
-			copy(buf, buf[i:])
-		}
+		h.acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
 	}
-}
 
-func (h *HTTPListener) parse(b []byte, t time.Time, precision string) error {
-	metrics, err := h.parser.ParseWithDefaultTimePrecision(b, t, precision)
-
-	for _, m := range metrics {
-		h.acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+	if return400 {
+		badRequest(res)
+	} else {
+		res.WriteHeader(http.StatusNoContent)
 	}
+}
+
+// countingReader counts the bytes read in stat.
+type countingReader struct {
+	r    io.Reader
+	stat selfstat.Stat
+}
 
-	return err
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.stat.Incr(int64(n))
+	return n, err
 }
 
 func tooLarge(res http.ResponseWriter) {
@@ -254,6 +254,26 @@ func TestWriteHTTPHighTraffic(t *testing.T) {
 	require.Equal(t, int64(25000), int64(acc.NMetrics()))
 }
 
+// writes a body much larger than the line buffer in a single request
+func TestWriteHTTPLargeBody(t *testing.T) {
+	listener := &HTTPListener{
+		ServiceAddress: ":0",
+		MaxLineSize:    100,
+	}
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	body := bytes.Repeat([]byte(testMsgs), 2000)
+	resp, err := http.Post(createURL(listener, "/write", "db=mydb"), "", bytes.NewBuffer(body))
+	require.NoError(t, err)
+	require.EqualValues(t, 204, resp.StatusCode)
+
+	acc.Wait(10000)
+	require.Equal(t, int64(10000), int64(acc.NMetrics()))
+}
+
 func TestReceive404ForInvalidEndpoint(t *testing.T) {
 	listener := newTestHTTPListener()
 
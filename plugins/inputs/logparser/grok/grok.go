@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -13,6 +15,7 @@ import (
 	"github.com/vjeantet/grok"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/globpath"
 	"github.com/influxdata/telegraf/metric"
 )
 
@@ -65,11 +68,23 @@ type Parser struct {
 	// specified by the user in Patterns.
 	// They will look like:
 	//   GROK_INTERNAL_PATTERN_0, GROK_INTERNAL_PATTERN_1, etc.
-	namedPatterns      []string
-	CustomPatterns     string
+	namedPatterns  []string
+	CustomPatterns string
+	// CustomPatternFiles are the paths of the pattern files, or of the
+	// directories of pattern files, with the glob syntax of the files.
 	CustomPatternFiles []string
 	Measurement        string
 
+	// TimestampLayouts names custom timestamp layouts, the captures with the
+	// ts-<name> modifier are parsed with them. The generic ts modifier tries
+	// them before the built-in layouts.
+	//   ie, {"inverter": "02.01.2006 15:04:05"}
+	TimestampLayouts map[string]string
+	// Timezone is the location of the timestamps without a timezone, ie
+	// "Local" or "Europe/Berlin", UTC if empty.
+	Timezone string
+	loc      *time.Location
+
 	// typeMap is a map of patterns -> capture name -> modifier,
 	//   ie, {
 	//          "%{TESTLOG}":
@@ -111,6 +126,17 @@ func (p *Parser) Compile() error {
 	p.patterns = make(map[string]string)
 	p.tsModder = &tsModder{}
 	var err error
+	p.loc = time.UTC
+	if p.Timezone != "" {
+		if p.loc, err = time.LoadLocation(p.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone %q, %s", p.Timezone, err)
+		}
+	}
+	for name := range p.TimestampLayouts {
+		if _, ok := timeLayouts["ts-"+name]; ok || name == "" {
+			return fmt.Errorf("invalid timestamp layout name %q", name)
+		}
+	}
 	p.g, err = grok.NewWithConfig(&grok.Config{NamedCapturesOnly: true})
 	if err != nil {
 		return err
@@ -141,14 +167,16 @@ func (p *Parser) Compile() error {
 	}
 
 	// Parse any custom pattern files supplied.
-	for _, filename := range p.CustomPatternFiles {
-		file, err := os.Open(filename)
+	for _, path := range p.CustomPatternFiles {
+		filenames, err := patternFiles(path)
 		if err != nil {
 			return err
 		}
-
-		scanner := bufio.NewScanner(bufio.NewReader(file))
-		p.addCustomPatterns(scanner)
+		for _, filename := range filenames {
+			if err := p.addCustomPatternFile(filename); err != nil {
+				return err
+			}
+		}
 	}
 
 	if p.Measurement == "" {
@@ -158,6 +186,61 @@ func (p *Parser) Compile() error {
 	return p.compileCustomPatterns()
 }
 
+// patternFiles returns the pattern files of the path, sorted: the files
+// matching the glob and the files of the matching directories.
+func patternFiles(path string) ([]string, error) {
+	g, err := globpath.Compile(path)
+	if err != nil {
+		return nil, err
+	}
+	matches := g.Match()
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no pattern file matches %s", path)
+	}
+
+	var filenames []string
+	for filename, info := range matches {
+		if !info.IsDir() {
+			filenames = append(filenames, filename)
+			continue
+		}
+		dir, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		infos, err := dir.Readdir(-1)
+		dir.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range infos {
+			if !info.IsDir() && !strings.HasPrefix(info.Name(), ".") {
+				filenames = append(filenames, filepath.Join(filename, info.Name()))
+			}
+		}
+	}
+	sort.Strings(filenames)
+	return filenames, nil
+}
+
+func (p *Parser) addCustomPatternFile(filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(bufio.NewReader(file))
+	p.addCustomPatterns(scanner)
+	return scanner.Err()
+}
+
+// parseTime parses the timestamp with the layout, in the timezone of the
+// parser if it has none.
+func (p *Parser) parseTime(layout, v string) (time.Time, error) {
+	return time.ParseInLocation(layout, v, p.loc)
+}
+
 func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
 	var err error
 	// values are the parsed fields from the log line
@@ -251,18 +334,18 @@ func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
 			var foundTs bool
 			// first try timestamp layouts that we've already found
 			for _, layout := range p.foundTsLayouts {
-				ts, err := time.Parse(layout, v)
+				ts, err := p.parseTime(layout, v)
 				if err == nil {
 					timestamp = ts
 					foundTs = true
 					break
 				}
 			}
-			// if we haven't found a timestamp layout yet, try all timestamp
-			// layouts.
+			// if we haven't found a timestamp layout yet, try the custom
+			// timestamp layouts, then all the built-in layouts.
 			if !foundTs {
-				for _, layout := range timeLayouts {
-					ts, err := time.Parse(layout, v)
+				for _, layout := range p.layouts() {
+					ts, err := p.parseTime(layout, v)
 					if err == nil {
 						timestamp = ts
 						foundTs = true
@@ -280,7 +363,7 @@ func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
 		case DROP:
 		// goodbye!
 		default:
-			ts, err := time.Parse(t, v)
+			ts, err := p.parseTime(t, v)
 			if err == nil {
 				timestamp = ts
 			} else {
@@ -292,6 +375,29 @@ func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
 	return metric.New(p.Measurement, tags, fields, p.tsModder.tsMod(timestamp))
 }
 
+// layouts returns the layouts tried by the generic ts modifier, the custom
+// layouts first, sorted by name.
+func (p *Parser) layouts() []string {
+	names := make([]string, 0, len(p.TimestampLayouts))
+	for name := range p.TimestampLayouts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	layouts := make([]string, 0, len(names)+len(timeLayouts))
+	for _, name := range names {
+		layouts = append(layouts, p.TimestampLayouts[name])
+	}
+	for _, layout := range timeLayouts {
+		switch layout {
+		case EPOCH, EPOCH_NANO, GENERIC_TIMESTAMP:
+		default:
+			layouts = append(layouts, layout)
+		}
+	}
+	return layouts
+}
+
 func (p *Parser) addCustomPatterns(scanner *bufio.Scanner) {
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
@@ -361,6 +467,9 @@ func (p *Parser) parseTypedCaptures(name, pattern string) (string, error) {
 			if layout, ok := timeLayouts[match[2]]; ok {
 				// built-in time format
 				p.tsMap[patternName][match[1]] = layout
+			} else if layout, ok := p.TimestampLayouts[strings.TrimPrefix(match[2], "ts-")]; ok {
+				// named custom time format
+				p.tsMap[patternName][match[1]] = layout
 			} else {
 				// custom time format
 				p.tsMap[patternName][match[1]] = strings.TrimSuffix(strings.TrimPrefix(match[2], `ts-"`), `"`)
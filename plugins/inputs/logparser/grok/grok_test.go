@@ -1,6 +1,9 @@
 package grok
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -707,3 +710,86 @@ func TestShortPatternRegression(t *testing.T) {
 		},
 		metric.Fields())
 }
+
+func TestNamedTimestampLayouts(t *testing.T) {
+	p := &Parser{
+		Patterns: []string{"%{DATALOGGER_TS:timestamp:ts-datalogger} P=%{NUMBER:power:float}"},
+		CustomPatterns: `
+			DATALOGGER_TS %{MONTHDAY}\.%{MONTHNUM}\.%{YEAR} %{HOUR}:%{MINUTE}:%{SECOND}
+		`,
+		TimestampLayouts: map[string]string{"datalogger": "02.01.2006 15:04:05"},
+		Timezone:         "Europe/Berlin",
+	}
+	require.NoError(t, p.Compile())
+
+	m, err := p.ParseLine(`01.06.2017 14:00:00 P=1530.5`)
+	require.NoError(t, err)
+	require.NotNil(t, m)
+	assert.Equal(t, map[string]interface{}{"power": 1530.5}, m.Fields())
+	assert.Equal(t, time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC).UnixNano(),
+		m.Time().UnixNano())
+}
+
+func TestGenericTimestampCustomLayouts(t *testing.T) {
+	p := &Parser{
+		Patterns: []string{"%{GREEDYDATA:timestamp:ts};%{NUMBER:power:float}"},
+		TimestampLayouts: map[string]string{
+			"inverter":   "2006/01/02 15:04",
+			"datalogger": "02.01.2006 15:04:05",
+		},
+	}
+	require.NoError(t, p.Compile())
+
+	for line, want := range map[string]time.Time{
+		"01.06.2017 12:00:00;1": time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC),
+		"2017/06/01 12:05;2":    time.Date(2017, 6, 1, 12, 5, 0, 0, time.UTC),
+		// built-in layout
+		"2017-06-01T12:10:00Z;3": time.Date(2017, 6, 1, 12, 10, 0, 0, time.UTC),
+	} {
+		m, err := p.ParseLine(line)
+		require.NoError(t, err)
+		require.NotNil(t, m, line)
+		assert.Equal(t, want.UnixNano(), m.Time().UnixNano(), line)
+	}
+}
+
+func TestTimestampLayoutErrors(t *testing.T) {
+	p := &Parser{
+		Patterns: []string{"%{NUMBER:value:int}"},
+		Timezone: "Mars/Olympus",
+	}
+	assert.Error(t, p.Compile())
+
+	p = &Parser{
+		Patterns:         []string{"%{NUMBER:value:int}"},
+		TimestampLayouts: map[string]string{"httpd": "2006"},
+	}
+	assert.Error(t, p.Compile())
+}
+
+func TestCompilePatternDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestCompilePatternDirectory")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "inverter"),
+		[]byte("INVERTER_LOG %{WORD:inverter:tag} %{NUMBER:power:float}\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, ".hidden"),
+		[]byte("not a pattern\n"), 0644))
+
+	p := &Parser{
+		Patterns:           []string{"%{INVERTER_LOG}", "%{TEST_LOG_B}"},
+		CustomPatternFiles: []string{dir, "./testdata/test-*"},
+	}
+	require.NoError(t, p.Compile())
+
+	m, err := p.ParseLine(`inv1 1530.5`)
+	require.NoError(t, err)
+	require.NotNil(t, m)
+	assert.Equal(t, map[string]string{"inverter": "inv1"}, m.Tags())
+
+	p = &Parser{
+		Patterns:           []string{"%{TEST_LOG_B}"},
+		CustomPatternFiles: []string{"./testdata/missing-*"},
+	}
+	assert.Error(t, p.Compile())
+}
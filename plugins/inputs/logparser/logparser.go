@@ -5,6 +5,7 @@ import (
 	"log"
 	"reflect"
 	"sync"
+	"time"
 
 	"github.com/influxdata/tail"
 
@@ -35,6 +36,7 @@ type LogParserPlugin struct {
 	sync.Mutex
 
 	GrokParser *grok.Parser `toml:"grok"`
+	Multiline  *Multiline
 }
 
 const sampleConfig = `
@@ -67,6 +69,25 @@ const sampleConfig = `
     ## Custom patterns can also be defined here. Put one pattern per line.
     custom_patterns = '''
     '''
+    ## Timezone of the timestamps without a timezone, ie "Local" or
+    ## "Europe/Berlin", UTC if empty.
+    # timezone = ""
+    ## Named timestamp layouts, used with the ts-<name> modifier. The generic
+    ## ts modifier tries them first.
+    # [inputs.logparser.grok.timestamp_layouts]
+    #   datalogger = "02.01.2006 15:04:05"
+
+  ## Join the lines of the messages spanning several lines, ie the stack
+  ## traces, before parsing them.
+  # [inputs.logparser.multiline]
+  #   ## The lines matching the pattern are joined to the previous line, or to
+  #   ## the next one if match_which_line = "next".
+  #   pattern = '^\s'
+  #   match_which_line = "previous"
+  #   ## Join the lines not matching the pattern instead.
+  #   invert_match = false
+  #   ## Time after which a message is parsed if no other line is read.
+  #   timeout = "5s"
 `
 
 func (l *LogParserPlugin) SampleConfig() string {
@@ -122,6 +143,11 @@ func (l *LogParserPlugin) Start(acc telegraf.Accumulator) error {
 			return err
 		}
 	}
+	if l.Multiline != nil {
+		if err := l.Multiline.Compile(); err != nil {
+			return err
+		}
+	}
 
 	l.wg.Add(1)
 	go l.parser()
@@ -177,6 +203,11 @@ func (l *LogParserPlugin) tailNewfiles(fromBeginning bool) error {
 func (l *LogParserPlugin) receiver(tailer *tail.Tail) {
 	defer l.wg.Done()
 
+	if l.Multiline != nil {
+		l.multilineReceiver(tailer)
+		return
+	}
+
 	var line *tail.Line
 	for line = range tailer.Lines {
 
@@ -186,13 +217,48 @@ func (l *LogParserPlugin) receiver(tailer *tail.Tail) {
 			continue
 		}
 
+		l.send(line.Text)
+	}
+}
+
+// multilineReceiver sends the messages joined from the lines of the logfile,
+// the last message once no other line is read before the timeout.
+func (l *LogParserPlugin) multilineReceiver(tailer *tail.Tail) {
+	buffer := &multilineBuffer{Multiline: l.Multiline}
+	ticker := time.NewTicker(l.Multiline.Timeout.Duration)
+	defer ticker.Stop()
+
+	for {
 		select {
-		case <-l.done:
-		case l.lines <- line.Text:
+		case line, ok := <-tailer.Lines:
+			if !ok {
+				l.send(buffer.flush())
+				return
+			}
+			if line.Err != nil {
+				log.Printf("E! Error tailing file %s, Error: %s\n",
+					tailer.Filename, line.Err)
+				continue
+			}
+			l.send(buffer.add(line.Text))
+		case <-ticker.C:
+			if time.Since(buffer.last) >= l.Multiline.Timeout.Duration {
+				l.send(buffer.flush())
+			}
 		}
 	}
 }
 
+func (l *LogParserPlugin) send(line string) {
+	if line == "" {
+		return
+	}
+	select {
+	case <-l.done:
+	case l.lines <- line:
+	}
+}
+
 // parser is launched as a goroutine to watch the l.lines channel.
 // when a line is available, parser parses it and adds the metric(s) to the
 // accumulator.
@@ -6,7 +6,9 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/testutil"
 
 	"github.com/influxdata/telegraf/plugins/inputs/logparser/grok"
@@ -153,6 +155,66 @@ func TestGrokParseLogFilesOneBad(t *testing.T) {
 		map[string]string{"response_code": "200"})
 }
 
+func TestGrokParseMultiline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestGrokParseMultiline")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, ioutil.WriteFile(dir+"/app.log", []byte(`2017-06-01 12:00:00 ERROR failed
+java.lang.NullPointerException
+	at com.example.Inverter.read(Inverter.java:42)
+2017-06-01 12:00:01 INFO recovered
+`), 0644))
+
+	p := &grok.Parser{
+		Patterns: []string{`(?s)%{TIMESTAMP_ISO8601:timestamp:ts-"2006-01-02 15:04:05"} %{LOGLEVEL:level:tag} %{GREEDYDATA:message}`},
+	}
+	logparser := &LogParserPlugin{
+		FromBeginning: true,
+		Files:         []string{dir + "/app.log"},
+		GrokParser:    p,
+		Multiline: &Multiline{
+			Pattern:     `^\d{4}-`,
+			InvertMatch: true,
+			Timeout:     internal.Duration{Duration: 10 * time.Millisecond},
+		},
+	}
+
+	acc := testutil.Accumulator{}
+	assert.NoError(t, logparser.Start(&acc))
+	acc.Wait(2)
+	logparser.Stop()
+
+	acc.AssertContainsTaggedFields(t, "logparser_grok",
+		map[string]interface{}{
+			"message": "failed\njava.lang.NullPointerException\n\tat com.example.Inverter.read(Inverter.java:42)",
+		},
+		map[string]string{"level": "ERROR"})
+	// The last message is parsed after the timeout
+	acc.AssertContainsTaggedFields(t, "logparser_grok",
+		map[string]interface{}{"message": "recovered"},
+		map[string]string{"level": "INFO"})
+}
+
+func TestMultilineNext(t *testing.T) {
+	m := &Multiline{Pattern: `\\$`, MatchWhichLine: "next"}
+	assert.NoError(t, m.Compile())
+	b := &multilineBuffer{Multiline: m}
+
+	assert.Equal(t, "", b.add(`a \`))
+	assert.Equal(t, "", b.add(`b \`))
+	assert.Equal(t, "a \\\nb \\\nc", b.add("c"))
+	assert.Equal(t, "d", b.add("d"))
+	assert.Equal(t, "", b.flush())
+
+	for _, m := range []*Multiline{
+		{},
+		{Pattern: "("},
+		{Pattern: "^ ", MatchWhichLine: "last"},
+	} {
+		assert.Error(t, m.Compile())
+	}
+}
+
 func getCurrentDir() string {
 	_, filename, _, _ := runtime.Caller(1)
 	return strings.Replace(filename, "logparser_test.go", "", 1)
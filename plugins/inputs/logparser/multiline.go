@@ -0,0 +1,94 @@
+package logparser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+)
+
+const (
+	previous = "previous"
+	next     = "next"
+)
+
+// Multiline joins the lines of the messages spanning several lines, ie the
+// stack traces, before they are parsed.
+type Multiline struct {
+	// Pattern matches the lines joined to the previous or to the next line,
+	// according to MatchWhichLine, "previous" by default. With InvertMatch
+	// the lines not matching the pattern are joined.
+	Pattern        string
+	MatchWhichLine string
+	InvertMatch    bool
+	// Timeout is the time after which a message is parsed if no other line
+	// is read, 5s by default.
+	Timeout internal.Duration
+
+	re *regexp.Regexp
+}
+
+func (m *Multiline) Compile() error {
+	if m.Pattern == "" {
+		return fmt.Errorf("multiline pattern required")
+	}
+	var err error
+	if m.re, err = regexp.Compile(m.Pattern); err != nil {
+		return fmt.Errorf("invalid multiline pattern %q, %s", m.Pattern, err)
+	}
+	switch m.MatchWhichLine {
+	case "":
+		m.MatchWhichLine = previous
+	case previous, next:
+	default:
+		return fmt.Errorf("invalid multiline match_which_line %q", m.MatchWhichLine)
+	}
+	if m.Timeout.Duration <= 0 {
+		m.Timeout.Duration = 5 * time.Second
+	}
+	return nil
+}
+
+// joined reports whether the line is joined to another.
+func (m *Multiline) joined(line string) bool {
+	return m.re.MatchString(line) != m.InvertMatch
+}
+
+// multilineBuffer is the message being read from a file.
+type multilineBuffer struct {
+	*Multiline
+	lines []string
+	// last is the time the last line was read.
+	last time.Time
+}
+
+// add adds a line and returns the previous message, or the message of the
+// line, if it is complete. The lines are joined with newlines.
+func (b *multilineBuffer) add(line string) string {
+	b.last = time.Now()
+	joined := b.joined(line)
+	if b.MatchWhichLine == next {
+		b.lines = append(b.lines, line)
+		if joined {
+			return ""
+		}
+		return b.flush()
+	}
+
+	if joined && len(b.lines) > 0 {
+		b.lines = append(b.lines, line)
+		return ""
+	}
+	message := b.flush()
+	b.lines = append(b.lines, line)
+	return message
+}
+
+// flush returns the message read and empties the buffer.
+func (b *multilineBuffer) flush() string {
+	message := strings.Join(b.lines, "\n")
+	b.lines = b.lines[:0]
+	return message
+}
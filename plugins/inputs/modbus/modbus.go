@@ -0,0 +1,159 @@
+package modbus
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/modbus"
+	"github.com/influxdata/telegraf/internal/serial"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+type Modbus struct {
+	Name     string            `toml:"name"`
+	Port     string            `toml:"port"`
+	BaudRate int               `toml:"baud_rate"`
+	DataBits int               `toml:"data_bits"`
+	Parity   string            `toml:"parity"`
+	StopBits int               `toml:"stop_bits"`
+	Timeout  internal.Duration `toml:"timeout"`
+
+	SlaveIDs  []int              `toml:"slave_ids"`
+	Registers []*modbus.Register `toml:"registers"`
+
+	port serial.Port
+}
+
+var sampleConfig = `
+  ## Name of the device, added as the "name" tag.
+  name = "meter"
+
+  ## Serial port the RS-485 adapter is connected to and its settings.
+  port = "/dev/ttyUSB0"
+  # baud_rate = 9600
+  # data_bits = 8
+  ## Parity is "N" (none), "E" (even) or "O" (odd).
+  # parity = "N"
+  # stop_bits = 1
+
+  ## Amount of time to wait for a slave to respond.
+  # timeout = "1s"
+
+  ## Modbus addresses of the slaves to poll.
+  slave_ids = [1]
+
+  ## Registers to read from each slave.
+  ##   type       - "coil", "discrete_input", "holding" or "input"
+  ##   address    - zero based register address
+  ##   data_type  - "INT16", "UINT16", "INT32", "UINT32", "INT64", "UINT64",
+  ##                "FLOAT32" or "FLOAT64", ignored for coils and discrete inputs
+  ##   byte_order - "ABCD" (big endian), "CDAB", "BADC" or "DCBA" (little
+  ##                endian), only relevant for values spanning several registers
+  ##   scale      - optional factor applied to the value, creates a float field
+  [[inputs.modbus.registers]]
+    name = "voltage"
+    type = "input"
+    address = 0
+    data_type = "FLOAT32"
+    byte_order = "ABCD"
+
+  [[inputs.modbus.registers]]
+    name = "energy_total"
+    type = "holding"
+    address = 100
+    data_type = "UINT32"
+    scale = 0.01
+`
+
+func (m *Modbus) SampleConfig() string {
+	return sampleConfig
+}
+
+func (m *Modbus) Description() string {
+	return "Read registers from Modbus RTU devices over a serial line"
+}
+
+func (m *Modbus) validate() error {
+	if len(m.SlaveIDs) == 0 {
+		return fmt.Errorf("at least one slave id must be configured")
+	}
+	for _, id := range m.SlaveIDs {
+		if id < 1 || id > 247 {
+			return fmt.Errorf("invalid slave id %d", id)
+		}
+	}
+	for _, r := range m.Registers {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Modbus) Gather(acc telegraf.Accumulator) error {
+	if m.port == nil {
+		if err := m.validate(); err != nil {
+			return err
+		}
+		port, err := serial.Open(&serial.Config{
+			Port:     m.Port,
+			BaudRate: m.BaudRate,
+			DataBits: m.DataBits,
+			Parity:   m.Parity,
+			StopBits: m.StopBits,
+			Timeout:  m.Timeout.Duration,
+		})
+		if err != nil {
+			return err
+		}
+		m.port = port
+	}
+
+	for _, id := range m.SlaveIDs {
+		fields := make(map[string]interface{})
+		for _, r := range m.Registers {
+			value, err := m.readRegister(byte(id), r)
+			if err != nil {
+				acc.AddError(fmt.Errorf("[slave_id=%d register=%s]: %s", id, r.Name, err))
+				continue
+			}
+			fields[r.Name] = value
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		tags := map[string]string{
+			"slave_id": strconv.Itoa(id),
+		}
+		if m.Name != "" {
+			tags["name"] = m.Name
+		}
+		acc.AddFields("modbus", fields, tags)
+	}
+	return nil
+}
+
+func (m *Modbus) readRegister(slave byte, r *modbus.Register) (interface{}, error) {
+	// Discard the remains of any previous, timed out, response.
+	if err := m.port.Flush(); err != nil {
+		return nil, err
+	}
+
+	data, err := modbus.ReadRTU(m.port, slave, r.Function(), uint16(r.Address), r.Quantity())
+	if err != nil {
+		return nil, err
+	}
+	return r.Decode(data)
+}
+
+func init() {
+	inputs.Add("modbus", func() telegraf.Input {
+		return &Modbus{
+			Timeout: internal.Duration{Duration: time.Second},
+		}
+	})
+}
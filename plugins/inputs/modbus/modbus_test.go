@@ -0,0 +1,155 @@
+package modbus
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/influxdata/telegraf/internal/modbus"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSlave answers Modbus RTU read requests from its register tables.
+type fakeSlave struct {
+	id        byte
+	registers map[uint16]uint16
+	coils     map[uint16]bool
+	response  bytes.Buffer
+}
+
+func (s *fakeSlave) Write(b []byte) (int, error) {
+	if len(b) != 8 || modbus.CRC16(b[:6]) != binary.LittleEndian.Uint16(b[6:]) {
+		return 0, fmt.Errorf("invalid request %x", b)
+	}
+	if b[0] != s.id {
+		// Other slaves stay silent.
+		return len(b), nil
+	}
+
+	function := b[1]
+	address := binary.BigEndian.Uint16(b[2:])
+	quantity := binary.BigEndian.Uint16(b[4:])
+
+	frame := []byte{s.id, function}
+	switch function {
+	case modbus.FuncReadCoils, modbus.FuncReadDiscreteInputs:
+		v, ok := s.coils[address]
+		if !ok {
+			frame = append([]byte{s.id, function | 0x80}, 0x02)
+			break
+		}
+		var data byte
+		if v {
+			data = 1
+		}
+		frame = append(frame, 1, data)
+	case modbus.FuncReadHoldingRegisters, modbus.FuncReadInputRegisters:
+		frame = append(frame, byte(quantity*2))
+		for i := uint16(0); i < quantity; i++ {
+			v, ok := s.registers[address+i]
+			if !ok {
+				frame = append([]byte{s.id, function | 0x80}, 0x02)
+				break
+			}
+			frame = append(frame, byte(v>>8), byte(v))
+		}
+	}
+	crc := modbus.CRC16(frame)
+	s.response.Write(append(frame, byte(crc), byte(crc>>8)))
+	return len(b), nil
+}
+
+func (s *fakeSlave) Read(b []byte) (int, error) {
+	if s.response.Len() == 0 {
+		return 0, fmt.Errorf("timeout")
+	}
+	return s.response.Read(b)
+}
+
+func (s *fakeSlave) Flush() error {
+	s.response.Reset()
+	return nil
+}
+
+func (s *fakeSlave) Close() error {
+	return nil
+}
+
+func TestGather(t *testing.T) {
+	m := &Modbus{
+		Name:     "meter",
+		SlaveIDs: []int{1, 2},
+		Registers: []*modbus.Register{
+			{Name: "voltage", Type: "input", Address: 0, DataType: "FLOAT32"},
+			{Name: "energy_total", Type: "holding", Address: 100, DataType: "uint32",
+				ByteOrder: "cdab", Scale: 0.01},
+			{Name: "temperature", Type: "holding", Address: 200, DataType: "INT16"},
+			{Name: "relay", Type: "coil", Address: 5},
+		},
+		port: &fakeSlave{
+			id: 1,
+			registers: map[uint16]uint16{
+				0:   0x4366, // 230.4 as FLOAT32
+				1:   0x6666,
+				100: 0x86A0, // 100000 with the low word first
+				101: 0x0001,
+				200: 0xFFF6,
+			},
+			coils: map[uint16]bool{5: true},
+		},
+	}
+	require.NoError(t, m.validate())
+
+	var acc testutil.Accumulator
+	require.NoError(t, m.Gather(&acc))
+
+	// Slave 2 does not respond.
+	assert.Len(t, acc.Errors, 4)
+	require.Len(t, acc.Metrics, 1)
+	acc.AssertContainsTaggedFields(t, "modbus",
+		map[string]interface{}{
+			"voltage":      float64(float32(230.4)),
+			"energy_total": float64(1000),
+			"temperature":  int64(-10),
+			"relay":        true,
+		},
+		map[string]string{
+			"name":     "meter",
+			"slave_id": "1",
+		})
+}
+
+func TestGatherException(t *testing.T) {
+	m := &Modbus{
+		SlaveIDs: []int{1},
+		Registers: []*modbus.Register{
+			{Name: "missing", Type: "holding", Address: 42, DataType: "UINT16"},
+		},
+		port: &fakeSlave{id: 1},
+	}
+	require.NoError(t, m.validate())
+
+	var acc testutil.Accumulator
+	require.NoError(t, m.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	assert.Contains(t, acc.Errors[0].Error(), "illegal data address")
+	assert.Len(t, acc.Metrics, 0)
+}
+
+func TestValidate(t *testing.T) {
+	var tests = []*Modbus{
+		{},
+		{SlaveIDs: []int{0}},
+		{SlaveIDs: []int{1}, Registers: []*modbus.Register{{Name: "a", Type: "bad"}}},
+		{SlaveIDs: []int{1}, Registers: []*modbus.Register{{Name: "a", Type: "input", DataType: "BCD"}}},
+		{SlaveIDs: []int{1}, Registers: []*modbus.Register{{Type: "input", DataType: "INT16"}}},
+		{SlaveIDs: []int{1}, Registers: []*modbus.Register{
+			{Name: "a", Type: "input", DataType: "INT32", ByteOrder: "ABDC"}}},
+	}
+	for _, m := range tests {
+		assert.Error(t, m.validate())
+	}
+}
@@ -15,6 +15,12 @@ import (
 	"github.com/eclipse/paho.mqtt.golang"
 )
 
+// topicParser is implemented by parsers that need to know the topic a
+// message was published on, such as the sparkplug_b data format.
+type topicParser interface {
+	ParseWithTopic(topic string, buf []byte) ([]telegraf.Metric, error)
+}
+
 type MQTTConsumer struct {
 	Servers  []string
 	Topics   []string
@@ -164,7 +170,13 @@ func (m *MQTTConsumer) receiver() {
 			return
 		case msg := <-m.in:
 			topic := msg.Topic()
-			metrics, err := m.parser.Parse(msg.Payload())
+			var metrics []telegraf.Metric
+			var err error
+			if tp, ok := m.parser.(topicParser); ok {
+				metrics, err = tp.ParseWithTopic(topic, msg.Payload())
+			} else {
+				metrics, err = m.parser.Parse(msg.Payload())
+			}
 			if err != nil {
 				m.acc.AddError(fmt.Errorf("E! MQTT Parse Error\nmessage: %s\nerror: %s",
 					string(msg.Payload()), err.Error()))
@@ -0,0 +1,276 @@
+package power_prices
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	awattarURL = "https://api.awattar.%s/v1/marketdata"
+	entsoeURL  = "https://web-api.tp.entsoe.eu/api"
+)
+
+type PowerPrices struct {
+	Provider    string            `toml:"provider"`
+	BiddingZone string            `toml:"bidding_zone"`
+	APIToken    string            `toml:"api_token"`
+	URL         string            `toml:"url"`
+	Timeout     internal.Duration `toml:"timeout"`
+
+	client *http.Client
+}
+
+// price is the price of electricity for one delivery period.
+type price struct {
+	start    time.Time
+	end      time.Time
+	value    float64
+	currency string
+	unit     string
+}
+
+var sampleConfig = `
+  ## Source of the prices, either "awattar" or "entsoe".
+  provider = "entsoe"
+
+  ## Bidding zone to read the prices of. For aWATTar this is "DE" or "AT",
+  ## for ENTSO-E the EIC code of the zone, ie "10Y1001A1001A82H" (DE-LU).
+  bidding_zone = "10Y1001A1001A82H"
+
+  ## Security token of the ENTSO-E transparency platform, not used by aWATTar.
+  # api_token = ""
+
+  ## Override the API URL of the provider.
+  # url = ""
+
+  ## Amount of time allowed to complete the HTTP request
+  # timeout = "10s"
+
+  ## Day-ahead prices are published once a day, no need to poll them often.
+  interval = "1h"
+`
+
+func (p *PowerPrices) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *PowerPrices) Description() string {
+	return "Read day-ahead electricity prices from aWATTar or ENTSO-E"
+}
+
+func (p *PowerPrices) Gather(acc telegraf.Accumulator) error {
+	if p.client == nil {
+		p.client = &http.Client{
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+			},
+			Timeout: p.Timeout.Duration,
+		}
+	}
+
+	// Request everything from the start of today until the end of tomorrow,
+	// which includes the next day once it was published.
+	now := time.Now().UTC()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	end := start.Add(48 * time.Hour)
+
+	var prices []price
+	var err error
+	switch p.Provider {
+	case "awattar":
+		prices, err = p.fetchAwattar(start, end)
+	case "entsoe":
+		prices, err = p.fetchEntsoe(start, end)
+	default:
+		return fmt.Errorf("unsupported provider %q", p.Provider)
+	}
+	if err != nil {
+		return fmt.Errorf("[provider=%s]: %s", p.Provider, err)
+	}
+
+	for _, pr := range prices {
+		tags := map[string]string{
+			"provider":     p.Provider,
+			"bidding_zone": p.BiddingZone,
+			"currency":     pr.currency,
+			"unit":         pr.unit,
+		}
+		fields := map[string]interface{}{
+			"price":    pr.value,
+			"duration": int64(pr.end.Sub(pr.start).Seconds()),
+		}
+		acc.AddFields("power_prices", fields, tags, pr.start)
+	}
+	return nil
+}
+
+func (p *PowerPrices) get(u string, v interface{}, decode func(*http.Response, interface{}) error) error {
+	resp, err := p.client.Get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("received status code %d (%s), expected %d (%s)",
+			resp.StatusCode, http.StatusText(resp.StatusCode),
+			http.StatusOK, http.StatusText(http.StatusOK))
+	}
+	return decode(resp, v)
+}
+
+type awattarResponse struct {
+	Data []struct {
+		StartTimestamp int64   `json:"start_timestamp"`
+		EndTimestamp   int64   `json:"end_timestamp"`
+		MarketPrice    float64 `json:"marketprice"`
+		Unit           string  `json:"unit"`
+	} `json:"data"`
+}
+
+func (p *PowerPrices) fetchAwattar(start, end time.Time) ([]price, error) {
+	base := p.URL
+	if base == "" {
+		base = fmt.Sprintf(awattarURL, strings.ToLower(p.BiddingZone))
+	}
+	params := url.Values{}
+	params.Set("start", strconv.FormatInt(start.UnixNano()/int64(time.Millisecond), 10))
+	params.Set("end", strconv.FormatInt(end.UnixNano()/int64(time.Millisecond), 10))
+
+	var r awattarResponse
+	err := p.get(base+"?"+params.Encode(), &r, func(resp *http.Response, v interface{}) error {
+		return json.NewDecoder(resp.Body).Decode(v)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	prices := make([]price, 0, len(r.Data))
+	for _, d := range r.Data {
+		// The unit has the form "Eur/MWh".
+		currency, unit := d.Unit, ""
+		if i := strings.Index(d.Unit, "/"); i != -1 {
+			currency, unit = d.Unit[:i], d.Unit[i+1:]
+		}
+		prices = append(prices, price{
+			start:    time.Unix(0, d.StartTimestamp*int64(time.Millisecond)),
+			end:      time.Unix(0, d.EndTimestamp*int64(time.Millisecond)),
+			value:    d.MarketPrice,
+			currency: strings.ToUpper(currency),
+			unit:     unit,
+		})
+	}
+	return prices, nil
+}
+
+// entsoeDocument is the Publication_MarketDocument returned for day-ahead
+// price (A44) requests.
+type entsoeDocument struct {
+	TimeSeries []struct {
+		Currency string `xml:"currency_Unit.name"`
+		Unit     string `xml:"price_Measure_Unit.name"`
+		Period   []struct {
+			Start      string `xml:"timeInterval>start"`
+			Resolution string `xml:"resolution"`
+			Points     []struct {
+				Position int     `xml:"position"`
+				Price    float64 `xml:"price.amount"`
+			} `xml:"Point"`
+		} `xml:"Period"`
+	} `xml:"TimeSeries"`
+}
+
+// entsoeAcknowledgement is returned instead of a market document on errors
+// or when no data is available.
+type entsoeAcknowledgement struct {
+	XMLName xml.Name `xml:"Acknowledgement_MarketDocument"`
+	Text    string   `xml:"Reason>text"`
+}
+
+func (p *PowerPrices) fetchEntsoe(start, end time.Time) ([]price, error) {
+	if p.APIToken == "" {
+		return nil, fmt.Errorf("api_token is required")
+	}
+	base := p.URL
+	if base == "" {
+		base = entsoeURL
+	}
+	params := url.Values{}
+	params.Set("securityToken", p.APIToken)
+	params.Set("documentType", "A44")
+	params.Set("in_Domain", p.BiddingZone)
+	params.Set("out_Domain", p.BiddingZone)
+	params.Set("periodStart", start.Format("200601021504"))
+	params.Set("periodEnd", end.Format("200601021504"))
+
+	var doc entsoeDocument
+	err := p.get(base+"?"+params.Encode(), &doc, func(resp *http.Response, v interface{}) error {
+		buf, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		var ack entsoeAcknowledgement
+		if xml.Unmarshal(buf, &ack) == nil {
+			return fmt.Errorf("request rejected: %s", ack.Text)
+		}
+		return xml.Unmarshal(buf, v)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var prices []price
+	for _, ts := range doc.TimeSeries {
+		for _, period := range ts.Period {
+			periodStart, err := time.Parse("2006-01-02T15:04Z", period.Start)
+			if err != nil {
+				return nil, fmt.Errorf("invalid period start %q", period.Start)
+			}
+			resolution, err := parseResolution(period.Resolution)
+			if err != nil {
+				return nil, err
+			}
+			for _, pt := range period.Points {
+				s := periodStart.Add(time.Duration(pt.Position-1) * resolution)
+				prices = append(prices, price{
+					start:    s,
+					end:      s.Add(resolution),
+					value:    pt.Price,
+					currency: ts.Currency,
+					unit:     ts.Unit,
+				})
+			}
+		}
+	}
+	return prices, nil
+}
+
+// parseResolution converts ISO 8601 durations like PT60M or PT15M.
+func parseResolution(s string) (time.Duration, error) {
+	if strings.HasPrefix(s, "PT") && strings.HasSuffix(s, "M") {
+		n, err := strconv.Atoi(s[2 : len(s)-1])
+		if err == nil && n > 0 {
+			return time.Duration(n) * time.Minute, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported resolution %q", s)
+}
+
+func init() {
+	inputs.Add("power_prices", func() telegraf.Input {
+		return &PowerPrices{
+			Timeout: internal.Duration{Duration: time.Second * 10},
+		}
+	})
+}
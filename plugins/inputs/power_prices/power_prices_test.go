@@ -0,0 +1,135 @@
+package power_prices
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const awattarResult = `
+{
+  "object": "list",
+  "data": [
+    {"start_timestamp": 1496268000000, "end_timestamp": 1496271600000, "marketprice": 31.2, "unit": "Eur/MWh"},
+    {"start_timestamp": 1496271600000, "end_timestamp": 1496275200000, "marketprice": 29.87, "unit": "Eur/MWh"}
+  ],
+  "url": "/at/v1/marketdata"
+}
+`
+
+const entsoeResult = `<?xml version="1.0" encoding="UTF-8"?>
+<Publication_MarketDocument xmlns="urn:iec62325.351:tc57wg16:451-3:publicationdocument:7:0">
+  <mRID>1</mRID>
+  <type>A44</type>
+  <TimeSeries>
+    <mRID>1</mRID>
+    <currency_Unit.name>EUR</currency_Unit.name>
+    <price_Measure_Unit.name>MWH</price_Measure_Unit.name>
+    <Period>
+      <timeInterval>
+        <start>2017-05-31T22:00Z</start>
+        <end>2017-06-01T22:00Z</end>
+      </timeInterval>
+      <resolution>PT60M</resolution>
+      <Point><position>1</position><price.amount>31.20</price.amount></Point>
+      <Point><position>2</position><price.amount>29.87</price.amount></Point>
+    </Period>
+  </TimeSeries>
+</Publication_MarketDocument>
+`
+
+const entsoeNoData = `<?xml version="1.0" encoding="UTF-8"?>
+<Acknowledgement_MarketDocument xmlns="urn:iec62325.351:tc57wg16:451-1:acknowledgementdocument:7:0">
+  <Reason>
+    <code>999</code>
+    <text>No matching data found</text>
+  </Reason>
+</Acknowledgement_MarketDocument>
+`
+
+func TestGatherAwattar(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NotEmpty(t, r.URL.Query().Get("start"))
+		assert.NotEmpty(t, r.URL.Query().Get("end"))
+		w.Write([]byte(awattarResult))
+	}))
+	defer ts.Close()
+
+	p := &PowerPrices{Provider: "awattar", BiddingZone: "AT", URL: ts.URL}
+
+	var acc testutil.Accumulator
+	require.NoError(t, p.Gather(&acc))
+	require.Len(t, acc.Metrics, 2)
+
+	tags := map[string]string{
+		"provider":     "awattar",
+		"bidding_zone": "AT",
+		"currency":     "EUR",
+		"unit":         "MWh",
+	}
+	acc.AssertContainsTaggedFields(t, "power_prices",
+		map[string]interface{}{"price": float64(31.2), "duration": int64(3600)}, tags)
+	assert.Equal(t, int64(1496268000000000000), acc.Metrics[0].Time.UnixNano())
+}
+
+func TestGatherEntsoe(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		assert.Equal(t, "token", q.Get("securityToken"))
+		assert.Equal(t, "A44", q.Get("documentType"))
+		assert.Equal(t, "10YAT-APG------L", q.Get("in_Domain"))
+		w.Write([]byte(entsoeResult))
+	}))
+	defer ts.Close()
+
+	p := &PowerPrices{
+		Provider:    "entsoe",
+		BiddingZone: "10YAT-APG------L",
+		APIToken:    "token",
+		URL:         ts.URL,
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, p.Gather(&acc))
+	require.Len(t, acc.Metrics, 2)
+
+	acc.AssertContainsTaggedFields(t, "power_prices",
+		map[string]interface{}{"price": float64(31.2), "duration": int64(3600)},
+		map[string]string{
+			"provider":     "entsoe",
+			"bidding_zone": "10YAT-APG------L",
+			"currency":     "EUR",
+			"unit":         "MWH",
+		})
+	assert.Equal(t,
+		time.Date(2017, 5, 31, 23, 0, 0, 0, time.UTC).UnixNano(),
+		acc.Metrics[1].Time.UnixNano())
+}
+
+func TestGatherEntsoeNoData(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(entsoeNoData))
+	}))
+	defer ts.Close()
+
+	p := &PowerPrices{Provider: "entsoe", BiddingZone: "X", APIToken: "token", URL: ts.URL}
+
+	var acc testutil.Accumulator
+	err := p.Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "No matching data found")
+}
+
+func TestParseResolution(t *testing.T) {
+	d, err := parseResolution("PT15M")
+	require.NoError(t, err)
+	assert.Equal(t, 15*time.Minute, d)
+
+	_, err = parseResolution("P1D")
+	assert.Error(t, err)
+}
@@ -0,0 +1,130 @@
+package rest
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// pathStep is a single step of a compiled JSONPath expression.
+type pathStep struct {
+	// key selects a member of an object, if index is not used.
+	key string
+	// index selects an element of an array when key is empty.
+	index int
+	// wildcard selects all members of an object or elements of an array.
+	wildcard bool
+}
+
+// jsonPath is a compiled JSONPath expression. Only the subset of JSONPath
+// needed to address values in API responses is supported: "$" for the root
+// (or current) element, ".key" or "['key']" for a member of an object, "[n]"
+// for an element of an array where negative indexes count from the end, and
+// "[*]" or ".*" for all elements of an array or members of an object.
+type jsonPath []pathStep
+
+func compilePath(expr string) (jsonPath, error) {
+	s := strings.TrimSpace(expr)
+	if !strings.HasPrefix(s, "$") {
+		return nil, fmt.Errorf("invalid JSONPath %q: must start with '$'", expr)
+	}
+	s = s[1:]
+
+	var path jsonPath
+	for len(s) > 0 {
+		switch s[0] {
+		case '.':
+			s = s[1:]
+			end := strings.IndexAny(s, ".[")
+			if end == -1 {
+				end = len(s)
+			}
+			key := s[:end]
+			if key == "" {
+				return nil, fmt.Errorf("invalid JSONPath %q: empty key", expr)
+			}
+			if key == "*" {
+				path = append(path, pathStep{wildcard: true})
+			} else {
+				path = append(path, pathStep{key: key})
+			}
+			s = s[end:]
+		case '[':
+			end := strings.IndexByte(s, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("invalid JSONPath %q: missing ']'", expr)
+			}
+			sel := strings.TrimSpace(s[1:end])
+			s = s[end+1:]
+
+			switch {
+			case sel == "*":
+				path = append(path, pathStep{wildcard: true})
+			case len(sel) >= 2 && (sel[0] == '\'' || sel[0] == '"') &&
+				sel[len(sel)-1] == sel[0]:
+				path = append(path, pathStep{key: sel[1 : len(sel)-1]})
+			default:
+				n, err := strconv.Atoi(sel)
+				if err != nil {
+					return nil, fmt.Errorf("invalid JSONPath %q: bad index %q", expr, sel)
+				}
+				path = append(path, pathStep{index: n})
+			}
+		default:
+			return nil, fmt.Errorf("invalid JSONPath %q: unexpected %q", expr, s[0])
+		}
+	}
+	return path, nil
+}
+
+// Find returns all values matched by the path in the given decoded JSON
+// document. Objects are walked in key order so results are stable.
+func (p jsonPath) Find(doc interface{}) []interface{} {
+	current := []interface{}{doc}
+	for _, step := range p {
+		var next []interface{}
+		for _, v := range current {
+			switch node := v.(type) {
+			case map[string]interface{}:
+				if step.wildcard {
+					keys := make([]string, 0, len(node))
+					for k := range node {
+						keys = append(keys, k)
+					}
+					sort.Strings(keys)
+					for _, k := range keys {
+						next = append(next, node[k])
+					}
+				} else if step.key != "" {
+					if child, ok := node[step.key]; ok {
+						next = append(next, child)
+					}
+				}
+			case []interface{}:
+				if step.wildcard {
+					next = append(next, node...)
+				} else if step.key == "" {
+					i := step.index
+					if i < 0 {
+						i += len(node)
+					}
+					if i >= 0 && i < len(node) {
+						next = append(next, node[i])
+					}
+				}
+			}
+		}
+		current = next
+	}
+	return current
+}
+
+// First returns the first value matched by the path, or nil.
+func (p jsonPath) First(doc interface{}) interface{} {
+	values := p.Find(doc)
+	if len(values) == 0 {
+		return nil
+	}
+	return values[0]
+}
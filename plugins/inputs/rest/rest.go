@@ -0,0 +1,284 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+type Rest struct {
+	URLs        []string `toml:"urls"`
+	Method      string
+	Body        string
+	Headers     map[string]string
+	Username    string
+	Password    string
+	BearerToken string `toml:"bearer_token"`
+	Timeout     internal.Duration
+
+	MetricPath string            `toml:"metric_path"`
+	FieldPaths map[string]string `toml:"field_paths"`
+	TagPaths   map[string]string `toml:"tag_paths"`
+	TimePath   string            `toml:"time_path"`
+	TimeFormat string            `toml:"time_format"`
+
+	// Path to CA file
+	SSLCA string `toml:"ssl_ca"`
+	// Path to host cert file
+	SSLCert string `toml:"ssl_cert"`
+	// Path to cert key file
+	SSLKey string `toml:"ssl_key"`
+	// Use SSL but skip chain & host verification
+	InsecureSkipVerify bool
+
+	client *http.Client
+
+	metricPath jsonPath
+	fieldPaths map[string]jsonPath
+	tagPaths   map[string]jsonPath
+	timePath   jsonPath
+}
+
+var sampleConfig = `
+  ## One or more URLs to poll.
+  urls = ["http://localhost/api/v1/status"]
+
+  ## HTTP method and optional request body.
+  # method = "GET"
+  # body = ""
+
+  ## HTTP Headers (all values must be strings)
+  # [inputs.rest.headers]
+  #   Accept = "application/json"
+
+  ## Optional HTTP Basic Auth credentials or bearer token.
+  # username = "username"
+  # password = "pa$$word"
+  # bearer_token = "my-token"
+
+  ## Amount of time allowed to complete the HTTP request
+  # timeout = "5s"
+
+  ## Optional SSL Config
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  ## Use SSL but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## JSONPath selecting the element(s) of the response to create metrics
+  ## from, each matched element creates one metric. All other paths are
+  ## relative to the selected element.
+  ## Supported syntax: $, .key, ['key'], [n], [*] and .*
+  metric_path = "$"
+
+  ## Optional JSONPath and format of the metric timestamp. The format is
+  ## "unix", "unix_ms", "unix_us", "unix_ns" or a Go reference time layout,
+  ## the default is RFC3339. If unset the time of the request is used.
+  # time_path = "$.lastUpdateTime"
+  # time_format = "2006-01-02 15:04:05"
+
+  ## Fields to create, as a map of field name to JSONPath.
+  [inputs.rest.field_paths]
+    status = "$.status"
+
+  ## Tags to create, as a map of tag name to JSONPath.
+  # [inputs.rest.tag_paths]
+  #   id = "$.id"
+`
+
+func (r *Rest) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *Rest) Description() string {
+	return "Read metrics from REST APIs, mapping JSON values with JSONPath"
+}
+
+func (r *Rest) compile() error {
+	var err error
+	if r.MetricPath == "" {
+		r.MetricPath = "$"
+	}
+	if r.metricPath, err = compilePath(r.MetricPath); err != nil {
+		return err
+	}
+
+	if len(r.FieldPaths) == 0 {
+		return fmt.Errorf("at least one field path must be configured")
+	}
+	r.fieldPaths = make(map[string]jsonPath, len(r.FieldPaths))
+	for name, expr := range r.FieldPaths {
+		if r.fieldPaths[name], err = compilePath(expr); err != nil {
+			return err
+		}
+	}
+
+	r.tagPaths = make(map[string]jsonPath, len(r.TagPaths))
+	for name, expr := range r.TagPaths {
+		if r.tagPaths[name], err = compilePath(expr); err != nil {
+			return err
+		}
+	}
+
+	if r.TimePath != "" {
+		if r.timePath, err = compilePath(r.TimePath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Rest) Gather(acc telegraf.Accumulator) error {
+	if r.fieldPaths == nil {
+		if err := r.compile(); err != nil {
+			return err
+		}
+	}
+
+	if r.client == nil {
+		tlsCfg, err := internal.GetTLSConfig(
+			r.SSLCert, r.SSLKey, r.SSLCA, r.InsecureSkipVerify)
+		if err != nil {
+			return err
+		}
+		r.client = &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsCfg,
+			},
+			Timeout: r.Timeout.Duration,
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, u := range r.URLs {
+		wg.Add(1)
+		go func(url string) {
+			defer wg.Done()
+			if err := r.gatherURL(acc, url); err != nil {
+				acc.AddError(fmt.Errorf("[url=%s]: %s", url, err))
+			}
+		}(u)
+	}
+
+	wg.Wait()
+	return nil
+}
+
+func (r *Rest) gatherURL(acc telegraf.Accumulator, url string) error {
+	method := r.Method
+	if method == "" {
+		method = "GET"
+	}
+
+	var body io.Reader
+	if r.Body != "" {
+		body = strings.NewReader(r.Body)
+	}
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range r.Headers {
+		if strings.ToLower(k) == "host" {
+			req.Host = v
+		} else {
+			req.Header.Set(k, v)
+		}
+	}
+	if r.Username != "" || r.Password != "" {
+		req.SetBasicAuth(r.Username, r.Password)
+	}
+	if r.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+r.BearerToken)
+	}
+
+	now := time.Now()
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("received status code %d (%s), expected %d (%s)",
+			resp.StatusCode, http.StatusText(resp.StatusCode),
+			http.StatusOK, http.StatusText(http.StatusOK))
+	}
+
+	buf, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(buf, &doc); err != nil {
+		return fmt.Errorf("unable to parse response as JSON, %s", err)
+	}
+
+	for _, elem := range r.metricPath.Find(doc) {
+		fields := make(map[string]interface{})
+		for name, path := range r.fieldPaths {
+			switch v := path.First(elem).(type) {
+			case float64, bool, string:
+				fields[name] = v
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		tags := map[string]string{"url": url}
+		for name, path := range r.tagPaths {
+			if v, ok := tagValue(path.First(elem)); ok {
+				tags[name] = v
+			}
+		}
+
+		t := now
+		if r.timePath != nil {
+			t, err = internal.ParseTimestamp(r.timePath.First(elem), r.TimeFormat, nil)
+			if err != nil {
+				acc.AddError(fmt.Errorf("[url=%s]: %s", url, err))
+				continue
+			}
+		}
+
+		acc.AddFields("rest", fields, tags, t)
+	}
+	return nil
+}
+
+func tagValue(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+func init() {
+	inputs.Add("rest", func() telegraf.Input {
+		return &Rest{
+			Method:  "GET",
+			Timeout: internal.Duration{Duration: time.Second * 5},
+		}
+	})
+}
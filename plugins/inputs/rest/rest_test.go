@@ -0,0 +1,161 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sitesResponse = `
+{
+  "sites": {
+    "count": 2,
+    "site": [
+      {
+        "id": 123,
+        "name": "Home",
+        "lastUpdateTime": "2017-06-01 10:15:00",
+        "overview": {"currentPower": {"power": 1530.5}, "status": "Active"}
+      },
+      {
+        "id": 456,
+        "name": "Barn",
+        "lastUpdateTime": "2017-06-01 10:30:00",
+        "overview": {"currentPower": {"power": 0}, "status": "Pending"}
+      }
+    ]
+  }
+}
+`
+
+func TestGatherArray(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		assert.Equal(t, "application/json", r.Header.Get("Accept"))
+		w.Write([]byte(sitesResponse))
+	}))
+	defer ts.Close()
+
+	plugin := &Rest{
+		URLs:        []string{ts.URL},
+		BearerToken: "token",
+		Headers:     map[string]string{"Accept": "application/json"},
+		MetricPath:  "$.sites.site[*]",
+		FieldPaths: map[string]string{
+			"power":  "$.overview.currentPower.power",
+			"status": "$.overview['status']",
+		},
+		TagPaths: map[string]string{
+			"site_id": "$.id",
+			"name":    "$.name",
+		},
+		TimePath:   "$.lastUpdateTime",
+		TimeFormat: "2006-01-02 15:04:05",
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.Errors, 0)
+	require.Len(t, acc.Metrics, 2)
+
+	acc.AssertContainsTaggedFields(t, "rest",
+		map[string]interface{}{
+			"power":  float64(1530.5),
+			"status": "Active",
+		},
+		map[string]string{
+			"url":     ts.URL,
+			"site_id": "123",
+			"name":    "Home",
+		})
+
+	for _, m := range acc.Metrics {
+		if m.Tags["site_id"] == "456" {
+			assert.Equal(t, time.Date(2017, 6, 1, 10, 30, 0, 0, time.UTC), m.Time.UTC())
+		}
+	}
+}
+
+func TestGatherUnixTime(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": {"t": 1496311200000, "values": [1, 2, 3]}}`))
+	}))
+	defer ts.Close()
+
+	plugin := &Rest{
+		URLs: []string{ts.URL},
+		FieldPaths: map[string]string{
+			"last": "$.data.values[-1]",
+		},
+		TimePath:   "$.data.t",
+		TimeFormat: "unix_ms",
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.Metrics, 1)
+	assert.Equal(t, map[string]interface{}{"last": float64(3)}, acc.Metrics[0].Fields)
+	assert.Equal(t, int64(1496311200000000000), acc.Metrics[0].Time.UnixNano())
+}
+
+func TestGatherBadStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	plugin := &Rest{
+		URLs:       []string{ts.URL},
+		FieldPaths: map[string]string{"value": "$.value"},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	assert.Len(t, acc.Metrics, 0)
+}
+
+func TestCompileErrors(t *testing.T) {
+	plugin := &Rest{FieldPaths: map[string]string{"value": "value"}}
+	var acc testutil.Accumulator
+	assert.Error(t, plugin.Gather(&acc))
+
+	plugin = &Rest{}
+	assert.Error(t, plugin.Gather(&acc))
+}
+
+func TestJSONPath(t *testing.T) {
+	doc := map[string]interface{}{
+		"a": map[string]interface{}{
+			"b": []interface{}{float64(1), float64(2)},
+			"c": "x",
+		},
+	}
+
+	var tests = []struct {
+		path     string
+		expected []interface{}
+	}{
+		{"$", []interface{}{doc}},
+		{"$.a.c", []interface{}{"x"}},
+		{"$.a.b[0]", []interface{}{float64(1)}},
+		{"$['a'].b[*]", []interface{}{float64(1), float64(2)}},
+		{"$.a.b[5]", nil},
+		{"$.missing.key", nil},
+	}
+	for _, tt := range tests {
+		p, err := compilePath(tt.path)
+		require.NoError(t, err, tt.path)
+		assert.Equal(t, tt.expected, p.Find(doc), tt.path)
+	}
+
+	for _, bad := range []string{"a.b", "$.", "$[1", "$[x]", "$a"} {
+		_, err := compilePath(bad)
+		assert.Error(t, err, bad)
+	}
+}
@@ -0,0 +1,262 @@
+package sma_cloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	jsonparser "github.com/influxdata/telegraf/plugins/parsers/json"
+)
+
+type SmaCloud struct {
+	APIURL       string            `toml:"api_url"`
+	TokenURL     string            `toml:"token_url"`
+	ClientID     string            `toml:"client_id"`
+	ClientSecret string            `toml:"client_secret"`
+	Plants       []string          `toml:"plants"`
+	Sets         []string          `toml:"sets"`
+	Timeout      internal.Duration `toml:"timeout"`
+
+	client *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiry      time.Time
+}
+
+type plant struct {
+	PlantID string `json:"plantId"`
+	Name    string `json:"name"`
+}
+
+var sampleConfig = `
+  ## URLs of the SMA monitoring API and of its OAuth2 token endpoint.
+  # api_url = "https://async-auth.smaapis.de"
+  # token_url = "https://auth.smaapis.de/oauth2/token"
+
+  ## Client credentials of the application registered with SMA, which must
+  ## have been granted access to the plants by their owner.
+  client_id = ""
+  client_secret = ""
+
+  ## Ids of the plants to read, by default all plants accessible with the
+  ## credentials are read.
+  # plants = []
+
+  ## Measurement sets to read for each plant.
+  sets = ["EnergyAndPowerPv"]
+
+  ## Amount of time allowed to complete the HTTP requests
+  # timeout = "10s"
+
+  ## The portal updates the data every 5 minutes.
+  interval = "5m"
+`
+
+func (s *SmaCloud) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *SmaCloud) Description() string {
+	return "Read plant telemetry from the SMA cloud monitoring API"
+}
+
+func (s *SmaCloud) Gather(acc telegraf.Accumulator) error {
+	if s.client == nil {
+		s.client = &http.Client{
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+			},
+			Timeout: s.Timeout.Duration,
+		}
+	}
+
+	plants, err := s.plants()
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for _, p := range plants {
+		for _, set := range s.Sets {
+			wg.Add(1)
+			go func(p plant, set string) {
+				defer wg.Done()
+				if err := s.gatherSet(acc, p, set); err != nil {
+					acc.AddError(fmt.Errorf("[plant=%s set=%s]: %s", p.PlantID, set, err))
+				}
+			}(p, set)
+		}
+	}
+	wg.Wait()
+	return nil
+}
+
+// token returns a valid access token, requesting a new one with the client
+// credentials when needed.
+func (s *SmaCloud) token() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.accessToken != "" && time.Now().Before(s.expiry) {
+		return s.accessToken, nil
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", s.ClientID)
+	form.Set("client_secret", s.ClientSecret)
+
+	resp, err := s.client.PostForm(s.TokenURL, form)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("login failed with status code %d (%s)",
+			resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	var t struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return "", fmt.Errorf("unable to parse token response, %s", err)
+	}
+	if t.AccessToken == "" {
+		return "", fmt.Errorf("no access token in token response")
+	}
+
+	s.accessToken = t.AccessToken
+	// Renew the token a minute before it expires.
+	s.expiry = time.Now().Add(time.Duration(t.ExpiresIn)*time.Second - time.Minute)
+	return s.accessToken, nil
+}
+
+func (s *SmaCloud) get(path string, v interface{}) error {
+	token, err := s.token()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("GET", strings.TrimRight(s.APIURL, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		// Force a new login on the next request.
+		s.mu.Lock()
+		s.accessToken = ""
+		s.mu.Unlock()
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("received status code %d (%s), expected %d (%s)",
+			resp.StatusCode, http.StatusText(resp.StatusCode),
+			http.StatusOK, http.StatusText(http.StatusOK))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (s *SmaCloud) plants() ([]plant, error) {
+	var r struct {
+		Plants []plant `json:"plants"`
+	}
+	if err := s.get("/monitoring/v1/plants", &r); err != nil {
+		return nil, fmt.Errorf("unable to list plants, %s", err)
+	}
+	if len(s.Plants) == 0 {
+		return r.Plants, nil
+	}
+
+	selected := make(map[string]bool, len(s.Plants))
+	for _, id := range s.Plants {
+		selected[id] = true
+	}
+	var plants []plant
+	for _, p := range r.Plants {
+		if selected[p.PlantID] {
+			plants = append(plants, p)
+		}
+	}
+	return plants, nil
+}
+
+func (s *SmaCloud) gatherSet(acc telegraf.Accumulator, p plant, set string) error {
+	var r struct {
+		Set []map[string]interface{} `json:"set"`
+	}
+	path := fmt.Sprintf("/monitoring/v1/plants/%s/measurements/sets/%s/Recent",
+		url.QueryEscape(p.PlantID), url.QueryEscape(set))
+	if err := s.get(path, &r); err != nil {
+		return err
+	}
+
+	for _, entry := range r.Set {
+		t := time.Now()
+		if v, ok := entry["time"].(string); ok {
+			ts, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return fmt.Errorf("unable to parse time %q, %s", v, err)
+			}
+			t = ts
+		}
+		delete(entry, "time")
+		// Values the plant does not measure are null.
+		for k, v := range entry {
+			if v == nil {
+				delete(entry, k)
+			}
+		}
+
+		f := jsonparser.JSONFlattener{}
+		if err := f.FlattenJSON("", entry); err != nil {
+			return err
+		}
+		if len(f.Fields) == 0 {
+			continue
+		}
+		fields := make(map[string]interface{}, len(f.Fields))
+		for k, v := range f.Fields {
+			fields[internal.SnakeCase(k)] = v
+		}
+
+		tags := map[string]string{
+			"plant_id": p.PlantID,
+			"set":      set,
+		}
+		if p.Name != "" {
+			tags["plant_name"] = p.Name
+		}
+		acc.AddFields("sma_cloud", fields, tags, t)
+	}
+	return nil
+}
+
+func init() {
+	inputs.Add("sma_cloud", func() telegraf.Input {
+		return &SmaCloud{
+			APIURL:   "https://async-auth.smaapis.de",
+			TokenURL: "https://auth.smaapis.de/oauth2/token",
+			Sets:     []string{"EnergyAndPowerPv"},
+			Timeout:  internal.Duration{Duration: time.Second * 10},
+		}
+	})
+}
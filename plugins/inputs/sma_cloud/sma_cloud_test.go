@@ -0,0 +1,112 @@
+package sma_cloud
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newServer(t *testing.T, logins *int) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		*logins++
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.Form.Get("grant_type"))
+		if r.Form.Get("client_secret") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"access_token":"abc","expires_in":3600,"token_type":"bearer"}`))
+	})
+	mux.HandleFunc("/monitoring/v1/plants", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer abc", r.Header.Get("Authorization"))
+		w.Write([]byte(`{"plants":[
+			{"plantId":"7190","name":"Roof","timezone":"Europe/Berlin"},
+			{"plantId":"7191","name":"Barn","timezone":"Europe/Berlin"}]}`))
+	})
+	mux.HandleFunc("/monitoring/v1/plants/7190/measurements/sets/EnergyAndPowerPv/Recent",
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{
+				"plant":{"plantId":"7190","name":"Roof"},
+				"setType":"EnergyAndPowerPv",
+				"set":[{"time":"2017-06-01T10:15:00Z","pvGeneration":125.5,"pvPower":1530,"batteryPower":null}]}`))
+		})
+	return httptest.NewServer(mux)
+}
+
+func TestGather(t *testing.T) {
+	var logins int
+	ts := newServer(t, &logins)
+	defer ts.Close()
+
+	s := &SmaCloud{
+		APIURL:       ts.URL,
+		TokenURL:     ts.URL + "/oauth2/token",
+		ClientID:     "telegraf",
+		ClientSecret: "secret",
+		Plants:       []string{"7190"},
+		Sets:         []string{"EnergyAndPowerPv"},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+	require.Len(t, acc.Errors, 0)
+	require.Len(t, acc.Metrics, 1)
+
+	acc.AssertContainsTaggedFields(t, "sma_cloud",
+		map[string]interface{}{
+			"pv_generation": float64(125.5),
+			"pv_power":      float64(1530),
+		},
+		map[string]string{
+			"plant_id":   "7190",
+			"plant_name": "Roof",
+			"set":        "EnergyAndPowerPv",
+		})
+	assert.Equal(t,
+		time.Date(2017, 6, 1, 10, 15, 0, 0, time.UTC).UnixNano(),
+		acc.Metrics[0].Time.UnixNano())
+
+	// The access token is reused until it expires.
+	require.NoError(t, s.Gather(&acc))
+	assert.Equal(t, 1, logins)
+}
+
+func TestGatherAllPlants(t *testing.T) {
+	var logins int
+	ts := newServer(t, &logins)
+	defer ts.Close()
+
+	s := &SmaCloud{
+		APIURL:       ts.URL,
+		TokenURL:     ts.URL + "/oauth2/token",
+		ClientSecret: "secret",
+		Sets:         []string{"EnergyAndPowerPv"},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+	// The second plant has no data for the set.
+	assert.Len(t, acc.Metrics, 1)
+	assert.Len(t, acc.Errors, 1)
+}
+
+func TestLoginFailure(t *testing.T) {
+	var logins int
+	ts := newServer(t, &logins)
+	defer ts.Close()
+
+	s := &SmaCloud{
+		APIURL:       ts.URL,
+		TokenURL:     ts.URL + "/oauth2/token",
+		ClientSecret: "wrong",
+	}
+
+	var acc testutil.Accumulator
+	assert.Error(t, s.Gather(&acc))
+}
@@ -0,0 +1,269 @@
+package solarman
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/modbus"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// Solarman V5 frame constants.
+const (
+	frameStart       = 0xA5
+	frameEnd         = 0x15
+	controlRequest   = 0x4510
+	controlResponse  = 0x1510
+	controlHeartbeat = 0x4710
+	headerLength     = 11
+)
+
+type Solarman struct {
+	Address      string             `toml:"address"`
+	LoggerSerial uint32             `toml:"logger_serial"`
+	SlaveID      int                `toml:"slave_id"`
+	Model        string             `toml:"model"`
+	Registers    []*modbus.Register `toml:"registers"`
+	Timeout      internal.Duration  `toml:"timeout"`
+
+	registers []*modbus.Register
+	sequence  uint16
+}
+
+var sampleConfig = `
+  ## Address of the data logger, the V5 protocol listens on port 8899.
+  address = "192.168.1.30:8899"
+
+  ## Serial number of the data logger (not of the inverter), as printed on
+  ## the logger and shown in its web interface.
+  logger_serial = 1712345678
+
+  ## Modbus address of the inverter behind the logger.
+  # slave_id = 1
+
+  ## Built-in register map to read, either "deye_string" or "sofar". Leave
+  ## empty to only read the registers configured below.
+  model = "deye_string"
+
+  ## Amount of time allowed to complete the requests
+  # timeout = "5s"
+
+  ## Additional registers, see the modbus input for the available settings.
+  # [[inputs.solarman.registers]]
+  #   name = "pv3_voltage"
+  #   type = "holding"
+  #   address = 113
+  #   data_type = "UINT16"
+  #   scale = 0.1
+`
+
+// models are register maps for common inverters. Field names use the units
+// W, kWh, V, A and Hz.
+var models = map[string][]*modbus.Register{
+	"deye_string": {
+		{Name: "today_energy", Type: "holding", Address: 0x3C, DataType: "UINT16", Scale: 0.1},
+		{Name: "total_energy", Type: "holding", Address: 0x3F, DataType: "UINT32", ByteOrder: "CDAB", Scale: 0.1},
+		{Name: "grid_voltage", Type: "holding", Address: 0x49, DataType: "UINT16", Scale: 0.1},
+		{Name: "grid_frequency", Type: "holding", Address: 0x4F, DataType: "UINT16", Scale: 0.01},
+		{Name: "ac_power", Type: "holding", Address: 0x56, DataType: "UINT32", ByteOrder: "CDAB", Scale: 0.1},
+		{Name: "pv1_voltage", Type: "holding", Address: 0x6D, DataType: "UINT16", Scale: 0.1},
+		{Name: "pv1_current", Type: "holding", Address: 0x6E, DataType: "UINT16", Scale: 0.1},
+		{Name: "pv2_voltage", Type: "holding", Address: 0x6F, DataType: "UINT16", Scale: 0.1},
+		{Name: "pv2_current", Type: "holding", Address: 0x70, DataType: "UINT16", Scale: 0.1},
+	},
+	"sofar": {
+		{Name: "pv1_voltage", Type: "holding", Address: 0x06, DataType: "UINT16", Scale: 0.1},
+		{Name: "pv1_current", Type: "holding", Address: 0x07, DataType: "UINT16", Scale: 0.01},
+		{Name: "ac_power", Type: "holding", Address: 0x0C, DataType: "UINT16", Scale: 10},
+		{Name: "grid_frequency", Type: "holding", Address: 0x0E, DataType: "UINT16", Scale: 0.01},
+		{Name: "grid_voltage", Type: "holding", Address: 0x0F, DataType: "UINT16", Scale: 0.1},
+		{Name: "total_energy", Type: "holding", Address: 0x15, DataType: "UINT32", Scale: 1},
+		{Name: "today_energy", Type: "holding", Address: 0x19, DataType: "UINT16", Scale: 0.01},
+	},
+}
+
+func (s *Solarman) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Solarman) Description() string {
+	return "Read inverter registers from Solarman data loggers"
+}
+
+func (s *Solarman) initRegisters() error {
+	var registers []*modbus.Register
+	if s.Model != "" {
+		model, ok := models[s.Model]
+		if !ok {
+			return fmt.Errorf("unknown model %q", s.Model)
+		}
+		for _, r := range model {
+			c := *r
+			registers = append(registers, &c)
+		}
+	}
+	registers = append(registers, s.Registers...)
+	if len(registers) == 0 {
+		return fmt.Errorf("no registers configured")
+	}
+	for _, r := range registers {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+	}
+	s.registers = registers
+	return nil
+}
+
+func (s *Solarman) Gather(acc telegraf.Accumulator) error {
+	if s.registers == nil {
+		if err := s.initRegisters(); err != nil {
+			return err
+		}
+	}
+
+	conn, err := net.DialTimeout("tcp", s.Address, s.Timeout.Duration)
+	if err != nil {
+		return fmt.Errorf("[address=%s]: %s", s.Address, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(s.Timeout.Duration))
+
+	fields := make(map[string]interface{})
+	for _, r := range s.registers {
+		value, err := s.readRegister(conn, r)
+		if err != nil {
+			acc.AddError(fmt.Errorf("[address=%s register=%s]: %s", s.Address, r.Name, err))
+			if _, ok := err.(net.Error); ok {
+				// The connection is unusable after a timeout.
+				break
+			}
+			continue
+		}
+		fields[r.Name] = value
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	tags := map[string]string{
+		"logger_serial": strconv.FormatUint(uint64(s.LoggerSerial), 10),
+	}
+	if s.Model != "" {
+		tags["model"] = s.Model
+	}
+	acc.AddFields("solarman", fields, tags)
+	return nil
+}
+
+func (s *Solarman) readRegister(rw io.ReadWriter, r *modbus.Register) (interface{}, error) {
+	slave := byte(s.SlaveID)
+	s.sequence++
+	request := encodeFrame(s.LoggerSerial, s.sequence,
+		modbus.Request(slave, r.Function(), uint16(r.Address), r.Quantity()))
+	if _, err := rw.Write(request); err != nil {
+		return nil, err
+	}
+
+	rtu, err := readFrame(rw)
+	if err != nil {
+		return nil, err
+	}
+	data, err := modbus.ParseResponse(rtu, slave, r.Function())
+	if err != nil {
+		return nil, err
+	}
+	return r.Decode(data)
+}
+
+// encodeFrame wraps a Modbus RTU frame in a Solarman V5 request frame.
+func encodeFrame(serial uint32, sequence uint16, rtu []byte) []byte {
+	payloadLength := 15 + len(rtu)
+	frame := make([]byte, headerLength+payloadLength, headerLength+payloadLength+2)
+	frame[0] = frameStart
+	binary.LittleEndian.PutUint16(frame[1:], uint16(payloadLength))
+	binary.LittleEndian.PutUint16(frame[3:], controlRequest)
+	binary.LittleEndian.PutUint16(frame[5:], sequence)
+	binary.LittleEndian.PutUint32(frame[7:], serial)
+	// Frame type 2 followed by the sensor type and three time fields, which
+	// are all zero in requests.
+	frame[headerLength] = 0x02
+	copy(frame[headerLength+15:], rtu)
+	return append(frame, checksum(frame), frameEnd)
+}
+
+func checksum(frame []byte) byte {
+	var sum byte
+	for _, b := range frame[1:] {
+		sum += b
+	}
+	return sum
+}
+
+// readFrame reads V5 frames until a response is received and returns the
+// Modbus RTU frame it contains. Heartbeats sent by the logger are skipped.
+func readFrame(r io.Reader) ([]byte, error) {
+	for {
+		header := make([]byte, headerLength)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, err
+		}
+		if header[0] != frameStart {
+			return nil, fmt.Errorf("invalid frame start 0x%02x", header[0])
+		}
+		length := int(binary.LittleEndian.Uint16(header[1:]))
+		frame := make([]byte, headerLength+length+2)
+		copy(frame, header)
+		if _, err := io.ReadFull(r, frame[headerLength:]); err != nil {
+			return nil, err
+		}
+
+		n := len(frame)
+		if frame[n-1] != frameEnd {
+			return nil, fmt.Errorf("invalid frame end 0x%02x", frame[n-1])
+		}
+		if frame[n-2] != checksum(frame[:n-2]) {
+			return nil, fmt.Errorf("invalid frame checksum")
+		}
+
+		switch binary.LittleEndian.Uint16(frame[3:]) {
+		case controlHeartbeat:
+			continue
+		case controlResponse:
+		default:
+			return nil, fmt.Errorf("unexpected control code 0x%04x",
+				binary.LittleEndian.Uint16(frame[3:]))
+		}
+
+		// Response payloads start with the frame type, a status and three
+		// time fields.
+		if length < 14+5 {
+			return nil, fmt.Errorf("logger did not return a Modbus response, the inverter may be offline")
+		}
+		rtu := frame[headerLength+14 : n-2]
+		// Some loggers pad the Modbus frame with zeros.
+		size := 5
+		if rtu[1]&0x80 == 0 {
+			size += int(rtu[2])
+		}
+		if size < len(rtu) {
+			rtu = rtu[:size]
+		}
+		return rtu, nil
+	}
+}
+
+func init() {
+	inputs.Add("solarman", func() telegraf.Input {
+		return &Solarman{
+			SlaveID: 1,
+			Timeout: internal.Duration{Duration: time.Second * 5},
+		}
+	})
+}
@@ -0,0 +1,160 @@
+package solarman
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/modbus"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const loggerSerial = 1712345678
+
+// responseFrame wraps a Modbus RTU frame in a V5 frame.
+func responseFrame(control uint16, rtu []byte) []byte {
+	payload := append([]byte{0x02, 0x01}, make([]byte, 12)...)
+	payload = append(payload, rtu...)
+
+	frame := make([]byte, headerLength)
+	frame[0] = frameStart
+	binary.LittleEndian.PutUint16(frame[1:], uint16(len(payload)))
+	binary.LittleEndian.PutUint16(frame[3:], control)
+	binary.LittleEndian.PutUint32(frame[7:], loggerSerial)
+	frame = append(frame, payload...)
+	return append(frame, checksum(frame), frameEnd)
+}
+
+// newLogger starts a fake data logger answering from the holding registers.
+func newLogger(t *testing.T, registers map[uint16]uint16) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// Loggers send heartbeats at any time.
+		conn.Write(responseFrame(controlHeartbeat, nil))
+
+		for {
+			request := make([]byte, headerLength+15+8+2)
+			if _, err := conn.Read(request); err != nil {
+				return
+			}
+			assert.Equal(t, uint32(loggerSerial), binary.LittleEndian.Uint32(request[7:]))
+			assert.Equal(t, checksum(request[:len(request)-2]), request[len(request)-2])
+
+			rtu := request[headerLength+15 : headerLength+15+8]
+			address := binary.BigEndian.Uint16(rtu[2:])
+			quantity := binary.BigEndian.Uint16(rtu[4:])
+
+			resp := []byte{rtu[0], rtu[1], byte(quantity * 2)}
+			for i := uint16(0); i < quantity; i++ {
+				v, ok := registers[address+i]
+				if !ok {
+					resp = []byte{rtu[0], rtu[1] | 0x80, 0x02}
+					break
+				}
+				resp = append(resp, byte(v>>8), byte(v))
+			}
+			crc := modbus.CRC16(resp)
+			resp = append(resp, byte(crc), byte(crc>>8))
+			// Pad the frame like some logger firmwares do.
+			resp = append(resp, 0, 0)
+			conn.Write(responseFrame(controlResponse, resp))
+		}
+	}()
+	return l
+}
+
+func TestGatherModel(t *testing.T) {
+	l := newLogger(t, map[uint16]uint16{
+		0x3C: 123,
+		0x3F: 0x86A0,
+		0x40: 0x0001,
+		0x49: 2301,
+		0x4F: 5001,
+		0x56: 15300,
+		0x57: 0,
+		0x6D: 3520,
+		0x6E: 43,
+		0x6F: 3490,
+		0x70: 42,
+	})
+	defer l.Close()
+
+	s := &Solarman{
+		Address:      l.Addr().String(),
+		LoggerSerial: loggerSerial,
+		SlaveID:      1,
+		Model:        "deye_string",
+		Timeout:      internal.Duration{Duration: time.Second},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+	require.Len(t, acc.Errors, 0)
+	require.Len(t, acc.Metrics, 1)
+
+	m := acc.Metrics[0]
+	assert.Equal(t, "solarman", m.Measurement)
+	assert.Equal(t, map[string]string{
+		"logger_serial": "1712345678",
+		"model":         "deye_string",
+	}, m.Tags)
+	assert.InDelta(t, 12.3, m.Fields["today_energy"], 1e-9)
+	assert.InDelta(t, 10000, m.Fields["total_energy"], 1e-9)
+	assert.InDelta(t, 1530, m.Fields["ac_power"], 1e-9)
+	assert.InDelta(t, 50.01, m.Fields["grid_frequency"], 1e-9)
+	assert.InDelta(t, 4.3, m.Fields["pv1_current"], 1e-9)
+}
+
+func TestGatherException(t *testing.T) {
+	l := newLogger(t, map[uint16]uint16{100: 42})
+	defer l.Close()
+
+	s := &Solarman{
+		Address:      l.Addr().String(),
+		LoggerSerial: loggerSerial,
+		SlaveID:      1,
+		Registers: []*modbus.Register{
+			{Name: "a", Type: "holding", Address: 100, DataType: "UINT16"},
+			{Name: "b", Type: "holding", Address: 200, DataType: "UINT16"},
+		},
+		Timeout: internal.Duration{Duration: time.Second},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	assert.Contains(t, acc.Errors[0].Error(), "illegal data address")
+	acc.AssertContainsTaggedFields(t, "solarman",
+		map[string]interface{}{"a": int64(42)},
+		map[string]string{"logger_serial": "1712345678"})
+}
+
+func TestEncodeFrame(t *testing.T) {
+	frame := encodeFrame(loggerSerial, 1, modbus.Request(1, modbus.FuncReadHoldingRegisters, 0x3C, 1))
+	require.Len(t, frame, headerLength+15+8+2)
+	assert.Equal(t, byte(frameStart), frame[0])
+	assert.Equal(t, uint16(23), binary.LittleEndian.Uint16(frame[1:]))
+	assert.Equal(t, uint16(controlRequest), binary.LittleEndian.Uint16(frame[3:]))
+	assert.Equal(t, byte(frameEnd), frame[len(frame)-1])
+}
+
+func TestInvalidModel(t *testing.T) {
+	s := &Solarman{Model: "unknown"}
+	var acc testutil.Accumulator
+	assert.Error(t, s.Gather(&acc))
+
+	s = &Solarman{}
+	assert.Error(t, s.Gather(&acc))
+}
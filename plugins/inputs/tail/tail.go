@@ -2,6 +2,7 @@ package tail
 
 import (
 	"fmt"
+	"os"
 	"sync"
 
 	"github.com/influxdata/tail"
@@ -18,6 +19,7 @@ type Tail struct {
 	Pipe          bool
 
 	tailers []*tail.Tail
+	offsets map[string]int64
 	parser  parsers.Parser
 	wg      sync.WaitGroup
 	acc     telegraf.Accumulator
@@ -28,6 +30,7 @@ type Tail struct {
 func NewTail() *Tail {
 	return &Tail{
 		FromBeginning: false,
+		offsets:       make(map[string]int64),
 	}
 }
 
@@ -87,11 +90,23 @@ func (t *Tail) Start(acc telegraf.Accumulator) error {
 			t.acc.AddError(fmt.Errorf("E! Error Glob %s failed to compile, %s", filepath, err))
 		}
 		for file, _ := range g.Match() {
+			location := seek
+			if offset, ok := t.offsets[file]; ok && !t.Pipe {
+				// Continue where the previous run stopped, unless the file
+				// was truncated since.
+				if info, err := os.Stat(file); err == nil && info.Size() >= offset {
+					location = &tail.SeekInfo{
+						Whence: 0,
+						Offset: offset,
+					}
+				}
+			}
+
 			tailer, err := tail.TailFile(file,
 				tail.Config{
 					ReOpen:    true,
 					Follow:    true,
-					Location:  seek,
+					Location:  location,
 					MustExist: true,
 					Pipe:      t.Pipe,
 				})
@@ -125,7 +140,11 @@ func (t *Tail) receiver(tailer *tail.Tail) {
 		}
 		m, err = t.parser.ParseLine(line.Text)
 		if err == nil {
-			t.acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+			// The parser may consume lines without returning a metric,
+			// ie the header of a CSV file.
+			if m != nil {
+				t.acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+			}
 		} else {
 			t.acc.AddError(fmt.Errorf("E! Malformed log line in %s: [%s], Error: %s\n",
 				tailer.Filename, line.Text, err))
@@ -142,6 +161,11 @@ func (t *Tail) Stop() {
 	defer t.Unlock()
 
 	for _, tailer := range t.tailers {
+		if !t.Pipe {
+			if offset, err := tailer.Tell(); err == nil {
+				t.offsets[tailer.Filename] = offset
+			}
+		}
 		err := tailer.Stop()
 		if err != nil {
 			t.acc.AddError(fmt.Errorf("E! Error stopping tail on file %s\n", tailer.Filename))
@@ -151,6 +175,31 @@ func (t *Tail) Stop() {
 	t.wg.Wait()
 }
 
+// GetState returns the offsets reached in the files.
+func (t *Tail) GetState() interface{} {
+	t.Lock()
+	defer t.Unlock()
+
+	offsets := make(map[string]int64, len(t.offsets))
+	for file, offset := range t.offsets {
+		offsets[file] = offset
+	}
+	return offsets
+}
+
+// SetState restores the offsets to start reading the files from.
+func (t *Tail) SetState(state interface{}) error {
+	offsets, ok := state.(map[string]int64)
+	if !ok {
+		return fmt.Errorf("invalid state of type %T", state)
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	t.offsets = offsets
+	return nil
+}
+
 func (t *Tail) SetParser(parser parsers.Parser) {
 	t.parser = parser
 }
@@ -103,3 +103,43 @@ func TestTailBadLine(t *testing.T) {
 	acc.WaitError(1)
 	assert.Contains(t, acc.Errors[0].Error(), "E! Malformed log line")
 }
+
+func TestTailState(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+	first := "cpu,mytag=foo usage_idle=100\n"
+	_, err = tmpfile.WriteString(first + "cpu,mytag=bar usage_idle=50\n")
+	require.NoError(t, err)
+
+	tt := NewTail()
+	tt.FromBeginning = true
+	tt.Files = []string{tmpfile.Name()}
+	p, _ := parsers.NewInfluxParser()
+	tt.SetParser(p)
+	defer tmpfile.Close()
+
+	require.Error(t, tt.SetState("invalid"))
+	require.NoError(t, tt.SetState(map[string]int64{
+		tmpfile.Name(): int64(len(first)),
+	}))
+
+	acc := testutil.Accumulator{}
+	require.NoError(t, tt.Start(&acc))
+	acc.Wait(1)
+	tt.Stop()
+
+	// The first line was read by the previous run.
+	assert.Len(t, acc.Metrics, 1)
+	acc.AssertContainsTaggedFields(t, "cpu",
+		map[string]interface{}{
+			"usage_idle": float64(50),
+		},
+		map[string]string{
+			"mytag": "bar",
+		})
+
+	state, ok := tt.GetState().(map[string]int64)
+	require.True(t, ok)
+	assert.Contains(t, state, tmpfile.Name())
+}
@@ -0,0 +1,110 @@
+package azure_data_explorer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// metadataTokenURL is the token endpoint of the managed identities of
+	// the Azure VMs.
+	metadataTokenURL = "http://169.254.169.254/metadata/identity/oauth2/token"
+	loginURL         = "https://login.microsoftonline.com"
+)
+
+// tokenSource returns the Azure AD access tokens of the cluster, those of
+// an application if its secret is set, those of the managed identity of the
+// VM otherwise.
+type tokenSource struct {
+	client   *http.Client
+	resource string
+	// clientID is the application, or the user-assigned managed identity,
+	// empty for the system-assigned managed identity.
+	clientID     string
+	clientSecret string
+	tenantID     string
+	// metadataURL and loginURL are replaced by the tests.
+	metadataURL string
+	loginURL    string
+
+	mu          sync.Mutex
+	accessToken string
+	expiry      time.Time
+}
+
+// token returns a valid access token, requesting a new one when needed.
+func (ts *tokenSource) token() (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.accessToken != "" && time.Now().Before(ts.expiry) {
+		return ts.accessToken, nil
+	}
+
+	var req *http.Request
+	var err error
+	if ts.clientSecret != "" {
+		form := url.Values{}
+		form.Set("grant_type", "client_credentials")
+		form.Set("client_id", ts.clientID)
+		form.Set("client_secret", ts.clientSecret)
+		form.Set("scope", strings.TrimRight(ts.resource, "/")+"/.default")
+		u := fmt.Sprintf("%s/%s/oauth2/v2.0/token", ts.loginURL, ts.tenantID)
+		req, err = http.NewRequest("POST", u, strings.NewReader(form.Encode()))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	} else {
+		params := url.Values{}
+		params.Set("api-version", "2018-02-01")
+		params.Set("resource", ts.resource)
+		if ts.clientID != "" {
+			params.Set("client_id", ts.clientID)
+		}
+		req, err = http.NewRequest("GET", ts.metadataURL+"?"+params.Encode(), nil)
+		if err == nil {
+			req.Header.Set("Metadata", "true")
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := ts.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request failed with status code %d (%s)",
+			resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	// The managed identities return expires_in as a string.
+	var t struct {
+		AccessToken string      `json:"access_token"`
+		ExpiresIn   json.Number `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return "", fmt.Errorf("unable to parse token response, %s", err)
+	}
+	if t.AccessToken == "" {
+		return "", fmt.Errorf("no access token in token response")
+	}
+	expiresIn, err := strconv.ParseInt(string(t.ExpiresIn), 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid expires_in %q in token response", t.ExpiresIn)
+	}
+
+	ts.accessToken = t.AccessToken
+	// Renew the token a minute before it expires.
+	ts.expiry = time.Now().Add(time.Duration(expiresIn)*time.Second - time.Minute)
+	return ts.accessToken, nil
+}
@@ -0,0 +1,365 @@
+package azure_data_explorer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+const (
+	tablePerMetric = "tablepermetric"
+	singleTable    = "singletable"
+
+	ingestionQueued    = "queued"
+	ingestionStreaming = "streaming"
+)
+
+// AzureDataExplorer ingests the metrics into the tables of an Azure Data
+// Explorer database, each metric being a row with its name, tags, fields
+// and timestamp.
+type AzureDataExplorer struct {
+	Endpoint string `toml:"endpoint_url"`
+	Database string
+	// MetricsGrouping is "tablepermetric", the metrics being ingested into
+	// the table of their measurement, or "singletable", the metrics being
+	// ingested into TableName.
+	MetricsGrouping string `toml:"metrics_grouping_type"`
+	TableName       string
+	// CreateTables creates the tables and their IngestionMapping, named
+	// "<table>_mapping" if empty.
+	CreateTables     bool
+	IngestionMapping string
+	// IngestionType is "queued" or "streaming".
+	IngestionType string
+	Timeout       internal.Duration
+
+	// ClientID is the user-assigned managed identity, or the application with
+	// ClientSecret and TenantID.
+	ClientID     string `toml:"client_id"`
+	ClientSecret string
+	TenantID     string `toml:"tenant_id"`
+
+	client    *http.Client
+	tokens    *tokenSource
+	ingestURL string
+	// created are the tables created, or updated, since the start.
+	created   map[string]bool
+	resources *ingestionResources
+}
+
+var sampleConfig = `
+  ## URL of the Azure Data Explorer cluster.
+  endpoint_url = "https://mycluster.westeurope.kusto.windows.net"
+
+  ## Database of the tables.
+  database = "telegraf"
+
+  ## Ingest the metrics into the table of their measurement, with
+  ## "tablepermetric", or into table_name, with "singletable".
+  # metrics_grouping_type = "tablepermetric"
+  # table_name = ""
+
+  ## Create the tables and their JSON ingestion mapping, or add their missing
+  ## columns. Requires the table admin role on the database.
+  # create_tables = true
+
+  ## JSON ingestion mapping of the tables, "<table>_mapping" if empty.
+  # ingestion_mapping = ""
+
+  ## Ingestion type, "queued" or "streaming". Streaming ingestion has a low
+  ## latency but must be enabled on the cluster and the tables.
+  # ingestion_type = "queued"
+
+  ## Timeout of the requests.
+  # timeout = "20s"
+
+  ## The requests are authenticated with the managed identity of the VM,
+  ## client_id being a user-assigned identity, or with the application of
+  ## client_id and client_secret in the tenant tenant_id.
+  # client_id = ""
+  # client_secret = ""
+  # tenant_id = ""
+`
+
+func (a *AzureDataExplorer) Connect() error {
+	if a.Endpoint == "" || a.Database == "" {
+		return fmt.Errorf("endpoint_url and database are required")
+	}
+	switch strings.ToLower(a.MetricsGrouping) {
+	case tablePerMetric:
+	case singleTable:
+		if a.TableName == "" {
+			return fmt.Errorf("table_name is required with the singletable grouping")
+		}
+	default:
+		return fmt.Errorf("invalid metrics_grouping_type %q", a.MetricsGrouping)
+	}
+	switch a.IngestionType {
+	case ingestionQueued, ingestionStreaming:
+	default:
+		return fmt.Errorf("invalid ingestion_type %q", a.IngestionType)
+	}
+	if a.ClientSecret != "" && (a.ClientID == "" || a.TenantID == "") {
+		return fmt.Errorf("client_id and tenant_id are required with client_secret")
+	}
+
+	a.Endpoint = strings.TrimRight(a.Endpoint, "/")
+	if a.ingestURL == "" {
+		u, err := url.Parse(a.Endpoint)
+		if err != nil {
+			return fmt.Errorf("invalid endpoint_url %q: %s", a.Endpoint, err)
+		}
+		// The queued ingestion resources are those of the data management
+		// service of the cluster.
+		u.Host = "ingest-" + u.Host
+		a.ingestURL = u.String()
+	}
+
+	a.client = &http.Client{
+		Timeout: a.Timeout.Duration,
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+		},
+	}
+	if a.tokens == nil {
+		a.tokens = &tokenSource{
+			metadataURL: metadataTokenURL,
+			loginURL:    loginURL,
+		}
+	}
+	a.tokens.client = a.client
+	a.tokens.resource = a.Endpoint
+	a.tokens.clientID = a.ClientID
+	a.tokens.clientSecret = a.ClientSecret
+	a.tokens.tenantID = a.TenantID
+	a.created = make(map[string]bool)
+	return nil
+}
+
+func (a *AzureDataExplorer) Close() error {
+	return nil
+}
+
+func (a *AzureDataExplorer) SampleConfig() string {
+	return sampleConfig
+}
+
+func (a *AzureDataExplorer) Description() string {
+	return "Ingest metrics into Azure Data Explorer"
+}
+
+func (a *AzureDataExplorer) Write(metrics []telegraf.Metric) error {
+	var tables []string
+	rows := make(map[string][]byte)
+	for _, m := range metrics {
+		table := a.TableName
+		if strings.ToLower(a.MetricsGrouping) == tablePerMetric {
+			table = m.Name()
+		}
+		b, err := row(m)
+		if err != nil {
+			log.Printf("E! Azure Data Explorer: unable to serialize metric %s: %s", m.Name(), err)
+			continue
+		}
+		if _, ok := rows[table]; !ok {
+			tables = append(tables, table)
+		}
+		rows[table] = append(append(rows[table], b...), '\n')
+	}
+
+	for _, table := range tables {
+		if err := a.ingest(table, rows[table]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// row returns the JSON row of the metric.
+func row(m telegraf.Metric) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"name":      m.Name(),
+		"tags":      m.Tags(),
+		"fields":    m.Fields(),
+		"timestamp": m.Time().UTC().Format(time.RFC3339Nano),
+	})
+}
+
+func (a *AzureDataExplorer) mapping(table string) string {
+	if a.IngestionMapping != "" {
+		return a.IngestionMapping
+	}
+	return table + "_mapping"
+}
+
+func (a *AzureDataExplorer) ingest(table string, rows []byte) error {
+	if a.CreateTables && !a.created[table] {
+		if err := a.createTable(table); err != nil {
+			return fmt.Errorf("Azure Data Explorer: unable to create table %s: %s", table, err)
+		}
+		a.created[table] = true
+	}
+
+	var body bytes.Buffer
+	w := gzip.NewWriter(&body)
+	w.Write(rows)
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	var err error
+	if a.IngestionType == ingestionStreaming {
+		err = a.streamingIngest(table, body.Bytes())
+	} else {
+		err = a.queuedIngest(table, body.Bytes(), len(rows))
+	}
+	if err != nil {
+		return fmt.Errorf("Azure Data Explorer: unable to ingest into table %s: %s", table, err)
+	}
+	return nil
+}
+
+// createTable creates the table, or adds its missing columns, and its JSON
+// ingestion mapping.
+func (a *AzureDataExplorer) createTable(table string) error {
+	name := quoteName(table)
+	command := fmt.Sprintf(".create-merge table %s (['fields']:dynamic, ['name']:string, ['tags']:dynamic, ['timestamp']:datetime)", name)
+	if _, err := a.mgmt(a.Endpoint, command); err != nil {
+		return err
+	}
+
+	var columns []map[string]interface{}
+	for _, c := range []string{"fields", "name", "tags", "timestamp"} {
+		columns = append(columns, map[string]interface{}{
+			"column":     c,
+			"Properties": map[string]string{"Path": "$." + c},
+		})
+	}
+	mapping, err := json.Marshal(columns)
+	if err != nil {
+		return err
+	}
+	command = fmt.Sprintf(".create-or-alter table %s ingestion json mapping %s %s",
+		name, quoteString(a.mapping(table)), quoteString(string(mapping)))
+	_, err = a.mgmt(a.Endpoint, command)
+	return err
+}
+
+func (a *AzureDataExplorer) streamingIngest(table string, body []byte) error {
+	params := url.Values{}
+	params.Set("streamFormat", "multijson")
+	params.Set("mappingName", a.mapping(table))
+	u := fmt.Sprintf("%s/v1/rest/ingest/%s/%s?%s", a.Endpoint,
+		url.PathEscape(a.Database), url.PathEscape(table), params.Encode())
+	req, err := http.NewRequest("POST", u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	_, err = a.do(req)
+	return err
+}
+
+// kustoResult is the result of a management command in the v1 format of the
+// REST API, its first table being the result of the command.
+type kustoResult struct {
+	Tables []struct {
+		Columns []struct {
+			ColumnName string
+		}
+		Rows [][]interface{}
+	}
+}
+
+// column returns the values of the column of the first table of the result.
+func (r *kustoResult) column(name string) []string {
+	if len(r.Tables) == 0 {
+		return nil
+	}
+	t := r.Tables[0]
+	for i, c := range t.Columns {
+		if c.ColumnName != name {
+			continue
+		}
+		var values []string
+		for _, row := range t.Rows {
+			if i < len(row) {
+				s, _ := row[i].(string)
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// mgmt runs the management command on the database of the service.
+func (a *AzureDataExplorer) mgmt(service, command string) (*kustoResult, error) {
+	body, err := json.Marshal(map[string]string{"db": a.Database, "csl": command})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", service+"/v1/rest/mgmt", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Accept", "application/json")
+	b, err := a.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result kustoResult
+	if err := json.Unmarshal(b, &result); err != nil {
+		return nil, fmt.Errorf("unable to parse result of %q: %s", command, err)
+	}
+	return &result, nil
+}
+
+// do sends the request with an access token of the cluster, returning the
+// response body.
+func (a *AzureDataExplorer) do(req *http.Request) ([]byte, error) {
+	token, err := a.tokens.token()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get an access token: %s", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("x-ms-app", "Telegraf")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("request %s failed with status code %d: %s",
+			req.URL.Path, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+func init() {
+	outputs.Add("azure_data_explorer", func() telegraf.Output {
+		return &AzureDataExplorer{
+			MetricsGrouping: tablePerMetric,
+			CreateTables:    true,
+			IngestionType:   ingestionQueued,
+			Timeout:         internal.Duration{Duration: 20 * time.Second},
+		}
+	})
+}
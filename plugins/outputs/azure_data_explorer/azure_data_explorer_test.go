@@ -0,0 +1,234 @@
+package azure_data_explorer
+
+import (
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCluster is a cluster, with its data management service, its storage
+// and the managed identity endpoint.
+type fakeCluster struct {
+	t  *testing.T
+	ts *httptest.Server
+
+	mu        sync.Mutex
+	commands  []string
+	streamed  map[string]string
+	blobs     map[string]string
+	messages  []ingestionMessage
+	tokenHits int
+}
+
+func newFakeCluster(t *testing.T) *fakeCluster {
+	c := &fakeCluster{
+		t:        t,
+		streamed: make(map[string]string),
+		blobs:    make(map[string]string),
+	}
+	c.ts = httptest.NewServer(http.HandlerFunc(c.serve))
+	return c
+}
+
+func (c *fakeCluster) serve(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case r.URL.Path == "/metadata/token":
+		assert.Equal(c.t, "true", r.Header.Get("Metadata"))
+		assert.Equal(c.t, c.ts.URL, r.URL.Query().Get("resource"))
+		c.tokenHits++
+		fmt.Fprint(w, `{"access_token":"token-1","expires_in":"3599","token_type":"Bearer"}`)
+		return
+	case strings.HasPrefix(r.URL.Path, "/blobs/"), strings.HasPrefix(r.URL.Path, "/queue/"):
+		assert.Equal(c.t, "sig=sas", r.URL.RawQuery)
+	default:
+		assert.Equal(c.t, "Bearer token-1", r.Header.Get("Authorization"))
+	}
+
+	switch {
+	case r.URL.Path == "/v1/rest/mgmt":
+		var body struct {
+			DB  string `json:"db"`
+			CSL string `json:"csl"`
+		}
+		require.NoError(c.t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(c.t, "telegraf", body.DB)
+		c.commands = append(c.commands, body.CSL)
+		switch body.CSL {
+		case ".get ingestion resources":
+			fmt.Fprintf(w, `{"Tables":[{"TableName":"Table_0","Columns":[{"ColumnName":"ResourceTypeName","DataType":"String"},{"ColumnName":"StorageRoot","DataType":"String"}],"Rows":[["SecuredReadyForAggregationQueue","%[1]s/queue?sig=sas"],["TempStorage","%[1]s/blobs?sig=sas"],["FailedIngestionsQueue","%[1]s/failed?sig=sas"]]}]}`, c.ts.URL)
+		case ".get kusto identity token":
+			fmt.Fprint(w, `{"Tables":[{"TableName":"Table_0","Columns":[{"ColumnName":"AuthorizationContext","DataType":"String"}],"Rows":[["auth-context"]]}]}`)
+		default:
+			fmt.Fprint(w, `{"Tables":[{"TableName":"Table_0","Columns":[],"Rows":[]}]}`)
+		}
+	case strings.HasPrefix(r.URL.Path, "/v1/rest/ingest/"):
+		assert.Equal(c.t, "multijson", r.URL.Query().Get("streamFormat"))
+		assert.Equal(c.t, "gzip", r.Header.Get("Content-Encoding"))
+		c.streamed[r.URL.Path+"?"+r.URL.Query().Get("mappingName")] = c.gunzip(r)
+	case strings.HasPrefix(r.URL.Path, "/blobs/"):
+		assert.Equal(c.t, "PUT", r.Method)
+		assert.Equal(c.t, "BlockBlob", r.Header.Get("x-ms-blob-type"))
+		c.blobs[c.ts.URL+r.URL.Path+"?sig=sas"] = c.gunzip(r)
+		w.WriteHeader(http.StatusCreated)
+	case r.URL.Path == "/queue/messages":
+		var queueMsg struct {
+			MessageText string
+		}
+		require.NoError(c.t, xml.NewDecoder(r.Body).Decode(&queueMsg))
+		b, err := base64.StdEncoding.DecodeString(queueMsg.MessageText)
+		require.NoError(c.t, err)
+		var msg ingestionMessage
+		require.NoError(c.t, json.Unmarshal(b, &msg))
+		c.messages = append(c.messages, msg)
+		w.WriteHeader(http.StatusCreated)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (c *fakeCluster) gunzip(r *http.Request) string {
+	zr, err := gzip.NewReader(r.Body)
+	require.NoError(c.t, err)
+	b, err := ioutil.ReadAll(zr)
+	require.NoError(c.t, err)
+	return string(b)
+}
+
+func (c *fakeCluster) output(grouping, ingestion string) *AzureDataExplorer {
+	return &AzureDataExplorer{
+		Endpoint:        c.ts.URL,
+		Database:        "telegraf",
+		MetricsGrouping: grouping,
+		TableName:       "metrics",
+		CreateTables:    true,
+		IngestionType:   ingestion,
+		Timeout:         internal.Duration{Duration: 5 * time.Second},
+		ingestURL:       c.ts.URL,
+		tokens: &tokenSource{
+			metadataURL: c.ts.URL + "/metadata/token",
+		},
+	}
+}
+
+func testMetrics(t *testing.T) []telegraf.Metric {
+	cpu, err := metric.New("cpu",
+		map[string]string{"host": "server-1"},
+		map[string]interface{}{"usage_idle": 91.5},
+		time.Unix(1500000000, 0))
+	require.NoError(t, err)
+	mem, err := metric.New("mem",
+		map[string]string{"host": "server-1"},
+		map[string]interface{}{"used": int64(1024)},
+		time.Unix(1500000000, 500))
+	require.NoError(t, err)
+	return []telegraf.Metric{cpu, mem}
+}
+
+const (
+	cpuRow = `{"fields":{"usage_idle":91.5},"name":"cpu","tags":{"host":"server-1"},"timestamp":"2017-07-14T02:40:00Z"}` + "\n"
+	memRow = `{"fields":{"used":1024},"name":"mem","tags":{"host":"server-1"},"timestamp":"2017-07-14T02:40:00.0000005Z"}` + "\n"
+)
+
+func TestStreamingIngestion(t *testing.T) {
+	c := newFakeCluster(t)
+	defer c.ts.Close()
+
+	a := c.output(tablePerMetric, ingestionStreaming)
+	require.NoError(t, a.Connect())
+	require.NoError(t, a.Write(testMetrics(t)))
+	require.NoError(t, a.Write(testMetrics(t)[:1]))
+
+	assert.Equal(t, map[string]string{
+		"/v1/rest/ingest/telegraf/cpu?cpu_mapping": cpuRow,
+		"/v1/rest/ingest/telegraf/mem?mem_mapping": memRow,
+	}, c.streamed)
+	// The tables are created once, and the token is reused.
+	require.Len(t, c.commands, 4)
+	assert.Equal(t, ".create-merge table ['cpu'] (['fields']:dynamic, ['name']:string, ['tags']:dynamic, ['timestamp']:datetime)", c.commands[0])
+	assert.Equal(t, `.create-or-alter table ['cpu'] ingestion json mapping 'cpu_mapping' '[{"Properties":{"Path":"$.fields"},"column":"fields"},{"Properties":{"Path":"$.name"},"column":"name"},{"Properties":{"Path":"$.tags"},"column":"tags"},{"Properties":{"Path":"$.timestamp"},"column":"timestamp"}]'`, c.commands[1])
+	assert.True(t, strings.HasPrefix(c.commands[2], ".create-merge table ['mem']"))
+	assert.Equal(t, 1, c.tokenHits)
+}
+
+func TestQueuedIngestion(t *testing.T) {
+	c := newFakeCluster(t)
+	defer c.ts.Close()
+
+	a := c.output(singleTable, ingestionQueued)
+	a.IngestionMapping = "telegraf_mapping"
+	require.NoError(t, a.Connect())
+	require.NoError(t, a.Write(testMetrics(t)))
+	require.NoError(t, a.Write(testMetrics(t)))
+
+	// The ingestion resources are fetched once.
+	require.Len(t, c.commands, 4)
+	assert.Equal(t, ".get ingestion resources", c.commands[2])
+	assert.Equal(t, ".get kusto identity token", c.commands[3])
+
+	require.Len(t, c.messages, 2)
+	msg := c.messages[0]
+	assert.Equal(t, "telegraf", msg.DatabaseName)
+	assert.Equal(t, "metrics", msg.TableName)
+	assert.Equal(t, len(cpuRow+memRow), msg.RawDataSize)
+	assert.Equal(t, map[string]string{
+		"authorizationContext":      "auth-context",
+		"format":                    "multijson",
+		"ingestionMappingReference": "telegraf_mapping",
+		"ingestionMappingType":      "Json",
+	}, msg.AdditionalProperties)
+	assert.NotEqual(t, msg.ID, c.messages[1].ID)
+	assert.True(t, strings.HasPrefix(msg.BlobPath, c.ts.URL+"/blobs/telegraf__metrics__"+msg.ID))
+	assert.Equal(t, cpuRow+memRow, c.blobs[msg.BlobPath])
+}
+
+func TestWriteError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/metadata/token" {
+			fmt.Fprint(w, `{"access_token":"token-1","expires_in":3599}`)
+			return
+		}
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	a := &AzureDataExplorer{
+		Endpoint:        ts.URL,
+		Database:        "telegraf",
+		MetricsGrouping: tablePerMetric,
+		IngestionType:   ingestionStreaming,
+		tokens:          &tokenSource{metadataURL: ts.URL + "/metadata/token"},
+	}
+	require.NoError(t, a.Connect())
+	err := a.Write(testMetrics(t))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "403")
+}
+
+func TestConnectErrors(t *testing.T) {
+	a := &AzureDataExplorer{Endpoint: "https://cluster", Database: "telegraf",
+		MetricsGrouping: singleTable, IngestionType: ingestionQueued}
+	assert.Error(t, a.Connect())
+
+	a = &AzureDataExplorer{Endpoint: "https://cluster", Database: "telegraf",
+		MetricsGrouping: tablePerMetric, IngestionType: "managed"}
+	assert.Error(t, a.Connect())
+}
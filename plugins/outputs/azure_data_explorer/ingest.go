@@ -0,0 +1,195 @@
+package azure_data_explorer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	mathrand "math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// resourcesTTL is the duration after which the ingestion resources are
+// fetched again, their SAS URLs being renewed by the service.
+const resourcesTTL = time.Hour
+
+// ingestionResources are the queues and the blob containers of the queued
+// ingestion, and the authorization context of the ingestion messages.
+type ingestionResources struct {
+	queues     []string
+	containers []string
+	authCtx    string
+	fetched    time.Time
+}
+
+// ingestionMessage is the message queued for each blob to ingest.
+type ingestionMessage struct {
+	ID                   string            `json:"Id"`
+	BlobPath             string            `json:"BlobPath"`
+	RawDataSize          int               `json:"RawDataSize"`
+	DatabaseName         string            `json:"DatabaseName"`
+	TableName            string            `json:"TableName"`
+	RetainBlobOnSuccess  bool              `json:"RetainBlobOnSuccess"`
+	FlushImmediately     bool              `json:"FlushImmediately"`
+	ReportLevel          int               `json:"ReportLevel"`
+	ReportMethod         int               `json:"ReportMethod"`
+	AdditionalProperties map[string]string `json:"AdditionalProperties"`
+}
+
+// quoteName returns the name as a Kusto quoted identifier.
+func quoteName(name string) string {
+	return "[" + quoteString(name) + "]"
+}
+
+// quoteString returns the string as a Kusto string literal.
+func quoteString(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `'`, `\'`, -1)
+	return "'" + s + "'"
+}
+
+// uuid returns a random, version 4, UUID.
+func uuid() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// getResources returns the ingestion resources, fetching them from the data
+// management service of the cluster when they are too old.
+func (a *AzureDataExplorer) getResources() (*ingestionResources, error) {
+	if a.resources != nil && time.Since(a.resources.fetched) < resourcesTTL {
+		return a.resources, nil
+	}
+
+	result, err := a.mgmt(a.ingestURL, ".get ingestion resources")
+	if err != nil {
+		return nil, err
+	}
+	r := &ingestionResources{fetched: time.Now()}
+	types := result.column("ResourceTypeName")
+	roots := result.column("StorageRoot")
+	for i := range types {
+		if i >= len(roots) {
+			break
+		}
+		switch types[i] {
+		case "SecuredReadyForAggregationQueue":
+			r.queues = append(r.queues, roots[i])
+		case "TempStorage":
+			r.containers = append(r.containers, roots[i])
+		}
+	}
+	if len(r.queues) == 0 || len(r.containers) == 0 {
+		return nil, fmt.Errorf("no ingestion queue or temporary storage in the ingestion resources")
+	}
+
+	result, err = a.mgmt(a.ingestURL, ".get kusto identity token")
+	if err != nil {
+		return nil, err
+	}
+	tokens := result.column("AuthorizationContext")
+	if len(tokens) == 0 || tokens[0] == "" {
+		return nil, fmt.Errorf("no authorization context in the identity token")
+	}
+	r.authCtx = tokens[0]
+
+	a.resources = r
+	return r, nil
+}
+
+// queuedIngest uploads the rows to a temporary blob and queues its
+// ingestion, the ingestion itself being asynchronous.
+func (a *AzureDataExplorer) queuedIngest(table string, body []byte, size int) error {
+	r, err := a.getResources()
+	if err != nil {
+		return fmt.Errorf("unable to get the ingestion resources: %s", err)
+	}
+
+	id, err := uuid()
+	if err != nil {
+		return err
+	}
+	container, err := url.Parse(r.containers[mathrand.Intn(len(r.containers))])
+	if err != nil {
+		return fmt.Errorf("invalid temporary storage: %s", err)
+	}
+	container.Path += fmt.Sprintf("/%s__%s__%s.multijson.gz", a.Database, table, id)
+	blob := container.String()
+	if err := a.storageRequest("PUT", blob, body, map[string]string{
+		"x-ms-blob-type": "BlockBlob",
+		"Content-Type":   "application/octet-stream",
+	}); err != nil {
+		return fmt.Errorf("unable to upload blob: %s", err)
+	}
+
+	msg, err := json.Marshal(ingestionMessage{
+		ID:           id,
+		BlobPath:     blob,
+		RawDataSize:  size,
+		DatabaseName: a.Database,
+		TableName:    table,
+		// Only the failures are reported, in the ingestion failures of the
+		// cluster.
+		ReportLevel:  0,
+		ReportMethod: 0,
+		AdditionalProperties: map[string]string{
+			"authorizationContext":      r.authCtx,
+			"format":                    "multijson",
+			"ingestionMappingReference": a.mapping(table),
+			"ingestionMappingType":      "Json",
+		},
+	})
+	if err != nil {
+		return err
+	}
+	var queueMsg bytes.Buffer
+	queueMsg.WriteString("<QueueMessage><MessageText>")
+	xml.EscapeText(&queueMsg, []byte(base64.StdEncoding.EncodeToString(msg)))
+	queueMsg.WriteString("</MessageText></QueueMessage>")
+
+	queue, err := url.Parse(r.queues[mathrand.Intn(len(r.queues))])
+	if err != nil {
+		return fmt.Errorf("invalid ingestion queue: %s", err)
+	}
+	queue.Path += "/messages"
+	if err := a.storageRequest("POST", queue.String(), queueMsg.Bytes(), map[string]string{
+		"Content-Type": "application/xml",
+	}); err != nil {
+		return fmt.Errorf("unable to queue ingestion: %s", err)
+	}
+	return nil
+}
+
+// storageRequest sends a request to the storage of the ingestion resources,
+// authorized by the SAS of their URL.
+func (a *AzureDataExplorer) storageRequest(method, u string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-ms-version", "2019-12-12")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s %s failed with status code %d (%s)", method, req.URL.Path,
+			resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	return nil
+}
@@ -0,0 +1,314 @@
+package bigquery
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"time"
+
+	"golang.org/x/net/http2"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/gcp"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+// maxRequestSize is the size of the rows of an AppendRows request above
+// which they are split in several requests, the API limit being 10MB.
+const maxRequestSize = 9 * 1024 * 1024
+
+// bigqueryScope is the OAuth2 scope of the tokens of the requests.
+const bigqueryScope = "https://www.googleapis.com/auth/bigquery"
+
+// timestampColumn is the column of the time of the metrics, in microseconds.
+const timestampColumn = "timestamp"
+
+var invalidNameCharRE = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// BigQuery appends the metrics to the tables of a BigQuery dataset with the
+// Storage Write API.
+type BigQuery struct {
+	Project         string
+	Dataset         string
+	CredentialsFile string
+	// Endpoint is the URL of the Storage Write API, the requests being made
+	// over HTTP/2 without TLS, and without authentication, with the http
+	// scheme.
+	Endpoint string
+	Timeout  internal.Duration
+	// CompactTable appends the metrics to CompactTableName, their tags and
+	// fields being JSON columns, instead of to the table of their
+	// measurement, their tags and fields being columns.
+	CompactTable     bool
+	CompactTableName string
+
+	client *http.Client
+	tokens *gcp.TokenSource
+}
+
+var sampleConfig = `
+  ## GCP project and BigQuery dataset of the tables.
+  project = "my-project"
+  dataset = "telegraf"
+
+  ## Key file of the service account appending the rows. If empty, the
+  ## service account of the GCE instance is used.
+  # credentials_file = "path/to/my/creds.json"
+
+  ## URL of the Storage Write API.
+  # endpoint = "https://bigquerystorage.googleapis.com"
+
+  ## Timeout of the requests.
+  # timeout = "5s"
+
+  ## Append the metrics to the compact_table_name table, with the columns
+  ## name, timestamp, tags and fields, tags and fields being JSON columns,
+  ## instead of to the table of their measurement.
+  # compact_table = false
+  # compact_table_name = "telegraf_metrics"
+`
+
+func (b *BigQuery) Connect() error {
+	if b.Project == "" || b.Dataset == "" {
+		return fmt.Errorf("project and dataset are required")
+	}
+	if b.CompactTable && b.CompactTableName == "" {
+		return fmt.Errorf("compact_table_name is required with compact_table")
+	}
+	u, err := url.Parse(b.Endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint %q: %s", b.Endpoint, err)
+	}
+
+	// The API is only available over gRPC.
+	var transport http.RoundTripper
+	if u.Scheme == "http" {
+		timeout := b.Timeout.Duration
+		transport = &http2.Transport{
+			AllowHTTP: true,
+			DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+				return net.DialTimeout(network, addr, timeout)
+			},
+		}
+	} else {
+		transport = &http2.Transport{}
+		b.tokens, err = gcp.NewTokenSource(&http.Client{
+			Timeout: b.Timeout.Duration,
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+			},
+		}, b.CredentialsFile, bigqueryScope)
+		if err != nil {
+			return err
+		}
+	}
+	b.client = &http.Client{
+		Timeout:   b.Timeout.Duration,
+		Transport: transport,
+	}
+	return nil
+}
+
+func (b *BigQuery) Close() error {
+	return nil
+}
+
+func (b *BigQuery) SampleConfig() string {
+	return sampleConfig
+}
+
+func (b *BigQuery) Description() string {
+	return "Append metrics to Google Cloud BigQuery tables"
+}
+
+func (b *BigQuery) Write(metrics []telegraf.Metric) error {
+	var tables []string
+	byTable := make(map[string][]telegraf.Metric)
+	for _, m := range metrics {
+		table := b.CompactTableName
+		if !b.CompactTable {
+			table = invalidNameCharRE.ReplaceAllString(m.Name(), "_")
+		}
+		if _, ok := byTable[table]; !ok {
+			tables = append(tables, table)
+		}
+		byTable[table] = append(byTable[table], m)
+	}
+
+	for _, table := range tables {
+		var columns []*column
+		var rows [][]byte
+		if b.CompactTable {
+			columns, rows = compactRows(byTable[table])
+		} else {
+			columns, rows = tableRows(byTable[table])
+		}
+		descriptor := marshalDescriptor(columns)
+
+		for len(rows) > 0 {
+			n, size := 0, 0
+			for n < len(rows) && (n == 0 || size+len(rows[n]) <= maxRequestSize) {
+				size += len(rows[n])
+				n++
+			}
+			if err := b.appendRows(table, descriptor, rows[:n]); err != nil {
+				return fmt.Errorf("BigQuery: unable to append rows to table %s: %s", table, err)
+			}
+			rows = rows[n:]
+		}
+	}
+	return nil
+}
+
+// tableRows returns the columns of the metrics, the timestamp, their tags and
+// their fields, and their rows. The columns of the tags which are also fields
+// are those of the fields, the type of a field being that of its first value.
+func tableRows(metrics []telegraf.Metric) ([]*column, [][]byte) {
+	types := make(map[string]int)
+	for _, m := range metrics {
+		for k, v := range m.Fields() {
+			if _, ok := types[k]; ok || k == timestampColumn {
+				continue
+			}
+			if typ := fieldType(v); typ != 0 {
+				types[k] = typ
+			}
+		}
+	}
+	for _, m := range metrics {
+		for k := range m.Tags() {
+			if _, ok := types[k]; !ok && k != timestampColumn {
+				types[k] = typeString
+			}
+		}
+	}
+
+	names := make([]string, 0, len(types))
+	for k := range types {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	columns := []*column{{name: timestampColumn, number: 1, typ: typeInt64}}
+	for i, k := range names {
+		columns = append(columns, &column{name: k, number: i + 2, typ: types[k]})
+	}
+
+	rows := make([][]byte, 0, len(metrics))
+	for _, m := range metrics {
+		row := appendValue(nil, columns[0], m.Time().UnixNano()/int64(time.Microsecond))
+		fields := m.Fields()
+		tags := m.Tags()
+		for _, c := range columns[1:] {
+			v, ok := fields[c.name]
+			if !ok {
+				if tag, ok := tags[c.name]; ok {
+					v = tag
+				}
+			}
+			if v = convert(v, c.typ); v != nil {
+				row = appendValue(row, c, v)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return columns, rows
+}
+
+// compactRows returns the columns of the compact table and the rows of the
+// metrics.
+func compactRows(metrics []telegraf.Metric) ([]*column, [][]byte) {
+	columns := []*column{
+		{name: "name", number: 1, typ: typeString},
+		{name: timestampColumn, number: 2, typ: typeInt64},
+		{name: "tags", number: 3, typ: typeString},
+		{name: "fields", number: 4, typ: typeString},
+	}
+
+	rows := make([][]byte, 0, len(metrics))
+	for _, m := range metrics {
+		tags, err := json.Marshal(m.Tags())
+		if err != nil {
+			log.Printf("E! BigQuery: unable to serialize the tags of metric %s: %s", m.Name(), err)
+			continue
+		}
+		fields, err := json.Marshal(m.Fields())
+		if err != nil {
+			log.Printf("E! BigQuery: unable to serialize the fields of metric %s: %s", m.Name(), err)
+			continue
+		}
+		row := appendValue(nil, columns[0], m.Name())
+		row = appendValue(row, columns[1], m.Time().UnixNano()/int64(time.Microsecond))
+		row = appendValue(row, columns[2], string(tags))
+		row = appendValue(row, columns[3], string(fields))
+		rows = append(rows, row)
+	}
+	return columns, rows
+}
+
+// fieldType returns the type of the column of the field value, 0 if it is
+// not supported.
+func fieldType(v interface{}) int {
+	switch v.(type) {
+	case float64:
+		return typeDouble
+	case int64, uint64:
+		return typeInt64
+	case bool:
+		return typeBool
+	case string:
+		return typeString
+	}
+	return 0
+}
+
+// convert returns the value as a value of the type, nil if it can not be
+// converted.
+func convert(v interface{}, typ int) interface{} {
+	switch typ {
+	case typeDouble:
+		switch v := v.(type) {
+		case float64:
+			return v
+		case int64:
+			return float64(v)
+		case uint64:
+			return float64(v)
+		}
+	case typeInt64:
+		switch v := v.(type) {
+		case int64:
+			return v
+		case uint64:
+			if v > uint64(1<<63-1) {
+				return int64(1<<63 - 1)
+			}
+			return int64(v)
+		}
+	case typeBool:
+		if v, ok := v.(bool); ok {
+			return v
+		}
+	case typeString:
+		if v, ok := v.(string); ok {
+			return v
+		}
+	}
+	return nil
+}
+
+func init() {
+	outputs.Add("bigquery", func() telegraf.Output {
+		return &BigQuery{
+			Endpoint:         "https://bigquerystorage.googleapis.com",
+			Timeout:          internal.Duration{Duration: 5 * time.Second},
+			CompactTableName: "telegraf_metrics",
+		}
+	})
+}
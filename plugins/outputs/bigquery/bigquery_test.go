@@ -0,0 +1,263 @@
+package bigquery
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// appendRowsRequest is a decoded AppendRowsRequest, its rows being decoded
+// with their writer schema.
+type appendRowsRequest struct {
+	stream  string
+	columns map[string]int
+	rows    []map[string]interface{}
+}
+
+func decodeRequest(t *testing.T, b []byte) *appendRowsRequest {
+	r := &appendRowsRequest{columns: make(map[string]int)}
+	names := make(map[int]string)
+	var rows [][]byte
+	require.NoError(t, walkFields(b, func(field int, v uint64, data []byte) error {
+		switch field {
+		case 1:
+			r.stream = string(data)
+		case 4:
+			return walkFields(data, func(field int, v uint64, data []byte) error {
+				switch field {
+				case 1:
+					// ProtoSchema.proto_descriptor.field
+					return walkFields(data, func(field int, v uint64, data []byte) error {
+						return walkFields(data, func(field int, v uint64, data []byte) error {
+							if field != 2 {
+								return nil
+							}
+							var name string
+							var number, typ int
+							walkFields(data, func(field int, v uint64, data []byte) error {
+								switch field {
+								case 1:
+									name = string(data)
+								case 3:
+									number = int(v)
+								case 5:
+									typ = int(v)
+								}
+								return nil
+							})
+							names[number] = name
+							r.columns[name] = typ
+							return nil
+						})
+					})
+				case 2:
+					return walkFields(data, func(field int, v uint64, data []byte) error {
+						rows = append(rows, data)
+						return nil
+					})
+				}
+				return nil
+			})
+		}
+		return nil
+	}))
+
+	for _, row := range rows {
+		values := make(map[string]interface{})
+		require.NoError(t, walkFields(row, func(field int, v uint64, data []byte) error {
+			name := names[field]
+			switch r.columns[name] {
+			case typeDouble:
+				values[name] = math.Float64frombits(v)
+			case typeInt64:
+				values[name] = int64(v)
+			case typeBool:
+				values[name] = v == 1
+			default:
+				values[name] = string(data)
+			}
+			return nil
+		}))
+		r.rows = append(r.rows, values)
+	}
+	return r
+}
+
+// serveH2C serves the AppendRows calls over HTTP/2 without TLS, responding
+// with the response and the status.
+func serveH2C(t *testing.T, response []byte, status string) (string, func() []*appendRowsRequest, func()) {
+	var mu sync.Mutex
+	var requests []*appendRowsRequest
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, appendRowsMethod, r.URL.Path)
+		assert.Equal(t, "application/grpc", r.Header.Get("Content-Type"))
+		assert.Equal(t, "5000m", r.Header.Get("Grpc-Timeout"))
+
+		b, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.True(t, len(b) > 5)
+		assert.Equal(t, len(b)-5, int(binary.BigEndian.Uint32(b[1:5])))
+		request := decodeRequest(t, b[5:])
+		assert.Equal(t, "write_stream="+url.QueryEscape(request.stream),
+			r.Header.Get("X-Goog-Request-Params"))
+		mu.Lock()
+		requests = append(requests, request)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+		msg := make([]byte, 5, 5+len(response))
+		binary.BigEndian.PutUint32(msg[1:], uint32(len(response)))
+		w.Write(append(msg, response...))
+		w.Header().Set("Grpc-Status", status)
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go (&http2.Server{}).ServeConn(conn, &http2.ServeConnOpts{Handler: handler})
+		}
+	}()
+	get := func() []*appendRowsRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+	return "http://" + l.Addr().String(), get, func() { l.Close() }
+}
+
+func testMetrics(t *testing.T) []telegraf.Metric {
+	cpu1, err := metric.New("cpu",
+		map[string]string{"host": "server-1", "cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": 91.5, "up": true},
+		time.Unix(1500000000, 0))
+	require.NoError(t, err)
+	cpu2, err := metric.New("cpu",
+		map[string]string{"host": "server-2"},
+		map[string]interface{}{"usage_idle": int64(90), "state": "ok"},
+		time.Unix(1500000001, 0))
+	require.NoError(t, err)
+	mem, err := metric.New("mem-host",
+		map[string]string{"host": "server-1"},
+		map[string]interface{}{"used": int64(1024)},
+		time.Unix(1500000000, 1000))
+	require.NoError(t, err)
+	return []telegraf.Metric{cpu1, cpu2, mem}
+}
+
+func newOutput(endpoint string) *BigQuery {
+	return &BigQuery{
+		Project:          "my-project",
+		Dataset:          "telegraf",
+		Endpoint:         endpoint,
+		Timeout:          internal.Duration{Duration: 5 * time.Second},
+		CompactTableName: "telegraf_metrics",
+	}
+}
+
+func TestWrite(t *testing.T) {
+	endpoint, requests, stop := serveH2C(t, nil, "0")
+	defer stop()
+
+	b := newOutput(endpoint)
+	require.NoError(t, b.Connect())
+	require.NoError(t, b.Write(testMetrics(t)))
+
+	r := requests()
+	require.Len(t, r, 2)
+	assert.Equal(t, "projects/my-project/datasets/telegraf/tables/cpu/streams/_default", r[0].stream)
+	assert.Equal(t, map[string]int{
+		"timestamp":  typeInt64,
+		"cpu":        typeString,
+		"host":       typeString,
+		"state":      typeString,
+		"up":         typeBool,
+		"usage_idle": typeDouble,
+	}, r[0].columns)
+	assert.Equal(t, []map[string]interface{}{
+		{"timestamp": int64(1500000000000000), "cpu": "cpu0", "host": "server-1", "up": true, "usage_idle": 91.5},
+		{"timestamp": int64(1500000001000000), "host": "server-2", "state": "ok", "usage_idle": 90.0},
+	}, r[0].rows)
+
+	assert.Equal(t, "projects/my-project/datasets/telegraf/tables/mem_host/streams/_default", r[1].stream)
+	assert.Equal(t, []map[string]interface{}{
+		{"timestamp": int64(1500000000000001), "host": "server-1", "used": int64(1024)},
+	}, r[1].rows)
+}
+
+func TestWriteCompact(t *testing.T) {
+	endpoint, requests, stop := serveH2C(t, nil, "0")
+	defer stop()
+
+	b := newOutput(endpoint)
+	b.CompactTable = true
+	require.NoError(t, b.Connect())
+	require.NoError(t, b.Write(testMetrics(t)))
+
+	r := requests()
+	require.Len(t, r, 1)
+	assert.Equal(t, "projects/my-project/datasets/telegraf/tables/telegraf_metrics/streams/_default", r[0].stream)
+	assert.Equal(t, map[string]int{
+		"name":      typeString,
+		"timestamp": typeInt64,
+		"tags":      typeString,
+		"fields":    typeString,
+	}, r[0].columns)
+	require.Len(t, r[0].rows, 3)
+	assert.Equal(t, map[string]interface{}{
+		"name":      "cpu",
+		"timestamp": int64(1500000000000000),
+		"tags":      `{"cpu":"cpu0","host":"server-1"}`,
+		"fields":    `{"up":true,"usage_idle":91.5}`,
+	}, r[0].rows[0])
+}
+
+func TestWriteErrors(t *testing.T) {
+	// A retryable error.
+	endpoint, requests, stop := serveH2C(t, nil, "14")
+	defer stop()
+	b := newOutput(endpoint)
+	require.NoError(t, b.Connect())
+	err := b.Write(testMetrics(t))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "grpc-status 14")
+	assert.Len(t, requests(), 1)
+
+	// The rejected rows are dropped.
+	var rowErrors []byte
+	rowErrors = appendVarint(rowErrors, 1, 1)
+	rowErrors = appendVarint(rowErrors, 2, 1)
+	rowErrors = appendString(rowErrors, 3, "invalid value")
+	var status []byte
+	status = appendVarint(status, 1, codeInvalidArgument)
+	status = appendString(status, 2, "rows rejected")
+	var response []byte
+	response = appendMessage(response, 2, status)
+	response = appendMessage(response, 4, rowErrors)
+	endpoint, requests, stop = serveH2C(t, response, "0")
+	defer stop()
+	b = newOutput(endpoint)
+	require.NoError(t, b.Connect())
+	require.NoError(t, b.Write(testMetrics(t)))
+	assert.Len(t, requests(), 2)
+}
@@ -0,0 +1,113 @@
+package bigquery
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// appendRowsMethod is the path of the AppendRows call of the Storage Write
+// API.
+const appendRowsMethod = "/google.cloud.bigquery.storage.v1.BigQueryWrite/AppendRows"
+
+// codeInvalidArgument is the gRPC status code of the requests whose rows
+// or schema are rejected, appending them again would fail again.
+const codeInvalidArgument = 3
+
+// appendRows appends the rows to the default stream of the table, the
+// AppendRows streaming call being made with a single request.
+func (b *BigQuery) appendRows(table string, descriptor []byte, rows [][]byte) error {
+	stream := fmt.Sprintf("projects/%s/datasets/%s/tables/%s/streams/_default",
+		b.Project, b.Dataset, table)
+	request := marshalAppendRowsRequest(stream, descriptor, rows)
+
+	// A gRPC message is prefixed by its compression flag and its length.
+	body := make([]byte, 5, 5+len(request))
+	binary.BigEndian.PutUint32(body[1:], uint32(len(request)))
+	body = append(body, request...)
+
+	req, err := http.NewRequest("POST", strings.TrimRight(b.Endpoint, "/")+appendRowsMethod,
+		bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	req.Header.Set("User-Agent", "Telegraf")
+	// The stream routes the request to the region of the table.
+	req.Header.Set("X-Goog-Request-Params", "write_stream="+url.QueryEscape(stream))
+	if b.Timeout.Duration > 0 {
+		req.Header.Set("Grpc-Timeout", fmt.Sprintf("%dm", b.Timeout.Duration/time.Millisecond))
+	}
+	if b.tokens != nil {
+		token, err := b.tokens.Token()
+		if err != nil {
+			return fmt.Errorf("unable to get an access token: %s", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// The status is in the trailers, read with the body.
+	msg, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request failed with status code %d (%s)",
+			resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	status := resp.Trailer.Get("Grpc-Status")
+	message := resp.Trailer.Get("Grpc-Message")
+	if status == "" {
+		// A Trailers-Only response.
+		status = resp.Header.Get("Grpc-Status")
+		message = resp.Header.Get("Grpc-Message")
+	}
+	code, err := strconv.Atoi(status)
+	if err != nil {
+		return fmt.Errorf("request failed with invalid grpc-status %q", status)
+	}
+	if m, err := url.PathUnescape(message); err == nil {
+		message = m
+	}
+
+	var response *appendRowsResponse
+	if len(msg) >= 5 && msg[0] == 0 && int(binary.BigEndian.Uint32(msg[1:5])) <= len(msg)-5 {
+		response, err = unmarshalAppendRowsResponse(msg[5 : 5+binary.BigEndian.Uint32(msg[1:5])])
+		if err != nil {
+			return fmt.Errorf("invalid AppendRowsResponse: %s", err)
+		}
+	}
+	if code == 0 && response != nil && response.code != 0 {
+		code, message = response.code, response.message
+	}
+	if response != nil && len(response.rowErrors) > 0 {
+		// None of the rows are appended.
+		for _, e := range response.rowErrors {
+			log.Printf("E! BigQuery: row %d of table %s rejected: %s", e.index, table, e.message)
+		}
+		log.Printf("E! BigQuery: dropping %d rows of table %s", len(rows), table)
+		return nil
+	}
+	switch code {
+	case 0:
+		return nil
+	case codeInvalidArgument:
+		log.Printf("E! BigQuery: dropping %d rows of table %s: %s", len(rows), table, message)
+		return nil
+	}
+	return fmt.Errorf("request failed with grpc-status %d: %s", code, message)
+}
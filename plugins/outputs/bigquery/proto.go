@@ -0,0 +1,227 @@
+package bigquery
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// The messages of the AppendRows call of the Storage Write API, encoded as
+// the google.cloud.bigquery.storage.v1 messages:
+// AppendRowsRequest{string write_stream = 1; ProtoData proto_rows = 4},
+// ProtoData{ProtoSchema writer_schema = 1; ProtoRows rows = 2},
+// ProtoSchema{DescriptorProto proto_descriptor = 1} and
+// ProtoRows{repeated bytes serialized_rows = 1}.
+//
+// The rows are messages whose DescriptorProto is the writer schema,
+// DescriptorProto{string name = 1; repeated FieldDescriptorProto field = 2}
+// and FieldDescriptorProto{string name = 1; int32 number = 3;
+// Label label = 4; Type type = 5}.
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// The types of FieldDescriptorProto of the columns.
+const (
+	typeDouble = 1
+	typeInt64  = 3
+	typeBool   = 8
+	typeString = 9
+
+	labelOptional = 1
+)
+
+// column is a column of the rows, and a field of their writer schema.
+type column struct {
+	name string
+	// number is the number of the field of the column in the rows.
+	number int
+	typ    int
+}
+
+// marshalDescriptor returns the DescriptorProto of the rows of the columns.
+func marshalDescriptor(columns []*column) []byte {
+	var buf []byte
+	buf = appendString(buf, 1, "Row")
+	for _, c := range columns {
+		var fb []byte
+		fb = appendString(fb, 1, c.name)
+		fb = appendVarint(fb, 3, uint64(c.number))
+		fb = appendVarint(fb, 4, labelOptional)
+		fb = appendVarint(fb, 5, uint64(c.typ))
+		buf = appendMessage(buf, 2, fb)
+	}
+	return buf
+}
+
+// appendValue appends the value of the column to the serialized row, the
+// value having the type of the column.
+func appendValue(buf []byte, c *column, v interface{}) []byte {
+	switch c.typ {
+	case typeDouble:
+		buf = appendKey(buf, c.number, wireFixed64)
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], math.Float64bits(v.(float64)))
+		return append(buf, b[:]...)
+	case typeInt64:
+		return appendVarint(buf, c.number, uint64(v.(int64)))
+	case typeBool:
+		var b uint64
+		if v.(bool) {
+			b = 1
+		}
+		return appendVarint(buf, c.number, b)
+	default:
+		return appendString(buf, c.number, v.(string))
+	}
+}
+
+// marshalAppendRowsRequest returns the AppendRowsRequest of the serialized
+// rows.
+func marshalAppendRowsRequest(stream string, descriptor []byte, rows [][]byte) []byte {
+	var schema []byte
+	schema = appendMessage(schema, 1, descriptor)
+	var protoRows []byte
+	for _, row := range rows {
+		protoRows = appendMessage(protoRows, 1, row)
+	}
+	var data []byte
+	data = appendMessage(data, 1, schema)
+	data = appendMessage(data, 2, protoRows)
+
+	var buf []byte
+	buf = appendString(buf, 1, stream)
+	return appendMessage(buf, 4, data)
+}
+
+func appendKey(buf []byte, field, wire int) []byte {
+	return appendUvarint(buf, uint64(field<<3|wire))
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	return append(buf, b[:n]...)
+}
+
+func appendVarint(buf []byte, field int, v uint64) []byte {
+	buf = appendKey(buf, field, wireVarint)
+	return appendUvarint(buf, v)
+}
+
+func appendString(buf []byte, field int, s string) []byte {
+	buf = appendKey(buf, field, wireBytes)
+	buf = appendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+func appendMessage(buf []byte, field int, msg []byte) []byte {
+	buf = appendKey(buf, field, wireBytes)
+	buf = appendUvarint(buf, uint64(len(msg)))
+	return append(buf, msg...)
+}
+
+// rowError is a RowError of an AppendRowsResponse,
+// RowError{int64 index = 1; RowErrorCode code = 2; string message = 3}.
+type rowError struct {
+	index   int64
+	message string
+}
+
+// appendRowsResponse is the part of the AppendRowsResponse used by the
+// output, AppendRowsResponse{google.rpc.Status error = 2;
+// repeated RowError row_errors = 4} and
+// google.rpc.Status{int32 code = 1; string message = 2}.
+type appendRowsResponse struct {
+	code      int
+	message   string
+	rowErrors []rowError
+}
+
+func unmarshalAppendRowsResponse(b []byte) (*appendRowsResponse, error) {
+	r := &appendRowsResponse{}
+	err := walkFields(b, func(field int, v uint64, data []byte) error {
+		switch field {
+		case 2:
+			return walkFields(data, func(field int, v uint64, data []byte) error {
+				switch field {
+				case 1:
+					r.code = int(v)
+				case 2:
+					r.message = string(data)
+				}
+				return nil
+			})
+		case 4:
+			var e rowError
+			err := walkFields(data, func(field int, v uint64, data []byte) error {
+				switch field {
+				case 1:
+					e.index = int64(v)
+				case 3:
+					e.message = string(data)
+				}
+				return nil
+			})
+			r.rowErrors = append(r.rowErrors, e)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// walkFields calls fn with the fields of the message, with their value if
+// they are varints or their data if they are length-delimited.
+func walkFields(b []byte, fn func(field int, v uint64, data []byte) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return fmt.Errorf("invalid field key")
+		}
+		b = b[n:]
+		field := int(key >> 3)
+		var v uint64
+		var data []byte
+		switch key & 7 {
+		case wireVarint:
+			v, n = binary.Uvarint(b)
+			if n <= 0 {
+				return fmt.Errorf("invalid varint of field %d", field)
+			}
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return fmt.Errorf("invalid fixed64 of field %d", field)
+			}
+			v = binary.LittleEndian.Uint64(b)
+			b = b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return fmt.Errorf("invalid fixed32 of field %d", field)
+			}
+			v = uint64(binary.LittleEndian.Uint32(b))
+			b = b[4:]
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				return fmt.Errorf("invalid length of field %d", field)
+			}
+			data = b[n : n+int(l)]
+			b = b[n+int(l):]
+		default:
+			return fmt.Errorf("unsupported wire type %d of field %d", key&7, field)
+		}
+		if err := fn(field, v, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,448 @@
+package clickhouse
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+// The column types of the values.
+const (
+	typeTime    = "DateTime64(9, 'UTC')"
+	typeString  = "String"
+	typeInt64   = "Int64"
+	typeUInt64  = "UInt64"
+	typeFloat64 = "Float64"
+	typeBool    = "UInt8"
+)
+
+const (
+	timeColumn        = "time"
+	measurementColumn = "measurement"
+	timeFormat        = "2006-01-02 15:04:05.000000000"
+)
+
+type ClickHouse struct {
+	URL      string
+	Database string
+	Username string
+	Password string
+	// Table is the table of all the metrics, with a measurement column, the
+	// metrics being written to the table of their measurement if empty.
+	Table string
+	// Engine is the engine of the tables created.
+	Engine             string
+	AsyncInsert        bool
+	WaitForAsyncInsert bool
+	Timeout            internal.Duration
+
+	// Path to CA file
+	SSLCA string `toml:"ssl_ca"`
+	// Path to host cert file
+	SSLCert string `toml:"ssl_cert"`
+	// Path to cert key file
+	SSLKey string `toml:"ssl_key"`
+	// Use SSL but skip chain & host verification
+	InsecureSkipVerify bool
+
+	client *http.Client
+	// tables are the columns of the tables written to, and their types.
+	tables map[string]map[string]string
+}
+
+var sampleConfig = `
+  ## URL of the HTTP interface of the ClickHouse server.
+  url = "http://localhost:8123"
+
+  ## Database of the tables.
+  # database = "default"
+
+  ## Credentials of the ClickHouse user.
+  # username = "default"
+  # password = ""
+
+  ## Table of all the metrics, with their measurement in the measurement
+  ## column. If empty, the metrics are written to the table named after
+  ## their measurement.
+  # table = ""
+
+  ## Engine of the tables created, with its ORDER BY and PARTITION BY
+  ## clauses.
+  # engine = "MergeTree() ORDER BY time"
+
+  ## Use the asynchronous inserts of the server, which batches the inserts
+  ## itself, optionally waiting for the data to be written.
+  # async_insert = false
+  # wait_for_async_insert = true
+
+  ## Timeout of the HTTP requests.
+  # timeout = "5s"
+
+  ## Optional SSL Config
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  ## Use SSL but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+func (c *ClickHouse) Connect() error {
+	if c.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+	if _, err := url.Parse(c.URL); err != nil {
+		return fmt.Errorf("invalid url %q: %s", c.URL, err)
+	}
+
+	tlsConfig, err := internal.GetTLSConfig(
+		c.SSLCert, c.SSLKey, c.SSLCA, c.InsecureSkipVerify)
+	if err != nil {
+		return err
+	}
+	c.client = &http.Client{
+		Timeout: c.Timeout.Duration,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}
+	c.tables = make(map[string]map[string]string)
+	return nil
+}
+
+func (c *ClickHouse) Close() error {
+	return nil
+}
+
+func (c *ClickHouse) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *ClickHouse) Description() string {
+	return "Configuration for sending metrics to ClickHouse"
+}
+
+// Write inserts the metrics of each table at once, creating the tables and
+// adding the columns missing.
+func (c *ClickHouse) Write(metrics []telegraf.Metric) error {
+	for _, b := range c.batches(metrics) {
+		if err := c.prepareTable(b); err != nil {
+			return fmt.Errorf("preparing table %s: %s", b.table, err)
+		}
+		if err := c.insert(b); err != nil {
+			// The columns may have been changed by someone else.
+			delete(c.tables, b.table)
+			return fmt.Errorf("inserting into table %s: %s", b.table, err)
+		}
+	}
+	return nil
+}
+
+// batch is the rows of the metrics written to a table.
+type batch struct {
+	table   string
+	columns map[string]string
+	// order is the order of the columns.
+	order []string
+	rows  []map[string]interface{}
+}
+
+func (c *ClickHouse) batches(metrics []telegraf.Metric) []*batch {
+	var batches []*batch
+	byTable := make(map[string]*batch)
+	for _, m := range metrics {
+		table := c.Table
+		if table == "" {
+			table = m.Name()
+		}
+		b, ok := byTable[table]
+		if !ok {
+			b = &batch{table: table, columns: make(map[string]string)}
+			b.column(timeColumn, typeTime)
+			if c.Table != "" {
+				b.column(measurementColumn, typeString)
+			}
+			byTable[table] = b
+			batches = append(batches, b)
+		}
+		b.add(m, c.Table != "")
+	}
+	return batches
+}
+
+// column adds the column to the batch, returning false if it exists with
+// another type.
+func (b *batch) column(name, dataType string) bool {
+	if t, ok := b.columns[name]; ok {
+		return t == dataType
+	}
+	b.columns[name] = dataType
+	b.order = append(b.order, name)
+	return true
+}
+
+func (b *batch) add(m telegraf.Metric, withMeasurement bool) {
+	row := map[string]interface{}{
+		timeColumn: m.Time().UTC().Format(timeFormat),
+	}
+	if withMeasurement {
+		row[measurementColumn] = m.Name()
+	}
+	tags := m.Tags()
+	for _, k := range sortedKeys(tags) {
+		if !b.column(k, typeString) {
+			log.Printf("D! ClickHouse output: dropping tag %s of %s, of another type than its column", k, m.Name())
+			continue
+		}
+		row[k] = tags[k]
+	}
+
+	fields := m.Fields()
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if _, ok := tags[k]; ok {
+			log.Printf("D! ClickHouse output: dropping field %s of %s, a tag has the same name", k, m.Name())
+			continue
+		}
+		value, dataType, ok := columnValue(fields[k])
+		if !ok {
+			continue
+		}
+		if !b.column(k, dataType) {
+			log.Printf("D! ClickHouse output: dropping field %s of %s, of another type than its column", k, m.Name())
+			continue
+		}
+		row[k] = value
+	}
+	b.rows = append(b.rows, row)
+}
+
+// columnValue returns the value and the column type of the field, false if
+// it can not be stored.
+func columnValue(v interface{}) (interface{}, string, bool) {
+	switch v := v.(type) {
+	case int64:
+		return v, typeInt64, true
+	case uint64:
+		return v, typeUInt64, true
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, "", false
+		}
+		return v, typeFloat64, true
+	case bool:
+		if v {
+			return 1, typeBool, true
+		}
+		return 0, typeBool, true
+	case string:
+		return v, typeString, true
+	}
+	return nil, "", false
+}
+
+// prepareTable creates the table of the batch or adds its columns missing.
+// The values of the columns of another type than the columns of the table
+// are dropped.
+func (c *ClickHouse) prepareTable(b *batch) error {
+	columns, ok := c.tables[b.table]
+	if !ok {
+		var err error
+		columns, err = c.tableColumns(b.table)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(columns) == 0 {
+		defs := make([]string, len(b.order))
+		for i, name := range b.order {
+			defs[i] = quoteIdent(name) + " " + b.columns[name]
+		}
+		query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s) ENGINE = %s",
+			c.qualifiedName(b.table), strings.Join(defs, ", "), c.Engine)
+		if _, err := c.query(query, nil, nil); err != nil {
+			return err
+		}
+		c.tables[b.table] = b.columns
+		return nil
+	}
+
+	var missing []string
+	for _, name := range b.order {
+		dataType, ok := columns[name]
+		if !ok {
+			missing = append(missing, "ADD COLUMN IF NOT EXISTS "+quoteIdent(name)+" "+b.columns[name])
+			columns[name] = b.columns[name]
+			continue
+		}
+		if dataType == b.columns[name] {
+			continue
+		}
+		log.Printf("D! ClickHouse output: dropping the values of column %s of table %s, of type %s instead of %s",
+			name, b.table, b.columns[name], dataType)
+		for _, row := range b.rows {
+			delete(row, name)
+		}
+	}
+	if len(missing) > 0 {
+		log.Printf("I! ClickHouse output: adding %d columns to table %s", len(missing), b.table)
+		query := fmt.Sprintf("ALTER TABLE %s %s", c.qualifiedName(b.table), strings.Join(missing, ", "))
+		if _, err := c.query(query, nil, nil); err != nil {
+			delete(c.tables, b.table)
+			return err
+		}
+	}
+	c.tables[b.table] = columns
+	return nil
+}
+
+// tableColumns returns the columns of the table and their types, none if
+// the table does not exist.
+func (c *ClickHouse) tableColumns(table string) (map[string]string, error) {
+	database := "currentDatabase()"
+	if c.Database != "" {
+		database = quoteString(c.Database)
+	}
+	query := fmt.Sprintf("SELECT name, type FROM system.columns WHERE database = %s AND table = %s FORMAT TabSeparated",
+		database, quoteString(table))
+	out, err := c.query(query, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "\t", 2)
+		if len(parts) == 2 {
+			columns[unescapeTSV(parts[0])] = unescapeTSV(parts[1])
+		}
+	}
+	return columns, scanner.Err()
+}
+
+func (c *ClickHouse) insert(b *batch) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, row := range b.rows {
+		if err := enc.Encode(row); err != nil {
+			return err
+		}
+	}
+
+	settings := url.Values{}
+	if c.AsyncInsert {
+		settings.Set("async_insert", "1")
+		if c.WaitForAsyncInsert {
+			settings.Set("wait_for_async_insert", "1")
+		} else {
+			settings.Set("wait_for_async_insert", "0")
+		}
+	}
+	query := fmt.Sprintf("INSERT INTO %s FORMAT JSONEachRow", c.qualifiedName(b.table))
+	_, err := c.query(query, settings, &body)
+	return err
+}
+
+// query runs the query with the settings, the body being the data of the
+// inserts, and returns the output of the server.
+func (c *ClickHouse) query(query string, settings url.Values, body io.Reader) ([]byte, error) {
+	params := url.Values{}
+	for k, v := range settings {
+		params[k] = v
+	}
+	if c.Database != "" {
+		params.Set("database", c.Database)
+	}
+
+	var req *http.Request
+	var err error
+	if body == nil {
+		req, err = http.NewRequest("POST", c.URL+"/?"+params.Encode(), strings.NewReader(query))
+	} else {
+		params.Set("query", query)
+		req, err = http.NewRequest("POST", c.URL+"/?"+params.Encode(), body)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c.Username != "" {
+		req.Header.Set("X-ClickHouse-User", c.Username)
+	}
+	if c.Password != "" {
+		req.Header.Set("X-ClickHouse-Key", c.Password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	out, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status code [%d]: %s", resp.StatusCode, strings.TrimSpace(string(out)))
+	}
+	return out, nil
+}
+
+func (c *ClickHouse) qualifiedName(table string) string {
+	if c.Database == "" {
+		return quoteIdent(table)
+	}
+	return quoteIdent(c.Database) + "." + quoteIdent(table)
+}
+
+func quoteIdent(name string) string {
+	return "`" + strings.NewReplacer(`\`, `\\`, "`", "\\`").Replace(name) + "`"
+}
+
+func quoteString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+var tsvUnescaper = strings.NewReplacer(`\t`, "\t", `\n`, "\n", `\\`, `\`, `\'`, "'")
+
+func unescapeTSV(s string) string {
+	return tsvUnescaper.Replace(s)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func init() {
+	outputs.Add("clickhouse", func() telegraf.Output {
+		return &ClickHouse{
+			Engine:             "MergeTree() ORDER BY time",
+			WaitForAsyncInsert: true,
+			Timeout:            internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package clickhouse
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// server records the queries and the inserts, answering the queries of
+// system.columns with columns.
+type server struct {
+	sync.Mutex
+	columns string
+	queries []string
+	inserts map[string]string
+	params  []string
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+	body, _ := ioutil.ReadAll(r.Body)
+	if query := r.URL.Query().Get("query"); query != "" {
+		s.inserts[query] = string(body)
+		s.params = append(s.params, r.URL.RawQuery)
+		return
+	}
+	s.queries = append(s.queries, string(body))
+	if strings.Contains(string(body), "system.columns") {
+		w.Write([]byte(s.columns))
+	}
+}
+
+func testMetrics(t *testing.T) []telegraf.Metric {
+	now := time.Unix(1500000000, 5).UTC()
+	m1, err := metric.New("cpu", map[string]string{"host": "a"},
+		map[string]interface{}{"usage": 1.5, "ok": true}, now)
+	require.NoError(t, err)
+	m2, err := metric.New("mem", map[string]string{"host": "a"},
+		map[string]interface{}{"free": int64(10)}, now)
+	require.NoError(t, err)
+	return []telegraf.Metric{m1, m2}
+}
+
+func TestWriteCreateTables(t *testing.T) {
+	s := &server{inserts: make(map[string]string)}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	c := &ClickHouse{
+		URL:      ts.URL,
+		Database: "telegraf",
+		Engine:   "MergeTree() ORDER BY time",
+	}
+	require.NoError(t, c.Connect())
+	require.NoError(t, c.Write(testMetrics(t)))
+
+	assert.Equal(t, []string{
+		"SELECT name, type FROM system.columns WHERE database = 'telegraf' AND table = 'cpu' FORMAT TabSeparated",
+		"CREATE TABLE IF NOT EXISTS `telegraf`.`cpu` (`time` DateTime64(9, 'UTC'), `host` String, " +
+			"`ok` UInt8, `usage` Float64) ENGINE = MergeTree() ORDER BY time",
+		"SELECT name, type FROM system.columns WHERE database = 'telegraf' AND table = 'mem' FORMAT TabSeparated",
+		"CREATE TABLE IF NOT EXISTS `telegraf`.`mem` (`time` DateTime64(9, 'UTC'), `host` String, " +
+			"`free` Int64) ENGINE = MergeTree() ORDER BY time",
+	}, s.queries)
+	assert.Equal(t, map[string]string{
+		"INSERT INTO `telegraf`.`cpu` FORMAT JSONEachRow": `{"host":"a","ok":1,"time":"2017-07-14 02:40:00.000000005","usage":1.5}` + "\n",
+		"INSERT INTO `telegraf`.`mem` FORMAT JSONEachRow": `{"free":10,"host":"a","time":"2017-07-14 02:40:00.000000005"}` + "\n",
+	}, s.inserts)
+
+	// The columns of the tables are cached.
+	s.queries = nil
+	require.NoError(t, c.Write(testMetrics(t)))
+	assert.Empty(t, s.queries)
+}
+
+func TestWriteAddColumns(t *testing.T) {
+	s := &server{
+		inserts: make(map[string]string),
+		columns: "time\tDateTime64(9, \\'UTC\\')\nmeasurement\tString\nhost\tString\nusage\tString\n",
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	c := &ClickHouse{
+		URL:         ts.URL,
+		Table:       "metrics",
+		AsyncInsert: true,
+	}
+	require.NoError(t, c.Connect())
+	require.NoError(t, c.Write(testMetrics(t)[:1]))
+
+	assert.Equal(t, []string{
+		"SELECT name, type FROM system.columns WHERE database = currentDatabase() AND table = 'metrics' FORMAT TabSeparated",
+		"ALTER TABLE `metrics` ADD COLUMN IF NOT EXISTS `ok` UInt8",
+	}, s.queries)
+	// The values of the columns of another type are dropped.
+	assert.Equal(t, map[string]string{
+		"INSERT INTO `metrics` FORMAT JSONEachRow": `{"host":"a","measurement":"cpu","ok":1,"time":"2017-07-14 02:40:00.000000005"}` + "\n",
+	}, s.inserts)
+	assert.Contains(t, s.params[0], "async_insert=1")
+	assert.Contains(t, s.params[0], "wait_for_async_insert=0")
+}
+
+func TestWriteError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Code: 241. DB::Exception: Memory limit exceeded"))
+	}))
+	defer ts.Close()
+
+	c := &ClickHouse{URL: ts.URL}
+	require.NoError(t, c.Connect())
+	err := c.Write(testMetrics(t))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Memory limit exceeded")
+}
@@ -0,0 +1,131 @@
+package cratedb
+
+import (
+	gosql "database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// statements are the statements executed by the fake driver.
+var statements struct {
+	sync.Mutex
+	list []string
+	err  error
+}
+
+func init() {
+	gosql.Register("fakecrate", fakeDriver{})
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	return fakeConn{}, nil
+}
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("not supported")
+}
+
+func (fakeConn) Close() error {
+	return nil
+}
+
+func (fakeConn) Begin() (driver.Tx, error) {
+	return nil, fmt.Errorf("not supported")
+}
+
+func (fakeConn) Exec(query string, args []driver.Value) (driver.Result, error) {
+	statements.Lock()
+	defer statements.Unlock()
+	if statements.err != nil {
+		return nil, statements.err
+	}
+	statements.list = append(statements.list, query)
+	return driver.RowsAffected(1), nil
+}
+
+func newOutput(t *testing.T) *CrateDB {
+	statements.list = nil
+	statements.err = nil
+	c := &CrateDB{
+		URL:          "postgres://crate@localhost/doc",
+		Timeout:      internal.Duration{Duration: 5 * time.Second},
+		Table:        "doc.metrics",
+		TableCreate:  true,
+		KeySeparator: "_",
+		driver:       "fakecrate",
+	}
+	require.NoError(t, c.Connect())
+	return c
+}
+
+func TestConnect(t *testing.T) {
+	c := newOutput(t)
+	defer c.Close()
+	require.Len(t, statements.list, 1)
+	assert.Equal(t, `CREATE TABLE IF NOT EXISTS "doc"."metrics" (
+	"hash_id" LONG INDEX OFF,
+	"timestamp" TIMESTAMP,
+	"name" STRING,
+	"tags" OBJECT(DYNAMIC),
+	"fields" OBJECT(DYNAMIC),
+	"week" TIMESTAMP GENERATED ALWAYS AS date_trunc('week', "timestamp"),
+	PRIMARY KEY ("timestamp", "hash_id", "week")
+) PARTITIONED BY ("week")`, statements.list[0])
+}
+
+func TestWrite(t *testing.T) {
+	c := newOutput(t)
+	defer c.Close()
+
+	now := time.Unix(1500000000, 0)
+	m1, err := metric.New("cpu", map[string]string{"host": "a", "net.dev": "o'neil"},
+		map[string]interface{}{"usage": 1.5, "count": int64(2), "up": true, "state": "ok"}, now)
+	require.NoError(t, err)
+	m2, err := metric.New("mem", nil,
+		map[string]interface{}{"free": uint64(10)}, now)
+	require.NoError(t, err)
+	require.NoError(t, c.Write([]telegraf.Metric{m1, m2}))
+
+	require.Len(t, statements.list, 2)
+	assert.Equal(t, fmt.Sprintf(`INSERT INTO "doc"."metrics" ("hash_id", "timestamp", "name", "tags", "fields") VALUES `+
+		`(%d, 1500000000000, 'cpu', {"host" = 'a', "net_dev" = 'o''neil'}, {"count" = 2, "state" = 'ok', "up" = true, "usage" = 1.5}), `+
+		`(%d, 1500000000000, 'mem', {}, {"free" = 10}) ON CONFLICT DO NOTHING`, hashID(m1), hashID(m2)),
+		statements.list[1])
+}
+
+func TestHashID(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	m1, _ := metric.New("cpu", map[string]string{"host": "a", "cpu": "0"},
+		map[string]interface{}{"usage": 1.5}, now)
+	m2, _ := metric.New("cpu", map[string]string{"cpu": "0", "host": "a"},
+		map[string]interface{}{"idle": 1.5}, now.Add(time.Minute))
+	m3, _ := metric.New("cpu", map[string]string{"host": "a", "cpu": "1"},
+		map[string]interface{}{"usage": 1.5}, now)
+
+	// The hash is the one of the series.
+	assert.Equal(t, hashID(m1), hashID(m2))
+	assert.NotEqual(t, hashID(m1), hashID(m3))
+}
+
+func TestWriteError(t *testing.T) {
+	c := newOutput(t)
+	defer c.Close()
+	statements.err = fmt.Errorf("connection refused")
+
+	m, _ := metric.New("cpu", nil, map[string]interface{}{"usage": 1.5}, time.Unix(0, 0))
+	assert.Error(t, c.Write([]telegraf.Metric{m}))
+}
@@ -0,0 +1,332 @@
+package dynatrace
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+const (
+	// oneAgentURL is the metrics ingest endpoint of the local OneAgent.
+	oneAgentURL = "http://127.0.0.1:14499/metrics/ingest"
+
+	maxKeyLength            = 250
+	maxDimensionKeyLength   = 100
+	maxDimensionValueLength = 250
+	maxDimensions           = 50
+	// maxLines is the maximum number of lines of a request.
+	maxLines = 1000
+)
+
+var (
+	invalidKeyCharRE          = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+	invalidDimensionKeyCharRE = regexp.MustCompile(`[^a-z0-9_.:-]+`)
+	dimensionValueEscaper     = strings.NewReplacer(
+		`\`, `\\`, `,`, `\,`, `=`, `\=`, ` `, `\ `, `"`, `\"`)
+)
+
+// Dynatrace sends the metrics to the metrics ingest API of Dynatrace, ie of
+// a Dynatrace environment or of the local OneAgent, a line per field.
+type Dynatrace struct {
+	URL      string `toml:"url"`
+	APIToken string `toml:"api_token"`
+	Prefix   string
+	Timeout  internal.Duration
+	// AdditionalCounters are the metric keys sent as counters, in addition to
+	// the fields of the counter metrics.
+	AdditionalCounters []string
+	// DefaultDimensions are the dimensions of all the lines, the tags of the
+	// same name replacing them.
+	DefaultDimensions map[string]string
+
+	// Path to CA file
+	SSLCA string `toml:"ssl_ca"`
+	// Path to host cert file
+	SSLCert string `toml:"ssl_cert"`
+	// Path to cert key file
+	SSLKey string `toml:"ssl_key"`
+	// Use SSL but skip chain & host verification
+	InsecureSkipVerify bool
+
+	client   *http.Client
+	counters map[string]bool
+	// previous are the previous values of the counters, by line, their
+	// deltas being sent.
+	previous map[string]float64
+}
+
+var sampleConfig = `
+  ## URL of the metrics ingest API, ie
+  ## "https://{your-environment-id}.live.dynatrace.com/api/v2/metrics/ingest".
+  ## If empty, the metrics are sent to the local OneAgent, which needs no
+  ## API token.
+  url = ""
+
+  ## API token of the environment, with the "Ingest metrics" scope.
+  # api_token = ""
+
+  ## Prefix of the metric keys.
+  # prefix = "telegraf"
+
+  ## Timeout of the requests.
+  # timeout = "5s"
+
+  ## Metric keys sent as counters, the fields of the counter metrics being
+  ## always sent as counters.
+  # additional_counters = []
+
+  ## Dimensions of all the metrics, the tags replacing them.
+  # [outputs.dynatrace.default_dimensions]
+  #   environment = "production"
+
+  ## Optional SSL Config
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  ## Use SSL but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+func (d *Dynatrace) Connect() error {
+	if d.URL == "" {
+		log.Printf("I! Dynatrace: no url, sending the metrics to the local OneAgent at %s", oneAgentURL)
+		d.URL = oneAgentURL
+	}
+	if d.URL != oneAgentURL && d.APIToken == "" {
+		return fmt.Errorf("api_token is required with url %s", d.URL)
+	}
+
+	tlsConfig, err := internal.GetTLSConfig(
+		d.SSLCert, d.SSLKey, d.SSLCA, d.InsecureSkipVerify)
+	if err != nil {
+		return err
+	}
+	d.client = &http.Client{
+		Timeout: d.Timeout.Duration,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}
+	d.counters = make(map[string]bool)
+	for _, key := range d.AdditionalCounters {
+		d.counters[key] = true
+	}
+	d.previous = make(map[string]float64)
+	return nil
+}
+
+func (d *Dynatrace) Close() error {
+	return nil
+}
+
+func (d *Dynatrace) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *Dynatrace) Description() string {
+	return "Send metrics to Dynatrace"
+}
+
+func (d *Dynatrace) Write(metrics []telegraf.Metric) error {
+	var lines []string
+	for _, m := range metrics {
+		lines = append(lines, d.lines(m)...)
+	}
+	for len(lines) > 0 {
+		n := len(lines)
+		if n > maxLines {
+			n = maxLines
+		}
+		if err := d.send(lines[:n]); err != nil {
+			return err
+		}
+		lines = lines[n:]
+	}
+	return nil
+}
+
+// lines returns the lines of the fields of the metric, the lines of the
+// first values of the counters being omitted.
+func (d *Dynatrace) lines(m telegraf.Metric) []string {
+	dims := d.dimensions(m.Tags())
+	fields := m.Fields()
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var lines []string
+	for _, k := range keys {
+		value, ok := floatValue(fields[k])
+		if !ok {
+			continue
+		}
+		name := m.Name()
+		if k != "value" {
+			name += "." + k
+		}
+		if d.Prefix != "" {
+			name = d.Prefix + "." + name
+		}
+		key := metricKey(name)
+		if key == "" {
+			log.Printf("D! Dynatrace: dropping field %s of %s, invalid metric key", k, m.Name())
+			continue
+		}
+
+		series := key + dims
+		payload := "gauge," + formatValue(value)
+		if m.Type() == telegraf.Counter || d.counters[key] {
+			previous, ok := d.previous[series]
+			d.previous[series] = value
+			if !ok || value < previous {
+				// The first value, or the counter was reset.
+				continue
+			}
+			payload = "count,delta=" + formatValue(value-previous)
+		}
+		lines = append(lines, fmt.Sprintf("%s %s %d",
+			series, payload, m.Time().UnixNano()/int64(time.Millisecond)))
+	}
+	return lines
+}
+
+// dimensions returns the dimensions of the tags, and of the default
+// dimensions, as the part of the lines after the metric key.
+func (d *Dynatrace) dimensions(tags map[string]string) string {
+	dims := make(map[string]string)
+	for k, v := range d.DefaultDimensions {
+		if k = dimensionKey(k); k != "" {
+			dims[k] = v
+		}
+	}
+	for k, v := range tags {
+		if k = dimensionKey(k); k != "" {
+			dims[k] = v
+		}
+	}
+	keys := make([]string, 0, len(dims))
+	for k := range dims {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if len(keys) > maxDimensions {
+		keys = keys[:maxDimensions]
+	}
+
+	var b bytes.Buffer
+	for _, k := range keys {
+		v := dims[k]
+		if len(v) > maxDimensionValueLength {
+			v = v[:maxDimensionValueLength]
+		}
+		b.WriteByte(',')
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(dimensionValueEscaper.Replace(v))
+	}
+	return b.String()
+}
+
+// metricKey returns the name as a metric key, starting with a letter and of
+// letters, digits, underscores, hyphens and dots, empty if it has no letter.
+func metricKey(name string) string {
+	key := invalidKeyCharRE.ReplaceAllString(name, "_")
+	key = strings.TrimLeftFunc(key, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z')
+	})
+	if len(key) > maxKeyLength {
+		key = key[:maxKeyLength]
+	}
+	return key
+}
+
+// dimensionKey returns the tag key as a dimension key, lowercase and of the
+// characters of the metric keys and colons.
+func dimensionKey(tag string) string {
+	key := invalidDimensionKeyCharRE.ReplaceAllString(strings.ToLower(tag), "_")
+	key = strings.TrimLeftFunc(key, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z')
+	})
+	if len(key) > maxDimensionKeyLength {
+		key = key[:maxDimensionKeyLength]
+	}
+	return key
+}
+
+func floatValue(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func (d *Dynatrace) send(lines []string) error {
+	body := strings.Join(lines, "\n")
+	req, err := http.NewRequest("POST", d.URL, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=UTF-8")
+	req.Header.Set("User-Agent", "Telegraf")
+	if d.APIToken != "" {
+		req.Header.Set("Authorization", "Api-Token "+d.APIToken)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("sending to %s failed with status code %d: %s",
+		d.URL, resp.StatusCode, strings.TrimSpace(string(msg)))
+	if resp.StatusCode == http.StatusBadRequest {
+		// Lines are invalid, the other lines being ingested, sending them
+		// again would fail again.
+		log.Printf("E! Dynatrace: %s", err)
+		return nil
+	}
+	return err
+}
+
+func init() {
+	outputs.Add("dynatrace", func() telegraf.Output {
+		return &Dynatrace{
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package dynatrace
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	var lines []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Api-Token secret", r.Header.Get("Authorization"))
+		assert.Equal(t, "text/plain; charset=UTF-8", r.Header.Get("Content-Type"))
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		lines = strings.Split(string(body), "\n")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	d := &Dynatrace{
+		URL:               ts.URL,
+		APIToken:          "secret",
+		Prefix:            "telegraf",
+		DefaultDimensions: map[string]string{"env": "prod", "Host": "default"},
+	}
+	require.NoError(t, d.Connect())
+
+	now := time.Unix(1500000000, 0)
+	m1, err := metric.New("cpu",
+		map[string]string{"Host": "a b", "1cpu": "cpu,0", "__": "dropped"},
+		map[string]interface{}{"usage idle": 91.5, "up": true, "state": "ok", "value": int64(3)},
+		now)
+	require.NoError(t, err)
+	require.NoError(t, d.Write([]telegraf.Metric{m1}))
+	assert.Equal(t, []string{
+		`telegraf.cpu.up,cpu=cpu\,0,env=prod,host=a\ b gauge,1 1500000000000`,
+		`telegraf.cpu.usage_idle,cpu=cpu\,0,env=prod,host=a\ b gauge,91.5 1500000000000`,
+		`telegraf.cpu,cpu=cpu\,0,env=prod,host=a\ b gauge,3 1500000000000`,
+	}, lines)
+}
+
+func TestCounters(t *testing.T) {
+	var lines []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		lines = append(lines, strings.Split(string(body), "\n")...)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	d := &Dynatrace{
+		URL:                ts.URL,
+		APIToken:           "secret",
+		AdditionalCounters: []string{"net.bytes_recv"},
+	}
+	require.NoError(t, d.Connect())
+
+	now := time.Unix(1500000000, 0)
+	for _, v := range []int64{10, 15, 5, 7} {
+		m, err := metric.New("net", nil,
+			map[string]interface{}{"bytes_recv": v, "speed": int64(100)}, now)
+		require.NoError(t, err)
+		c, err := metric.New("requests", nil,
+			map[string]interface{}{"value": v}, now, telegraf.Counter)
+		require.NoError(t, err)
+		require.NoError(t, d.Write([]telegraf.Metric{m, c}))
+	}
+	// The first values and the values after a reset are omitted.
+	assert.Equal(t, []string{
+		"net.speed gauge,100 1500000000000",
+		"net.bytes_recv count,delta=5 1500000000000",
+		"net.speed gauge,100 1500000000000",
+		"requests count,delta=5 1500000000000",
+		"net.speed gauge,100 1500000000000",
+		"net.bytes_recv count,delta=2 1500000000000",
+		"net.speed gauge,100 1500000000000",
+		"requests count,delta=2 1500000000000",
+	}, lines)
+}
+
+func TestWriteError(t *testing.T) {
+	status := http.StatusBadRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	d := &Dynatrace{URL: ts.URL, APIToken: "secret"}
+	require.NoError(t, d.Connect())
+	m, err := metric.New("cpu", nil, map[string]interface{}{"usage": 1.5}, time.Unix(0, 0))
+	require.NoError(t, err)
+
+	// The invalid lines are dropped.
+	require.NoError(t, d.Write([]telegraf.Metric{m}))
+	status = http.StatusServiceUnavailable
+	require.Error(t, d.Write([]telegraf.Metric{m}))
+}
+
+func TestOneAgent(t *testing.T) {
+	d := &Dynatrace{}
+	require.NoError(t, d.Connect())
+	assert.Equal(t, oneAgentURL, d.URL)
+
+	d = &Dynatrace{URL: "https://example.live.dynatrace.com/api/v2/metrics/ingest"}
+	require.Error(t, d.Connect())
+}
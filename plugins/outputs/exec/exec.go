@@ -0,0 +1,112 @@
+package exec
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+// maxStderrBytes is the size of the stderr of the failed commands kept in
+// their errors.
+const maxStderrBytes = 512
+
+const sampleConfig = `
+  ## Command to run for each batch of metrics, with its arguments, the
+  ## metrics being written to its stdin.
+  command = ["/usr/bin/mycommand", "--foo=bar"]
+
+  ## Timeout of the command, which is killed once it expires.
+  # timeout = "5s"
+
+  ## Data format to output.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_OUTPUT.md
+  data_format = "influx"
+`
+
+// Exec runs a command for each batch of metrics, the batch being its stdin.
+type Exec struct {
+	Command []string
+	Timeout internal.Duration
+
+	serializer serializers.Serializer
+}
+
+func (e *Exec) SetSerializer(serializer serializers.Serializer) {
+	e.serializer = serializer
+}
+
+func (e *Exec) Connect() error {
+	if len(e.Command) == 0 {
+		return fmt.Errorf("no command defined")
+	}
+	return nil
+}
+
+func (e *Exec) Close() error {
+	return nil
+}
+
+func (e *Exec) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Exec) Description() string {
+	return "Send metrics to the stdin of a command"
+}
+
+func (e *Exec) Write(metrics []telegraf.Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+	buf, err := e.serializeBatch(metrics)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(e.Command[0], e.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(buf)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := internal.RunTimeout(cmd, e.Timeout.Duration); err != nil {
+		msg := stderr.String()
+		if len(msg) > maxStderrBytes {
+			msg = msg[:maxStderrBytes] + "..."
+		}
+		return fmt.Errorf("exec: %s for command %s: %s",
+			err, e.Command[0], strings.TrimSpace(msg))
+	}
+	return nil
+}
+
+func (e *Exec) serializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	if s, ok := e.serializer.(serializers.BatchSerializer); ok {
+		return s.SerializeBatch(metrics)
+	}
+	var buf []byte
+	for _, m := range metrics {
+		b, err := e.serializer.Serialize(m)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, b...)
+	}
+	return buf, nil
+}
+
+func init() {
+	outputs.Add("exec", func() telegraf.Output {
+		return &Exec{
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
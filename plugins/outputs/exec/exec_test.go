@@ -0,0 +1,95 @@
+package exec
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHelperProcess is run as the command by the tests, it copies its stdin
+// to EXEC_TEST_OUTPUT, fails with "fail" and hangs with "hang".
+func TestHelperProcess(t *testing.T) {
+	mode := os.Getenv("EXEC_TEST_MODE")
+	switch mode {
+	case "":
+		return
+	case "fail":
+		fmt.Fprintln(os.Stderr, "invalid metrics")
+		os.Exit(1)
+	case "hang":
+		time.Sleep(time.Minute)
+	}
+
+	var lines []byte
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Bytes()...)
+		lines = append(lines, '\n')
+	}
+	if err := ioutil.WriteFile(os.Getenv("EXEC_TEST_OUTPUT"), lines, 0644); err != nil {
+		os.Exit(2)
+	}
+	os.Exit(0)
+}
+
+func newTestExec(t *testing.T, mode string) *Exec {
+	os.Setenv("EXEC_TEST_MODE", mode)
+	serializer, _ := serializers.NewInfluxSerializer()
+	e := &Exec{
+		Command:    []string{os.Args[0], "-test.run=TestHelperProcess"},
+		Timeout:    internal.Duration{Duration: 5 * time.Second},
+		serializer: serializer,
+	}
+	require.NoError(t, e.Connect())
+	return e
+}
+
+func TestWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "exec")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "metrics")
+	os.Setenv("EXEC_TEST_OUTPUT", output)
+	defer os.Unsetenv("EXEC_TEST_OUTPUT")
+
+	e := newTestExec(t, "copy")
+	defer os.Unsetenv("EXEC_TEST_MODE")
+	metrics := []telegraf.Metric{testutil.TestMetric(1, "m1"), testutil.TestMetric(2, "m2")}
+	require.NoError(t, e.Write(metrics))
+
+	b, err := ioutil.ReadFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, metrics[0].String()+metrics[1].String(), string(b))
+}
+
+func TestWriteFailure(t *testing.T) {
+	e := newTestExec(t, "fail")
+	defer os.Unsetenv("EXEC_TEST_MODE")
+	err := e.Write([]telegraf.Metric{testutil.TestMetric(1)})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid metrics")
+}
+
+func TestWriteTimeout(t *testing.T) {
+	e := newTestExec(t, "hang")
+	defer os.Unsetenv("EXEC_TEST_MODE")
+	e.Timeout = internal.Duration{Duration: 100 * time.Millisecond}
+	require.Error(t, e.Write([]telegraf.Metric{testutil.TestMetric(1)}))
+}
+
+func TestConnectNoCommand(t *testing.T) {
+	e := &Exec{}
+	require.Error(t, e.Connect())
+}
@@ -0,0 +1,258 @@
+package execd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+const sampleConfig = `
+  ## Program to run as daemon, with its arguments, the metrics being
+  ## written to its stdin.
+  command = ["/usr/bin/mycommand", "--foo=bar"]
+
+  ## Delay before the process is restarted after an unexpected termination
+  restart_delay = "10s"
+
+  ## Data format to output.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_OUTPUT.md
+  data_format = "influx"
+`
+
+// closeTimeout is the delay given to the process to exit once its stdin is
+// closed.
+const closeTimeout = 5 * time.Second
+
+var errStopped = errors.New("plugin stopped")
+
+// Execd writes the metrics to the stdin of a long-running process,
+// restarted when it terminates.
+type Execd struct {
+	Command      []string
+	RestartDelay internal.Duration
+
+	serializer serializers.Serializer
+
+	sync.Mutex
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	// running is false once the process terminated, until it is restarted.
+	running bool
+	done    chan struct{}
+	wg      sync.WaitGroup
+	// readers waits for the output of the running process to be read.
+	readers sync.WaitGroup
+}
+
+func NewExecd() *Execd {
+	return &Execd{
+		RestartDelay: internal.Duration{Duration: 10 * time.Second},
+	}
+}
+
+func (e *Execd) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Execd) Description() string {
+	return "Run executable as long-running output plugin"
+}
+
+func (e *Execd) SetSerializer(serializer serializers.Serializer) {
+	e.serializer = serializer
+}
+
+func (e *Execd) Connect() error {
+	if len(e.Command) == 0 {
+		return fmt.Errorf("no command defined")
+	}
+
+	e.done = make(chan struct{})
+	if err := e.start(); err != nil {
+		return err
+	}
+
+	e.wg.Add(1)
+	go e.run()
+	return nil
+}
+
+// start starts the process and the readers of its output.
+func (e *Execd) start() error {
+	cmd := exec.Command(e.Command[0], e.Command[1:]...)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	// Close kills the running process, do not start a new one once it did.
+	e.Lock()
+	defer e.Unlock()
+	select {
+	case <-e.done:
+		return errStopped
+	default:
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("error starting %s: %s", e.Command[0], err)
+	}
+	e.cmd = cmd
+	e.stdin = stdin
+	e.running = true
+
+	e.readers.Add(2)
+	go e.readOut(stdout)
+	go e.readErr(stderr)
+	return nil
+}
+
+// run waits for the process to exit and restarts it after the restart delay
+// until the plugin is closed.
+func (e *Execd) run() {
+	defer e.wg.Done()
+
+	for {
+		e.Lock()
+		cmd := e.cmd
+		e.Unlock()
+
+		// The pipes are closed by Wait, read them until the end first.
+		e.readers.Wait()
+		err := cmd.Wait()
+
+		e.Lock()
+		e.running = false
+		e.Unlock()
+		select {
+		case <-e.done:
+			return
+		default:
+		}
+		if err != nil {
+			log.Printf("E! [outputs.execd] Process %s terminated: %s\n", e.Command[0], err)
+		} else {
+			log.Printf("E! [outputs.execd] Process %s terminated\n", e.Command[0])
+		}
+
+		for {
+			log.Printf("I! [outputs.execd] Restarting %s in %s\n",
+				e.Command[0], e.RestartDelay.Duration)
+			select {
+			case <-e.done:
+				return
+			case <-time.After(e.RestartDelay.Duration):
+			}
+
+			err := e.start()
+			if err == errStopped {
+				return
+			}
+			if err != nil {
+				log.Printf("E! [outputs.execd] %s\n", err)
+				continue
+			}
+			break
+		}
+	}
+}
+
+func (e *Execd) readOut(r io.Reader) {
+	defer e.readers.Done()
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		log.Printf("I! [outputs.execd] stdout of %s: %s\n", e.Command[0], scanner.Text())
+	}
+}
+
+func (e *Execd) readErr(r io.Reader) {
+	defer e.readers.Done()
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		log.Printf("E! [outputs.execd] stderr of %s: %s\n", e.Command[0], scanner.Text())
+	}
+}
+
+func (e *Execd) Write(metrics []telegraf.Metric) error {
+	e.Lock()
+	defer e.Unlock()
+	if !e.running {
+		// The metrics are kept by the output until the process restarts.
+		return fmt.Errorf("process %s is not running", e.Command[0])
+	}
+
+	for i, m := range metrics {
+		b, err := e.serializer.Serialize(m)
+		if err != nil {
+			log.Printf("E! [outputs.execd] Unable to serialize metric %s: %s\n", m.Name(), err)
+			continue
+		}
+		if _, err := e.stdin.Write(b); err != nil {
+			return fmt.Errorf("error writing to stdin of %s after %d metrics: %s",
+				e.Command[0], i, err)
+		}
+	}
+	return nil
+}
+
+func (e *Execd) Close() error {
+	if e.done == nil {
+		return nil
+	}
+	close(e.done)
+
+	// The process exits once it read the metrics written to its stdin, it
+	// is killed if it does not in time.
+	e.Lock()
+	if e.stdin != nil {
+		e.stdin.Close()
+	}
+	e.Unlock()
+	exited := make(chan struct{})
+	go func() {
+		e.wg.Wait()
+		close(exited)
+	}()
+	select {
+	case <-exited:
+		return nil
+	case <-time.After(closeTimeout):
+	}
+
+	e.Lock()
+	if e.cmd != nil && e.cmd.Process != nil {
+		e.cmd.Process.Kill()
+	}
+	e.Unlock()
+	<-exited
+	return nil
+}
+
+func init() {
+	outputs.Add("execd", func() telegraf.Output {
+		return NewExecd()
+	})
+}
@@ -0,0 +1,116 @@
+package execd
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHelperProcess is run as the external program by the tests, it appends
+// the lines of its stdin to EXECD_TEST_OUTPUT, exiting after the first line
+// with "once".
+func TestHelperProcess(t *testing.T) {
+	mode := os.Getenv("EXECD_TEST_MODE")
+	if mode == "" {
+		return
+	}
+
+	f, err := os.OpenFile(os.Getenv("EXECD_TEST_OUTPUT"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		os.Exit(2)
+	}
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		f.WriteString(scanner.Text() + "\n")
+		if mode == "once" {
+			f.Close()
+			os.Exit(1)
+		}
+	}
+	f.Close()
+	os.Exit(0)
+}
+
+func newTestExecd(t *testing.T, mode string) (*Execd, string, func()) {
+	dir, err := ioutil.TempDir("", "execd")
+	require.NoError(t, err)
+	output := filepath.Join(dir, "metrics")
+	os.Setenv("EXECD_TEST_MODE", mode)
+	os.Setenv("EXECD_TEST_OUTPUT", output)
+
+	e := NewExecd()
+	e.Command = []string{os.Args[0], "-test.run=TestHelperProcess"}
+	e.RestartDelay = internal.Duration{Duration: 10 * time.Millisecond}
+	serializer, _ := serializers.NewInfluxSerializer()
+	e.SetSerializer(serializer)
+	return e, output, func() {
+		os.Unsetenv("EXECD_TEST_MODE")
+		os.Unsetenv("EXECD_TEST_OUTPUT")
+		os.RemoveAll(dir)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	e, output, cleanup := newTestExecd(t, "copy")
+	defer cleanup()
+	require.NoError(t, e.Connect())
+
+	metrics := []telegraf.Metric{testutil.TestMetric(1, "m1"), testutil.TestMetric(2, "m2")}
+	require.NoError(t, e.Write(metrics))
+	require.NoError(t, e.Write(metrics[:1]))
+	// The process reads the metrics written before it exits.
+	require.NoError(t, e.Close())
+
+	b, err := ioutil.ReadFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, metrics[0].String()+metrics[1].String()+metrics[0].String(), string(b))
+}
+
+func TestRestart(t *testing.T) {
+	e, output, cleanup := newTestExecd(t, "once")
+	defer cleanup()
+	require.NoError(t, e.Connect())
+	defer e.Close()
+
+	m := testutil.TestMetric(1, "m1")
+	require.NoError(t, e.Write([]telegraf.Metric{m}))
+
+	// The process exits after the first metric and is restarted.
+	waitRunning := func(running bool) {
+		for i := 0; i < 500; i++ {
+			e.Lock()
+			r := e.running
+			e.Unlock()
+			if r == running {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+		t.Fatalf("process running is not %v", running)
+	}
+	waitRunning(false)
+	waitRunning(true)
+	require.NoError(t, e.Write([]telegraf.Metric{m}))
+	waitRunning(false)
+
+	b, err := ioutil.ReadFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, m.String()+m.String(), string(b))
+}
+
+func TestWriteNotRunning(t *testing.T) {
+	e := NewExecd()
+	e.Command = []string{"true"}
+	require.Error(t, e.Write([]telegraf.Metric{testutil.TestMetric(1)}))
+}
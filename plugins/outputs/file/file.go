@@ -3,7 +3,11 @@ package file
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/outputs"
@@ -28,6 +32,10 @@ var sampleConfig = `
   ## more about them here:
   ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_OUTPUT.md
   data_format = "influx"
+
+  ## With the formats written by batch, ie parquet, each write is a new file
+  ## named after the files with the time of the write in nanoseconds, ie
+  ## /tmp/metrics-1496318400000000000.parquet for /tmp/metrics.parquet.
 `
 
 func (f *File) SetSerializer(serializer serializers.Serializer) {
@@ -40,6 +48,10 @@ func (f *File) Connect() error {
 	if len(f.Files) == 0 {
 		f.Files = []string{"stdout"}
 	}
+	if _, ok := f.serializer.(serializers.BatchSerializer); ok {
+		// The files are created by each write.
+		return nil
+	}
 
 	for _, file := range f.Files {
 		if file == "stdout" {
@@ -89,6 +101,9 @@ func (f *File) Write(metrics []telegraf.Metric) error {
 	if len(metrics) == 0 {
 		return nil
 	}
+	if s, ok := f.serializer.(serializers.BatchSerializer); ok {
+		return f.writeBatch(s, metrics)
+	}
 
 	for _, metric := range metrics {
 		b, err := f.serializer.Serialize(metric)
@@ -103,6 +118,31 @@ func (f *File) Write(metrics []telegraf.Metric) error {
 	return nil
 }
 
+// writeBatch writes the metrics serialized as a whole to a new file, named
+// after each of the files with the time of the write.
+func (f *File) writeBatch(s serializers.BatchSerializer, metrics []telegraf.Metric) error {
+	b, err := s.SerializeBatch(metrics)
+	if err != nil {
+		return fmt.Errorf("failed to serialize message: %s", err)
+	}
+
+	now := time.Now().UnixNano()
+	for _, file := range f.Files {
+		if file == "stdout" {
+			if _, err := os.Stdout.Write(b); err != nil {
+				return fmt.Errorf("failed to write message: %s", err)
+			}
+			continue
+		}
+		ext := filepath.Ext(file)
+		name := fmt.Sprintf("%s-%d%s", strings.TrimSuffix(file, ext), now, ext)
+		if err := ioutil.WriteFile(name, b, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %s", name, err)
+		}
+	}
+	return nil
+}
+
 func init() {
 	outputs.Add("file", func() telegraf.Output {
 		return &File{}
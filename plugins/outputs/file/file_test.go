@@ -5,6 +5,8 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -170,6 +172,40 @@ func TestFileStdout(t *testing.T) {
 	assert.Equal(t, expNewFile, out)
 }
 
+func TestFileBatch(t *testing.T) {
+	s, _ := serializers.NewParquetSerializer()
+	d, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+	f := File{
+		Files:      []string{filepath.Join(d, "metrics.parquet")},
+		serializer: s,
+	}
+
+	err = f.Connect()
+	assert.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		err = f.Write(testutil.MockMetrics())
+		assert.NoError(t, err)
+	}
+
+	// A new file per write
+	files, err := filepath.Glob(filepath.Join(d, "metrics-*.parquet"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+	for _, file := range files {
+		buf, err := ioutil.ReadFile(file)
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(buf), "PAR1"))
+	}
+	_, err = os.Stat(filepath.Join(d, "metrics.parquet"))
+	assert.True(t, os.IsNotExist(err))
+
+	err = f.Close()
+	assert.NoError(t, err)
+}
+
 func createFile() *os.File {
 	f, err := ioutil.TempFile("", "")
 	if err != nil {
@@ -9,17 +9,39 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/serializers"
 )
 
 type Graphite struct {
 	// URL is only for backwards compatability
-	Servers  []string
-	Prefix   string
-	Template string
-	Timeout  int
-	conns    []net.Conn
+	Servers            []string
+	Prefix             string
+	Template           string
+	Templates          []string
+	GraphiteTagSupport bool
+	Timeout            int
+	// Failover writes to the first server up, in order, instead of a random
+	// one.
+	Failover bool
+	// RetryInterval is the interval between the connections to a server down.
+	RetryInterval internal.Duration
+	// SpoolFile is the file the metrics are written to while all the servers
+	// are down, up to SpoolMaxSize bytes.
+	SpoolFile    string
+	SpoolMaxSize int64
+
+	servers []*server
+	spool   *spool
+}
+
+// server is a graphite server, its connection being nil while it is down.
+type server struct {
+	address string
+	conn    net.Conn
+	// retry is the time the server is connected to again.
+	retry time.Time
 }
 
 var sampleConfig = `
@@ -27,11 +49,31 @@ var sampleConfig = `
   ## If multiple endpoints are configured, output will be load balanced.
   ## Only one of the endpoints will be written to with each iteration.
   servers = ["localhost:2003"]
+  ## Write to the first endpoint up, in the order above, instead of load
+  ## balancing, the other endpoints being only used while it is down.
+  # failover = false
+  ## Interval between the reconnections to an endpoint down.
+  # retry_interval = "10s"
+  ## File the metrics are written to while all the endpoints are down, and
+  ## sent before the next metrics once one of them is up again. The metrics
+  ## are kept in the output buffer instead if empty, or when the file reaches
+  ## spool_max_size bytes.
+  # spool_file = ""
+  # spool_max_size = 104857600
   ## Prefix metrics name
   prefix = ""
   ## Graphite output template
   ## see https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_OUTPUT.md
   template = "host.tags.measurement.field"
+  ## Graphite templates of the measurements, the first template matching the
+  ## measurement is used, the template above otherwise.
+  # templates = [
+  #   "cpu* tags.measurement.field",
+  #   "disk host.path.measurement.field",
+  # ]
+  ## Enable Graphite 1.1 tags, ie "cpu.usage_idle;host=tars", the templates
+  ## being ignored.
+  # graphite_tag_support = false
   ## timeout in seconds for the write connection to graphite
   timeout = 2
 `
@@ -44,22 +86,52 @@ func (g *Graphite) Connect() error {
 	if len(g.Servers) == 0 {
 		g.Servers = append(g.Servers, "localhost:2003")
 	}
-	// Get Connections
-	var conns []net.Conn
-	for _, server := range g.Servers {
-		conn, err := net.DialTimeout("tcp", server, time.Duration(g.Timeout)*time.Second)
-		if err == nil {
-			conns = append(conns, conn)
+	if g.SpoolFile != "" {
+		sp, err := openSpool(g.SpoolFile, g.SpoolMaxSize)
+		if err != nil {
+			return err
 		}
+		g.spool = sp
+	}
+	// Get Connections, the servers down being connected to on the writes
+	g.servers = make([]*server, 0, len(g.Servers))
+	for _, address := range g.Servers {
+		s := &server{address: address}
+		g.connect(s)
+		g.servers = append(g.servers, s)
 	}
-	g.conns = conns
 	return nil
 }
 
+// connect connects to the server, marking it down if it fails.
+func (g *Graphite) connect(s *server) bool {
+	conn, err := net.DialTimeout("tcp", s.address, time.Duration(g.Timeout)*time.Second)
+	if err != nil {
+		log.Printf("E! Graphite server %s is down: %s", s.address, err)
+		g.down(s)
+		return false
+	}
+	s.conn = conn
+	return true
+}
+
+// down closes the connection of the server, it being connected to again
+// after the retry interval.
+func (g *Graphite) down(s *server) {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+	s.retry = time.Now().Add(g.RetryInterval.Duration)
+}
+
 func (g *Graphite) Close() error {
 	// Closing all connections
-	for _, conn := range g.conns {
-		conn.Close()
+	for _, s := range g.servers {
+		if s.conn != nil {
+			s.conn.Close()
+			s.conn = nil
+		}
 	}
 	return nil
 }
@@ -77,14 +149,15 @@ func (g *Graphite) Description() string {
 // We can detect that by finding an eof
 // if not for this, we can happily write and flush without getting errors (in Go) but getting RST tcp packets back (!)
 // props to Tv via the authors of carbon-relay-ng` for this trick.
-func checkEOF(conn net.Conn) {
+// checkEOF returns false if the connection is closed.
+func checkEOF(conn net.Conn) bool {
 	b := make([]byte, 1024)
 	conn.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
 	num, err := conn.Read(b)
 	if err == io.EOF {
 		log.Printf("E! Conn %s is closed. closing conn explicitly", conn)
 		conn.Close()
-		return
+		return false
 	}
 	// just in case i misunderstand something or the remote behaves badly
 	if num != 0 {
@@ -94,15 +167,19 @@ func checkEOF(conn net.Conn) {
 	if e, ok := err.(net.Error); !(ok && e.Timeout()) {
 		log.Printf("E! conn %s checkEOF .conn.Read returned err != EOF, which is unexpected.  closing conn. error: %s\n", conn, err)
 		conn.Close()
+		return false
 	}
+	return true
 }
 
-// Choose a random server in the cluster to write to until a successful write
-// occurs, logging each unsuccessful. If all servers fail, return error.
+// Choose a server in the cluster to write to until a successful write
+// occurs, logging each unsuccessful. If all servers fail, spool the metrics
+// or return error.
 func (g *Graphite) Write(metrics []telegraf.Metric) error {
 	// Prepare data
 	var batch []byte
-	s, err := serializers.NewGraphiteSerializer(g.Prefix, g.Template)
+	s, err := serializers.NewGraphiteSerializer(g.Prefix, g.Template,
+		g.GraphiteTagSupport, g.Templates)
 	if err != nil {
 		return err
 	}
@@ -115,35 +192,78 @@ func (g *Graphite) Write(metrics []telegraf.Metric) error {
 		batch = append(batch, buf...)
 	}
 
-	// This will get set to nil if a successful write occurs
-	err = errors.New("Could not write to any Graphite server in cluster\n")
-	// Send data to a random server
-	p := rand.Perm(len(g.conns))
-	for _, n := range p {
+	var spooled []byte
+	if g.spool != nil {
+		// The metrics spooled are sent first, with the batch.
+		spooled, err = g.spool.read()
+		if err != nil {
+			log.Printf("E! Error reading graphite spool file: %s", err)
+		}
+	}
+
+	if g.write(append(spooled, batch...)) {
+		if len(spooled) > 0 {
+			log.Printf("I! Sent %d bytes spooled to graphite", len(spooled))
+			if err := g.spool.clear(); err != nil {
+				log.Printf("E! Error clearing graphite spool file: %s", err)
+			}
+		}
+		return nil
+	}
+
+	if g.spool != nil {
+		err := g.spool.append(batch)
+		if err == nil {
+			return nil
+		}
+		log.Printf("E! Error spooling graphite metrics: %s", err)
+	}
+	return errors.New("Could not write to any Graphite server in cluster\n")
+}
+
+// write writes the data to one of the servers up, in order with failover or
+// to a random one otherwise, returning false if all the servers are down.
+func (g *Graphite) write(data []byte) bool {
+	order := rand.Perm(len(g.servers))
+	if g.Failover {
+		for i := range order {
+			order[i] = i
+		}
+	}
+	for _, n := range order {
+		s := g.servers[n]
+		if s.conn == nil {
+			// try to reconnect
+			if time.Now().Before(s.retry) || !g.connect(s) {
+				continue
+			}
+		}
 		if g.Timeout > 0 {
-			g.conns[n].SetWriteDeadline(time.Now().Add(time.Duration(g.Timeout) * time.Second))
+			s.conn.SetWriteDeadline(time.Now().Add(time.Duration(g.Timeout) * time.Second))
 		}
-		checkEOF(g.conns[n])
-		if _, e := g.conns[n].Write(batch); e != nil {
+		if !checkEOF(s.conn) {
+			s.conn = nil
+			g.down(s)
+			continue
+		}
+		if _, e := s.conn.Write(data); e != nil {
 			// Error
 			log.Println("E! Graphite Error: " + e.Error())
 			// Let's try the next one
-		} else {
-			// Success
-			err = nil
-			break
+			g.down(s)
+			continue
 		}
+		// Success
+		return true
 	}
-	// try to reconnect
-	if err != nil {
-		log.Println("E! Reconnecting: ")
-		g.Connect()
-	}
-	return err
+	return false
 }
 
 func init() {
 	outputs.Add("graphite", func() telegraf.Output {
-		return &Graphite{}
+		return &Graphite{
+			RetryInterval: internal.Duration{Duration: 10 * time.Second},
+			SpoolMaxSize:  100 * 1024 * 1024,
+		}
 	})
 }
@@ -2,8 +2,11 @@ package graphite
 
 import (
 	"bufio"
+	"io/ioutil"
 	"net"
 	"net/textproto"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
@@ -129,3 +132,100 @@ func TCPServer2(t *testing.T, wg *sync.WaitGroup) {
 		tcpServer.Close()
 	}()
 }
+
+// listen returns a server reading the lines written to it.
+func listen(t *testing.T) (net.Listener, chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	lines := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				tp := textproto.NewReader(bufio.NewReader(conn))
+				for {
+					line, err := tp.ReadLine()
+					if err != nil {
+						conn.Close()
+						return
+					}
+					lines <- line
+				}
+			}()
+		}
+	}()
+	return l, lines
+}
+
+func testMetric(value float64) []telegraf.Metric {
+	m, _ := metric.New(
+		"mymeasurement",
+		map[string]string{"host": "192.168.0.1"},
+		map[string]interface{}{"value": value},
+		time.Date(2010, time.November, 10, 23, 0, 0, 0, time.UTC),
+	)
+	return []telegraf.Metric{m}
+}
+
+func TestGraphiteFailover(t *testing.T) {
+	l1, lines1 := listen(t)
+	l2, lines2 := listen(t)
+	defer l2.Close()
+
+	g := Graphite{
+		Servers:  []string{l1.Addr().String(), l2.Addr().String()},
+		Failover: true,
+	}
+	require.NoError(t, g.Connect())
+	defer g.Close()
+
+	require.NoError(t, g.Write(testMetric(1)))
+	assert.Equal(t, "192_168_0_1.mymeasurement 1 1289430000", <-lines1)
+
+	// The second server is written to while the first one is down.
+	l1.Close()
+	g.servers[0].conn.Close()
+	require.NoError(t, g.Write(testMetric(2)))
+	assert.Equal(t, "192_168_0_1.mymeasurement 2 1289430000", <-lines2)
+	assert.Nil(t, g.servers[0].conn)
+}
+
+func TestGraphiteSpool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "graphite")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := l.Addr().String()
+	l.Close()
+
+	g := Graphite{
+		Servers:      []string{address},
+		SpoolFile:    filepath.Join(dir, "spool"),
+		SpoolMaxSize: 50,
+	}
+	require.NoError(t, g.Connect())
+	defer g.Close()
+
+	// The metrics are spooled while the server is down, up to the maximum
+	// size of the spool file.
+	require.NoError(t, g.Write(testMetric(1)))
+	err = g.Write(testMetric(2))
+	require.Error(t, err)
+	assert.Equal(t, "Could not write to any Graphite server in cluster\n", err.Error())
+
+	l, lines := listen(t)
+	defer l.Close()
+	g.servers[0].address = l.Addr().String()
+
+	require.NoError(t, g.Write(testMetric(3)))
+	assert.Equal(t, "192_168_0_1.mymeasurement 1 1289430000", <-lines)
+	assert.Equal(t, "192_168_0_1.mymeasurement 3 1289430000", <-lines)
+	data, err := ioutil.ReadFile(g.SpoolFile)
+	require.NoError(t, err)
+	assert.Empty(t, data)
+}
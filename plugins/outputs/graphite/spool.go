@@ -0,0 +1,61 @@
+package graphite
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// spool is the file of the metrics written while all the servers are down.
+type spool struct {
+	path    string
+	maxSize int64
+	size    int64
+}
+
+// openSpool opens the spool file, creating it if needed, the metrics it
+// already has being kept.
+func openSpool(path string, maxSize int64) (*spool, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open spool file: %s", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("unable to open spool file: %s", err)
+	}
+	return &spool{path: path, maxSize: maxSize, size: info.Size()}, nil
+}
+
+// append appends the data to the file, failing if it would exceed its
+// maximum size.
+func (s *spool) append(data []byte) error {
+	if s.maxSize > 0 && s.size+int64(len(data)) > s.maxSize {
+		return fmt.Errorf("spool file %s is full", s.path)
+	}
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	n, err := f.Write(data)
+	s.size += int64(n)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// read returns the data of the file.
+func (s *spool) read() ([]byte, error) {
+	if s.size == 0 {
+		return nil, nil
+	}
+	return ioutil.ReadFile(s.path)
+}
+
+// clear empties the file.
+func (s *spool) clear() error {
+	s.size = 0
+	return os.Truncate(s.path, 0)
+}
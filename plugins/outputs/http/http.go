@@ -0,0 +1,251 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+var sampleConfig = `
+  ## URL the metrics are sent to.
+  url = "http://127.0.0.1:8080/telegraf"
+
+  ## HTTP method, "POST" or "PUT".
+  # method = "POST"
+
+  ## Timeout of the requests.
+  # timeout = "5s"
+
+  ## Basic authentication of the requests.
+  # username = "username"
+  # password = "pa$$word"
+
+  ## OAuth2 client credentials grant, the requests being authenticated with
+  ## the bearer tokens of the client, renewed before they expire.
+  # token_url = "https://identityprovider/oauth2/v1/token"
+  # client_id = "clientid"
+  # client_secret = "secret"
+  # scopes = ["urn:opc:idm:__myscopes__"]
+
+  ## Additional HTTP headers of the requests.
+  # [outputs.http.headers]
+  #   Content-Type = "text/plain; charset=utf-8"
+
+  ## Compression of the requests, "identity" or "gzip".
+  # content_encoding = "identity"
+
+  ## Optional SSL Config
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  ## Use SSL but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## Data format to output.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_OUTPUT.md
+  data_format = "influx"
+`
+
+// HTTP sends the metrics of each write in the body of a request.
+type HTTP struct {
+	URL      string `toml:"url"`
+	Method   string
+	Timeout  internal.Duration
+	Username string
+	Password string
+	// TokenURL, ClientID, ClientSecret and Scopes are the OAuth2 client
+	// credentials grant.
+	TokenURL        string `toml:"token_url"`
+	ClientID        string `toml:"client_id"`
+	ClientSecret    string `toml:"client_secret"`
+	Scopes          []string
+	Headers         map[string]string
+	ContentEncoding string
+
+	// Path to CA file
+	SSLCA string `toml:"ssl_ca"`
+	// Path to host cert file
+	SSLCert string `toml:"ssl_cert"`
+	// Path to cert key file
+	SSLKey string `toml:"ssl_key"`
+	// Use SSL but skip chain & host verification
+	InsecureSkipVerify bool
+
+	client     *http.Client
+	tokens     *tokenSource
+	serializer serializers.Serializer
+}
+
+func (h *HTTP) SetSerializer(serializer serializers.Serializer) {
+	h.serializer = serializer
+}
+
+func (h *HTTP) Connect() error {
+	if h.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+	h.Method = strings.ToUpper(h.Method)
+	if h.Method == "" {
+		h.Method = "POST"
+	}
+	if h.Method != "POST" && h.Method != "PUT" {
+		return fmt.Errorf("invalid method %q", h.Method)
+	}
+	switch h.ContentEncoding {
+	case "", "identity", "gzip":
+	default:
+		return fmt.Errorf("invalid content_encoding %q", h.ContentEncoding)
+	}
+	if h.TokenURL != "" {
+		if h.ClientID == "" {
+			return fmt.Errorf("client_id is required with token_url")
+		}
+		if h.Username != "" || h.Password != "" {
+			return fmt.Errorf("username and password can not be used with token_url")
+		}
+	}
+
+	tlsConfig, err := internal.GetTLSConfig(
+		h.SSLCert, h.SSLKey, h.SSLCA, h.InsecureSkipVerify)
+	if err != nil {
+		return err
+	}
+	h.client = &http.Client{
+		Timeout: h.Timeout.Duration,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}
+	if h.TokenURL != "" {
+		h.tokens = &tokenSource{
+			client:       h.client,
+			tokenURL:     h.TokenURL,
+			clientID:     h.ClientID,
+			clientSecret: h.ClientSecret,
+			scopes:       h.Scopes,
+		}
+	}
+	return nil
+}
+
+func (h *HTTP) Close() error {
+	return nil
+}
+
+func (h *HTTP) SampleConfig() string {
+	return sampleConfig
+}
+
+func (h *HTTP) Description() string {
+	return "A plugin that can transmit metrics over HTTP"
+}
+
+func (h *HTTP) Write(metrics []telegraf.Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+	body, err := h.serializeBatch(metrics)
+	if err != nil {
+		return err
+	}
+	if h.ContentEncoding == "gzip" {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		w.Write(body)
+		if err := w.Close(); err != nil {
+			return err
+		}
+		body = buf.Bytes()
+	}
+
+	retried, err := h.send(body)
+	if retried {
+		// The token was rejected before its expiry, ie revoked, the request
+		// is sent again with a new token.
+		_, err = h.send(body)
+	}
+	return err
+}
+
+// send sends the request of the body, returning true if its token was
+// rejected and the request should be sent again.
+func (h *HTTP) send(body []byte) (bool, error) {
+	req, err := http.NewRequest(h.Method, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	req.Header.Set("User-Agent", "Telegraf")
+	if h.ContentEncoding == "gzip" {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for k, v := range h.Headers {
+		req.Header.Set(k, v)
+	}
+	if h.Username != "" || h.Password != "" {
+		req.SetBasicAuth(h.Username, h.Password)
+	}
+	var token string
+	if h.tokens != nil {
+		token, err = h.tokens.token()
+		if err != nil {
+			return false, fmt.Errorf("unable to get an access token: %s", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		io.Copy(ioutil.Discard, resp.Body)
+		return false, nil
+	}
+
+	rejected := resp.StatusCode == http.StatusUnauthorized && token != ""
+	if rejected {
+		h.tokens.invalidate(token)
+	}
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	return rejected, fmt.Errorf("when writing to [%s] received status code: %d: %s",
+		h.URL, resp.StatusCode, strings.TrimSpace(string(msg)))
+}
+
+func (h *HTTP) serializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	if s, ok := h.serializer.(serializers.BatchSerializer); ok {
+		return s.SerializeBatch(metrics)
+	}
+	var buf []byte
+	for _, m := range metrics {
+		b, err := h.serializer.Serialize(m)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, b...)
+	}
+	return buf, nil
+}
+
+func init() {
+	outputs.Add("http", func() telegraf.Output {
+		return &HTTP{
+			Method:  "POST",
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
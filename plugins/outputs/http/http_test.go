@@ -0,0 +1,128 @@
+package http
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newOutput(t *testing.T, url string) *HTTP {
+	serializer, err := serializers.NewInfluxSerializer()
+	require.NoError(t, err)
+	h := &HTTP{URL: url, Method: "POST"}
+	h.SetSerializer(serializer)
+	return h
+}
+
+func TestWrite(t *testing.T) {
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "PUT", r.Method)
+		assert.Equal(t, "application/x-influx", r.Header.Get("Content-Type"))
+		user, pass, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "user", user)
+		assert.Equal(t, "pass", pass)
+		require.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		zr, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		b, err := ioutil.ReadAll(zr)
+		require.NoError(t, err)
+		body = string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	h := newOutput(t, ts.URL)
+	h.Method = "put"
+	h.Username = "user"
+	h.Password = "pass"
+	h.ContentEncoding = "gzip"
+	h.Headers = map[string]string{"Content-Type": "application/x-influx"}
+	require.NoError(t, h.Connect())
+	require.NoError(t, h.Write(testutil.MockMetrics()))
+	assert.Equal(t, "test1,tag1=value1 value=1 1257894000000000000\n", body)
+}
+
+func TestWriteError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid metric", http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	h := newOutput(t, ts.URL)
+	require.NoError(t, h.Connect())
+	err := h.Write(testutil.MockMetrics())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "400: invalid metric")
+}
+
+func TestOAuth2(t *testing.T) {
+	var mu sync.Mutex
+	var tokens, writes int
+	revoked := map[string]bool{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.URL.Path {
+		case "/token":
+			id, secret, ok := r.BasicAuth()
+			assert.True(t, ok)
+			assert.Equal(t, "client", id)
+			assert.Equal(t, "s%40cret", secret)
+			assert.Equal(t, "client_credentials", r.FormValue("grant_type"))
+			assert.Equal(t, "read write", r.FormValue("scope"))
+			tokens++
+			fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"bearer","expires_in":3600}`, tokens)
+		case "/write":
+			auth := r.Header.Get("Authorization")
+			if revoked[auth] {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			writes++
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	h := newOutput(t, ts.URL+"/write")
+	h.TokenURL = ts.URL + "/token"
+	h.ClientID = "client"
+	h.ClientSecret = "s@cret"
+	h.Scopes = []string{"read", "write"}
+	require.NoError(t, h.Connect())
+
+	// The token is reused until it is rejected.
+	require.NoError(t, h.Write(testutil.MockMetrics()))
+	require.NoError(t, h.Write(testutil.MockMetrics()))
+	mu.Lock()
+	revoked["Bearer token-1"] = true
+	mu.Unlock()
+	require.NoError(t, h.Write(testutil.MockMetrics()))
+	assert.Equal(t, 2, tokens)
+	assert.Equal(t, 3, writes)
+}
+
+func TestConnectErrors(t *testing.T) {
+	h := newOutput(t, "http://localhost")
+	h.Method = "GET"
+	assert.Error(t, h.Connect())
+
+	h = newOutput(t, "http://localhost")
+	h.TokenURL = "http://localhost/token"
+	assert.Error(t, h.Connect())
+}
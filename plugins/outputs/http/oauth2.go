@@ -0,0 +1,93 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenSource returns the access tokens of the OAuth2 client credentials
+// grant of the client.
+type tokenSource struct {
+	client       *http.Client
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	scopes       []string
+
+	mu          sync.Mutex
+	accessToken string
+	expiry      time.Time
+}
+
+// token returns a valid access token, requesting a new one when needed.
+func (ts *tokenSource) token() (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.accessToken != "" && (ts.expiry.IsZero() || time.Now().Before(ts.expiry)) {
+		return ts.accessToken, nil
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	if len(ts.scopes) > 0 {
+		form.Set("scope", strings.Join(ts.scopes, " "))
+	}
+	req, err := http.NewRequest("POST", ts.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// The client credentials are sent with the basic authentication scheme,
+	// url encoded as required by RFC 6749.
+	req.SetBasicAuth(url.QueryEscape(ts.clientID), url.QueryEscape(ts.clientSecret))
+
+	resp, err := ts.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request failed with status code %d (%s)",
+			resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	var t struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return "", fmt.Errorf("unable to parse token response, %s", err)
+	}
+	if t.AccessToken == "" {
+		return "", fmt.Errorf("no access token in token response")
+	}
+
+	ts.accessToken = t.AccessToken
+	ts.expiry = time.Time{}
+	if t.ExpiresIn > 0 {
+		// Renew the token a minute before it expires, or halfway through its
+		// lifetime if it is shorter.
+		margin := time.Minute
+		if lifetime := time.Duration(t.ExpiresIn) * time.Second; lifetime < 2*margin {
+			margin = lifetime / 2
+		}
+		ts.expiry = time.Now().Add(time.Duration(t.ExpiresIn)*time.Second - margin)
+	}
+	return ts.accessToken, nil
+}
+
+// invalidate discards the token, rejected before it expires.
+func (ts *tokenSource) invalidate(token string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.accessToken == token {
+		ts.accessToken = ""
+	}
+}
@@ -0,0 +1,52 @@
+package influxdb
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"sort"
+	"strconv"
+)
+
+// replicas is the number of points of each server on the hash ring, so the
+// series are evenly spread across the servers.
+const replicas = 100
+
+// hashRing maps keys to servers by consistent hashing: adding or removing a
+// server only moves the keys of this server.
+type hashRing struct {
+	points  []uint32
+	servers map[uint32]int
+}
+
+// newHashRing returns a ring of the servers identified by their urls.
+func newHashRing(urls []string) *hashRing {
+	r := &hashRing{servers: make(map[uint32]int)}
+	for n, u := range urls {
+		for i := 0; i < replicas; i++ {
+			h := hash(u + "-" + strconv.Itoa(i))
+			if _, ok := r.servers[h]; ok {
+				continue
+			}
+			r.servers[h] = n
+			r.points = append(r.points, h)
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+	return r
+}
+
+// get returns the index of the server of the key.
+func (r *hashRing) get(key string) int {
+	h := hash(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.servers[r.points[i]]
+}
+
+// hash returns a hash of s evenly distributed even for similar strings.
+func hash(s string) uint32 {
+	sum := md5.Sum([]byte(s))
+	return binary.BigEndian.Uint32(sum[:4])
+}
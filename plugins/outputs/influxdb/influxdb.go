@@ -6,6 +6,7 @@ import (
 	"log"
 	"math/rand"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -42,8 +43,14 @@ type InfluxDB struct {
 	// Precision is only here for legacy support. It will be ignored.
 	Precision string
 
+	LoadBalance    string `toml:"load_balance"`
+	LoadBalanceTag string `toml:"load_balance_tag"`
+
 	clients      []client.Client
 	splitPayload bool
+	// next is the client written to first by round robin.
+	next uint32
+	ring *hashRing
 }
 
 var sampleConfig = `
@@ -55,6 +62,16 @@ var sampleConfig = `
   ## this means that only ONE of the urls will be written to each interval.
   # urls = ["udp://localhost:8089"] # UDP endpoint example
   urls = ["http://localhost:8086"] # required
+
+  ## How the writes are distributed across multiple urls, the next urls are
+  ## written to when a write fails:
+  ##   "random"      : each batch is written to a random url.
+  ##   "round_robin" : the batches are written to the urls in turn.
+  ##   "hash"        : the metrics are written to the url chosen by
+  ##                   consistent hashing of the load_balance_tag, so the
+  ##                   metrics of a series always go to the same url.
+  # load_balance = "random"
+  # load_balance_tag = "host"
   ## The target database for metrics (telegraf will create it if not exists).
   database = "telegraf" # required
 
@@ -93,6 +110,17 @@ func (i *InfluxDB) Connect() error {
 		urls = append(urls, i.URL)
 	}
 
+	switch i.LoadBalance {
+	case "", "random", "round_robin":
+	case "hash":
+		if i.LoadBalanceTag == "" {
+			return fmt.Errorf("load_balance_tag is required to load balance by hash")
+		}
+		i.ring = newHashRing(urls)
+	default:
+		return fmt.Errorf("invalid load_balance %q", i.LoadBalance)
+	}
+
 	tlsConfig, err := internal.GetTLSConfig(
 		i.SSLCert, i.SSLKey, i.SSLCA, i.InsecureSkipVerify)
 	if err != nil {
@@ -174,20 +202,64 @@ func (i *InfluxDB) getReader(metrics []telegraf.Metric) io.Reader {
 	return metric.NewReader(splitData)
 }
 
-// Write will choose a random server in the cluster to write to until a successful write
-// occurs, logging each unsuccessful. If all servers fail, return error.
+// Write will choose a server in the cluster to write to, following the load
+// balancing, until a successful write occurs, logging each unsuccessful. If
+// all servers fail, return error.
 func (i *InfluxDB) Write(metrics []telegraf.Metric) error {
+	switch i.LoadBalance {
+	case "round_robin":
+		n := int(atomic.AddUint32(&i.next, 1)-1) % len(i.clients)
+		return i.writeFrom(n, metrics)
+	case "hash":
+		// the metrics of each server are written separately, only the
+		// metrics of the failed batches are retried.
+		batches := make(map[int][]telegraf.Metric)
+		var order []int
+		for _, m := range metrics {
+			tag := m.Tags()[i.LoadBalanceTag]
+			n := i.ring.get(tag)
+			if _, ok := batches[n]; !ok {
+				order = append(order, n)
+			}
+			batches[n] = append(batches[n], m)
+		}
+		var err error
+		var failed []telegraf.Metric
+		for _, n := range order {
+			if e := i.writeFrom(n, batches[n]); e != nil {
+				err = e
+				failed = append(failed, batches[n]...)
+			}
+		}
+		if err != nil && len(failed) < len(metrics) {
+			return &telegraf.PartialWriteError{Failed: failed, Err: err}
+		}
+		return err
+	}
+	return i.writeTo(rand.Perm(len(i.clients)), metrics)
+}
+
+// writeFrom writes to the servers in turn, starting with server n.
+func (i *InfluxDB) writeFrom(n int, metrics []telegraf.Metric) error {
+	order := make([]int, len(i.clients))
+	for j := range order {
+		order[j] = (n + j) % len(i.clients)
+	}
+	return i.writeTo(order, metrics)
+}
+
+// writeTo writes to the servers in the given order until a write succeeds.
+func (i *InfluxDB) writeTo(order []int, metrics []telegraf.Metric) error {
 	bufsize := 0
 	for _, m := range metrics {
 		bufsize += m.Len()
 	}
-	r := i.getReader(metrics)
 
 	// This will get set to nil if a successful write occurs
 	err := fmt.Errorf("Could not write to any InfluxDB server in cluster")
 
-	p := rand.Perm(len(i.clients))
-	for _, n := range p {
+	for _, n := range order {
+		r := i.getReader(metrics)
 		if _, e := i.clients[n].WriteStream(r, bufsize); e != nil {
 			// If the database was not found, try to recreate it:
 			if strings.Contains(e.Error(), "database not found") {
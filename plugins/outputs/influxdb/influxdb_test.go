@@ -2,12 +2,18 @@ package influxdb
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/testutil"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -164,3 +170,137 @@ func TestHTTPError_FieldTypeConflict(t *testing.T) {
 	require.NoError(t, err)
 	require.NoError(t, i.Close())
 }
+
+// newCountingServer returns a server counting the lines written to it, it
+// fails the writes if fail is set.
+func newCountingServer(lines *int, fail *bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/write":
+			if *fail {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			body, _ := ioutil.ReadAll(r.Body)
+			*lines += strings.Count(string(body), "\n")
+			w.WriteHeader(http.StatusNoContent)
+		case "/query":
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintln(w, `{"results":[{}]}`)
+		}
+	}))
+}
+
+func TestHTTPInflux_LoadBalanceRoundRobin(t *testing.T) {
+	var lines [2]int
+	var fail [2]bool
+	ts1 := newCountingServer(&lines[0], &fail[0])
+	defer ts1.Close()
+	ts2 := newCountingServer(&lines[1], &fail[1])
+	defer ts2.Close()
+
+	i := newInflux()
+	i.URLs = []string{ts1.URL, ts2.URL}
+	i.Database = "test"
+	i.LoadBalance = "round_robin"
+	require.NoError(t, i.Connect())
+
+	for n := 0; n < 4; n++ {
+		require.NoError(t, i.Write(testutil.MockMetrics()))
+	}
+	assert.Equal(t, [2]int{2, 2}, lines)
+
+	// the next server is written to when a write fails.
+	fail[0] = true
+	for n := 0; n < 2; n++ {
+		require.NoError(t, i.Write(testutil.MockMetrics()))
+	}
+	assert.Equal(t, [2]int{2, 4}, lines)
+}
+
+func TestHTTPInflux_LoadBalanceHash(t *testing.T) {
+	var lines [2]int
+	var fail [2]bool
+	ts1 := newCountingServer(&lines[0], &fail[0])
+	defer ts1.Close()
+	ts2 := newCountingServer(&lines[1], &fail[1])
+	defer ts2.Close()
+
+	i := newInflux()
+	i.URLs = []string{ts1.URL, ts2.URL}
+	i.Database = "test"
+	i.LoadBalance = "hash"
+	i.LoadBalanceTag = "host"
+	require.NoError(t, i.Connect())
+
+	var metrics []telegraf.Metric
+	for n := 0; n < 100; n++ {
+		m, err := metric.New("cpu",
+			map[string]string{"host": fmt.Sprintf("host%d", n)},
+			map[string]interface{}{"value": 1}, time.Now())
+		require.NoError(t, err)
+		metrics = append(metrics, m)
+	}
+	require.NoError(t, i.Write(metrics))
+	assert.Equal(t, 100, lines[0]+lines[1])
+	assert.NotZero(t, lines[0])
+	assert.NotZero(t, lines[1])
+
+	// the metrics of a host always go to the same server.
+	first := lines
+	require.NoError(t, i.Write(metrics))
+	assert.Equal(t, [2]int{2 * first[0], 2 * first[1]}, lines)
+}
+
+func TestHTTPInflux_LoadBalanceHashPartial(t *testing.T) {
+	// the servers fail the writes of the metrics of host0.
+	var lines int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/write":
+			body, _ := ioutil.ReadAll(r.Body)
+			if strings.Contains(string(body), "host=host0 ") {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			lines += strings.Count(string(body), "\n")
+			w.WriteHeader(http.StatusNoContent)
+		case "/query":
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintln(w, `{"results":[{}]}`)
+		}
+	}))
+	defer ts.Close()
+
+	i := newInflux()
+	i.URLs = []string{ts.URL, ts.URL + "/"}
+	i.Database = "test"
+	i.LoadBalance = "hash"
+	i.LoadBalanceTag = "host"
+	require.NoError(t, i.Connect())
+
+	var metrics []telegraf.Metric
+	for n := 0; n < 100; n++ {
+		m, err := metric.New("cpu",
+			map[string]string{"host": fmt.Sprintf("host%d", n)},
+			map[string]interface{}{"value": 1}, time.Now())
+		require.NoError(t, err)
+		metrics = append(metrics, m)
+	}
+	err := i.Write(metrics)
+	require.Error(t, err)
+	pe, ok := err.(*telegraf.PartialWriteError)
+	require.True(t, ok)
+	assert.Equal(t, 100, lines+len(pe.Failed))
+	assert.Contains(t, pe.Failed, metrics[0])
+}
+
+func TestInflux_LoadBalanceInvalid(t *testing.T) {
+	i := newInflux()
+	i.LoadBalance = "hash"
+	require.Error(t, i.Connect())
+
+	i = newInflux()
+	i.LoadBalance = "sticky"
+	require.Error(t, i.Connect())
+}
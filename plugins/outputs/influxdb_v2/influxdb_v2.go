@@ -0,0 +1,279 @@
+package influxdb_v2
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+// InfluxDB writes the metrics to the InfluxDB 2.x write API.
+type InfluxDB struct {
+	URLs         []string `toml:"urls"`
+	Token        string
+	Organization string
+	Bucket       string
+	// BucketTag is the tag of the bucket of each metric, the metrics
+	// without it being written to Bucket. ExcludeBucketTag removes the tag
+	// from the metrics written.
+	BucketTag        string
+	ExcludeBucketTag bool
+	Timeout          internal.Duration
+	UserAgent        string
+	// ContentEncoding is "gzip", the default, or "identity".
+	ContentEncoding string
+
+	// Path to CA file
+	SSLCA string `toml:"ssl_ca"`
+	// Path to host cert file
+	SSLCert string `toml:"ssl_cert"`
+	// Path to cert key file
+	SSLKey string `toml:"ssl_key"`
+	// Use SSL but skip chain & host verification
+	InsecureSkipVerify bool
+
+	client *http.Client
+}
+
+var sampleConfig = `
+  ## The URLs of the InfluxDB cluster nodes. Only one of the urls is written
+  ## to each interval, the next urls are written to when a write fails.
+  urls = ["http://127.0.0.1:9999"]
+
+  ## Token for authentication.
+  token = ""
+
+  ## Organization is the name of the organization you wish to write to.
+  organization = ""
+
+  ## Destination bucket to write into.
+  bucket = ""
+
+  ## The value of this tag will be used to determine the bucket. If this
+  ## tag is not set the 'bucket' option is used as the default.
+  # bucket_tag = ""
+
+  ## If true, the bucket tag will not be added to the metric.
+  # exclude_bucket_tag = false
+
+  ## Timeout for HTTP messages.
+  # timeout = "5s"
+
+  ## HTTP User-Agent
+  # user_agent = "telegraf"
+
+  ## Content-Encoding for write request body, can be set to "gzip" to
+  ## compress body or "identity" to apply no encoding.
+  # content_encoding = "gzip"
+
+  ## Optional SSL Config
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  ## Use SSL but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+func (i *InfluxDB) Connect() error {
+	if len(i.URLs) == 0 {
+		return fmt.Errorf("at least one url is required")
+	}
+	for _, u := range i.URLs {
+		parsed, err := url.Parse(u)
+		if err != nil {
+			return fmt.Errorf("error parsing url [%s]: %s", u, err)
+		}
+		if parsed.Scheme != "http" && parsed.Scheme != "https" {
+			return fmt.Errorf("unsupported scheme [%s]: %q", u, parsed.Scheme)
+		}
+	}
+	if i.Bucket == "" && i.BucketTag == "" {
+		return fmt.Errorf("bucket or bucket_tag is required")
+	}
+	switch i.ContentEncoding {
+	case "", "gzip", "identity":
+	default:
+		return fmt.Errorf("invalid content_encoding %q", i.ContentEncoding)
+	}
+
+	tlsConfig, err := internal.GetTLSConfig(
+		i.SSLCert, i.SSLKey, i.SSLCA, i.InsecureSkipVerify)
+	if err != nil {
+		return err
+	}
+	i.client = &http.Client{
+		Timeout: i.Timeout.Duration,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}
+	return nil
+}
+
+func (i *InfluxDB) Close() error {
+	return nil
+}
+
+func (i *InfluxDB) SampleConfig() string {
+	return sampleConfig
+}
+
+func (i *InfluxDB) Description() string {
+	return "Configuration for sending metrics to InfluxDB 2.0"
+}
+
+// Write writes the metrics of each bucket to a random url, trying the next
+// urls until a write succeeds.
+func (i *InfluxDB) Write(metrics []telegraf.Metric) error {
+	batches := make(map[string][]telegraf.Metric)
+	var buckets []string
+	for _, m := range metrics {
+		bucket := i.Bucket
+		if i.BucketTag != "" {
+			if v, ok := m.Tags()[i.BucketTag]; ok && v != "" {
+				bucket = v
+				if i.ExcludeBucketTag {
+					m = m.Copy()
+					m.RemoveTag(i.BucketTag)
+				}
+			}
+		}
+		if bucket == "" {
+			log.Printf("E! InfluxDB v2 Output Error: no bucket for metric %s, dropping it", m.Name())
+			continue
+		}
+		if _, ok := batches[bucket]; !ok {
+			buckets = append(buckets, bucket)
+		}
+		batches[bucket] = append(batches[bucket], m)
+	}
+
+	var err error
+	order := rand.Perm(len(i.URLs))
+	for _, bucket := range buckets {
+		if e := i.writeBucket(order, bucket, batches[bucket]); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (i *InfluxDB) writeBucket(order []int, bucket string, metrics []telegraf.Metric) error {
+	body, err := i.body(metrics)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range order {
+		e := i.write(i.URLs[n], bucket, body)
+		if e == nil {
+			return nil
+		}
+		if _, ok := e.(*pointsError); ok {
+			// The points rejected are dropped, otherwise they would be
+			// retried forever.
+			log.Printf("E! InfluxDB v2 Output Error: %s, dropping the points", e)
+			return nil
+		}
+		log.Printf("E! InfluxDB v2 Output Error: %s", e)
+	}
+	return fmt.Errorf("Could not write to any InfluxDB v2 server")
+}
+
+// body returns the line protocol of the metrics, compressed according to
+// ContentEncoding.
+func (i *InfluxDB) body(metrics []telegraf.Metric) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.Writer = &buf
+	var gz *gzip.Writer
+	if i.ContentEncoding != "identity" {
+		gz = gzip.NewWriter(&buf)
+		w = gz
+	}
+	for _, m := range metrics {
+		if _, err := w.Write(m.Serialize()); err != nil {
+			return nil, err
+		}
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// pointsError is the error of the writes whose points are rejected by the
+// server.
+type pointsError struct {
+	status  int
+	message string
+}
+
+func (e *pointsError) Error() string {
+	return fmt.Sprintf("points rejected, status code [%d]: %s", e.status, e.message)
+}
+
+func (i *InfluxDB) write(u, bucket string, body []byte) error {
+	params := url.Values{}
+	params.Set("org", i.Organization)
+	params.Set("bucket", bucket)
+	params.Set("precision", "ns")
+	req, err := http.NewRequest("POST",
+		strings.TrimRight(u, "/")+"/api/v2/write?"+params.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if i.ContentEncoding != "identity" {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if i.Token != "" {
+		req.Header.Set("Authorization", "Token "+i.Token)
+	}
+	userAgent := i.UserAgent
+	if userAgent == "" {
+		userAgent = "telegraf"
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	switch resp.StatusCode {
+	case http.StatusNoContent, http.StatusOK:
+		return nil
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		return &pointsError{status: resp.StatusCode, message: strings.TrimSpace(string(message))}
+	}
+	return fmt.Errorf("write to [%s] failed, status code [%d]: %s",
+		u, resp.StatusCode, strings.TrimSpace(string(message)))
+}
+
+func newInfluxDB() *InfluxDB {
+	return &InfluxDB{
+		Timeout: internal.Duration{Duration: time.Second * 5},
+	}
+}
+
+func init() {
+	rand.Seed(time.Now().UnixNano())
+	outputs.Add("influxdb_v2", func() telegraf.Output { return newInfluxDB() })
+}
@@ -0,0 +1,119 @@
+package influxdb_v2
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v2/write", r.URL.Path)
+		assert.Equal(t, "myorg", r.FormValue("org"))
+		assert.Equal(t, "telegraf", r.FormValue("bucket"))
+		assert.Equal(t, "ns", r.FormValue("precision"))
+		assert.Equal(t, "Token secret", r.Header.Get("Authorization"))
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+
+		gz, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		b, err := ioutil.ReadAll(gz)
+		require.NoError(t, err)
+		body = string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	i := newInfluxDB()
+	i.URLs = []string{ts.URL}
+	i.Token = "secret"
+	i.Organization = "myorg"
+	i.Bucket = "telegraf"
+	require.NoError(t, i.Connect())
+
+	metrics := testutil.MockMetrics()
+	require.NoError(t, i.Write(metrics))
+	assert.Equal(t, string(metrics[0].Serialize()), body)
+}
+
+func TestWriteBucketTag(t *testing.T) {
+	var mu sync.Mutex
+	bodies := make(map[string]string)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "", r.Header.Get("Content-Encoding"))
+		b, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		mu.Lock()
+		bodies[r.FormValue("bucket")] += string(b)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	i := newInfluxDB()
+	i.URLs = []string{ts.URL}
+	i.Bucket = "default"
+	i.BucketTag = "bucket"
+	i.ExcludeBucketTag = true
+	i.ContentEncoding = "identity"
+	require.NoError(t, i.Connect())
+
+	now := time.Unix(0, 0)
+	m1, _ := metric.New("cpu", map[string]string{"bucket": "hosts"},
+		map[string]interface{}{"value": 1.0}, now)
+	m2, _ := metric.New("cpu", map[string]string{"host": "a"},
+		map[string]interface{}{"value": 2.0}, now)
+	require.NoError(t, i.Write([]telegraf.Metric{m1, m2}))
+
+	assert.Equal(t, map[string]string{
+		"hosts":   "cpu value=1 0\n",
+		"default": "cpu,host=a value=2 0\n",
+	}, bodies)
+	// The metrics of the batch are not modified.
+	assert.True(t, m1.HasTag("bucket"))
+}
+
+func TestWriteErrors(t *testing.T) {
+	status := http.StatusBadRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(`{"code":"invalid","message":"bad point"}`))
+	}))
+	defer ts.Close()
+
+	i := newInfluxDB()
+	i.URLs = []string{ts.URL}
+	i.Bucket = "telegraf"
+	require.NoError(t, i.Connect())
+
+	// The points rejected are dropped.
+	require.NoError(t, i.Write(testutil.MockMetrics()))
+
+	for _, status = range []int{http.StatusUnauthorized, http.StatusTooManyRequests,
+		http.StatusServiceUnavailable} {
+		assert.Error(t, i.Write(testutil.MockMetrics()), http.StatusText(status))
+	}
+}
+
+func TestConnectErrors(t *testing.T) {
+	for _, i := range []*InfluxDB{
+		{Bucket: "telegraf"},
+		{URLs: []string{"udp://localhost:8089"}, Bucket: "telegraf"},
+		{URLs: []string{"http://localhost:9999"}},
+		{URLs: []string{"http://localhost:9999"}, Bucket: "telegraf", ContentEncoding: "zip"},
+	} {
+		assert.Error(t, i.Connect())
+	}
+}
@@ -0,0 +1,310 @@
+package loki
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+var invalidLabelCharRE = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// Loki pushes the metrics as log lines to the push API of Loki, the tags
+// being the labels of the streams.
+type Loki struct {
+	Domain   string
+	Endpoint string
+	TenantID string `toml:"tenant_id"`
+	Username string
+	Password string
+	Token    string
+	Headers  map[string]string
+	Timeout  internal.Duration
+	GZip     bool `toml:"gzip"`
+	// LineField is the string field which is the line of the metrics having
+	// it, the line of the other metrics being their fields in logfmt.
+	LineField string
+	// MeasurementLabel is the label of the measurement, omitted if empty.
+	MeasurementLabel string
+
+	// Path to CA file
+	SSLCA string `toml:"ssl_ca"`
+	// Path to host cert file
+	SSLCert string `toml:"ssl_cert"`
+	// Path to cert key file
+	SSLKey string `toml:"ssl_key"`
+	// Use SSL but skip chain & host verification
+	InsecureSkipVerify bool
+
+	client *http.Client
+}
+
+var sampleConfig = `
+  ## The domain of Loki.
+  domain = "http://localhost:3100"
+
+  ## Endpoint of the push API.
+  # endpoint = "/loki/api/v1/push"
+
+  ## Tenant of the streams, sent as the X-Scope-OrgID header, in the
+  ## multi-tenant mode of Loki.
+  # tenant_id = ""
+
+  ## Basic authentication or bearer token of the requests.
+  # username = ""
+  # password = ""
+  # token = ""
+
+  ## Additional HTTP headers of the requests.
+  # [outputs.loki.headers]
+  #   X-Custom-Header = "value"
+
+  ## Timeout of the requests.
+  # timeout = "5s"
+
+  ## Compress the requests with gzip.
+  # gzip = true
+
+  ## String field which is the log line of the metrics having it, ie the
+  ## message field of the syslog input. The line of the other metrics is
+  ## their fields in logfmt.
+  # line_field = "message"
+
+  ## Label of the measurement of the metrics, omitted if empty.
+  # measurement_label = "measurement"
+
+  ## Optional SSL Config
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  ## Use SSL but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+func (l *Loki) Connect() error {
+	if l.Domain == "" {
+		return fmt.Errorf("domain is required")
+	}
+
+	tlsConfig, err := internal.GetTLSConfig(
+		l.SSLCert, l.SSLKey, l.SSLCA, l.InsecureSkipVerify)
+	if err != nil {
+		return err
+	}
+	l.client = &http.Client{
+		Timeout: l.Timeout.Duration,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}
+	return nil
+}
+
+func (l *Loki) Close() error {
+	return nil
+}
+
+func (l *Loki) SampleConfig() string {
+	return sampleConfig
+}
+
+func (l *Loki) Description() string {
+	return "Send metrics as logs to Loki"
+}
+
+// stream is a stream of the push requests, its values being the timestamps
+// in nanoseconds, as strings, and the lines of its entries.
+type stream struct {
+	Labels map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+
+	timestamps []int64
+}
+
+func (s *stream) Len() int           { return len(s.Values) }
+func (s *stream) Less(i, j int) bool { return s.timestamps[i] < s.timestamps[j] }
+func (s *stream) Swap(i, j int) {
+	s.Values[i], s.Values[j] = s.Values[j], s.Values[i]
+	s.timestamps[i], s.timestamps[j] = s.timestamps[j], s.timestamps[i]
+}
+
+func (l *Loki) Write(metrics []telegraf.Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	var streams []*stream
+	byLabels := make(map[string]*stream)
+	for _, m := range metrics {
+		labels := l.labels(m)
+		key := labelsKey(labels)
+		s, ok := byLabels[key]
+		if !ok {
+			s = &stream{Labels: labels}
+			byLabels[key] = s
+			streams = append(streams, s)
+		}
+		ts := m.UnixNano()
+		s.Values = append(s.Values, [2]string{strconv.FormatInt(ts, 10), l.line(m)})
+		s.timestamps = append(s.timestamps, ts)
+	}
+	// The entries of a stream are pushed in the order of their time.
+	for _, s := range streams {
+		sort.Stable(s)
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"streams": streams})
+	if err != nil {
+		return err
+	}
+	return l.push(body)
+}
+
+// labels returns the labels of the metric, its tags with valid names and its
+// measurement.
+func (l *Loki) labels(m telegraf.Metric) map[string]string {
+	labels := make(map[string]string)
+	for k, v := range m.Tags() {
+		k = invalidLabelCharRE.ReplaceAllString(k, "_")
+		if k == "" || strings.HasPrefix(k, "__") || (k[0] >= '0' && k[0] <= '9') {
+			continue
+		}
+		labels[k] = v
+	}
+	if l.MeasurementLabel != "" {
+		labels[l.MeasurementLabel] = m.Name()
+	}
+	return labels
+}
+
+func labelsKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b bytes.Buffer
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte(0)
+		b.WriteString(labels[k])
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+// line returns the log line of the metric, its line field or its fields in
+// logfmt, sorted by key.
+func (l *Loki) line(m telegraf.Metric) string {
+	fields := m.Fields()
+	if l.LineField != "" {
+		if line, ok := fields[l.LineField].(string); ok {
+			return line
+		}
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		var v string
+		switch fv := fields[k].(type) {
+		case string:
+			v = fv
+		case float64:
+			v = strconv.FormatFloat(fv, 'f', -1, 64)
+		default:
+			v = fmt.Sprint(fv)
+		}
+		if v == "" || strings.ContainsAny(v, " =\"\t\n") {
+			v = strconv.Quote(v)
+		}
+		parts = append(parts, k+"="+v)
+	}
+	return strings.Join(parts, " ")
+}
+
+func (l *Loki) push(body []byte) error {
+	var reader io.Reader = bytes.NewReader(body)
+	if l.GZip {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		w.Write(body)
+		if err := w.Close(); err != nil {
+			return err
+		}
+		reader = &buf
+	}
+
+	u := strings.TrimRight(l.Domain, "/") + l.Endpoint
+	req, err := http.NewRequest("POST", u, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Telegraf")
+	if l.GZip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if l.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", l.TenantID)
+	}
+	for k, v := range l.Headers {
+		req.Header.Set(k, v)
+	}
+	if l.Username != "" || l.Password != "" {
+		req.SetBasicAuth(l.Username, l.Password)
+	} else if l.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+l.Token)
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("push to %s failed with status code %d: %s",
+		u, resp.StatusCode, strings.TrimSpace(string(msg)))
+	if resp.StatusCode == http.StatusBadRequest {
+		// The entries are rejected, ie out of order or too old, pushing them
+		// again would fail again.
+		log.Printf("E! Loki: %s", err)
+		return nil
+	}
+	return err
+}
+
+func init() {
+	outputs.Add("loki", func() telegraf.Output {
+		return &Loki{
+			Endpoint:         "/loki/api/v1/push",
+			Timeout:          internal.Duration{Duration: 5 * time.Second},
+			GZip:             true,
+			LineField:        "message",
+			MeasurementLabel: "measurement",
+		}
+	})
+}
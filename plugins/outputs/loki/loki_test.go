@@ -0,0 +1,120 @@
+package loki
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type pushRequest struct {
+	Streams []struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	} `json:"streams"`
+}
+
+func testMetrics(t *testing.T) []telegraf.Metric {
+	syslog1, err := metric.New("syslog",
+		map[string]string{"hostname": "server-1", "app-name": "sshd"},
+		map[string]interface{}{"message": "session opened", "severity_code": int64(6)},
+		time.Unix(1500000001, 0))
+	require.NoError(t, err)
+	syslog2, err := metric.New("syslog",
+		map[string]string{"hostname": "server-1", "app-name": "sshd"},
+		map[string]interface{}{"message": "accepted key"},
+		time.Unix(1500000000, 0))
+	require.NoError(t, err)
+	cpu, err := metric.New("cpu",
+		map[string]string{"__internal": "x"},
+		map[string]interface{}{"usage_idle": 91.5, "state": "two words", "up": true},
+		time.Unix(1500000000, 0))
+	require.NoError(t, err)
+	return []telegraf.Metric{syslog1, syslog2, cpu}
+}
+
+func TestWrite(t *testing.T) {
+	var push pushRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/loki/api/v1/push", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "tenant-1", r.Header.Get("X-Scope-OrgID"))
+		user, pass, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "user", user)
+		assert.Equal(t, "pass", pass)
+		require.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		zr, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.NewDecoder(zr).Decode(&push))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	l := &Loki{
+		Domain:           ts.URL,
+		Endpoint:         "/loki/api/v1/push",
+		TenantID:         "tenant-1",
+		Username:         "user",
+		Password:         "pass",
+		GZip:             true,
+		LineField:        "message",
+		MeasurementLabel: "measurement",
+	}
+	require.NoError(t, l.Connect())
+	require.NoError(t, l.Write(testMetrics(t)))
+
+	require.Len(t, push.Streams, 2)
+	assert.Equal(t, map[string]string{
+		"hostname":    "server-1",
+		"app_name":    "sshd",
+		"measurement": "syslog",
+	}, push.Streams[0].Stream)
+	assert.Equal(t, [][2]string{
+		{"1500000000000000000", "accepted key"},
+		{"1500000001000000000", "session opened"},
+	}, push.Streams[0].Values)
+	assert.Equal(t, map[string]string{"measurement": "cpu"}, push.Streams[1].Stream)
+	assert.Equal(t, [][2]string{
+		{"1500000000000000000", `state="two words" up=true usage_idle=91.5`},
+	}, push.Streams[1].Values)
+}
+
+func TestWriteToken(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.Equal(t, "", r.Header.Get("Content-Encoding"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	l := &Loki{Domain: ts.URL, Endpoint: "/loki/api/v1/push", Token: "secret"}
+	require.NoError(t, l.Connect())
+	require.NoError(t, l.Write(testMetrics(t)))
+}
+
+func TestWriteErrors(t *testing.T) {
+	status := http.StatusBadRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "entry out of order", status)
+	}))
+	defer ts.Close()
+
+	l := &Loki{Domain: ts.URL, Endpoint: "/loki/api/v1/push"}
+	require.NoError(t, l.Connect())
+	// The rejected entries are dropped.
+	require.NoError(t, l.Write(testMetrics(t)))
+
+	status = http.StatusTooManyRequests
+	err := l.Write(testMetrics(t))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "429")
+}
@@ -1,9 +1,12 @@
 package mqtt
 
 import (
+	"bytes"
 	"fmt"
 	"strings"
 	"sync"
+	"text/template"
+	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
@@ -16,11 +19,36 @@ import (
 var sampleConfig = `
   servers = ["localhost:1883"] # required.
 
+  ## MQTT protocol version, "3.1.1" or "5".
+  # protocol_version = "3.1.1"
+
   ## MQTT outputs send metrics to this topic format
   ##    "<topic_prefix>/<hostname>/<pluginname>/"
   ##   ex: prefix/web01.example.com/mem
   topic_prefix = "telegraf"
 
+  ## Go template of the topic of each metric, overriding topic_prefix. The
+  ## metric is the data of the template: {{.Name}} is its name and
+  ## {{.Tag "site_id"}} the value of its site_id tag.
+  # topic = 'telegraf/{{.Tag "site_id"}}/{{.Name}}'
+
+  ## QoS of the messages, 0, 1 or 2.
+  # qos = 0
+
+  ## Whether the messages are retained by the server.
+  # retain = false
+
+  ## When true, the metrics of a write with the same topic are published
+  ## as one message instead of a message per metric.
+  # batch = false
+
+  ## MQTT 5 properties of the messages, only sent with protocol_version = "5".
+  # content_type = "text/plain"
+  # response_topic = ""
+  # message_expiry = "0s"
+  # [outputs.mqtt.user_properties]
+  #   source = "telegraf"
+
   ## username and password to connect MQTT server.
   # username = "telegraf"
   # password = "metricsmetricsmetricsmetrics"
@@ -51,6 +79,19 @@ type MQTT struct {
 	TopicPrefix string
 	QoS         int    `toml:"qos"`
 	ClientID    string `toml:"client_id"`
+	// ProtocolVersion is "3.1.1", the default, or "5"
+	ProtocolVersion string
+	// Topic is the template of the topics, overriding TopicPrefix
+	Topic  string
+	Retain bool
+	// Batch publishes the metrics with the same topic as one message
+	Batch bool
+
+	// MQTT 5 properties of the messages
+	ContentType    string
+	ResponseTopic  string
+	MessageExpiry  internal.Duration
+	UserProperties map[string]string
 
 	// Path to CA file
 	SSLCA string `toml:"ssl_ca"`
@@ -61,8 +102,9 @@ type MQTT struct {
 	// Use SSL but skip chain & host verification
 	InsecureSkipVerify bool
 
-	client paho.Client
+	client client
 	opts   *paho.ClientOptions
+	topic  *template.Template
 
 	serializer serializers.Serializer
 
@@ -77,17 +119,30 @@ func (m *MQTT) Connect() error {
 		return fmt.Errorf("MQTT Output, invalid QoS value: %d", m.QoS)
 	}
 
-	m.opts, err = m.createOpts()
-	if err != nil {
-		return err
+	if m.Topic != "" {
+		m.topic, err = template.New("topic").Option("missingkey=zero").Parse(m.Topic)
+		if err != nil {
+			return fmt.Errorf("MQTT Output, invalid topic template: %s", err)
+		}
 	}
 
-	m.client = paho.NewClient(m.opts)
-	if token := m.client.Connect(); token.Wait() && token.Error() != nil {
-		return token.Error()
+	switch m.ProtocolVersion {
+	case "", "3.1.1":
+		m.opts, err = m.createOpts()
+		if err != nil {
+			return err
+		}
+		m.client = &pahoClient{client: paho.NewClient(m.opts)}
+	case "5":
+		m.client, err = m.createMQTT5Client()
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("MQTT Output, unsupported protocol version: %q", m.ProtocolVersion)
 	}
 
-	return nil
+	return m.client.Connect()
 }
 
 func (m *MQTT) SetSerializer(serializer serializers.Serializer) {
@@ -95,10 +150,10 @@ func (m *MQTT) SetSerializer(serializer serializers.Serializer) {
 }
 
 func (m *MQTT) Close() error {
-	if m.client.IsConnected() {
-		m.client.Disconnect(20)
+	if m.client == nil {
+		return nil
 	}
-	return nil
+	return m.client.Close()
 }
 
 func (m *MQTT) SampleConfig() string {
@@ -120,26 +175,39 @@ func (m *MQTT) Write(metrics []telegraf.Metric) error {
 		hostname = ""
 	}
 
+	var topics []string
+	batches := make(map[string][]telegraf.Metric)
 	for _, metric := range metrics {
-		var t []string
-		if m.TopicPrefix != "" {
-			t = append(t, m.TopicPrefix)
-		}
-		if hostname != "" {
-			t = append(t, hostname)
+		topic, err := m.metricTopic(metric, hostname)
+		if err != nil {
+			return fmt.Errorf("MQTT Could not build the topic of metric: %s, %s",
+				metric.String(), err)
 		}
 
-		t = append(t, metric.Name())
-		topic := strings.Join(t, "/")
+		if !m.Batch {
+			buf, err := m.serializer.Serialize(metric)
+			if err != nil {
+				return fmt.Errorf("MQTT Could not serialize metric: %s",
+					metric.String())
+			}
+			if err := m.publish(topic, buf); err != nil {
+				return fmt.Errorf("Could not write to MQTT server, %s", err)
+			}
+			continue
+		}
 
-		buf, err := m.serializer.Serialize(metric)
-		if err != nil {
-			return fmt.Errorf("MQTT Could not serialize metric: %s",
-				metric.String())
+		if _, ok := batches[topic]; !ok {
+			topics = append(topics, topic)
 		}
+		batches[topic] = append(batches[topic], metric)
+	}
 
-		err = m.publish(topic, buf)
+	for _, topic := range topics {
+		buf, err := m.serializeBatch(batches[topic])
 		if err != nil {
+			return fmt.Errorf("MQTT Could not serialize the metrics of topic %s: %s", topic, err)
+		}
+		if err := m.publish(topic, buf); err != nil {
 			return fmt.Errorf("Could not write to MQTT server, %s", err)
 		}
 	}
@@ -147,15 +215,123 @@ func (m *MQTT) Write(metrics []telegraf.Metric) error {
 	return nil
 }
 
+// metricTopic returns the topic of the metric, hostname being the host of
+// the legacy topics.
+func (m *MQTT) metricTopic(metric telegraf.Metric, hostname string) (string, error) {
+	if m.topic != nil {
+		var b bytes.Buffer
+		if err := m.topic.Execute(&b, topicMetric{metric}); err != nil {
+			return "", err
+		}
+		return b.String(), nil
+	}
+
+	var t []string
+	if m.TopicPrefix != "" {
+		t = append(t, m.TopicPrefix)
+	}
+	if hostname != "" {
+		t = append(t, hostname)
+	}
+
+	t = append(t, metric.Name())
+	return strings.Join(t, "/"), nil
+}
+
+// topicMetric is the data of the topic templates.
+type topicMetric struct {
+	telegraf.Metric
+}
+
+// Tag returns the value of the tag, empty if the metric does not have it.
+func (m topicMetric) Tag(key string) string {
+	return m.Tags()[key]
+}
+
+func (m *MQTT) serializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	if s, ok := m.serializer.(serializers.BatchSerializer); ok {
+		return s.SerializeBatch(metrics)
+	}
+	var buf []byte
+	for _, metric := range metrics {
+		b, err := m.serializer.Serialize(metric)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, b...)
+	}
+	return buf, nil
+}
+
 func (m *MQTT) publish(topic string, body []byte) error {
-	token := m.client.Publish(topic, byte(m.QoS), false, body)
-	token.Wait()
-	if token.Error() != nil {
+	return m.client.Publish(topic, byte(m.QoS), m.Retain, body)
+}
+
+// client is the connection to the MQTT servers, of MQTT 3.1.1 or 5.
+type client interface {
+	Connect() error
+	Publish(topic string, qos byte, retain bool, payload []byte) error
+	Close() error
+}
+
+// pahoClient is the MQTT 3.1.1 client.
+type pahoClient struct {
+	client paho.Client
+}
+
+func (c *pahoClient) Connect() error {
+	if token := c.client.Connect(); token.Wait() && token.Error() != nil {
 		return token.Error()
 	}
 	return nil
 }
 
+func (c *pahoClient) Publish(topic string, qos byte, retain bool, payload []byte) error {
+	token := c.client.Publish(topic, qos, retain, payload)
+	token.Wait()
+	return token.Error()
+}
+
+func (c *pahoClient) Close() error {
+	if c.client.IsConnected() {
+		c.client.Disconnect(20)
+	}
+	return nil
+}
+
+func (m *MQTT) createMQTT5Client() (*mqtt5Client, error) {
+	tlsCfg, err := internal.GetTLSConfig(
+		m.SSLCert, m.SSLKey, m.SSLCA, m.InsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	if len(m.Servers) == 0 {
+		return nil, fmt.Errorf("could not get host infomations")
+	}
+
+	c := &mqtt5Client{
+		servers:   m.Servers,
+		tlsConfig: tlsCfg,
+		clientID:  m.ClientID,
+		username:  m.Username,
+		password:  m.Password,
+		timeout:   m.Timeout.Duration,
+		properties: publishProperties{
+			ContentType:    m.ContentType,
+			ResponseTopic:  m.ResponseTopic,
+			MessageExpiry:  m.MessageExpiry.Duration,
+			UserProperties: m.UserProperties,
+		},
+	}
+	if c.clientID == "" {
+		c.clientID = "Telegraf-Output-" + internal.RandomString(5)
+	}
+	if c.timeout == 0 {
+		c.timeout = 5 * time.Second
+	}
+	return c, nil
+}
+
 func (m *MQTT) createOpts() (*paho.ClientOptions, error) {
 	opts := paho.NewClientOptions()
 
@@ -0,0 +1,329 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"time"
+)
+
+// The control packet types of MQTT.
+const (
+	packetConnect    = 1
+	packetConnack    = 2
+	packetPublish    = 3
+	packetPuback     = 4
+	packetPubrec     = 5
+	packetPubrel     = 6
+	packetPubcomp    = 7
+	packetPingresp   = 13
+	packetDisconnect = 14
+)
+
+// The properties of MQTT 5 sent by the client.
+const (
+	propertyMessageExpiry = 0x02
+	propertyContentType   = 0x03
+	propertyResponseTopic = 0x08
+	propertyUserProperty  = 0x26
+)
+
+// publishProperties are the MQTT 5 properties of the messages published.
+type publishProperties struct {
+	ContentType    string
+	ResponseTopic  string
+	MessageExpiry  time.Duration
+	UserProperties map[string]string
+}
+
+// mqtt5Client is a client of MQTT 5 that only publishes: each message is
+// sent and acknowledged in turn, the client being used by a single
+// goroutine.
+type mqtt5Client struct {
+	servers    []string
+	tlsConfig  *tls.Config
+	clientID   string
+	username   string
+	password   string
+	timeout    time.Duration
+	properties publishProperties
+
+	conn     net.Conn
+	r        *bufio.Reader
+	packetID uint16
+}
+
+func (c *mqtt5Client) Connect() error {
+	var err error
+	for _, server := range c.servers {
+		if err = c.connect(server); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (c *mqtt5Client) connect(server string) error {
+	dialer := &net.Dialer{Timeout: c.timeout}
+	var conn net.Conn
+	var err error
+	if c.tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", server, c.tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", server)
+	}
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+	c.r = bufio.NewReader(conn)
+
+	var b bytes.Buffer
+	writeString(&b, "MQTT")
+	b.WriteByte(5)
+	var flags byte = 0x02 // clean start
+	if c.username != "" {
+		flags |= 0x80
+	}
+	if c.password != "" {
+		flags |= 0x40
+	}
+	b.WriteByte(flags)
+	// No keep alive: the connection is idle between the writes, it is
+	// opened again when it has been closed by the server.
+	binary.Write(&b, binary.BigEndian, uint16(0))
+	writeVarint(&b, 0)
+	writeString(&b, c.clientID)
+	if c.username != "" {
+		writeString(&b, c.username)
+	}
+	if c.password != "" {
+		writeString(&b, c.password)
+	}
+	if err := c.send(packetConnect<<4, b.Bytes()); err != nil {
+		c.Close()
+		return err
+	}
+
+	packetType, body, err := c.receive()
+	if err != nil {
+		c.Close()
+		return err
+	}
+	if packetType != packetConnack || len(body) < 2 {
+		c.Close()
+		return fmt.Errorf("mqtt5: unexpected packet %d instead of CONNACK", packetType)
+	}
+	if body[1] >= 0x80 {
+		c.Close()
+		return fmt.Errorf("mqtt5: connection refused by %s, reason code 0x%02x", server, body[1])
+	}
+	return nil
+}
+
+// Publish sends the message, waiting for its acknowledgement with QoS 1
+// and 2. The message is sent again on a new connection when the connection
+// has been closed.
+func (c *mqtt5Client) Publish(topic string, qos byte, retain bool, payload []byte) error {
+	if c.conn == nil {
+		if err := c.Connect(); err != nil {
+			return err
+		}
+	}
+	err := c.publish(topic, qos, retain, payload)
+	if _, ok := err.(*reasonError); ok || err == nil {
+		return err
+	}
+	c.Close()
+	if err := c.Connect(); err != nil {
+		return err
+	}
+	return c.publish(topic, qos, retain, payload)
+}
+
+func (c *mqtt5Client) publish(topic string, qos byte, retain bool, payload []byte) error {
+	var b bytes.Buffer
+	writeString(&b, topic)
+	var id uint16
+	if qos > 0 {
+		c.packetID++
+		if c.packetID == 0 {
+			c.packetID = 1
+		}
+		id = c.packetID
+		binary.Write(&b, binary.BigEndian, id)
+	}
+	props := c.properties.encode()
+	writeVarint(&b, len(props))
+	b.Write(props)
+	b.Write(payload)
+
+	flags := byte(packetPublish<<4) | qos<<1
+	if retain {
+		flags |= 0x01
+	}
+	if err := c.send(flags, b.Bytes()); err != nil {
+		return err
+	}
+
+	switch qos {
+	case 1:
+		return c.ack(id, packetPuback)
+	case 2:
+		if err := c.ack(id, packetPubrec); err != nil {
+			return err
+		}
+		var rel bytes.Buffer
+		binary.Write(&rel, binary.BigEndian, id)
+		if err := c.send(packetPubrel<<4|0x02, rel.Bytes()); err != nil {
+			return err
+		}
+		return c.ack(id, packetPubcomp)
+	}
+	return nil
+}
+
+// reasonError is the error of the messages refused by the server.
+type reasonError struct {
+	packet int
+	code   byte
+}
+
+func (e *reasonError) Error() string {
+	return fmt.Sprintf("mqtt5: message refused, packet %d reason code 0x%02x", e.packet, e.code)
+}
+
+// ack waits for the acknowledgement of the packet id.
+func (c *mqtt5Client) ack(id uint16, expected int) error {
+	for {
+		packetType, body, err := c.receive()
+		if err != nil {
+			return err
+		}
+		switch packetType {
+		case expected:
+			if len(body) < 2 || binary.BigEndian.Uint16(body) != id {
+				return fmt.Errorf("mqtt5: unexpected packet id in acknowledgement")
+			}
+			if len(body) > 2 && body[2] >= 0x80 {
+				return &reasonError{packet: expected, code: body[2]}
+			}
+			return nil
+		case packetPingresp:
+		case packetDisconnect:
+			return fmt.Errorf("mqtt5: disconnected by the server")
+		default:
+			return fmt.Errorf("mqtt5: unexpected packet %d", packetType)
+		}
+	}
+}
+
+func (c *mqtt5Client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	c.send(packetDisconnect<<4, nil)
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+func (c *mqtt5Client) send(header byte, body []byte) error {
+	var b bytes.Buffer
+	b.WriteByte(header)
+	writeVarint(&b, len(body))
+	b.Write(body)
+	if c.timeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	}
+	_, err := c.conn.Write(b.Bytes())
+	return err
+}
+
+// receive returns the type and the body of the next packet.
+func (c *mqtt5Client) receive() (int, []byte, error) {
+	if c.timeout > 0 {
+		c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+	}
+	header, err := c.r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length, err := readVarint(c.r)
+	if err != nil {
+		return 0, nil, err
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return 0, nil, err
+	}
+	return int(header >> 4), body, nil
+}
+
+func (p *publishProperties) encode() []byte {
+	var b bytes.Buffer
+	if p.MessageExpiry > 0 {
+		b.WriteByte(propertyMessageExpiry)
+		binary.Write(&b, binary.BigEndian, uint32(p.MessageExpiry/time.Second))
+	}
+	if p.ContentType != "" {
+		b.WriteByte(propertyContentType)
+		writeString(&b, p.ContentType)
+	}
+	if p.ResponseTopic != "" {
+		b.WriteByte(propertyResponseTopic)
+		writeString(&b, p.ResponseTopic)
+	}
+	keys := make([]string, 0, len(p.UserProperties))
+	for k := range p.UserProperties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b.WriteByte(propertyUserProperty)
+		writeString(&b, k)
+		writeString(&b, p.UserProperties[k])
+	}
+	return b.Bytes()
+}
+
+func writeString(b *bytes.Buffer, s string) {
+	binary.Write(b, binary.BigEndian, uint16(len(s)))
+	b.WriteString(s)
+}
+
+// writeVarint writes the variable byte integer n.
+func writeVarint(b *bytes.Buffer, n int) {
+	for {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		b.WriteByte(digit)
+		if n == 0 {
+			return
+		}
+	}
+}
+
+func readVarint(r io.ByteReader) (int, error) {
+	n, multiplier := 0, 1
+	for i := 0; i < 4; i++ {
+		digit, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		n += int(digit&0x7f) * multiplier
+		if digit&0x80 == 0 {
+			return n, nil
+		}
+		multiplier *= 128
+	}
+	return 0, fmt.Errorf("mqtt5: malformed variable byte integer")
+}
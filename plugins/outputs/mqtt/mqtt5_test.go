@@ -0,0 +1,152 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// message is a PUBLISH packet received by the test server.
+type message struct {
+	flags      byte
+	topic      string
+	properties []byte
+	payload    string
+}
+
+// serveMQTT5 accepts a connection, acknowledges the CONNECT, and sends the
+// messages published to the channel, acknowledging them with reason code.
+func serveMQTT5(t *testing.T, l net.Listener, reason byte, messages chan<- message) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	read := func() (byte, []byte) {
+		header, err := r.ReadByte()
+		if err != nil {
+			return 0, nil
+		}
+		length, err := readVarint(r)
+		require.NoError(t, err)
+		body := make([]byte, length)
+		_, err = io.ReadFull(r, body)
+		require.NoError(t, err)
+		return header, body
+	}
+
+	header, body := read()
+	require.Equal(t, byte(packetConnect<<4), header)
+	assert.Equal(t, []byte{0, 4, 'M', 'Q', 'T', 'T', 5}, body[:7])
+	conn.Write([]byte{packetConnack << 4, 3, 0, 0, 0})
+
+	for {
+		header, body := read()
+		switch header >> 4 {
+		case packetPublish:
+			var msg message
+			msg.flags = header & 0x0f
+			n := int(binary.BigEndian.Uint16(body))
+			msg.topic = string(body[2 : 2+n])
+			body = body[2+n:]
+			var id []byte
+			if msg.flags&0x06 != 0 {
+				id, body = body[:2], body[2:]
+			}
+			length, err := readVarint(bytes.NewReader(body))
+			require.NoError(t, err)
+			var varint bytes.Buffer
+			writeVarint(&varint, length)
+			body = body[varint.Len():]
+			msg.properties, msg.payload = body[:length], string(body[length:])
+			messages <- msg
+			if id != nil {
+				conn.Write(append([]byte{packetPuback << 4, 3}, id[0], id[1], reason))
+			}
+		case packetDisconnect:
+			return
+		default:
+			return
+		}
+	}
+}
+
+func TestMQTT5Write(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	messages := make(chan message, 10)
+	go serveMQTT5(t, l, 0, messages)
+
+	s, _ := serializers.NewInfluxSerializer()
+	m := &MQTT{
+		Servers:         []string{l.Addr().String()},
+		ProtocolVersion: "5",
+		Topic:           `site/{{.Tag "site_id"}}/{{.Name}}`,
+		QoS:             1,
+		Retain:          true,
+		ContentType:     "text/plain",
+		UserProperties:  map[string]string{"source": "telegraf"},
+		serializer:      s,
+	}
+	require.NoError(t, m.Connect())
+	defer m.Close()
+
+	metrics := testutil.MockMetrics()
+	metrics[0].AddTag("site_id", "par1")
+	require.NoError(t, m.Write(metrics))
+
+	msg := <-messages
+	assert.Equal(t, byte(0x03), msg.flags)
+	assert.Equal(t, "site/par1/test1", msg.topic)
+	assert.Equal(t, metrics[0].String(), msg.payload)
+
+	var props bytes.Buffer
+	props.WriteByte(propertyContentType)
+	writeString(&props, "text/plain")
+	props.WriteByte(propertyUserProperty)
+	writeString(&props, "source")
+	writeString(&props, "telegraf")
+	assert.Equal(t, props.Bytes(), msg.properties)
+}
+
+func TestMQTT5Refused(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	messages := make(chan message, 10)
+	// 0x87: not authorized
+	go serveMQTT5(t, l, 0x87, messages)
+
+	s, _ := serializers.NewInfluxSerializer()
+	m := &MQTT{
+		Servers:         []string{l.Addr().String()},
+		ProtocolVersion: "5",
+		QoS:             1,
+		serializer:      s,
+	}
+	require.NoError(t, m.Connect())
+	defer m.Close()
+	assert.Error(t, m.Write(testutil.MockMetrics()))
+}
+
+func TestVarint(t *testing.T) {
+	for _, n := range []int{0, 127, 128, 16383, 16384, 268435455} {
+		var b bytes.Buffer
+		writeVarint(&b, n)
+		v, err := readVarint(&b)
+		require.NoError(t, err)
+		assert.Equal(t, n, v)
+	}
+}
@@ -1,11 +1,14 @@
 package mqtt
 
 import (
+	"net"
 	"testing"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/serializers"
 	"github.com/influxdata/telegraf/testutil"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -29,3 +32,46 @@ func TestConnectAndWrite(t *testing.T) {
 	err = m.Write(testutil.MockMetrics())
 	require.NoError(t, err)
 }
+
+func TestWriteBatch(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	messages := make(chan message, 10)
+	go serveMQTT5(t, l, 0, messages)
+
+	s, _ := serializers.NewInfluxSerializer()
+	m := &MQTT{
+		Servers:         []string{l.Addr().String()},
+		ProtocolVersion: "5",
+		Topic:           "telegraf/{{.Name}}",
+		Batch:           true,
+		serializer:      s,
+	}
+	require.NoError(t, m.Connect())
+	defer m.Close()
+
+	metrics := []telegraf.Metric{
+		testutil.TestMetric(1.0, "cpu"),
+		testutil.TestMetric(2.0, "mem"),
+		testutil.TestMetric(3.0, "cpu"),
+	}
+	require.NoError(t, m.Write(metrics))
+
+	msg := <-messages
+	assert.Equal(t, "telegraf/cpu", msg.topic)
+	assert.Equal(t, metrics[0].String()+metrics[2].String(), msg.payload)
+	msg = <-messages
+	assert.Equal(t, "telegraf/mem", msg.topic)
+	assert.Equal(t, metrics[1].String(), msg.payload)
+}
+
+func TestConnectErrors(t *testing.T) {
+	for _, m := range []*MQTT{
+		{Servers: []string{"localhost:1883"}, QoS: 3},
+		{Servers: []string{"localhost:1883"}, ProtocolVersion: "4"},
+		{Servers: []string{"localhost:1883"}, Topic: "{{.Name"},
+	} {
+		assert.Error(t, m.Connect())
+	}
+}
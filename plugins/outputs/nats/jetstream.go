@@ -0,0 +1,178 @@
+package nats
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	nats_client "github.com/nats-io/nats"
+
+	"github.com/influxdata/telegraf/internal"
+)
+
+// Stream is the configuration of a JetStream stream.
+type Stream struct {
+	Name      string            `toml:"name"`
+	Subjects  []string          `toml:"subjects"`
+	Storage   string            `toml:"storage"`
+	Retention string            `toml:"retention"`
+	MaxAge    internal.Duration `toml:"max_age"`
+	MaxBytes  int64             `toml:"max_bytes"`
+	MaxMsgs   int64             `toml:"max_msgs"`
+	Replicas  int               `toml:"replicas"`
+}
+
+// streamConfig is the stream configuration of the JetStream API.
+type streamConfig struct {
+	Name      string   `json:"name"`
+	Subjects  []string `json:"subjects"`
+	Retention string   `json:"retention"`
+	Storage   string   `json:"storage"`
+	// MaxAge is in nanoseconds, unlimited if 0, the other limits being
+	// unlimited if -1.
+	MaxAge       int64 `json:"max_age"`
+	MaxBytes     int64 `json:"max_bytes"`
+	MaxMsgs      int64 `json:"max_msgs"`
+	MaxConsumers int   `json:"max_consumers"`
+	Replicas     int   `json:"num_replicas"`
+}
+
+// apiError is the error of the responses of the JetStream API and of the
+// publish acks.
+type apiError struct {
+	Code        int    `json:"code"`
+	Description string `json:"description"`
+}
+
+func (e *apiError) String() string {
+	return fmt.Sprintf("%s (%d)", e.Description, e.Code)
+}
+
+// pubAck is the ack of a message published to a stream.
+type pubAck struct {
+	Stream string    `json:"stream"`
+	Seq    uint64    `json:"seq"`
+	Error  *apiError `json:"error"`
+}
+
+func (s *Stream) config() *streamConfig {
+	c := &streamConfig{
+		Name:         s.Name,
+		Subjects:     s.Subjects,
+		Retention:    s.Retention,
+		Storage:      s.Storage,
+		MaxAge:       int64(s.MaxAge.Duration),
+		MaxBytes:     s.MaxBytes,
+		MaxMsgs:      s.MaxMsgs,
+		MaxConsumers: -1,
+		Replicas:     s.Replicas,
+	}
+	if c.Retention == "" {
+		c.Retention = "limits"
+	}
+	if c.Storage == "" {
+		c.Storage = "file"
+	}
+	if c.MaxBytes == 0 {
+		c.MaxBytes = -1
+	}
+	if c.MaxMsgs == 0 {
+		c.MaxMsgs = -1
+	}
+	if c.Replicas == 0 {
+		c.Replicas = 1
+	}
+	return c
+}
+
+// ensureStream creates the stream, or updates its configuration if it
+// exists.
+func (n *NATS) ensureStream(s *Stream) error {
+	if s.Name == "" || len(s.Subjects) == 0 {
+		return fmt.Errorf("the name and the subjects of the streams are required")
+	}
+
+	var resp struct {
+		Error *apiError `json:"error"`
+	}
+	if err := n.apiRequest("$JS.API.STREAM.INFO."+s.Name, nil, &resp); err != nil {
+		return err
+	}
+	action := "UPDATE"
+	if resp.Error != nil {
+		if resp.Error.Code != 404 {
+			return fmt.Errorf("unable to get stream %s: %s", s.Name, resp.Error)
+		}
+		action = "CREATE"
+	}
+
+	resp.Error = nil
+	if err := n.apiRequest("$JS.API.STREAM."+action+"."+s.Name, s.config(), &resp); err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("unable to %s stream %s: %s",
+			strings.ToLower(action), s.Name, resp.Error)
+	}
+	return nil
+}
+
+// apiRequest makes a request to the JetStream API.
+func (n *NATS) apiRequest(subject string, req, resp interface{}) error {
+	var data []byte
+	if req != nil {
+		var err error
+		data, err = json.Marshal(req)
+		if err != nil {
+			return err
+		}
+	}
+	msg, err := n.conn.Request(subject, data, n.AckTimeout.Duration)
+	if err != nil {
+		return fmt.Errorf("JetStream request %s failed: %s", subject, err)
+	}
+	if err := json.Unmarshal(msg.Data, resp); err != nil {
+		return fmt.Errorf("invalid response to JetStream request %s: %s", subject, err)
+	}
+	return nil
+}
+
+// publishJetStream publishes the messages, waiting for their acks. The
+// messages acknowledged before an error are published again with the next
+// write.
+func (n *NATS) publishJetStream(messages []message) error {
+	// The acks are the replies to the inbox of the write, the reply subject
+	// of each message being a token of this inbox.
+	inbox := nats_client.NewInbox()
+	sub, err := n.conn.SubscribeSync(inbox + ".*")
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	for i, msg := range messages {
+		reply := fmt.Sprintf("%s.%d", inbox, i)
+		if err := n.conn.PublishRequest(msg.subject, reply, msg.data); err != nil {
+			return fmt.Errorf("FAILED to send NATS message: %s", err)
+		}
+	}
+
+	deadline := time.Now().Add(n.AckTimeout.Duration)
+	var failed error
+	for pending := len(messages); pending > 0; pending-- {
+		ackMsg, err := sub.NextMsg(time.Until(deadline))
+		if err != nil {
+			return fmt.Errorf("%d of %d NATS messages not acknowledged: %s",
+				pending, len(messages), err)
+		}
+		var ack pubAck
+		if err := json.Unmarshal(ackMsg.Data, &ack); err != nil {
+			return fmt.Errorf("invalid JetStream ack: %s", err)
+		}
+		if ack.Error != nil && failed == nil {
+			failed = fmt.Errorf("NATS message not stored: %s", ack.Error)
+		}
+	}
+	return failed
+}
@@ -1,7 +1,11 @@
 package nats
 
 import (
+	"bytes"
 	"fmt"
+	"strings"
+	"text/template"
+	"time"
 
 	nats_client "github.com/nats-io/nats"
 
@@ -17,9 +21,17 @@ type NATS struct {
 	// Credentials
 	Username string
 	Password string
-	// NATS subject to publish metrics to
+	// NATS subject to publish metrics to, a template rendered with each
+	// metric.
 	Subject string
 
+	// JetStream publishes the metrics to the streams of their subject,
+	// waiting for the acks of the streams, Streams being created or updated
+	// when connecting.
+	JetStream  bool              `toml:"jetstream"`
+	AckTimeout internal.Duration `toml:"ack_timeout"`
+	Streams    []*Stream         `toml:"streams"`
+
 	// Path to CA file
 	SSLCA string `toml:"ssl_ca"`
 	// Path to host cert file
@@ -30,6 +42,7 @@ type NATS struct {
 	InsecureSkipVerify bool
 
 	conn       *nats_client.Conn
+	subject    *template.Template
 	serializer serializers.Serializer
 }
 
@@ -39,8 +52,32 @@ var sampleConfig = `
   ## Optional credentials
   # username = ""
   # password = ""
-  ## NATS subject for producer messages
+  ## NATS subject for producer messages. The subject is a Go template
+  ## rendered with each metric: {{.Name}} is the name of the metric and
+  ## {{.Tag "host"}} the value of its host tag, the dots, spaces and
+  ## wildcards of the names and values being replaced by "_".
   subject = "telegraf"
+  # subject = 'telegraf.{{.Name}}.{{.Tag "host"}}'
+
+  ## Publish to JetStream, a write failing unless the messages are
+  ## acknowledged by the streams of their subject within ack_timeout.
+  # jetstream = false
+  # ack_timeout = "5s"
+
+  ## Streams created, or updated, when connecting to JetStream. The default
+  ## values are those of the NATS server.
+  # [[outputs.nats.streams]]
+  #   name = "telegraf"
+  #   subjects = ["telegraf.>"]
+  #   ## Storage, "file" or "memory".
+  #   storage = "file"
+  #   ## Retention policy, "limits", "interest" or "workqueue".
+  #   retention = "limits"
+  #   ## Limits of the stream, unlimited if 0.
+  #   max_age = "168h"
+  #   max_bytes = 0
+  #   max_msgs = 0
+  #   replicas = 1
 
   ## Optional SSL Config
   # ssl_ca = "/etc/telegraf/ca.pem"
@@ -63,6 +100,11 @@ func (n *NATS) SetSerializer(serializer serializers.Serializer) {
 func (n *NATS) Connect() error {
 	var err error
 
+	n.subject, err = parseSubject(n.Subject)
+	if err != nil {
+		return err
+	}
+
 	// set default NATS connection options
 	opts := nats_client.DefaultOptions
 
@@ -92,8 +134,19 @@ func (n *NATS) Connect() error {
 
 	// try and connect
 	n.conn, err = opts.Connect()
+	if err != nil {
+		return err
+	}
 
-	return err
+	if n.JetStream {
+		for _, s := range n.Streams {
+			if err := n.ensureStream(s); err != nil {
+				n.conn.Close()
+				return err
+			}
+		}
+	}
+	return nil
 }
 
 func (n *NATS) Close() error {
@@ -114,13 +167,24 @@ func (n *NATS) Write(metrics []telegraf.Metric) error {
 		return nil
 	}
 
+	messages := make([]message, 0, len(metrics))
 	for _, metric := range metrics {
+		subject, err := n.renderSubject(metric)
+		if err != nil {
+			return fmt.Errorf("unable to render the subject of metric %s: %s", metric.Name(), err)
+		}
 		buf, err := n.serializer.Serialize(metric)
 		if err != nil {
 			return err
 		}
+		messages = append(messages, message{subject: subject, data: buf})
+	}
 
-		err = n.conn.Publish(n.Subject, buf)
+	if n.JetStream {
+		return n.publishJetStream(messages)
+	}
+	for _, msg := range messages {
+		err := n.conn.Publish(msg.subject, msg.data)
 		if err != nil {
 			return fmt.Errorf("FAILED to send NATS message: %s", err)
 		}
@@ -128,8 +192,55 @@ func (n *NATS) Write(metrics []telegraf.Metric) error {
 	return nil
 }
 
+// message is a message to publish.
+type message struct {
+	subject string
+	data    []byte
+}
+
+func parseSubject(subject string) (*template.Template, error) {
+	t, err := template.New("subject").Option("missingkey=zero").Parse(subject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subject template: %s", err)
+	}
+	return t, nil
+}
+
+func (n *NATS) renderSubject(m telegraf.Metric) (string, error) {
+	var b bytes.Buffer
+	if err := n.subject.Execute(&b, subjectMetric{m}); err != nil {
+		return "", err
+	}
+	if b.Len() == 0 {
+		return "", fmt.Errorf("empty subject")
+	}
+	return b.String(), nil
+}
+
+// subjectTokenReplacer replaces the characters which are not allowed in the
+// tokens of the subjects.
+var subjectTokenReplacer = strings.NewReplacer(
+	".", "_", "*", "_", ">", "_", " ", "_", "\t", "_", "\r", "_", "\n", "_")
+
+// subjectMetric is the data of the subject templates.
+type subjectMetric struct {
+	metric telegraf.Metric
+}
+
+// Name returns the name of the metric.
+func (m subjectMetric) Name() string {
+	return subjectTokenReplacer.Replace(m.metric.Name())
+}
+
+// Tag returns the value of the tag, empty if the metric does not have it.
+func (m subjectMetric) Tag(key string) string {
+	return subjectTokenReplacer.Replace(m.metric.Tags()[key])
+}
+
 func init() {
 	outputs.Add("nats", func() telegraf.Output {
-		return &NATS{}
+		return &NATS{
+			AckTimeout: internal.Duration{Duration: 5 * time.Second},
+		}
 	})
 }
@@ -2,7 +2,9 @@ package nats
 
 import (
 	"testing"
+	"time"
 
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/serializers"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/require"
@@ -29,3 +31,59 @@ func TestConnectAndWrite(t *testing.T) {
 	err = n.Write(testutil.MockMetrics())
 	require.NoError(t, err)
 }
+
+func TestSubject(t *testing.T) {
+	n := &NATS{}
+	var err error
+	n.subject, err = parseSubject(`telegraf.{{.Name}}.{{.Tag "host"}}`)
+	require.NoError(t, err)
+
+	m := testutil.TestMetric(1.0, "cpu.usage")
+	m.AddTag("host", "server 1")
+	subject, err := n.renderSubject(m)
+	require.NoError(t, err)
+	require.Equal(t, "telegraf.cpu_usage.server_1", subject)
+
+	n.subject, err = parseSubject(`{{.Tag "missing"}}`)
+	require.NoError(t, err)
+	_, err = n.renderSubject(m)
+	require.Error(t, err)
+}
+
+func TestConnectAndWriteJetStream(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	server := []string{"nats://" + testutil.GetLocalHost() + ":4222"}
+	s, _ := serializers.NewInfluxSerializer()
+	n := &NATS{
+		Servers:    server,
+		Subject:    `telegraf.{{.Name}}`,
+		JetStream:  true,
+		AckTimeout: internal.Duration{Duration: 5 * time.Second},
+		Streams: []*Stream{
+			{Name: "telegraf", Subjects: []string{"telegraf.>"}, Storage: "memory"},
+		},
+		serializer: s,
+	}
+
+	// Verify that the stream is created, and updated when reconnecting
+	err := n.Connect()
+	require.NoError(t, err)
+	n.Close()
+	err = n.Connect()
+	require.NoError(t, err)
+	defer n.Close()
+
+	// Verify that the messages are acknowledged by the stream
+	err = n.Write(testutil.MockMetrics())
+	require.NoError(t, err)
+
+	// Verify that the messages of the subjects without stream are not
+	n.Subject = "not-stored"
+	n.subject, _ = parseSubject(n.Subject)
+	n.AckTimeout.Duration = 100 * time.Millisecond
+	err = n.Write(testutil.MockMetrics())
+	require.Error(t, err)
+}
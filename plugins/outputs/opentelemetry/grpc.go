@@ -0,0 +1,108 @@
+package opentelemetry
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportMethod is the path of the Export call of the OTLP metrics service.
+const exportMethod = "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export"
+
+// The gRPC status codes the OTLP exporters retry.
+var retryableCodes = map[int]string{
+	1:  "CANCELLED",
+	4:  "DEADLINE_EXCEEDED",
+	8:  "RESOURCE_EXHAUSTED",
+	10: "ABORTED",
+	11: "OUT_OF_RANGE",
+	14: "UNAVAILABLE",
+	15: "DATA_LOSS",
+}
+
+// grpcExport makes the Export call of the request, sent as a gRPC unary
+// call over HTTP/2, returning the ExportMetricsServiceResponse.
+func (o *OpenTelemetry) grpcExport(request []byte) ([]byte, error) {
+	// A gRPC message is prefixed by its compression flag and its length.
+	body := make([]byte, 5, 5+len(request))
+	if o.Compression == "gzip" {
+		body[0] = 1
+	}
+	binary.BigEndian.PutUint32(body[1:], uint32(len(request)))
+	body = append(body, request...)
+
+	req, err := http.NewRequest("POST", strings.TrimRight(o.Endpoint, "/")+exportMethod,
+		bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	req.Header.Set("User-Agent", "Telegraf")
+	if o.Compression == "gzip" {
+		req.Header.Set("Grpc-Encoding", "gzip")
+	}
+	if o.Timeout.Duration > 0 {
+		req.Header.Set("Grpc-Timeout", fmt.Sprintf("%dm", o.Timeout.Duration/time.Millisecond))
+	}
+	for k, v := range o.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, &retryableError{err: err}
+	}
+	defer resp.Body.Close()
+	// The status is in the trailers, read with the body.
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &retryableError{err: err}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		// An error of a proxy, mapped to the gRPC status codes.
+		err := fmt.Errorf("export failed with status code %d (%s)",
+			resp.StatusCode, http.StatusText(resp.StatusCode))
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return nil, &retryableError{err: err}
+		}
+		return nil, err
+	}
+
+	status := resp.Trailer.Get("Grpc-Status")
+	message := resp.Trailer.Get("Grpc-Message")
+	if status == "" {
+		// A Trailers-Only response.
+		status = resp.Header.Get("Grpc-Status")
+		message = resp.Header.Get("Grpc-Message")
+	}
+	code, err := strconv.Atoi(status)
+	if err != nil {
+		return nil, fmt.Errorf("export failed with invalid grpc-status %q", status)
+	}
+	if code != 0 {
+		if m, err := url.PathUnescape(message); err == nil {
+			message = m
+		}
+		if name, ok := retryableCodes[code]; ok {
+			return nil, &retryableError{err: fmt.Errorf(
+				"export failed with grpc-status %d (%s): %s", code, name, message)}
+		}
+		return nil, fmt.Errorf("export failed with grpc-status %d: %s", code, message)
+	}
+
+	if len(b) < 5 || b[0] != 0 || int(binary.BigEndian.Uint32(b[1:5])) != len(b)-5 {
+		// The response is not needed, it is only read for partial successes.
+		return nil, nil
+	}
+	return b[5:], nil
+}
@@ -0,0 +1,441 @@
+package opentelemetry
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http2"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	otlp "github.com/influxdata/telegraf/plugins/serializers/opentelemetry"
+)
+
+type OpenTelemetry struct {
+	// Protocol is "grpc" or "http", the OTLP/HTTP requests being encoded as
+	// protobuf or JSON.
+	Protocol    string
+	Endpoint    string
+	Encoding    string
+	Compression string
+	Headers     map[string]string
+	Timeout     internal.Duration
+
+	// The ResourceTags and, with GlobalTagsAsResource, the global tags are
+	// the attributes of the resources, renamed by ResourceAttributeNames.
+	ResourceTags           []string
+	GlobalTagsAsResource   bool `toml:"global_tags_as_resource"`
+	ResourceAttributeNames map[string]string
+
+	// The failed exports are retried as by the OTLP exporters, waiting from
+	// InitialInterval to MaxInterval, until MaxElapsedTime has been waited.
+	InitialInterval internal.Duration
+	MaxInterval     internal.Duration
+	MaxElapsedTime  internal.Duration
+
+	// Path to CA file
+	SSLCA string `toml:"ssl_ca"`
+	// Path to host cert file
+	SSLCert string `toml:"ssl_cert"`
+	// Path to cert key file
+	SSLKey string `toml:"ssl_key"`
+	// Use SSL but skip chain & host verification
+	InsecureSkipVerify bool
+
+	client     *http.Client
+	globalTags map[string]string
+	serializer *otlp.OpenTelemetrySerializer
+	// sleep waits between the retries, replaced by the tests.
+	sleep func(time.Duration)
+}
+
+var sampleConfig = `
+  ## OTLP protocol, "grpc" or "http".
+  # protocol = "grpc"
+
+  ## Endpoint of the OTLP receiver, defaults to "http://localhost:4317" with
+  ## grpc and to "http://localhost:4318/v1/metrics" with http. With grpc, the
+  ## "http" scheme sends the calls without TLS.
+  # endpoint = "http://localhost:4317"
+
+  ## Encoding of the OTLP/HTTP requests, "protobuf" or "json".
+  # encoding = "protobuf"
+
+  ## Compression of the requests, "gzip" or "none".
+  # compression = "gzip"
+
+  ## Additional HTTP headers, or gRPC metadata, of the requests.
+  # [outputs.opentelemetry.headers]
+  #   Authorization = "Bearer my-token"
+
+  ## Timeout of the requests.
+  # timeout = "10s"
+
+  ## Tags which are the attributes of the resources instead of the data
+  ## points. With global_tags_as_resource, the global tags, including host,
+  ## are also resource attributes.
+  # resource_tags = []
+  # global_tags_as_resource = true
+
+  ## Names of the resource attributes of the tags, ie their semantic
+  ## conventions.
+  # [outputs.opentelemetry.resource_attribute_names]
+  #   host = "host.name"
+
+  ## The exports failing with a retryable error are retried, waiting from
+  ## initial_interval to max_interval, with a jitter, or the delay requested
+  ## by the receiver, between the retries. Once max_elapsed_time has been
+  ## waited, the write fails and the metrics are kept in the buffer of the
+  ## output. The exports failing with another error are not retried, their
+  ## metrics being dropped.
+  # initial_interval = "5s"
+  # max_interval = "30s"
+  # max_elapsed_time = "1m"
+
+  ## Optional SSL Config
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  ## Use SSL but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+func (o *OpenTelemetry) SetGlobalTags(tags map[string]string) {
+	o.globalTags = tags
+}
+
+func (o *OpenTelemetry) Connect() error {
+	switch o.Protocol {
+	case "grpc":
+		if o.Endpoint == "" {
+			o.Endpoint = "http://localhost:4317"
+		}
+		o.Encoding = "protobuf"
+	case "http":
+		if o.Endpoint == "" {
+			o.Endpoint = "http://localhost:4318/v1/metrics"
+		}
+		if o.Encoding == "" {
+			o.Encoding = "protobuf"
+		}
+		if o.Encoding != "protobuf" && o.Encoding != "json" {
+			return fmt.Errorf("invalid encoding %q", o.Encoding)
+		}
+	default:
+		return fmt.Errorf("invalid protocol %q", o.Protocol)
+	}
+	if o.Compression == "" {
+		o.Compression = "none"
+	}
+	if o.Compression != "gzip" && o.Compression != "none" {
+		return fmt.Errorf("invalid compression %q", o.Compression)
+	}
+	u, err := url.Parse(o.Endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint %q: %s", o.Endpoint, err)
+	}
+
+	tlsConfig, err := internal.GetTLSConfig(
+		o.SSLCert, o.SSLKey, o.SSLCA, o.InsecureSkipVerify)
+	if err != nil {
+		return err
+	}
+	var transport http.RoundTripper
+	switch {
+	case o.Protocol == "http":
+		transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		}
+	case u.Scheme == "http":
+		// HTTP/2 with prior knowledge.
+		timeout := o.Timeout.Duration
+		transport = &http2.Transport{
+			AllowHTTP: true,
+			DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+				return net.DialTimeout(network, addr, timeout)
+			},
+		}
+	default:
+		transport = &http2.Transport{TLSClientConfig: tlsConfig}
+	}
+	o.client = &http.Client{
+		Timeout:   o.Timeout.Duration,
+		Transport: transport,
+	}
+
+	resourceTags := o.ResourceTags
+	if o.GlobalTagsAsResource {
+		for k := range o.globalTags {
+			resourceTags = append(resourceTags, k)
+		}
+	}
+	o.serializer = &otlp.OpenTelemetrySerializer{
+		Encoding:     o.Encoding,
+		ResourceTags: resourceTags,
+	}
+	if o.sleep == nil {
+		o.sleep = time.Sleep
+	}
+	return nil
+}
+
+func (o *OpenTelemetry) Close() error {
+	return nil
+}
+
+func (o *OpenTelemetry) SampleConfig() string {
+	return sampleConfig
+}
+
+func (o *OpenTelemetry) Description() string {
+	return "Send metrics to an OpenTelemetry receiver over OTLP"
+}
+
+func (o *OpenTelemetry) Write(metrics []telegraf.Metric) error {
+	request := o.serializer.Request(metrics)
+	if len(request.ResourceMetrics) == 0 {
+		return nil
+	}
+	for _, rm := range request.ResourceMetrics {
+		for i, kv := range rm.Resource.Attributes {
+			if name, ok := o.ResourceAttributeNames[kv.Key]; ok {
+				rm.Resource.Attributes[i].Key = name
+			}
+		}
+	}
+	body, err := o.serializer.Marshal(request)
+	if err != nil {
+		return err
+	}
+	if o.Compression == "gzip" {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		w.Write(body)
+		if err := w.Close(); err != nil {
+			return err
+		}
+		body = buf.Bytes()
+	}
+
+	var waited time.Duration
+	interval := o.InitialInterval.Duration
+	for {
+		err := o.export(body)
+		if err == nil {
+			return nil
+		}
+		re, ok := err.(*retryableError)
+		if !ok {
+			// Exporting the metrics again would fail again.
+			log.Printf("E! OpenTelemetry: %s, dropping %d metrics", err, len(metrics))
+			return nil
+		}
+
+		// The interval is randomized by ±50%, as by the OTLP exporters.
+		wait := interval/2 + time.Duration(rand.Int63n(int64(interval)+1))
+		if re.retryAfter > 0 {
+			wait = re.retryAfter
+		}
+		if waited+wait > o.MaxElapsedTime.Duration {
+			return err
+		}
+		log.Printf("D! OpenTelemetry: %s, retrying in %s", err, wait)
+		o.sleep(wait)
+		waited += wait
+		interval = interval * 3 / 2
+		if interval > o.MaxInterval.Duration {
+			interval = o.MaxInterval.Duration
+		}
+	}
+}
+
+// retryableError is the error of the exports that can be retried, after
+// retryAfter if requested by the receiver.
+type retryableError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (o *OpenTelemetry) export(body []byte) error {
+	var response []byte
+	var err error
+	if o.Protocol == "grpc" {
+		response, err = o.grpcExport(body)
+	} else {
+		response, err = o.httpExport(body)
+	}
+	if err != nil {
+		return err
+	}
+
+	// The data points rejected by the receiver are dropped.
+	rejected, message, err := o.partialSuccess(response)
+	if err != nil {
+		log.Printf("D! OpenTelemetry: unable to parse export response: %s", err)
+	} else if rejected > 0 || message != "" {
+		log.Printf("W! OpenTelemetry: %d data points rejected: %s", rejected, message)
+	}
+	return nil
+}
+
+// httpExport sends the OTLP/HTTP request, returning the response body.
+func (o *OpenTelemetry) httpExport(body []byte) ([]byte, error) {
+	req, err := http.NewRequest("POST", o.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if o.Encoding == "json" {
+		req.Header.Set("Content-Type", "application/json")
+	} else {
+		req.Header.Set("Content-Type", "application/x-protobuf")
+	}
+	if o.Compression == "gzip" {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	req.Header.Set("User-Agent", "Telegraf")
+	for k, v := range o.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, &retryableError{err: err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return ioutil.ReadAll(resp.Body)
+	}
+
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("export to %s failed with status code %d: %s",
+		o.Endpoint, resp.StatusCode, strings.TrimSpace(string(msg)))
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		re := &retryableError{err: err}
+		if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s > 0 {
+			re.retryAfter = time.Duration(s) * time.Second
+		}
+		return nil, re
+	}
+	return nil, err
+}
+
+// partialSuccess returns the rejected data points and the error message of
+// the ExportMetricsServiceResponse.
+func (o *OpenTelemetry) partialSuccess(response []byte) (int64, string, error) {
+	if len(response) == 0 {
+		return 0, "", nil
+	}
+	if o.Encoding == "json" {
+		var r struct {
+			PartialSuccess struct {
+				// An int64, encoded as a string or a number.
+				RejectedDataPoints json.RawMessage `json:"rejectedDataPoints"`
+				ErrorMessage       string          `json:"errorMessage"`
+			} `json:"partialSuccess"`
+		}
+		if err := json.Unmarshal(response, &r); err != nil {
+			return 0, "", err
+		}
+		var rejected int64
+		if raw := strings.Trim(string(r.PartialSuccess.RejectedDataPoints), `"`); raw != "" {
+			var err error
+			if rejected, err = strconv.ParseInt(raw, 10, 64); err != nil {
+				return 0, "", err
+			}
+		}
+		return rejected, r.PartialSuccess.ErrorMessage, nil
+	}
+
+	partial, err := field(response, 1)
+	if err != nil || partial == nil {
+		return 0, "", err
+	}
+	var rejected int64
+	var message string
+	for len(partial) > 0 {
+		key, n := binary.Uvarint(partial)
+		if n <= 0 {
+			return 0, "", fmt.Errorf("invalid partial success")
+		}
+		partial = partial[n:]
+		switch {
+		case key == 1<<3: // rejected_data_points, a varint
+			v, n := binary.Uvarint(partial)
+			if n <= 0 {
+				return 0, "", fmt.Errorf("invalid rejected data points")
+			}
+			rejected = int64(v)
+			partial = partial[n:]
+		case key&7 == 2: // error_message or an unknown field
+			l, n := binary.Uvarint(partial)
+			if n <= 0 || uint64(len(partial)-n) < l {
+				return 0, "", fmt.Errorf("invalid partial success")
+			}
+			if key>>3 == 2 {
+				message = string(partial[n : n+int(l)])
+			}
+			partial = partial[n+int(l):]
+		default:
+			return 0, "", fmt.Errorf("unexpected field %d of partial success", key>>3)
+		}
+	}
+	return rejected, message, nil
+}
+
+// field returns the embedded message of the field of a message, the other
+// fields being length-delimited.
+func field(buf []byte, number uint64) ([]byte, error) {
+	var value []byte
+	for len(buf) > 0 {
+		key, n := binary.Uvarint(buf)
+		if n <= 0 || key&7 != 2 {
+			return nil, fmt.Errorf("invalid message")
+		}
+		buf = buf[n:]
+		l, n := binary.Uvarint(buf)
+		if n <= 0 || uint64(len(buf)-n) < l {
+			return nil, fmt.Errorf("invalid message")
+		}
+		if key>>3 == number {
+			value = buf[n : n+int(l)]
+		}
+		buf = buf[n+int(l):]
+	}
+	return value, nil
+}
+
+func init() {
+	outputs.Add("opentelemetry", func() telegraf.Output {
+		return &OpenTelemetry{
+			Protocol:             "grpc",
+			Compression:          "gzip",
+			Timeout:              internal.Duration{Duration: 10 * time.Second},
+			GlobalTagsAsResource: true,
+			InitialInterval:      internal.Duration{Duration: 5 * time.Second},
+			MaxInterval:          internal.Duration{Duration: 30 * time.Second},
+			MaxElapsedTime:       internal.Duration{Duration: time.Minute},
+		}
+	})
+}
@@ -0,0 +1,241 @@
+package opentelemetry
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	otlp "github.com/influxdata/telegraf/plugins/serializers/opentelemetry"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testMetrics(t *testing.T) []telegraf.Metric {
+	ts := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	cpu, err := metric.New("cpu",
+		map[string]string{"host": "server-1", "dc": "eu", "cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": 91.5},
+		ts)
+	require.NoError(t, err)
+	mem, err := metric.New("mem",
+		map[string]string{"host": "server-1", "dc": "eu"},
+		map[string]interface{}{"used": int64(42)},
+		ts)
+	require.NoError(t, err)
+	return []telegraf.Metric{cpu, mem}
+}
+
+// expected returns the request of the metrics, the host and dc tags being
+// the attributes of the resource.
+func expected(t *testing.T) *otlp.ExportMetricsServiceRequest {
+	s := &otlp.OpenTelemetrySerializer{ResourceTags: []string{"dc", "host"}}
+	r := s.Request(testMetrics(t))
+	r.ResourceMetrics[0].Resource.Attributes[1].Key = "host.name"
+	return r
+}
+
+// serveH2C serves the handler over HTTP/2 without TLS.
+func serveH2C(t *testing.T, handler http.Handler) (string, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go (&http2.Server{}).ServeConn(conn, &http2.ServeConnOpts{Handler: handler})
+		}
+	}()
+	return "http://" + l.Addr().String(), func() { l.Close() }
+}
+
+func newOutput(protocol, endpoint string) *OpenTelemetry {
+	o := &OpenTelemetry{
+		Protocol:               protocol,
+		Endpoint:               endpoint,
+		Timeout:                internal.Duration{Duration: 5 * time.Second},
+		ResourceTags:           []string{"dc"},
+		GlobalTagsAsResource:   true,
+		ResourceAttributeNames: map[string]string{"host": "host.name"},
+		InitialInterval:        internal.Duration{Duration: time.Second},
+		MaxInterval:            internal.Duration{Duration: 2 * time.Second},
+		MaxElapsedTime:         internal.Duration{Duration: 10 * time.Second},
+		sleep:                  func(time.Duration) {},
+	}
+	o.SetGlobalTags(map[string]string{"host": "server-1"})
+	return o
+}
+
+func TestGRPC(t *testing.T) {
+	var body []byte
+	endpoint, stop := serveH2C(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, exportMethod, r.URL.Path)
+		assert.Equal(t, "application/grpc", r.Header.Get("Content-Type"))
+		assert.Equal(t, "gzip", r.Header.Get("Grpc-Encoding"))
+		assert.Equal(t, "5000m", r.Header.Get("Grpc-Timeout"))
+		assert.Equal(t, "secret", r.Header.Get("Api-Key"))
+
+		b, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.True(t, len(b) > 5)
+		assert.Equal(t, byte(1), b[0])
+		assert.Equal(t, len(b)-5, int(binary.BigEndian.Uint32(b[1:5])))
+		zr, err := gzip.NewReader(bytes.NewReader(b[5:]))
+		require.NoError(t, err)
+		body, err = ioutil.ReadAll(zr)
+		require.NoError(t, err)
+
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+		w.Write([]byte{0, 0, 0, 0, 0})
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	defer stop()
+
+	o := newOutput("grpc", endpoint)
+	o.Compression = "gzip"
+	o.Headers = map[string]string{"api-key": "secret"}
+	require.NoError(t, o.Connect())
+	require.NoError(t, o.Write(testMetrics(t)))
+	assert.Equal(t, expected(t).Marshal(), body)
+}
+
+func TestGRPCRetry(t *testing.T) {
+	var calls int
+	endpoint, stop := serveH2C(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/grpc")
+		if calls == 1 {
+			// A Trailers-Only response.
+			w.Header().Set("Grpc-Status", "14")
+			w.Header().Set("Grpc-Message", "try%20later")
+			return
+		}
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.Write([]byte{0, 0, 0, 0, 0})
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	defer stop()
+
+	var waits []time.Duration
+	o := newOutput("grpc", endpoint)
+	o.sleep = func(d time.Duration) { waits = append(waits, d) }
+	require.NoError(t, o.Connect())
+	require.NoError(t, o.Write(testMetrics(t)))
+	assert.Equal(t, 2, calls)
+	require.Len(t, waits, 1)
+	assert.True(t, waits[0] >= 500*time.Millisecond && waits[0] <= 1500*time.Millisecond)
+}
+
+func TestGRPCDropped(t *testing.T) {
+	var calls int
+	endpoint, stop := serveH2C(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Grpc-Status", "3")
+		w.Header().Set("Grpc-Message", "invalid metric")
+	}))
+	defer stop()
+
+	o := newOutput("grpc", endpoint)
+	require.NoError(t, o.Connect())
+	require.NoError(t, o.Write(testMetrics(t)))
+	assert.Equal(t, 1, calls)
+}
+
+func TestHTTP(t *testing.T) {
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/metrics", r.URL.Path)
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		assert.Equal(t, "", r.Header.Get("Content-Encoding"))
+		var err error
+		body, err = ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer ts.Close()
+
+	o := newOutput("http", ts.URL+"/v1/metrics")
+	require.NoError(t, o.Connect())
+	require.NoError(t, o.Write(testMetrics(t)))
+	assert.Equal(t, expected(t).Marshal(), body)
+}
+
+func TestHTTPRetry(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		w.Header().Set("Retry-After", "3")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	var waits []time.Duration
+	o := newOutput("http", ts.URL)
+	o.Encoding = "json"
+	o.sleep = func(d time.Duration) { waits = append(waits, d) }
+	require.NoError(t, o.Connect())
+	// The retries stop once 10s have been waited.
+	require.Error(t, o.Write(testMetrics(t)))
+	assert.Equal(t, 4, calls)
+	assert.Equal(t, []time.Duration{3 * time.Second, 3 * time.Second, 3 * time.Second}, waits)
+}
+
+func TestHTTPDropped(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	o := newOutput("http", ts.URL)
+	require.NoError(t, o.Connect())
+	require.NoError(t, o.Write(testMetrics(t)))
+	assert.Equal(t, 1, calls)
+}
+
+func TestPartialSuccess(t *testing.T) {
+	o := &OpenTelemetry{Encoding: "protobuf"}
+	// partial_success{rejected_data_points: 2, error_message: "bad"}
+	partial := []byte{1 << 3, 2, 2<<3 | 2, 3, 'b', 'a', 'd'}
+	response := append([]byte{1<<3 | 2, byte(len(partial))}, partial...)
+	rejected, message, err := o.partialSuccess(response)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), rejected)
+	assert.Equal(t, "bad", message)
+
+	o.Encoding = "json"
+	rejected, message, err = o.partialSuccess(
+		[]byte(`{"partialSuccess":{"rejectedDataPoints":"3","errorMessage":"bad"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), rejected)
+	assert.Equal(t, "bad", message)
+}
+
+func TestConnectErrors(t *testing.T) {
+	o := newOutput("udp", "")
+	assert.Error(t, o.Connect())
+	o = newOutput("http", "")
+	o.Encoding = "xml"
+	assert.Error(t, o.Connect())
+	o = newOutput("grpc", "")
+	o.Compression = "zstd"
+	assert.Error(t, o.Connect())
+}
@@ -0,0 +1,112 @@
+package postgresql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testMetrics(t *testing.T) []telegraf.Metric {
+	now := time.Unix(1500000000, 0).UTC()
+	m1, err := metric.New("cpu", map[string]string{"host": "a"},
+		map[string]interface{}{"usage": 1.5, "count": int64(2)}, now)
+	require.NoError(t, err)
+	m2, err := metric.New("cpu", map[string]string{"host": "b", "cpu": "cpu0"},
+		map[string]interface{}{"usage": 2.5, "ok": true}, now)
+	require.NoError(t, err)
+	m3, err := metric.New("mem", map[string]string{"host": "a"},
+		map[string]interface{}{"free": uint64(10), "state": "ok"}, now)
+	require.NoError(t, err)
+	return []telegraf.Metric{m1, m2, m3}
+}
+
+func TestBatches(t *testing.T) {
+	p := &Postgresql{}
+	metrics := testMetrics(t)
+	batches := p.batches(metrics)
+	require.Len(t, batches, 2)
+
+	cpu := batches[0]
+	assert.Equal(t, "cpu", cpu.table)
+	assert.Equal(t, []column{
+		{"time", typeTimestamp},
+		{"host", typeText},
+		{"count", typeBigint},
+		{"usage", typeDouble},
+		{"cpu", typeText},
+		{"ok", typeBoolean},
+	}, cpu.columns)
+	now := metrics[0].Time()
+	assert.Equal(t, [][]interface{}{
+		{now, "a", int64(2), 1.5, nil, nil},
+		{now, "b", nil, 2.5, "cpu0", true},
+	}, cpu.rows)
+
+	mem := batches[1]
+	assert.Equal(t, [][]interface{}{{now, "a", int64(10), "ok"}}, mem.rows)
+}
+
+func TestBatchesWide(t *testing.T) {
+	p := &Postgresql{Layout: "wide", WideTable: "metrics"}
+	batches := p.batches(testMetrics(t))
+	require.Len(t, batches, 1)
+	b := batches[0]
+	assert.Equal(t, "metrics", b.table)
+	assert.Equal(t, column{"measurement", typeText}, b.columns[1])
+	require.Len(t, b.rows, 3)
+	assert.Equal(t, "mem", b.rows[2][1])
+	for _, row := range b.rows {
+		assert.Len(t, row, len(b.columns))
+	}
+}
+
+func TestCheckColumns(t *testing.T) {
+	p := &Postgresql{}
+	b := p.batches(testMetrics(t))[0]
+	missing := b.checkColumns(map[string]string{
+		"time":  typeTimestamp,
+		"host":  typeText,
+		"usage": typeDouble,
+		"count": typeText,
+	})
+	assert.Equal(t, []column{{"cpu", typeText}, {"ok", typeBoolean}}, missing)
+	// The values of the columns of another type are dropped.
+	assert.Nil(t, b.rows[0][b.index["count"]])
+}
+
+func TestSQL(t *testing.T) {
+	columns := []column{{"time", typeTimestamp}, {`a"b`, typeText}}
+	assert.Equal(t, `CREATE TABLE IF NOT EXISTS "public"."cpu" ("time" timestamptz, "a""b" text)`,
+		createTableSQL("public", "cpu", columns))
+	assert.Equal(t, `ALTER TABLE "cpu" ADD COLUMN IF NOT EXISTS "time" timestamptz, ADD COLUMN IF NOT EXISTS "a""b" text`,
+		addColumnsSQL("", "cpu", columns))
+}
+
+func TestConnectAndWrite(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	p := &Postgresql{
+		Connection: "host=" + testutil.GetLocalHost() + " user=postgres sslmode=disable",
+		Schema:     "public",
+	}
+	require.NoError(t, p.Connect())
+	defer p.Close()
+	p.conn.Exec(`DROP TABLE IF EXISTS "cpu", "mem"`)
+
+	metrics := testMetrics(t)
+	require.NoError(t, p.Write(metrics[:1]))
+	// The columns of the new tags and fields are added.
+	require.NoError(t, p.Write(metrics))
+
+	var count int
+	require.NoError(t, p.conn.QueryRow(`SELECT count(*) FROM "cpu" WHERE "ok"`).Scan(&count))
+	assert.Equal(t, 1, count)
+}
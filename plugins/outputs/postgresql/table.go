@@ -0,0 +1,219 @@
+package postgresql
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+// The column types of the values.
+const (
+	typeTimestamp = "timestamptz"
+	typeText      = "text"
+	typeBigint    = "bigint"
+	typeDouble    = "double precision"
+	typeBoolean   = "boolean"
+)
+
+const (
+	timeColumn        = "time"
+	measurementColumn = "measurement"
+)
+
+// column is a column of a table.
+type column struct {
+	name     string
+	dataType string
+}
+
+// batch is the rows of the metrics written to a table.
+type batch struct {
+	table string
+	// columns are the columns of the rows, in order, the index of each
+	// column being in index.
+	columns []column
+	index   map[string]int
+	rows    [][]interface{}
+}
+
+func newBatch(table string, wide bool) *batch {
+	b := &batch{table: table, index: make(map[string]int)}
+	b.column(timeColumn, typeTimestamp)
+	if wide {
+		b.column(measurementColumn, typeText)
+	}
+	return b
+}
+
+// column returns the index of the column, adding it if needed, or -1 if
+// the column exists with another type.
+func (b *batch) column(name, dataType string) int {
+	if i, ok := b.index[name]; ok {
+		if b.columns[i].dataType != dataType {
+			return -1
+		}
+		return i
+	}
+	b.index[name] = len(b.columns)
+	b.columns = append(b.columns, column{name: name, dataType: dataType})
+	for i := range b.rows {
+		b.rows[i] = append(b.rows[i], nil)
+	}
+	return len(b.columns) - 1
+}
+
+// add appends the row of the metric.
+func (b *batch) add(m telegraf.Metric, wide bool) {
+	tags := m.Tags()
+	fields := m.Fields()
+	row := make([]interface{}, len(b.columns), len(b.columns)+len(tags)+len(fields))
+	row[0] = m.Time()
+	if wide {
+		row[1] = m.Name()
+	}
+	set := func(name, dataType string, value interface{}) {
+		i := b.column(name, dataType)
+		if i == -1 {
+			log.Printf("D! PostgreSQL output: dropping value of column %s of table %s with type %s",
+				name, b.table, dataType)
+			return
+		}
+		for len(row) <= i {
+			row = append(row, nil)
+		}
+		row[i] = value
+	}
+
+	for _, k := range sortedKeys(tags) {
+		set(k, typeText, tags[k])
+	}
+	for _, k := range sortedFieldKeys(fields) {
+		if _, ok := tags[k]; ok {
+			log.Printf("D! PostgreSQL output: dropping field %s of %s, a tag has the same name", k, m.Name())
+			continue
+		}
+		value, dataType, ok := columnValue(fields[k])
+		if !ok {
+			continue
+		}
+		set(k, dataType, value)
+	}
+	b.rows = append(b.rows, row)
+}
+
+// columnValue returns the value and the column type of the field, false if
+// it can not be stored.
+func columnValue(v interface{}) (interface{}, string, bool) {
+	switch v := v.(type) {
+	case int64:
+		return v, typeBigint, true
+	case uint64:
+		if v > math.MaxInt64 {
+			return nil, "", false
+		}
+		return int64(v), typeBigint, true
+	case float64:
+		return v, typeDouble, true
+	case bool:
+		return v, typeBoolean, true
+	case string:
+		return v, typeText, true
+	}
+	return nil, "", false
+}
+
+// checkColumns removes the values of the columns whose type differs from the
+// type of the columns of the table, and returns the columns missing from the
+// table.
+func (b *batch) checkColumns(table map[string]string) []column {
+	var missing []column
+	for i, c := range b.columns {
+		dataType, ok := table[c.name]
+		if !ok {
+			missing = append(missing, c)
+			continue
+		}
+		if dataType == c.dataType {
+			continue
+		}
+		log.Printf("D! PostgreSQL output: dropping the values of column %s of table %s, of type %s instead of %s",
+			c.name, b.table, c.dataType, dataType)
+		for _, row := range b.rows {
+			row[i] = nil
+		}
+	}
+	return missing
+}
+
+// createTableSQL returns the statement creating the table with the columns.
+func createTableSQL(schema, table string, columns []column) string {
+	defs := make([]string, len(columns))
+	for i, c := range columns {
+		defs[i] = quoteIdent(c.name) + " " + c.dataType
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)",
+		qualifiedName(schema, table), strings.Join(defs, ", "))
+}
+
+// addColumnsSQL returns the statement adding the columns to the table.
+func addColumnsSQL(schema, table string, columns []column) string {
+	defs := make([]string, len(columns))
+	for i, c := range columns {
+		defs[i] = "ADD COLUMN IF NOT EXISTS " + quoteIdent(c.name) + " " + c.dataType
+	}
+	return fmt.Sprintf("ALTER TABLE %s %s", qualifiedName(schema, table), strings.Join(defs, ", "))
+}
+
+func qualifiedName(schema, table string) string {
+	if schema == "" {
+		return quoteIdent(table)
+	}
+	return quoteIdent(schema) + "." + quoteIdent(table)
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// normalizeType returns the type of the columns of information_schema as
+// the types of the batches.
+func normalizeType(dataType string) string {
+	switch dataType {
+	case "timestamp with time zone":
+		return typeTimestamp
+	case "character varying":
+		return typeText
+	}
+	return dataType
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedFieldKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// columnNames returns the names of the columns.
+func columnNames(columns []column) []string {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.name
+	}
+	return names
+}
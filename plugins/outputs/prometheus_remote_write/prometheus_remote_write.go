@@ -0,0 +1,318 @@
+package prometheus_remote_write
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+var invalidNameCharRE = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+type PrometheusRemoteWrite struct {
+	URL         string `toml:"url"`
+	Username    string
+	Password    string
+	BearerToken string
+	Headers     map[string]string
+	Timeout     internal.Duration
+	// MaxSeriesPerRequest is the maximum number of series of a request.
+	MaxSeriesPerRequest int
+	// MaxRetries is the number of retries of the requests failing with a 5xx
+	// or 429 status, waiting from MinBackoff to MaxBackoff between them.
+	MaxRetries int
+	MinBackoff internal.Duration
+	MaxBackoff internal.Duration
+
+	// Path to CA file
+	SSLCA string `toml:"ssl_ca"`
+	// Path to host cert file
+	SSLCert string `toml:"ssl_cert"`
+	// Path to cert key file
+	SSLKey string `toml:"ssl_key"`
+	// Use SSL but skip chain & host verification
+	InsecureSkipVerify bool
+
+	client *http.Client
+	// sleep waits between the retries, replaced by the tests.
+	sleep func(time.Duration)
+}
+
+var sampleConfig = `
+  ## URL of the remote write endpoint, ie of Prometheus, Mimir, Thanos or
+  ## VictoriaMetrics.
+  url = "http://localhost:9090/api/v1/write"
+
+  ## Basic authentication or bearer token of the requests.
+  # username = ""
+  # password = ""
+  # bearer_token = ""
+
+  ## Additional HTTP headers of the requests, ie the tenant of Mimir.
+  # [outputs.prometheus_remote_write.headers]
+  #   X-Scope-OrgID = "telegraf"
+
+  ## Timeout of the requests.
+  # timeout = "5s"
+
+  ## Maximum number of series of a request.
+  # max_series_per_request = 2000
+
+  ## The requests failing with a 5xx or 429 status are retried up to
+  ## max_retries times, waiting from min_backoff to max_backoff, doubling
+  ## each time, between the retries. The requests failing with another
+  ## status are not retried, their samples being dropped.
+  # max_retries = 3
+  # min_backoff = "30ms"
+  # max_backoff = "5s"
+
+  ## Optional SSL Config
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  ## Use SSL but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+func (p *PrometheusRemoteWrite) Connect() error {
+	if p.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+
+	tlsConfig, err := internal.GetTLSConfig(
+		p.SSLCert, p.SSLKey, p.SSLCA, p.InsecureSkipVerify)
+	if err != nil {
+		return err
+	}
+	p.client = &http.Client{
+		Timeout: p.Timeout.Duration,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}
+	if p.sleep == nil {
+		p.sleep = time.Sleep
+	}
+	return nil
+}
+
+func (p *PrometheusRemoteWrite) Close() error {
+	return nil
+}
+
+func (p *PrometheusRemoteWrite) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *PrometheusRemoteWrite) Description() string {
+	return "Configuration for the Prometheus remote write client"
+}
+
+func (p *PrometheusRemoteWrite) Write(metrics []telegraf.Metric) error {
+	series := buildSeries(metrics)
+	max := p.MaxSeriesPerRequest
+	if max <= 0 {
+		max = len(series)
+	}
+	for len(series) > 0 {
+		n := len(series)
+		if n > max {
+			n = max
+		}
+		if err := p.send(series[:n]); err != nil {
+			return err
+		}
+		series = series[n:]
+	}
+	return nil
+}
+
+// buildSeries returns the series of the numeric fields of the metrics, in the
+// order of the metrics and of the field names, their samples being sorted by
+// time.
+func buildSeries(metrics []telegraf.Metric) []*timeSeries {
+	var series []*timeSeries
+	byKey := make(map[string]*timeSeries)
+	for _, m := range metrics {
+		key := invalidNameCharRE.ReplaceAllString(m.Name(), "_")
+
+		var labels []label
+		for k, v := range m.Tags() {
+			k = invalidNameCharRE.ReplaceAllString(k, "_")
+			if len(k) == 0 || k == "__name__" {
+				continue
+			}
+			labels = append(labels, label{name: k, value: v})
+		}
+
+		fields := m.Fields()
+		names := make([]string, 0, len(fields))
+		for n := range fields {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+
+		for _, n := range names {
+			var value float64
+			val := fields[n]
+			switch val := val.(type) {
+			case int64:
+				value = float64(val)
+			case uint64:
+				value = float64(val)
+			case float64:
+				value = val
+			default:
+				// Ignore string and bool fields.
+				continue
+			}
+
+			n = invalidNameCharRE.ReplaceAllString(n, "_")
+			name := key
+			if n != "value" {
+				name = key + "_" + n
+			}
+			ls := append([]label{{name: "__name__", value: name}}, labels...)
+			sort.Sort(byName(ls))
+
+			id := seriesID(ls)
+			ts, ok := byKey[id]
+			if !ok {
+				ts = &timeSeries{labels: ls}
+				byKey[id] = ts
+				series = append(series, ts)
+			}
+			ts.samples = append(ts.samples, sample{
+				value:     value,
+				timestamp: m.UnixNano() / int64(time.Millisecond),
+			})
+		}
+	}
+
+	for _, ts := range series {
+		sort.Stable(byTimestamp(ts.samples))
+	}
+	return series
+}
+
+func seriesID(labels []label) string {
+	var b bytes.Buffer
+	for _, l := range labels {
+		b.WriteString(l.name)
+		b.WriteByte(0)
+		b.WriteString(l.value)
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+type byName []label
+
+func (l byName) Len() int           { return len(l) }
+func (l byName) Less(i, j int) bool { return l[i].name < l[j].name }
+func (l byName) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+
+type byTimestamp []sample
+
+func (s byTimestamp) Len() int           { return len(s) }
+func (s byTimestamp) Less(i, j int) bool { return s[i].timestamp < s[j].timestamp }
+func (s byTimestamp) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// retryableError is the error of the requests that can be retried.
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+// send sends the series, retrying with backoff the requests failing with a
+// retryable error.
+func (p *PrometheusRemoteWrite) send(series []*timeSeries) error {
+	body := snappy.Encode(nil, marshalWriteRequest(series))
+
+	backoff := p.MinBackoff.Duration
+	for attempt := 0; ; attempt++ {
+		err := p.post(body)
+		if err == nil {
+			return nil
+		}
+		if _, ok := err.(*retryableError); !ok {
+			// Sending the samples again would fail again.
+			log.Printf("E! Prometheus remote write: %s, dropping %d series", err, len(series))
+			return nil
+		}
+		if attempt >= p.MaxRetries {
+			return err
+		}
+		log.Printf("D! Prometheus remote write: %s, retrying in %s", err, backoff)
+		p.sleep(backoff)
+		backoff *= 2
+		if backoff > p.MaxBackoff.Duration {
+			backoff = p.MaxBackoff.Duration
+		}
+	}
+}
+
+func (p *PrometheusRemoteWrite) post(body []byte) error {
+	req, err := http.NewRequest("POST", p.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", "Telegraf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	for k, v := range p.Headers {
+		req.Header.Set(k, v)
+	}
+	if p.Username != "" || p.Password != "" {
+		req.SetBasicAuth(p.Username, p.Password)
+	} else if p.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+p.BearerToken)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return &retryableError{err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("write to %s failed with status code %d: %s",
+		p.URL, resp.StatusCode, strings.TrimSpace(string(msg)))
+	if resp.StatusCode/100 == 5 || resp.StatusCode == http.StatusTooManyRequests {
+		return &retryableError{err}
+	}
+	return err
+}
+
+func init() {
+	outputs.Add("prometheus_remote_write", func() telegraf.Output {
+		return &PrometheusRemoteWrite{
+			Timeout:             internal.Duration{Duration: 5 * time.Second},
+			MaxSeriesPerRequest: 2000,
+			MaxRetries:          3,
+			MinBackoff:          internal.Duration{Duration: 30 * time.Millisecond},
+			MaxBackoff:          internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,207 @@
+package prometheus_remote_write
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fields decodes the fields of a message, the varint and fixed64 values
+// being returned as their little endian bytes.
+func fields(t *testing.T, buf []byte) (keys []uint64, values [][]byte) {
+	for len(buf) > 0 {
+		key, n := binary.Uvarint(buf)
+		require.True(t, n > 0)
+		buf = buf[n:]
+		var value []byte
+		switch key & 7 {
+		case wireVarint:
+			v, n := binary.Uvarint(buf)
+			require.True(t, n > 0)
+			value = make([]byte, 8)
+			binary.LittleEndian.PutUint64(value, v)
+			buf = buf[n:]
+		case wireFixed64:
+			require.True(t, len(buf) >= 8)
+			value, buf = buf[:8], buf[8:]
+		case wireBytes:
+			l, n := binary.Uvarint(buf)
+			require.True(t, n > 0 && uint64(len(buf)-n) >= l)
+			value, buf = buf[n:n+int(l)], buf[n+int(l):]
+		default:
+			t.Fatalf("unexpected wire type of key %d", key)
+		}
+		keys = append(keys, key>>3)
+		values = append(values, value)
+	}
+	return keys, values
+}
+
+// decodeWriteRequest decodes the series of a WriteRequest.
+func decodeWriteRequest(t *testing.T, buf []byte) []*timeSeries {
+	var series []*timeSeries
+	_, messages := fields(t, buf)
+	for _, msg := range messages {
+		ts := &timeSeries{}
+		keys, values := fields(t, msg)
+		for i, key := range keys {
+			fk, fv := fields(t, values[i])
+			switch key {
+			case 1:
+				var l label
+				for j := range fk {
+					if fk[j] == 1 {
+						l.name = string(fv[j])
+					} else {
+						l.value = string(fv[j])
+					}
+				}
+				ts.labels = append(ts.labels, l)
+			case 2:
+				var s sample
+				for j := range fk {
+					v := binary.LittleEndian.Uint64(fv[j])
+					if fk[j] == 1 {
+						s.value = math.Float64frombits(v)
+					} else {
+						s.timestamp = int64(v)
+					}
+				}
+				ts.samples = append(ts.samples, s)
+			}
+		}
+		series = append(series, ts)
+	}
+	return series
+}
+
+func testMetrics() []telegraf.Metric {
+	m1, _ := metric.New("cpu",
+		map[string]string{"host": "a", "cpu-total": "yes"},
+		map[string]interface{}{"usage_idle": 90.5, "value": int64(3), "state": "ok"},
+		time.Unix(1500000001, 0))
+	m2, _ := metric.New("cpu",
+		map[string]string{"host": "a", "cpu-total": "yes"},
+		map[string]interface{}{"usage_idle": 80.0},
+		time.Unix(1500000000, 0))
+	return []telegraf.Metric{m1, m2}
+}
+
+func TestBuildSeries(t *testing.T) {
+	series := buildSeries(testMetrics())
+	require.Len(t, series, 2)
+
+	byName := make(map[string]*timeSeries)
+	for _, ts := range series {
+		byName[ts.labels[0].value] = ts
+	}
+
+	idle := byName["cpu_usage_idle"]
+	require.NotNil(t, idle)
+	assert.Equal(t, []label{
+		{name: "__name__", value: "cpu_usage_idle"},
+		{name: "cpu_total", value: "yes"},
+		{name: "host", value: "a"},
+	}, idle.labels)
+	assert.Equal(t, []sample{
+		{value: 80.0, timestamp: 1500000000000},
+		{value: 90.5, timestamp: 1500000001000},
+	}, idle.samples)
+
+	value := byName["cpu"]
+	require.NotNil(t, value)
+	assert.Equal(t, []sample{{value: 3, timestamp: 1500000001000}}, value.samples)
+}
+
+func TestWrite(t *testing.T) {
+	var series []*timeSeries
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "snappy", r.Header.Get("Content-Encoding"))
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		assert.Equal(t, "0.1.0", r.Header.Get("X-Prometheus-Remote-Write-Version"))
+		assert.Equal(t, "tenant", r.Header.Get("X-Scope-OrgID"))
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		buf, err := snappy.Decode(nil, body)
+		require.NoError(t, err)
+		series = append(series, decodeWriteRequest(t, buf)...)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	p := &PrometheusRemoteWrite{
+		URL:         ts.URL,
+		BearerToken: "secret",
+		Headers:     map[string]string{"X-Scope-OrgID": "tenant"},
+	}
+	require.NoError(t, p.Connect())
+	require.NoError(t, p.Write(testMetrics()))
+
+	assert.Equal(t, buildSeries(testMetrics()), series)
+}
+
+func TestWriteRetry(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	var backoffs []time.Duration
+	p := &PrometheusRemoteWrite{
+		URL:        ts.URL,
+		MaxRetries: 3,
+		MinBackoff: internal.Duration{Duration: 30 * time.Millisecond},
+		MaxBackoff: internal.Duration{Duration: 50 * time.Millisecond},
+		sleep:      func(d time.Duration) { backoffs = append(backoffs, d) },
+	}
+	require.NoError(t, p.Connect())
+	require.NoError(t, p.Write(testMetrics()))
+	assert.Equal(t, 3, requests)
+	assert.Equal(t, []time.Duration{30 * time.Millisecond, 50 * time.Millisecond}, backoffs)
+
+	// The retries are exhausted.
+	requests = -10
+	backoffs = nil
+	require.Error(t, p.Write(testMetrics()))
+	assert.Equal(t, -6, requests)
+	assert.Len(t, backoffs, 3)
+}
+
+func TestWriteDropped(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	p := &PrometheusRemoteWrite{
+		URL:        ts.URL,
+		MaxRetries: 3,
+		sleep:      func(time.Duration) {},
+	}
+	require.NoError(t, p.Connect())
+	require.NoError(t, p.Write(testMetrics()))
+	assert.Equal(t, 1, requests)
+}
@@ -0,0 +1,88 @@
+package prometheus_remote_write
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// The messages of the remote write protocol, encoded as the prompb
+// messages: WriteRequest{repeated TimeSeries timeseries = 1},
+// TimeSeries{repeated Label labels = 1; repeated Sample samples = 2},
+// Label{string name = 1; string value = 2} and
+// Sample{double value = 1; int64 timestamp = 2}.
+
+type label struct {
+	name  string
+	value string
+}
+
+type sample struct {
+	value float64
+	// timestamp is in milliseconds.
+	timestamp int64
+}
+
+type timeSeries struct {
+	labels  []label
+	samples []sample
+}
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+// marshalWriteRequest returns the WriteRequest of the series.
+func marshalWriteRequest(series []*timeSeries) []byte {
+	var buf []byte
+	for _, ts := range series {
+		buf = appendMessage(buf, 1, ts.marshal())
+	}
+	return buf
+}
+
+func (ts *timeSeries) marshal() []byte {
+	var buf []byte
+	for _, l := range ts.labels {
+		var lb []byte
+		lb = appendString(lb, 1, l.name)
+		lb = appendString(lb, 2, l.value)
+		buf = appendMessage(buf, 1, lb)
+	}
+	for _, s := range ts.samples {
+		var sb []byte
+		sb = appendKey(sb, 1, wireFixed64)
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], math.Float64bits(s.value))
+		sb = append(sb, b[:]...)
+		if s.timestamp != 0 {
+			sb = appendKey(sb, 2, wireVarint)
+			sb = appendUvarint(sb, uint64(s.timestamp))
+		}
+		buf = appendMessage(buf, 2, sb)
+	}
+	return buf
+}
+
+func appendKey(buf []byte, field, wire int) []byte {
+	return appendUvarint(buf, uint64(field<<3|wire))
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	return append(buf, b[:n]...)
+}
+
+func appendString(buf []byte, field int, s string) []byte {
+	buf = appendKey(buf, field, wireBytes)
+	buf = appendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+func appendMessage(buf []byte, field int, msg []byte) []byte {
+	buf = appendKey(buf, field, wireBytes)
+	buf = appendUvarint(buf, uint64(len(msg)))
+	return append(buf, msg...)
+}
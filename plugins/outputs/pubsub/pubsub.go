@@ -0,0 +1,256 @@
+package pubsub
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/gcp"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+// maxMessagesPerRequest is the maximum number of messages of a publish
+// request.
+const (
+	maxMessagesPerRequest = 1000
+
+	// pubsubScope is the OAuth2 scope of the tokens of the requests.
+	pubsubScope = "https://www.googleapis.com/auth/pubsub"
+)
+
+type PubSub struct {
+	Project         string
+	Topic           string
+	CredentialsFile string
+	// Endpoint is the URL of the Pub/Sub API, EmulatorHost the address of
+	// an emulator, which is not authenticated, overriding it.
+	Endpoint     string
+	EmulatorHost string
+	// SendBatched publishes the metrics of a write in a message, or in a
+	// message per ordering key, instead of a message per metric.
+	SendBatched    bool
+	OrderingKeyTag string
+	Attributes     map[string]string
+	Timeout        internal.Duration
+
+	client     *http.Client
+	tokens     *gcp.TokenSource
+	serializer serializers.Serializer
+}
+
+var sampleConfig = `
+  ## GCP project of the topic.
+  project = "my-project"
+
+  ## Pub/Sub topic the messages are published to.
+  topic = "telegraf"
+
+  ## Key file of the service account publishing the messages. If empty, the
+  ## service account of the GCE instance is used.
+  # credentials_file = "path/to/my/creds.json"
+
+  ## URL of the Pub/Sub API, ie of a regional endpoint.
+  # endpoint = "https://pubsub.googleapis.com"
+
+  ## Address of a Pub/Sub emulator, the messages being published to it
+  ## without authentication.
+  # emulator_host = "localhost:8085"
+
+  ## Publish the metrics of each write as one message, instead of a message
+  ## per metric.
+  # send_batched = true
+
+  ## Tag whose value is the ordering key of the messages, the ordering of
+  ## the messages of a key being kept by the subscriptions with message
+  ## ordering. With send_batched, the metrics are batched by ordering key.
+  # ordering_key_tag = ""
+
+  ## Attributes of the messages.
+  # [outputs.pubsub.attributes]
+  #   source = "telegraf"
+
+  ## Timeout of the publish requests.
+  # timeout = "5s"
+
+  ## Data format to output.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_OUTPUT.md
+  data_format = "influx"
+`
+
+func (p *PubSub) SetSerializer(serializer serializers.Serializer) {
+	p.serializer = serializer
+}
+
+func (p *PubSub) Connect() error {
+	if p.Project == "" || p.Topic == "" {
+		return fmt.Errorf("project and topic are required")
+	}
+
+	p.client = &http.Client{
+		Timeout: p.Timeout.Duration,
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+		},
+	}
+	var err error
+	p.tokens, err = gcp.NewTokenSource(p.client, p.CredentialsFile, pubsubScope)
+	return err
+}
+
+func (p *PubSub) Close() error {
+	return nil
+}
+
+func (p *PubSub) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *PubSub) Description() string {
+	return "Publish Telegraf metrics to a Google Cloud PubSub topic"
+}
+
+// message is a Pub/Sub message of the publish requests.
+type message struct {
+	Data        string            `json:"data"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	OrderingKey string            `json:"orderingKey,omitempty"`
+}
+
+func (p *PubSub) Write(metrics []telegraf.Metric) error {
+	messages, err := p.messages(metrics)
+	if err != nil {
+		return err
+	}
+	for len(messages) > 0 {
+		n := len(messages)
+		if n > maxMessagesPerRequest {
+			n = maxMessagesPerRequest
+		}
+		if err := p.publish(messages[:n]); err != nil {
+			return err
+		}
+		messages = messages[n:]
+	}
+	return nil
+}
+
+// messages returns the messages of the metrics, in the order of the metrics.
+func (p *PubSub) messages(metrics []telegraf.Metric) ([]message, error) {
+	var messages []message
+	if !p.SendBatched {
+		for _, m := range metrics {
+			buf, err := p.serializer.Serialize(m)
+			if err != nil {
+				return nil, err
+			}
+			messages = append(messages, p.message(buf, p.orderingKey(m)))
+		}
+		return messages, nil
+	}
+
+	var keys []string
+	batches := make(map[string][]telegraf.Metric)
+	for _, m := range metrics {
+		key := p.orderingKey(m)
+		if _, ok := batches[key]; !ok {
+			keys = append(keys, key)
+		}
+		batches[key] = append(batches[key], m)
+	}
+	for _, key := range keys {
+		buf, err := p.serializeBatch(batches[key])
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, p.message(buf, key))
+	}
+	return messages, nil
+}
+
+func (p *PubSub) orderingKey(m telegraf.Metric) string {
+	if p.OrderingKeyTag == "" {
+		return ""
+	}
+	return m.Tags()[p.OrderingKeyTag]
+}
+
+func (p *PubSub) message(data []byte, orderingKey string) message {
+	return message{
+		Data:        base64.StdEncoding.EncodeToString(data),
+		Attributes:  p.Attributes,
+		OrderingKey: orderingKey,
+	}
+}
+
+func (p *PubSub) serializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	if s, ok := p.serializer.(serializers.BatchSerializer); ok {
+		return s.SerializeBatch(metrics)
+	}
+	var buf []byte
+	for _, m := range metrics {
+		b, err := p.serializer.Serialize(m)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, b...)
+	}
+	return buf, nil
+}
+
+func (p *PubSub) publish(messages []message) error {
+	body, err := json.Marshal(map[string]interface{}{"messages": messages})
+	if err != nil {
+		return err
+	}
+
+	endpoint := strings.TrimRight(p.Endpoint, "/")
+	if p.EmulatorHost != "" {
+		endpoint = "http://" + p.EmulatorHost
+	}
+	u := fmt.Sprintf("%s/v1/projects/%s/topics/%s:publish", endpoint, p.Project, p.Topic)
+	req, err := http.NewRequest("POST", u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.EmulatorHost == "" {
+		token, err := p.tokens.Token()
+		if err != nil {
+			return fmt.Errorf("PubSub: unable to get an access token: %s", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("PubSub: publish to topic %s failed with status code %d: %s",
+			p.Topic, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func init() {
+	outputs.Add("pubsub", func() telegraf.Output {
+		return &PubSub{
+			Endpoint:    "https://pubsub.googleapis.com",
+			SendBatched: true,
+			Timeout:     internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
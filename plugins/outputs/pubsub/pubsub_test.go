@@ -0,0 +1,191 @@
+package pubsub
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// server is a Pub/Sub API and token endpoint, recording the messages
+// published.
+type server struct {
+	t   *testing.T
+	key *rsa.PublicKey
+
+	mu       sync.Mutex
+	tokens   int
+	requests [][]message
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/token":
+		assert.Equal(s.t, "urn:ietf:params:oauth:grant-type:jwt-bearer", r.FormValue("grant_type"))
+		parts := strings.Split(r.FormValue("assertion"), ".")
+		require.Len(s.t, parts, 3)
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		require.NoError(s.t, err)
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		assert.NoError(s.t, rsa.VerifyPKCS1v15(s.key, crypto.SHA256, digest[:], signature))
+		claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+		require.NoError(s.t, err)
+		assert.Contains(s.t, string(claims), `"iss":"telegraf@my-project.iam.gserviceaccount.com"`)
+		assert.Contains(s.t, string(claims), `"scope":"https://www.googleapis.com/auth/pubsub"`)
+
+		s.tokens++
+		w.Write([]byte(`{"access_token":"secret","expires_in":3600}`))
+	case "/v1/projects/my-project/topics/telegraf:publish":
+		if s.key != nil {
+			assert.Equal(s.t, "Bearer secret", r.Header.Get("Authorization"))
+		} else {
+			assert.Equal(s.t, "", r.Header.Get("Authorization"))
+		}
+		var body struct {
+			Messages []message `json:"messages"`
+		}
+		require.NoError(s.t, json.NewDecoder(r.Body).Decode(&body))
+		s.requests = append(s.requests, body.Messages)
+		w.Write([]byte(`{"messageIds":["1"]}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func credentialsFile(t *testing.T, tokenURI string) (string, *rsa.PublicKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	der := x509.MarshalPKCS1PrivateKey(key)
+	creds, err := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "telegraf@my-project.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: der})),
+		"token_uri":    tokenURI,
+	})
+	require.NoError(t, err)
+
+	f, err := ioutil.TempFile("", "pubsub-creds")
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = f.Write(creds)
+	require.NoError(t, err)
+	return f.Name(), &key.PublicKey
+}
+
+func metrics() []telegraf.Metric {
+	m1 := testutil.TestMetric(1.0, "cpu")
+	m1.AddTag("host", "a")
+	m2 := testutil.TestMetric(2.0, "cpu")
+	m2.AddTag("host", "b")
+	m3 := testutil.TestMetric(3.0, "mem")
+	m3.AddTag("host", "a")
+	return []telegraf.Metric{m1, m2, m3}
+}
+
+func decode(t *testing.T, m message) string {
+	data, err := base64.StdEncoding.DecodeString(m.Data)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestWriteBatched(t *testing.T) {
+	s := &server{t: t}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	file, key := credentialsFile(t, ts.URL+"/token")
+	defer os.Remove(file)
+	s.key = key
+
+	serializer, _ := serializers.NewInfluxSerializer()
+	p := &PubSub{
+		Project:         "my-project",
+		Topic:           "telegraf",
+		CredentialsFile: file,
+		Endpoint:        ts.URL,
+		SendBatched:     true,
+		OrderingKeyTag:  "host",
+		Attributes:      map[string]string{"source": "telegraf"},
+		serializer:      serializer,
+	}
+	require.NoError(t, p.Connect())
+
+	ms := metrics()
+	require.NoError(t, p.Write(ms))
+	require.NoError(t, p.Write(ms))
+
+	// The token is requested once.
+	assert.Equal(t, 1, s.tokens)
+	require.Len(t, s.requests, 2)
+	messages := s.requests[0]
+	require.Len(t, messages, 2)
+	assert.Equal(t, "a", messages[0].OrderingKey)
+	assert.Equal(t, ms[0].String()+ms[2].String(), decode(t, messages[0]))
+	assert.Equal(t, map[string]string{"source": "telegraf"}, messages[0].Attributes)
+	assert.Equal(t, "b", messages[1].OrderingKey)
+	assert.Equal(t, ms[1].String(), decode(t, messages[1]))
+}
+
+func TestWriteEmulator(t *testing.T) {
+	s := &server{t: t}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	serializer, _ := serializers.NewInfluxSerializer()
+	p := &PubSub{
+		Project:      "my-project",
+		Topic:        "telegraf",
+		EmulatorHost: strings.TrimPrefix(ts.URL, "http://"),
+		serializer:   serializer,
+	}
+	require.NoError(t, p.Connect())
+
+	ms := metrics()
+	require.NoError(t, p.Write(ms))
+	require.Len(t, s.requests, 1)
+	require.Len(t, s.requests[0], 3)
+	for i, m := range s.requests[0] {
+		assert.Equal(t, "", m.OrderingKey)
+		assert.Equal(t, ms[i].String(), decode(t, m))
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":{"message":"Resource not found"}}`))
+	}))
+	defer ts.Close()
+
+	serializer, _ := serializers.NewInfluxSerializer()
+	p := &PubSub{
+		Project:      "my-project",
+		Topic:        "telegraf",
+		EmulatorHost: strings.TrimPrefix(ts.URL, "http://"),
+		serializer:   serializer,
+	}
+	require.NoError(t, p.Connect())
+	err := p.Write(metrics())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Resource not found")
+}
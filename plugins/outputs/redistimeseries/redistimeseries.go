@@ -0,0 +1,391 @@
+package redistimeseries
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+// maxSamples is the maximum number of samples of a TS.MADD command.
+const maxSamples = 1000
+
+var duplicatePolicies = map[string]bool{
+	"BLOCK": true, "FIRST": true, "LAST": true, "MIN": true, "MAX": true, "SUM": true,
+}
+
+// RedisTimeSeries adds the fields of the metrics as samples of the time
+// series of RedisTimeSeries, created with the tags as labels.
+type RedisTimeSeries struct {
+	Address  string
+	Username string
+	Password string
+	Database int
+	Timeout  internal.Duration
+	// Key is the template of the keys of the time series of the fields.
+	Key string
+	// Retention and DuplicatePolicy are those of the time series created.
+	Retention       internal.Duration
+	DuplicatePolicy string
+
+	// Path to CA file
+	SSLCA string `toml:"ssl_ca"`
+	// Path to host cert file
+	SSLCert string `toml:"ssl_cert"`
+	// Path to cert key file
+	SSLKey string `toml:"ssl_key"`
+	// Use SSL but skip chain & host verification
+	InsecureSkipVerify bool
+
+	key       *template.Template
+	tlsConfig *tls.Config
+	conn      net.Conn
+	r         *bufio.Reader
+	w         *bufio.Writer
+	// created are the keys of the time series created.
+	created map[string]bool
+}
+
+var sampleConfig = `
+  ## Address of the Redis server.
+  address = "localhost:6379"
+
+  ## Authentication of the connection, the username requiring Redis 6.
+  # username = ""
+  # password = ""
+
+  ## Database of the time series.
+  # database = 0
+
+  ## Timeout of the connection and commands.
+  # timeout = "5s"
+
+  ## Go template of the keys of the time series of the fields. The metric is
+  ## the data of the template: {{.Name}} is its name, {{.Field}} the field,
+  ## {{.Tag "host"}} the value of its host tag and {{.Tags}} its tags.
+  # key = '{{.Name}}:{{.Field}}{{range $k, $v := .Tags}}:{{$k}}={{$v}}{{end}}'
+
+  ## Retention of the time series created, unlimited if 0, and policy of
+  ## their duplicate samples, "BLOCK", "FIRST", "LAST", "MIN", "MAX" or
+  ## "SUM", the policy of the server if empty.
+  # retention = "0s"
+  # duplicate_policy = ""
+
+  ## Optional SSL Config
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  ## Use SSL but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+func (r *RedisTimeSeries) Connect() error {
+	if r.Address == "" {
+		return fmt.Errorf("address is required")
+	}
+	r.DuplicatePolicy = strings.ToUpper(r.DuplicatePolicy)
+	if r.DuplicatePolicy != "" && !duplicatePolicies[r.DuplicatePolicy] {
+		return fmt.Errorf("invalid duplicate_policy %q", r.DuplicatePolicy)
+	}
+	var err error
+	r.key, err = template.New("key").Option("missingkey=zero").Parse(r.Key)
+	if err != nil {
+		return fmt.Errorf("invalid key template: %s", err)
+	}
+	r.tlsConfig, err = internal.GetTLSConfig(
+		r.SSLCert, r.SSLKey, r.SSLCA, r.InsecureSkipVerify)
+	if err != nil {
+		return err
+	}
+	r.created = make(map[string]bool)
+	return r.dial()
+}
+
+// dial connects to the server, authenticating and selecting the database.
+func (r *RedisTimeSeries) dial() error {
+	dialer := &net.Dialer{Timeout: r.Timeout.Duration}
+	var conn net.Conn
+	var err error
+	if r.tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", r.Address, r.tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", r.Address)
+	}
+	if err != nil {
+		return err
+	}
+	r.conn = conn
+	r.r = bufio.NewReader(conn)
+	r.w = bufio.NewWriter(conn)
+
+	var commands [][]string
+	if r.Password != "" {
+		if r.Username != "" {
+			commands = append(commands, []string{"AUTH", r.Username, r.Password})
+		} else {
+			commands = append(commands, []string{"AUTH", r.Password})
+		}
+	}
+	if r.Database != 0 {
+		commands = append(commands, []string{"SELECT", strconv.Itoa(r.Database)})
+	}
+	replies, err := r.do(commands...)
+	if err == nil {
+		for _, reply := range replies {
+			if e, ok := reply.(redisError); ok {
+				err = e
+				break
+			}
+		}
+	}
+	if err != nil {
+		r.close()
+		return err
+	}
+	return nil
+}
+
+// do pipelines the commands, returning their replies.
+func (r *RedisTimeSeries) do(commands ...[]string) ([]interface{}, error) {
+	if len(commands) == 0 {
+		return nil, nil
+	}
+	if r.Timeout.Duration > 0 {
+		r.conn.SetDeadline(time.Now().Add(r.Timeout.Duration))
+	}
+	for _, args := range commands {
+		writeCommand(r.w, args...)
+	}
+	if err := r.w.Flush(); err != nil {
+		return nil, err
+	}
+	replies := make([]interface{}, len(commands))
+	for i := range replies {
+		reply, err := readReply(r.r)
+		if err != nil {
+			return nil, err
+		}
+		replies[i] = reply
+	}
+	return replies, nil
+}
+
+func (r *RedisTimeSeries) close() {
+	if r.conn != nil {
+		r.conn.Close()
+		r.conn = nil
+	}
+}
+
+func (r *RedisTimeSeries) Close() error {
+	r.close()
+	return nil
+}
+
+func (r *RedisTimeSeries) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *RedisTimeSeries) Description() string {
+	return "Add metrics to RedisTimeSeries"
+}
+
+// sample is a sample of a time series.
+type sample struct {
+	key    string
+	time   string
+	value  string
+	labels map[string]string
+}
+
+// Write creates the time series of the samples of the metrics not created,
+// then adds the samples with TS.MADD, the connection being reopened after a
+// failure.
+func (r *RedisTimeSeries) Write(metrics []telegraf.Metric) error {
+	samples, err := r.samples(metrics)
+	if err != nil {
+		return err
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+	if r.conn == nil {
+		if err := r.dial(); err != nil {
+			return err
+		}
+	}
+	if err := r.write(samples); err != nil {
+		r.close()
+		return err
+	}
+	return nil
+}
+
+func (r *RedisTimeSeries) write(samples []sample) error {
+	var creates [][]string
+	var keys []string
+	for _, s := range samples {
+		if r.created[s.key] {
+			continue
+		}
+		r.created[s.key] = true
+		keys = append(keys, s.key)
+		creates = append(creates, r.createCommand(s))
+	}
+	replies, err := r.do(creates...)
+	if err != nil {
+		for _, key := range keys {
+			delete(r.created, key)
+		}
+		return err
+	}
+	for i, reply := range replies {
+		// The time series created before keep their retention and labels.
+		if e, ok := reply.(redisError); ok && !strings.Contains(string(e), "already exists") {
+			log.Printf("E! RedisTimeSeries: creating %s failed: %s", keys[i], e)
+		}
+	}
+
+	for len(samples) > 0 {
+		n := len(samples)
+		if n > maxSamples {
+			n = maxSamples
+		}
+		args := make([]string, 0, 1+3*n)
+		args = append(args, "TS.MADD")
+		for _, s := range samples[:n] {
+			args = append(args, s.key, s.time, s.value)
+		}
+		replies, err := r.do(args)
+		if err != nil {
+			return err
+		}
+		if e, ok := replies[0].(redisError); ok {
+			return fmt.Errorf("TS.MADD failed: %s", e)
+		}
+		// The samples rejected, ie duplicates with the BLOCK policy, would
+		// be rejected again.
+		if results, ok := replies[0].([]interface{}); ok {
+			for i, result := range results {
+				if e, ok := result.(redisError); ok && i < n {
+					log.Printf("E! RedisTimeSeries: adding sample of %s at %s failed: %s",
+						samples[i].key, samples[i].time, e)
+				}
+			}
+		}
+		samples = samples[n:]
+	}
+	return nil
+}
+
+// createCommand returns the TS.CREATE command of the time series of the
+// sample.
+func (r *RedisTimeSeries) createCommand(s sample) []string {
+	args := []string{"TS.CREATE", s.key}
+	if r.Retention.Duration > 0 {
+		args = append(args, "RETENTION",
+			strconv.FormatInt(int64(r.Retention.Duration/time.Millisecond), 10))
+	}
+	if r.DuplicatePolicy != "" {
+		args = append(args, "DUPLICATE_POLICY", r.DuplicatePolicy)
+	}
+	if len(s.labels) > 0 {
+		args = append(args, "LABELS")
+		keys := make([]string, 0, len(s.labels))
+		for k := range s.labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			args = append(args, k, s.labels[k])
+		}
+	}
+	return args
+}
+
+// samples returns the samples of the numeric and boolean fields of the
+// metrics.
+func (r *RedisTimeSeries) samples(metrics []telegraf.Metric) ([]sample, error) {
+	var samples []sample
+	for _, m := range metrics {
+		fields := m.Fields()
+		names := make([]string, 0, len(fields))
+		for k := range fields {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+
+		ts := strconv.FormatInt(m.Time().UnixNano()/int64(time.Millisecond), 10)
+		for _, field := range names {
+			value, ok := formatValue(fields[field])
+			if !ok {
+				continue
+			}
+			var b bytes.Buffer
+			if err := r.key.Execute(&b, keyMetric{m, field}); err != nil {
+				return nil, err
+			}
+			samples = append(samples, sample{
+				key:    b.String(),
+				time:   ts,
+				value:  value,
+				labels: m.Tags(),
+			})
+		}
+	}
+	return samples, nil
+}
+
+func formatValue(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), true
+	case bool:
+		if v {
+			return "1", true
+		}
+		return "0", true
+	}
+	return "", false
+}
+
+// keyMetric is the data of the key templates.
+type keyMetric struct {
+	telegraf.Metric
+	field string
+}
+
+// Field returns the field of the time series.
+func (m keyMetric) Field() string {
+	return m.field
+}
+
+// Tag returns the value of the tag, empty if the metric does not have it.
+func (m keyMetric) Tag(key string) string {
+	return m.Tags()[key]
+}
+
+func init() {
+	outputs.Add("redistimeseries", func() telegraf.Output {
+		return &RedisTimeSeries{
+			Address: "localhost:6379",
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+			Key:     `{{.Name}}:{{.Field}}{{range $k, $v := .Tags}}:{{$k}}={{$v}}{{end}}`,
+		}
+	})
+}
@@ -0,0 +1,175 @@
+package redistimeseries
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// server is a Redis server with the RedisTimeSeries commands, recording the
+// commands.
+type server struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	commands [][]string
+	keys     map[string]bool
+}
+
+func newServer(t *testing.T) *server {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &server{listener: l, keys: map[string]bool{"cpu:usage:host=b": true}}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *server) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return
+		}
+		n, _ := strconv.Atoi(strings.TrimPrefix(line, "*"))
+		args := make([]string, n)
+		for i := range args {
+			readLine(r)
+			if args[i], err = readLine(r); err != nil {
+				return
+			}
+		}
+		fmt.Fprint(conn, s.reply(args))
+	}
+}
+
+func (s *server) reply(args []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commands = append(s.commands, args)
+	switch args[0] {
+	case "AUTH", "SELECT":
+		return "+OK\r\n"
+	case "TS.CREATE":
+		if s.keys[args[1]] {
+			return "-ERR TSDB: key already exists\r\n"
+		}
+		s.keys[args[1]] = true
+		return "+OK\r\n"
+	case "TS.MADD":
+		reply := fmt.Sprintf("*%d\r\n", (len(args)-1)/3)
+		for i := 1; i < len(args); i += 3 {
+			if args[i+2] == "-1" {
+				reply += "-ERR TSDB: duplicate\r\n"
+			} else {
+				reply += ":" + args[i+1] + "\r\n"
+			}
+		}
+		return reply
+	}
+	return "-ERR unknown command\r\n"
+}
+
+func newOutput(t *testing.T, s *server) *RedisTimeSeries {
+	r := &RedisTimeSeries{
+		Address:         s.listener.Addr().String(),
+		Password:        "secret",
+		Database:        2,
+		Timeout:         internal.Duration{Duration: 5 * time.Second},
+		Key:             `{{.Name}}:{{.Field}}{{range $k, $v := .Tags}}:{{$k}}={{$v}}{{end}}`,
+		Retention:       internal.Duration{Duration: time.Hour},
+		DuplicatePolicy: "last",
+	}
+	require.NoError(t, r.Connect())
+	return r
+}
+
+func TestWrite(t *testing.T) {
+	s := newServer(t)
+	defer s.listener.Close()
+	r := newOutput(t, s)
+	defer r.Close()
+
+	now := time.Unix(1500000000, 0)
+	m1, err := metric.New("cpu", map[string]string{"host": "a"},
+		map[string]interface{}{"usage": 1.5, "up": true, "state": "ok"}, now)
+	require.NoError(t, err)
+	m2, err := metric.New("cpu", map[string]string{"host": "b"},
+		map[string]interface{}{"usage": int64(-1)}, now)
+	require.NoError(t, err)
+	require.NoError(t, r.Write([]telegraf.Metric{m1, m2}))
+
+	assert.Equal(t, [][]string{
+		{"AUTH", "secret"},
+		{"SELECT", "2"},
+		{"TS.CREATE", "cpu:up:host=a", "RETENTION", "3600000", "DUPLICATE_POLICY", "LAST", "LABELS", "host", "a"},
+		{"TS.CREATE", "cpu:usage:host=a", "RETENTION", "3600000", "DUPLICATE_POLICY", "LAST", "LABELS", "host", "a"},
+		{"TS.CREATE", "cpu:usage:host=b", "RETENTION", "3600000", "DUPLICATE_POLICY", "LAST", "LABELS", "host", "b"},
+		{"TS.MADD",
+			"cpu:up:host=a", "1500000000000", "1",
+			"cpu:usage:host=a", "1500000000000", "1.5",
+			"cpu:usage:host=b", "1500000000000", "-1"},
+	}, s.commands)
+
+	// The time series are created once.
+	s.commands = nil
+	require.NoError(t, r.Write([]telegraf.Metric{m1}))
+	assert.Equal(t, [][]string{
+		{"TS.MADD",
+			"cpu:up:host=a", "1500000000000", "1",
+			"cpu:usage:host=a", "1500000000000", "1.5"},
+	}, s.commands)
+}
+
+func TestWriteReconnect(t *testing.T) {
+	s := newServer(t)
+	defer s.listener.Close()
+	r := newOutput(t, s)
+	r.Close()
+	r.Key = "{{.Name}}"
+	require.NoError(t, r.Connect())
+	defer r.Close()
+
+	// The connection is reopened by the write following a failure.
+	r.conn.Close()
+	m, err := metric.New("mem", nil, map[string]interface{}{"free": uint64(10)}, time.Unix(0, 0))
+	require.NoError(t, err)
+	require.Error(t, r.Write([]telegraf.Metric{m}))
+	s.commands = nil
+	require.NoError(t, r.Write([]telegraf.Metric{m}))
+	assert.Equal(t, [][]string{
+		{"AUTH", "secret"},
+		{"SELECT", "2"},
+		{"TS.CREATE", "mem", "RETENTION", "3600000", "DUPLICATE_POLICY", "LAST"},
+		{"TS.MADD", "mem", "0", "10"},
+	}, s.commands)
+}
+
+func TestConnectErrors(t *testing.T) {
+	r := &RedisTimeSeries{Address: "localhost:6379", DuplicatePolicy: "newest"}
+	assert.Error(t, r.Connect())
+
+	r = &RedisTimeSeries{Address: "localhost:6379", Key: "{{.Name"}
+	assert.Error(t, r.Connect())
+}
@@ -11,3 +11,10 @@ var Outputs = map[string]Creator{}
 func Add(name string, creator Creator) {
 	Outputs[name] = creator
 }
+
+// GlobalTagsOutput is an output using the global tags, which are also the
+// tags of its metrics, ie to make them the attributes of a resource. The map
+// is completed by the agent, with the host tag, before the output connects.
+type GlobalTagsOutput interface {
+	SetGlobalTags(tags map[string]string)
+}
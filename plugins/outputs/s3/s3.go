@@ -0,0 +1,366 @@
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	internalaws "github.com/influxdata/telegraf/internal/config/aws"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+// objectIDPlaceholder stands for the id of the objects in the rendered keys,
+// the id being known when the object is uploaded.
+const objectIDPlaceholder = "\x00objectid\x00"
+
+type S3 struct {
+	Region    string `toml:"region"`
+	AccessKey string `toml:"access_key"`
+	SecretKey string `toml:"secret_key"`
+	RoleARN   string `toml:"role_arn"`
+	Profile   string `toml:"profile"`
+	Filename  string `toml:"shared_credential_file"`
+	Token     string `toml:"token"`
+	// EndpointURL and ForcePathStyle are the settings of the S3 compatible
+	// stores.
+	EndpointURL    string `toml:"endpoint_url"`
+	ForcePathStyle bool   `toml:"force_path_style"`
+
+	Bucket string `toml:"bucket"`
+	// Key is the template of the keys of the objects, the metrics with the
+	// same key being uploaded in the same object.
+	Key          string `toml:"key"`
+	StorageClass string `toml:"storage_class"`
+	Compression  string `toml:"compression"`
+
+	// An object is uploaded once its size, the size of its metrics
+	// serialized one by one, reaches MaxObjectSize or once it is older than
+	// RotationInterval.
+	MaxObjectSize    int64             `toml:"max_object_size"`
+	RotationInterval internal.Duration `toml:"rotation_interval"`
+
+	svc        uploadClient
+	key        *template.Template
+	serializer serializers.Serializer
+
+	mu      sync.Mutex
+	objects map[string]*object
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// uploadClient is the part of the S3 API used by the output.
+type uploadClient interface {
+	PutObject(*s3.PutObjectInput) (*s3.PutObjectOutput, error)
+}
+
+// object is an object being buffered.
+type object struct {
+	key     string
+	created time.Time
+	metrics []telegraf.Metric
+	size    int64
+}
+
+var sampleConfig = `
+  ## Amazon REGION
+  region = "us-east-1"
+
+  ## Amazon Credentials
+  ## Credentials are loaded in the following order
+  ## 1) Assumed credentials via STS if role_arn is specified
+  ## 2) explicit credentials from 'access_key' and 'secret_key'
+  ## 3) shared profile from 'profile'
+  ## 4) environment variables
+  ## 5) shared credentials file
+  ## 6) EC2 Instance Profile
+  #access_key = ""
+  #secret_key = ""
+  #token = ""
+  #role_arn = ""
+  #profile = ""
+  #shared_credential_file = ""
+
+  ## Endpoint of a S3 compatible store, ie MinIO, whose buckets are often
+  ## only reachable in the path style.
+  # endpoint_url = "http://localhost:9000"
+  # force_path_style = false
+
+  ## Bucket of the objects.
+  bucket = "telemetry"
+
+  ## Go template of the keys of the objects, the metrics with the same key
+  ## being uploaded in the same object. The metric is the data of the
+  ## template: {{.Name}} is its name, {{.Tag "host"}} the value of its host
+  ## tag and {{.Time}} its time, in UTC. {{.ObjectID}} is a unique id of the
+  ## object, without it the successive objects of a key replace each other.
+  key = 'telegraf/dt={{.Time.Format "2006-01-02"}}/hour={{.Time.Format "15"}}/{{.Tag "host"}}-{{.ObjectID}}.parquet'
+
+  ## Storage class of the objects, ie "STANDARD_IA".
+  # storage_class = ""
+
+  ## Compression of the objects, "gzip" or "none".
+  # compression = "none"
+
+  ## An object is uploaded once the size of its metrics, serialized one by
+  ## one before compression, reaches max_object_size bytes, or once it is
+  ## older than rotation_interval. The remaining objects are uploaded when
+  ## Telegraf stops.
+  # max_object_size = 16777216
+  # rotation_interval = "5m"
+
+  ## Data format to output.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_OUTPUT.md
+  data_format = "parquet"
+`
+
+func (s *S3) SetSerializer(serializer serializers.Serializer) {
+	s.serializer = serializer
+}
+
+func (s *S3) Connect() error {
+	if s.Bucket == "" {
+		return fmt.Errorf("bucket is required")
+	}
+	if s.Compression == "" {
+		s.Compression = "none"
+	}
+	if s.Compression != "gzip" && s.Compression != "none" {
+		return fmt.Errorf("invalid compression %q", s.Compression)
+	}
+	var err error
+	s.key, err = template.New("key").Option("missingkey=zero").Parse(s.Key)
+	if err != nil {
+		return fmt.Errorf("invalid key template: %s", err)
+	}
+
+	if s.svc == nil {
+		credentialConfig := &internalaws.CredentialConfig{
+			Region:    s.Region,
+			AccessKey: s.AccessKey,
+			SecretKey: s.SecretKey,
+			RoleARN:   s.RoleARN,
+			Profile:   s.Profile,
+			Filename:  s.Filename,
+			Token:     s.Token,
+		}
+		config := &aws.Config{S3ForcePathStyle: aws.Bool(s.ForcePathStyle)}
+		if s.EndpointURL != "" {
+			config.Endpoint = aws.String(s.EndpointURL)
+		}
+		s.svc = s3.New(credentialConfig.Credentials(), config)
+	}
+
+	s.objects = make(map[string]*object)
+	s.done = make(chan struct{})
+	if s.RotationInterval.Duration > 0 {
+		s.wg.Add(1)
+		go s.rotate()
+	}
+	return nil
+}
+
+// rotate uploads the objects older than the rotation interval, even if no
+// metrics are written.
+func (s *S3) rotate() {
+	defer s.wg.Done()
+	period := s.RotationInterval.Duration / 10
+	if period < time.Second {
+		period = time.Second
+	}
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case now := <-ticker.C:
+			s.mu.Lock()
+			if err := s.upload(now, false); err != nil {
+				log.Printf("E! S3: %s", err)
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *S3) Close() error {
+	if s.done == nil {
+		return nil
+	}
+	close(s.done)
+	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.upload(time.Now(), true)
+}
+
+func (s *S3) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *S3) Description() string {
+	return "Upload the metrics as objects to AWS S3 or a S3 compatible store"
+}
+
+func (s *S3) Write(metrics []telegraf.Metric) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The metrics are buffered only if the previous objects are uploaded,
+	// they are kept by the output otherwise.
+	now := time.Now()
+	if err := s.upload(now, false); err != nil {
+		return err
+	}
+
+	for _, m := range metrics {
+		key, err := s.objectKey(m)
+		if err != nil {
+			log.Printf("E! S3: unable to render the key of metric %s: %s", m.Name(), err)
+			continue
+		}
+		b, err := s.serializer.Serialize(m)
+		if err != nil {
+			log.Printf("E! S3: unable to serialize metric %s: %s", m.Name(), err)
+			continue
+		}
+		o, ok := s.objects[key]
+		if !ok {
+			o = &object{key: key, created: now}
+			s.objects[key] = o
+		}
+		o.metrics = append(o.metrics, m)
+		o.size += int64(len(b))
+	}
+
+	// The metrics are buffered, an object failing to be uploaded is uploaded
+	// again at the next write.
+	if err := s.upload(now, false); err != nil {
+		log.Printf("E! S3: %s", err)
+	}
+	return nil
+}
+
+func (s *S3) objectKey(m telegraf.Metric) (string, error) {
+	var b bytes.Buffer
+	if err := s.key.Execute(&b, keyMetric{m}); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// keyMetric is the data of the key templates.
+type keyMetric struct {
+	telegraf.Metric
+}
+
+// Tag returns the value of the tag, empty if the metric does not have it.
+func (m keyMetric) Tag(key string) string {
+	return m.Tags()[key]
+}
+
+// Time returns the time of the metric in UTC.
+func (m keyMetric) Time() time.Time {
+	return m.Metric.Time().UTC()
+}
+
+// ObjectID returns the placeholder of the id of the object.
+func (m keyMetric) ObjectID() string {
+	return objectIDPlaceholder
+}
+
+// upload uploads the objects to rotate, or all the objects, in the order of
+// their keys, stopping at the first failure.
+func (s *S3) upload(now time.Time, all bool) error {
+	var keys []string
+	for key, o := range s.objects {
+		if all || (s.MaxObjectSize > 0 && o.size >= s.MaxObjectSize) ||
+			(s.RotationInterval.Duration > 0 && now.Sub(o.created) >= s.RotationInterval.Duration) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := s.put(s.objects[key]); err != nil {
+			return err
+		}
+		delete(s.objects, key)
+	}
+	return nil
+}
+
+func (s *S3) put(o *object) error {
+	body, err := s.serializeBatch(o.metrics)
+	if err != nil {
+		return err
+	}
+	if s.Compression == "gzip" {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		w.Write(body)
+		if err := w.Close(); err != nil {
+			return err
+		}
+		body = buf.Bytes()
+	}
+
+	key := strings.Replace(o.key, objectIDPlaceholder,
+		strconv.FormatInt(o.created.UnixNano(), 10), -1)
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(body),
+	}
+	if s.StorageClass != "" {
+		input.StorageClass = aws.String(s.StorageClass)
+	}
+	if _, err := s.svc.PutObject(input); err != nil {
+		return fmt.Errorf("unable to upload object %s: %s", key, err)
+	}
+	log.Printf("D! S3: uploaded object %s with %d metrics", key, len(o.metrics))
+	return nil
+}
+
+func (s *S3) serializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	if bs, ok := s.serializer.(serializers.BatchSerializer); ok {
+		return bs.SerializeBatch(metrics)
+	}
+	var buf []byte
+	for _, m := range metrics {
+		b, err := s.serializer.Serialize(m)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, b...)
+	}
+	return buf, nil
+}
+
+func init() {
+	outputs.Add("s3", func() telegraf.Output {
+		return &S3{
+			Key:              `telegraf/dt={{.Time.Format "2006-01-02"}}/hour={{.Time.Format "15"}}/{{.Tag "host"}}-{{.ObjectID}}.parquet`,
+			Compression:      "none",
+			MaxObjectSize:    16 * 1024 * 1024,
+			RotationInterval: internal.Duration{Duration: 5 * time.Minute},
+		}
+	})
+}
@@ -0,0 +1,149 @@
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/serializers"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockClient records the objects uploaded, failing while err is set.
+type mockClient struct {
+	objects map[string][]byte
+	puts    int
+	err     error
+}
+
+func (c *mockClient) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	c.puts++
+	if c.err != nil {
+		return nil, c.err
+	}
+	b, err := ioutil.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	if c.objects == nil {
+		c.objects = make(map[string][]byte)
+	}
+	c.objects[*input.Key] = b
+	return &s3.PutObjectOutput{}, nil
+}
+
+func testMetric(t *testing.T, host string, value float64, tm time.Time) telegraf.Metric {
+	m, err := metric.New("cpu",
+		map[string]string{"host": host},
+		map[string]interface{}{"usage_idle": value},
+		tm)
+	require.NoError(t, err)
+	return m
+}
+
+func newOutput(t *testing.T, client *mockClient) *S3 {
+	serializer, _ := serializers.NewInfluxSerializer()
+	s := &S3{
+		Bucket:     "telemetry",
+		Key:        `telemetry/dt={{.Time.Format "2006-01-02"}}/hour={{.Time.Format "15"}}/{{.Tag "host"}}.influx`,
+		serializer: serializer,
+		svc:        client,
+	}
+	require.NoError(t, s.Connect())
+	return s
+}
+
+func TestWriteClose(t *testing.T) {
+	client := &mockClient{}
+	s := newOutput(t, client)
+
+	tm := time.Date(2024, 5, 1, 13, 30, 0, 0, time.FixedZone("CEST", 2*3600))
+	m1 := testMetric(t, "a", 1, tm)
+	m2 := testMetric(t, "b", 2, tm)
+	m3 := testMetric(t, "a", 3, tm.Add(time.Minute))
+	require.NoError(t, s.Write([]telegraf.Metric{m1, m2}))
+	require.NoError(t, s.Write([]telegraf.Metric{m3}))
+	assert.Equal(t, 0, client.puts)
+
+	require.NoError(t, s.Close())
+	assert.Equal(t, map[string][]byte{
+		"telemetry/dt=2024-05-01/hour=11/a.influx": []byte(m1.String() + m3.String()),
+		"telemetry/dt=2024-05-01/hour=11/b.influx": []byte(m2.String()),
+	}, client.objects)
+}
+
+func TestRotation(t *testing.T) {
+	client := &mockClient{}
+	s := newOutput(t, client)
+	s.Key = `{{.Name}}-{{.ObjectID}}.influx.gz`
+	s.Compression = "gzip"
+	s.MaxObjectSize = 100
+	require.NoError(t, s.Connect())
+
+	tm := time.Unix(1500000000, 0)
+	var metrics []telegraf.Metric
+	for i := 0; i < 5; i++ {
+		metrics = append(metrics, testMetric(t, "a", float64(i), tm))
+	}
+	// The object is uploaded once its size reaches 100 bytes.
+	require.NoError(t, s.Write(metrics))
+	require.Len(t, client.objects, 1)
+	var first string
+	for key, body := range client.objects {
+		first = key
+		assert.Regexp(t, `^cpu-[0-9]+\.influx\.gz$`, key)
+		r, err := gzip.NewReader(bytes.NewReader(body))
+		require.NoError(t, err)
+		b, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		var expected string
+		for _, m := range metrics {
+			expected += m.String()
+		}
+		assert.Equal(t, expected, string(b))
+	}
+
+	// The object is uploaded once older than the rotation interval.
+	s.RotationInterval = internal.Duration{Duration: time.Minute}
+	require.NoError(t, s.Write(metrics[:1]))
+	require.Len(t, client.objects, 1)
+	require.NoError(t, s.upload(time.Now().Add(time.Minute), false))
+	require.Len(t, client.objects, 2)
+	for key := range client.objects {
+		if key != first {
+			assert.Regexp(t, `^cpu-[0-9]+\.influx\.gz$`, key)
+		}
+	}
+}
+
+func TestUploadFailure(t *testing.T) {
+	client := &mockClient{err: fmt.Errorf("service unavailable")}
+	s := newOutput(t, client)
+	s.MaxObjectSize = 1
+
+	tm := time.Unix(1500000000, 0)
+	m1 := testMetric(t, "a", 1, tm)
+	m2 := testMetric(t, "a", 2, tm)
+	// The metrics are buffered, the upload is retried at the next write.
+	require.NoError(t, s.Write([]telegraf.Metric{m1}))
+	assert.Equal(t, 1, client.puts)
+	// The object still failing to be uploaded, the metrics are not buffered.
+	require.Error(t, s.Write([]telegraf.Metric{m2}))
+	assert.Equal(t, 2, client.puts)
+
+	client.err = nil
+	require.NoError(t, s.Write([]telegraf.Metric{m2}))
+	assert.Equal(t, map[string][]byte{
+		"telemetry/dt=2017-07-14/hour=02/a.influx": []byte(m2.String()),
+	}, client.objects)
+}
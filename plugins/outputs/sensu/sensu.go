@@ -0,0 +1,454 @@
+package sensu
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+// The statuses of the checks, as in Sensu.
+const (
+	statusOK       = 0
+	statusWarning  = 1
+	statusCritical = 2
+)
+
+var statusNames = map[int]string{
+	statusOK:       "ok",
+	statusWarning:  "warning",
+	statusCritical: "critical",
+}
+
+// Rule is a check of a field of the metrics, its status being warning or
+// critical when the field crosses the thresholds.
+type Rule struct {
+	// Check is the name of the check, <measurement>_<field> if empty.
+	Check       string
+	Measurement string
+	Field       string
+	Warning     *float64
+	Critical    *float64
+	// Below is true if the status is not OK below the thresholds.
+	Below bool
+
+	measurement filter.Filter
+}
+
+// Sensu sends the events of the checks of the metrics to the events API of
+// Sensu Go, or to a webhook.
+type Sensu struct {
+	URL       string `toml:"url"`
+	APIKey    string `toml:"api_key"`
+	Namespace string
+	// Format is the format of the events, "sensu" or "json".
+	Format   string
+	Timeout  internal.Duration
+	Headers  map[string]string
+	Handlers []string
+	// EntityTag is the tag of the entity of the events, EntityName being the
+	// entity of the metrics without it.
+	EntityTag  string
+	EntityName string
+	// SendAll sends an event for each metric of the checks not OK, instead
+	// of only when their status changes.
+	SendAll bool
+	Rules   []*Rule `toml:"rule"`
+
+	// Path to CA file
+	SSLCA string `toml:"ssl_ca"`
+	// Path to host cert file
+	SSLCert string `toml:"ssl_cert"`
+	// Path to cert key file
+	SSLKey string `toml:"ssl_key"`
+	// Use SSL but skip chain & host verification
+	InsecureSkipVerify bool
+
+	client *http.Client
+	// states are the states of the checks, by check and series, the checks
+	// without state being OK.
+	states map[string]state
+}
+
+// state is the state of a check of a series.
+type state struct {
+	status      int
+	occurrences int
+}
+
+var sampleConfig = `
+  ## URL of the events, ie the events API of the Sensu Go backend, or of a
+  ## webhook.
+  url = "http://127.0.0.1:8080/api/core/v2/namespaces/default/events"
+
+  ## API key of the Sensu Go backend.
+  # api_key = ""
+
+  ## Format of the events, "sensu" for the Sensu Go events, or "json" for
+  ## flat JSON events.
+  # format = "sensu"
+
+  ## Timeout of the requests.
+  # timeout = "5s"
+
+  ## Additional HTTP headers of the requests.
+  # [outputs.sensu.headers]
+  #   X-Custom-Header = "value"
+
+  ## Handlers of the Sensu Go events.
+  # handlers = []
+
+  ## Tag of the entity of the events, the entity being entity_name for the
+  ## metrics without it, the hostname by default.
+  # entity_tag = "host"
+  # entity_name = ""
+
+  ## Send an event for each metric of the checks not OK, instead of only
+  ## when their status changes.
+  # send_all = false
+
+  ## Checks of the fields of the metrics, their status being warning or
+  ## critical when the field is above the thresholds, or below them with
+  ## below = true. The check is named <measurement>_<field> by default.
+  [[outputs.sensu.rule]]
+    # check = "cpu_usage_idle"
+    measurement = "cpu"
+    field = "usage_idle"
+    warning = 20.0
+    critical = 10.0
+    below = true
+
+  ## Optional SSL Config
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  ## Use SSL but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+func (s *Sensu) Connect() error {
+	if s.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+	switch s.Format {
+	case "":
+		s.Format = "sensu"
+	case "sensu", "json":
+	default:
+		return fmt.Errorf("invalid format %q", s.Format)
+	}
+	if len(s.Rules) == 0 {
+		return fmt.Errorf("at least one rule is required")
+	}
+	for _, r := range s.Rules {
+		if r.Measurement == "" || r.Field == "" {
+			return fmt.Errorf("measurement and field are required in the rules")
+		}
+		if r.Warning == nil && r.Critical == nil {
+			return fmt.Errorf("rule of %s %s has no threshold", r.Measurement, r.Field)
+		}
+		f, err := filter.Compile([]string{r.Measurement})
+		if err != nil {
+			return err
+		}
+		r.measurement = f
+	}
+	if s.EntityName == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return err
+		}
+		s.EntityName = hostname
+	}
+
+	tlsConfig, err := internal.GetTLSConfig(
+		s.SSLCert, s.SSLKey, s.SSLCA, s.InsecureSkipVerify)
+	if err != nil {
+		return err
+	}
+	s.client = &http.Client{
+		Timeout: s.Timeout.Duration,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}
+	s.states = make(map[string]state)
+	return nil
+}
+
+func (s *Sensu) Close() error {
+	return nil
+}
+
+func (s *Sensu) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Sensu) Description() string {
+	return "Send events of threshold checks of the metrics to Sensu Go or a webhook"
+}
+
+// event is a check of a metric.
+type event struct {
+	check       string
+	entity      string
+	status      int
+	previous    int
+	occurrences int
+	output      string
+	measurement string
+	field       string
+	value       float64
+	tags        map[string]string
+	time        time.Time
+}
+
+// Write evaluates the rules of the metrics, sending the events of the checks
+// whose status changed. The states of the checks are only updated once the
+// events are sent, the metrics being evaluated again when they are retried.
+func (s *Sensu) Write(metrics []telegraf.Metric) error {
+	var events []*event
+	states := make(map[string]state)
+	for _, m := range metrics {
+		for _, r := range s.Rules {
+			if e := s.evaluate(r, m, states); e != nil {
+				events = append(events, e)
+			}
+		}
+	}
+	for _, e := range events {
+		if err := s.send(e); err != nil {
+			return err
+		}
+	}
+	for key, st := range states {
+		s.states[key] = st
+	}
+	return nil
+}
+
+// evaluate returns the event of the check of the rule of the metric, nil if
+// it does not apply or if its status did not change, the states of the
+// checks updated being set in states.
+func (s *Sensu) evaluate(r *Rule, m telegraf.Metric, states map[string]state) *event {
+	if !r.measurement.Match(m.Name()) {
+		return nil
+	}
+	value, ok := floatValue(m.Fields()[r.Field])
+	if !ok {
+		return nil
+	}
+
+	check := r.Check
+	if check == "" {
+		check = m.Name() + "_" + r.Field
+	}
+	status, threshold := r.status(value)
+	key := check + "\x00" + seriesKey(m)
+	st, ok := states[key]
+	if !ok {
+		// A check without state is OK.
+		st = s.states[key]
+	}
+	previous := st.status
+	if status == previous {
+		st.occurrences++
+	} else {
+		st.status = status
+		st.occurrences = 1
+	}
+	states[key] = st
+	if status == previous && !(s.SendAll && status != statusOK) {
+		return nil
+	}
+
+	entity := s.EntityName
+	if v, ok := m.Tags()[s.EntityTag]; ok && s.EntityTag != "" {
+		entity = v
+	}
+	output := fmt.Sprintf("%s %s is %s", m.Name(), r.Field, formatValue(value))
+	if status != statusOK {
+		direction := "above"
+		if r.Below {
+			direction = "below"
+		}
+		output += fmt.Sprintf(", %s threshold %s %s",
+			statusNames[status], direction, formatValue(threshold))
+	}
+	return &event{
+		check:       check,
+		entity:      entity,
+		status:      status,
+		previous:    previous,
+		occurrences: st.occurrences,
+		output:      output,
+		measurement: m.Name(),
+		field:       r.Field,
+		value:       value,
+		tags:        m.Tags(),
+		time:        m.Time(),
+	}
+}
+
+// status returns the status of the value, and the threshold crossed.
+func (r *Rule) status(value float64) (int, float64) {
+	crossed := func(threshold *float64) bool {
+		if threshold == nil {
+			return false
+		}
+		if r.Below {
+			return value < *threshold
+		}
+		return value > *threshold
+	}
+	if crossed(r.Critical) {
+		return statusCritical, *r.Critical
+	}
+	if crossed(r.Warning) {
+		return statusWarning, *r.Warning
+	}
+	return statusOK, 0
+}
+
+func seriesKey(m telegraf.Metric) string {
+	tags := m.Tags()
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b bytes.Buffer
+	b.WriteString(m.Name())
+	for _, k := range keys {
+		b.WriteByte(0)
+		b.WriteString(k)
+		b.WriteByte(0)
+		b.WriteString(tags[k])
+	}
+	return b.String()
+}
+
+func floatValue(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// body returns the JSON of the event in the format of the output.
+func (s *Sensu) body(e *event) ([]byte, error) {
+	if s.Format == "json" {
+		return json.Marshal(map[string]interface{}{
+			"check":           e.check,
+			"entity":          e.entity,
+			"status":          e.status,
+			"severity":        statusNames[e.status],
+			"previous_status": e.previous,
+			"occurrences":     e.occurrences,
+			"output":          e.output,
+			"measurement":     e.measurement,
+			"field":           e.field,
+			"value":           e.value,
+			"tags":            e.tags,
+			"timestamp":       e.time.Unix(),
+		})
+	}
+
+	handlers := s.Handlers
+	if handlers == nil {
+		handlers = []string{}
+	}
+	return json.Marshal(map[string]interface{}{
+		"entity": map[string]interface{}{
+			"entity_class": "proxy",
+			"metadata": map[string]interface{}{
+				"name": e.entity,
+			},
+		},
+		"check": map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"name":   e.check,
+				"labels": e.tags,
+			},
+			"status":      e.status,
+			"output":      e.output,
+			"occurrences": e.occurrences,
+			"handlers":    handlers,
+			"executed":    e.time.Unix(),
+			"issued":      e.time.Unix(),
+			"interval":    0,
+		},
+	})
+}
+
+func (s *Sensu) send(e *event) error {
+	body, err := s.body(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Telegraf")
+	for k, v := range s.Headers {
+		req.Header.Set(k, v)
+	}
+	if s.APIKey != "" {
+		req.Header.Set("Authorization", "Key "+s.APIKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("sending event of %s to %s failed with status code %d: %s",
+		e.check, s.URL, resp.StatusCode, strings.TrimSpace(string(msg)))
+	if resp.StatusCode == http.StatusBadRequest {
+		// The event is invalid, sending it again would fail again.
+		log.Printf("E! Sensu: %s", err)
+		return nil
+	}
+	return err
+}
+
+func init() {
+	outputs.Add("sensu", func() telegraf.Output {
+		return &Sensu{
+			Format:    "sensu",
+			Timeout:   internal.Duration{Duration: 5 * time.Second},
+			EntityTag: "host",
+		}
+	})
+}
@@ -0,0 +1,153 @@
+package sensu
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func cpu(t *testing.T, host string, idle float64) telegraf.Metric {
+	m, err := metric.New("cpu",
+		map[string]string{"host": host, "cpu": "cpu-total"},
+		map[string]interface{}{"usage_idle": idle},
+		time.Unix(1500000000, 0))
+	require.NoError(t, err)
+	return m
+}
+
+func TestWriteSensu(t *testing.T) {
+	var events []map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/core/v2/namespaces/default/events", r.URL.Path)
+		assert.Equal(t, "Key secret", r.Header.Get("Authorization"))
+		var e map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&e))
+		events = append(events, e)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	s := &Sensu{
+		URL:       ts.URL + "/api/core/v2/namespaces/default/events",
+		APIKey:    "secret",
+		EntityTag: "host",
+		Handlers:  []string{"slack"},
+		Rules: []*Rule{{
+			Measurement: "cpu",
+			Field:       "usage_idle",
+			Warning:     float(20),
+			Critical:    float(10),
+			Below:       true,
+		}},
+	}
+	require.NoError(t, s.Connect())
+
+	require.NoError(t, s.Write([]telegraf.Metric{cpu(t, "a", 50), cpu(t, "a", 15)}))
+	require.Len(t, events, 1)
+	assert.Equal(t, map[string]interface{}{
+		"entity": map[string]interface{}{
+			"entity_class": "proxy",
+			"metadata":     map[string]interface{}{"name": "a"},
+		},
+		"check": map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"name":   "cpu_usage_idle",
+				"labels": map[string]interface{}{"host": "a", "cpu": "cpu-total"},
+			},
+			"status":      float64(1),
+			"output":      "cpu usage_idle is 15, warning threshold below 20",
+			"occurrences": float64(1),
+			"handlers":    []interface{}{"slack"},
+			"executed":    float64(1500000000),
+			"issued":      float64(1500000000),
+			"interval":    float64(0),
+		},
+	}, events[0])
+
+	// The events are only sent when the status changes.
+	require.NoError(t, s.Write([]telegraf.Metric{cpu(t, "a", 16), cpu(t, "a", 5), cpu(t, "b", 90)}))
+	require.Len(t, events, 2)
+	check := events[1]["check"].(map[string]interface{})
+	assert.Equal(t, float64(2), check["status"])
+	assert.Equal(t, "cpu usage_idle is 5, critical threshold below 10", check["output"])
+
+	require.NoError(t, s.Write([]telegraf.Metric{cpu(t, "a", 80)}))
+	require.Len(t, events, 3)
+	check = events[2]["check"].(map[string]interface{})
+	assert.Equal(t, float64(0), check["status"])
+	assert.Equal(t, "cpu usage_idle is 80", check["output"])
+}
+
+func TestWriteJSON(t *testing.T) {
+	var events []map[string]interface{}
+	status := http.StatusServiceUnavailable
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&e))
+		if status == http.StatusOK {
+			events = append(events, e)
+		}
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	s := &Sensu{
+		URL:        ts.URL,
+		Format:     "json",
+		EntityName: "telegraf",
+		SendAll:    true,
+		Rules: []*Rule{{
+			Check:       "cpu_busy",
+			Measurement: "c*",
+			Field:       "usage_idle",
+			Critical:    float(10),
+			Below:       true,
+		}},
+	}
+	require.NoError(t, s.Connect())
+
+	// The events failing to be sent are sent again with the metrics.
+	metrics := []telegraf.Metric{cpu(t, "a", 5), cpu(t, "a", 6)}
+	require.Error(t, s.Write(metrics))
+	status = http.StatusOK
+	require.NoError(t, s.Write(metrics))
+	require.Len(t, events, 2)
+	assert.Equal(t, map[string]interface{}{
+		"check":           "cpu_busy",
+		"entity":          "telegraf",
+		"status":          float64(2),
+		"severity":        "critical",
+		"previous_status": float64(2),
+		"occurrences":     float64(2),
+		"output":          "cpu usage_idle is 6, critical threshold below 10",
+		"measurement":     "cpu",
+		"field":           "usage_idle",
+		"value":           float64(6),
+		"tags":            map[string]interface{}{"host": "a", "cpu": "cpu-total"},
+		"timestamp":       float64(1500000000),
+	}, events[1])
+}
+
+func TestConnectErrors(t *testing.T) {
+	s := &Sensu{URL: "http://localhost"}
+	assert.Error(t, s.Connect())
+
+	s.Rules = []*Rule{{Measurement: "cpu", Field: "usage_idle"}}
+	assert.Error(t, s.Connect())
+
+	s.Rules[0].Warning = float(20)
+	s.Format = "xml"
+	assert.Error(t, s.Connect())
+}
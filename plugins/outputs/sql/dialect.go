@@ -0,0 +1,108 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// dialect is the SQL dialect of a driver.
+type dialect struct {
+	// quote returns the name as a quoted identifier.
+	quote func(name string) string
+	// placeholder returns the placeholder of the nth argument, from 1.
+	placeholder func(n int) string
+	// emptySelect is the query returning the columns of a table, without
+	// rows, failing if the table does not exist.
+	emptySelect string
+	// addColumn is the default statement adding a column to a table.
+	addColumn string
+	// types are the default column types.
+	types Convert
+}
+
+func quoteDouble(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+func questionMark(int) string {
+	return "?"
+}
+
+// ansiDialect is the dialect of the drivers without a specific dialect.
+var ansiDialect = &dialect{
+	quote:       quoteDouble,
+	placeholder: questionMark,
+	emptySelect: "SELECT * FROM %s LIMIT 0",
+	addColumn:   "ALTER TABLE {TABLE} ADD COLUMN {COLUMN}",
+	types: Convert{
+		Integer:   "BIGINT",
+		Real:      "DOUBLE PRECISION",
+		Text:      "TEXT",
+		Timestamp: "TIMESTAMP",
+		Bool:      "BOOLEAN",
+	},
+}
+
+// dialects are the dialects of the drivers.
+var dialects = map[string]*dialect{
+	"mysql": {
+		quote: func(name string) string {
+			return "`" + strings.Replace(name, "`", "``", -1) + "`"
+		},
+		placeholder: questionMark,
+		emptySelect: "SELECT * FROM %s LIMIT 0",
+		addColumn:   "ALTER TABLE {TABLE} ADD COLUMN {COLUMN}",
+		types: Convert{
+			Integer:   "BIGINT",
+			Real:      "DOUBLE",
+			Text:      "TEXT",
+			Timestamp: "DATETIME(6)",
+			Bool:      "BOOL",
+		},
+	},
+	"pgx": {
+		quote: quoteDouble,
+		placeholder: func(n int) string {
+			return fmt.Sprintf("$%d", n)
+		},
+		emptySelect: "SELECT * FROM %s LIMIT 0",
+		addColumn:   "ALTER TABLE {TABLE} ADD COLUMN {COLUMN}",
+		types: Convert{
+			Integer:   "BIGINT",
+			Real:      "DOUBLE PRECISION",
+			Text:      "TEXT",
+			Timestamp: "TIMESTAMP WITH TIME ZONE",
+			Bool:      "BOOLEAN",
+		},
+	},
+	"mssql": {
+		quote: func(name string) string {
+			return "[" + strings.Replace(name, "]", "]]", -1) + "]"
+		},
+		placeholder: func(n int) string {
+			return fmt.Sprintf("@p%d", n)
+		},
+		emptySelect: "SELECT TOP 0 * FROM %s",
+		addColumn:   "ALTER TABLE {TABLE} ADD {COLUMN}",
+		types: Convert{
+			Integer:   "BIGINT",
+			Real:      "FLOAT",
+			Text:      "NVARCHAR(MAX)",
+			Timestamp: "DATETIME2",
+			Bool:      "BIT",
+		},
+	},
+	"sqlite3": {
+		quote:       quoteDouble,
+		placeholder: questionMark,
+		emptySelect: "SELECT * FROM %s LIMIT 0",
+		addColumn:   "ALTER TABLE {TABLE} ADD COLUMN {COLUMN}",
+		types: Convert{
+			Integer:   "INTEGER",
+			Real:      "REAL",
+			Text:      "TEXT",
+			Timestamp: "TIMESTAMP",
+			Bool:      "BOOLEAN",
+		},
+	},
+}
@@ -0,0 +1,200 @@
+package sql
+
+import (
+	gosql "database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDB is the database of the fake driver, recording the statements and
+// the rows inserted, its tables having the columns of the test.
+type fakeDB struct {
+	mu         sync.Mutex
+	tables     map[string][]string
+	statements []string
+	inserted   [][]driver.Value
+	commits    int
+}
+
+var db = &fakeDB{}
+
+func init() {
+	gosql.Register("fakedb", fakeDriver{})
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	return &fakeConn{}, nil
+}
+
+type fakeConn struct{}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.commits++
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	return nil
+}
+
+type fakeStmt struct {
+	query    string
+	executed bool
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if strings.HasPrefix(s.query, "INSERT") {
+		db.inserted = append(db.inserted, args)
+	}
+	// The prepared statements are recorded once.
+	if !s.executed {
+		db.statements = append(db.statements, s.query)
+		s.executed = true
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for table, columns := range db.tables {
+		if s.query == fmt.Sprintf(`SELECT * FROM "%s" LIMIT 0`, table) {
+			return &fakeRows{columns: columns}, nil
+		}
+	}
+	return nil, fmt.Errorf("no such table")
+}
+
+type fakeRows struct {
+	columns []string
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	return io.EOF
+}
+
+func testMetrics(t *testing.T) []telegraf.Metric {
+	now := time.Unix(1500000000, 0).UTC()
+	m1, err := metric.New("cpu", map[string]string{"host": "a"},
+		map[string]interface{}{"usage": 1.5, "count": int64(2)}, now)
+	require.NoError(t, err)
+	m2, err := metric.New("cpu", map[string]string{"host": "b", "cpu": "cpu0"},
+		map[string]interface{}{"usage": 2.5, "ok": true, "host": "dropped"}, now)
+	require.NoError(t, err)
+	m3, err := metric.New("mem", map[string]string{"host": "a"},
+		map[string]interface{}{"free": int64(10), "state": "ok"}, now)
+	require.NoError(t, err)
+	return []telegraf.Metric{m1, m2, m3}
+}
+
+func newOutput() *SQL {
+	return &SQL{
+		Driver:          "fakedb",
+		DataSourceName:  "test",
+		TimestampColumn: "timestamp",
+		TableTemplate:   "CREATE TABLE {TABLE}({COLUMNS})",
+	}
+}
+
+func TestWrite(t *testing.T) {
+	*db = fakeDB{tables: map[string][]string{"mem": {"timestamp", "host"}}}
+
+	s := newOutput()
+	require.NoError(t, s.Connect())
+	defer s.Close()
+	require.NoError(t, s.Write(testMetrics(t)))
+
+	assert.Equal(t, []string{
+		`CREATE TABLE "cpu"("timestamp" TIMESTAMP, "host" TEXT, "count" BIGINT, "usage" DOUBLE PRECISION, "cpu" TEXT, "ok" BOOLEAN)`,
+		`INSERT INTO "cpu" ("timestamp", "host", "count", "usage", "cpu", "ok") VALUES (?, ?, ?, ?, ?, ?)`,
+		`ALTER TABLE "mem" ADD COLUMN "free" BIGINT`,
+		`ALTER TABLE "mem" ADD COLUMN "state" TEXT`,
+		`INSERT INTO "mem" ("timestamp", "host", "free", "state") VALUES (?, ?, ?, ?)`,
+	}, db.statements)
+	now := time.Unix(1500000000, 0).UTC()
+	assert.Equal(t, [][]driver.Value{
+		{now, "a", int64(2), 1.5, nil, nil},
+		{now, "b", nil, 2.5, "cpu0", true},
+		{now, "a", int64(10), "ok"},
+	}, db.inserted)
+	assert.Equal(t, 2, db.commits)
+
+	// The columns of the tables are kept.
+	db.statements = nil
+	require.NoError(t, s.Write(testMetrics(t)[:1]))
+	assert.Equal(t, []string{
+		`INSERT INTO "cpu" ("timestamp", "host", "count", "usage") VALUES (?, ?, ?, ?)`,
+	}, db.statements)
+}
+
+func TestConvert(t *testing.T) {
+	*db = fakeDB{}
+
+	s := newOutput()
+	s.Convert = Convert{Integer: "INT", Text: "VARCHAR(255)"}
+	s.TableUpdateTemplate = "ALTER TABLE {TABLE} ADD {COLUMN}"
+	require.NoError(t, s.Connect())
+	defer s.Close()
+	require.NoError(t, s.Write(testMetrics(t)[2:]))
+	assert.Equal(t,
+		`CREATE TABLE "mem"("timestamp" TIMESTAMP, "host" VARCHAR(255), "free" INT, "state" VARCHAR(255))`,
+		db.statements[0])
+}
+
+func TestDialects(t *testing.T) {
+	b := &batch{table: "c]p`u\"", columns: []column{{"timestamp", ""}, {"host", ""}}}
+	for driver, expected := range map[string]string{
+		"pgx":     `INSERT INTO "c]p` + "`" + `u""" ("timestamp", "host") VALUES ($1, $2)`,
+		"mysql":   "INSERT INTO `c]p``u\"` (`timestamp`, `host`) VALUES (?, ?)",
+		"mssql":   "INSERT INTO [c]]p`u\"] ([timestamp], [host]) VALUES (@p1, @p2)",
+		"sqlite3": `INSERT INTO "c]p` + "`" + `u""" ("timestamp", "host") VALUES (?, ?)`,
+	} {
+		s := &SQL{dialect: dialects[driver]}
+		assert.Equal(t, expected, s.insertSQL(b), driver)
+	}
+}
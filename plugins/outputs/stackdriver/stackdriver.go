@@ -0,0 +1,620 @@
+package stackdriver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/gcp"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+const (
+	// monitoringScope is the OAuth2 scope of the tokens of the requests.
+	monitoringScope = "https://www.googleapis.com/auth/monitoring"
+
+	// maxTimeSeriesPerRequest is the maximum number of time series of a
+	// create request, each having a single point.
+	maxTimeSeriesPerRequest = 200
+	// minPointInterval is the minimum interval between the points of a time
+	// series, the points more frequent being rejected.
+	minPointInterval = 5 * time.Second
+	// defaultRetryAfter is the time the writes are suspended after a
+	// request is rate limited, without a Retry-After header.
+	defaultRetryAfter = time.Minute
+)
+
+var (
+	invalidTypeCharRE  = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+	invalidLabelCharRE = regexp.MustCompile(`[^a-z0-9_]`)
+)
+
+// ResourceMapping is the monitored resource of the time series of the
+// measurements matching Measurement.
+type ResourceMapping struct {
+	Measurement string
+	Type        string
+	Labels      map[string]string
+	// LabelTags are the tags of the resource labels, by label, the tags
+	// not being metric labels.
+	LabelTags map[string]string
+
+	measurement filter.Filter
+}
+
+// Stackdriver writes the metrics as custom metrics to Google Cloud
+// Monitoring, a time series per field.
+type Stackdriver struct {
+	Project         string
+	Namespace       string
+	CredentialsFile string
+	// Endpoint is the URL of the Cloud Monitoring API, the requests being
+	// made without authentication with the http scheme.
+	Endpoint string
+	Timeout  internal.Duration
+	// ResourceType, ResourceLabels and ResourceLabelTags are the monitored
+	// resource of the measurements without resource mapping.
+	ResourceType      string
+	ResourceLabels    map[string]string
+	ResourceLabelTags map[string]string
+	ResourceMappings  []*ResourceMapping `toml:"resource_mapping"`
+	// CreateMetricDescriptors creates the descriptors of the metrics, with
+	// their labels, kind and value type, before their first time series.
+	CreateMetricDescriptors bool
+
+	client *http.Client
+	tokens *gcp.TokenSource
+	// descriptors are the descriptors created, by metric type.
+	descriptors map[string]*descriptor
+	// series are the states of the time series written to.
+	series map[string]*seriesState
+	// retryAfter is the time the writes are suspended until, after a
+	// request was rate limited.
+	retryAfter time.Time
+}
+
+// descriptor is a metric descriptor created.
+type descriptor struct {
+	kind      string
+	valueType string
+	labels    map[string]bool
+}
+
+// seriesState is the state of a time series, the time of its last point and
+// the start time of its cumulative points.
+type seriesState struct {
+	last  time.Time
+	start time.Time
+}
+
+var sampleConfig = `
+  ## GCP project of the metrics.
+  project = "my-project"
+
+  ## Namespace of the metric types, custom.googleapis.com/<namespace>/
+  ## <measurement>/<field>.
+  # namespace = "telegraf"
+
+  ## Key file of the service account writing the metrics. If empty, the
+  ## service account of the GCE instance is used.
+  # credentials_file = "path/to/my/creds.json"
+
+  ## URL of the Cloud Monitoring API.
+  # endpoint = "https://monitoring.googleapis.com"
+
+  ## Timeout of the requests.
+  # timeout = "5s"
+
+  ## Monitored resource of the time series, its labels being resource_labels
+  ## and the tags of resource_label_tags, by label, which are not metric
+  ## labels. The project_id label of the global resource is the project.
+  # resource_type = "global"
+  # [outputs.stackdriver.resource_labels]
+  #   location = "us-central1-a"
+  # [outputs.stackdriver.resource_label_tags]
+  #   node_id = "host"
+
+  ## Monitored resources of the measurements, the first mapping matching
+  ## the measurement being used instead of the resource above.
+  # [[outputs.stackdriver.resource_mapping]]
+  #   measurement = "docker*"
+  #   type = "generic_node"
+  #   [outputs.stackdriver.resource_mapping.labels]
+  #     namespace = "docker"
+  #   [outputs.stackdriver.resource_mapping.label_tags]
+  #     node_id = "host"
+  #     location = "zone"
+
+  ## Create the descriptors of the metrics, instead of letting Cloud
+  ## Monitoring create them from their first time series.
+  # create_metric_descriptors = true
+`
+
+func (s *Stackdriver) Connect() error {
+	if s.Project == "" {
+		return fmt.Errorf("project is required")
+	}
+	if s.ResourceType == "" {
+		s.ResourceType = "global"
+	}
+	for _, r := range s.ResourceMappings {
+		if r.Measurement == "" || r.Type == "" {
+			return fmt.Errorf("measurement and type are required in the resource mappings")
+		}
+		f, err := filter.Compile([]string{r.Measurement})
+		if err != nil {
+			return err
+		}
+		r.measurement = f
+	}
+
+	s.client = &http.Client{
+		Timeout: s.Timeout.Duration,
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+		},
+	}
+	if !strings.HasPrefix(s.Endpoint, "http://") {
+		var err error
+		s.tokens, err = gcp.NewTokenSource(s.client, s.CredentialsFile, monitoringScope)
+		if err != nil {
+			return err
+		}
+	}
+	s.descriptors = make(map[string]*descriptor)
+	s.series = make(map[string]*seriesState)
+	return nil
+}
+
+func (s *Stackdriver) Close() error {
+	return nil
+}
+
+func (s *Stackdriver) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Stackdriver) Description() string {
+	return "Write metrics to Google Cloud Monitoring (Stackdriver)"
+}
+
+// timeSeries is a time series of the create requests.
+type timeSeries struct {
+	Metric     seriesMetric      `json:"metric"`
+	Resource   monitoredResource `json:"resource"`
+	MetricKind string            `json:"metricKind"`
+	ValueType  string            `json:"valueType"`
+	Points     []point           `json:"points"`
+
+	key  string
+	time time.Time
+}
+
+type seriesMetric struct {
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type monitoredResource struct {
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type point struct {
+	Interval interval   `json:"interval"`
+	Value    typedValue `json:"value"`
+}
+
+type interval struct {
+	StartTime string `json:"startTime,omitempty"`
+	EndTime   string `json:"endTime"`
+}
+
+type typedValue struct {
+	// Int64Value is a string, as the 64 bits integers of the JSON of the
+	// API.
+	Int64Value  *string  `json:"int64Value,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	StringValue *string  `json:"stringValue,omitempty"`
+}
+
+// Write creates the time series of the fields of the metrics, with requests
+// of a point per time series, the points of a time series being written in
+// the order of their time.
+func (s *Stackdriver) Write(metrics []telegraf.Metric) error {
+	if time.Now().Before(s.retryAfter) {
+		return fmt.Errorf("Stackdriver: rate limited, the writes are suspended until %s",
+			s.retryAfter.Format(time.RFC3339))
+	}
+
+	// The points of each time series, in the order of the time series.
+	var keys []string
+	points := make(map[string][]*timeSeries)
+	for _, m := range metrics {
+		series, err := s.timeSeries(m)
+		if err != nil {
+			return err
+		}
+		for _, ts := range series {
+			if _, ok := points[ts.key]; !ok {
+				keys = append(keys, ts.key)
+			}
+			points[ts.key] = append(points[ts.key], ts)
+		}
+	}
+
+	// The nth request of a round has the nth point of each time series, a
+	// round being sent in requests of at most maxTimeSeriesPerRequest.
+	var rounds [][]*timeSeries
+	for _, key := range keys {
+		series := s.accepted(key, points[key])
+		for i, ts := range series {
+			if i == len(rounds) {
+				rounds = append(rounds, nil)
+			}
+			rounds[i] = append(rounds[i], ts)
+		}
+	}
+	for _, round := range rounds {
+		for len(round) > 0 {
+			n := len(round)
+			if n > maxTimeSeriesPerRequest {
+				n = maxTimeSeriesPerRequest
+			}
+			if err := s.create(round[:n]); err != nil {
+				return err
+			}
+			round = round[n:]
+		}
+	}
+	return nil
+}
+
+// accepted returns the points of the time series in the order of their time,
+// those older than its last point or within minPointInterval of the
+// previous point being dropped.
+func (s *Stackdriver) accepted(key string, series []*timeSeries) []*timeSeries {
+	sort.SliceStable(series, func(i, j int) bool {
+		return series[i].time.Before(series[j].time)
+	})
+	last := time.Time{}
+	if st, ok := s.series[key]; ok {
+		last = st.last
+	}
+	accepted := series[:0]
+	for _, ts := range series {
+		if !last.IsZero() && ts.time.Sub(last) < minPointInterval {
+			log.Printf("D! Stackdriver: dropping point of %s at %s, too close to the previous point",
+				ts.Metric.Type, ts.time.Format(time.RFC3339Nano))
+			continue
+		}
+		last = ts.time
+		accepted = append(accepted, ts)
+	}
+	return accepted
+}
+
+// timeSeries returns the time series of the fields of the metric, each with
+// its point.
+func (s *Stackdriver) timeSeries(m telegraf.Metric) ([]*timeSeries, error) {
+	resource, used := s.resource(m)
+	labels := make(map[string]string)
+	for k, v := range m.Tags() {
+		if used[k] {
+			continue
+		}
+		labels[labelKey(k)] = v
+	}
+	resourceKey := resource.Type + "\x00" + labelsKey(resource.Labels)
+
+	fields := m.Fields()
+	names := make([]string, 0, len(fields))
+	for k := range fields {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var series []*timeSeries
+	for _, field := range names {
+		v := fields[field]
+		metricType := fmt.Sprintf("custom.googleapis.com/%s/%s/%s", s.Namespace,
+			invalidTypeCharRE.ReplaceAllString(m.Name(), "_"),
+			invalidTypeCharRE.ReplaceAllString(field, "_"))
+		value, valueType, ok := typed(v)
+		if !ok {
+			continue
+		}
+		kind := "GAUGE"
+		if m.Type() == telegraf.Counter && (valueType == "INT64" || valueType == "DOUBLE") {
+			kind = "CUMULATIVE"
+		}
+
+		if s.CreateMetricDescriptors {
+			d, err := s.descriptor(metricType, m.Name(), field, kind, valueType, labels)
+			if err != nil {
+				return nil, err
+			}
+			if d.valueType != valueType {
+				// The value type of the metric is the one of its descriptor,
+				// the integers of the double metrics being converted.
+				if d.valueType != "DOUBLE" || valueType != "INT64" {
+					log.Printf("D! Stackdriver: dropping field %s of %s, its type %s is not the type %s of %s",
+						field, m.Name(), valueType, d.valueType, metricType)
+					continue
+				}
+				f, _ := strconv.ParseFloat(*value.Int64Value, 64)
+				value = typedValue{DoubleValue: &f}
+				valueType = d.valueType
+			}
+			kind = d.kind
+		}
+
+		ts := &timeSeries{
+			Metric:     seriesMetric{Type: metricType, Labels: labels},
+			Resource:   resource,
+			MetricKind: kind,
+			ValueType:  valueType,
+			time:       m.Time(),
+		}
+		ts.key = metricType + "\x00" + labelsKey(labels) + "\x00" + resourceKey
+		p := point{
+			Interval: interval{EndTime: m.Time().UTC().Format(time.RFC3339Nano)},
+			Value:    value,
+		}
+		if kind == "CUMULATIVE" {
+			p.Interval.StartTime = s.startTime(ts.key, m.Time()).UTC().Format(time.RFC3339Nano)
+		}
+		ts.Points = []point{p}
+		series = append(series, ts)
+	}
+	return series, nil
+}
+
+// startTime returns the start time of the cumulative points of the time
+// series, just before its first point.
+func (s *Stackdriver) startTime(key string, t time.Time) time.Time {
+	st, ok := s.series[key]
+	if !ok {
+		st = &seriesState{}
+		s.series[key] = st
+	}
+	if st.start.IsZero() || !st.start.Before(t) {
+		st.start = t.Add(-time.Millisecond)
+	}
+	return st.start
+}
+
+// resource returns the monitored resource of the metric, and the tags of its
+// labels.
+func (s *Stackdriver) resource(m telegraf.Metric) (monitoredResource, map[string]bool) {
+	resourceType, labels, labelTags := s.ResourceType, s.ResourceLabels, s.ResourceLabelTags
+	for _, r := range s.ResourceMappings {
+		if r.measurement.Match(m.Name()) {
+			resourceType, labels, labelTags = r.Type, r.Labels, r.LabelTags
+			break
+		}
+	}
+
+	resource := monitoredResource{Type: resourceType, Labels: make(map[string]string)}
+	for k, v := range labels {
+		resource.Labels[k] = v
+	}
+	if resourceType == "global" && resource.Labels["project_id"] == "" {
+		resource.Labels["project_id"] = s.Project
+	}
+	used := make(map[string]bool)
+	tags := m.Tags()
+	for label, tag := range labelTags {
+		if v, ok := tags[tag]; ok {
+			resource.Labels[label] = v
+			used[tag] = true
+		}
+	}
+	return resource, used
+}
+
+// labelKey returns the tag key as a label key, lowercase and of letters,
+// digits and underscores, starting with a letter.
+func labelKey(tag string) string {
+	key := invalidLabelCharRE.ReplaceAllString(strings.ToLower(tag), "_")
+	if key == "" || key[0] < 'a' || key[0] > 'z' {
+		key = "tag_" + key
+	}
+	if len(key) > 100 {
+		key = key[:100]
+	}
+	return key
+}
+
+func labelsKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b bytes.Buffer
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte(0)
+		b.WriteString(labels[k])
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+// typed returns the value of the field and its value type, false if it can
+// not be written.
+func typed(v interface{}) (typedValue, string, bool) {
+	switch v := v.(type) {
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		return typedValue{Int64Value: &s}, "INT64", true
+	case uint64:
+		if v > math.MaxInt64 {
+			f := float64(v)
+			return typedValue{DoubleValue: &f}, "DOUBLE", true
+		}
+		s := strconv.FormatUint(v, 10)
+		return typedValue{Int64Value: &s}, "INT64", true
+	case float64:
+		return typedValue{DoubleValue: &v}, "DOUBLE", true
+	case bool:
+		return typedValue{BoolValue: &v}, "BOOL", true
+	case string:
+		return typedValue{StringValue: &v}, "STRING", true
+	}
+	return typedValue{}, "", false
+}
+
+// descriptor returns the descriptor of the metric type, creating it if it
+// was not created, or if it does not have the labels.
+func (s *Stackdriver) descriptor(
+	metricType, measurement, field, kind, valueType string,
+	labels map[string]string,
+) (*descriptor, error) {
+	d, ok := s.descriptors[metricType]
+	if ok {
+		missing := false
+		for k := range labels {
+			if !d.labels[k] {
+				missing = true
+				break
+			}
+		}
+		if !missing {
+			return d, nil
+		}
+	} else {
+		d = &descriptor{kind: kind, valueType: valueType}
+	}
+
+	// The descriptor is created again with the labels of its time series.
+	created := &descriptor{kind: d.kind, valueType: d.valueType, labels: make(map[string]bool)}
+	for k := range d.labels {
+		created.labels[k] = true
+	}
+	for k := range labels {
+		created.labels[k] = true
+	}
+	keys := make([]string, 0, len(created.labels))
+	for k := range created.labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	labelDescriptors := make([]map[string]string, len(keys))
+	for i, k := range keys {
+		labelDescriptors[i] = map[string]string{"key": k, "valueType": "STRING"}
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"type":        metricType,
+		"metricKind":  created.kind,
+		"valueType":   created.valueType,
+		"labels":      labelDescriptors,
+		"displayName": measurement + " " + field,
+		"description": fmt.Sprintf("Field %s of the %s measurement of Telegraf", field, measurement),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.post("metricDescriptors", body); err != nil {
+		return nil, err
+	}
+	// The descriptors rejected are not created again, their time series
+	// being rejected with the reason logged.
+	s.descriptors[metricType] = created
+	return created, nil
+}
+
+// create creates the time series, the states of the time series being
+// updated once they are written.
+func (s *Stackdriver) create(series []*timeSeries) error {
+	body, err := json.Marshal(map[string]interface{}{"timeSeries": series})
+	if err != nil {
+		return err
+	}
+	if err := s.post("timeSeries", body); err != nil {
+		return err
+	}
+	for _, ts := range series {
+		st, ok := s.series[ts.key]
+		if !ok {
+			st = &seriesState{}
+			s.series[ts.key] = st
+		}
+		st.last = ts.time
+	}
+	return nil
+}
+
+// post posts the body to the collection of the project, the requests
+// rejected as invalid being logged and dropped.
+func (s *Stackdriver) post(collection string, body []byte) error {
+	u := fmt.Sprintf("%s/v3/projects/%s/%s", strings.TrimRight(s.Endpoint, "/"), s.Project, collection)
+	req, err := http.NewRequest("POST", u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Telegraf")
+	if s.tokens != nil {
+		token, err := s.tokens.Token()
+		if err != nil {
+			return fmt.Errorf("Stackdriver: unable to get an access token: %s", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("Stackdriver: creating %s failed with status code %d: %s",
+		collection, resp.StatusCode, strings.TrimSpace(string(msg)))
+	switch resp.StatusCode {
+	case http.StatusBadRequest:
+		// Some points are invalid, the other points being written, writing
+		// them again would fail again.
+		log.Printf("E! %s", err)
+		return nil
+	case http.StatusTooManyRequests:
+		retryAfter := defaultRetryAfter
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+		s.retryAfter = time.Now().Add(retryAfter)
+	}
+	return err
+}
+
+func init() {
+	outputs.Add("stackdriver", func() telegraf.Output {
+		return &Stackdriver{
+			Namespace:               "telegraf",
+			Endpoint:                "https://monitoring.googleapis.com",
+			Timeout:                 internal.Duration{Duration: 5 * time.Second},
+			ResourceType:            "global",
+			CreateMetricDescriptors: true,
+		}
+	})
+}
@@ -0,0 +1,229 @@
+package stackdriver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// server is a Cloud Monitoring API, recording the descriptors and the time
+// series created.
+type server struct {
+	t *testing.T
+
+	mu          sync.Mutex
+	status      int
+	descriptors []map[string]interface{}
+	requests    [][]timeSeries
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.status != 0 {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(s.status)
+		w.Write([]byte(`{"error":{"code":429,"message":"Quota exceeded"}}`))
+		return
+	}
+	switch r.URL.Path {
+	case "/v3/projects/my-project/metricDescriptors":
+		var d map[string]interface{}
+		require.NoError(s.t, json.NewDecoder(r.Body).Decode(&d))
+		s.descriptors = append(s.descriptors, d)
+		json.NewEncoder(w).Encode(d)
+	case "/v3/projects/my-project/timeSeries":
+		var body struct {
+			TimeSeries []timeSeries `json:"timeSeries"`
+		}
+		require.NoError(s.t, json.NewDecoder(r.Body).Decode(&body))
+		s.requests = append(s.requests, body.TimeSeries)
+		w.Write([]byte(`{}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newOutput(t *testing.T, url string) *Stackdriver {
+	s := &Stackdriver{
+		Project:                 "my-project",
+		Namespace:               "telegraf",
+		Endpoint:                url,
+		CreateMetricDescriptors: true,
+	}
+	require.NoError(t, s.Connect())
+	return s
+}
+
+func TestWrite(t *testing.T) {
+	srv := &server{t: t}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	s := newOutput(t, ts.URL)
+	s.ResourceMappings = []*ResourceMapping{{
+		Measurement: "mem",
+		Type:        "generic_node",
+		Labels:      map[string]string{"namespace": "telegraf"},
+		LabelTags:   map[string]string{"node_id": "host"},
+	}}
+	require.NoError(t, s.Connect())
+
+	now := time.Unix(1500000000, 0)
+	cpu, err := metric.New("cpu", map[string]string{"host": "a", "CPU-Name": "cpu0"},
+		map[string]interface{}{"usage": 1.5, "state": "ok"}, now)
+	require.NoError(t, err)
+	mem, err := metric.New("mem", map[string]string{"host": "a"},
+		map[string]interface{}{"free": int64(10)}, now)
+	require.NoError(t, err)
+	require.NoError(t, s.Write([]telegraf.Metric{cpu, mem}))
+
+	require.Len(t, srv.descriptors, 3)
+	assert.Equal(t, map[string]interface{}{
+		"type":        "custom.googleapis.com/telegraf/cpu/state",
+		"metricKind":  "GAUGE",
+		"valueType":   "STRING",
+		"displayName": "cpu state",
+		"description": "Field state of the cpu measurement of Telegraf",
+		"labels": []interface{}{
+			map[string]interface{}{"key": "cpu_name", "valueType": "STRING"},
+			map[string]interface{}{"key": "host", "valueType": "STRING"},
+		},
+	}, srv.descriptors[0])
+
+	require.Len(t, srv.requests, 1)
+	series := srv.requests[0]
+	require.Len(t, series, 3)
+	assert.Equal(t, seriesMetric{
+		Type:   "custom.googleapis.com/telegraf/cpu/usage",
+		Labels: map[string]string{"host": "a", "cpu_name": "cpu0"},
+	}, series[1].Metric)
+	assert.Equal(t, monitoredResource{
+		Type:   "global",
+		Labels: map[string]string{"project_id": "my-project"},
+	}, series[1].Resource)
+	assert.Equal(t, "GAUGE", series[1].MetricKind)
+	assert.Equal(t, "DOUBLE", series[1].ValueType)
+	require.Len(t, series[1].Points, 1)
+	assert.Equal(t, "2017-07-14T02:40:00Z", series[1].Points[0].Interval.EndTime)
+	assert.Equal(t, 1.5, *series[1].Points[0].Value.DoubleValue)
+
+	// The tags of the resource labels are not metric labels.
+	assert.Equal(t, monitoredResource{
+		Type:   "generic_node",
+		Labels: map[string]string{"namespace": "telegraf", "node_id": "a"},
+	}, series[2].Resource)
+	assert.Empty(t, series[2].Metric.Labels)
+	assert.Equal(t, "INT64", series[2].ValueType)
+	assert.Equal(t, "10", *series[2].Points[0].Value.Int64Value)
+
+	// The descriptors are created once, or again for new labels.
+	cpu, err = metric.New("cpu", map[string]string{"host": "a", "CPU-Name": "cpu0", "zone": "z"},
+		map[string]interface{}{"usage": 1.5, "state": "ok"}, now.Add(time.Minute))
+	require.NoError(t, err)
+	mem, err = metric.New("mem", map[string]string{"host": "a"},
+		map[string]interface{}{"free": int64(10)}, now.Add(time.Minute))
+	require.NoError(t, err)
+	require.NoError(t, s.Write([]telegraf.Metric{cpu, mem}))
+	require.Len(t, srv.descriptors, 5)
+	assert.Len(t, srv.descriptors[3]["labels"], 3)
+}
+
+func TestWriteCounter(t *testing.T) {
+	srv := &server{t: t}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	s := newOutput(t, ts.URL)
+
+	now := time.Unix(1500000000, 0)
+	for i := 0; i < 2; i++ {
+		m, err := metric.New("net", nil,
+			map[string]interface{}{"bytes": int64(10 + i)}, now.Add(time.Duration(i)*time.Minute),
+			telegraf.Counter)
+		require.NoError(t, err)
+		require.NoError(t, s.Write([]telegraf.Metric{m}))
+	}
+
+	assert.Equal(t, "CUMULATIVE", srv.descriptors[0]["metricKind"])
+	require.Len(t, srv.requests, 2)
+	// The start time of the points is the one of the first point.
+	for _, r := range srv.requests {
+		assert.Equal(t, "CUMULATIVE", r[0].MetricKind)
+		assert.Equal(t, "2017-07-14T02:39:59.999Z", r[0].Points[0].Interval.StartTime)
+	}
+	assert.Equal(t, "2017-07-14T02:41:00Z", srv.requests[1][0].Points[0].Interval.EndTime)
+}
+
+func TestWriteBatching(t *testing.T) {
+	srv := &server{t: t}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	s := newOutput(t, ts.URL)
+	s.CreateMetricDescriptors = false
+
+	now := time.Unix(1500000000, 0)
+	var metrics []telegraf.Metric
+	for i := 0; i < 250; i++ {
+		m, err := metric.New("cpu", map[string]string{"cpu": fmt.Sprint(i)},
+			map[string]interface{}{"usage": 1.5}, now)
+		require.NoError(t, err)
+		metrics = append(metrics, m)
+	}
+	// The points of a time series are written in order, in separate
+	// requests, the points too close being dropped.
+	for _, offset := range []time.Duration{20 * time.Second, 10 * time.Second, 12 * time.Second} {
+		m, err := metric.New("cpu", map[string]string{"cpu": "0"},
+			map[string]interface{}{"usage": 1.5}, now.Add(offset))
+		require.NoError(t, err)
+		metrics = append(metrics, m)
+	}
+	require.NoError(t, s.Write(metrics))
+
+	assert.Empty(t, srv.descriptors)
+	require.Len(t, srv.requests, 4)
+	assert.Len(t, srv.requests[0], 200)
+	assert.Len(t, srv.requests[1], 50)
+	assert.Len(t, srv.requests[2], 1)
+	assert.Equal(t, "2017-07-14T02:40:10Z", srv.requests[2][0].Points[0].Interval.EndTime)
+	assert.Len(t, srv.requests[3], 1)
+	assert.Equal(t, "2017-07-14T02:40:20Z", srv.requests[3][0].Points[0].Interval.EndTime)
+
+	// The points older than the points written are dropped.
+	require.NoError(t, s.Write(metrics[:1]))
+	assert.Len(t, srv.requests, 4)
+}
+
+func TestWriteRateLimited(t *testing.T) {
+	srv := &server{t: t, status: http.StatusTooManyRequests}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	s := newOutput(t, ts.URL)
+	s.CreateMetricDescriptors = false
+
+	m, err := metric.New("cpu", nil, map[string]interface{}{"usage": 1.5}, time.Unix(1500000000, 0))
+	require.NoError(t, err)
+	err = s.Write([]telegraf.Metric{m})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Quota exceeded")
+
+	// The writes are suspended until the time of the Retry-After header.
+	srv.status = 0
+	err = s.Write([]telegraf.Metric{m})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "rate limited")
+	assert.Empty(t, srv.requests)
+	s.retryAfter = time.Time{}
+	require.NoError(t, s.Write([]telegraf.Metric{m}))
+	assert.Len(t, srv.requests, 1)
+}
@@ -0,0 +1,274 @@
+package websocket
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+const sampleConfig = `
+  ## URL of the WebSocket endpoint, with the "ws" or "wss" scheme.
+  url = "ws://localhost:8080/telegraf"
+
+  ## Timeouts of the connection and of the writes.
+  # connect_timeout = "30s"
+  # write_timeout = "30s"
+
+  ## The connection is reopened when it fails, waiting from
+  ## reconnect_interval to max_reconnect_interval, doubling each time,
+  ## between the failed attempts. The writes fail until it is reopened.
+  # reconnect_interval = "1s"
+  # max_reconnect_interval = "1m"
+
+  ## Send the messages as text frames instead of binary frames.
+  # use_text_frames = false
+
+  ## Send the metrics of each write as one message, instead of a message
+  ## per metric.
+  # batch = false
+
+  ## Additional HTTP headers of the opening handshake, ie for the
+  ## authentication.
+  # [outputs.websocket.headers]
+  #   Authorization = "Bearer my-token"
+
+  ## Optional SSL Config
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  ## Use SSL but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## Data format to output.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_OUTPUT.md
+  data_format = "influx"
+`
+
+// WebSocket writes the metrics as the messages of a persistent WebSocket
+// connection.
+type WebSocket struct {
+	URL            string
+	ConnectTimeout internal.Duration
+	WriteTimeout   internal.Duration
+	// The failed connections are reopened after ReconnectInterval, doubling
+	// up to MaxReconnectInterval as long as they fail to be reopened.
+	ReconnectInterval    internal.Duration
+	MaxReconnectInterval internal.Duration
+	UseTextFrames        bool
+	Batch                bool
+	Headers              map[string]string
+
+	// Path to CA file
+	SSLCA string `toml:"ssl_ca"`
+	// Path to host cert file
+	SSLCert string `toml:"ssl_cert"`
+	// Path to cert key file
+	SSLKey string `toml:"ssl_key"`
+	// Use SSL but skip chain & host verification
+	InsecureSkipVerify bool
+
+	serializer serializers.Serializer
+	config     *websocket.Config
+
+	mu   sync.Mutex
+	conn *websocket.Conn
+	// backoff is the delay before the next attempt to connect, nextDial the
+	// time of this attempt, both being reset once connected.
+	backoff  time.Duration
+	nextDial time.Time
+}
+
+func (w *WebSocket) SetSerializer(serializer serializers.Serializer) {
+	w.serializer = serializer
+}
+
+func (w *WebSocket) Connect() error {
+	u, err := url.Parse(w.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url %q: %s", w.URL, err)
+	}
+	origin := "http://" + u.Host
+	switch u.Scheme {
+	case "ws":
+	case "wss":
+		origin = "https://" + u.Host
+	default:
+		return fmt.Errorf("invalid url %q: the scheme is not ws or wss", w.URL)
+	}
+
+	w.config, err = websocket.NewConfig(w.URL, origin)
+	if err != nil {
+		return err
+	}
+	w.config.TlsConfig, err = internal.GetTLSConfig(
+		w.SSLCert, w.SSLKey, w.SSLCA, w.InsecureSkipVerify)
+	if err != nil {
+		return err
+	}
+	for k, v := range w.Headers {
+		w.config.Header.Set(k, v)
+	}
+	w.config.Dialer = &net.Dialer{Timeout: w.ConnectTimeout.Duration}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.dial()
+}
+
+// dial opens the connection, scheduling the next attempt when it fails.
+func (w *WebSocket) dial() error {
+	conn, err := websocket.DialConfig(w.config)
+	if err != nil {
+		if w.backoff == 0 {
+			w.backoff = w.ReconnectInterval.Duration
+		} else {
+			w.backoff *= 2
+		}
+		if w.backoff > w.MaxReconnectInterval.Duration {
+			w.backoff = w.MaxReconnectInterval.Duration
+		}
+		w.nextDial = time.Now().Add(w.backoff)
+		return err
+	}
+	if w.UseTextFrames {
+		conn.PayloadType = websocket.TextFrame
+	} else {
+		conn.PayloadType = websocket.BinaryFrame
+	}
+
+	w.conn = conn
+	w.backoff = 0
+	w.nextDial = time.Time{}
+	go w.read(conn)
+	return nil
+}
+
+// read reads the messages of the server, which are discarded, until the
+// connection is closed, the control frames being handled while reading.
+func (w *WebSocket) read(conn *websocket.Conn) {
+	for {
+		var msg []byte
+		if err := websocket.Message.Receive(conn, &msg); err != nil {
+			w.mu.Lock()
+			if w.conn == conn {
+				log.Printf("E! WebSocket: connection to %s closed: %s", w.URL, err)
+				w.close()
+			}
+			w.mu.Unlock()
+			return
+		}
+		log.Printf("D! WebSocket: discarding message of %d bytes from %s", len(msg), w.URL)
+	}
+}
+
+func (w *WebSocket) close() error {
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+func (w *WebSocket) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.close()
+}
+
+func (w *WebSocket) SampleConfig() string {
+	return sampleConfig
+}
+
+func (w *WebSocket) Description() string {
+	return "Send metrics as the messages of a WebSocket connection"
+}
+
+func (w *WebSocket) Write(metrics []telegraf.Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+	messages, err := w.messages(metrics)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		if now := time.Now(); now.Before(w.nextDial) {
+			return fmt.Errorf("WebSocket: not connected to %s, reconnecting in %s",
+				w.URL, w.nextDial.Sub(now))
+		}
+		if err := w.dial(); err != nil {
+			return fmt.Errorf("WebSocket: unable to reconnect to %s: %s", w.URL, err)
+		}
+	}
+
+	for _, msg := range messages {
+		if w.WriteTimeout.Duration > 0 {
+			w.conn.SetWriteDeadline(time.Now().Add(w.WriteTimeout.Duration))
+		}
+		if _, err := w.conn.Write(msg); err != nil {
+			// Some of the metrics may be sent again with the next writes.
+			w.close()
+			return fmt.Errorf("WebSocket: error writing to %s: %s", w.URL, err)
+		}
+	}
+	return nil
+}
+
+func (w *WebSocket) messages(metrics []telegraf.Metric) ([][]byte, error) {
+	if !w.Batch {
+		messages := make([][]byte, 0, len(metrics))
+		for _, m := range metrics {
+			b, err := w.serializer.Serialize(m)
+			if err != nil {
+				log.Printf("E! WebSocket: unable to serialize metric %s: %s", m.Name(), err)
+				continue
+			}
+			messages = append(messages, b)
+		}
+		return messages, nil
+	}
+
+	if s, ok := w.serializer.(serializers.BatchSerializer); ok {
+		b, err := s.SerializeBatch(metrics)
+		if err != nil {
+			return nil, err
+		}
+		return [][]byte{b}, nil
+	}
+	var buf []byte
+	for _, m := range metrics {
+		b, err := w.serializer.Serialize(m)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, b...)
+	}
+	return [][]byte{buf}, nil
+}
+
+func init() {
+	outputs.Add("websocket", func() telegraf.Output {
+		return &WebSocket{
+			ConnectTimeout:       internal.Duration{Duration: 30 * time.Second},
+			WriteTimeout:         internal.Duration{Duration: 30 * time.Second},
+			ReconnectInterval:    internal.Duration{Duration: time.Second},
+			MaxReconnectInterval: internal.Duration{Duration: time.Minute},
+		}
+	})
+}
@@ -0,0 +1,159 @@
+package websocket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type message struct {
+	text bool
+	data string
+}
+
+// frameCodec receives the messages with the type of their frames.
+var frameCodec = websocket.Codec{
+	Unmarshal: func(data []byte, payloadType byte, v interface{}) error {
+		*v.(*message) = message{text: payloadType == websocket.TextFrame, data: string(data)}
+		return nil
+	},
+}
+
+// serve serves WebSocket connections, sending their messages to the channel
+// and closing them after closeAfter messages if set.
+func serve(t *testing.T, closeAfter int) (*httptest.Server, chan message) {
+	messages := make(chan message, 10)
+	s := websocket.Server{
+		Handshake: func(config *websocket.Config, r *http.Request) error {
+			assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+			return nil
+		},
+		Handler: func(conn *websocket.Conn) {
+			for n := 0; closeAfter == 0 || n < closeAfter; n++ {
+				var m message
+				if err := frameCodec.Receive(conn, &m); err != nil {
+					return
+				}
+				messages <- m
+			}
+			conn.Close()
+		},
+	}
+	return httptest.NewServer(s), messages
+}
+
+func newOutput(t *testing.T, ts *httptest.Server) *WebSocket {
+	serializer, _ := serializers.NewInfluxSerializer()
+	return &WebSocket{
+		URL:                  "ws" + strings.TrimPrefix(ts.URL, "http") + "/telegraf",
+		ConnectTimeout:       internal.Duration{Duration: 5 * time.Second},
+		WriteTimeout:         internal.Duration{Duration: 5 * time.Second},
+		ReconnectInterval:    internal.Duration{Duration: time.Minute},
+		MaxReconnectInterval: internal.Duration{Duration: 2 * time.Minute},
+		Headers:              map[string]string{"Authorization": "Bearer secret"},
+		serializer:           serializer,
+	}
+}
+
+func receive(t *testing.T, messages chan message) message {
+	select {
+	case m := <-messages:
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+	return message{}
+}
+
+func TestWrite(t *testing.T) {
+	ts, messages := serve(t, 0)
+	defer ts.Close()
+
+	w := newOutput(t, ts)
+	require.NoError(t, w.Connect())
+	defer w.Close()
+
+	metrics := []telegraf.Metric{testutil.TestMetric(1, "m1"), testutil.TestMetric(2, "m2")}
+	require.NoError(t, w.Write(metrics))
+	assert.Equal(t, message{data: metrics[0].String()}, receive(t, messages))
+	assert.Equal(t, message{data: metrics[1].String()}, receive(t, messages))
+}
+
+func TestWriteBatchText(t *testing.T) {
+	ts, messages := serve(t, 0)
+	defer ts.Close()
+
+	w := newOutput(t, ts)
+	w.Batch = true
+	w.UseTextFrames = true
+	require.NoError(t, w.Connect())
+	defer w.Close()
+
+	metrics := []telegraf.Metric{testutil.TestMetric(1, "m1"), testutil.TestMetric(2, "m2")}
+	require.NoError(t, w.Write(metrics))
+	assert.Equal(t, message{text: true, data: metrics[0].String() + metrics[1].String()},
+		receive(t, messages))
+}
+
+func TestReconnect(t *testing.T) {
+	ts, messages := serve(t, 1)
+	defer ts.Close()
+
+	w := newOutput(t, ts)
+	require.NoError(t, w.Connect())
+	defer w.Close()
+
+	m := testutil.TestMetric(1, "m1")
+	require.NoError(t, w.Write([]telegraf.Metric{m}))
+	receive(t, messages)
+
+	// The server closes the connection, which is reopened by the next write.
+	for i := 0; i < 500; i++ {
+		w.mu.Lock()
+		closed := w.conn == nil
+		w.mu.Unlock()
+		if closed {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	require.NoError(t, w.Write([]telegraf.Metric{m}))
+	assert.Equal(t, message{data: m.String()}, receive(t, messages))
+}
+
+func TestReconnectBackoff(t *testing.T) {
+	ts, _ := serve(t, 0)
+	w := newOutput(t, ts)
+	ts.Close()
+
+	require.Error(t, w.Connect())
+	assert.Equal(t, time.Minute, w.backoff)
+	// The next attempt is delayed.
+	err := w.Write([]telegraf.Metric{testutil.TestMetric(1)})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "reconnecting in")
+
+	w.nextDial = time.Now()
+	require.Error(t, w.Write([]telegraf.Metric{testutil.TestMetric(1)}))
+	assert.Equal(t, 2*time.Minute, w.backoff)
+	w.nextDial = time.Now()
+	require.Error(t, w.Write([]telegraf.Metric{testutil.TestMetric(1)}))
+	assert.Equal(t, 2*time.Minute, w.backoff)
+}
+
+func TestConnectInvalidURL(t *testing.T) {
+	w := &WebSocket{URL: "http://localhost:8080"}
+	require.Error(t, w.Connect())
+}
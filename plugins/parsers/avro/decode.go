@@ -0,0 +1,167 @@
+package avro
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// decoder decodes the Avro binary encoding.
+type decoder struct {
+	buf []byte
+	pos int
+}
+
+func (d *decoder) long() (int64, error) {
+	u, n := binary.Uvarint(d.buf[d.pos:])
+	if n <= 0 {
+		return 0, fmt.Errorf("invalid varint at offset %d", d.pos)
+	}
+	d.pos += n
+	// zigzag encoding
+	return int64(u>>1) ^ -int64(u&1), nil
+}
+
+func (d *decoder) next(n int) ([]byte, error) {
+	if n < 0 || d.pos+n > len(d.buf) {
+		return nil, fmt.Errorf("unexpected end of data at offset %d", d.pos)
+	}
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *decoder) bytes() ([]byte, error) {
+	n, err := d.long()
+	if err != nil {
+		return nil, err
+	}
+	return d.next(int(n))
+}
+
+// decode returns the value of the schema: nil, bool, int64, float64,
+// string, time.Time for the timestamp logical types, []interface{} for the
+// arrays and map[string]interface{} for the maps and records.
+func (d *decoder) decode(s *schema) (interface{}, error) {
+	switch s.typ {
+	case "null":
+		return nil, nil
+	case "boolean":
+		b, err := d.next(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0] != 0, nil
+	case "int", "long":
+		i, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		switch s.logicalType {
+		case "timestamp-millis":
+			return time.Unix(0, i*int64(time.Millisecond)).UTC(), nil
+		case "timestamp-micros":
+			return time.Unix(0, i*int64(time.Microsecond)).UTC(), nil
+		}
+		return i, nil
+	case "float":
+		b, err := d.next(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b))), nil
+	case "double":
+		b, err := d.next(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+	case "bytes", "string":
+		b, err := d.bytes()
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case "fixed":
+		b, err := d.next(s.size)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case "enum":
+		i, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || int(i) >= len(s.symbols) {
+			return nil, fmt.Errorf("invalid index %d of enum %s", i, s.name)
+		}
+		return s.symbols[i], nil
+	case "union":
+		i, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || int(i) >= len(s.union) {
+			return nil, fmt.Errorf("invalid index %d of union", i)
+		}
+		return d.decode(s.union[i])
+	case "record":
+		record := make(map[string]interface{}, len(s.fields))
+		for _, f := range s.fields {
+			v, err := d.decode(f.schema)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %s", f.name, err)
+			}
+			record[f.name] = v
+		}
+		return record, nil
+	case "array":
+		var items []interface{}
+		err := d.blocks(func() error {
+			v, err := d.decode(s.items)
+			items = append(items, v)
+			return err
+		})
+		return items, err
+	case "map":
+		values := make(map[string]interface{})
+		err := d.blocks(func() error {
+			k, err := d.bytes()
+			if err != nil {
+				return err
+			}
+			values[string(k)], err = d.decode(s.items)
+			return err
+		})
+		return values, err
+	}
+	return nil, fmt.Errorf("unsupported type %q", s.typ)
+}
+
+// blocks reads the blocks of items of an array or a map, ending with an
+// empty block.
+func (d *decoder) blocks(item func() error) error {
+	for {
+		n, err := d.long()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return nil
+		}
+		if n < 0 {
+			// The count is followed by the size of the block in bytes.
+			n = -n
+			if _, err := d.long(); err != nil {
+				return err
+			}
+		}
+		for ; n > 0; n-- {
+			if err := item(); err != nil {
+				return err
+			}
+		}
+	}
+}
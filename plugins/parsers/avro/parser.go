@@ -0,0 +1,228 @@
+package avro
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// AvroParser makes a metric of an Avro record, ie a Kafka message, with the
+// fields of the nested records flattened.
+//
+// With a schema registry the messages are in the Confluent wire format: a
+// zero magic byte and the id of the writer schema as a big endian uint32,
+// followed by the record.
+type AvroParser struct {
+	MetricName string
+
+	// SchemaRegistry is the URL of the Confluent Schema Registry the writer
+	// schemas are fetched from. Schema is the JSON of the writer schema of
+	// the records without the wire format header, used when there is no
+	// registry.
+	SchemaRegistry string
+	Schema         string
+
+	// Measurement is the field of the measurement name, MetricName if empty.
+	Measurement string
+	// Tags are the fields making tags instead of fields.
+	Tags []string
+	// Fields are the fields to keep, all by default.
+	Fields []string
+	// Timestamp is the field of the timestamp, the time of the parsing is
+	// used if empty. The timestamp-millis and timestamp-micros logical types
+	// are converted, otherwise TimestampFormat is "unix", "unix_ms",
+	// "unix_us", "unix_ns" or a Go reference time layout, the default is
+	// "unix" for the numbers and RFC3339 for the strings.
+	Timestamp       string
+	TimestampFormat string
+	// FieldSeparator joins the names of the nested fields and of the items
+	// of the arrays, "_" if empty.
+	FieldSeparator string
+
+	DefaultTags map[string]string
+
+	once     sync.Once
+	err      error
+	schema   *schema
+	registry *schemaRegistry
+}
+
+// Init parses the schema or sets up the registry, it is called by Parse if
+// needed.
+func (p *AvroParser) Init() error {
+	p.once.Do(func() {
+		switch {
+		case p.SchemaRegistry != "":
+			p.registry = newSchemaRegistry(p.SchemaRegistry)
+		case p.Schema != "":
+			p.schema, p.err = parseSchema(p.Schema)
+		default:
+			p.err = fmt.Errorf("a schema registry or a schema is required")
+		}
+	})
+	return p.err
+}
+
+func (p *AvroParser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	if err := p.Init(); err != nil {
+		return nil, err
+	}
+
+	s := p.schema
+	if p.registry != nil {
+		if len(buf) < 5 || buf[0] != 0 {
+			return nil, fmt.Errorf("message is not in the schema registry wire format")
+		}
+		var err error
+		if s, err = p.registry.get(binary.BigEndian.Uint32(buf[1:5])); err != nil {
+			return nil, err
+		}
+		buf = buf[5:]
+	}
+	if s.typ != "record" {
+		return nil, fmt.Errorf("schema %s is not a record", s.typ)
+	}
+
+	d := &decoder{buf: buf}
+	record, err := d.decode(s)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode record %s, %s", s.name, err)
+	}
+
+	m, err := p.makeMetric(record.(map[string]interface{}))
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return []telegraf.Metric{}, nil
+	}
+	return []telegraf.Metric{m}, nil
+}
+
+func (p *AvroParser) makeMetric(record map[string]interface{}) (telegraf.Metric, error) {
+	separator := p.FieldSeparator
+	if separator == "" {
+		separator = "_"
+	}
+	values := make(map[string]interface{})
+	flatten("", record, separator, values)
+
+	name := p.MetricName
+	if p.Measurement != "" {
+		if v, ok := values[p.Measurement]; ok {
+			name = toString(v)
+			delete(values, p.Measurement)
+		}
+	}
+
+	tags := make(map[string]string)
+	for k, v := range p.DefaultTags {
+		tags[k] = v
+	}
+	for _, tag := range p.Tags {
+		if v, ok := values[tag]; ok {
+			tags[tag] = toString(v)
+			delete(values, tag)
+		}
+	}
+
+	t := time.Now().UTC()
+	if p.Timestamp != "" {
+		v, ok := values[p.Timestamp]
+		if !ok {
+			return nil, fmt.Errorf("timestamp field %q not found", p.Timestamp)
+		}
+		format := p.TimestampFormat
+		if _, ok := v.(string); !ok && format == "" {
+			format = "unix"
+		}
+		var err error
+		if t, err = internal.ParseTimestamp(v, format, nil); err != nil {
+			return nil, err
+		}
+		delete(values, p.Timestamp)
+	}
+
+	fields := make(map[string]interface{})
+	if len(p.Fields) == 0 {
+		for k, v := range values {
+			fields[k] = fieldValue(v)
+		}
+	} else {
+		for _, k := range p.Fields {
+			if v, ok := values[k]; ok {
+				fields[k] = fieldValue(v)
+			}
+		}
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return metric.New(name, tags, fields, t)
+}
+
+// flatten adds the values of the nested records, maps and arrays with their
+// names joined by the separator, the null values are skipped.
+func flatten(prefix string, v interface{}, separator string, values map[string]interface{}) {
+	join := func(name string) string {
+		if prefix == "" {
+			return name
+		}
+		return prefix + separator + name
+	}
+
+	switch v := v.(type) {
+	case nil:
+	case map[string]interface{}:
+		for k, item := range v {
+			flatten(join(k), item, separator, values)
+		}
+	case []interface{}:
+		for i, item := range v {
+			flatten(join(strconv.Itoa(i)), item, separator, values)
+		}
+	default:
+		values[prefix] = v
+	}
+}
+
+// fieldValue converts the times to RFC3339 strings.
+func fieldValue(v interface{}) interface{} {
+	if t, ok := v.(time.Time); ok {
+		return t.Format(time.RFC3339Nano)
+	}
+	return v
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
+
+func (p *AvroParser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("Can not parse the line: %s, for data format: avro", line)
+	}
+
+	return metrics[0], nil
+}
+
+func (p *AvroParser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
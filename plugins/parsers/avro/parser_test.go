@@ -0,0 +1,223 @@
+package avro
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const readingSchema = `{
+  "type": "record",
+  "name": "Reading",
+  "namespace": "plant.sensors",
+  "fields": [
+    {"name": "device", "type": "string"},
+    {"name": "line", "type": {"type": "enum", "name": "Line", "symbols": ["LINE_1", "LINE_2"]}},
+    {"name": "power", "type": "double"},
+    {"name": "voltage", "type": "float"},
+    {"name": "running", "type": "boolean"},
+    {"name": "cycles", "type": "long"},
+    {"name": "time", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "location", "type": {
+      "type": "record",
+      "name": "Location",
+      "fields": [
+        {"name": "site", "type": "string"},
+        {"name": "floor", "type": "int"}
+      ]
+    }},
+    {"name": "previous", "type": ["null", "Location"]},
+    {"name": "note", "type": ["null", "string"]},
+    {"name": "phases", "type": {"type": "array", "items": "double"}},
+    {"name": "counters", "type": {"type": "map", "values": "long"}}
+  ]
+}`
+
+// encoder writes the Avro binary encoding of the test records.
+type encoder struct {
+	bytes.Buffer
+}
+
+func (e *encoder) long(i int64) *encoder {
+	b := make([]byte, binary.MaxVarintLen64)
+	e.Write(b[:binary.PutVarint(b, i)])
+	return e
+}
+
+func (e *encoder) string(s string) *encoder {
+	e.long(int64(len(s)))
+	e.WriteString(s)
+	return e
+}
+
+func (e *encoder) double(f float64) *encoder {
+	binary.Write(e, binary.LittleEndian, math.Float64bits(f))
+	return e
+}
+
+func (e *encoder) float(f float32) *encoder {
+	binary.Write(e, binary.LittleEndian, math.Float32bits(f))
+	return e
+}
+
+func (e *encoder) boolean(b bool) *encoder {
+	if b {
+		e.WriteByte(1)
+	} else {
+		e.WriteByte(0)
+	}
+	return e
+}
+
+func reading() []byte {
+	e := &encoder{}
+	e.string("pump-1").long(1).double(1530.5).float(230.5).boolean(true)
+	e.long(42).long(1496318400000)
+	e.string("factory").long(2)
+	e.long(0)
+	e.long(1).string("maintenance")
+	e.long(3).double(1.5).double(2.5).double(3.5).long(0)
+	// A block with its size in bytes
+	e.long(-1).long(8).string("starts").long(7).long(0)
+	return e.Bytes()
+}
+
+func TestParseSchema(t *testing.T) {
+	parser := &AvroParser{
+		MetricName:  "avro",
+		Schema:      readingSchema,
+		Measurement: "device",
+		Tags:        []string{"line", "location_site"},
+		Timestamp:   "time",
+		DefaultTags: map[string]string{"source": "kafka"},
+	}
+
+	metrics, err := parser.Parse(reading())
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+
+	m := metrics[0]
+	assert.Equal(t, "pump-1", m.Name())
+	assert.Equal(t, map[string]string{
+		"line":          "LINE_2",
+		"location_site": "factory",
+		"source":        "kafka",
+	}, m.Tags())
+	assert.Equal(t, map[string]interface{}{
+		"power":           1530.5,
+		"voltage":         230.5,
+		"running":         true,
+		"cycles":          int64(42),
+		"location_floor":  int64(2),
+		"note":            "maintenance",
+		"phases_0":        1.5,
+		"phases_1":        2.5,
+		"phases_2":        3.5,
+		"counters_starts": int64(7),
+	}, m.Fields())
+	assert.Equal(t, time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC).UnixNano(),
+		m.Time().UnixNano())
+}
+
+func TestParseFields(t *testing.T) {
+	parser := &AvroParser{
+		MetricName:     "reading",
+		Schema:         readingSchema,
+		Fields:         []string{"power", "location.floor"},
+		FieldSeparator: ".",
+	}
+
+	m, err := parser.ParseLine(string(reading()))
+	require.NoError(t, err)
+	assert.Equal(t, "reading", m.Name())
+	assert.Equal(t, map[string]interface{}{
+		"power":          1530.5,
+		"location.floor": int64(2),
+	}, m.Fields())
+}
+
+func TestParseSchemaRegistry(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/schemas/ids/7" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error_code":40403,"message":"Schema not found"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"schema":%s}`, strconv.Quote(readingSchema))
+	}))
+	defer ts.Close()
+
+	parser := &AvroParser{
+		MetricName:     "reading",
+		SchemaRegistry: ts.URL + "/",
+		Tags:           []string{"device"},
+		Fields:         []string{"power"},
+	}
+
+	msg := append([]byte{0, 0, 0, 0, 7}, reading()...)
+	for i := 0; i < 2; i++ {
+		metrics, err := parser.Parse(msg)
+		require.NoError(t, err)
+		require.Len(t, metrics, 1)
+		assert.Equal(t, map[string]string{"device": "pump-1"}, metrics[0].Tags())
+		assert.Equal(t, map[string]interface{}{"power": 1530.5}, metrics[0].Fields())
+	}
+	// The schema is cached
+	assert.Equal(t, 1, requests)
+
+	_, err := parser.Parse(append([]byte{0, 0, 0, 0, 8}, reading()...))
+	assert.Error(t, err)
+	_, err = parser.Parse(reading())
+	assert.Error(t, err)
+}
+
+func TestParseErrors(t *testing.T) {
+	parser := &AvroParser{MetricName: "test"}
+	assert.Error(t, parser.Init())
+
+	parser = &AvroParser{MetricName: "test", Schema: `{"type": "record", "name": "a", "fields": [{"name": "b", "type": "c"}]}`}
+	assert.Error(t, parser.Init())
+
+	parser = &AvroParser{MetricName: "test", Schema: readingSchema}
+	_, err := parser.Parse(reading()[:10])
+	assert.Error(t, err)
+
+	parser = &AvroParser{MetricName: "test", Schema: readingSchema, Timestamp: "missing"}
+	_, err = parser.Parse(reading())
+	assert.Error(t, err)
+}
+
+func TestParseRecursiveSchema(t *testing.T) {
+	s, err := parseSchema(`{
+	  "type": "record", "name": "Node",
+	  "fields": [
+	    {"name": "value", "type": "long"},
+	    {"name": "next", "type": ["null", "Node"]}
+	  ]
+	}`)
+	require.NoError(t, err)
+
+	e := &encoder{}
+	e.long(1).long(1).long(2).long(0)
+	d := &decoder{buf: e.Bytes()}
+	v, err := d.decode(s)
+	require.NoError(t, err)
+
+	values := make(map[string]interface{})
+	flatten("", v, "_", values)
+	assert.Equal(t, map[string]interface{}{
+		"value":      int64(1),
+		"next_value": int64(2),
+	}, values)
+}
@@ -0,0 +1,68 @@
+package avro
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// schemaRegistry fetches the writer schemas by id from a Confluent Schema
+// Registry, the schemas never change so they are cached.
+type schemaRegistry struct {
+	url    string
+	client *http.Client
+
+	mu      sync.Mutex
+	schemas map[uint32]*schema
+}
+
+func newSchemaRegistry(url string) *schemaRegistry {
+	return &schemaRegistry{
+		url:     strings.TrimSuffix(url, "/"),
+		client:  &http.Client{Timeout: 10 * time.Second},
+		schemas: make(map[uint32]*schema),
+	}
+}
+
+func (r *schemaRegistry) get(id uint32) (*schema, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s, ok := r.schemas[id]; ok {
+		return s, nil
+	}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/schemas/ids/%d", r.url, id), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching schema %d: %s", id, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("schema registry returned HTTP status %s for schema %d",
+			resp.Status, id)
+	}
+
+	var body struct {
+		Schema     string `json:"schema"`
+		SchemaType string `json:"schemaType"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("error reading schema %d: %s", id, err)
+	}
+	if body.SchemaType != "" && body.SchemaType != "AVRO" {
+		return nil, fmt.Errorf("schema %d is a %s schema", id, body.SchemaType)
+	}
+	s, err := parseSchema(body.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("schema %d: %s", id, err)
+	}
+	r.schemas[id] = s
+	return s, nil
+}
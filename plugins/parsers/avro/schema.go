@@ -0,0 +1,176 @@
+package avro
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// schema is a parsed Avro schema, as needed to decode the binary encoding.
+type schema struct {
+	// typ is a primitive type, "record", "enum", "array", "map", "fixed" or
+	// "union".
+	typ         string
+	name        string
+	logicalType string
+
+	fields  []field
+	symbols []string
+	// items is the schema of the items of an array or of the values of a
+	// map.
+	items *schema
+	size  int
+	union []*schema
+}
+
+type field struct {
+	name   string
+	schema *schema
+}
+
+// schemaParser resolves the references to the named types, by full name or
+// by name in the enclosing namespace.
+type schemaParser struct {
+	named map[string]*schema
+}
+
+func parseSchema(text string) (*schema, error) {
+	var v interface{}
+	if err := json.Unmarshal([]byte(text), &v); err != nil {
+		return nil, fmt.Errorf("invalid schema, %s", err)
+	}
+	p := &schemaParser{named: make(map[string]*schema)}
+	return p.parse(v, "")
+}
+
+func (p *schemaParser) parse(v interface{}, namespace string) (*schema, error) {
+	switch v := v.(type) {
+	case string:
+		switch v {
+		case "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+			return &schema{typ: v}, nil
+		}
+		if s, ok := p.named[v]; ok {
+			return s, nil
+		}
+		if s, ok := p.named[namespace+"."+v]; ok {
+			return s, nil
+		}
+		return nil, fmt.Errorf("unknown type %q", v)
+	case []interface{}:
+		s := &schema{typ: "union"}
+		for _, branch := range v {
+			b, err := p.parse(branch, namespace)
+			if err != nil {
+				return nil, err
+			}
+			s.union = append(s.union, b)
+		}
+		return s, nil
+	case map[string]interface{}:
+		return p.parseComplex(v, namespace)
+	}
+	return nil, fmt.Errorf("invalid schema %v", v)
+}
+
+func (p *schemaParser) parseComplex(v map[string]interface{}, namespace string) (*schema, error) {
+	typ, ok := v["type"].(string)
+	if !ok {
+		// ie {"type": {"type": "array", "items": "long"}}
+		return p.parse(v["type"], namespace)
+	}
+	logicalType, _ := v["logicalType"].(string)
+
+	switch typ {
+	case "record", "error", "enum", "fixed":
+		name, namespace, err := fullName(v, namespace)
+		if err != nil {
+			return nil, err
+		}
+		s := &schema{typ: typ, name: name}
+		if typ == "error" {
+			s.typ = "record"
+		}
+		// Registered first for the recursive records.
+		p.named[name] = s
+		switch typ {
+		case "record", "error":
+			fields, ok := v["fields"].([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("record %s has no fields", name)
+			}
+			for _, f := range fields {
+				f, ok := f.(map[string]interface{})
+				if !ok {
+					return nil, fmt.Errorf("invalid field in record %s", name)
+				}
+				fieldName, _ := f["name"].(string)
+				if fieldName == "" {
+					return nil, fmt.Errorf("field without a name in record %s", name)
+				}
+				fs, err := p.parse(f["type"], namespace)
+				if err != nil {
+					return nil, fmt.Errorf("field %s of record %s: %s", fieldName, name, err)
+				}
+				s.fields = append(s.fields, field{name: fieldName, schema: fs})
+			}
+		case "enum":
+			symbols, _ := v["symbols"].([]interface{})
+			for _, symbol := range symbols {
+				str, ok := symbol.(string)
+				if !ok {
+					return nil, fmt.Errorf("invalid symbol in enum %s", name)
+				}
+				s.symbols = append(s.symbols, str)
+			}
+		case "fixed":
+			size, ok := v["size"].(float64)
+			if !ok || size < 0 {
+				return nil, fmt.Errorf("fixed %s has no size", name)
+			}
+			s.size = int(size)
+			s.logicalType = logicalType
+		}
+		return s, nil
+	case "array", "map":
+		key := "items"
+		if typ == "map" {
+			key = "values"
+		}
+		items, err := p.parse(v[key], namespace)
+		if err != nil {
+			return nil, err
+		}
+		return &schema{typ: typ, items: items}, nil
+	}
+
+	s, err := p.parse(typ, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if logicalType != "" {
+		// A copy, the named types are shared.
+		c := *s
+		c.logicalType = logicalType
+		s = &c
+	}
+	return s, nil
+}
+
+// fullName returns the full name of a named type and its namespace.
+func fullName(v map[string]interface{}, namespace string) (string, string, error) {
+	name, _ := v["name"].(string)
+	if name == "" {
+		return "", "", fmt.Errorf("named type without a name")
+	}
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name, name[:i], nil
+	}
+	if ns, ok := v["namespace"].(string); ok {
+		namespace = ns
+	}
+	if namespace == "" {
+		return name, "", nil
+	}
+	return namespace + "." + name, namespace, nil
+}
@@ -0,0 +1,273 @@
+package binary
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// Field is a field of the records, of Type at Offset. The strings and the
+// bytes have a Length, the other types their size.
+type Field struct {
+	Name string
+	// Type is "int8", "int16", "int32", "int64", "uint8", "uint16",
+	// "uint32", "uint64", "float32", "float64", "bool", "string" or "bytes",
+	// the bytes being a hexadecimal string.
+	Type   string
+	Offset int
+	Length int
+	// Endianness is "be" or "le", the endianness of the parser if empty.
+	Endianness string
+}
+
+// size returns the number of bytes of the field.
+func (f *Field) size() int {
+	switch f.Type {
+	case "int8", "uint8", "bool":
+		return 1
+	case "int16", "uint16":
+		return 2
+	case "int32", "uint32", "float32":
+		return 4
+	case "int64", "uint64", "float64":
+		return 8
+	}
+	return f.Length
+}
+
+// BinaryParser makes a metric of each fixed-layout binary record, ie of the
+// frames sent over UDP by energy meters, reading the fields at their offset.
+type BinaryParser struct {
+	MetricName string
+
+	// Endianness is "be", the default, or "le".
+	Endianness string
+	// RecordLength is the length of the records, the buffers being split
+	// into records. The buffers are single records if it is zero.
+	RecordLength int
+	Fields       []Field
+	// Tags are the fields making tags.
+	Tags []string
+	// Timestamp is the integer field of the timestamp, in seconds or in the
+	// unit of TimestampFormat, "unix", "unix_ms", "unix_us" or "unix_ns".
+	// The time of the parsing is used if empty.
+	Timestamp       string
+	TimestampFormat string
+
+	DefaultTags map[string]string
+}
+
+// Check checks the layout of the records.
+func (p *BinaryParser) Check() error {
+	if len(p.Fields) == 0 {
+		return fmt.Errorf("no binary field")
+	}
+	if _, err := byteOrder(p.Endianness); err != nil {
+		return err
+	}
+	if p.RecordLength < 0 {
+		return fmt.Errorf("invalid record length %d", p.RecordLength)
+	}
+
+	names := make(map[string]bool)
+	for _, f := range p.Fields {
+		if f.Name == "" {
+			return fmt.Errorf("binary field without name")
+		}
+		if names[f.Name] {
+			return fmt.Errorf("duplicate binary field %s", f.Name)
+		}
+		names[f.Name] = true
+		switch f.Type {
+		case "int8", "int16", "int32", "int64", "uint8", "uint16", "uint32",
+			"uint64", "float32", "float64", "bool":
+		case "string", "bytes":
+			if f.Length <= 0 {
+				return fmt.Errorf("binary field %s: length required", f.Name)
+			}
+		default:
+			return fmt.Errorf("binary field %s: invalid type %q", f.Name, f.Type)
+		}
+		if _, err := byteOrder(f.Endianness); err != nil {
+			return fmt.Errorf("binary field %s: %s", f.Name, err)
+		}
+		if f.Offset < 0 {
+			return fmt.Errorf("binary field %s: invalid offset %d", f.Name, f.Offset)
+		}
+		if p.RecordLength > 0 && f.Offset+f.size() > p.RecordLength {
+			return fmt.Errorf("binary field %s: beyond the record length", f.Name)
+		}
+	}
+	if p.Timestamp != "" && !names[p.Timestamp] {
+		return fmt.Errorf("timestamp field %s not found", p.Timestamp)
+	}
+	switch p.TimestampFormat {
+	case "", "unix", "unix_ms", "unix_us", "unix_ns":
+	default:
+		return fmt.Errorf("invalid timestamp format %q", p.TimestampFormat)
+	}
+	return nil
+}
+
+func byteOrder(endianness string) (binary.ByteOrder, error) {
+	switch endianness {
+	case "", "be":
+		return binary.BigEndian, nil
+	case "le":
+		return binary.LittleEndian, nil
+	}
+	return nil, fmt.Errorf("invalid endianness %q", endianness)
+}
+
+func (p *BinaryParser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	if err := p.Check(); err != nil {
+		return nil, err
+	}
+
+	metrics := make([]telegraf.Metric, 0)
+	now := time.Now().UTC()
+	records := [][]byte{buf}
+	if p.RecordLength > 0 {
+		if len(buf)%p.RecordLength != 0 {
+			return nil, fmt.Errorf("%d bytes are not a multiple of the record length %d",
+				len(buf), p.RecordLength)
+		}
+		records = records[:0]
+		for len(buf) > 0 {
+			records = append(records, buf[:p.RecordLength])
+			buf = buf[p.RecordLength:]
+		}
+	}
+
+	for _, record := range records {
+		m, err := p.parseRecord(record, now)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+func (p *BinaryParser) parseRecord(record []byte, now time.Time) (telegraf.Metric, error) {
+	tags := make(map[string]string)
+	for k, v := range p.DefaultTags {
+		tags[k] = v
+	}
+	fields := make(map[string]interface{})
+	t := now
+
+	for _, f := range p.Fields {
+		end := f.Offset + f.size()
+		if end > len(record) {
+			return nil, fmt.Errorf("record of %d bytes too short for field %s", len(record), f.Name)
+		}
+		order, _ := byteOrder(f.Endianness)
+		if f.Endianness == "" {
+			order, _ = byteOrder(p.Endianness)
+		}
+		v := decode(f.Type, record[f.Offset:end], order)
+
+		if f.Name == p.Timestamp {
+			ts, err := p.parseTime(v)
+			if err != nil {
+				return nil, err
+			}
+			t = ts
+			continue
+		}
+		if p.isTag(f.Name) {
+			tags[f.Name] = fmt.Sprint(v)
+			continue
+		}
+		fields[f.Name] = v
+	}
+	return metric.New(p.MetricName, tags, fields, t)
+}
+
+// decode decodes the bytes of a field of the type, as an int64, an uint64,
+// a float64, a bool or a string.
+func decode(typ string, b []byte, order binary.ByteOrder) interface{} {
+	switch typ {
+	case "int8":
+		return int64(int8(b[0]))
+	case "int16":
+		return int64(int16(order.Uint16(b)))
+	case "int32":
+		return int64(int32(order.Uint32(b)))
+	case "int64":
+		return int64(order.Uint64(b))
+	case "uint8":
+		return uint64(b[0])
+	case "uint16":
+		return uint64(order.Uint16(b))
+	case "uint32":
+		return uint64(order.Uint32(b))
+	case "uint64":
+		return order.Uint64(b)
+	case "float32":
+		return float64(math.Float32frombits(order.Uint32(b)))
+	case "float64":
+		return math.Float64frombits(order.Uint64(b))
+	case "bool":
+		return b[0] != 0
+	case "string":
+		// The strings are padded with NUL or space characters.
+		return strings.TrimRight(string(b), "\x00 ")
+	}
+	return hex.EncodeToString(b)
+}
+
+func (p *BinaryParser) isTag(name string) bool {
+	for _, tag := range p.Tags {
+		if tag == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTime converts the integer timestamp according to TimestampFormat.
+func (p *BinaryParser) parseTime(v interface{}) (time.Time, error) {
+	var i int64
+	switch v := v.(type) {
+	case int64:
+		i = v
+	case uint64:
+		if v > math.MaxInt64 {
+			return time.Time{}, fmt.Errorf("timestamp %d out of range", v)
+		}
+		i = int64(v)
+	default:
+		return time.Time{}, fmt.Errorf("timestamp field %s is not an integer", p.Timestamp)
+	}
+
+	if p.TimestampFormat == "" {
+		return internal.ParseTimestamp(i, "unix", nil)
+	}
+	return internal.ParseTimestamp(i, p.TimestampFormat, nil)
+}
+
+func (p *BinaryParser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("Can not parse the line: %s, for data format: binary", line)
+	}
+
+	return metrics[0], nil
+}
+
+func (p *BinaryParser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
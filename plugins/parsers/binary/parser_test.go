@@ -0,0 +1,137 @@
+package binary
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// meterFrame is a frame of an energy meter: the serial number, the
+// timestamp, the voltage in tenth of volts, the power, the status flags and
+// a little endian counter.
+func meterFrame(serial string, ts uint32, voltage uint16, power float32) []byte {
+	frame := make([]byte, 24)
+	copy(frame[0:8], serial)
+	binary.BigEndian.PutUint32(frame[8:], ts)
+	binary.BigEndian.PutUint16(frame[12:], voltage)
+	binary.BigEndian.PutUint32(frame[14:], math.Float32bits(power))
+	frame[18] = 0xa5
+	frame[19] = 1
+	binary.LittleEndian.PutUint32(frame[20:], 0xfffffffe)
+	return frame
+}
+
+func meterParser() *BinaryParser {
+	return &BinaryParser{
+		MetricName: "meter",
+		Fields: []Field{
+			{Name: "serial", Type: "string", Offset: 0, Length: 8},
+			{Name: "time", Type: "uint32", Offset: 8},
+			{Name: "voltage", Type: "uint16", Offset: 12},
+			{Name: "power", Type: "float32", Offset: 14},
+			{Name: "flags", Type: "bytes", Offset: 18, Length: 1},
+			{Name: "online", Type: "bool", Offset: 19},
+			{Name: "counter", Type: "int32", Offset: 20, Endianness: "le"},
+		},
+		Tags:        []string{"serial"},
+		Timestamp:   "time",
+		DefaultTags: map[string]string{"site": "plant"},
+	}
+}
+
+func TestParse(t *testing.T) {
+	parser := meterParser()
+	metrics, err := parser.Parse(meterFrame("EM-42", 1496318400, 2301, 1530.5))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+
+	m := metrics[0]
+	assert.Equal(t, "meter", m.Name())
+	assert.Equal(t, map[string]string{"serial": "EM-42", "site": "plant"}, m.Tags())
+	assert.Equal(t, map[string]interface{}{
+		"voltage": int64(2301),
+		"power":   1530.5,
+		"flags":   "a5",
+		"online":  true,
+		"counter": int64(-2),
+	}, m.Fields())
+	assert.Equal(t, time.Unix(1496318400, 0).UnixNano(), m.Time().UnixNano())
+}
+
+func TestParseRecords(t *testing.T) {
+	parser := &BinaryParser{
+		MetricName:   "meter",
+		Endianness:   "le",
+		RecordLength: 4,
+		Fields: []Field{
+			{Name: "voltage", Type: "uint16", Offset: 0},
+			{Name: "current", Type: "int16", Offset: 2},
+		},
+	}
+	metrics, err := parser.Parse([]byte{0xfd, 0x08, 0xff, 0xff, 0x01, 0x00, 0x02, 0x00})
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	assert.Equal(t, map[string]interface{}{
+		"voltage": int64(2301),
+		"current": int64(-1),
+	}, metrics[0].Fields())
+	assert.Equal(t, map[string]interface{}{
+		"voltage": int64(1),
+		"current": int64(2),
+	}, metrics[1].Fields())
+
+	_, err = parser.Parse([]byte{1, 2, 3, 4, 5})
+	assert.Error(t, err)
+}
+
+func TestParseTimestampFormat(t *testing.T) {
+	parser := &BinaryParser{
+		MetricName: "meter",
+		Fields: []Field{
+			{Name: "time", Type: "int64", Offset: 0},
+			{Name: "energy", Type: "float64", Offset: 8},
+		},
+		Timestamp:       "time",
+		TimestampFormat: "unix_ms",
+	}
+	record := make([]byte, 16)
+	binary.BigEndian.PutUint64(record, 1496318400123)
+	binary.BigEndian.PutUint64(record[8:], math.Float64bits(42.5))
+
+	m, err := parser.ParseLine(string(record))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"energy": 42.5}, m.Fields())
+	assert.Equal(t, int64(1496318400123), m.Time().UnixNano()/int64(time.Millisecond))
+}
+
+func TestParseErrors(t *testing.T) {
+	// The record is too short
+	_, err := meterParser().Parse(make([]byte, 10))
+	assert.Error(t, err)
+
+	// The timestamp is not an integer
+	parser := meterParser()
+	parser.Timestamp = "power"
+	_, err = parser.Parse(meterFrame("EM-42", 1496318400, 2301, 1530.5))
+	assert.Error(t, err)
+
+	for _, parser := range []*BinaryParser{
+		{},
+		{Fields: []Field{{Name: "a", Type: "int128"}}},
+		{Fields: []Field{{Name: "a", Type: "string"}}},
+		{Fields: []Field{{Name: "a", Type: "int8"}, {Name: "a", Type: "int8"}}},
+		{Fields: []Field{{Name: "a", Type: "int8", Offset: -1}}},
+		{Fields: []Field{{Name: "a", Type: "int8", Endianness: "middle"}}},
+		{Fields: []Field{{Type: "int8"}}},
+		{Fields: []Field{{Name: "a", Type: "int32"}}, RecordLength: 2},
+		{Fields: []Field{{Name: "a", Type: "int8"}}, Endianness: "pdp"},
+		{Fields: []Field{{Name: "a", Type: "int8"}}, Timestamp: "b"},
+		{Fields: []Field{{Name: "a", Type: "int8"}}, Timestamp: "a", TimestampFormat: "rfc3339"},
+	} {
+		assert.Error(t, parser.Check(), "%+v", parser)
+	}
+}
@@ -0,0 +1,336 @@
+package csv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// CSVParser makes a metric of each record of CSV data, with a field per
+// column by default.
+type CSVParser struct {
+	MetricName string
+
+	// HeaderRowCount is the number of rows naming the columns, the names of
+	// the columns spanning several rows are concatenated. The names are
+	// sanitized: the characters other than letters, digits and underscores
+	// are replaced by underscores, ie "Power (W)" becomes "Power_W".
+	HeaderRowCount int
+	// SkipRows is the number of lines skipped before the header, ie the
+	// preamble of an export.
+	SkipRows int
+	// SkipColumns is the number of leading columns skipped.
+	SkipColumns int
+	// Delimiter separates the columns, "," if empty.
+	Delimiter string
+	// Comment starts the lines to ignore, none if empty.
+	Comment string
+
+	// ColumnNames names the columns, overriding the header. The columns
+	// without a name are named column_<n>, starting at 1.
+	ColumnNames []string
+	// ColumnTypes are the types of the columns, "int", "float", "bool" or
+	// "string", in the order of the columns. The type is inferred from the
+	// value for the other columns.
+	ColumnTypes []string
+
+	// MeasurementColumn is the column of the measurement name, MetricName
+	// if empty.
+	MeasurementColumn string
+	// TagColumns are the columns making tags instead of fields.
+	TagColumns []string
+	// TimestampColumn is the column of the timestamp, the time of the
+	// parsing is used if empty. TimestampFormat is "unix", "unix_ms",
+	// "unix_us", "unix_ns" or a Go reference time layout, the default is
+	// RFC3339.
+	TimestampColumn string
+	TimestampFormat string
+	// Timezone is the location of the timestamps without a timezone, ie
+	// "Europe/Berlin" or "Local", UTC if empty.
+	Timezone string
+
+	DefaultTags map[string]string
+
+	// lines is the state of ParseLine, the header is read once from the
+	// first lines.
+	lines state
+}
+
+// state tracks the rows read before the records.
+type state struct {
+	skipped    int
+	headerRows int
+	header     []string
+}
+
+// Parse parses a whole CSV document, starting with its header if any.
+func (p *CSVParser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	loc, err := p.location()
+	if err != nil {
+		return nil, err
+	}
+
+	var s state
+	metrics := make([]telegraf.Metric, 0)
+	for len(buf) > 0 && s.skipped < p.SkipRows {
+		if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+			buf = buf[i+1:]
+		} else {
+			buf = nil
+		}
+		s.skipped++
+	}
+
+	r, err := p.newReader(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	for _, record := range records {
+		if s.headerRows < p.HeaderRowCount {
+			s.addHeader(p.skipColumns(record))
+			continue
+		}
+		m, err := p.parseRecord(p.skipColumns(record), s.header, now, loc)
+		if err != nil {
+			return nil, err
+		}
+		if m != nil {
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+// ParseLine parses a line of a CSV file read line by line, ie by the tail
+// input: the first lines are skipped or read as the header, no metric is
+// returned for them, nor for the records without any field.
+func (p *CSVParser) ParseLine(line string) (telegraf.Metric, error) {
+	if p.lines.skipped < p.SkipRows {
+		p.lines.skipped++
+		return nil, nil
+	}
+	if p.Comment != "" && strings.HasPrefix(line, p.Comment) {
+		return nil, nil
+	}
+
+	loc, err := p.location()
+	if err != nil {
+		return nil, err
+	}
+	r, err := p.newReader(strings.NewReader(line))
+	if err != nil {
+		return nil, err
+	}
+	record, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.lines.headerRows < p.HeaderRowCount {
+		p.lines.addHeader(p.skipColumns(record))
+		return nil, nil
+	}
+	return p.parseRecord(p.skipColumns(record), p.lines.header, time.Now().UTC(), loc)
+}
+
+func (p *CSVParser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
+
+func (p *CSVParser) newReader(data io.Reader) (*csv.Reader, error) {
+	r := csv.NewReader(data)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	if p.Delimiter != "" {
+		c, size := utf8.DecodeRuneInString(p.Delimiter)
+		if size != len(p.Delimiter) {
+			return nil, fmt.Errorf("delimiter %q is not a single character", p.Delimiter)
+		}
+		r.Comma = c
+	}
+	if p.Comment != "" {
+		c, size := utf8.DecodeRuneInString(p.Comment)
+		if size != len(p.Comment) {
+			return nil, fmt.Errorf("comment %q is not a single character", p.Comment)
+		}
+		r.Comment = c
+	}
+	return r, nil
+}
+
+func (p *CSVParser) location() (*time.Location, error) {
+	if p.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(p.Timezone)
+}
+
+func (p *CSVParser) skipColumns(record []string) []string {
+	if p.SkipColumns >= len(record) {
+		return nil
+	}
+	return record[p.SkipColumns:]
+}
+
+// addHeader adds a header row to the names of the columns.
+func (s *state) addHeader(row []string) {
+	for i, name := range row {
+		if i < len(s.header) {
+			s.header[i] += name
+		} else {
+			s.header = append(s.header, name)
+		}
+	}
+	s.headerRows++
+}
+
+// columnNames returns the sanitized names of the columns by index.
+func (p *CSVParser) columnNames(header []string, n int) []string {
+	names := make([]string, n)
+	seen := make(map[string]int)
+	for i := range names {
+		var name string
+		switch {
+		case i < len(p.ColumnNames):
+			name = p.ColumnNames[i]
+		case i < len(header):
+			name = sanitize(header[i])
+		}
+		if name == "" {
+			name = "column_" + strconv.Itoa(i+1)
+		}
+		// Deduplicated, ie two "Power (W)" columns of different inverters.
+		if seen[name]++; seen[name] > 1 {
+			name += "_" + strconv.Itoa(seen[name])
+		}
+		names[i] = name
+	}
+	return names
+}
+
+// sanitize replaces the characters of a header name other than letters,
+// digits and underscores by underscores, without leading, trailing or
+// repeated underscores.
+func sanitize(name string) string {
+	name = strings.TrimPrefix(name, "\ufeff")
+	var b bytes.Buffer
+	underscore := false
+	for _, r := range strings.TrimSpace(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			underscore = false
+		} else if !underscore {
+			b.WriteByte('_')
+			underscore = true
+		}
+	}
+	return strings.Trim(b.String(), "_")
+}
+
+func (p *CSVParser) parseRecord(
+	record []string,
+	header []string,
+	now time.Time,
+	loc *time.Location,
+) (telegraf.Metric, error) {
+	names := p.columnNames(header, len(record))
+
+	name := p.MetricName
+	tags := make(map[string]string)
+	for k, v := range p.DefaultTags {
+		tags[k] = v
+	}
+	fields := make(map[string]interface{})
+	t := now
+	haveTime := false
+
+columns:
+	for i, value := range record {
+		column := names[i]
+		switch column {
+		case p.MeasurementColumn:
+			if value != "" {
+				name = value
+			}
+			continue
+		case p.TimestampColumn:
+			var err error
+			if t, err = internal.ParseTimestamp(strings.TrimSpace(value), p.TimestampFormat, loc); err != nil {
+				return nil, err
+			}
+			haveTime = true
+			continue
+		}
+		for _, tag := range p.TagColumns {
+			if tag == column {
+				tags[column] = value
+				continue columns
+			}
+		}
+
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		var typ string
+		if i < len(p.ColumnTypes) {
+			typ = p.ColumnTypes[i]
+		}
+		v, err := convert(value, typ)
+		if err != nil {
+			return nil, fmt.Errorf("unable to convert column %q, %s", column, err)
+		}
+		fields[column] = v
+	}
+
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	if p.TimestampColumn != "" && !haveTime {
+		return nil, fmt.Errorf("timestamp column %q not found", p.TimestampColumn)
+	}
+	return metric.New(name, tags, fields, t)
+}
+
+// convert converts the value to the type, or to the type inferred from the
+// value if empty: int, float, bool or string.
+func convert(v string, typ string) (interface{}, error) {
+	switch typ {
+	case "":
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return i, nil
+		}
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f, nil
+		}
+		if v == "true" || v == "false" {
+			return v == "true", nil
+		}
+		return v, nil
+	case "int":
+		return strconv.ParseInt(v, 10, 64)
+	case "float":
+		return strconv.ParseFloat(v, 64)
+	case "bool":
+		return strconv.ParseBool(v)
+	case "string":
+		return v, nil
+	}
+	return nil, fmt.Errorf("unknown type %q", typ)
+}
@@ -0,0 +1,161 @@
+package csv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// inverterLog is an export of an inverter, with a preamble and a header
+// spanning two rows.
+const inverterLog = `Inverter export
+Serial number: 1234567
+Date;Serial;Inverter;AC Power;DC Voltage;Status
+;;;(W);(V);
+2017-06-01 12:00:00;1234567;inv-1;1530,5;350;OK
+2017-06-01 12:05:00;1234567;inv-1;1498;;OK
+`
+
+func TestParseInverterLog(t *testing.T) {
+	parser := &CSVParser{
+		MetricName:      "inverter",
+		SkipRows:        2,
+		HeaderRowCount:  2,
+		Delimiter:       ";",
+		TagColumns:      []string{"Inverter"},
+		TimestampColumn: "Date",
+		TimestampFormat: "2006-01-02 15:04:05",
+		Timezone:        "Europe/Berlin",
+		// The power has a decimal comma
+		ColumnTypes: []string{"", "string", "", "string"},
+	}
+
+	metrics, err := parser.Parse([]byte(inverterLog))
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+
+	assert.Equal(t, "inverter", metrics[0].Name())
+	assert.Equal(t, map[string]string{"Inverter": "inv-1"}, metrics[0].Tags())
+	assert.Equal(t, map[string]interface{}{
+		"Serial":       "1234567",
+		"AC_Power_W":   "1530,5",
+		"DC_Voltage_V": int64(350),
+		"Status":       "OK",
+	}, metrics[0].Fields())
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2017, 6, 1, 12, 0, 0, 0, berlin).UnixNano(),
+		metrics[0].Time().UnixNano())
+
+	// Empty values are skipped
+	assert.Equal(t, map[string]interface{}{
+		"Serial":     "1234567",
+		"AC_Power_W": "1498",
+		"Status":     "OK",
+	}, metrics[1].Fields())
+}
+
+func TestParseSkipColumns(t *testing.T) {
+	parser := &CSVParser{
+		MetricName:     "meter",
+		HeaderRowCount: 1,
+		SkipColumns:    2,
+		ColumnTypes:    []string{"float"},
+	}
+
+	metrics, err := parser.Parse([]byte("id,note,Energy (kWh),Energy (kWh),\n1,x,10,20,30\n"))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, map[string]interface{}{
+		"Energy_kWh":   10.0,
+		"Energy_kWh_2": int64(20),
+		"column_3":     int64(30),
+	}, metrics[0].Fields())
+}
+
+func TestParseColumnNames(t *testing.T) {
+	parser := &CSVParser{
+		MetricName:        "sensor",
+		ColumnNames:       []string{"name", "room", "time", "temperature", "online"},
+		MeasurementColumn: "name",
+		TagColumns:        []string{"room"},
+		TimestampColumn:   "time",
+		TimestampFormat:   "unix",
+		Comment:           "#",
+	}
+
+	metrics, err := parser.Parse([]byte("# sensors\nthermo,kitchen,1500000000,21.5,true\n"))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "thermo", metrics[0].Name())
+	assert.Equal(t, map[string]string{"room": "kitchen"}, metrics[0].Tags())
+	assert.Equal(t, map[string]interface{}{
+		"temperature": 21.5,
+		"online":      true,
+	}, metrics[0].Fields())
+	assert.Equal(t, int64(1500000000), metrics[0].Time().Unix())
+}
+
+func TestParseLine(t *testing.T) {
+	parser := &CSVParser{
+		MetricName:     "inverter",
+		SkipRows:       1,
+		HeaderRowCount: 1,
+	}
+
+	m, err := parser.ParseLine("export of inv-1")
+	require.NoError(t, err)
+	assert.Nil(t, m)
+	m, err = parser.ParseLine(" AC Power , Status")
+	require.NoError(t, err)
+	assert.Nil(t, m)
+
+	m, err = parser.ParseLine("1530.5,OK")
+	require.NoError(t, err)
+	require.NotNil(t, m)
+	assert.Equal(t, map[string]interface{}{
+		"AC_Power": 1530.5,
+		"Status":   "OK",
+	}, m.Fields())
+
+	m, err = parser.ParseLine("1498,OK")
+	require.NoError(t, err)
+	assert.Equal(t, 1498, int(m.Fields()["AC_Power"].(int64)))
+}
+
+func TestParseErrors(t *testing.T) {
+	parser := &CSVParser{
+		MetricName:  "test",
+		ColumnTypes: []string{"int"},
+	}
+	_, err := parser.Parse([]byte("abc\n"))
+	assert.Error(t, err)
+
+	parser = &CSVParser{MetricName: "test", Timezone: "Mars/Olympus"}
+	_, err = parser.Parse([]byte("1\n"))
+	assert.Error(t, err)
+
+	parser = &CSVParser{
+		MetricName:      "test",
+		ColumnNames:     []string{"value", "time"},
+		TimestampColumn: "time",
+	}
+	_, err = parser.Parse([]byte("1\n"))
+	assert.Error(t, err)
+	_, err = parser.Parse([]byte("1,yesterday\n"))
+	assert.Error(t, err)
+
+	parser = &CSVParser{MetricName: "test", Delimiter: ";;"}
+	_, err = parser.Parse([]byte("1\n"))
+	assert.Error(t, err)
+}
+
+func TestSanitize(t *testing.T) {
+	assert.Equal(t, "AC_Power_W", sanitize(" AC Power (W) "))
+	assert.Equal(t, "Temp_C", sanitize("\ufeffTemp °C"))
+	assert.Equal(t, "a_b", sanitize("a__b"))
+	assert.Equal(t, "", sanitize(" (%) "))
+}
@@ -0,0 +1,150 @@
+package influx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// ErrLineTooLong is returned by StreamParser.Next for the lines longer than
+// its buffer, which are skipped.
+var ErrLineTooLong = errors.New("line too long")
+
+// ParseError is the error of a line of the stream.
+type ParseError struct {
+	Line int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+// StreamParser parses the line protocol metrics read from a reader one at a
+// time, the memory being bounded by its buffer whatever the size of the
+// payload.
+type StreamParser struct {
+	// DefaultTags will be added to every parsed metric
+	DefaultTags map[string]string
+	// DefaultTime is the time of the metrics without timestamp, and
+	// Precision the precision of the timestamps, nanoseconds if empty.
+	DefaultTime time.Time
+	Precision   string
+
+	r   io.Reader
+	buf []byte
+	// buf[start:end] is read but not parsed yet.
+	start, end int
+	eof        bool
+	// skipping is set while the rest of a line too long is discarded.
+	skipping bool
+	lineno   int
+	metrics  []telegraf.Metric
+}
+
+// NewStreamParser returns a parser of the metrics read from r, buf holding
+// the lines being parsed: the lines longer than buf are skipped.
+func NewStreamParser(r io.Reader, buf []byte) *StreamParser {
+	return &StreamParser{
+		DefaultTime: time.Now(),
+		r:           r,
+		buf:         buf,
+	}
+}
+
+// Next returns the next metric, or io.EOF once the reader is consumed. The
+// errors of the lines failing to parse or too long are ParseErrors, Next may
+// be called again to parse the next lines. The errors of the reader are
+// returned as is.
+func (p *StreamParser) Next() (telegraf.Metric, error) {
+	for len(p.metrics) == 0 {
+		line, err := p.readLine()
+		if err == ErrLineTooLong {
+			return nil, &ParseError{Line: p.lineno,
+				Err: fmt.Errorf("%s, longer than the maximum of %d bytes", err, len(p.buf))}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		if line[len(line)-1] != '\n' {
+			line = append(line[:len(line):len(line)], '\n')
+		}
+
+		metrics, err := metric.ParseWithDefaultTimePrecision(line, p.DefaultTime, p.Precision)
+		if err != nil {
+			return nil, &ParseError{Line: p.lineno, Err: err}
+		}
+		p.metrics = metrics
+	}
+
+	m := p.metrics[0]
+	p.metrics = p.metrics[1:]
+	for k, v := range p.DefaultTags {
+		// only set the default tag if it doesn't already exist:
+		if !m.HasTag(k) {
+			m.AddTag(k, v)
+		}
+	}
+	return m, nil
+}
+
+// readLine returns the next line, with its newline unless it is the last
+// line of the reader. The line is only valid until the next call.
+func (p *StreamParser) readLine() ([]byte, error) {
+	for {
+		if i := bytes.IndexByte(p.buf[p.start:p.end], '\n'); i >= 0 {
+			line := p.buf[p.start : p.start+i+1]
+			p.start += i + 1
+			if p.skipping {
+				// The end of the line too long.
+				p.skipping = false
+				continue
+			}
+			p.lineno++
+			return line, nil
+		}
+
+		if p.eof {
+			line := p.buf[p.start:p.end]
+			p.start = p.end
+			if len(line) == 0 || p.skipping {
+				p.skipping = false
+				return nil, io.EOF
+			}
+			p.lineno++
+			return line, nil
+		}
+
+		// Move the beginning of the line to the front of the buffer, or
+		// drop the line if it fills the buffer.
+		if p.start > 0 {
+			copy(p.buf, p.buf[p.start:p.end])
+			p.end -= p.start
+			p.start = 0
+		}
+		if p.end == len(p.buf) {
+			p.end = 0
+			if !p.skipping {
+				p.skipping = true
+				p.lineno++
+				return nil, ErrLineTooLong
+			}
+		}
+
+		n, err := p.r.Read(p.buf[p.end:])
+		p.end += n
+		if err == io.EOF {
+			p.eof = true
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package influx
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/influxdata/telegraf"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseAll returns the metrics and the errors of the stream.
+func parseAll(p *StreamParser) ([]telegraf.Metric, []error) {
+	var metrics []telegraf.Metric
+	var errs []error
+	for {
+		m, err := p.Next()
+		if err == io.EOF {
+			return metrics, errs
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		metrics = append(metrics, m)
+	}
+}
+
+func TestStreamParse(t *testing.T) {
+	// The reader returns a byte at a time to parse the lines across reads.
+	p := NewStreamParser(iotest.OneByteReader(strings.NewReader(influxMulti+validInfluxNoNewline)),
+		make([]byte, 128))
+	p.DefaultTags = map[string]string{"host": "bar", "source": "http"}
+
+	metrics, errs := parseAll(p)
+	assert.Empty(t, errs)
+	require.Len(t, metrics, 8)
+	assert.Equal(t, map[string]string{
+		"host":       "foo",
+		"datacenter": "us-east",
+		"source":     "http",
+	}, metrics[0].Tags())
+	assert.Equal(t, "cpu_load_short", metrics[7].Name())
+	assert.Equal(t, exptime, metrics[7].Time().UnixNano())
+}
+
+func TestStreamParseDefaultTime(t *testing.T) {
+	p := NewStreamParser(strings.NewReader("cpu value=1 1257894000\n"), make([]byte, 64))
+	p.Precision = "s"
+	m, err := p.Next()
+	require.NoError(t, err)
+	assert.Equal(t, exptime, m.Time().UnixNano())
+
+	p = NewStreamParser(strings.NewReader("cpu value=1\n"), make([]byte, 64))
+	p.DefaultTime = time.Unix(0, exptime)
+	m, err = p.Next()
+	require.NoError(t, err)
+	assert.Equal(t, exptime, m.Time().UnixNano())
+}
+
+func TestStreamParseErrors(t *testing.T) {
+	p := NewStreamParser(strings.NewReader(influxMultiSomeInvalid), make([]byte, 128))
+	metrics, errs := parseAll(p)
+	assert.Len(t, metrics, 4)
+	require.Len(t, errs, 2)
+	assert.Contains(t, errs[0].Error(), "line 5:")
+	assert.Contains(t, errs[1].Error(), "line 6:")
+}
+
+func TestStreamParseLineTooLong(t *testing.T) {
+	long := "cpu,host=" + strings.Repeat("x", 300) + " value=1\n"
+	p := NewStreamParser(strings.NewReader(validInflux+long+validInflux+long), make([]byte, 64))
+	metrics, errs := parseAll(p)
+	assert.Len(t, metrics, 2)
+	require.Len(t, errs, 2)
+	assert.Equal(t, 2, errs[0].(*ParseError).Line)
+	assert.Equal(t, 4, errs[1].(*ParseError).Line)
+}
+
+func TestStreamParseLarge(t *testing.T) {
+	// The payload is much larger than the buffer.
+	var payload bytes.Buffer
+	for i := 0; i < 10000; i++ {
+		payload.WriteString(validInflux)
+	}
+	p := NewStreamParser(&payload, make([]byte, 1024))
+	n := 0
+	for {
+		_, err := p.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		n++
+	}
+	assert.Equal(t, 10000, n)
+}
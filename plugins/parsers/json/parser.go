@@ -9,13 +9,24 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/metric"
 )
 
 type JSONParser struct {
-	MetricName  string
-	TagKeys     []string
-	DefaultTags map[string]string
+	MetricName string
+	TagKeys    []string
+	// TimeKey is the key of the timestamp, a dot separated path of object
+	// keys and array indexes for the nested timestamps, ie "meta.time". The
+	// time of the parsing is used if empty. TimeFormat is "unix", "unix_ms",
+	// "unix_us", "unix_ns" or a Go reference time layout, the numbers being
+	// in seconds and the strings RFC3339 timestamps if empty.
+	TimeKey    string
+	TimeFormat string
+	// StringsAsNumbers converts the numeric strings, ie "230.5", into float
+	// fields instead of ignoring them.
+	StringsAsNumbers bool
+	DefaultTags      map[string]string
 }
 
 func (p *JSONParser) parseArray(buf []byte) ([]telegraf.Metric, error) {
@@ -29,6 +40,9 @@ func (p *JSONParser) parseArray(buf []byte) ([]telegraf.Metric, error) {
 	}
 	for _, item := range jsonOut {
 		metrics, err = p.parseObject(metrics, item)
+		if err != nil {
+			return nil, err
+		}
 	}
 	return metrics, nil
 }
@@ -52,13 +66,29 @@ func (p *JSONParser) parseObject(metrics []telegraf.Metric, jsonOut map[string]i
 		delete(jsonOut, tag)
 	}
 
-	f := JSONFlattener{}
+	t := time.Now().UTC()
+	if p.TimeKey != "" {
+		v, ok := pop(jsonOut, strings.Split(p.TimeKey, "."))
+		if !ok {
+			return nil, fmt.Errorf("JSON time key %q not found", p.TimeKey)
+		}
+		format := p.TimeFormat
+		if _, ok := v.(string); !ok && format == "" {
+			format = "unix"
+		}
+		var err error
+		if t, err = internal.ParseTimestamp(v, format, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	f := JSONFlattener{StringsAsNumbers: p.StringsAsNumbers}
 	err := f.FlattenJSON("", jsonOut)
 	if err != nil {
 		return nil, err
 	}
 
-	metric, err := metric.New(p.MetricName, tags, f.Fields, time.Now().UTC())
+	metric, err := metric.New(p.MetricName, tags, f.Fields, t)
 
 	if err != nil {
 		return nil, err
@@ -66,6 +96,34 @@ func (p *JSONParser) parseObject(metrics []telegraf.Metric, jsonOut map[string]i
 	return append(metrics, metric), nil
 }
 
+// pop removes the value at the path, of object keys and array indexes, from
+// the document and returns it, or false if there is none. The elements of
+// the arrays are left in place.
+func pop(v interface{}, path []string) (interface{}, bool) {
+	for i, key := range path {
+		last := i == len(path)-1
+		switch t := v.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if v, ok = t[key]; !ok {
+				return nil, false
+			}
+			if last {
+				delete(t, key)
+			}
+		case []interface{}:
+			n, err := strconv.Atoi(key)
+			if err != nil || n < 0 || n >= len(t) {
+				return nil, false
+			}
+			v = t[n]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
 func (p *JSONParser) Parse(buf []byte) ([]telegraf.Metric, error) {
 
 	if !isarray(buf) {
@@ -101,6 +159,8 @@ func (p *JSONParser) SetDefaultTags(tags map[string]string) {
 
 type JSONFlattener struct {
 	Fields map[string]interface{}
+	// StringsAsNumbers converts the numeric strings into floats.
+	StringsAsNumbers bool
 }
 
 // FlattenJSON flattens nested maps/interfaces into a fields map (ignoring bools and string)
@@ -143,6 +203,12 @@ func (f *JSONFlattener) FullFlattenJSON(
 	case float64:
 		f.Fields[fieldname] = t
 	case string:
+		if f.StringsAsNumbers {
+			if n, err := strconv.ParseFloat(strings.TrimSpace(t), 64); err == nil {
+				f.Fields[fieldname] = n
+				return nil
+			}
+		}
 		if convertString {
 			f.Fields[fieldname] = v.(string)
 		} else {
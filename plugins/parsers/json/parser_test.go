@@ -418,3 +418,55 @@ func TestParseArrayWithTagKeys(t *testing.T) {
 		"othertag": "baz",
 	}, metrics[1].Tags())
 }
+
+func TestParseTimeKey(t *testing.T) {
+	parser := JSONParser{
+		MetricName: "json_test",
+		TimeKey:    "meta.time",
+		TimeFormat: "unix_ms",
+	}
+	metrics, err := parser.Parse([]byte(`{"a": 5, "meta": {"time": 1496318400123, "id": 3}}`))
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 1)
+	assert.Equal(t, map[string]interface{}{
+		"a":       float64(5),
+		"meta_id": float64(3),
+	}, metrics[0].Fields())
+	assert.Equal(t, int64(1496318400123000000), metrics[0].Time().UnixNano())
+
+	parser.TimeKey = "samples.1.at"
+	parser.TimeFormat = "2006-01-02 15:04:05"
+	metrics, err = parser.Parse([]byte(`[{"samples": [{"at": "x"}, {"at": "2017-06-01 12:00:00", "v": 1}]}]`))
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 1)
+	assert.Equal(t, map[string]interface{}{
+		"samples_1_v": float64(1),
+	}, metrics[0].Fields())
+	assert.Equal(t, int64(1496318400), metrics[0].Time().Unix())
+
+	parser.TimeKey = "time"
+	parser.TimeFormat = ""
+	metrics, err = parser.Parse([]byte(`{"a": 5, "time": "2017-06-01T12:00:00Z"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1496318400), metrics[0].Time().Unix())
+
+	_, err = parser.Parse([]byte(`{"a": 5}`))
+	assert.Error(t, err)
+	_, err = parser.Parse([]byte(`[{"a": 5, "time": 1496318400}, {"a": 5, "time": true}]`))
+	assert.Error(t, err)
+}
+
+func TestParseStringsAsNumbers(t *testing.T) {
+	parser := JSONParser{
+		MetricName:       "json_test",
+		StringsAsNumbers: true,
+	}
+	metrics, err := parser.Parse([]byte(`{"voltage": "230.5", "current": " 4 ", "serial": "EM-42", "power": 1127}`))
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 1)
+	assert.Equal(t, map[string]interface{}{
+		"voltage": float64(230.5),
+		"current": float64(4),
+		"power":   float64(1127),
+	}, metrics[0].Fields())
+}
@@ -0,0 +1,290 @@
+package json_v2
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// JSONV2Parser makes metrics of the values selected with GJSON paths in
+// JSON documents, so nested documents like the responses of REST APIs can
+// be parsed.
+//
+// The Query selects the object, or the array of objects, each becoming a
+// metric; all other paths are relative to these objects. The arrays of
+// arrays selected by iterating with "#" are flattened.
+type JSONV2Parser struct {
+	MetricName string
+
+	Query string
+	// NamePath is the path of the measurement name, MetricName if empty.
+	NamePath string
+	// TimePath is the path of the timestamp, the time of the parsing is used
+	// if empty. TimeFormat is "unix", "unix_ms", "unix_us", "unix_ns" or a
+	// Go reference time layout, the default is RFC3339.
+	TimePath   string
+	TimeFormat string
+
+	// TagPaths and FieldPaths map the tag and field names to their paths.
+	// When no field paths are set the numeric and boolean values of the
+	// objects are flattened into fields, like the json data format does.
+	TagPaths   map[string]string
+	FieldPaths map[string]string
+	// FieldTypes converts the fields to "int", "float", "string" or "bool",
+	// the type is inferred from the JSON value otherwise.
+	FieldTypes map[string]string
+
+	DefaultTags map[string]string
+}
+
+// Parse makes a metric of each object selected by the query, the objects
+// without any field are skipped.
+func (p *JSONV2Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("unable to parse out as JSON, %s", err)
+	}
+
+	metrics := make([]telegraf.Metric, 0)
+	selected, ok := query(doc, p.Query)
+	if !ok {
+		return metrics, nil
+	}
+	objects := flatten(nil, selected)
+
+	now := time.Now().UTC()
+	for _, obj := range objects {
+		m, err := p.parseObject(obj, now)
+		if err != nil {
+			return nil, err
+		}
+		if m != nil {
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+// flatten appends the elements of the arrays, and of the arrays nested in
+// them when iterating over several arrays with "#", or the value itself.
+func flatten(objects []interface{}, v interface{}) []interface{} {
+	if elems, ok := v.([]interface{}); ok {
+		for _, elem := range elems {
+			objects = flatten(objects, elem)
+		}
+		return objects
+	}
+	return append(objects, v)
+}
+
+func (p *JSONV2Parser) parseObject(obj interface{}, now time.Time) (telegraf.Metric, error) {
+	name := p.MetricName
+	if p.NamePath != "" {
+		if v, ok := query(obj, p.NamePath); ok {
+			if s, ok := tagValue(v); ok && s != "" {
+				name = s
+			}
+		}
+	}
+
+	tags := make(map[string]string)
+	for k, v := range p.DefaultTags {
+		tags[k] = v
+	}
+	for tag, path := range p.TagPaths {
+		if v, ok := query(obj, path); ok {
+			if s, ok := tagValue(v); ok {
+				tags[tag] = s
+			}
+		}
+	}
+
+	fields := make(map[string]interface{})
+	if len(p.FieldPaths) == 0 {
+		if err := p.addFields(fields, "", obj, ""); err != nil {
+			return nil, err
+		}
+		// The timestamp is not a field
+		delete(fields, strings.Join(splitPath(p.TimePath), "_"))
+	}
+	// Sorted so the errors are reported consistently.
+	names := make([]string, 0, len(p.FieldPaths))
+	for field := range p.FieldPaths {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+	for _, field := range names {
+		if v, ok := query(obj, p.FieldPaths[field]); ok {
+			if err := p.addFields(fields, field, v, p.FieldTypes[field]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	t := now
+	if p.TimePath != "" {
+		v, _ := query(obj, p.TimePath)
+		var err error
+		if t, err = internal.ParseTimestamp(v, p.TimeFormat, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return metric.New(name, tags, fields, t)
+}
+
+// addFields adds the value as the field, the objects and arrays are
+// flattened into one field per value, named after their keys and indexes.
+func (p *JSONV2Parser) addFields(
+	fields map[string]interface{},
+	field string,
+	v interface{},
+	typ string,
+) error {
+	join := func(key string) string {
+		if field == "" {
+			return key
+		}
+		return field + "_" + key
+	}
+
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for key, elem := range t {
+			if err := p.addFields(fields, join(key), elem, typ); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []interface{}:
+		for i, elem := range t {
+			if err := p.addFields(fields, join(strconv.Itoa(i)), elem, typ); err != nil {
+				return err
+			}
+		}
+		return nil
+	case nil:
+		return nil
+	case string:
+		// Strings are only kept when their field is requested, they are
+		// usually tags otherwise.
+		if typ == "" && len(p.FieldPaths) == 0 {
+			return nil
+		}
+	}
+
+	value, err := convert(v, typ)
+	if err != nil {
+		return fmt.Errorf("unable to convert field %q, %s", field, err)
+	}
+	fields[field] = value
+	return nil
+}
+
+// convert converts the JSON value to the type, or to the type inferred from
+// the value if empty: int64 for the integers and float64 for the other
+// numbers.
+func convert(v interface{}, typ string) (interface{}, error) {
+	switch typ {
+	case "":
+		if n, ok := v.(json.Number); ok {
+			if i, err := n.Int64(); err == nil {
+				return i, nil
+			}
+			return n.Float64()
+		}
+		return v, nil
+	case "int":
+		switch t := v.(type) {
+		case json.Number:
+			if i, err := t.Int64(); err == nil {
+				return i, nil
+			}
+			f, err := t.Float64()
+			return int64(f), err
+		case string:
+			return strconv.ParseInt(t, 10, 64)
+		case bool:
+			if t {
+				return int64(1), nil
+			}
+			return int64(0), nil
+		}
+	case "float":
+		switch t := v.(type) {
+		case json.Number:
+			return t.Float64()
+		case string:
+			return strconv.ParseFloat(t, 64)
+		case bool:
+			if t {
+				return 1.0, nil
+			}
+			return 0.0, nil
+		}
+	case "string":
+		switch t := v.(type) {
+		case json.Number:
+			return t.String(), nil
+		case string:
+			return t, nil
+		case bool:
+			return strconv.FormatBool(t), nil
+		}
+	case "bool":
+		switch t := v.(type) {
+		case json.Number:
+			f, err := t.Float64()
+			return f != 0, err
+		case string:
+			return strconv.ParseBool(t)
+		case bool:
+			return t, nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown type %q", typ)
+	}
+	return nil, fmt.Errorf("cannot convert %v to %s", v, typ)
+}
+
+func tagValue(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case json.Number:
+		return v.String(), true
+	}
+	return "", false
+}
+
+func (p *JSONV2Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("Can not parse the line: %s, for data format: json_v2", line)
+	}
+
+	return metrics[0], nil
+}
+
+func (p *JSONV2Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
@@ -0,0 +1,214 @@
+package json_v2
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const siteEnergy = `
+{
+  "sitesEnergy": {
+    "timeUnit": "DAY",
+    "unit": "Wh",
+    "count": 2,
+    "siteEnergyList": [
+      {
+        "siteId": 1,
+        "energyValues": {
+          "measuredBy": "INVERTER",
+          "values": [
+            {"date": "2017-06-01 00:00:00", "value": 12500.5},
+            {"date": "2017-06-02 00:00:00", "value": null}
+          ]
+        }
+      },
+      {
+        "siteId": 2,
+        "energyValues": {
+          "measuredBy": "METER",
+          "values": [
+            {"date": "2017-06-01 00:00:00", "value": 8100}
+          ]
+        }
+      }
+    ]
+  }
+}
+`
+
+func TestQuery(t *testing.T) {
+	var doc interface{}
+	dec := json.NewDecoder(strings.NewReader(`{"a": {"b.c": [1, 2, {"d": 3}]}, "list": [{"id": "x"}, {"id": "y"}, {}]}`))
+	dec.UseNumber()
+	require.NoError(t, dec.Decode(&doc))
+
+	v, ok := query(doc, `a.b\.c.2.d`)
+	require.True(t, ok)
+	assert.Equal(t, json.Number("3"), v)
+
+	v, ok = query(doc, `a.b\.c.#`)
+	require.True(t, ok)
+	assert.Equal(t, int64(3), v)
+
+	v, ok = query(doc, "list.#.id")
+	require.True(t, ok)
+	assert.Equal(t, []interface{}{"x", "y"}, v)
+
+	_, ok = query(doc, "a.missing")
+	assert.False(t, ok)
+	_, ok = query(doc, `a.b\.c.5`)
+	assert.False(t, ok)
+
+	v, ok = query(doc, "")
+	require.True(t, ok)
+	assert.Equal(t, doc, v)
+}
+
+func TestParseArrayOfObjects(t *testing.T) {
+	parser := &JSONV2Parser{
+		MetricName: "solaredge",
+		Query:      "sitesEnergy.siteEnergyList",
+		TagPaths: map[string]string{
+			"site":        "siteId",
+			"measured_by": "energyValues.measuredBy",
+		},
+		FieldPaths: map[string]string{
+			"energy":      "energyValues.values.0.value",
+			"value_count": "energyValues.values.#",
+		},
+		FieldTypes:  map[string]string{"energy": "float"},
+		DefaultTags: map[string]string{"unit": "Wh"},
+	}
+
+	metrics, err := parser.Parse([]byte(siteEnergy))
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+
+	assert.Equal(t, "solaredge", metrics[0].Name())
+	assert.Equal(t, map[string]string{
+		"site":        "1",
+		"measured_by": "INVERTER",
+		"unit":        "Wh",
+	}, metrics[0].Tags())
+	assert.Equal(t, map[string]interface{}{
+		"energy":      12500.5,
+		"value_count": int64(2),
+	}, metrics[0].Fields())
+
+	assert.Equal(t, map[string]interface{}{
+		"energy":      8100.0,
+		"value_count": int64(1),
+	}, metrics[1].Fields())
+}
+
+func TestParseIterateArray(t *testing.T) {
+	parser := &JSONV2Parser{
+		MetricName: "solaredge",
+		Query:      "sitesEnergy.siteEnergyList.#.energyValues.values",
+		FieldPaths: map[string]string{"energy": "value"},
+		TimePath:   "date",
+		TimeFormat: "2006-01-02 15:04:05",
+	}
+
+	// The values of all the sites, the null value is skipped.
+	metrics, err := parser.Parse([]byte(siteEnergy))
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	assert.Equal(t, map[string]interface{}{"energy": 12500.5}, metrics[0].Fields())
+	assert.Equal(t, map[string]interface{}{"energy": int64(8100)}, metrics[1].Fields())
+
+	parser.Query = "sitesEnergy.siteEnergyList.0.energyValues.values"
+	metrics, err = parser.Parse([]byte(siteEnergy))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, map[string]interface{}{"energy": 12500.5}, metrics[0].Fields())
+	assert.Equal(t, time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC).UnixNano(),
+		metrics[0].Time().UnixNano())
+}
+
+func TestParseFlattenDefault(t *testing.T) {
+	parser := &JSONV2Parser{
+		MetricName: "overview",
+		Query:      "overview",
+		NamePath:   "kind",
+		TimePath:   "lastUpdate",
+		TimeFormat: "unix",
+	}
+
+	metrics, err := parser.Parse([]byte(`
+{
+  "overview": {
+    "kind": "site_overview",
+    "lastUpdate": 1500000000,
+    "currentPower": {"power": 1530.5},
+    "lifeTimeData": {"energy": 761985},
+    "online": true
+  }
+}`))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "site_overview", metrics[0].Name())
+	assert.Equal(t, map[string]interface{}{
+		"currentPower_power":  1530.5,
+		"lifeTimeData_energy": int64(761985),
+		"online":              true,
+	}, metrics[0].Fields())
+	assert.Equal(t, int64(1500000000), metrics[0].Time().Unix())
+}
+
+func TestParseFieldTypes(t *testing.T) {
+	parser := &JSONV2Parser{
+		MetricName: "device",
+		FieldPaths: map[string]string{
+			"status":  "status",
+			"serial":  "serial",
+			"enabled": "enabled",
+			"count":   "count",
+		},
+		FieldTypes: map[string]string{
+			"serial":  "string",
+			"enabled": "bool",
+			"count":   "int",
+		},
+	}
+
+	m, err := parser.ParseLine(`{"status": "OK", "serial": 1234, "enabled": "true", "count": "7"}`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"status":  "OK",
+		"serial":  "1234",
+		"enabled": true,
+		"count":   int64(7),
+	}, m.Fields())
+
+	_, err = parser.ParseLine(`{"count": "seven"}`)
+	assert.Error(t, err)
+}
+
+func TestParseErrors(t *testing.T) {
+	parser := &JSONV2Parser{
+		MetricName: "test",
+		FieldPaths: map[string]string{"value": "value"},
+		TimePath:   "time",
+	}
+
+	_, err := parser.Parse([]byte(`{"value": 1`))
+	assert.Error(t, err)
+
+	_, err = parser.Parse([]byte(`{"value": 1, "time": 1500000000}`))
+	assert.Error(t, err)
+
+	_, err = parser.Parse([]byte(`{"value": 1, "time": "yesterday"}`))
+	assert.Error(t, err)
+
+	// Nothing selected by the query
+	parser.Query = "missing"
+	metrics, err := parser.Parse([]byte(`{"value": 1}`))
+	require.NoError(t, err)
+	assert.Len(t, metrics, 0)
+}
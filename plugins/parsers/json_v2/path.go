@@ -0,0 +1,70 @@
+package json_v2
+
+import (
+	"strconv"
+)
+
+// splitPath splits a GJSON path into its components, on the dots not
+// escaped with a backslash.
+func splitPath(path string) []string {
+	var parts []string
+	var part []byte
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && i+1 < len(path):
+			i++
+			part = append(part, path[i])
+		case path[i] == '.':
+			parts = append(parts, string(part))
+			part = part[:0]
+		default:
+			part = append(part, path[i])
+		}
+	}
+	return append(parts, string(part))
+}
+
+// query returns the value at the GJSON path in the decoded JSON document,
+// and false if there is none. The path is a dot separated list of object
+// keys and array indexes; "#" returns the length of an array when last, or
+// applies the rest of the path to every element of the array otherwise.
+// The empty path and "@this" return the document itself.
+func query(v interface{}, path string) (interface{}, bool) {
+	if path == "" || path == "@this" {
+		return v, true
+	}
+	return lookup(v, splitPath(path))
+}
+
+func lookup(v interface{}, parts []string) (interface{}, bool) {
+	for i, part := range parts {
+		switch t := v.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if v, ok = t[part]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			if part == "#" {
+				if i == len(parts)-1 {
+					return int64(len(t)), true
+				}
+				values := make([]interface{}, 0, len(t))
+				for _, elem := range t {
+					if value, ok := lookup(elem, parts[i+1:]); ok {
+						values = append(values, value)
+					}
+				}
+				return values, true
+			}
+			n, err := strconv.Atoi(part)
+			if err != nil || n < 0 || n >= len(t) {
+				return nil, false
+			}
+			v = t[n]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
@@ -0,0 +1,114 @@
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const nodeExporter = `
+# HELP http_requests_total The total number of HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total{method="post",code="200"} 1027 1395066363000
+http_requests_total{method="post",code="400"} 3 1395066363000
+# HELP temperature_celsius The temperature.
+# TYPE temperature_celsius gauge
+temperature_celsius{room="kitchen"} 21.5
+temperature_celsius{room="cellar"} NaN
+# HELP rpc_duration_seconds A summary of the RPC duration in seconds.
+# TYPE rpc_duration_seconds summary
+rpc_duration_seconds{quantile="0.5"} 4773
+rpc_duration_seconds{quantile="0.99"} 76656
+rpc_duration_seconds_sum 1.7560473e+07
+rpc_duration_seconds_count 2693
+# HELP request_duration_seconds A histogram of the request duration.
+# TYPE request_duration_seconds histogram
+request_duration_seconds_bucket{le="0.1"} 33444
+request_duration_seconds_bucket{le="0.5"} 129389
+request_duration_seconds_bucket{le="+Inf"} 144320
+request_duration_seconds_sum 53423
+request_duration_seconds_count 144320
+version 3
+`
+
+func TestParse(t *testing.T) {
+	parser := &PrometheusParser{
+		DefaultTags: map[string]string{"host": "node-1"},
+	}
+
+	metrics, err := parser.Parse([]byte(nodeExporter))
+	require.NoError(t, err)
+	// The NaN gauge is skipped
+	require.Len(t, metrics, 6)
+
+	// Sorted by name
+	m := metrics[0]
+	assert.Equal(t, "http_requests_total", m.Name())
+	assert.Equal(t, map[string]string{
+		"method": "post",
+		"code":   "200",
+		"host":   "node-1",
+	}, m.Tags())
+	assert.Equal(t, map[string]interface{}{"counter": float64(1027)}, m.Fields())
+	assert.Equal(t, telegraf.Counter, m.Type())
+	assert.Equal(t, time.Unix(1395066363, 0).UnixNano(), m.Time().UnixNano())
+
+	m = metrics[2]
+	assert.Equal(t, "request_duration_seconds", m.Name())
+	assert.Equal(t, map[string]interface{}{
+		"0.1":   float64(33444),
+		"0.5":   float64(129389),
+		"+Inf":  float64(144320),
+		"sum":   float64(53423),
+		"count": float64(144320),
+	}, m.Fields())
+	assert.Equal(t, telegraf.Untyped, m.Type())
+
+	m = metrics[3]
+	assert.Equal(t, "rpc_duration_seconds", m.Name())
+	assert.Equal(t, map[string]interface{}{
+		"0.5":   float64(4773),
+		"0.99":  float64(76656),
+		"sum":   1.7560473e+07,
+		"count": float64(2693),
+	}, m.Fields())
+
+	m = metrics[4]
+	assert.Equal(t, "temperature_celsius", m.Name())
+	assert.Equal(t, "kitchen", m.Tags()["room"])
+	assert.Equal(t, map[string]interface{}{"gauge": 21.5}, m.Fields())
+	assert.Equal(t, telegraf.Gauge, m.Type())
+
+	m = metrics[5]
+	assert.Equal(t, "version", m.Name())
+	assert.Equal(t, map[string]interface{}{"value": float64(3)}, m.Fields())
+}
+
+func TestParseNaN(t *testing.T) {
+	for _, typ := range []string{"counter", "untyped"} {
+		metrics, err := Parse([]byte("# TYPE test "+typ+"\ntest NaN\n"), nil)
+		require.NoError(t, err, typ)
+		assert.Len(t, metrics, 0, typ)
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	parser := &PrometheusParser{}
+	m, err := parser.ParseLine(`up{job="node"} 1`)
+	require.NoError(t, err)
+	assert.Equal(t, "up", m.Name())
+	assert.Equal(t, map[string]string{"job": "node"}, m.Tags())
+	assert.Equal(t, map[string]interface{}{"value": float64(1)}, m.Fields())
+
+	_, err = parser.ParseLine("# HELP up Whether the target is up.")
+	assert.Error(t, err)
+}
+
+func TestParseInvalid(t *testing.T) {
+	parser := &PrometheusParser{}
+	_, err := parser.Parse([]byte("cpu,host=foo usage_idle=99\n"))
+	assert.Error(t, err)
+}
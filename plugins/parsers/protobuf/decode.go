@@ -0,0 +1,246 @@
+package protobuf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// decoder decodes the protobuf wire format with the types of a descriptor
+// set.
+type decoder struct {
+	types *types
+}
+
+// decode returns the fields of a message by name: bool, int64, uint64,
+// float64, string, time.Time for google.protobuf.Timestamp,
+// []interface{} for the repeated fields and map[string]interface{} for the
+// messages and maps. The enums are the names of their values.
+func (d *decoder) decode(m *message, buf []byte) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	for len(buf) > 0 {
+		key, n := binary.Uvarint(buf)
+		if n <= 0 {
+			return nil, fmt.Errorf("invalid field key")
+		}
+		buf = buf[n:]
+		number, wire := int32(key>>3), int(key&7)
+
+		var raw uint64
+		var data []byte
+		switch wire {
+		case wireVarint:
+			if raw, n = binary.Uvarint(buf); n <= 0 {
+				return nil, fmt.Errorf("invalid varint of field %d", number)
+			}
+			buf = buf[n:]
+		case wireFixed64:
+			if len(buf) < 8 {
+				return nil, fmt.Errorf("unexpected end of field %d", number)
+			}
+			raw, buf = binary.LittleEndian.Uint64(buf), buf[8:]
+		case wireFixed32:
+			if len(buf) < 4 {
+				return nil, fmt.Errorf("unexpected end of field %d", number)
+			}
+			raw, buf = uint64(binary.LittleEndian.Uint32(buf)), buf[4:]
+		case wireBytes:
+			size, n := binary.Uvarint(buf)
+			if n <= 0 || uint64(len(buf)-n) < size {
+				return nil, fmt.Errorf("unexpected end of field %d", number)
+			}
+			data, buf = buf[n:n+int(size)], buf[n+int(size):]
+		default:
+			return nil, fmt.Errorf("unsupported wire type %d of field %d", wire, number)
+		}
+
+		f, ok := m.fields[number]
+		if !ok {
+			// Unknown fields are skipped, ie added by a newer schema.
+			continue
+		}
+
+		var items []interface{}
+		if wire == wireBytes && scalar(f.GetType()) {
+			// packed repeated scalars
+			var err error
+			if items, err = unpack(f.GetType(), data); err != nil {
+				return nil, fmt.Errorf("field %s: %s", f.GetName(), err)
+			}
+			for i, raw := range items {
+				if items[i], err = d.value(f, raw.(uint64), nil); err != nil {
+					return nil, err
+				}
+			}
+		} else {
+			v, err := d.value(f, raw, data)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %s", f.GetName(), err)
+			}
+			items = []interface{}{v}
+		}
+
+		name := f.GetName()
+		if sub, ok := d.types.messages[f.GetTypeName()]; ok && sub.entry {
+			// map entries, with a key and a value field
+			entries, _ := values[name].(map[string]interface{})
+			if entries == nil {
+				entries = make(map[string]interface{})
+				values[name] = entries
+			}
+			for _, item := range items {
+				entry := item.(map[string]interface{})
+				if value, ok := entry["value"]; ok {
+					entries[fmt.Sprint(entry["key"])] = value
+				}
+			}
+			continue
+		}
+		if f.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED {
+			list, _ := values[name].([]interface{})
+			values[name] = append(list, items...)
+		} else {
+			values[name] = items[len(items)-1]
+		}
+	}
+
+	if m.proto3 {
+		// The zero values are not encoded in proto3.
+		for _, f := range m.fields {
+			if _, ok := values[f.GetName()]; !ok && f.OneofIndex == nil &&
+				f.GetLabel() != descriptor.FieldDescriptorProto_LABEL_REPEATED {
+				if v, ok := d.zero(f); ok {
+					values[f.GetName()] = v
+				}
+			}
+		}
+	}
+	return values, nil
+}
+
+// value converts the raw value of a field, data is the content of the
+// length delimited fields.
+func (d *decoder) value(f *descriptor.FieldDescriptorProto, raw uint64, data []byte) (interface{}, error) {
+	switch f.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_DOUBLE:
+		return math.Float64frombits(raw), nil
+	case descriptor.FieldDescriptorProto_TYPE_FLOAT:
+		return float64(math.Float32frombits(uint32(raw))), nil
+	case descriptor.FieldDescriptorProto_TYPE_INT64,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+		return int64(raw), nil
+	case descriptor.FieldDescriptorProto_TYPE_INT32,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+		return int64(int32(raw)), nil
+	case descriptor.FieldDescriptorProto_TYPE_UINT64,
+		descriptor.FieldDescriptorProto_TYPE_FIXED64:
+		return raw, nil
+	case descriptor.FieldDescriptorProto_TYPE_UINT32,
+		descriptor.FieldDescriptorProto_TYPE_FIXED32:
+		return int64(uint32(raw)), nil
+	case descriptor.FieldDescriptorProto_TYPE_SINT32,
+		descriptor.FieldDescriptorProto_TYPE_SINT64:
+		return int64(raw>>1) ^ -int64(raw&1), nil
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		return raw != 0, nil
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		if name, ok := d.types.enums[f.GetTypeName()][int32(raw)]; ok {
+			return name, nil
+		}
+		return int64(int32(raw)), nil
+	case descriptor.FieldDescriptorProto_TYPE_STRING,
+		descriptor.FieldDescriptorProto_TYPE_BYTES:
+		return string(data), nil
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE:
+		m, err := d.types.message(f.GetTypeName())
+		if err != nil {
+			return nil, err
+		}
+		values, err := d.decode(m, data)
+		if err != nil {
+			return nil, err
+		}
+		if m.name == ".google.protobuf.Timestamp" {
+			seconds, _ := values["seconds"].(int64)
+			nanos, _ := values["nanos"].(int64)
+			return time.Unix(seconds, nanos).UTC(), nil
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", f.GetType())
+}
+
+// zero returns the default value of the scalar fields of proto3.
+func (d *decoder) zero(f *descriptor.FieldDescriptorProto) (interface{}, bool) {
+	switch f.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE:
+		return nil, false
+	case descriptor.FieldDescriptorProto_TYPE_STRING,
+		descriptor.FieldDescriptorProto_TYPE_BYTES:
+		return "", true
+	}
+	v, err := d.value(f, 0, nil)
+	return v, err == nil
+}
+
+func scalar(t descriptor.FieldDescriptorProto_Type) bool {
+	switch t {
+	case descriptor.FieldDescriptorProto_TYPE_STRING,
+		descriptor.FieldDescriptorProto_TYPE_BYTES,
+		descriptor.FieldDescriptorProto_TYPE_MESSAGE,
+		descriptor.FieldDescriptorProto_TYPE_GROUP:
+		return false
+	}
+	return true
+}
+
+// unpack returns the raw values of a packed repeated field.
+func unpack(t descriptor.FieldDescriptorProto_Type, data []byte) ([]interface{}, error) {
+	size := 0
+	switch t {
+	case descriptor.FieldDescriptorProto_TYPE_DOUBLE,
+		descriptor.FieldDescriptorProto_TYPE_FIXED64,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+		size = 8
+	case descriptor.FieldDescriptorProto_TYPE_FLOAT,
+		descriptor.FieldDescriptorProto_TYPE_FIXED32,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+		size = 4
+	}
+
+	var items []interface{}
+	for len(data) > 0 {
+		switch size {
+		case 8:
+			if len(data) < 8 {
+				return nil, fmt.Errorf("invalid packed values")
+			}
+			items = append(items, binary.LittleEndian.Uint64(data))
+			data = data[8:]
+		case 4:
+			if len(data) < 4 {
+				return nil, fmt.Errorf("invalid packed values")
+			}
+			items = append(items, uint64(binary.LittleEndian.Uint32(data)))
+			data = data[4:]
+		default:
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return nil, fmt.Errorf("invalid packed values")
+			}
+			items = append(items, v)
+			data = data[n:]
+		}
+	}
+	return items, nil
+}
@@ -0,0 +1,93 @@
+package protobuf
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// message is a message type of a descriptor set with its fields by number.
+type message struct {
+	name   string
+	proto3 bool
+	entry  bool
+	fields map[int32]*descriptor.FieldDescriptorProto
+}
+
+// types indexes the message and enum types of a descriptor set by full name,
+// ie ".sensors.Reading".
+type types struct {
+	messages map[string]*message
+	enums    map[string]map[int32]string
+}
+
+// loadTypes reads a FileDescriptorSet, as written by
+// protoc --include_imports --descriptor_set_out.
+func loadTypes(path string) (*types, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	set := &descriptor.FileDescriptorSet{}
+	if err := proto.Unmarshal(buf, set); err != nil {
+		return nil, fmt.Errorf("invalid descriptor set %s, %s", path, err)
+	}
+
+	t := &types{
+		messages: make(map[string]*message),
+		enums:    make(map[string]map[int32]string),
+	}
+	for _, file := range set.GetFile() {
+		prefix := ""
+		if file.GetPackage() != "" {
+			prefix = "." + file.GetPackage()
+		}
+		proto3 := file.GetSyntax() == "proto3"
+		t.addEnums(prefix, file.GetEnumType())
+		t.addMessages(prefix, file.GetMessageType(), proto3)
+	}
+	return t, nil
+}
+
+func (t *types) addMessages(prefix string, messages []*descriptor.DescriptorProto, proto3 bool) {
+	for _, m := range messages {
+		name := prefix + "." + m.GetName()
+		msg := &message{
+			name:   name,
+			proto3: proto3,
+			entry:  m.GetOptions().GetMapEntry(),
+			fields: make(map[int32]*descriptor.FieldDescriptorProto),
+		}
+		for _, f := range m.GetField() {
+			msg.fields[f.GetNumber()] = f
+		}
+		t.messages[name] = msg
+		t.addEnums(name, m.GetEnumType())
+		t.addMessages(name, m.GetNestedType(), proto3)
+	}
+}
+
+func (t *types) addEnums(prefix string, enums []*descriptor.EnumDescriptorProto) {
+	for _, e := range enums {
+		values := make(map[int32]string)
+		for _, v := range e.GetValue() {
+			values[v.GetNumber()] = v.GetName()
+		}
+		t.enums[prefix+"."+e.GetName()] = values
+	}
+}
+
+// message returns the message type by full name, with or without the
+// leading dot.
+func (t *types) message(name string) (*message, error) {
+	if len(name) > 0 && name[0] != '.' {
+		name = "." + name
+	}
+	m, ok := t.messages[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown message type %s", name[1:])
+	}
+	return m, nil
+}
@@ -0,0 +1,209 @@
+package protobuf
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// ProtobufParser makes a metric of a protobuf message, decoded with the
+// message types of a compiled FileDescriptorSet loaded at startup, with the
+// fields of the nested messages flattened.
+type ProtobufParser struct {
+	MetricName string
+
+	// DescriptorSet is the path of the FileDescriptorSet, as written by
+	// protoc --include_imports --descriptor_set_out, and MessageType the
+	// full name of the type of the messages, ie "sensors.Reading".
+	DescriptorSet string
+	MessageType   string
+
+	// Measurement is the field of the measurement name, MetricName if empty.
+	Measurement string
+	// Tags are the fields making tags instead of fields.
+	Tags []string
+	// Fields are the fields to keep, all by default.
+	Fields []string
+	// Timestamp is the field of the timestamp, the time of the parsing is
+	// used if empty. The google.protobuf.Timestamp messages are converted,
+	// otherwise TimestampFormat is "unix", "unix_ms", "unix_us", "unix_ns"
+	// or a Go reference time layout, the default is "unix" for the numbers
+	// and RFC3339 for the strings.
+	Timestamp       string
+	TimestampFormat string
+	// FieldSeparator joins the names of the nested fields and of the items
+	// of the repeated fields, "_" if empty.
+	FieldSeparator string
+
+	DefaultTags map[string]string
+
+	once    sync.Once
+	err     error
+	decoder *decoder
+	message *message
+}
+
+// Init loads the descriptor set, it is called by Parse if needed.
+func (p *ProtobufParser) Init() error {
+	p.once.Do(func() {
+		if p.DescriptorSet == "" || p.MessageType == "" {
+			p.err = fmt.Errorf("a descriptor set and a message type are required")
+			return
+		}
+		types, err := loadTypes(p.DescriptorSet)
+		if err != nil {
+			p.err = err
+			return
+		}
+		p.decoder = &decoder{types: types}
+		p.message, p.err = types.message(p.MessageType)
+	})
+	return p.err
+}
+
+func (p *ProtobufParser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	if err := p.Init(); err != nil {
+		return nil, err
+	}
+
+	values, err := p.decoder.decode(p.message, buf)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode %s message, %s", p.MessageType, err)
+	}
+
+	m, err := p.makeMetric(values)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return []telegraf.Metric{}, nil
+	}
+	return []telegraf.Metric{m}, nil
+}
+
+func (p *ProtobufParser) makeMetric(message map[string]interface{}) (telegraf.Metric, error) {
+	separator := p.FieldSeparator
+	if separator == "" {
+		separator = "_"
+	}
+	values := make(map[string]interface{})
+	flatten("", message, separator, values)
+
+	name := p.MetricName
+	if p.Measurement != "" {
+		if v, ok := values[p.Measurement]; ok {
+			name = toString(v)
+			delete(values, p.Measurement)
+		}
+	}
+
+	tags := make(map[string]string)
+	for k, v := range p.DefaultTags {
+		tags[k] = v
+	}
+	for _, tag := range p.Tags {
+		if v, ok := values[tag]; ok {
+			tags[tag] = toString(v)
+			delete(values, tag)
+		}
+	}
+
+	t := time.Now().UTC()
+	if p.Timestamp != "" {
+		v, ok := values[p.Timestamp]
+		if !ok {
+			return nil, fmt.Errorf("timestamp field %q not found", p.Timestamp)
+		}
+		format := p.TimestampFormat
+		if _, ok := v.(string); !ok && format == "" {
+			format = "unix"
+		}
+		var err error
+		if t, err = internal.ParseTimestamp(v, format, nil); err != nil {
+			return nil, err
+		}
+		delete(values, p.Timestamp)
+	}
+
+	fields := make(map[string]interface{})
+	if len(p.Fields) == 0 {
+		for k, v := range values {
+			fields[k] = fieldValue(v)
+		}
+	} else {
+		for _, k := range p.Fields {
+			if v, ok := values[k]; ok {
+				fields[k] = fieldValue(v)
+			}
+		}
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return metric.New(name, tags, fields, t)
+}
+
+// flatten adds the values of the nested messages, maps and repeated fields
+// with their names joined by the separator.
+func flatten(prefix string, v interface{}, separator string, values map[string]interface{}) {
+	join := func(name string) string {
+		if prefix == "" {
+			return name
+		}
+		return prefix + separator + name
+	}
+
+	switch v := v.(type) {
+	case nil:
+	case map[string]interface{}:
+		for k, item := range v {
+			flatten(join(k), item, separator, values)
+		}
+	case []interface{}:
+		for i, item := range v {
+			flatten(join(strconv.Itoa(i)), item, separator, values)
+		}
+	default:
+		values[prefix] = v
+	}
+}
+
+// fieldValue converts the times to RFC3339 strings.
+func fieldValue(v interface{}) interface{} {
+	if t, ok := v.(time.Time); ok {
+		return t.Format(time.RFC3339Nano)
+	}
+	return v
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
+
+func (p *ProtobufParser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("Can not parse the line: %s, for data format: protobuf", line)
+	}
+
+	return metrics[0], nil
+}
+
+func (p *ProtobufParser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
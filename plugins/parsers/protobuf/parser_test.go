@@ -0,0 +1,172 @@
+package protobuf
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const descriptorSet = "testdata/reading.desc"
+
+// encoder writes the fields of the test messages.
+type encoder struct {
+	*proto.Buffer
+}
+
+func newEncoder() *encoder {
+	return &encoder{proto.NewBuffer(nil)}
+}
+
+func (e *encoder) key(number, wire int) *encoder {
+	e.EncodeVarint(uint64(number<<3 | wire))
+	return e
+}
+
+func (e *encoder) varint(number int, v uint64) *encoder {
+	e.key(number, wireVarint).EncodeVarint(v)
+	return e
+}
+
+func (e *encoder) bytes(number int, b []byte) *encoder {
+	e.key(number, wireBytes).EncodeRawBytes(b)
+	return e
+}
+
+func (e *encoder) string(number int, s string) *encoder {
+	return e.bytes(number, []byte(s))
+}
+
+func reading() []byte {
+	location := newEncoder().string(1, "factory").varint(2, 2)
+	timestamp := newEncoder().varint(1, 1496318400).varint(2, 500000000)
+	counter := newEncoder().string(1, "starts").varint(2, 7)
+	phases := newEncoder()
+	for _, f := range []float64{1.5, 2.5, 3.5} {
+		phases.EncodeFixed64(math.Float64bits(f))
+	}
+
+	offset := int64(-3)
+
+	e := newEncoder()
+	e.string(1, "pump-1")
+	e.varint(2, 1)
+	e.key(3, wireFixed64).EncodeFixed64(math.Float64bits(1530.5))
+	e.key(4, wireFixed32).EncodeFixed32(uint64(math.Float32bits(230.5)))
+	e.key(5, wireVarint).EncodeZigzag64(uint64(offset))
+	e.varint(6, 42)
+	e.varint(7, 1)
+	e.bytes(8, timestamp.Bytes())
+	e.bytes(9, location.Bytes())
+	e.bytes(10, phases.Bytes())
+	e.bytes(11, counter.Bytes())
+	// An unknown field
+	e.varint(99, 1)
+	return e.Bytes()
+}
+
+func TestParse(t *testing.T) {
+	parser := &ProtobufParser{
+		MetricName:    "protobuf",
+		DescriptorSet: descriptorSet,
+		MessageType:   "sensors.Reading",
+		Measurement:   "device",
+		Tags:          []string{"status", "location_site"},
+		Timestamp:     "time",
+		DefaultTags:   map[string]string{"source": "mqtt"},
+	}
+
+	metrics, err := parser.Parse(reading())
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+
+	m := metrics[0]
+	assert.Equal(t, "pump-1", m.Name())
+	assert.Equal(t, map[string]string{
+		"status":        "RUNNING",
+		"location_site": "factory",
+		"source":        "mqtt",
+	}, m.Tags())
+	assert.Equal(t, map[string]interface{}{
+		"power":           1530.5,
+		"voltage":         230.5,
+		"offset":          int64(-3),
+		"cycles":          int64(42),
+		"alarm":           true,
+		"location_floor":  int64(2),
+		"phases_0":        1.5,
+		"phases_1":        2.5,
+		"phases_2":        3.5,
+		"counters_starts": int64(7),
+		// The zero values are not encoded in proto3
+		"errors": int64(0),
+	}, m.Fields())
+	assert.Equal(t, time.Date(2017, 6, 1, 12, 0, 0, 500000000, time.UTC).UnixNano(),
+		m.Time().UnixNano())
+}
+
+func TestParseFields(t *testing.T) {
+	parser := &ProtobufParser{
+		MetricName:     "reading",
+		DescriptorSet:  descriptorSet,
+		MessageType:    ".sensors.Reading",
+		Fields:         []string{"power", "location.floor", "missing"},
+		FieldSeparator: ".",
+	}
+
+	m, err := parser.ParseLine(string(reading()))
+	require.NoError(t, err)
+	assert.Equal(t, "reading", m.Name())
+	assert.Equal(t, map[string]interface{}{
+		"power":          1530.5,
+		"location.floor": int64(2),
+	}, m.Fields())
+}
+
+func TestParseUnpacked(t *testing.T) {
+	parser := &ProtobufParser{
+		MetricName:    "reading",
+		DescriptorSet: descriptorSet,
+		MessageType:   "sensors.Reading",
+		Fields:        []string{"phases_0", "phases_1"},
+	}
+
+	e := newEncoder()
+	for _, f := range []float64{1.5, 2.5} {
+		e.key(10, wireFixed64).EncodeFixed64(math.Float64bits(f))
+	}
+	metrics, err := parser.Parse(e.Bytes())
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, map[string]interface{}{
+		"phases_0": 1.5,
+		"phases_1": 2.5,
+	}, metrics[0].Fields())
+}
+
+func TestParseErrors(t *testing.T) {
+	parser := &ProtobufParser{MetricName: "test"}
+	assert.Error(t, parser.Init())
+
+	parser = &ProtobufParser{MetricName: "test", DescriptorSet: "testdata/missing.desc", MessageType: "sensors.Reading"}
+	assert.Error(t, parser.Init())
+
+	parser = &ProtobufParser{MetricName: "test", DescriptorSet: descriptorSet, MessageType: "sensors.Missing"}
+	assert.Error(t, parser.Init())
+
+	parser = &ProtobufParser{MetricName: "test", DescriptorSet: descriptorSet, MessageType: "sensors.Reading"}
+	_, err := parser.Parse(reading()[:12])
+	assert.Error(t, err)
+
+	parser = &ProtobufParser{
+		MetricName:    "test",
+		DescriptorSet: descriptorSet,
+		MessageType:   "sensors.Reading",
+		Timestamp:     "device",
+	}
+	_, err = parser.Parse(reading())
+	assert.Error(t, err)
+}
@@ -2,15 +2,27 @@ package parsers
 
 import (
 	"fmt"
+	"time"
+	"unicode/utf8"
 
 	"github.com/influxdata/telegraf"
 
+	"github.com/influxdata/telegraf/plugins/parsers/avro"
+	"github.com/influxdata/telegraf/plugins/parsers/binary"
 	"github.com/influxdata/telegraf/plugins/parsers/collectd"
+	"github.com/influxdata/telegraf/plugins/parsers/csv"
 	"github.com/influxdata/telegraf/plugins/parsers/graphite"
 	"github.com/influxdata/telegraf/plugins/parsers/influx"
 	"github.com/influxdata/telegraf/plugins/parsers/json"
+	"github.com/influxdata/telegraf/plugins/parsers/json_v2"
 	"github.com/influxdata/telegraf/plugins/parsers/nagios"
+	"github.com/influxdata/telegraf/plugins/parsers/prometheus"
+	"github.com/influxdata/telegraf/plugins/parsers/protobuf"
+	"github.com/influxdata/telegraf/plugins/parsers/sparkplug_b"
+	"github.com/influxdata/telegraf/plugins/parsers/syslog"
 	"github.com/influxdata/telegraf/plugins/parsers/value"
+	"github.com/influxdata/telegraf/plugins/parsers/wavefront"
+	"github.com/influxdata/telegraf/plugins/parsers/xml"
 )
 
 // ParserInput is an interface for input plugins that are able to parse
@@ -41,7 +53,9 @@ type Parser interface {
 // Config is a struct that covers the data types needed for all parser types,
 // and can be used to instantiate _any_ of the parsers.
 type Config struct {
-	// Dataformat can be one of: json, influx, graphite, value, nagios
+	// Dataformat can be one of: json, json_v2, influx, graphite, value,
+	// nagios, collectd, sparkplug_b, xml, csv, prometheus, avro, protobuf,
+	// syslog, binary, wavefront
 	DataFormat string
 
 	// Separator only applied to Graphite data.
@@ -51,9 +65,112 @@ type Config struct {
 
 	// TagKeys only apply to JSON data
 	TagKeys []string
-	// MetricName applies to JSON & value. This will be the name of the measurement.
+	// JSONTimeKey is the dotted path of the timestamp of the JSON data,
+	// parsed with JSONTimeFormat, and JSONStringsAsNumbers converts the
+	// numeric strings into floats.
+	JSONTimeKey          string
+	JSONTimeFormat       string
+	JSONStringsAsNumbers bool
+	// MetricName applies to JSON, value & sparkplug_b. This will be the name of
+	// the measurement.
 	MetricName string
 
+	// JSONV2Query is the GJSON path of the objects making the metrics, the
+	// other json_v2 paths are relative to them.
+	JSONV2Query string
+	// JSONV2NamePath is the path of the measurement name, only applies to
+	// json_v2 data, as do the other JSONV2 options.
+	JSONV2NamePath string
+	// JSONV2TimePath is the path of the timestamp, parsed with
+	// JSONV2TimeFormat.
+	JSONV2TimePath   string
+	JSONV2TimeFormat string
+	// JSONV2TagPaths and JSONV2FieldPaths map the tags and fields to their
+	// paths, JSONV2FieldTypes the fields to their types.
+	JSONV2TagPaths   map[string]string
+	JSONV2FieldPaths map[string]string
+	JSONV2FieldTypes map[string]string
+
+	// XMLQuery is the XPath of the nodes making the metrics, the other xml
+	// paths are relative to them. The XML options mirror the JSONV2 ones.
+	XMLQuery      string
+	XMLNamePath   string
+	XMLTimePath   string
+	XMLTimeFormat string
+	XMLTagPaths   map[string]string
+	XMLFieldPaths map[string]string
+	XMLFieldTypes map[string]string
+
+	// CSVHeaderRowCount is the number of header rows of csv data, after the
+	// CSVSkipRows lines skipped, the CSV options only apply to csv data.
+	CSVHeaderRowCount int
+	CSVSkipRows       int
+	CSVSkipColumns    int
+	CSVDelimiter      string
+	CSVComment        string
+	// CSVColumnNames and CSVColumnTypes name and type the columns in order.
+	CSVColumnNames []string
+	CSVColumnTypes []string
+	// CSVMeasurementColumn, CSVTagColumns and CSVTimestampColumn are the
+	// columns of the measurement name, the tags and the timestamp, parsed
+	// with CSVTimestampFormat in CSVTimezone when it has no timezone.
+	CSVMeasurementColumn string
+	CSVTagColumns        []string
+	CSVTimestampColumn   string
+	CSVTimestampFormat   string
+	CSVTimezone          string
+
+	// AvroSchemaRegistry is the URL of the schema registry of the avro
+	// writer schemas, AvroSchema the writer schema when there is none.
+	AvroSchemaRegistry string
+	AvroSchema         string
+	// AvroMeasurement, AvroTags and AvroTimestamp are the fields of the
+	// measurement name, the tags and the timestamp, parsed with
+	// AvroTimestampFormat. AvroFields are the fields kept, all if empty.
+	AvroMeasurement     string
+	AvroTags            []string
+	AvroFields          []string
+	AvroTimestamp       string
+	AvroTimestampFormat string
+	// AvroFieldSeparator joins the names of the nested fields.
+	AvroFieldSeparator string
+
+	// ProtobufDescriptorSet is the path of the compiled FileDescriptorSet
+	// and ProtobufMessageType the full name of the type of the messages.
+	ProtobufDescriptorSet string
+	ProtobufMessageType   string
+	// ProtobufMeasurement, ProtobufTags and ProtobufTimestamp are the fields
+	// of the measurement name, the tags and the timestamp, parsed with
+	// ProtobufTimestampFormat. ProtobufFields are the fields kept, all if
+	// empty.
+	ProtobufMeasurement     string
+	ProtobufTags            []string
+	ProtobufFields          []string
+	ProtobufTimestamp       string
+	ProtobufTimestampFormat string
+	// ProtobufFieldSeparator joins the names of the nested fields.
+	ProtobufFieldSeparator string
+
+	// SyslogFormat is the format of the syslog messages, "rfc5424",
+	// "rfc3164" or "auto". SyslogSdparamSeparator joins the SD-IDs and the
+	// names of the structured data parameters, SyslogTimezone is the
+	// location of the RFC 3164 timestamps.
+	SyslogFormat           string
+	SyslogSdparamSeparator string
+	SyslogTimezone         string
+
+	// BinaryFields are the fields of the binary records, at their offset,
+	// in BinaryEndianness unless they have their own. The buffers are split
+	// into records of BinaryRecordLength if set.
+	BinaryFields       []binary.Field
+	BinaryEndianness   string
+	BinaryRecordLength int
+	// BinaryTags are the fields making tags, BinaryTimestamp the integer
+	// field of the timestamp, in the unit of BinaryTimestampFormat.
+	BinaryTags            []string
+	BinaryTimestamp       string
+	BinaryTimestampFormat string
+
 	// Authentication file for collectd
 	CollectdAuthFile string
 	// One of none (default), sign, or encrypt
@@ -63,6 +180,11 @@ type Config struct {
 
 	// DataType only applies to value, this will be the type to parse value to
 	DataType string
+	// ValueFieldNames only applies to value, these are the names of the
+	// fields of the messages holding several values, split by ValueSeparator
+	// or by whitespace.
+	ValueFieldNames []string
+	ValueSeparator  string
 
 	// DefaultTags are the default tags that will be added to all parsed metrics.
 	DefaultTags map[string]string
@@ -74,11 +196,16 @@ func NewParser(config *Config) (Parser, error) {
 	var parser Parser
 	switch config.DataFormat {
 	case "json":
-		parser, err = NewJSONParser(config.MetricName,
-			config.TagKeys, config.DefaultTags)
+		parser, err = newJSONParser(config)
+	case "json_v2":
+		parser, err = NewJSONV2Parser(config)
 	case "value":
-		parser, err = NewValueParser(config.MetricName,
-			config.DataType, config.DefaultTags)
+		if len(config.ValueFieldNames) > 0 || config.ValueSeparator != "" {
+			parser, err = NewMultiValueParser(config)
+		} else {
+			parser, err = NewValueParser(config.MetricName,
+				config.DataType, config.DefaultTags)
+		}
 	case "influx":
 		parser, err = NewInfluxParser()
 	case "nagios":
@@ -89,6 +216,24 @@ func NewParser(config *Config) (Parser, error) {
 	case "collectd":
 		parser, err = NewCollectdParser(config.CollectdAuthFile,
 			config.CollectdSecurityLevel, config.CollectdTypesDB)
+	case "sparkplug_b":
+		parser, err = NewSparkplugBParser(config.MetricName, config.DefaultTags)
+	case "xml":
+		parser, err = NewXMLParser(config)
+	case "csv":
+		parser, err = NewCSVParser(config)
+	case "prometheus":
+		parser, err = NewPrometheusParser(config.DefaultTags)
+	case "avro":
+		parser, err = NewAvroParser(config)
+	case "protobuf":
+		parser, err = NewProtobufParser(config)
+	case "syslog":
+		parser, err = NewSyslogParser(config)
+	case "binary":
+		parser, err = NewBinaryParser(config)
+	case "wavefront":
+		parser, err = NewWavefrontParser(config.DefaultTags)
 	default:
 		err = fmt.Errorf("Invalid data format: %s", config.DataFormat)
 	}
@@ -108,6 +253,136 @@ func NewJSONParser(
 	return parser, nil
 }
 
+// newJSONParser returns the JSON parser with the timestamp and number
+// options of the config.
+func newJSONParser(config *Config) (Parser, error) {
+	return &json.JSONParser{
+		MetricName:       config.MetricName,
+		TagKeys:          config.TagKeys,
+		TimeKey:          config.JSONTimeKey,
+		TimeFormat:       config.JSONTimeFormat,
+		StringsAsNumbers: config.JSONStringsAsNumbers,
+		DefaultTags:      config.DefaultTags,
+	}, nil
+}
+
+// NewJSONV2Parser returns the json_v2 parser configured with the JSONV2
+// options of the config.
+func NewJSONV2Parser(config *Config) (Parser, error) {
+	for field, typ := range config.JSONV2FieldTypes {
+		switch typ {
+		case "int", "float", "string", "bool":
+		default:
+			return nil, fmt.Errorf("invalid type %q for field %s", typ, field)
+		}
+	}
+	return &json_v2.JSONV2Parser{
+		MetricName:  config.MetricName,
+		Query:       config.JSONV2Query,
+		NamePath:    config.JSONV2NamePath,
+		TimePath:    config.JSONV2TimePath,
+		TimeFormat:  config.JSONV2TimeFormat,
+		TagPaths:    config.JSONV2TagPaths,
+		FieldPaths:  config.JSONV2FieldPaths,
+		FieldTypes:  config.JSONV2FieldTypes,
+		DefaultTags: config.DefaultTags,
+	}, nil
+}
+
+// NewAvroParser returns the avro parser configured with the Avro options of
+// the config.
+func NewAvroParser(config *Config) (Parser, error) {
+	parser := &avro.AvroParser{
+		MetricName:      config.MetricName,
+		SchemaRegistry:  config.AvroSchemaRegistry,
+		Schema:          config.AvroSchema,
+		Measurement:     config.AvroMeasurement,
+		Tags:            config.AvroTags,
+		Fields:          config.AvroFields,
+		Timestamp:       config.AvroTimestamp,
+		TimestampFormat: config.AvroTimestampFormat,
+		FieldSeparator:  config.AvroFieldSeparator,
+		DefaultTags:     config.DefaultTags,
+	}
+	if err := parser.Init(); err != nil {
+		return nil, err
+	}
+	return parser, nil
+}
+
+// NewProtobufParser returns the protobuf parser configured with the Protobuf
+// options of the config, the descriptor set is loaded.
+func NewProtobufParser(config *Config) (Parser, error) {
+	parser := &protobuf.ProtobufParser{
+		MetricName:      config.MetricName,
+		DescriptorSet:   config.ProtobufDescriptorSet,
+		MessageType:     config.ProtobufMessageType,
+		Measurement:     config.ProtobufMeasurement,
+		Tags:            config.ProtobufTags,
+		Fields:          config.ProtobufFields,
+		Timestamp:       config.ProtobufTimestamp,
+		TimestampFormat: config.ProtobufTimestampFormat,
+		FieldSeparator:  config.ProtobufFieldSeparator,
+		DefaultTags:     config.DefaultTags,
+	}
+	if err := parser.Init(); err != nil {
+		return nil, err
+	}
+	return parser, nil
+}
+
+// NewSyslogParser returns the syslog parser configured with the Syslog
+// options of the config.
+func NewSyslogParser(config *Config) (Parser, error) {
+	switch config.SyslogFormat {
+	case "", "auto", "rfc5424", "rfc3164":
+	default:
+		return nil, fmt.Errorf("invalid syslog_format %q", config.SyslogFormat)
+	}
+	if config.SyslogTimezone != "" {
+		if _, err := time.LoadLocation(config.SyslogTimezone); err != nil {
+			return nil, fmt.Errorf("invalid syslog_timezone %q, %s", config.SyslogTimezone, err)
+		}
+	}
+	return &syslog.SyslogParser{
+		Format:           config.SyslogFormat,
+		SdparamSeparator: config.SyslogSdparamSeparator,
+		Timezone:         config.SyslogTimezone,
+		DefaultTags:      config.DefaultTags,
+	}, nil
+}
+
+// NewBinaryParser returns the binary parser configured with the Binary
+// options of the config, the layout is checked.
+func NewBinaryParser(config *Config) (Parser, error) {
+	parser := &binary.BinaryParser{
+		MetricName:      config.MetricName,
+		Endianness:      config.BinaryEndianness,
+		RecordLength:    config.BinaryRecordLength,
+		Fields:          config.BinaryFields,
+		Tags:            config.BinaryTags,
+		Timestamp:       config.BinaryTimestamp,
+		TimestampFormat: config.BinaryTimestampFormat,
+		DefaultTags:     config.DefaultTags,
+	}
+	if err := parser.Check(); err != nil {
+		return nil, err
+	}
+	return parser, nil
+}
+
+func NewWavefrontParser(defaultTags map[string]string) (Parser, error) {
+	return &wavefront.WavefrontParser{
+		DefaultTags: defaultTags,
+	}, nil
+}
+
+func NewPrometheusParser(defaultTags map[string]string) (Parser, error) {
+	return &prometheus.PrometheusParser{
+		DefaultTags: defaultTags,
+	}, nil
+}
+
 func NewNagiosParser() (Parser, error) {
 	return &nagios.NagiosParser{}, nil
 }
@@ -136,6 +411,21 @@ func NewValueParser(
 	}, nil
 }
 
+// NewMultiValueParser returns a value parser of the messages holding a value
+// for each of the ValueFieldNames.
+func NewMultiValueParser(config *Config) (Parser, error) {
+	if len(config.ValueFieldNames) == 0 {
+		return nil, fmt.Errorf("value_field_names required with value_separator")
+	}
+	return &value.ValueParser{
+		MetricName:  config.MetricName,
+		DataType:    config.DataType,
+		FieldNames:  config.ValueFieldNames,
+		Separator:   config.ValueSeparator,
+		DefaultTags: config.DefaultTags,
+	}, nil
+}
+
 func NewCollectdParser(
 	authFile string,
 	securityLevel string,
@@ -143,3 +433,78 @@ func NewCollectdParser(
 ) (Parser, error) {
 	return collectd.NewCollectdParser(authFile, securityLevel, typesDB)
 }
+
+func NewSparkplugBParser(
+	metricName string,
+	defaultTags map[string]string,
+) (Parser, error) {
+	return &sparkplug_b.SparkplugBParser{
+		MetricName:  metricName,
+		DefaultTags: defaultTags,
+	}, nil
+}
+
+// NewXMLParser returns the xml parser configured with the XML options of the
+// config.
+func NewXMLParser(config *Config) (Parser, error) {
+	parser := &xml.XMLParser{
+		MetricName:  config.MetricName,
+		Query:       config.XMLQuery,
+		NamePath:    config.XMLNamePath,
+		TimePath:    config.XMLTimePath,
+		TimeFormat:  config.XMLTimeFormat,
+		TagPaths:    config.XMLTagPaths,
+		FieldPaths:  config.XMLFieldPaths,
+		FieldTypes:  config.XMLFieldTypes,
+		DefaultTags: config.DefaultTags,
+	}
+	if err := parser.Compile(); err != nil {
+		return nil, err
+	}
+	return parser, nil
+}
+
+// NewCSVParser returns the csv parser configured with the CSV options of the
+// config.
+func NewCSVParser(config *Config) (Parser, error) {
+	if config.CSVHeaderRowCount < 0 || config.CSVSkipRows < 0 || config.CSVSkipColumns < 0 {
+		return nil, fmt.Errorf("csv_header_row_count, csv_skip_rows and csv_skip_columns must not be negative")
+	}
+	for i, typ := range config.CSVColumnTypes {
+		switch typ {
+		case "", "int", "float", "bool", "string":
+		default:
+			return nil, fmt.Errorf("invalid type %q for column %d", typ, i+1)
+		}
+	}
+	if config.CSVTimezone != "" {
+		if _, err := time.LoadLocation(config.CSVTimezone); err != nil {
+			return nil, fmt.Errorf("invalid csv_timezone %q, %s", config.CSVTimezone, err)
+		}
+	}
+	for option, value := range map[string]string{
+		"csv_delimiter": config.CSVDelimiter,
+		"csv_comment":   config.CSVComment,
+	} {
+		if utf8.RuneCountInString(value) > 1 {
+			return nil, fmt.Errorf("%s %q is not a single character", option, value)
+		}
+	}
+
+	return &csv.CSVParser{
+		MetricName:        config.MetricName,
+		HeaderRowCount:    config.CSVHeaderRowCount,
+		SkipRows:          config.CSVSkipRows,
+		SkipColumns:       config.CSVSkipColumns,
+		Delimiter:         config.CSVDelimiter,
+		Comment:           config.CSVComment,
+		ColumnNames:       config.CSVColumnNames,
+		ColumnTypes:       config.CSVColumnTypes,
+		MeasurementColumn: config.CSVMeasurementColumn,
+		TagColumns:        config.CSVTagColumns,
+		TimestampColumn:   config.CSVTimestampColumn,
+		TimestampFormat:   config.CSVTimestampFormat,
+		Timezone:          config.CSVTimezone,
+		DefaultTags:       config.DefaultTags,
+	}, nil
+}
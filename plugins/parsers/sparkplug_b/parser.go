@@ -0,0 +1,210 @@
+package sparkplug_b
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+const namespace = "spBv1.0"
+
+// alias is a metric definition announced in a NBIRTH or DBIRTH message.
+type alias struct {
+	name     string
+	datatype uint32
+}
+
+// SparkplugBParser decodes Sparkplug B protobuf payloads.
+//
+// Sparkplug edge nodes announce the names and datatypes of their metrics in
+// birth certificates and may refer to them only by alias afterwards, so the
+// parser keeps an alias table per edge node. Because of this the parser needs
+// to know the topic each message was published on, see ParseWithTopic.
+type SparkplugBParser struct {
+	MetricName  string
+	DefaultTags map[string]string
+
+	sync.Mutex
+	aliases map[string]map[uint64]alias
+}
+
+// topic holds the parts of a Sparkplug B topic, which has the form
+// spBv1.0/<group_id>/<message_type>/<edge_node_id>[/<device_id>].
+type topic struct {
+	groupID     string
+	messageType string
+	edgeNodeID  string
+	deviceID    string
+}
+
+func parseTopic(t string) (*topic, error) {
+	parts := strings.Split(t, "/")
+	if len(parts) < 4 || len(parts) > 5 || parts[0] != namespace {
+		return nil, fmt.Errorf("%q is not a Sparkplug B topic", t)
+	}
+
+	tp := &topic{
+		groupID:     parts[1],
+		messageType: parts[2],
+		edgeNodeID:  parts[3],
+	}
+	if len(parts) == 5 {
+		tp.deviceID = parts[4]
+	}
+	return tp, nil
+}
+
+func (t *topic) node() string {
+	return t.groupID + "/" + t.edgeNodeID
+}
+
+// Parse decodes a payload without any topic information. Metrics are only
+// resolved by name and no group, edge node or device tags are added.
+func (p *SparkplugBParser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	return p.ParseWithTopic("", buf)
+}
+
+// ParseWithTopic decodes a payload received on the given Sparkplug B topic.
+// Birth certificates (re)build the alias table of the edge node, death
+// certificates clear it, and command and state messages are ignored.
+func (p *SparkplugBParser) ParseWithTopic(
+	topicName string,
+	buf []byte,
+) ([]telegraf.Metric, error) {
+	// Host application state messages are not Sparkplug B payloads.
+	if strings.HasPrefix(topicName, "STATE/") ||
+		strings.HasPrefix(topicName, namespace+"/STATE/") {
+		return nil, nil
+	}
+
+	var t *topic
+	if topicName != "" {
+		var err error
+		if t, err = parseTopic(topicName); err != nil {
+			return nil, err
+		}
+	}
+
+	var payload Payload
+	if err := proto.Unmarshal(buf, &payload); err != nil {
+		return nil, fmt.Errorf("unable to decode Sparkplug B payload, %s", err)
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	if p.aliases == nil {
+		p.aliases = make(map[string]map[uint64]alias)
+	}
+
+	var table map[uint64]alias
+	if t != nil {
+		switch t.messageType {
+		case "NBIRTH":
+			table = make(map[uint64]alias)
+			p.aliases[t.node()] = table
+		case "DBIRTH", "NDATA", "DDATA":
+			table = p.aliases[t.node()]
+			if table == nil {
+				table = make(map[uint64]alias)
+				p.aliases[t.node()] = table
+			}
+		case "NDEATH":
+			delete(p.aliases, t.node())
+			return nil, nil
+		default:
+			// DDEATH, NCMD and DCMD do not carry any measurements.
+			return nil, nil
+		}
+	}
+
+	now := time.Now().UTC()
+	grouped := make(map[int64]map[string]interface{})
+	for _, m := range payload.Metrics {
+		name := m.GetName()
+		var datatype uint32
+		if m.Datatype != nil {
+			datatype = *m.Datatype
+		}
+
+		if m.Alias != nil && table != nil {
+			if name != "" {
+				table[*m.Alias] = alias{name: name, datatype: datatype}
+			} else if a, ok := table[*m.Alias]; ok {
+				name = a.name
+				if datatype == 0 {
+					datatype = a.datatype
+				}
+			}
+		}
+		if name == "" {
+			continue
+		}
+
+		value := m.value(datatype)
+		if value == nil {
+			continue
+		}
+
+		var ts int64
+		switch {
+		case m.Timestamp != nil:
+			ts = int64(*m.Timestamp)
+		case payload.Timestamp != nil:
+			ts = int64(*payload.Timestamp)
+		default:
+			ts = now.UnixNano() / int64(time.Millisecond)
+		}
+
+		fields, ok := grouped[ts]
+		if !ok {
+			fields = make(map[string]interface{})
+			grouped[ts] = fields
+		}
+		fields[name] = value
+	}
+
+	timestamps := make([]int64, 0, len(grouped))
+	for ts := range grouped {
+		timestamps = append(timestamps, ts)
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+
+	metrics := make([]telegraf.Metric, 0, len(grouped))
+	for _, ts := range timestamps {
+		tags := make(map[string]string)
+		for k, v := range p.DefaultTags {
+			tags[k] = v
+		}
+		if t != nil {
+			tags["group_id"] = t.groupID
+			tags["edge_node_id"] = t.edgeNodeID
+			if t.deviceID != "" {
+				tags["device_id"] = t.deviceID
+			}
+		}
+
+		m, err := metric.New(p.MetricName, tags, grouped[ts],
+			time.Unix(0, ts*int64(time.Millisecond)).UTC())
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+func (p *SparkplugBParser) ParseLine(line string) (telegraf.Metric, error) {
+	return nil, fmt.Errorf("ParseLine is not supported by the sparkplug_b data format")
+}
+
+func (p *SparkplugBParser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
@@ -0,0 +1,152 @@
+package sparkplug_b
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encode(t *testing.T, ts uint64, metrics ...*Metric) []byte {
+	buf, err := proto.Marshal(&Payload{
+		Timestamp: proto.Uint64(ts),
+		Metrics:   metrics,
+		Seq:       proto.Uint64(0),
+	})
+	require.NoError(t, err)
+	return buf
+}
+
+func TestParseBirthAndAliasedData(t *testing.T) {
+	parser := &SparkplugBParser{MetricName: "sparkplug"}
+
+	birth := encode(t, 1500000000000,
+		&Metric{
+			Name:        proto.String("Inverter/AC Power"),
+			Alias:       proto.Uint64(1),
+			Datatype:    proto.Uint32(typeDouble),
+			DoubleValue: proto.Float64(1530.5),
+		},
+		&Metric{
+			Name:     proto.String("Inverter/Temperature"),
+			Alias:    proto.Uint64(2),
+			Datatype: proto.Uint32(typeInt16),
+			IntValue: proto.Uint32(uint32(0xFFFB)),
+		},
+	)
+
+	metrics, err := parser.ParseWithTopic("spBv1.0/plant/NBIRTH/gw01", birth)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "sparkplug", metrics[0].Name())
+	assert.Equal(t, map[string]string{
+		"group_id":     "plant",
+		"edge_node_id": "gw01",
+	}, metrics[0].Tags())
+	assert.Equal(t, map[string]interface{}{
+		"Inverter/AC Power":    float64(1530.5),
+		"Inverter/Temperature": int64(-5),
+	}, metrics[0].Fields())
+	assert.Equal(t, time.Unix(1500000000, 0).UnixNano(), metrics[0].UnixNano())
+
+	data := encode(t, 1500000060000,
+		&Metric{
+			Alias:       proto.Uint64(1),
+			DoubleValue: proto.Float64(1490),
+		},
+	)
+	metrics, err = parser.ParseWithTopic("spBv1.0/plant/DDATA/gw01/meter", data)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, map[string]string{
+		"group_id":     "plant",
+		"edge_node_id": "gw01",
+		"device_id":    "meter",
+	}, metrics[0].Tags())
+	assert.Equal(t, map[string]interface{}{
+		"Inverter/AC Power": float64(1490),
+	}, metrics[0].Fields())
+}
+
+func TestParseDeathClearsAliases(t *testing.T) {
+	parser := &SparkplugBParser{MetricName: "sparkplug"}
+
+	birth := encode(t, 1500000000000, &Metric{
+		Name:      proto.String("Meter/Energy"),
+		Alias:     proto.Uint64(7),
+		Datatype:  proto.Uint32(typeUInt64),
+		LongValue: proto.Uint64(42),
+	})
+	_, err := parser.ParseWithTopic("spBv1.0/plant/NBIRTH/gw01", birth)
+	require.NoError(t, err)
+
+	metrics, err := parser.ParseWithTopic("spBv1.0/plant/NDEATH/gw01",
+		encode(t, 1500000000000))
+	require.NoError(t, err)
+	assert.Len(t, metrics, 0)
+
+	data := encode(t, 1500000060000, &Metric{
+		Alias:     proto.Uint64(7),
+		LongValue: proto.Uint64(43),
+	})
+	metrics, err = parser.ParseWithTopic("spBv1.0/plant/NDATA/gw01", data)
+	require.NoError(t, err)
+	assert.Len(t, metrics, 0)
+}
+
+func TestParsePerMetricTimestamps(t *testing.T) {
+	parser := &SparkplugBParser{MetricName: "sparkplug"}
+
+	buf := encode(t, 1500000000000,
+		&Metric{
+			Name:         proto.String("a"),
+			Datatype:     proto.Uint32(typeBoolean),
+			BooleanValue: proto.Bool(true),
+		},
+		&Metric{
+			Name:        proto.String("b"),
+			Timestamp:   proto.Uint64(1499999990000),
+			Datatype:    proto.Uint32(typeString),
+			StringValue: proto.String("MPPT"),
+		},
+		&Metric{
+			Name:     proto.String("c"),
+			Datatype: proto.Uint32(typeFloat),
+			IsNull:   proto.Bool(true),
+		},
+	)
+
+	metrics, err := parser.Parse(buf)
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	assert.Equal(t, map[string]interface{}{"b": "MPPT"}, metrics[0].Fields())
+	assert.Equal(t, time.Unix(1499999990, 0).UnixNano(), metrics[0].UnixNano())
+	assert.Equal(t, map[string]interface{}{"a": true}, metrics[1].Fields())
+	assert.Equal(t, map[string]string{}, metrics[1].Tags())
+}
+
+func TestParseIgnoredMessages(t *testing.T) {
+	parser := &SparkplugBParser{MetricName: "sparkplug"}
+
+	metrics, err := parser.ParseWithTopic("STATE/scada", []byte("ONLINE"))
+	require.NoError(t, err)
+	assert.Len(t, metrics, 0)
+
+	metrics, err = parser.ParseWithTopic("spBv1.0/plant/DCMD/gw01/meter",
+		encode(t, 1500000000000, &Metric{
+			Name:         proto.String("Reboot"),
+			Datatype:     proto.Uint32(typeBoolean),
+			BooleanValue: proto.Bool(true),
+		}))
+	require.NoError(t, err)
+	assert.Len(t, metrics, 0)
+}
+
+func TestParseInvalidTopic(t *testing.T) {
+	parser := &SparkplugBParser{MetricName: "sparkplug"}
+
+	_, err := parser.ParseWithTopic("telegraf/host01/cpu", encode(t, 0))
+	assert.Error(t, err)
+}
@@ -0,0 +1,129 @@
+package sparkplug_b
+
+import (
+	"github.com/golang/protobuf/proto"
+)
+
+// The types below are a wire compatible subset of the Sparkplug B
+// sparkplug_b.proto definition (org.eclipse.tahu.protobuf). Only the parts
+// of the payload that are turned into metrics are declared, everything else
+// (metadata, property sets, datasets, templates) is kept as unrecognized
+// bytes by the protobuf library.
+//
+// The value oneof of the Metric message is declared as plain optional fields,
+// which is identical on the wire.
+
+// Payload is a Sparkplug B message body.
+type Payload struct {
+	Timestamp        *uint64   `protobuf:"varint,1,opt,name=timestamp" json:"timestamp,omitempty"`
+	Metrics          []*Metric `protobuf:"bytes,2,rep,name=metrics" json:"metrics,omitempty"`
+	Seq              *uint64   `protobuf:"varint,3,opt,name=seq" json:"seq,omitempty"`
+	Uuid             *string   `protobuf:"bytes,4,opt,name=uuid" json:"uuid,omitempty"`
+	Body             []byte    `protobuf:"bytes,5,opt,name=body" json:"body,omitempty"`
+	XXX_unrecognized []byte    `json:"-"`
+}
+
+func (m *Payload) Reset()         { *m = Payload{} }
+func (m *Payload) String() string { return proto.CompactTextString(m) }
+func (*Payload) ProtoMessage()    {}
+
+// Metric is a single value inside of a Sparkplug B payload.
+type Metric struct {
+	Name             *string  `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Alias            *uint64  `protobuf:"varint,2,opt,name=alias" json:"alias,omitempty"`
+	Timestamp        *uint64  `protobuf:"varint,3,opt,name=timestamp" json:"timestamp,omitempty"`
+	Datatype         *uint32  `protobuf:"varint,4,opt,name=datatype" json:"datatype,omitempty"`
+	IsHistorical     *bool    `protobuf:"varint,5,opt,name=is_historical" json:"is_historical,omitempty"`
+	IsTransient      *bool    `protobuf:"varint,6,opt,name=is_transient" json:"is_transient,omitempty"`
+	IsNull           *bool    `protobuf:"varint,7,opt,name=is_null" json:"is_null,omitempty"`
+	IntValue         *uint32  `protobuf:"varint,10,opt,name=int_value" json:"int_value,omitempty"`
+	LongValue        *uint64  `protobuf:"varint,11,opt,name=long_value" json:"long_value,omitempty"`
+	FloatValue       *float32 `protobuf:"fixed32,12,opt,name=float_value" json:"float_value,omitempty"`
+	DoubleValue      *float64 `protobuf:"fixed64,13,opt,name=double_value" json:"double_value,omitempty"`
+	BooleanValue     *bool    `protobuf:"varint,14,opt,name=boolean_value" json:"boolean_value,omitempty"`
+	StringValue      *string  `protobuf:"bytes,15,opt,name=string_value" json:"string_value,omitempty"`
+	BytesValue       []byte   `protobuf:"bytes,16,opt,name=bytes_value" json:"bytes_value,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *Metric) Reset()         { *m = Metric{} }
+func (m *Metric) String() string { return proto.CompactTextString(m) }
+func (*Metric) ProtoMessage()    {}
+
+func (m *Metric) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+// Sparkplug B metric datatypes.
+const (
+	typeInt8     = 1
+	typeInt16    = 2
+	typeInt32    = 3
+	typeInt64    = 4
+	typeUInt8    = 5
+	typeUInt16   = 6
+	typeUInt32   = 7
+	typeUInt64   = 8
+	typeFloat    = 9
+	typeDouble   = 10
+	typeBoolean  = 11
+	typeString   = 12
+	typeDateTime = 13
+	typeText     = 14
+	typeUUID     = 15
+)
+
+// value returns the Go value of the metric according to its datatype, or nil
+// if the metric is null or of a type that can't be represented as a field.
+func (m *Metric) value(datatype uint32) interface{} {
+	if m.IsNull != nil && *m.IsNull {
+		return nil
+	}
+
+	switch datatype {
+	case typeInt8:
+		if m.IntValue != nil {
+			return int64(int8(*m.IntValue))
+		}
+	case typeInt16:
+		if m.IntValue != nil {
+			return int64(int16(*m.IntValue))
+		}
+	case typeInt32:
+		if m.IntValue != nil {
+			return int64(int32(*m.IntValue))
+		}
+	case typeUInt8, typeUInt16, typeUInt32:
+		if m.IntValue != nil {
+			return int64(*m.IntValue)
+		}
+	case typeInt64:
+		if m.LongValue != nil {
+			return int64(*m.LongValue)
+		}
+	case typeUInt64, typeDateTime:
+		if m.LongValue != nil {
+			return *m.LongValue
+		}
+	case typeFloat:
+		if m.FloatValue != nil {
+			return float64(*m.FloatValue)
+		}
+	case typeDouble:
+		if m.DoubleValue != nil {
+			return *m.DoubleValue
+		}
+	case typeBoolean:
+		if m.BooleanValue != nil {
+			return *m.BooleanValue
+		}
+	case typeString, typeText, typeUUID:
+		if m.StringValue != nil {
+			return *m.StringValue
+		}
+	}
+	return nil
+}
@@ -0,0 +1,339 @@
+package syslog
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// Measurement is the name of the metrics of the syslog messages.
+const Measurement = "syslog"
+
+var severities = []string{
+	"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug",
+}
+
+var facilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console",
+	"solaris-cron", "local0", "local1", "local2", "local3", "local4",
+	"local5", "local6", "local7",
+}
+
+// SyslogParser makes a metric of each syslog message, in the RFC 5424 or
+// the RFC 3164 (BSD) format, ie of the syslog messages written to files or
+// forwarded through Kafka.
+//
+// The severity, facility, hostname and appname are the tags, the message,
+// the codes of the severity and facility, the procid, the msgid and the
+// version are fields, as well as the parameters of the structured data named
+// after their SD-ID and name, ie "origin_ip". The metric has the timestamp
+// of the message, or the time of the parsing if the message has none.
+type SyslogParser struct {
+	// Format is "rfc5424", "rfc3164" or "auto", the default, to detect the
+	// format of each message.
+	Format string
+	// SdparamSeparator joins the SD-IDs and the names of the parameters of
+	// the structured data, "_" if empty.
+	SdparamSeparator string
+	// Timezone is the location of the RFC 3164 timestamps, which have no
+	// timezone, ie "Local", UTC if empty.
+	Timezone string
+
+	DefaultTags map[string]string
+
+	// now is the time of the parsing, replaced by the tests.
+	now func() time.Time
+}
+
+// Parse parses the messages of the buffer, one per line or with the octet
+// counting framing of RFC 6587, each message preceded by its length.
+func (p *SyslogParser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	metrics := make([]telegraf.Metric, 0)
+	for {
+		buf = bytes.TrimLeft(buf, " \t\r\n")
+		if len(buf) == 0 {
+			return metrics, nil
+		}
+
+		var msg []byte
+		if buf[0] >= '0' && buf[0] <= '9' {
+			sp := bytes.IndexByte(buf, ' ')
+			if sp < 0 {
+				return nil, fmt.Errorf("invalid octet count")
+			}
+			n, err := strconv.Atoi(string(buf[:sp]))
+			if err != nil || n > len(buf)-sp-1 {
+				return nil, fmt.Errorf("invalid octet count %q", buf[:sp])
+			}
+			msg, buf = buf[sp+1:sp+1+n], buf[sp+1+n:]
+		} else if nl := bytes.IndexByte(buf, '\n'); nl >= 0 {
+			msg, buf = buf[:nl], buf[nl+1:]
+		} else {
+			msg, buf = buf, nil
+		}
+
+		m, err := p.ParseLine(string(msg))
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+}
+
+func (p *SyslogParser) ParseLine(line string) (telegraf.Metric, error) {
+	line = strings.TrimRight(line, "\r\n")
+	pri, rest, err := parsePriority(line)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &message{
+		tags: map[string]string{
+			"severity": severities[pri&7],
+			"facility": facilities[pri>>3],
+		},
+		fields: map[string]interface{}{
+			"severity_code": int64(pri & 7),
+			"facility_code": int64(pri >> 3),
+		},
+	}
+	for k, v := range p.DefaultTags {
+		if _, ok := msg.tags[k]; !ok {
+			msg.tags[k] = v
+		}
+	}
+
+	format := p.Format
+	if format == "" || format == "auto" {
+		format = "rfc3164"
+		if len(rest) > 1 && rest[0] >= '1' && rest[0] <= '9' && rest[1] == ' ' {
+			format = "rfc5424"
+		}
+	}
+	switch format {
+	case "rfc5424":
+		err = p.parseRFC5424(msg, rest)
+	case "rfc3164":
+		err = p.parseRFC3164(msg, rest)
+	default:
+		err = fmt.Errorf("invalid syslog format %q", p.Format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if msg.time.IsZero() {
+		msg.time = p.timeNow()
+	}
+	return metric.New(Measurement, msg.tags, msg.fields, msg.time)
+}
+
+func (p *SyslogParser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
+
+func (p *SyslogParser) timeNow() time.Time {
+	if p.now != nil {
+		return p.now()
+	}
+	return time.Now().UTC()
+}
+
+// message is a message being parsed.
+type message struct {
+	tags   map[string]string
+	fields map[string]interface{}
+	time   time.Time
+}
+
+// parsePriority parses the "<PRI>" prefix of the messages.
+func parsePriority(line string) (int, string, error) {
+	end := strings.IndexByte(line, '>')
+	if !strings.HasPrefix(line, "<") || end < 2 || end > 4 {
+		return 0, "", fmt.Errorf("invalid syslog message, no priority: %q", line)
+	}
+	pri, err := strconv.Atoi(line[1:end])
+	if err != nil || pri < 0 || pri > 191 {
+		return 0, "", fmt.Errorf("invalid syslog priority %q", line[1:end])
+	}
+	return pri, line[end+1:], nil
+}
+
+// nextToken returns the text up to the next space and the text following it.
+func nextToken(s string) (string, string) {
+	if sp := strings.IndexByte(s, ' '); sp >= 0 {
+		return s[:sp], s[sp+1:]
+	}
+	return s, ""
+}
+
+// parseRFC5424 parses the message following the priority:
+// VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG]
+func (p *SyslogParser) parseRFC5424(msg *message, s string) error {
+	var headers [6]string
+	for i := range headers {
+		if s == "" {
+			return fmt.Errorf("invalid RFC 5424 message, missing header")
+		}
+		headers[i], s = nextToken(s)
+	}
+
+	version, err := strconv.ParseInt(headers[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid RFC 5424 version %q", headers[0])
+	}
+	msg.fields["version"] = version
+	if headers[1] != "-" {
+		if msg.time, err = time.Parse(time.RFC3339Nano, headers[1]); err != nil {
+			return fmt.Errorf("invalid RFC 5424 timestamp %q", headers[1])
+		}
+	}
+	for name, value := range map[string]string{
+		"hostname": headers[2],
+		"appname":  headers[3],
+	} {
+		if value != "-" {
+			msg.tags[name] = value
+		}
+	}
+	for name, value := range map[string]string{
+		"procid": headers[4],
+		"msgid":  headers[5],
+	} {
+		if value != "-" {
+			msg.fields[name] = value
+		}
+	}
+
+	if s, err = p.parseStructuredData(msg, s); err != nil {
+		return err
+	}
+	s = strings.TrimPrefix(strings.TrimPrefix(s, " "), "\ufeff")
+	if s != "" {
+		msg.fields["message"] = s
+	}
+	return nil
+}
+
+// parseStructuredData parses the structured data, "-" or a sequence of
+// [SD-ID PARAM-NAME="PARAM-VALUE" ...] elements, and returns the text
+// following it. The elements without parameters are true fields.
+func (p *SyslogParser) parseStructuredData(msg *message, s string) (string, error) {
+	if s == "" {
+		return "", fmt.Errorf("invalid RFC 5424 message, missing structured data")
+	}
+	if s[0] == '-' {
+		return s[1:], nil
+	}
+	separator := p.SdparamSeparator
+	if separator == "" {
+		separator = "_"
+	}
+
+	for len(s) > 0 && s[0] == '[' {
+		end := strings.IndexAny(s, " ]")
+		if end < 0 {
+			return "", fmt.Errorf("invalid structured data %q", s)
+		}
+		id := s[1:end]
+		s = s[end:]
+		params := 0
+		for len(s) > 0 && s[0] == ' ' {
+			eq := strings.Index(s, "=\"")
+			if eq < 0 {
+				return "", fmt.Errorf("invalid structured data parameter in %q", id)
+			}
+			name := s[1:eq]
+			var value bytes.Buffer
+			i := eq + 2
+			for ; i < len(s) && s[i] != '"'; i++ {
+				// The escaped characters are '"', '\' and ']'.
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(`"\]`, s[i+1]) >= 0 {
+					i++
+				}
+				value.WriteByte(s[i])
+			}
+			if i == len(s) {
+				return "", fmt.Errorf("unterminated structured data parameter %s in %q", name, id)
+			}
+			msg.fields[id+separator+name] = value.String()
+			params++
+			s = s[i+1:]
+		}
+		if len(s) == 0 || s[0] != ']' {
+			return "", fmt.Errorf("invalid structured data element %q", id)
+		}
+		if params == 0 {
+			msg.fields[id] = true
+		}
+		s = s[1:]
+	}
+	return s, nil
+}
+
+// parseRFC3164 parses the message following the priority:
+// TIMESTAMP HOSTNAME TAG[PID]: MSG
+// The timestamp is "Jan _2 15:04:05", in the current year, or RFC 3339 as
+// written by rsyslog. The hostname and the tag are optional.
+func (p *SyslogParser) parseRFC3164(msg *message, s string) error {
+	loc := time.UTC
+	if p.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(p.Timezone); err != nil {
+			return err
+		}
+	}
+
+	if len(s) >= len(time.Stamp) {
+		if t, err := time.ParseInLocation(time.Stamp, s[:len(time.Stamp)], loc); err == nil {
+			now := p.timeNow().In(loc)
+			msg.time = t.AddDate(now.Year(), 0, 0)
+			// The messages of December read in January are of last year.
+			if msg.time.After(now.AddDate(0, 1, 0)) {
+				msg.time = msg.time.AddDate(-1, 0, 0)
+			}
+			s = strings.TrimPrefix(s[len(time.Stamp):], " ")
+		}
+	}
+	if msg.time.IsZero() {
+		token, rest := nextToken(s)
+		if t, err := time.Parse(time.RFC3339Nano, token); err == nil {
+			msg.time = t
+			s = rest
+		}
+	}
+
+	token, rest := nextToken(s)
+	if token != "" && !isTag(token) {
+		msg.tags["hostname"] = token
+		token, rest = nextToken(rest)
+	}
+	if isTag(token) {
+		tag := strings.TrimSuffix(token, ":")
+		if open := strings.IndexByte(tag, '['); open >= 0 && strings.HasSuffix(tag, "]") {
+			msg.fields["procid"] = tag[open+1 : len(tag)-1]
+			tag = tag[:open]
+		}
+		msg.tags["appname"] = tag
+		s = rest
+	} else if _, ok := msg.tags["hostname"]; ok {
+		s = strings.TrimPrefix(s, msg.tags["hostname"]+" ")
+	}
+
+	if s != "" {
+		msg.fields["message"] = s
+	}
+	return nil
+}
+
+// isTag reports whether the token is the tag of an RFC 3164 message, ie
+// "sshd[123]:".
+func isTag(token string) bool {
+	return len(token) > 1 && strings.HasSuffix(token, ":")
+}
@@ -0,0 +1,149 @@
+package syslog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fixedNow() time.Time {
+	return time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+}
+
+func TestParseRFC5424(t *testing.T) {
+	parser := &SyslogParser{DefaultTags: map[string]string{"source": "kafka"}}
+	m, err := parser.ParseLine(`<165>1 2017-06-01T11:59:58.123+02:00 mymachine.example.com evntslog - ID47 ` +
+		`[exampleSDID@32473 iut="3" eventSource="Application" eventID="1011"][origin ip="192.0.2.1"]` +
+		" \ufeffAn application event log entry...")
+	require.NoError(t, err)
+
+	assert.Equal(t, Measurement, m.Name())
+	assert.Equal(t, map[string]string{
+		"severity": "notice",
+		"facility": "local4",
+		"hostname": "mymachine.example.com",
+		"appname":  "evntslog",
+		"source":   "kafka",
+	}, m.Tags())
+	assert.Equal(t, map[string]interface{}{
+		"severity_code":                 int64(5),
+		"facility_code":                 int64(20),
+		"version":                       int64(1),
+		"msgid":                         "ID47",
+		"exampleSDID@32473_iut":         "3",
+		"exampleSDID@32473_eventSource": "Application",
+		"exampleSDID@32473_eventID":     "1011",
+		"origin_ip":                     "192.0.2.1",
+		"message":                       "An application event log entry...",
+	}, m.Fields())
+	assert.Equal(t, time.Date(2017, 6, 1, 9, 59, 58, 123000000, time.UTC).UnixNano(),
+		m.Time().UnixNano())
+}
+
+func TestParseStructuredData(t *testing.T) {
+	parser := &SyslogParser{SdparamSeparator: "."}
+	m, err := parser.ParseLine(`<13>1 - host app 42 - [meta sequenceId="1" note="a \"quoted\] \\ value"][timeQuality]`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"severity_code":   int64(5),
+		"facility_code":   int64(1),
+		"version":         int64(1),
+		"procid":          "42",
+		"meta.sequenceId": "1",
+		"meta.note":       `a "quoted] \ value`,
+		"timeQuality":     true,
+	}, m.Fields())
+
+	for _, line := range []string{
+		`<13>1 - host app - - [meta note="unterminated]`,
+		`<13>1 - host app - - [meta note]`,
+		`<13>1 - host app - -`,
+		`<13>1 yesterday host app - - -`,
+	} {
+		_, err := parser.ParseLine(line)
+		assert.Error(t, err, line)
+	}
+}
+
+func TestParseRFC3164(t *testing.T) {
+	parser := &SyslogParser{now: fixedNow, Timezone: "Europe/Berlin"}
+	m, err := parser.ParseLine("<34>Jun  1 13:58:00 mymachine su[230]: 'su root' failed for lonvick on /dev/pts/8")
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"severity": "crit",
+		"facility": "auth",
+		"hostname": "mymachine",
+		"appname":  "su",
+	}, m.Tags())
+	assert.Equal(t, map[string]interface{}{
+		"severity_code": int64(2),
+		"facility_code": int64(4),
+		"procid":        "230",
+		"message":       "'su root' failed for lonvick on /dev/pts/8",
+	}, m.Fields())
+	assert.Equal(t, time.Date(2017, 6, 1, 11, 58, 0, 0, time.UTC).UnixNano(),
+		m.Time().UnixNano())
+
+	// The messages of December are of last year in January.
+	parser.now = func() time.Time { return time.Date(2018, 1, 1, 0, 5, 0, 0, time.UTC) }
+	m, err = parser.ParseLine("<13>Dec 31 23:59:00 mymachine cron: done")
+	require.NoError(t, err)
+	assert.Equal(t, 2017, m.Time().Year())
+}
+
+func TestParseRFC3164Variants(t *testing.T) {
+	parser := &SyslogParser{now: fixedNow, Format: "rfc3164"}
+
+	m, err := parser.ParseLine("<30>2017-06-01T11:00:00.5Z web nginx: started")
+	require.NoError(t, err)
+	assert.Equal(t, "nginx", m.Tags()["appname"])
+	assert.Equal(t, "web", m.Tags()["hostname"])
+	assert.Equal(t, int64(500000000), int64(m.Time().Nanosecond()))
+
+	// Without timestamp nor hostname
+	m, err = parser.ParseLine("<30>kernel: disk full")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"severity": "info",
+		"facility": "daemon",
+		"appname":  "kernel",
+	}, m.Tags())
+	assert.Equal(t, "disk full", m.Fields()["message"])
+	assert.Equal(t, fixedNow().UnixNano(), m.Time().UnixNano())
+
+	// Without tag
+	m, err = parser.ParseLine("<30>Jun  1 11:00:00 web just a message")
+	require.NoError(t, err)
+	assert.Equal(t, "web", m.Tags()["hostname"])
+	assert.Equal(t, "just a message", m.Fields()["message"])
+}
+
+func TestParse(t *testing.T) {
+	parser := &SyslogParser{now: fixedNow}
+	metrics, err := parser.Parse([]byte("<34>Jun  1 11:00:00 a su: one\n" +
+		"<165>1 - b app - - - two\r\n\n"))
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	assert.Equal(t, "a", metrics[0].Tags()["hostname"])
+	assert.Equal(t, "two", metrics[1].Fields()["message"])
+
+	// Octet counting, the messages may span lines
+	metrics, err = parser.Parse([]byte("29 <34>Jun  1 11:00:00 a su: 1\n2" +
+		"22 <165>1 - b app - - - x"))
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	assert.Equal(t, "1\n2", metrics[0].Fields()["message"])
+	assert.Equal(t, "x", metrics[1].Fields()["message"])
+
+	for _, buf := range []string{"5 <34>", "x <34>", "<192>1 - - - - - -", "Jun  1 11:00:00 a su: one"} {
+		_, err = parser.Parse([]byte(buf))
+		assert.Error(t, err, buf)
+	}
+
+	parser.Format = "rfc1234"
+	_, err = parser.Parse([]byte("<34>Jun  1 11:00:00 a su: one"))
+	assert.Error(t, err)
+}
@@ -12,13 +12,22 @@ import (
 )
 
 type ValueParser struct {
-	MetricName  string
-	DataType    string
+	MetricName string
+	DataType   string
+	// FieldNames are the names of the fields of the values of the messages
+	// holding several values, split by Separator or by whitespace if it is
+	// empty. The values of the empty names are ignored. The messages hold
+	// a single "value" field if there are no names.
+	FieldNames  []string
+	Separator   string
 	DefaultTags map[string]string
 }
 
 func (v *ValueParser) Parse(buf []byte) ([]telegraf.Metric, error) {
 	vStr := string(bytes.TrimSpace(bytes.Trim(buf, "\x00")))
+	if len(v.FieldNames) > 0 {
+		return v.parseValues(vStr)
+	}
 
 	// unless it's a string, separate out any fields in the buffer,
 	// ignore anything but the last.
@@ -30,18 +39,7 @@ func (v *ValueParser) Parse(buf []byte) ([]telegraf.Metric, error) {
 		vStr = string(values[len(values)-1])
 	}
 
-	var value interface{}
-	var err error
-	switch v.DataType {
-	case "", "int", "integer":
-		value, err = strconv.Atoi(vStr)
-	case "float", "long":
-		value, err = strconv.ParseFloat(vStr, 64)
-	case "str", "string":
-		value = vStr
-	case "bool", "boolean":
-		value, err = strconv.ParseBool(vStr)
-	}
+	value, err := v.convert(vStr)
 	if err != nil {
 		return nil, err
 	}
@@ -56,6 +54,71 @@ func (v *ValueParser) Parse(buf []byte) ([]telegraf.Metric, error) {
 	return []telegraf.Metric{metric}, nil
 }
 
+// parseValues parses the messages holding a value for each field name, ie
+// "230.1,4.9,1127".
+func (v *ValueParser) parseValues(vStr string) ([]telegraf.Metric, error) {
+	if vStr == "" {
+		return []telegraf.Metric{}, nil
+	}
+
+	var values []string
+	if v.Separator == "" {
+		values = strings.Fields(vStr)
+	} else {
+		values = strings.Split(vStr, v.Separator)
+	}
+	if len(values) != len(v.FieldNames) {
+		return nil, fmt.Errorf("%d values for %d field names in %q",
+			len(values), len(v.FieldNames), vStr)
+	}
+
+	fields := make(map[string]interface{}, len(values))
+	for i, name := range v.FieldNames {
+		if name == "" {
+			continue
+		}
+		vStr := strings.TrimSpace(values[i])
+		// The values missing from the messages are left out, unless the
+		// values are strings.
+		if vStr == "" && v.DataType != "str" && v.DataType != "string" {
+			continue
+		}
+		value, err := v.convert(vStr)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %s", name, err)
+		}
+		fields[name] = value
+	}
+	if len(fields) == 0 {
+		return []telegraf.Metric{}, nil
+	}
+
+	metric, err := metric.New(v.MetricName, v.DefaultTags,
+		fields, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	return []telegraf.Metric{metric}, nil
+}
+
+// convert converts the value to the DataType.
+func (v *ValueParser) convert(vStr string) (interface{}, error) {
+	var value interface{}
+	var err error
+	switch v.DataType {
+	case "", "int", "integer":
+		value, err = strconv.Atoi(vStr)
+	case "float", "long":
+		value, err = strconv.ParseFloat(vStr, 64)
+	case "str", "string":
+		value = vStr
+	case "bool", "boolean":
+		value, err = strconv.ParseBool(vStr)
+	}
+	return value, err
+}
+
 func (v *ValueParser) ParseLine(line string) (telegraf.Metric, error) {
 	metrics, err := v.Parse([]byte(line))
 
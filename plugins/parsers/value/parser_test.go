@@ -251,3 +251,52 @@ func TestParseValuesWithNullCharacter(t *testing.T) {
 	}, metrics[0].Fields())
 	assert.Equal(t, map[string]string{}, metrics[0].Tags())
 }
+
+func TestParseFieldNames(t *testing.T) {
+	parser := ValueParser{
+		MetricName: "value_test",
+		DataType:   "float",
+		FieldNames: []string{"voltage", "current", "power"},
+		Separator:  ",",
+	}
+	metrics, err := parser.Parse([]byte("230.1,4.9,1127\n"))
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 1)
+	assert.Equal(t, map[string]interface{}{
+		"voltage": 230.1,
+		"current": 4.9,
+		"power":   float64(1127),
+	}, metrics[0].Fields())
+
+	// Missing values and ignored values
+	parser.FieldNames = []string{"voltage", "", "power"}
+	metrics, err = parser.Parse([]byte("230.1, 4.9, "))
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 1)
+	assert.Equal(t, map[string]interface{}{
+		"voltage": 230.1,
+	}, metrics[0].Fields())
+
+	// Split by whitespace without separator
+	parser = ValueParser{
+		MetricName: "value_test",
+		DataType:   "integer",
+		FieldNames: []string{"a", "b"},
+	}
+	metrics, err = parser.Parse([]byte("1   2"))
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 1)
+	assert.Equal(t, map[string]interface{}{
+		"a": int64(1),
+		"b": int64(2),
+	}, metrics[0].Fields())
+
+	metrics, err = parser.Parse([]byte(""))
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 0)
+
+	_, err = parser.Parse([]byte("1 2 3"))
+	assert.Error(t, err)
+	_, err = parser.Parse([]byte("1 x"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,174 @@
+package wavefront
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// WavefrontParser parses the points of the Wavefront data format, ie
+// "<metric> <value> [<timestamp>] source=<source> [<tag>=<value> ...]", the
+// metric being the name of the measurement, with a single "value" field, the
+// source and the point tags being tags. The timestamps are in seconds.
+type WavefrontParser struct {
+	DefaultTags map[string]string
+}
+
+func (p *WavefrontParser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	metrics := make([]telegraf.Metric, 0)
+	now := time.Now().UTC()
+
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		m, err := p.parsePoint(line, now)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+func (p *WavefrontParser) parsePoint(line string, now time.Time) (telegraf.Metric, error) {
+	tokens, err := tokenize(line)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wavefront point %q: %s", line, err)
+	}
+	if len(tokens) < 2 || tokens[0].key != "" || tokens[1].key != "" {
+		return nil, fmt.Errorf("invalid wavefront point %q: metric and value required", line)
+	}
+
+	name := tokens[0].value
+	value, err := strconv.ParseFloat(tokens[1].value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wavefront value %q", tokens[1].value)
+	}
+
+	t := now
+	tokens = tokens[2:]
+	if len(tokens) > 0 && tokens[0].key == "" {
+		ts, err := strconv.ParseFloat(tokens[0].value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid wavefront timestamp %q", tokens[0].value)
+		}
+		t = time.Unix(0, int64(ts*float64(time.Second))).UTC()
+		tokens = tokens[1:]
+	}
+
+	tags := make(map[string]string)
+	for k, v := range p.DefaultTags {
+		tags[k] = v
+	}
+	for _, token := range tokens {
+		if token.key == "" {
+			return nil, fmt.Errorf("invalid wavefront tag %q", token.value)
+		}
+		tags[token.key] = token.value
+	}
+
+	return metric.New(name, tags, map[string]interface{}{"value": value}, t)
+}
+
+// token is a word of a point, or a tag if key is set.
+type token struct {
+	key   string
+	value string
+}
+
+// tokenize splits the line into words and tags, the words and the values
+// of the tags being unquoted.
+func tokenize(line string) ([]token, error) {
+	var tokens []token
+	for {
+		line = strings.TrimLeft(line, " \t")
+		if line == "" {
+			return tokens, nil
+		}
+
+		var t token
+		var word string
+		var err error
+		if line[0] == '"' {
+			word, line, err = unquote(line)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			end := strings.IndexAny(line, " \t=")
+			if end == -1 {
+				end = len(line)
+			}
+			word, line = line[:end], line[end:]
+		}
+
+		if strings.HasPrefix(line, "=") {
+			t.key = word
+			line = line[1:]
+			if strings.HasPrefix(line, `"`) {
+				word, line, err = unquote(line)
+				if err != nil {
+					return nil, err
+				}
+			} else {
+				end := strings.IndexAny(line, " \t")
+				if end == -1 {
+					end = len(line)
+				}
+				word, line = line[:end], line[end:]
+			}
+		}
+		t.value = word
+		tokens = append(tokens, t)
+	}
+}
+
+// unquote returns the quoted string at the beginning of s and the rest of s.
+func unquote(s string) (string, string, error) {
+	var buf bytes.Buffer
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) && s[i+1] == '"' {
+				buf.WriteByte('"')
+				i++
+				continue
+			}
+			buf.WriteByte('\\')
+		case '"':
+			return buf.String(), s[i+1:], nil
+		default:
+			buf.WriteByte(s[i])
+		}
+	}
+	return "", "", fmt.Errorf("unterminated quoted string")
+}
+
+func (p *WavefrontParser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("Can not parse the line: %s, for data format: wavefront", line)
+	}
+
+	return metrics[0], nil
+}
+
+func (p *WavefrontParser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
@@ -0,0 +1,52 @@
+package wavefront
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePoint(t *testing.T) {
+	parser := &WavefrontParser{DefaultTags: map[string]string{"env": "test"}}
+	m, err := parser.ParseLine(`system.cpu.usage 23.5 1496318400 source=server-1 cpu=cpu0 "region name"="us west \"2\""`)
+	require.NoError(t, err)
+
+	assert.Equal(t, "system.cpu.usage", m.Name())
+	assert.Equal(t, map[string]string{
+		"source":      "server-1",
+		"cpu":         "cpu0",
+		"region name": `us west "2"`,
+		"env":         "test",
+	}, m.Tags())
+	assert.Equal(t, map[string]interface{}{"value": 23.5}, m.Fields())
+	assert.Equal(t, time.Unix(1496318400, 0).UnixNano(), m.Time().UnixNano())
+}
+
+func TestParse(t *testing.T) {
+	parser := &WavefrontParser{}
+	metrics, err := parser.Parse([]byte(`"quoted metric" -1e3 source=a
+# comment
+
+requests 42 1496318400.5 source="b"
+`))
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	assert.Equal(t, "quoted metric", metrics[0].Name())
+	assert.Equal(t, map[string]interface{}{"value": -1000.0}, metrics[0].Fields())
+	assert.Equal(t, map[string]string{"source": "b"}, metrics[1].Tags())
+	assert.Equal(t, int64(1496318400500000000), metrics[1].Time().UnixNano())
+
+	for _, line := range []string{
+		"requests",
+		"requests many source=a",
+		"requests 42 yesterday source=a",
+		"requests 42 source=a orphan",
+		`requests 42 source="a`,
+		"requests=1 42",
+	} {
+		_, err := parser.Parse([]byte(line))
+		assert.Error(t, err, line)
+	}
+}
@@ -0,0 +1,68 @@
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// node is an element of a XML document, or one of its attributes or its
+// text when selected by a path. The namespaces are ignored.
+type node struct {
+	name     string
+	attrs    []*node
+	children []*node
+	parent   *node
+	text     string
+}
+
+// value returns the text of the node, without the leading and trailing
+// white spaces.
+func (n *node) value() string {
+	return strings.TrimSpace(n.text)
+}
+
+// parse returns the document node of the XML data, whose child is the root
+// element.
+func parse(buf []byte) (*node, error) {
+	doc := &node{}
+	current := doc
+	dec := xml.NewDecoder(bytes.NewReader(buf))
+	var text []byte
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &node{name: t.Name.Local, parent: current}
+			for _, attr := range t.Attr {
+				n.attrs = append(n.attrs, &node{
+					name:   "@" + attr.Name.Local,
+					parent: n,
+					text:   attr.Value,
+				})
+			}
+			current.text += string(text)
+			text = text[:0]
+			current.children = append(current.children, n)
+			current = n
+		case xml.EndElement:
+			current.text += string(text)
+			text = text[:0]
+			current = current.parent
+		case xml.CharData:
+			text = append(text, t...)
+		}
+	}
+	if len(doc.children) == 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return doc, nil
+}
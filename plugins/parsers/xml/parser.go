@@ -0,0 +1,271 @@
+package xml
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// XMLParser makes metrics of the nodes selected with XPath expressions in
+// XML documents, ie the status pages of industrial equipment.
+//
+// The Query selects the nodes each becoming a metric, all other paths are
+// relative to these nodes.
+type XMLParser struct {
+	MetricName string
+
+	// Query is the path of the nodes to make metrics of, the root element
+	// if empty.
+	Query string
+	// NamePath is the path of the measurement name, MetricName if empty.
+	NamePath string
+	// TimePath is the path of the timestamp, the time of the parsing is used
+	// if empty. TimeFormat is "unix", "unix_ms", "unix_us", "unix_ns" or a
+	// Go reference time layout, the default is RFC3339.
+	TimePath   string
+	TimeFormat string
+
+	// TagPaths and FieldPaths map the tag and field names to their paths.
+	// When no field paths are set the attributes and the child elements
+	// without children of the nodes having a numeric or boolean value are
+	// the fields.
+	TagPaths   map[string]string
+	FieldPaths map[string]string
+	// FieldTypes converts the fields to "int", "float", "string" or "bool",
+	// the type is inferred from the value otherwise.
+	FieldTypes map[string]string
+
+	DefaultTags map[string]string
+
+	once       sync.Once
+	err        error
+	query      *xpath
+	namePath   *xpath
+	timePath   *xpath
+	tagPaths   map[string]*xpath
+	fieldPaths map[string]*xpath
+}
+
+// Compile checks the paths, it is called by Parse if needed.
+func (p *XMLParser) Compile() error {
+	p.once.Do(func() {
+		p.err = p.compile()
+	})
+	return p.err
+}
+
+func (p *XMLParser) compile() error {
+	var err error
+	query := p.Query
+	if query == "" {
+		query = "/*"
+	}
+	if p.query, err = compile(query); err != nil {
+		return err
+	}
+	if p.NamePath != "" {
+		if p.namePath, err = compile(p.NamePath); err != nil {
+			return err
+		}
+	}
+	if p.TimePath != "" {
+		if p.timePath, err = compile(p.TimePath); err != nil {
+			return err
+		}
+	}
+
+	p.tagPaths = make(map[string]*xpath, len(p.TagPaths))
+	for tag, path := range p.TagPaths {
+		if p.tagPaths[tag], err = compile(path); err != nil {
+			return fmt.Errorf("tag %s: %s", tag, err)
+		}
+	}
+	p.fieldPaths = make(map[string]*xpath, len(p.FieldPaths))
+	for field, path := range p.FieldPaths {
+		if p.fieldPaths[field], err = compile(path); err != nil {
+			return fmt.Errorf("field %s: %s", field, err)
+		}
+	}
+	for field, typ := range p.FieldTypes {
+		switch typ {
+		case "int", "float", "string", "bool":
+		default:
+			return fmt.Errorf("invalid type %q for field %s", typ, field)
+		}
+	}
+	return nil
+}
+
+// Parse makes a metric of each node selected by the query, the nodes
+// without any field are skipped.
+func (p *XMLParser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	if err := p.Compile(); err != nil {
+		return nil, err
+	}
+
+	doc, err := parse(buf)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse out as XML, %s", err)
+	}
+
+	metrics := make([]telegraf.Metric, 0)
+	now := time.Now().UTC()
+	for _, n := range p.query.selectNodes(doc) {
+		m, err := p.parseNode(n, now)
+		if err != nil {
+			return nil, err
+		}
+		if m != nil {
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+func (p *XMLParser) parseNode(n *node, now time.Time) (telegraf.Metric, error) {
+	name := p.MetricName
+	if p.namePath != nil {
+		if v, ok := p.namePath.first(n); ok && v != "" {
+			name = v
+		}
+	}
+
+	tags := make(map[string]string)
+	for k, v := range p.DefaultTags {
+		tags[k] = v
+	}
+	for tag, path := range p.tagPaths {
+		if v, ok := path.first(n); ok {
+			tags[tag] = v
+		}
+	}
+
+	fields := make(map[string]interface{})
+	if len(p.fieldPaths) == 0 {
+		var candidates []*node
+		candidates = append(candidates, n.attrs...)
+		for _, c := range n.children {
+			if len(c.children) == 0 {
+				candidates = append(candidates, c)
+			}
+		}
+		// The timestamp is not a field
+		var timeNodes []*node
+		if p.timePath != nil {
+			timeNodes = p.timePath.selectNodes(n)
+		}
+	candidates:
+		for _, c := range candidates {
+			for _, t := range timeNodes {
+				if c == t {
+					continue candidates
+				}
+			}
+			field := c.name
+			if field[0] == '@' {
+				field = field[1:]
+			}
+			if _, ok := fields[field]; ok {
+				continue
+			}
+			switch v := infer(c.value()).(type) {
+			case int64, float64, bool:
+				fields[field] = v
+			}
+		}
+	}
+	// Sorted so the errors are reported consistently.
+	names := make([]string, 0, len(p.fieldPaths))
+	for field := range p.fieldPaths {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+	for _, field := range names {
+		v, ok := p.fieldPaths[field].first(n)
+		if !ok {
+			continue
+		}
+		value, err := convert(v, p.FieldTypes[field])
+		if err != nil {
+			return nil, fmt.Errorf("unable to convert field %q, %s", field, err)
+		}
+		fields[field] = value
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	t := now
+	if p.timePath != nil {
+		v, ok := p.timePath.first(n)
+		if !ok {
+			return nil, fmt.Errorf("timestamp not found")
+		}
+		var err error
+		if t, err = internal.ParseTimestamp(v, p.TimeFormat, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return metric.New(name, tags, fields, t)
+}
+
+// infer returns the value as an int64, a float64 or a bool if it is one,
+// or as is.
+func infer(v string) interface{} {
+	if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(v, 64); err == nil {
+		return f
+	}
+	if b, err := strconv.ParseBool(v); err == nil && v != "1" && v != "0" {
+		return b
+	}
+	return v
+}
+
+// convert converts the value to the type, or to the type inferred from the
+// value if empty.
+func convert(v string, typ string) (interface{}, error) {
+	switch typ {
+	case "":
+		return infer(v), nil
+	case "int":
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return i, nil
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		return int64(f), err
+	case "float":
+		return strconv.ParseFloat(v, 64)
+	case "string":
+		return v, nil
+	case "bool":
+		return strconv.ParseBool(v)
+	}
+	return nil, fmt.Errorf("unknown type %q", typ)
+}
+
+func (p *XMLParser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("Can not parse the line: %s, for data format: xml", line)
+	}
+
+	return metrics[0], nil
+}
+
+func (p *XMLParser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
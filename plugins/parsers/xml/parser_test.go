@@ -0,0 +1,169 @@
+package xml
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const plcStatus = `<?xml version="1.0" encoding="UTF-8"?>
+<plc name="line-1" xmlns="urn:example:plc">
+  <timestamp>2017-06-01T12:00:00Z</timestamp>
+  <device id="pump-1" type="pump">
+    <status>RUNNING</status>
+    <speed unit="rpm">1450</speed>
+    <pressure>3.2</pressure>
+    <alarm>false</alarm>
+  </device>
+  <device id="pump-2" type="pump">
+    <status>STOPPED</status>
+    <speed unit="rpm">0</speed>
+    <pressure>0.1</pressure>
+    <alarm>true</alarm>
+  </device>
+  <device id="valve-1" type="valve">
+    <position>42</position>
+  </device>
+</plc>
+`
+
+func TestXPath(t *testing.T) {
+	doc, err := parse([]byte(plcStatus))
+	require.NoError(t, err)
+
+	tests := []struct {
+		path  string
+		count int
+		first string
+	}{
+		{"/plc/@name", 1, "line-1"},
+		{"/plc/device", 3, ""},
+		{"//speed/@unit", 2, "rpm"},
+		{"/plc/device[2]/status", 1, "STOPPED"},
+		{"/plc/device[last()]/@id", 1, "valve-1"},
+		{"/plc/device[@type='valve']/position", 1, "42"},
+		{"/plc/device[status='RUNNING']/@id", 1, "pump-1"},
+		{"/plc/device[@type=\"pump\"][2]/@id", 1, "pump-2"},
+		{"/plc/*[@id]", 3, ""},
+		{"//device/speed/../@id", 2, "pump-1"},
+		{"/plc/timestamp/text()", 1, "2017-06-01T12:00:00Z"},
+		{"/plc/missing", 0, ""},
+	}
+	for _, tt := range tests {
+		x, err := compile(tt.path)
+		require.NoError(t, err, tt.path)
+		nodes := x.selectNodes(doc)
+		assert.Len(t, nodes, tt.count, tt.path)
+		if tt.first != "" && len(nodes) > 0 {
+			assert.Equal(t, tt.first, nodes[0].value(), tt.path)
+		}
+	}
+
+	for _, path := range []string{"", "/plc//", "/plc/device[1"} {
+		_, err := compile(path)
+		assert.Error(t, err, path)
+	}
+}
+
+func TestParseNodes(t *testing.T) {
+	parser := &XMLParser{
+		MetricName: "plc",
+		Query:      "//device[@type='pump']",
+		TimePath:   "/plc/timestamp",
+		TagPaths: map[string]string{
+			"device": "@id",
+			"line":   "/plc/@name",
+		},
+		FieldPaths: map[string]string{
+			"status":   "status",
+			"speed":    "speed",
+			"pressure": "pressure",
+			"alarm":    "alarm",
+		},
+		FieldTypes:  map[string]string{"speed": "float"},
+		DefaultTags: map[string]string{"site": "factory"},
+	}
+
+	metrics, err := parser.Parse([]byte(plcStatus))
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+
+	assert.Equal(t, "plc", metrics[0].Name())
+	assert.Equal(t, map[string]string{
+		"device": "pump-1",
+		"line":   "line-1",
+		"site":   "factory",
+	}, metrics[0].Tags())
+	assert.Equal(t, map[string]interface{}{
+		"status":   "RUNNING",
+		"speed":    1450.0,
+		"pressure": 3.2,
+		"alarm":    false,
+	}, metrics[0].Fields())
+	assert.Equal(t, time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC).UnixNano(),
+		metrics[0].Time().UnixNano())
+
+	assert.Equal(t, "pump-2", metrics[1].Tags()["device"])
+	assert.Equal(t, true, metrics[1].Fields()["alarm"])
+}
+
+func TestParseDefaultFields(t *testing.T) {
+	parser := &XMLParser{
+		MetricName: "plc",
+		Query:      "/plc/device",
+		NamePath:   "@type",
+	}
+
+	metrics, err := parser.Parse([]byte(plcStatus))
+	require.NoError(t, err)
+	require.Len(t, metrics, 3)
+
+	assert.Equal(t, "pump", metrics[0].Name())
+	assert.Equal(t, map[string]interface{}{
+		"speed":    int64(1450),
+		"pressure": 3.2,
+		"alarm":    false,
+	}, metrics[0].Fields())
+	assert.Equal(t, "valve", metrics[2].Name())
+	assert.Equal(t, map[string]interface{}{
+		"position": int64(42),
+	}, metrics[2].Fields())
+}
+
+func TestParseUnixTime(t *testing.T) {
+	parser := &XMLParser{
+		MetricName: "sensor",
+		TimePath:   "@time",
+		TimeFormat: "unix_ms",
+	}
+
+	m, err := parser.ParseLine(`<reading time="1500000000000" temperature="21.5"/>`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"temperature": 21.5}, m.Fields())
+	assert.Equal(t, int64(1500000000), m.Time().Unix())
+}
+
+func TestParseErrors(t *testing.T) {
+	parser := &XMLParser{MetricName: "test"}
+	_, err := parser.Parse([]byte(`<a><b>1</b>`))
+	assert.Error(t, err)
+
+	parser = &XMLParser{
+		MetricName: "test",
+		FieldPaths: map[string]string{"value": "b"},
+		FieldTypes: map[string]string{"value": "int"},
+	}
+	_, err = parser.Parse([]byte(`<a><b>one</b></a>`))
+	assert.Error(t, err)
+
+	parser = &XMLParser{
+		MetricName: "test",
+		FieldTypes: map[string]string{"value": "uint"},
+	}
+	assert.Error(t, parser.Compile())
+
+	parser = &XMLParser{MetricName: "test", Query: "//"}
+	assert.Error(t, parser.Compile())
+}
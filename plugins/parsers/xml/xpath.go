@@ -0,0 +1,203 @@
+package xml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// step is a location step of a path, ie "item[@type='power']".
+type step struct {
+	// descendant is set for the steps following "//".
+	descendant bool
+	name       string
+	predicates []string
+}
+
+// xpath is a compiled path, supporting the subset of XPath made of
+// absolute and relative location paths, "//", ".", "..", "*", "@name",
+// "@*", "text()", and the predicates [n], [last()], [@name],
+// [@name='value'] and [name='value'].
+type xpath struct {
+	absolute bool
+	steps    []step
+}
+
+// splitSteps splits the path on the slashes outside of predicates.
+func splitSteps(path string) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case c == '/' && depth == 0:
+			parts = append(parts, path[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, path[start:])
+}
+
+func compile(path string) (*xpath, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil, fmt.Errorf("empty path")
+	}
+
+	x := &xpath{}
+	parts := splitSteps(path)
+	if parts[0] == "" {
+		x.absolute = true
+		parts = parts[1:]
+	}
+	descendant := false
+	for i, part := range parts {
+		if part == "" {
+			if descendant || i == len(parts)-1 {
+				return nil, fmt.Errorf("invalid path %q", path)
+			}
+			descendant = true
+			continue
+		}
+
+		s := step{descendant: descendant, name: part}
+		descendant = false
+		if open := strings.IndexByte(part, '['); open >= 0 {
+			if !strings.HasSuffix(part, "]") {
+				return nil, fmt.Errorf("invalid predicate in path %q", path)
+			}
+			s.name = part[:open]
+			for _, p := range strings.Split(part[open+1:len(part)-1], "][") {
+				s.predicates = append(s.predicates, strings.TrimSpace(p))
+			}
+		}
+		if s.name == "" {
+			return nil, fmt.Errorf("invalid path %q", path)
+		}
+		x.steps = append(x.steps, s)
+	}
+	return x, nil
+}
+
+// selectNodes returns the nodes selected by the path from the context node,
+// in document order.
+func (x *xpath) selectNodes(context *node) []*node {
+	nodes := []*node{context}
+	if x.absolute {
+		for context.parent != nil {
+			context = context.parent
+		}
+		nodes = []*node{context}
+	}
+	for _, s := range x.steps {
+		var next []*node
+		for _, n := range nodes {
+			if s.descendant {
+				for _, d := range descendantsOrSelf(n, nil) {
+					next = append(next, s.apply(d)...)
+				}
+			} else {
+				next = append(next, s.apply(n)...)
+			}
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// first returns the value of the first node selected from the context
+// node, and false if there is none.
+func (x *xpath) first(context *node) (string, bool) {
+	nodes := x.selectNodes(context)
+	if len(nodes) == 0 {
+		return "", false
+	}
+	return nodes[0].value(), true
+}
+
+func descendantsOrSelf(n *node, nodes []*node) []*node {
+	nodes = append(nodes, n)
+	for _, c := range n.children {
+		nodes = descendantsOrSelf(c, nodes)
+	}
+	return nodes
+}
+
+// apply returns the nodes selected by the step from the node.
+func (s *step) apply(n *node) []*node {
+	var nodes []*node
+	switch {
+	case s.name == ".":
+		nodes = []*node{n}
+	case s.name == "..":
+		if n.parent != nil {
+			nodes = []*node{n.parent}
+		}
+	case s.name == "text()":
+		nodes = []*node{{name: "text()", parent: n, text: n.text}}
+	case s.name == "@*":
+		nodes = n.attrs
+	case strings.HasPrefix(s.name, "@"):
+		for _, attr := range n.attrs {
+			if attr.name == s.name {
+				nodes = append(nodes, attr)
+			}
+		}
+	default:
+		for _, c := range n.children {
+			if s.name == "*" || c.name == s.name {
+				nodes = append(nodes, c)
+			}
+		}
+	}
+
+	for _, p := range s.predicates {
+		nodes = filter(nodes, p)
+	}
+	return nodes
+}
+
+// filter returns the nodes matching the predicate.
+func filter(nodes []*node, predicate string) []*node {
+	if predicate == "last()" {
+		if len(nodes) == 0 {
+			return nil
+		}
+		return nodes[len(nodes)-1:]
+	}
+	if i, err := strconv.Atoi(predicate); err == nil {
+		if i < 1 || i > len(nodes) {
+			return nil
+		}
+		return nodes[i-1 : i]
+	}
+
+	name, want, compare := predicate, "", false
+	if eq := strings.IndexByte(predicate, '='); eq >= 0 {
+		name = strings.TrimSpace(predicate[:eq])
+		want = strings.Trim(strings.TrimSpace(predicate[eq+1:]), `'"`)
+		compare = true
+	}
+	var matched []*node
+	for _, n := range nodes {
+		s := step{name: name}
+		for _, c := range s.apply(n) {
+			if !compare || c.value() == want {
+				matched = append(matched, n)
+				break
+			}
+		}
+	}
+	return matched
+}
@@ -0,0 +1,347 @@
+package converter
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Tags to convert, the tags matching the patterns of a type are converted
+  ## to fields of the type, and the tag of measurement is the name of the
+  ## metric.
+  [processors.converter.tags]
+    # measurement = []
+    string = []
+    integer = []
+    unsigned = []
+    boolean = []
+    float = []
+
+  ## Fields to convert, the fields matching the patterns of a type are
+  ## converted to the type, those of tag to tags, of measurement to the name
+  ## of the metric and of timestamp to its timestamp. The fields failing to
+  ## be converted are removed, the unsigned integers are stored as integers
+  ## capped to the maximum integer.
+  [processors.converter.fields]
+    # measurement = []
+    tag = []
+    string = []
+    integer = []
+    unsigned = []
+    boolean = []
+    float = []
+    # timestamp = []
+
+    ## Format of the timestamps, "unix", "unix_ms", "unix_us", "unix_ns" or
+    ## a Go time layout, RFC3339 if empty.
+    # timestamp_format = ""
+`
+
+// Conversion are the patterns of the names of the tags or fields converted
+// to each type.
+type Conversion struct {
+	Measurement     []string
+	Tag             []string
+	String          []string
+	Integer         []string
+	Unsigned        []string
+	Boolean         []string
+	Float           []string
+	Timestamp       []string
+	TimestampFormat string `toml:"timestamp_format"`
+}
+
+// Converter converts the tags and fields of the metrics.
+type Converter struct {
+	Tags   *Conversion
+	Fields *Conversion
+
+	Log telegraf.Logger `toml:"-"`
+
+	tagConversions   []conversion
+	fieldConversions []conversion
+}
+
+// conversion converts the tags or fields matching filter to typ.
+type conversion struct {
+	typ    string
+	filter filter.Filter
+}
+
+func (c *Converter) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *Converter) Description() string {
+	return "Convert values to another metric value type"
+}
+
+// Init compiles the patterns.
+func (c *Converter) Init() error {
+	var err error
+	c.tagConversions, err = compile(c.Tags)
+	if err != nil {
+		return err
+	}
+	c.fieldConversions, err = compile(c.Fields)
+	return err
+}
+
+// compile returns the conversions of the patterns of conv, in the order
+// they are tried.
+func compile(conv *Conversion) ([]conversion, error) {
+	if conv == nil {
+		return nil, nil
+	}
+	var conversions []conversion
+	for _, c := range []struct {
+		typ      string
+		patterns []string
+	}{
+		{"measurement", conv.Measurement},
+		{"tag", conv.Tag},
+		{"string", conv.String},
+		{"integer", conv.Integer},
+		{"unsigned", conv.Unsigned},
+		{"boolean", conv.Boolean},
+		{"float", conv.Float},
+		{"timestamp", conv.Timestamp},
+	} {
+		if len(c.patterns) == 0 {
+			continue
+		}
+		f, err := filter.Compile(c.patterns)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s patterns: %s", c.typ, err)
+		}
+		conversions = append(conversions, conversion{c.typ, f})
+	}
+	return conversions, nil
+}
+
+// typeOf returns the type the name is converted to, empty if it is not
+// converted.
+func typeOf(conversions []conversion, name string) string {
+	for _, c := range conversions {
+		if c.filter.Match(name) {
+			return c.typ
+		}
+	}
+	return ""
+}
+
+func (c *Converter) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := make([]telegraf.Metric, 0, len(in))
+	for _, m := range in {
+		converted, err := c.convert(m)
+		if err != nil {
+			c.Log.Errorf("could not convert %s: %s", m.Name(), err)
+			out = append(out, m)
+			continue
+		}
+		out = append(out, converted)
+	}
+	return out
+}
+
+func (c *Converter) convert(m telegraf.Metric) (telegraf.Metric, error) {
+	name, tm := m.Name(), m.Time()
+	tags, fields := m.Tags(), m.Fields()
+	changed := false
+
+	for k, v := range m.Tags() {
+		typ := typeOf(c.tagConversions, k)
+		if typ == "" {
+			continue
+		}
+		changed = true
+		delete(tags, k)
+		if typ == "measurement" {
+			name = v
+			continue
+		}
+		if value, ok := convertValue(v, typ); ok {
+			fields[k] = value
+		} else {
+			c.Log.Debugf("could not convert tag %s %q to %s", k, v, typ)
+		}
+	}
+
+	for k, v := range m.Fields() {
+		typ := typeOf(c.fieldConversions, k)
+		if typ == "" {
+			continue
+		}
+		changed = true
+		delete(fields, k)
+		switch typ {
+		case "measurement":
+			name = toString(v)
+		case "tag":
+			tags[k] = toString(v)
+		case "timestamp":
+			t, err := internal.ParseTimestamp(v, c.Fields.TimestampFormat, nil)
+			if err != nil {
+				c.Log.Debugf("could not convert field %s to the timestamp: %s", k, err)
+				continue
+			}
+			tm = t
+		default:
+			if value, ok := convertValue(v, typ); ok {
+				fields[k] = value
+			} else {
+				c.Log.Debugf("could not convert field %s %v to %s", k, v, typ)
+			}
+		}
+	}
+
+	if !changed {
+		return m, nil
+	}
+	return metric.New(name, tags, fields, tm, m.Type())
+}
+
+// convertValue converts the value to the type, false if it can not.
+func convertValue(v interface{}, typ string) (interface{}, bool) {
+	switch typ {
+	case "string":
+		return toString(v), true
+	case "integer":
+		return toInteger(v)
+	case "unsigned":
+		return toUnsigned(v)
+	case "boolean":
+		return toBool(v)
+	case "float":
+		return toFloat(v)
+	}
+	return nil, false
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(v)
+}
+
+func toInteger(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case int64:
+		return v, true
+	case uint64:
+		if v > math.MaxInt64 {
+			return nil, false
+		}
+		return int64(v), true
+	case float64:
+		if v < math.MinInt64 || v >= math.MaxInt64 || math.IsNaN(v) {
+			return nil, false
+		}
+		return int64(v), true
+	case bool:
+		if v {
+			return int64(1), true
+		}
+		return int64(0), true
+	case string:
+		if i, err := strconv.ParseInt(v, 0, 64); err == nil {
+			return i, true
+		}
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return toInteger(f)
+		}
+	}
+	return nil, false
+}
+
+func toUnsigned(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case int64:
+		if v < 0 {
+			return nil, false
+		}
+		return uint64(v), true
+	case uint64:
+		return v, true
+	case float64:
+		if v < 0 || v >= math.MaxUint64 || math.IsNaN(v) {
+			return nil, false
+		}
+		return uint64(v), true
+	case bool:
+		if v {
+			return uint64(1), true
+		}
+		return uint64(0), true
+	case string:
+		if u, err := strconv.ParseUint(v, 0, 64); err == nil {
+			return u, true
+		}
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return toUnsigned(f)
+		}
+	}
+	return nil, false
+}
+
+func toFloat(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1.0, true
+		}
+		return 0.0, true
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+func toBool(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case int64:
+		return v != 0, true
+	case uint64:
+		return v != 0, true
+	case float64:
+		return v != 0, true
+	case bool:
+		return v, true
+	case string:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+func init() {
+	processors.Add("converter", func() telegraf.Processor {
+		return &Converter{}
+	})
+}
@@ -0,0 +1,115 @@
+package converter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2018, time.May, 1, 12, 0, 0, 0, time.UTC)
+
+func newConverter(t *testing.T, tags, fields *Conversion) *Converter {
+	c := &Converter{
+		Tags:   tags,
+		Fields: fields,
+		Log:    models.NewLogger("processors", "converter", ""),
+	}
+	require.NoError(t, c.Init())
+	return c
+}
+
+func TestConvertFields(t *testing.T) {
+	c := newConverter(t, nil, &Conversion{
+		Tag:      []string{"serial"},
+		String:   []string{"code"},
+		Integer:  []string{"count", "hex", "bad"},
+		Unsigned: []string{"total"},
+		Boolean:  []string{"on"},
+		Float:    []string{"power_*"},
+	})
+
+	m, _ := metric.New("inverter", nil, map[string]interface{}{
+		"serial":  int64(123),
+		"code":    int64(4),
+		"count":   "42",
+		"hex":     "0x10",
+		"bad":     "x",
+		"total":   2.5,
+		"on":      "true",
+		"power_1": int64(230),
+		"power_2": "229.8",
+		"other":   "kept",
+	}, now, telegraf.Gauge)
+	out := c.Apply(m)
+	require.Len(t, out, 1)
+	assert.Equal(t, "inverter", out[0].Name())
+	assert.Equal(t, map[string]string{"serial": "123"}, out[0].Tags())
+	assert.Equal(t, map[string]interface{}{
+		"code":  "4",
+		"count": int64(42),
+		"hex":   int64(16),
+		// the metrics store the unsigned integers as integers.
+		"total":   int64(2),
+		"on":      true,
+		"power_1": 230.0,
+		"power_2": 229.8,
+		"other":   "kept",
+	}, out[0].Fields())
+	assert.Equal(t, now.UnixNano(), out[0].Time().UnixNano())
+	assert.Equal(t, telegraf.Gauge, out[0].Type())
+}
+
+func TestConvertTags(t *testing.T) {
+	c := newConverter(t, &Conversion{
+		Measurement: []string{"type"},
+		Integer:     []string{"port"},
+		Boolean:     []string{"active"},
+	}, nil)
+
+	m, _ := metric.New("device",
+		map[string]string{"type": "meter", "port": "502", "active": "no", "host": "a"},
+		map[string]interface{}{"value": 1.0}, now)
+	out := c.Apply(m)
+	require.Len(t, out, 1)
+	assert.Equal(t, "meter", out[0].Name())
+	assert.Equal(t, map[string]string{"host": "a"}, out[0].Tags())
+	assert.Equal(t, map[string]interface{}{"value": 1.0, "port": int64(502)}, out[0].Fields())
+}
+
+func TestConvertTimestamp(t *testing.T) {
+	for _, tt := range []struct {
+		format string
+		value  interface{}
+	}{
+		{"unix", int64(1525176000)},
+		{"unix_ms", "1525176000000"},
+		{"unix_us", 1525176000000000.0},
+		{"", "2018-05-01T12:00:00Z"},
+		{"2006-01-02 15:04:05", "2018-05-01 12:00:00"},
+	} {
+		c := newConverter(t, nil, &Conversion{
+			Timestamp:       []string{"time"},
+			TimestampFormat: tt.format,
+		})
+		m, _ := metric.New("m", nil,
+			map[string]interface{}{"time": tt.value, "value": 1.0}, time.Now())
+		out := c.Apply(m)
+		require.Len(t, out, 1)
+		assert.Equal(t, now.UnixNano(), out[0].Time().UnixNano(), tt.format)
+		assert.Equal(t, map[string]interface{}{"value": 1.0}, out[0].Fields())
+	}
+}
+
+// Verify that the metrics are passed on unchanged when none of their tags
+// and fields are converted.
+func TestConvertUnchanged(t *testing.T) {
+	c := newConverter(t, nil, &Conversion{Integer: []string{"count"}})
+	m, _ := metric.New("m", nil, map[string]interface{}{"value": 1.0}, now)
+	assert.Equal(t, []telegraf.Metric{m}, c.Apply(m))
+}
@@ -0,0 +1,95 @@
+package date
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Tag or field the date of the timestamp of the metrics is added to.
+  tag_key = "month"
+  # field_key = "month"
+
+  ## Go time layout of the date, ie "Jan" for the month, "Monday" for the
+  ## day of the week, "15" for the hour or "2006-01" for the year and month.
+  date_format = "Jan"
+
+  ## Offset added to the timestamps before formatting them, ie "-1s" to
+  ## date the metrics of midnight of the previous day.
+  # date_offset = "0s"
+
+  ## Timezone of the date, "UTC", "Local" or a name of the IANA Time Zone
+  ## database like "Europe/Paris".
+  # timezone = "UTC"
+`
+
+// Date adds the date of the timestamp of the metrics to a tag or field.
+type Date struct {
+	TagKey     string `toml:"tag_key"`
+	FieldKey   string `toml:"field_key"`
+	DateFormat string `toml:"date_format"`
+	DateOffset internal.Duration
+	Timezone   string
+
+	Log telegraf.Logger `toml:"-"`
+
+	location *time.Location
+}
+
+func (d *Date) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *Date) Description() string {
+	return "Add the date of the timestamp of the metrics to a tag or field"
+}
+
+// Init checks the keys and loads the timezone.
+func (d *Date) Init() error {
+	if (d.TagKey == "") == (d.FieldKey == "") {
+		return fmt.Errorf("one of tag_key or field_key is required")
+	}
+	if d.DateFormat == "" {
+		return fmt.Errorf("date_format is required")
+	}
+	var err error
+	d.location, err = time.LoadLocation(d.Timezone)
+	if err != nil {
+		return fmt.Errorf("invalid timezone %q: %s", d.Timezone, err)
+	}
+	return nil
+}
+
+func (d *Date) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := make([]telegraf.Metric, 0, len(in))
+	for _, m := range in {
+		date := m.Time().Add(d.DateOffset.Duration).In(d.location).Format(d.DateFormat)
+		tags, fields := m.Tags(), m.Fields()
+		if d.TagKey != "" {
+			tags[d.TagKey] = date
+		} else {
+			fields[d.FieldKey] = date
+		}
+		dated, err := metric.New(m.Name(), tags, fields, m.Time(), m.Type())
+		if err != nil {
+			d.Log.Errorf("could not date %s: %s", m.Name(), err)
+			out = append(out, m)
+			continue
+		}
+		out = append(out, dated)
+	}
+	return out
+}
+
+func init() {
+	processors.Add("date", func() telegraf.Processor {
+		return &Date{
+			Timezone: "UTC",
+		}
+	})
+}
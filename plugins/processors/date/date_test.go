@@ -0,0 +1,72 @@
+package date
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// midnight is the start of the 1st of May 2018 in UTC.
+var midnight = time.Date(2018, time.May, 1, 0, 0, 0, 0, time.UTC)
+
+func testMetric(t time.Time) telegraf.Metric {
+	m, _ := metric.New("energy", map[string]string{"host": "a"},
+		map[string]interface{}{"total": int64(1000)}, t, telegraf.Counter)
+	return m
+}
+
+func TestDateTag(t *testing.T) {
+	d := &Date{TagKey: "month", DateFormat: "Jan", Timezone: "UTC"}
+	require.NoError(t, d.Init())
+
+	out := d.Apply(testMetric(midnight), testMetric(midnight.Add(-time.Second)))
+	require.Len(t, out, 2)
+	assert.Equal(t, map[string]string{"host": "a", "month": "May"}, out[0].Tags())
+	assert.Equal(t, map[string]string{"host": "a", "month": "Apr"}, out[1].Tags())
+	assert.Equal(t, map[string]interface{}{"total": int64(1000)}, out[0].Fields())
+	assert.Equal(t, midnight.UnixNano(), out[0].Time().UnixNano())
+	assert.Equal(t, telegraf.Counter, out[0].Type())
+}
+
+func TestDateField(t *testing.T) {
+	d := &Date{
+		FieldKey:   "weekday",
+		DateFormat: "Monday",
+		DateOffset: internal.Duration{Duration: -time.Second},
+		Timezone:   "UTC",
+	}
+	require.NoError(t, d.Init())
+
+	out := d.Apply(testMetric(midnight))
+	require.Len(t, out, 1)
+	assert.Equal(t, map[string]interface{}{"total": int64(1000), "weekday": "Monday"},
+		out[0].Fields())
+}
+
+func TestDateTimezone(t *testing.T) {
+	d := &Date{TagKey: "hour", DateFormat: "15", Timezone: "Asia/Tokyo"}
+	if err := d.Init(); err != nil {
+		t.Skipf("timezone database not available: %s", err)
+	}
+
+	out := d.Apply(testMetric(midnight))
+	require.Len(t, out, 1)
+	assert.Equal(t, "09", out[0].Tags()["hour"])
+}
+
+func TestInit(t *testing.T) {
+	for _, d := range []*Date{
+		{DateFormat: "Jan"},
+		{TagKey: "a", FieldKey: "b", DateFormat: "Jan"},
+		{TagKey: "a"},
+		{TagKey: "a", DateFormat: "Jan", Timezone: "Nowhere/Nothing"},
+	} {
+		assert.Error(t, d.Init())
+	}
+}
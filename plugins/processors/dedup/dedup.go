@@ -0,0 +1,96 @@
+package dedup
+
+import (
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Maximum time to suppress the metrics whose fields did not change, a
+  ## metric is passed on at least once per interval for each series.
+  dedup_interval = "600s"
+`
+
+// Dedup drops the metrics with the same fields as the last metric of their
+// series passed on, unless more than DedupInterval ago.
+type Dedup struct {
+	DedupInterval internal.Duration
+
+	// cache is the last metric passed on of each series.
+	cache map[uint64]telegraf.Metric
+	// expiry is the time after which the cache is cleaned.
+	expiry time.Time
+}
+
+func (d *Dedup) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *Dedup) Description() string {
+	return "Drop metrics whose fields did not change"
+}
+
+func (d *Dedup) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	if d.cache == nil {
+		d.cache = make(map[uint64]telegraf.Metric)
+	}
+	out := make([]telegraf.Metric, 0, len(in))
+	for _, m := range in {
+		id := m.HashID()
+		last, ok := d.cache[id]
+		if ok {
+			// The metrics older than the last one are passed on, they are
+			// out of order.
+			if m.Time().Before(last.Time()) {
+				out = append(out, m)
+				continue
+			}
+			if m.Time().Sub(last.Time()) < d.DedupInterval.Duration &&
+				sameFields(m.Fields(), last.Fields()) {
+				continue
+			}
+		}
+		d.cache[id] = m
+		out = append(out, m)
+	}
+	d.clean()
+	return out
+}
+
+// clean removes the series without metrics for more than DedupInterval from
+// the cache, at most once per interval.
+func (d *Dedup) clean() {
+	now := time.Now()
+	if now.Before(d.expiry) {
+		return
+	}
+	for id, m := range d.cache {
+		if now.Sub(m.Time()) >= d.DedupInterval.Duration {
+			delete(d.cache, id)
+		}
+	}
+	d.expiry = now.Add(d.DedupInterval.Duration)
+}
+
+func sameFields(a, b map[string]interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || v != w {
+			return false
+		}
+	}
+	return true
+}
+
+func init() {
+	processors.Add("dedup", func() telegraf.Processor {
+		return &Dedup{
+			DedupInterval: internal.Duration{Duration: 10 * time.Minute},
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package dedup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testMetric(tags map[string]string, value interface{}, t time.Time) telegraf.Metric {
+	m, _ := metric.New("inverter", tags, map[string]interface{}{"mode": value}, t)
+	return m
+}
+
+func newDedup() *Dedup {
+	return &Dedup{DedupInterval: internal.Duration{Duration: 10 * time.Minute}}
+}
+
+func TestDedup(t *testing.T) {
+	d := newDedup()
+	now := time.Now()
+	tags := map[string]string{"host": "a"}
+
+	assert.Len(t, d.Apply(testMetric(tags, int64(1), now)), 1)
+	// unchanged fields are suppressed within the interval.
+	assert.Len(t, d.Apply(testMetric(tags, int64(1), now.Add(time.Minute))), 0)
+	// changed fields are passed on.
+	assert.Len(t, d.Apply(testMetric(tags, int64(2), now.Add(2*time.Minute))), 1)
+	// other series are deduplicated on their own.
+	assert.Len(t, d.Apply(testMetric(map[string]string{"host": "b"}, int64(2),
+		now.Add(2*time.Minute))), 1)
+	// unchanged fields are passed on once the interval is over.
+	assert.Len(t, d.Apply(testMetric(tags, int64(2), now.Add(11*time.Minute))), 0)
+	assert.Len(t, d.Apply(testMetric(tags, int64(2), now.Add(12*time.Minute))), 1)
+	// metrics out of order are passed on.
+	assert.Len(t, d.Apply(testMetric(tags, int64(2), now)), 1)
+}
+
+func TestDedupBatch(t *testing.T) {
+	d := newDedup()
+	now := time.Now()
+	out := d.Apply(
+		testMetric(nil, "idle", now),
+		testMetric(nil, "idle", now.Add(time.Second)),
+		testMetric(nil, "mppt", now.Add(2*time.Second)),
+	)
+	if assert.Len(t, out, 2) {
+		assert.Equal(t, "idle", out[0].Fields()["mode"])
+		assert.Equal(t, "mppt", out[1].Fields()["mode"])
+	}
+}
+
+func TestDedupClean(t *testing.T) {
+	d := newDedup()
+	old := time.Now().Add(-time.Hour)
+	assert.Len(t, d.Apply(testMetric(nil, int64(1), old)), 1)
+	assert.Len(t, d.cache, 0)
+	assert.Len(t, d.Apply(testMetric(nil, int64(1), time.Now())), 1)
+	assert.Len(t, d.cache, 1)
+}
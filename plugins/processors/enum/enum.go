@@ -0,0 +1,170 @@
+package enum
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  [[processors.enum.mapping]]
+    ## Name of the field to map.
+    field = "inverterMode"
+
+    ## Name of the tag to map, instead of a field.
+    # tag = "mode"
+
+    ## Field or tag of the mapped value, the field or tag mapped is replaced
+    ## if empty.
+    # dest = "mode_code"
+
+    ## Value of the values not in the mappings, they are kept if not set.
+    # default = 0
+
+    ## Mappings of the values, the numbers and booleans are mapped with their
+    ## text, ie "1" or "true". The values mapped to tags are converted to
+    ## text.
+    [processors.enum.mapping.value_mappings]
+      OFF = 1
+      SLEEPING = 2
+      STARTING = 3
+      MPPT = 4
+`
+
+// Enum maps the values of fields and tags to other values.
+type Enum struct {
+	Mapping []*Mapping
+
+	Log telegraf.Logger `toml:"-"`
+}
+
+// Mapping maps the values of a field or a tag.
+type Mapping struct {
+	Field         string
+	Tag           string
+	Dest          string
+	Default       interface{}
+	ValueMappings map[string]interface{}
+}
+
+func (e *Enum) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Enum) Description() string {
+	return "Map enum values according to given table"
+}
+
+// Init checks the mappings.
+func (e *Enum) Init() error {
+	for _, mapping := range e.Mapping {
+		if (mapping.Field == "") == (mapping.Tag == "") {
+			return fmt.Errorf("one of field or tag is required in mappings")
+		}
+		if mapping.Default != nil && !valid(mapping.Default) {
+			return fmt.Errorf("invalid default of %s%s", mapping.Field, mapping.Tag)
+		}
+		for k, v := range mapping.ValueMappings {
+			if !valid(v) {
+				return fmt.Errorf("invalid mapping of %s of %s%s", k, mapping.Field, mapping.Tag)
+			}
+		}
+	}
+	return nil
+}
+
+func (e *Enum) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := make([]telegraf.Metric, 0, len(in))
+	for _, m := range in {
+		tags, fields := m.Tags(), m.Fields()
+		changed := false
+		for _, mapping := range e.Mapping {
+			if mapping.Tag != "" {
+				v, ok := tags[mapping.Tag]
+				if !ok {
+					continue
+				}
+				if mapped, ok := mapping.mapValue(v); ok {
+					tags[mapping.dest()] = format(mapped)
+					changed = true
+				}
+				continue
+			}
+			v, ok := fields[mapping.Field]
+			if !ok {
+				continue
+			}
+			if mapped, ok := mapping.mapValue(v); ok {
+				fields[mapping.dest()] = mapped
+				changed = true
+			}
+		}
+
+		if !changed {
+			out = append(out, m)
+			continue
+		}
+		mapped, err := metric.New(m.Name(), tags, fields, m.Time(), m.Type())
+		if err != nil {
+			e.Log.Errorf("could not map the values of %s: %s", m.Name(), err)
+			out = append(out, m)
+			continue
+		}
+		out = append(out, mapped)
+	}
+	return out
+}
+
+// mapValue returns the value mapped from the value, false if the value is
+// not mapped and there is no default.
+func (mapping *Mapping) mapValue(v interface{}) (interface{}, bool) {
+	if mapped, ok := mapping.ValueMappings[format(v)]; ok {
+		return mapped, true
+	}
+	if mapping.Default != nil {
+		return mapping.Default, true
+	}
+	return nil, false
+}
+
+func (mapping *Mapping) dest() string {
+	if mapping.Dest != "" {
+		return mapping.Dest
+	}
+	return mapping.Field + mapping.Tag
+}
+
+// valid returns true if the value is of a type of the fields.
+func valid(v interface{}) bool {
+	switch v.(type) {
+	case int64, float64, bool, string:
+		return true
+	}
+	return false
+}
+
+// format returns the text of the value of a field.
+func format(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(v)
+}
+
+func init() {
+	processors.Add("enum", func() telegraf.Processor {
+		return &Enum{}
+	})
+}
@@ -0,0 +1,82 @@
+package enum
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2018, time.May, 1, 12, 0, 0, 0, time.UTC)
+
+func testMetric(tags map[string]string, fields map[string]interface{}) telegraf.Metric {
+	m, _ := metric.New("inverter", tags, fields, now)
+	return m
+}
+
+func TestEnumField(t *testing.T) {
+	e := &Enum{Mapping: []*Mapping{{
+		Field:         "mode",
+		ValueMappings: map[string]interface{}{"OFF": int64(1), "MPPT": int64(4)},
+	}}}
+	require.NoError(t, e.Init())
+
+	out := e.Apply(
+		testMetric(nil, map[string]interface{}{"mode": "MPPT", "power": 1.5}),
+		testMetric(nil, map[string]interface{}{"mode": "FAULT"}),
+	)
+	require.Len(t, out, 2)
+	assert.Equal(t, map[string]interface{}{"mode": int64(4), "power": 1.5}, out[0].Fields())
+	assert.Equal(t, now.UnixNano(), out[0].Time().UnixNano())
+	// the values not mapped are kept without a default.
+	assert.Equal(t, map[string]interface{}{"mode": "FAULT"}, out[1].Fields())
+}
+
+func TestEnumDest(t *testing.T) {
+	e := &Enum{Mapping: []*Mapping{{
+		Field:         "code",
+		Dest:          "status",
+		Default:       "unknown",
+		ValueMappings: map[string]interface{}{"1": "off", "4": "mppt", "true": "on"},
+	}}}
+	require.NoError(t, e.Init())
+
+	out := e.Apply(
+		testMetric(nil, map[string]interface{}{"code": int64(4)}),
+		testMetric(nil, map[string]interface{}{"code": true}),
+		testMetric(nil, map[string]interface{}{"code": int64(7)}),
+	)
+	require.Len(t, out, 3)
+	assert.Equal(t, map[string]interface{}{"code": int64(4), "status": "mppt"}, out[0].Fields())
+	assert.Equal(t, map[string]interface{}{"code": true, "status": "on"}, out[1].Fields())
+	assert.Equal(t, map[string]interface{}{"code": int64(7), "status": "unknown"}, out[2].Fields())
+}
+
+func TestEnumTag(t *testing.T) {
+	e := &Enum{Mapping: []*Mapping{{
+		Tag:           "mode",
+		ValueMappings: map[string]interface{}{"MPPT": int64(4)},
+	}}}
+	require.NoError(t, e.Init())
+
+	out := e.Apply(testMetric(map[string]string{"mode": "MPPT"},
+		map[string]interface{}{"power": 1.5}))
+	require.Len(t, out, 1)
+	assert.Equal(t, map[string]string{"mode": "4"}, out[0].Tags())
+}
+
+func TestInit(t *testing.T) {
+	e := &Enum{Mapping: []*Mapping{{}}}
+	assert.Error(t, e.Init())
+	e = &Enum{Mapping: []*Mapping{{Field: "a", Tag: "b"}}}
+	assert.Error(t, e.Init())
+	e = &Enum{Mapping: []*Mapping{{
+		Field:         "a",
+		ValueMappings: map[string]interface{}{"x": []interface{}{int64(1)}},
+	}}}
+	assert.Error(t, e.Init())
+}
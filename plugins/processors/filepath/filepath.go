@@ -0,0 +1,200 @@
+package filepath
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## The operations apply to the path in the tag or the string field, and
+  ## store the result in dest if set, or in the tag or field. They are
+  ## applied in the order clean, rewrite, basename, dirname then stem.
+
+  ## Clean the path, ie "/var//log/../log/syslog" to "/var/log/syslog".
+  # [[processors.filepath.clean]]
+  #   tag = "path"
+
+  ## Replace the directory from of the paths under it with to, ie
+  ## "/var/log/nginx/access.log" to "nginx/access.log".
+  # [[processors.filepath.rewrite]]
+  #   tag = "path"
+  #   from = "/var/log/nginx"
+  #   to = "nginx"
+
+  ## Last element of the path, ie "/var/log/syslog.1" to "syslog.1".
+  [[processors.filepath.basename]]
+    tag = "path"
+    # dest = "file"
+
+  ## Directory of the path, ie "/var/log/syslog.1" to "/var/log".
+  # [[processors.filepath.dirname]]
+  #   field = "path"
+  #   dest = "folder"
+
+  ## Last element of the path without its extension, ie "/var/log/syslog.1"
+  ## to "syslog".
+  # [[processors.filepath.stem]]
+  #   tag = "path"
+`
+
+// Filepath transforms the paths of tags and string fields.
+type Filepath struct {
+	Clean    []*Operation
+	Rewrite  []*Operation
+	Basename []*Operation
+	Dirname  []*Operation
+	Stem     []*Operation
+
+	Log telegraf.Logger `toml:"-"`
+}
+
+// Operation is an operation on the path of Tag or Field, the result being
+// stored in Dest.
+type Operation struct {
+	Tag   string
+	Field string
+	Dest  string
+	// From and To are the directories of the rewrites.
+	From string
+	To   string
+}
+
+func (f *Filepath) SampleConfig() string {
+	return sampleConfig
+}
+
+func (f *Filepath) Description() string {
+	return "Performs file path manipulations on tags and fields"
+}
+
+// Init checks the operations.
+func (f *Filepath) Init() error {
+	for _, op := range f.operations() {
+		if (op.Tag == "") == (op.Field == "") {
+			return fmt.Errorf("one of tag or field is required in %s", op.name)
+		}
+	}
+	for _, op := range f.Rewrite {
+		if op.From == "" {
+			return fmt.Errorf("from is required in rewrite")
+		}
+	}
+	return nil
+}
+
+// namedOperation is an operation with the function transforming the paths.
+type namedOperation struct {
+	*Operation
+	name string
+	fn   func(op *Operation, path string) string
+}
+
+// operations returns the operations in the order they are applied.
+func (f *Filepath) operations() []namedOperation {
+	var ops []namedOperation
+	for _, o := range []struct {
+		name string
+		ops  []*Operation
+		fn   func(op *Operation, path string) string
+	}{
+		{"clean", f.Clean, func(_ *Operation, path string) string {
+			return filepath.Clean(path)
+		}},
+		{"rewrite", f.Rewrite, rewrite},
+		{"basename", f.Basename, func(_ *Operation, path string) string {
+			return filepath.Base(path)
+		}},
+		{"dirname", f.Dirname, func(_ *Operation, path string) string {
+			return filepath.Dir(path)
+		}},
+		{"stem", f.Stem, func(_ *Operation, path string) string {
+			base := filepath.Base(path)
+			return strings.TrimSuffix(base, filepath.Ext(base))
+		}},
+	} {
+		for _, op := range o.ops {
+			ops = append(ops, namedOperation{op, o.name, o.fn})
+		}
+	}
+	return ops
+}
+
+// rewrite replaces the directory From of the path with To, it returns the
+// path unchanged if it is not under From.
+func rewrite(op *Operation, path string) string {
+	from := filepath.Clean(op.From)
+	path = filepath.Clean(path)
+	if path == from {
+		return filepath.Clean(op.To)
+	}
+	prefix := from
+	if !strings.HasSuffix(prefix, string(filepath.Separator)) {
+		prefix += string(filepath.Separator)
+	}
+	if !strings.HasPrefix(path, prefix) {
+		return path
+	}
+	rel := path[len(prefix):]
+	if op.To == "" {
+		return rel
+	}
+	return filepath.Join(op.To, rel)
+}
+
+func (f *Filepath) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	ops := f.operations()
+	out := make([]telegraf.Metric, 0, len(in))
+	for _, m := range in {
+		tags, fields := m.Tags(), m.Fields()
+		changed := false
+		for _, op := range ops {
+			if op.Tag != "" {
+				path, ok := tags[op.Tag]
+				if !ok || path == "" {
+					continue
+				}
+				dest := op.Tag
+				if op.Dest != "" {
+					dest = op.Dest
+				}
+				tags[dest] = op.fn(op.Operation, path)
+				changed = true
+				continue
+			}
+			path, ok := fields[op.Field].(string)
+			if !ok || path == "" {
+				continue
+			}
+			dest := op.Field
+			if op.Dest != "" {
+				dest = op.Dest
+			}
+			fields[dest] = op.fn(op.Operation, path)
+			changed = true
+		}
+
+		if !changed {
+			out = append(out, m)
+			continue
+		}
+		transformed, err := metric.New(m.Name(), tags, fields, m.Time(), m.Type())
+		if err != nil {
+			f.Log.Errorf("could not transform the paths of %s: %s", m.Name(), err)
+			out = append(out, m)
+			continue
+		}
+		out = append(out, transformed)
+	}
+	return out
+}
+
+func init() {
+	processors.Add("filepath", func() telegraf.Processor {
+		return &Filepath{}
+	})
+}
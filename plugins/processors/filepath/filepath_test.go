@@ -0,0 +1,103 @@
+package filepath
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2018, time.May, 1, 12, 0, 0, 0, time.UTC)
+
+func testMetric(tags map[string]string, fields map[string]interface{}) telegraf.Metric {
+	m, _ := metric.New("tail", tags, fields, now, telegraf.Gauge)
+	return m
+}
+
+func TestOperations(t *testing.T) {
+	for _, tt := range []struct {
+		op   string
+		path string
+		want string
+	}{
+		{"clean", "/var//log/../log/syslog", "/var/log/syslog"},
+		{"basename", "/var/log/syslog.1", "syslog.1"},
+		{"dirname", "/var/log/syslog.1", "/var/log"},
+		{"stem", "/var/log/syslog.1", "syslog"},
+		{"stem", "/var/log/access.log.gz", "access.log"},
+	} {
+		op := &Operation{Tag: "path"}
+		f := &Filepath{}
+		switch tt.op {
+		case "clean":
+			f.Clean = []*Operation{op}
+		case "basename":
+			f.Basename = []*Operation{op}
+		case "dirname":
+			f.Dirname = []*Operation{op}
+		case "stem":
+			f.Stem = []*Operation{op}
+		}
+		require.NoError(t, f.Init())
+		out := f.Apply(testMetric(map[string]string{"path": tt.path},
+			map[string]interface{}{"value": 1.0}))
+		require.Len(t, out, 1)
+		assert.Equal(t, tt.want, out[0].Tags()["path"], tt.op)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	for _, tt := range []struct {
+		from, to, path, want string
+	}{
+		{"/var/log/nginx", "nginx", "/var/log/nginx/access.log", "nginx/access.log"},
+		{"/var/log/nginx/", "", "/var/log/nginx/access.log", "access.log"},
+		{"/var/log/nginx", "/logs", "/var/log/nginx", "/logs"},
+		{"/var/log/nginx", "nginx", "/var/log/nginx2/access.log", "/var/log/nginx2/access.log"},
+	} {
+		f := &Filepath{Rewrite: []*Operation{{Tag: "path", From: tt.from, To: tt.to}}}
+		require.NoError(t, f.Init())
+		out := f.Apply(testMetric(map[string]string{"path": tt.path},
+			map[string]interface{}{"value": 1.0}))
+		require.Len(t, out, 1)
+		assert.Equal(t, tt.want, out[0].Tags()["path"], tt.path)
+	}
+}
+
+func TestDest(t *testing.T) {
+	f := &Filepath{
+		Basename: []*Operation{{Tag: "path", Dest: "file"}},
+		Dirname:  []*Operation{{Field: "path", Dest: "folder"}},
+	}
+	require.NoError(t, f.Init())
+
+	out := f.Apply(testMetric(map[string]string{"path": "/var/log/syslog"},
+		map[string]interface{}{"path": "/var/log/nginx/access.log", "size": int64(10)}))
+	require.Len(t, out, 1)
+	assert.Equal(t, map[string]string{"path": "/var/log/syslog", "file": "syslog"}, out[0].Tags())
+	assert.Equal(t, map[string]interface{}{
+		"path":   "/var/log/nginx/access.log",
+		"folder": "/var/log/nginx",
+		"size":   int64(10),
+	}, out[0].Fields())
+	assert.Equal(t, now.UnixNano(), out[0].Time().UnixNano())
+	assert.Equal(t, telegraf.Gauge, out[0].Type())
+
+	// the metrics without the paths are passed on unchanged.
+	m := testMetric(nil, map[string]interface{}{"path": int64(1)})
+	assert.Equal(t, []telegraf.Metric{m}, f.Apply(m))
+}
+
+func TestInit(t *testing.T) {
+	for _, f := range []*Filepath{
+		{Basename: []*Operation{{}}},
+		{Stem: []*Operation{{Tag: "a", Field: "b"}}},
+		{Rewrite: []*Operation{{Tag: "a"}}},
+	} {
+		assert.Error(t, f.Init())
+	}
+}
@@ -0,0 +1,69 @@
+package pivot
+
+import (
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Tag whose value becomes the name of the field of the value.
+  tag_key = "phase"
+  ## Field whose value is moved to the field named after the tag.
+  value_key = "value"
+`
+
+// Pivot rotates the value of the tag TagKey into the name of the field of
+// the value of the field ValueKey.
+type Pivot struct {
+	TagKey   string `toml:"tag_key"`
+	ValueKey string `toml:"value_key"`
+
+	Log telegraf.Logger `toml:"-"`
+}
+
+func (p *Pivot) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *Pivot) Description() string {
+	return "Rotate a single valued metric using a tag key as the field name"
+}
+
+// Apply pivots the metrics with both the tag and the field, and passes on
+// the others unchanged.
+func (p *Pivot) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := make([]telegraf.Metric, 0, len(in))
+	for _, m := range in {
+		tags := m.Tags()
+		key, ok := tags[p.TagKey]
+		if !ok || key == "" {
+			out = append(out, m)
+			continue
+		}
+		fields := m.Fields()
+		value, ok := fields[p.ValueKey]
+		if !ok {
+			out = append(out, m)
+			continue
+		}
+
+		delete(tags, p.TagKey)
+		delete(fields, p.ValueKey)
+		fields[key] = value
+		pivoted, err := metric.New(m.Name(), tags, fields, m.Time(), m.Type())
+		if err != nil {
+			p.Log.Errorf("could not pivot %s: %s", m.Name(), err)
+			out = append(out, m)
+			continue
+		}
+		out = append(out, pivoted)
+	}
+	return out
+}
+
+func init() {
+	processors.Add("pivot", func() telegraf.Processor {
+		return &Pivot{}
+	})
+}
@@ -0,0 +1,43 @@
+package pivot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2018, time.May, 1, 12, 0, 0, 0, time.UTC)
+
+func testMetric(tags map[string]string, fields map[string]interface{}) telegraf.Metric {
+	m, _ := metric.New("voltage", tags, fields, now, telegraf.Gauge)
+	return m
+}
+
+func TestPivot(t *testing.T) {
+	p := &Pivot{TagKey: "phase", ValueKey: "value"}
+
+	out := p.Apply(
+		testMetric(map[string]string{"phase": "L1", "host": "a"},
+			map[string]interface{}{"value": 230.1, "frequency": 50.0}),
+		testMetric(map[string]string{"host": "a"},
+			map[string]interface{}{"value": 229.8}),
+		testMetric(map[string]string{"phase": "L2"},
+			map[string]interface{}{"other": int64(1)}),
+	)
+	require.Len(t, out, 3)
+	assert.Equal(t, "voltage", out[0].Name())
+	assert.Equal(t, map[string]string{"host": "a"}, out[0].Tags())
+	assert.Equal(t, map[string]interface{}{"L1": 230.1, "frequency": 50.0}, out[0].Fields())
+	assert.Equal(t, now.UnixNano(), out[0].Time().UnixNano())
+	assert.Equal(t, telegraf.Gauge, out[0].Type())
+
+	// the metrics without the tag or the field are passed on unchanged.
+	assert.Equal(t, map[string]interface{}{"value": 229.8}, out[1].Fields())
+	assert.Equal(t, map[string]string{"phase": "L2"}, out[2].Tags())
+	assert.Equal(t, map[string]interface{}{"other": int64(1)}, out[2].Fields())
+}
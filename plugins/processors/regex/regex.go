@@ -0,0 +1,137 @@
+package regex
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Replacements of the values of tags matching the patterns, in order. The
+  ## replacement can refer to the groups of the pattern, ie ${1}, and the
+  ## result is stored in result_key if set, the original value being kept,
+  ## or in key.
+  [[processors.regex.tags]]
+    key = "serial"
+    pattern = "^([0-9A-F]+)-[0-9]+$"
+    replacement = "${1}"
+
+  [[processors.regex.tags]]
+    key = "host"
+    pattern = "^([a-z]+)-.*$"
+    replacement = "${1}"
+    result_key = "site"
+
+  ## Replacements of the values of string fields, as for tags.
+  # [[processors.regex.fields]]
+  #   key = "request"
+  #   pattern = "^/api(?P<method>/[\\w/]+)\\S*"
+  #   replacement = "${method}"
+  #   result_key = "method"
+`
+
+// Regex replaces the values of tags and string fields matching patterns.
+type Regex struct {
+	Tags   []*Replacement
+	Fields []*Replacement
+
+	Log telegraf.Logger `toml:"-"`
+}
+
+// Replacement replaces the value of Key matching Pattern.
+type Replacement struct {
+	Key         string
+	Pattern     string
+	Replacement string
+	ResultKey   string `toml:"result_key"`
+
+	re *regexp.Regexp
+}
+
+func (r *Regex) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *Regex) Description() string {
+	return "Transform tag and field values with regex pattern"
+}
+
+// Init compiles the patterns.
+func (r *Regex) Init() error {
+	for _, replacements := range [][]*Replacement{r.Tags, r.Fields} {
+		for _, c := range replacements {
+			if c.Key == "" {
+				return fmt.Errorf("key is required for pattern %q", c.Pattern)
+			}
+			var err error
+			c.re, err = regexp.Compile(c.Pattern)
+			if err != nil {
+				return fmt.Errorf("invalid pattern %q of %s: %s", c.Pattern, c.Key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// replace returns the key of the result and the value replaced, false if
+// the value does not match.
+func (c *Replacement) replace(value string) (string, string, bool) {
+	if !c.re.MatchString(value) {
+		return "", "", false
+	}
+	key := c.Key
+	if c.ResultKey != "" {
+		key = c.ResultKey
+	}
+	return key, c.re.ReplaceAllString(value, c.Replacement), true
+}
+
+func (r *Regex) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := make([]telegraf.Metric, 0, len(in))
+	for _, m := range in {
+		tags, fields := m.Tags(), m.Fields()
+		changed := false
+		for _, c := range r.Tags {
+			v, ok := tags[c.Key]
+			if !ok {
+				continue
+			}
+			if key, value, ok := c.replace(v); ok {
+				tags[key] = value
+				changed = true
+			}
+		}
+		for _, c := range r.Fields {
+			v, ok := fields[c.Key].(string)
+			if !ok {
+				continue
+			}
+			if key, value, ok := c.replace(v); ok {
+				fields[key] = value
+				changed = true
+			}
+		}
+
+		if !changed {
+			out = append(out, m)
+			continue
+		}
+		replaced, err := metric.New(m.Name(), tags, fields, m.Time(), m.Type())
+		if err != nil {
+			r.Log.Errorf("could not replace the values of %s: %s", m.Name(), err)
+			out = append(out, m)
+			continue
+		}
+		out = append(out, replaced)
+	}
+	return out
+}
+
+func init() {
+	processors.Add("regex", func() telegraf.Processor {
+		return &Regex{}
+	})
+}
@@ -0,0 +1,69 @@
+package regex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2018, time.May, 1, 12, 0, 0, 0, time.UTC)
+
+func TestRegexTags(t *testing.T) {
+	r := &Regex{Tags: []*Replacement{
+		{Key: "serial", Pattern: "^([0-9A-F]+)-[0-9]+$", Replacement: "${1}"},
+		{Key: "host", Pattern: "^([a-z]+)-.*$", Replacement: "${1}", ResultKey: "site"},
+	}}
+	require.NoError(t, r.Init())
+
+	m, _ := metric.New("inverter",
+		map[string]string{"serial": "7E1A2B-01", "host": "lyon-inverter1"},
+		map[string]interface{}{"power": 1.5}, now, telegraf.Gauge)
+	out := r.Apply(m)
+	require.Len(t, out, 1)
+	assert.Equal(t, map[string]string{
+		"serial": "7E1A2B",
+		"host":   "lyon-inverter1",
+		"site":   "lyon",
+	}, out[0].Tags())
+	assert.Equal(t, map[string]interface{}{"power": 1.5}, out[0].Fields())
+	assert.Equal(t, now.UnixNano(), out[0].Time().UnixNano())
+	assert.Equal(t, telegraf.Gauge, out[0].Type())
+
+	// the values not matching are kept.
+	m, _ = metric.New("inverter", map[string]string{"serial": "XYZ", "host": "1"},
+		map[string]interface{}{"power": 1.5}, now)
+	assert.Equal(t, []telegraf.Metric{m}, r.Apply(m))
+}
+
+func TestRegexFields(t *testing.T) {
+	r := &Regex{Fields: []*Replacement{
+		{Key: "request", Pattern: `^/api(?P<method>/[\w/]+)\S*`, Replacement: "${method}", ResultKey: "method"},
+		{Key: "code", Pattern: "^(\\d)\\d\\d$", Replacement: "${1}xx"},
+	}}
+	require.NoError(t, r.Init())
+
+	m, _ := metric.New("access", nil, map[string]interface{}{
+		"request": "/api/search/?q=x",
+		"code":    int64(200),
+	}, now)
+	out := r.Apply(m)
+	require.Len(t, out, 1)
+	// only the string fields are replaced.
+	assert.Equal(t, map[string]interface{}{
+		"request": "/api/search/?q=x",
+		"method":  "/search/",
+		"code":    int64(200),
+	}, out[0].Fields())
+}
+
+func TestInit(t *testing.T) {
+	r := &Regex{Tags: []*Replacement{{Pattern: "a"}}}
+	assert.Error(t, r.Init())
+	r = &Regex{Fields: []*Replacement{{Key: "a", Pattern: "("}}}
+	assert.Error(t, r.Init())
+}
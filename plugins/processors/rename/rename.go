@@ -0,0 +1,183 @@
+package rename
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Names of the measurements, tags and fields, renamed to the values.
+  # [processors.rename.measurements]
+  #   solaredge_inverter = "inverter"
+  # [processors.rename.tags]
+  #   serialNumber = "serial"
+  # [processors.rename.fields]
+  #   lifetimeEnergy = "energy_total"
+
+  ## Regular expressions renaming the names matching them, in order after
+  ## the names above. The scope is "measurement", "tag" or "field", the
+  ## replacement can refer to the groups of the pattern, ie ${1}, and the
+  ## result of the replacement is converted to lower case with lowercase.
+  [[processors.rename.regex]]
+    scope = "field"
+    pattern = "([a-z0-9])([A-Z])"
+    replacement = "${1}_${2}"
+    lowercase = true
+`
+
+// Rename renames the measurements, tags and fields of the metrics, with
+// the literal renames then the regular expressions.
+type Rename struct {
+	Measurements map[string]string
+	Tags         map[string]string
+	Fields       map[string]string
+	Regex        []*Regex
+
+	Log telegraf.Logger `toml:"-"`
+}
+
+// Regex renames the names of its scope matching Pattern.
+type Regex struct {
+	Scope       string
+	Pattern     string
+	Replacement string
+	Lowercase   bool
+
+	re *regexp.Regexp
+}
+
+func (r *Rename) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *Rename) Description() string {
+	return "Rename measurements, tags and fields with names or regular expressions"
+}
+
+// Init compiles the regular expressions.
+func (r *Rename) Init() error {
+	for _, re := range r.Regex {
+		switch re.Scope {
+		case "measurement", "tag", "field":
+		default:
+			return fmt.Errorf("invalid scope %q of %q", re.Scope, re.Pattern)
+		}
+		var err error
+		re.re, err = regexp.Compile(re.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %s", re.Pattern, err)
+		}
+	}
+	return nil
+}
+
+// rename returns the new name of the name of the scope, the name itself if
+// the renames result in an empty name.
+func (r *Rename) rename(scope string, names map[string]string, from string) string {
+	name := from
+	if to, ok := names[name]; ok {
+		name = to
+	}
+	for _, re := range r.Regex {
+		if re.Scope != scope || !re.re.MatchString(name) {
+			continue
+		}
+		name = re.re.ReplaceAllString(name, re.Replacement)
+		if re.Lowercase {
+			name = strings.ToLower(name)
+		}
+	}
+	if name == "" {
+		return from
+	}
+	return name
+}
+
+// Apply renames the metrics, a renamed tag or field replacing the one of
+// the same name, and the last one in alphabetical order replacing the other
+// ones renamed to the same name.
+func (r *Rename) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := make([]telegraf.Metric, 0, len(in))
+	for _, m := range in {
+		changed := false
+		name := r.rename("measurement", r.Measurements, m.Name())
+		if name != m.Name() {
+			changed = true
+		}
+
+		tags := make(map[string]string)
+		renamedTags := make(map[string]string)
+		mTags := m.Tags()
+		for _, k := range sortedKeys(mTags) {
+			v := mTags[k]
+			if to := r.rename("tag", r.Tags, k); to != k {
+				renamedTags[to] = v
+				changed = true
+			} else {
+				tags[k] = v
+			}
+		}
+		for k, v := range renamedTags {
+			tags[k] = v
+		}
+
+		fields := make(map[string]interface{})
+		renamedFields := make(map[string]interface{})
+		mFields := m.Fields()
+		for _, k := range sortedFieldKeys(mFields) {
+			v := mFields[k]
+			if to := r.rename("field", r.Fields, k); to != k {
+				renamedFields[to] = v
+				changed = true
+			} else {
+				fields[k] = v
+			}
+		}
+		for k, v := range renamedFields {
+			fields[k] = v
+		}
+
+		if !changed {
+			out = append(out, m)
+			continue
+		}
+		renamed, err := metric.New(name, tags, fields, m.Time(), m.Type())
+		if err != nil {
+			r.Log.Errorf("could not rename %s: %s", m.Name(), err)
+			out = append(out, m)
+			continue
+		}
+		out = append(out, renamed)
+	}
+	return out
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedFieldKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func init() {
+	processors.Add("rename", func() telegraf.Processor {
+		return &Rename{}
+	})
+}
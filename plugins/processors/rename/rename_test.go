@@ -0,0 +1,69 @@
+package rename
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2018, time.May, 1, 12, 0, 0, 0, time.UTC)
+
+func TestRename(t *testing.T) {
+	r := &Rename{
+		Measurements: map[string]string{"solaredge_inverter": "inverter"},
+		Tags:         map[string]string{"serialNumber": "serial"},
+		Fields:       map[string]string{"lifetimeEnergy": "energy_total"},
+		Regex: []*Regex{
+			{Scope: "field", Pattern: "([a-z0-9])([A-Z])", Replacement: "${1}_${2}", Lowercase: true},
+			{Scope: "tag", Pattern: "^site$", Replacement: ""},
+		},
+	}
+	require.NoError(t, r.Init())
+
+	m, _ := metric.New("solaredge_inverter",
+		map[string]string{"serialNumber": "123", "site": "home"},
+		map[string]interface{}{
+			"lifetimeEnergy": int64(1000),
+			"acPowerL1":      230.1,
+			"status":         "ok",
+		}, now, telegraf.Gauge)
+	out := r.Apply(m)
+	require.Len(t, out, 1)
+	assert.Equal(t, "inverter", out[0].Name())
+	assert.Equal(t, map[string]string{"serial": "123", "site": "home"}, out[0].Tags())
+	assert.Equal(t, map[string]interface{}{
+		"energy_total": int64(1000),
+		"ac_power_l1":  230.1,
+		"status":       "ok",
+	}, out[0].Fields())
+	assert.Equal(t, now.UnixNano(), out[0].Time().UnixNano())
+	assert.Equal(t, telegraf.Gauge, out[0].Type())
+
+	// the metrics not renamed are passed on as is.
+	m, _ = metric.New("cpu", nil, map[string]interface{}{"usage": 1.0}, now)
+	assert.Equal(t, []telegraf.Metric{m}, r.Apply(m))
+}
+
+// Verify that a renamed field replaces the field of the same name.
+func TestRenameReplace(t *testing.T) {
+	r := &Rename{Fields: map[string]string{"new": "value"}}
+	require.NoError(t, r.Init())
+
+	m, _ := metric.New("m", nil,
+		map[string]interface{}{"new": int64(2), "value": int64(1)}, now)
+	out := r.Apply(m)
+	require.Len(t, out, 1)
+	assert.Equal(t, map[string]interface{}{"value": int64(2)}, out[0].Fields())
+}
+
+func TestInit(t *testing.T) {
+	r := &Rename{Regex: []*Regex{{Scope: "metric", Pattern: "a"}}}
+	assert.Error(t, r.Init())
+	r = &Rename{Regex: []*Regex{{Scope: "field", Pattern: "("}}}
+	assert.Error(t, r.Init())
+}
@@ -0,0 +1,111 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Tag set to the result of the template.
+  tag = "site_serial"
+
+  ## Set the name of the metrics instead of a tag.
+  # measurement = false
+
+  ## Go template of the value, the metric is the data of the template:
+  ## {{.Name}} is its name, {{.Tag "host"}} the value of its host tag and
+  ## {{.Field "power"}} of its power field, {{.Time}} its timestamp.
+  template = '{{.Tag "site_id"}}-{{.Tag "serial_number"}}'
+`
+
+// Template sets a tag, or the name of the metrics, to the result of a
+// template of the metrics.
+type Template struct {
+	Tag         string
+	Measurement bool
+	Template    string
+
+	Log telegraf.Logger `toml:"-"`
+
+	tmpl *template.Template
+}
+
+func (t *Template) SampleConfig() string {
+	return sampleConfig
+}
+
+func (t *Template) Description() string {
+	return "Set a tag or the name of the metrics with a Go template"
+}
+
+// Init parses the template.
+func (t *Template) Init() error {
+	if (t.Tag == "") == !t.Measurement {
+		return fmt.Errorf("one of tag or measurement is required")
+	}
+	var err error
+	t.tmpl, err = template.New("template").Option("missingkey=zero").Parse(t.Template)
+	if err != nil {
+		return fmt.Errorf("invalid template: %s", err)
+	}
+	return nil
+}
+
+// Apply sets the tag or the name of the metrics, the metrics with an empty
+// result being passed on unchanged.
+func (t *Template) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := make([]telegraf.Metric, 0, len(in))
+	for _, m := range in {
+		var b bytes.Buffer
+		if err := t.tmpl.Execute(&b, templateMetric{m}); err != nil {
+			t.Log.Errorf("could not execute the template on %s: %s", m.Name(), err)
+			out = append(out, m)
+			continue
+		}
+		if b.Len() == 0 {
+			out = append(out, m)
+			continue
+		}
+
+		name, tags := m.Name(), m.Tags()
+		if t.Measurement {
+			name = b.String()
+		} else {
+			tags[t.Tag] = b.String()
+		}
+		templated, err := metric.New(name, tags, m.Fields(), m.Time(), m.Type())
+		if err != nil {
+			t.Log.Errorf("could not set the template on %s: %s", m.Name(), err)
+			out = append(out, m)
+			continue
+		}
+		out = append(out, templated)
+	}
+	return out
+}
+
+// templateMetric is the data of the templates.
+type templateMetric struct {
+	telegraf.Metric
+}
+
+// Tag returns the value of the tag, empty if the metric does not have it.
+func (m templateMetric) Tag(key string) string {
+	return m.Tags()[key]
+}
+
+// Field returns the value of the field, nil if the metric does not have it.
+func (m templateMetric) Field(key string) interface{} {
+	return m.Fields()[key]
+}
+
+func init() {
+	processors.Add("template", func() telegraf.Processor {
+		return &Template{}
+	})
+}
@@ -0,0 +1,79 @@
+package template
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2018, time.May, 1, 12, 0, 0, 0, time.UTC)
+
+func testMetric(tags map[string]string) telegraf.Metric {
+	m, _ := metric.New("inverter", tags,
+		map[string]interface{}{"power": 1.5, "mode": "mppt"}, now, telegraf.Gauge)
+	return m
+}
+
+func TestTemplateTag(t *testing.T) {
+	tmpl := &Template{
+		Tag:      "site_serial",
+		Template: `{{.Tag "site_id"}}-{{.Tag "serial_number"}}`,
+	}
+	require.NoError(t, tmpl.Init())
+
+	out := tmpl.Apply(testMetric(map[string]string{"site_id": "123", "serial_number": "7E1A"}))
+	require.Len(t, out, 1)
+	assert.Equal(t, map[string]string{
+		"site_id":       "123",
+		"serial_number": "7E1A",
+		"site_serial":   "123-7E1A",
+	}, out[0].Tags())
+	assert.Equal(t, map[string]interface{}{"power": 1.5, "mode": "mppt"}, out[0].Fields())
+	assert.Equal(t, now.UnixNano(), out[0].Time().UnixNano())
+	assert.Equal(t, telegraf.Gauge, out[0].Type())
+}
+
+func TestTemplateMeasurement(t *testing.T) {
+	tmpl := &Template{
+		Measurement: true,
+		Template:    `{{.Name}}_{{.Field "mode"}}`,
+	}
+	require.NoError(t, tmpl.Init())
+
+	out := tmpl.Apply(testMetric(nil))
+	require.Len(t, out, 1)
+	assert.Equal(t, "inverter_mppt", out[0].Name())
+}
+
+// Verify that the metrics are passed on unchanged when the template fails
+// or results in an empty value.
+func TestTemplateUnchanged(t *testing.T) {
+	tmpl := &Template{
+		Tag:      "site",
+		Template: `{{.Tag "site_id"}}{{if .Tag "fail"}}{{index .Fields 1}}{{end}}`,
+		Log:      models.NewLogger("processors", "template", ""),
+	}
+	require.NoError(t, tmpl.Init())
+
+	in := []telegraf.Metric{
+		testMetric(nil),
+		testMetric(map[string]string{"fail": "yes"}),
+	}
+	assert.Equal(t, in, tmpl.Apply(in...))
+}
+
+func TestInit(t *testing.T) {
+	for _, tmpl := range []*Template{
+		{Template: "{{.Name}}"},
+		{Tag: "a", Measurement: true, Template: "{{.Name}}"},
+		{Tag: "a", Template: "{{.Name"},
+	} {
+		assert.Error(t, tmpl.Init())
+	}
+}
@@ -0,0 +1,69 @@
+package unpivot
+
+import (
+	"sort"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Tag the name of the field is moved to.
+  tag_key = "name"
+  ## Field the value of the field is moved to.
+  value_key = "value"
+`
+
+// Unpivot splits the metrics into a metric per field, the name of the field
+// being the value of the tag TagKey and its value the field ValueKey.
+type Unpivot struct {
+	TagKey   string `toml:"tag_key"`
+	ValueKey string `toml:"value_key"`
+
+	Log telegraf.Logger `toml:"-"`
+}
+
+func (u *Unpivot) SampleConfig() string {
+	return sampleConfig
+}
+
+func (u *Unpivot) Description() string {
+	return "Rotate multi field metric into several single field metrics"
+}
+
+// Apply returns the metrics of the fields of the metrics, ordered by name
+// of the fields.
+func (u *Unpivot) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := make([]telegraf.Metric, 0, len(in))
+	for _, m := range in {
+		fields := m.Fields()
+		keys := make([]string, 0, len(fields))
+		for k := range fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			tags := m.Tags()
+			tags[u.TagKey] = k
+			unpivoted, err := metric.New(m.Name(), tags,
+				map[string]interface{}{u.ValueKey: fields[k]}, m.Time(), m.Type())
+			if err != nil {
+				u.Log.Errorf("could not unpivot %s: %s", m.Name(), err)
+				continue
+			}
+			out = append(out, unpivoted)
+		}
+	}
+	return out
+}
+
+func init() {
+	processors.Add("unpivot", func() telegraf.Processor {
+		return &Unpivot{
+			TagKey:   "name",
+			ValueKey: "value",
+		}
+	})
+}
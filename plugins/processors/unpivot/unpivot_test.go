@@ -0,0 +1,30 @@
+package unpivot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2018, time.May, 1, 12, 0, 0, 0, time.UTC)
+
+func TestUnpivot(t *testing.T) {
+	u := &Unpivot{TagKey: "name", ValueKey: "value"}
+
+	m, _ := metric.New("voltage", map[string]string{"host": "a"},
+		map[string]interface{}{"L2": 229.8, "L1": 230.1}, now, telegraf.Gauge)
+	out := u.Apply(m)
+	require.Len(t, out, 2)
+	for i, phase := range []string{"L1", "L2"} {
+		assert.Equal(t, "voltage", out[i].Name())
+		assert.Equal(t, map[string]string{"host": "a", "name": phase}, out[i].Tags())
+		assert.Equal(t, map[string]interface{}{"value": m.Fields()[phase]}, out[i].Fields())
+		assert.Equal(t, now.UnixNano(), out[i].Time().UnixNano())
+		assert.Equal(t, telegraf.Gauge, out[i].Type())
+	}
+}
@@ -0,0 +1,42 @@
+package env
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+type Env struct {
+	Prefix string `toml:"prefix"`
+}
+
+var sampleConfig = `
+  ## Prefix added to the keys to get the name of the environment variable,
+  ## ie with prefix "TELEGRAF_" the key "api_key" reads TELEGRAF_api_key.
+  # prefix = ""
+`
+
+func (e *Env) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Env) Description() string {
+	return "Read secrets from environment variables"
+}
+
+func (e *Env) Get(key string) (string, error) {
+	name := e.Prefix + key
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+func init() {
+	secretstores.Add("env", func() telegraf.SecretStore {
+		return &Env{}
+	})
+}
@@ -0,0 +1,22 @@
+package env
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet(t *testing.T) {
+	os.Setenv("TELEGRAF_TEST_SECRET", "s3cr3t")
+	defer os.Unsetenv("TELEGRAF_TEST_SECRET")
+
+	e := &Env{Prefix: "TELEGRAF_"}
+	value, err := e.Get("TEST_SECRET")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t", value)
+
+	_, err = e.Get("MISSING_SECRET")
+	assert.Error(t, err)
+}
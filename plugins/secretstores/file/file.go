@@ -0,0 +1,51 @@
+package file
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+type File struct {
+	Directory string `toml:"directory"`
+}
+
+var sampleConfig = `
+  ## Directory containing one file per secret, named after its key, as used
+  ## by Docker and Kubernetes secrets.
+  directory = "/run/secrets"
+`
+
+func (f *File) SampleConfig() string {
+	return sampleConfig
+}
+
+func (f *File) Description() string {
+	return "Read secrets from the files of a directory"
+}
+
+func (f *File) Get(key string) (string, error) {
+	if f.Directory == "" {
+		return "", fmt.Errorf("directory must be configured")
+	}
+	if key == "" || key != filepath.Base(key) || key == ".." {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+
+	contents, err := ioutil.ReadFile(filepath.Join(f.Directory, key))
+	if err != nil {
+		return "", err
+	}
+	// Files written by editors usually end with a newline.
+	return strings.TrimRight(string(contents), "\r\n"), nil
+}
+
+func init() {
+	secretstores.Add("file", func() telegraf.SecretStore {
+		return &File{}
+	})
+}
@@ -0,0 +1,28 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "api_key"), []byte("s3cr3t\n"), 0600))
+
+	f := &File{Directory: dir}
+	value, err := f.Get("api_key")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t", value)
+
+	_, err = f.Get("missing")
+	assert.Error(t, err)
+	_, err = f.Get("../api_key")
+	assert.Error(t, err)
+}
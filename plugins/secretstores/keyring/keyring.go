@@ -0,0 +1,81 @@
+package keyring
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+type Keyring struct {
+	Service string            `toml:"service"`
+	Timeout internal.Duration `toml:"timeout"`
+}
+
+var sampleConfig = `
+  ## Service the secrets are stored under. Secrets are added with
+  ##   secret-tool store --label=telegraf service telegraf key <key>
+  ## on Linux and with
+  ##   security add-generic-password -s telegraf -a <key> -w
+  ## on macOS.
+  service = "telegraf"
+
+  ## Amount of time allowed for the keyring to answer.
+  # timeout = "5s"
+`
+
+func (k *Keyring) SampleConfig() string {
+	return sampleConfig
+}
+
+func (k *Keyring) Description() string {
+	return "Read secrets from the keyring of the operating system"
+}
+
+// command returns the command printing a secret of the keyring.
+func command(goos, service, key string) (*exec.Cmd, error) {
+	switch goos {
+	case "linux", "freebsd", "openbsd":
+		// Secret Service API, ie GNOME Keyring or KWallet.
+		return exec.Command("secret-tool", "lookup", "service", service, "key", key), nil
+	case "darwin":
+		return exec.Command("security", "find-generic-password",
+			"-s", service, "-a", key, "-w"), nil
+	}
+	return nil, fmt.Errorf("keyring is not supported on %s", goos)
+}
+
+func (k *Keyring) Get(key string) (string, error) {
+	cmd, err := command(runtime.GOOS, k.Service, key)
+	if err != nil {
+		return "", err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := internal.RunTimeout(cmd, k.Timeout.Duration); err != nil {
+		return "", fmt.Errorf("unable to read secret %s: %s %s",
+			key, err, strings.TrimSpace(stderr.String()))
+	}
+	if stdout.Len() == 0 {
+		// secret-tool exits successfully when the secret does not exist.
+		return "", fmt.Errorf("secret %s not found", key)
+	}
+	return strings.TrimRight(stdout.String(), "\n"), nil
+}
+
+func init() {
+	secretstores.Add("keyring", func() telegraf.SecretStore {
+		return &Keyring{
+			Service: "telegraf",
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
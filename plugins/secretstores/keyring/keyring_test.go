@@ -0,0 +1,21 @@
+package keyring
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommand(t *testing.T) {
+	cmd, err := command("linux", "telegraf", "api_key")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"secret-tool", "lookup", "service", "telegraf", "key", "api_key"}, cmd.Args)
+
+	cmd, err = command("darwin", "telegraf", "api_key")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"security", "find-generic-password", "-s", "telegraf", "-a", "api_key", "-w"}, cmd.Args)
+
+	_, err = command("windows", "telegraf", "api_key")
+	assert.Error(t, err)
+}
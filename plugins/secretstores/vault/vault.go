@@ -0,0 +1,166 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+type Vault struct {
+	Address   string            `toml:"address"`
+	Token     string            `toml:"token"`
+	Mount     string            `toml:"mount"`
+	Path      string            `toml:"path"`
+	KVVersion int               `toml:"kv_version"`
+	Timeout   internal.Duration `toml:"timeout"`
+
+	// Path to CA file
+	SSLCA string `toml:"ssl_ca"`
+	// Path to host cert file
+	SSLCert string `toml:"ssl_cert"`
+	// Path to cert key file
+	SSLKey string `toml:"ssl_key"`
+	// Use SSL but skip chain & host verification
+	InsecureSkipVerify bool
+
+	client  *http.Client
+	secrets map[string]interface{}
+}
+
+var sampleConfig = `
+  ## Address of the Vault server, defaults to the VAULT_ADDR environment
+  ## variable.
+  # address = "https://127.0.0.1:8200"
+
+  ## Token used to authenticate, defaults to the VAULT_TOKEN environment
+  ## variable.
+  # token = ""
+
+  ## Mount point and version of the key/value secrets engine and path of the
+  ## secret, the keys are the names of the fields of the secret.
+  # mount = "secret"
+  # kv_version = 2
+  path = "telegraf"
+
+  ## Amount of time allowed to complete the HTTP request
+  # timeout = "5s"
+
+  ## Optional SSL Config
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  ## Use SSL but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+func (v *Vault) SampleConfig() string {
+	return sampleConfig
+}
+
+func (v *Vault) Description() string {
+	return "Read secrets from the key/value secrets engine of HashiCorp Vault"
+}
+
+func (v *Vault) Get(key string) (string, error) {
+	if v.secrets == nil {
+		secrets, err := v.read()
+		if err != nil {
+			return "", fmt.Errorf("unable to read secret %s from vault: %s", v.Path, err)
+		}
+		v.secrets = secrets
+	}
+
+	value, ok := v.secrets[key]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no field %s", v.Path, key)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprintf("%v", value), nil
+}
+
+// read returns the fields of the secret.
+func (v *Vault) read() (map[string]interface{}, error) {
+	address := v.Address
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	if address == "" {
+		address = "https://127.0.0.1:8200"
+	}
+	token := v.Token
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+
+	if v.client == nil {
+		tlsCfg, err := internal.GetTLSConfig(
+			v.SSLCert, v.SSLKey, v.SSLCA, v.InsecureSkipVerify)
+		if err != nil {
+			return nil, err
+		}
+		v.client = &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsCfg,
+			},
+			Timeout: v.Timeout.Duration,
+		}
+	}
+
+	path := strings.Trim(v.Mount, "/") + "/" + strings.Trim(v.Path, "/")
+	if v.KVVersion == 2 {
+		path = strings.Trim(v.Mount, "/") + "/data/" + strings.Trim(v.Path, "/")
+	}
+	req, err := http.NewRequest("GET", strings.TrimRight(address, "/")+"/v1/"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("received status code %d (%s), expected %d (%s)",
+			resp.StatusCode, http.StatusText(resp.StatusCode),
+			http.StatusOK, http.StatusText(http.StatusOK))
+	}
+
+	var secret struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return nil, err
+	}
+	if v.KVVersion != 2 {
+		return secret.Data, nil
+	}
+	// Version 2 nests the fields with the metadata of the secret.
+	data, ok := secret.Data["data"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected response format")
+	}
+	return data, nil
+}
+
+func init() {
+	secretstores.Add("vault", func() telegraf.SecretStore {
+		return &Vault{
+			Mount:     "secret",
+			KVVersion: 2,
+			Timeout:   internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,47 @@
+package vault
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/telegraf":
+			w.Write([]byte(`{"data": {"data": {"api_key": "s3cr3t", "port": 8086}, "metadata": {"version": 1}}}`))
+		case "/v1/kv/telegraf":
+			w.Write([]byte(`{"data": {"api_key": "0ld"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	v := &Vault{Address: ts.URL, Token: "token", Mount: "secret", Path: "telegraf", KVVersion: 2}
+	value, err := v.Get("api_key")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t", value)
+	value, err = v.Get("port")
+	require.NoError(t, err)
+	assert.Equal(t, "8086", value)
+	_, err = v.Get("missing")
+	assert.Error(t, err)
+
+	v = &Vault{Address: ts.URL, Token: "token", Mount: "kv", Path: "telegraf", KVVersion: 1}
+	value, err = v.Get("api_key")
+	require.NoError(t, err)
+	assert.Equal(t, "0ld", value)
+
+	v = &Vault{Address: ts.URL, Token: "wrong", Mount: "secret", Path: "telegraf", KVVersion: 2}
+	_, err = v.Get("api_key")
+	assert.Error(t, err)
+}
@@ -0,0 +1,90 @@
+package cloudevents
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/satori/go.uuid"
+)
+
+const (
+	DefaultSource = "telegraf"
+	DefaultType   = "com.influxdata.telegraf.metric"
+)
+
+// event is a CloudEvents 1.0 event in the structured JSON format.
+type event struct {
+	SpecVersion     string     `json:"specversion"`
+	ID              string     `json:"id"`
+	Source          string     `json:"source"`
+	Type            string     `json:"type"`
+	Time            string     `json:"time"`
+	DataContentType string     `json:"datacontenttype"`
+	Data            metricData `json:"data"`
+}
+
+type metricData struct {
+	Name   string                 `json:"name"`
+	Tags   map[string]string      `json:"tags"`
+	Fields map[string]interface{} `json:"fields"`
+}
+
+// CloudEventsSerializer wraps each metric in a CloudEvents 1.0 event, the
+// metric being the data of the event and its timestamp the time of the
+// event.
+type CloudEventsSerializer struct {
+	// Source and Type are the source and type attributes of the events,
+	// DefaultSource and DefaultType if empty.
+	Source string
+	Type   string
+}
+
+func (s *CloudEventsSerializer) event(metric telegraf.Metric) *event {
+	source, typ := s.Source, s.Type
+	if source == "" {
+		source = DefaultSource
+	}
+	if typ == "" {
+		typ = DefaultType
+	}
+	return &event{
+		SpecVersion:     "1.0",
+		ID:              uuid.NewV4().String(),
+		Source:          source,
+		Type:            typ,
+		Time:            metric.Time().UTC().Format(time.RFC3339Nano),
+		DataContentType: "application/json",
+		Data: metricData{
+			Name:   metric.Name(),
+			Tags:   metric.Tags(),
+			Fields: metric.Fields(),
+		},
+	}
+}
+
+func (s *CloudEventsSerializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	serialized, err := json.Marshal(s.event(metric))
+	if err != nil {
+		return []byte{}, err
+	}
+	return append(serialized, '\n'), nil
+}
+
+// CloudEventsBatchSerializer serializes the batches of metrics in the batched
+// JSON format of CloudEvents, an array of events.
+type CloudEventsBatchSerializer struct {
+	CloudEventsSerializer
+}
+
+func (s *CloudEventsBatchSerializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	events := make([]*event, 0, len(metrics))
+	for _, m := range metrics {
+		events = append(events, s.event(m))
+	}
+	serialized, err := json.Marshal(events)
+	if err != nil {
+		return []byte{}, err
+	}
+	return append(serialized, '\n'), nil
+}
@@ -0,0 +1,63 @@
+package cloudevents
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+func testMetric(t *testing.T, name string) telegraf.Metric {
+	m, err := metric.New(name,
+		map[string]string{"cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": float64(91.5)},
+		time.Date(2017, 6, 1, 12, 0, 0, 500, time.UTC))
+	require.NoError(t, err)
+	return m
+}
+
+func TestSerializeMetric(t *testing.T) {
+	s := CloudEventsSerializer{}
+	buf, err := s.Serialize(testMetric(t, "cpu"))
+	require.NoError(t, err)
+	assert.Equal(t, byte('\n'), buf[len(buf)-1])
+
+	var e map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf, &e))
+	assert.Len(t, e["id"], 36)
+	delete(e, "id")
+	assert.Equal(t, map[string]interface{}{
+		"specversion":     "1.0",
+		"source":          DefaultSource,
+		"type":            DefaultType,
+		"time":            "2017-06-01T12:00:00.0000005Z",
+		"datacontenttype": "application/json",
+		"data": map[string]interface{}{
+			"name":   "cpu",
+			"tags":   map[string]interface{}{"cpu": "cpu0"},
+			"fields": map[string]interface{}{"usage_idle": 91.5},
+		},
+	}, e)
+}
+
+func TestSerializeBatch(t *testing.T) {
+	s := CloudEventsBatchSerializer{CloudEventsSerializer{
+		Source: "/plant/line-1",
+		Type:   "com.example.telemetry",
+	}}
+	buf, err := s.SerializeBatch([]telegraf.Metric{testMetric(t, "cpu"), testMetric(t, "mem")})
+	require.NoError(t, err)
+
+	var events []map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf, &events))
+	require.Len(t, events, 2)
+	assert.Equal(t, "/plant/line-1", events[0]["source"])
+	assert.Equal(t, "com.example.telemetry", events[1]["type"])
+	assert.Equal(t, "mem", events[1]["data"].(map[string]interface{})["name"])
+	assert.NotEqual(t, events[0]["id"], events[1]["id"])
+}
@@ -0,0 +1,146 @@
+package csv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/influxdata/telegraf"
+)
+
+// CSVSerializer serializes each metric as a CSV row. The columns are
+// "timestamp", "measurement", "tag.<key>" and "field.<key>", in the order of
+// Columns, or the timestamp, the measurement, the tags and the fields of each
+// metric in the order of their keys if Columns is empty.
+type CSVSerializer struct {
+	Columns []string
+	// Header writes the header row before the first row, and before the
+	// rows whose columns differ from the previous ones when Columns is
+	// empty.
+	Header bool
+	// TimestampFormat is "unix", the default, "unix_ms", "unix_us",
+	// "unix_ns" or a Go reference time layout.
+	TimestampFormat string
+	// Separator is the field delimiter, "," if empty.
+	Separator string
+
+	// columns are the columns of the last header row.
+	columns []string
+}
+
+// Check checks the columns and the separator.
+func (s *CSVSerializer) Check() error {
+	for _, c := range s.Columns {
+		switch {
+		case c == "timestamp", c == "measurement":
+		case strings.HasPrefix(c, "tag.") && len(c) > 4:
+		case strings.HasPrefix(c, "field.") && len(c) > 6:
+		default:
+			return fmt.Errorf("invalid csv column %q", c)
+		}
+	}
+	if s.Separator != "" && utf8.RuneCountInString(s.Separator) != 1 {
+		return fmt.Errorf("csv separator %q is not a single character", s.Separator)
+	}
+	return nil
+}
+
+func (s *CSVSerializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	columns := s.Columns
+	if len(columns) == 0 {
+		columns = metricColumns(metric)
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if s.Separator != "" {
+		w.Comma, _ = utf8.DecodeRuneInString(s.Separator)
+	}
+
+	if s.Header && !equal(columns, s.columns) {
+		if err := w.Write(columns); err != nil {
+			return []byte{}, err
+		}
+		s.columns = columns
+	}
+
+	tags, fields := metric.Tags(), metric.Fields()
+	row := make([]string, 0, len(columns))
+	for _, c := range columns {
+		switch {
+		case c == "timestamp":
+			row = append(row, s.timestamp(metric.Time()))
+		case c == "measurement":
+			row = append(row, metric.Name())
+		case strings.HasPrefix(c, "tag."):
+			row = append(row, tags[c[4:]])
+		case strings.HasPrefix(c, "field."):
+			row = append(row, format(fields[c[6:]]))
+		default:
+			row = append(row, "")
+		}
+	}
+	if err := w.Write(row); err != nil {
+		return []byte{}, err
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return []byte{}, err
+	}
+	return buf.Bytes(), nil
+}
+
+// metricColumns returns the columns of the metric.
+func metricColumns(metric telegraf.Metric) []string {
+	var tags, fields []string
+	for k := range metric.Tags() {
+		tags = append(tags, "tag."+k)
+	}
+	for k := range metric.Fields() {
+		fields = append(fields, "field."+k)
+	}
+	sort.Strings(tags)
+	sort.Strings(fields)
+	return append(append([]string{"timestamp", "measurement"}, tags...), fields...)
+}
+
+func (s *CSVSerializer) timestamp(t time.Time) string {
+	switch s.TimestampFormat {
+	case "", "unix":
+		return strconv.FormatInt(t.Unix(), 10)
+	case "unix_ms":
+		return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)
+	case "unix_us":
+		return strconv.FormatInt(t.UnixNano()/int64(time.Microsecond), 10)
+	case "unix_ns":
+		return strconv.FormatInt(t.UnixNano(), 10)
+	}
+	return t.UTC().Format(s.TimestampFormat)
+}
+
+func format(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
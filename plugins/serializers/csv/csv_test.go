@@ -0,0 +1,67 @@
+package csv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+func testMetric(t *testing.T, tags map[string]string, fields map[string]interface{}) telegraf.Metric {
+	m, err := metric.New("cpu", tags, fields, time.Date(2017, 6, 1, 12, 0, 0, 500000000, time.UTC))
+	require.NoError(t, err)
+	return m
+}
+
+func TestSerializeMetric(t *testing.T) {
+	s := CSVSerializer{Header: true}
+	m := testMetric(t,
+		map[string]string{"host": "server-1", "cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": 91.5, "state": "idle, mostly"})
+
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+	assert.Equal(t, "timestamp,measurement,tag.cpu,tag.host,field.state,field.usage_idle\n"+
+		"1496318400,cpu,cpu0,server-1,\"idle, mostly\",91.5\n", string(buf))
+
+	// The header is written once for the same columns.
+	buf, err = s.Serialize(m)
+	require.NoError(t, err)
+	assert.Equal(t, "1496318400,cpu,cpu0,server-1,\"idle, mostly\",91.5\n", string(buf))
+
+	buf, err = s.Serialize(testMetric(t, nil, map[string]interface{}{"usage_idle": 90.0}))
+	require.NoError(t, err)
+	assert.Equal(t, "timestamp,measurement,field.usage_idle\n1496318400,cpu,90\n", string(buf))
+}
+
+func TestSerializeColumns(t *testing.T) {
+	s := CSVSerializer{
+		Columns:         []string{"tag.host", "field.usage_idle", "field.missing", "timestamp"},
+		TimestampFormat: "2006-01-02T15:04:05.000Z07:00",
+		Separator:       ";",
+	}
+	require.NoError(t, s.Check())
+	buf, err := s.Serialize(testMetric(t,
+		map[string]string{"host": "server-1", "cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": int64(91), "usage_user": 2.5}))
+	require.NoError(t, err)
+	assert.Equal(t, "server-1;91;;2017-06-01T12:00:00.500Z\n", string(buf))
+
+	s.TimestampFormat = "unix_ms"
+	s.Columns = []string{"timestamp"}
+	buf, err = s.Serialize(testMetric(t, nil, map[string]interface{}{"a": 1.0}))
+	require.NoError(t, err)
+	assert.Equal(t, "1496318400500\n", string(buf))
+
+	for _, s := range []CSVSerializer{
+		{Columns: []string{"host"}},
+		{Columns: []string{"tag."}},
+		{Separator: ";;"},
+	} {
+		assert.Error(t, s.Check())
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
 )
 
 const DEFAULT_TEMPLATE = "host.tags.measurement.field"
@@ -13,11 +14,65 @@ const DEFAULT_TEMPLATE = "host.tags.measurement.field"
 var (
 	fieldDeleter   = strings.NewReplacer(".FIELDNAME", "", "FIELDNAME.", "")
 	sanitizedChars = strings.NewReplacer("/", "-", "@", "-", "*", "-", " ", "_", "..", ".", `\`, "", ")", "_", "(", "_")
+	// The Graphite tag names and values can not hold these characters.
+	tagNameChars  = strings.NewReplacer(";", "_", "!", "_", "^", "_", "=", "_", "~", "_", " ", "_")
+	tagValueChars = strings.NewReplacer(";", "_", "~", "_", " ", "_")
 )
 
 type GraphiteSerializer struct {
 	Prefix   string
 	Template string
+
+	// TagSupport serializes the tags as Graphite 1.1 tags, ie
+	// "cpu.usage_idle;host=tars", instead of in the bucket, the templates
+	// being ignored.
+	TagSupport bool
+
+	templates []measurementTemplate
+}
+
+// measurementTemplate is the template of the measurements matching filter.
+type measurementTemplate struct {
+	filter   filter.Filter
+	template string
+}
+
+// NewGraphiteSerializer returns a serializer formatting the buckets with the
+// first of the templates matching the measurement. The templates are
+// "<measurement filter> <template>", ie "cpu* host.measurement.field", or a
+// template without filter overriding the default template.
+func NewGraphiteSerializer(prefix, template string, tagSupport bool, templates []string) (*GraphiteSerializer, error) {
+	s := &GraphiteSerializer{
+		Prefix:     prefix,
+		Template:   template,
+		TagSupport: tagSupport,
+	}
+	for _, t := range templates {
+		parts := strings.Fields(t)
+		switch len(parts) {
+		case 1:
+			s.Template = parts[0]
+		case 2:
+			f, err := filter.Compile([]string{parts[0]})
+			if err != nil {
+				return nil, fmt.Errorf("invalid graphite template filter %q, %s", parts[0], err)
+			}
+			s.templates = append(s.templates, measurementTemplate{filter: f, template: parts[1]})
+		default:
+			return nil, fmt.Errorf("invalid graphite template %q", t)
+		}
+	}
+	return s, nil
+}
+
+// template returns the template of the measurement.
+func (s *GraphiteSerializer) template(measurement string) string {
+	for _, t := range s.templates {
+		if t.filter.Match(measurement) {
+			return t.template
+		}
+	}
+	return s.Template
 }
 
 func (s *GraphiteSerializer) Serialize(metric telegraf.Metric) ([]byte, error) {
@@ -26,7 +81,22 @@ func (s *GraphiteSerializer) Serialize(metric telegraf.Metric) ([]byte, error) {
 	// Convert UnixNano to Unix timestamps
 	timestamp := metric.UnixNano() / 1000000000
 
-	bucket := SerializeBucketName(metric.Name(), metric.Tags(), s.Template, s.Prefix)
+	if s.TagSupport {
+		tags := serializeTags(metric.Tags())
+		for fieldName, value := range metric.Fields() {
+			bucket := SerializeBucketNameWithTags(metric.Name(), fieldName, s.Prefix)
+			valueS := fmt.Sprintf("%#v", value)
+			point := []byte(fmt.Sprintf("%s%s %s %d\n",
+				sanitizedChars.Replace(bucket),
+				tags,
+				sanitizedChars.Replace(valueS),
+				timestamp))
+			out = append(out, point...)
+		}
+		return out, nil
+	}
+
+	bucket := SerializeBucketName(metric.Name(), metric.Tags(), s.template(metric.Name()), s.Prefix)
 	if bucket == "" {
 		return out, nil
 	}
@@ -44,6 +114,39 @@ func (s *GraphiteSerializer) Serialize(metric telegraf.Metric) ([]byte, error) {
 	return out, nil
 }
 
+// SerializeBucketNameWithTags returns the name of the metric of the field,
+// "<prefix>.<measurement>.<field>", the field being left out if it is
+// "value". The tags are appended by the serializer.
+func SerializeBucketNameWithTags(measurement, fieldName, prefix string) string {
+	name := measurement
+	if fieldName != "value" {
+		name += "." + fieldName
+	}
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// serializeTags returns the Graphite 1.1 tags, ";name=value" in the order
+// of the names. The tags with empty values are left out.
+func serializeTags(tags map[string]string) string {
+	var keys []string
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var tag_str string
+	for _, k := range keys {
+		if tags[k] == "" {
+			continue
+		}
+		tag_str += ";" + tagNameChars.Replace(k) + "=" + tagValueChars.Replace(tags[k])
+	}
+	return tag_str
+}
+
 // SerializeBucketName will take the given measurement name and tags and
 // produce a graphite bucket. It will use the GraphiteSerializer.Template
 // to generate this, or DEFAULT_TEMPLATE.
@@ -416,3 +416,62 @@ func TestTemplate6(t *testing.T) {
 	expS := "localhost.cpu0.us-west-2.cpu.FIELDNAME"
 	assert.Equal(t, expS, mS)
 }
+
+func TestSerializeMetricTagSupport(t *testing.T) {
+	now := time.Now()
+	tags := map[string]string{
+		"host":       "localhost",
+		"cpu":        "cpu 0",
+		"datacenter": "us-west-2",
+		"empty":      "",
+	}
+	fields := map[string]interface{}{
+		"usage_idle": float64(91.5),
+		"value":      float64(8.5),
+	}
+	m, err := metric.New("cpu", tags, fields, now)
+	assert.NoError(t, err)
+
+	s, err := NewGraphiteSerializer("telegraf", template1, true, nil)
+	assert.NoError(t, err)
+	buf, _ := s.Serialize(m)
+	mS := strings.Split(strings.TrimSpace(string(buf)), "\n")
+
+	expS := []string{
+		fmt.Sprintf("telegraf.cpu.usage_idle;cpu=cpu_0;datacenter=us-west-2;host=localhost 91.5 %d", now.Unix()),
+		fmt.Sprintf("telegraf.cpu;cpu=cpu_0;datacenter=us-west-2;host=localhost 8.5 %d", now.Unix()),
+	}
+	sort.Strings(mS)
+	sort.Strings(expS)
+	assert.Equal(t, expS, mS)
+}
+
+func TestSerializeMetricTemplates(t *testing.T) {
+	now := time.Now()
+	fields := map[string]interface{}{
+		"usage_idle": float64(91.5),
+	}
+	cpu, err := metric.New("cpu", defaultTags, fields, now)
+	assert.NoError(t, err)
+	mem, err := metric.New("mem", defaultTags, fields, now)
+	assert.NoError(t, err)
+	disk, err := metric.New("disk", defaultTags, fields, now)
+	assert.NoError(t, err)
+
+	s, err := NewGraphiteSerializer("", "", false, []string{
+		"cpu* " + template2,
+		"mem " + template4,
+		template1,
+	})
+	assert.NoError(t, err)
+
+	buf, _ := s.Serialize(cpu)
+	assert.Equal(t, fmt.Sprintf("localhost.cpu.usage_idle 91.5 %d\n", now.Unix()), string(buf))
+	buf, _ = s.Serialize(mem)
+	assert.Equal(t, fmt.Sprintf("localhost.cpu0.us-west-2.mem 91.5 %d\n", now.Unix()), string(buf))
+	buf, _ = s.Serialize(disk)
+	assert.Equal(t, fmt.Sprintf("cpu0.us-west-2.localhost.disk.usage_idle 91.5 %d\n", now.Unix()), string(buf))
+
+	_, err = NewGraphiteSerializer("", "", false, []string{"cpu host.measurement.field extra"})
+	assert.Error(t, err)
+}
@@ -2,6 +2,8 @@ package json
 
 import (
 	ejson "encoding/json"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -9,20 +11,47 @@ import (
 
 type JsonSerializer struct {
 	TimestampUnits time.Duration
+
+	// TimestampFormat is "unix", "unix_ms", "unix_us", "unix_ns" or a Go
+	// reference time layout formatting the timestamp as a string, it
+	// overrides TimestampUnits when set.
+	TimestampFormat string
+
+	// NestSeparator splits the tag and field names into nested objects when
+	// set, ie "ac.power" becomes {"ac":{"power":...}} with ".".
+	NestSeparator string
 }
 
 func (s *JsonSerializer) Serialize(metric telegraf.Metric) ([]byte, error) {
 	m := make(map[string]interface{})
-	units_nanoseconds := s.TimestampUnits.Nanoseconds()
-	// if the units passed in were less than or equal to zero,
-	// then serialize the timestamp in seconds (the default)
-	if units_nanoseconds <= 0 {
-		units_nanoseconds = 1000000000
-	}
-	m["tags"] = metric.Tags()
-	m["fields"] = metric.Fields()
+
+	fields := make(map[string]interface{}, len(metric.Fields()))
+	for k, v := range metric.Fields() {
+		// The floats keep their decimal point, ie 91.0, to be told apart
+		// from the integers.
+		if f, ok := v.(float64); ok {
+			v = jsonFloat(f)
+		}
+		fields[k] = v
+	}
+	tags := make(map[string]interface{}, len(metric.Tags()))
+	for k, v := range metric.Tags() {
+		tags[k] = v
+	}
+	if s.NestSeparator != "" {
+		fields = nest(fields, s.NestSeparator)
+		tags = nest(tags, s.NestSeparator)
+	}
+
+	timestamp, err := s.timestamp(metric.Time())
+	if err != nil {
+		return []byte{}, err
+	}
+
+	m["tags"] = tags
+	m["fields"] = fields
 	m["name"] = metric.Name()
-	m["timestamp"] = metric.UnixNano() / units_nanoseconds
+	m["timestamp"] = timestamp
 	serialized, err := ejson.Marshal(m)
 	if err != nil {
 		return []byte{}, err
@@ -31,3 +60,85 @@ func (s *JsonSerializer) Serialize(metric telegraf.Metric) ([]byte, error) {
 
 	return serialized, nil
 }
+
+func (s *JsonSerializer) timestamp(t time.Time) (interface{}, error) {
+	switch s.TimestampFormat {
+	case "":
+		units_nanoseconds := s.TimestampUnits.Nanoseconds()
+		// if the units passed in were less than or equal to zero,
+		// then serialize the timestamp in seconds (the default)
+		if units_nanoseconds <= 0 {
+			units_nanoseconds = 1000000000
+		}
+		return t.UnixNano() / units_nanoseconds, nil
+	case "unix":
+		return t.Unix(), nil
+	case "unix_ms":
+		return t.UnixNano() / int64(time.Millisecond), nil
+	case "unix_us":
+		return t.UnixNano() / int64(time.Microsecond), nil
+	case "unix_ns":
+		return t.UnixNano(), nil
+	}
+	return t.UTC().Format(s.TimestampFormat), nil
+}
+
+// jsonFloat is a float64 always encoded with a decimal point or an
+// exponent.
+type jsonFloat float64
+
+func (f jsonFloat) MarshalJSON() ([]byte, error) {
+	b, err := ejson.Marshal(float64(f))
+	if err != nil {
+		return nil, err
+	}
+	if !strings.ContainsAny(string(b), ".eE") {
+		b = append(b, '.', '0')
+	}
+	return b, nil
+}
+
+// nest splits the names on the separator into nested objects. A name
+// conflicting with another one, ie "a" and "a.b", is kept as is.
+func nest(values map[string]interface{}, separator string) map[string]interface{} {
+	names := make([]string, 0, len(values))
+	for k := range values {
+		names = append(names, k)
+	}
+	// A name comes before the longer names it prefixes, so the conflicts
+	// are resolved the same way each time.
+	sort.Strings(names)
+
+	nested := make(map[string]interface{})
+	var conflicts []string
+	for _, name := range names {
+		parts := strings.Split(name, separator)
+		obj := nested
+		ok := true
+		for _, part := range parts[:len(parts)-1] {
+			switch child := obj[part].(type) {
+			case nil:
+				next := make(map[string]interface{})
+				obj[part] = next
+				obj = next
+			case map[string]interface{}:
+				obj = child
+			default:
+				ok = false
+			}
+			if !ok {
+				break
+			}
+		}
+		leaf := parts[len(parts)-1]
+		if _, exists := obj[leaf]; !ok || exists {
+			conflicts = append(conflicts, name)
+			continue
+		}
+		obj[leaf] = values[name]
+	}
+	for _, name := range conflicts {
+		nested[name] = values[name]
+	}
+	return nested
+}
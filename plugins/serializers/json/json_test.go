@@ -108,3 +108,64 @@ func TestSerializeMetricWithEscapes(t *testing.T) {
 	expS := []byte(fmt.Sprintf(`{"fields":{"U,age=Idle":90},"name":"My CPU","tags":{"cpu tag":"cpu0"},"timestamp":%d}`, now.Unix()) + "\n")
 	assert.Equal(t, string(expS), string(buf))
 }
+
+func TestSerializeMetricFloatType(t *testing.T) {
+	now := time.Now()
+	m, err := metric.New("cpu", map[string]string{}, map[string]interface{}{
+		"usage_idle": float64(91),
+		"usage_big":  float64(1e21),
+		"count":      int64(91),
+	}, now)
+	assert.NoError(t, err)
+
+	s := JsonSerializer{}
+	buf, err := s.Serialize(m)
+	assert.NoError(t, err)
+
+	expS := []byte(fmt.Sprintf(`{"fields":{"count":91,"usage_big":1e+21,"usage_idle":91.0},"name":"cpu","tags":{},"timestamp":%d}`, now.Unix()) + "\n")
+	assert.Equal(t, string(expS), string(buf))
+}
+
+func TestSerializeMetricNested(t *testing.T) {
+	now := time.Now()
+	tags := map[string]string{
+		"site.name": "roof",
+		"site.zone": "east",
+		"inverter":  "inv-1",
+	}
+	fields := map[string]interface{}{
+		"ac.power":   1530.5,
+		"ac.voltage": int64(230),
+		"dc":         int64(1),
+		"dc.power":   int64(2),
+	}
+	m, err := metric.New("inverter", tags, fields, now)
+	assert.NoError(t, err)
+
+	s := JsonSerializer{NestSeparator: "."}
+	buf, err := s.Serialize(m)
+	assert.NoError(t, err)
+
+	// dc.power conflicts with dc and is kept as is
+	expS := []byte(fmt.Sprintf(`{"fields":{"ac":{"power":1530.5,"voltage":230},"dc":1,"dc.power":2},"name":"inverter","tags":{"inverter":"inv-1","site":{"name":"roof","zone":"east"}},"timestamp":%d}`, now.Unix()) + "\n")
+	assert.Equal(t, string(expS), string(buf))
+}
+
+func TestSerializeMetricTimestampFormat(t *testing.T) {
+	now := time.Date(2017, 6, 1, 12, 0, 0, 500000000, time.UTC)
+	m, err := metric.New("cpu", map[string]string{}, map[string]interface{}{"value": int64(1)}, now)
+	assert.NoError(t, err)
+
+	tests := map[string]string{
+		"unix":                      "1496318400",
+		"unix_ms":                   "1496318400500",
+		"unix_ns":                   "1496318400500000000",
+		"2006-01-02T15:04:05Z07:00": `"2017-06-01T12:00:00Z"`,
+	}
+	for format, timestamp := range tests {
+		s := JsonSerializer{TimestampUnits: time.Nanosecond, TimestampFormat: format}
+		buf, err := s.Serialize(m)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"fields":{"value":1},"name":"cpu","tags":{},"timestamp":`+timestamp+"}\n", string(buf), format)
+	}
+}
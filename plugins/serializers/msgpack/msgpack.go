@@ -0,0 +1,173 @@
+package msgpack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// MsgpackSerializer encodes each metric as a MessagePack map with the
+// "name", "time", "tags" and "fields" keys, the time being a timestamp
+// extension. The metrics are concatenated without separator, each
+// MessagePack value delimits itself.
+type MsgpackSerializer struct{}
+
+func (s *MsgpackSerializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	e := &encoder{}
+	e.mapHeader(4)
+
+	e.string("name")
+	e.string(metric.Name())
+
+	e.string("time")
+	e.timestamp(metric.Time())
+
+	tags := metric.Tags()
+	e.string("tags")
+	e.mapHeader(len(tags))
+	for _, k := range sortedKeys(tags) {
+		e.string(k)
+		e.string(tags[k])
+	}
+
+	fields := metric.Fields()
+	names := make([]string, 0, len(fields))
+	for k := range fields {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	e.string("fields")
+	e.mapHeader(len(fields))
+	for _, k := range names {
+		e.string(k)
+		if err := e.value(fields[k]); err != nil {
+			return nil, fmt.Errorf("field %s: %s", k, err)
+		}
+	}
+	return e.Bytes(), nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// encoder writes the MessagePack encoding of values, in their smallest
+// representation.
+type encoder struct {
+	bytes.Buffer
+}
+
+func (e *encoder) write(b byte, v interface{}) {
+	e.WriteByte(b)
+	binary.Write(e, binary.BigEndian, v)
+}
+
+func (e *encoder) mapHeader(n int) {
+	switch {
+	case n < 16:
+		e.WriteByte(0x80 | byte(n))
+	case n <= math.MaxUint16:
+		e.write(0xde, uint16(n))
+	default:
+		e.write(0xdf, uint32(n))
+	}
+}
+
+func (e *encoder) string(s string) {
+	n := len(s)
+	switch {
+	case n < 32:
+		e.WriteByte(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		e.write(0xd9, uint8(n))
+	case n <= math.MaxUint16:
+		e.write(0xda, uint16(n))
+	default:
+		e.write(0xdb, uint32(n))
+	}
+	e.WriteString(s)
+}
+
+func (e *encoder) int(i int64) {
+	switch {
+	case i >= 0:
+		e.uint(uint64(i))
+	case i >= -32:
+		e.WriteByte(byte(i))
+	case i >= math.MinInt8:
+		e.write(0xd0, int8(i))
+	case i >= math.MinInt16:
+		e.write(0xd1, int16(i))
+	case i >= math.MinInt32:
+		e.write(0xd2, int32(i))
+	default:
+		e.write(0xd3, i)
+	}
+}
+
+func (e *encoder) uint(u uint64) {
+	switch {
+	case u < 128:
+		e.WriteByte(byte(u))
+	case u <= math.MaxUint8:
+		e.write(0xcc, uint8(u))
+	case u <= math.MaxUint16:
+		e.write(0xcd, uint16(u))
+	case u <= math.MaxUint32:
+		e.write(0xce, uint32(u))
+	default:
+		e.write(0xcf, u)
+	}
+}
+
+func (e *encoder) value(v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		e.WriteByte(0xc0)
+	case bool:
+		if v {
+			e.WriteByte(0xc3)
+		} else {
+			e.WriteByte(0xc2)
+		}
+	case int64:
+		e.int(v)
+	case uint64:
+		e.uint(v)
+	case float64:
+		e.write(0xcb, v)
+	case string:
+		e.string(v)
+	default:
+		return fmt.Errorf("unsupported type %T", v)
+	}
+	return nil
+}
+
+// timestamp writes the timestamp extension, type -1, in the 32, 64 or 96
+// bits format.
+func (e *encoder) timestamp(t time.Time) {
+	sec, nsec := t.Unix(), int64(t.Nanosecond())
+	switch {
+	case sec>>34 == 0 && nsec == 0 && sec <= math.MaxUint32:
+		e.WriteByte(0xd6)
+		e.write(0xff, uint32(sec))
+	case sec>>34 == 0:
+		e.WriteByte(0xd7)
+		e.write(0xff, uint64(nsec)<<34|uint64(sec))
+	default:
+		e.Write([]byte{0xc7, 12})
+		e.write(0xff, uint32(nsec))
+		binary.Write(e, binary.BigEndian, sec)
+	}
+}
@@ -0,0 +1,83 @@
+package msgpack
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/metric"
+)
+
+func TestSerializeMetric(t *testing.T) {
+	tags := map[string]string{
+		"cpu": "cpu0",
+	}
+	fields := map[string]interface{}{
+		"usage_idle": float64(91.5),
+		"running":    true,
+		"count":      int64(-300),
+		"state":      "ok",
+	}
+	m, err := metric.New("cpu", tags, fields, time.Unix(1500000000, 0))
+	require.NoError(t, err)
+
+	s := MsgpackSerializer{}
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+
+	exp := []byte{0x84,
+		0xa4, 'n', 'a', 'm', 'e', 0xa3, 'c', 'p', 'u',
+		0xa4, 't', 'i', 'm', 'e', 0xd6, 0xff, 0x59, 0x68, 0x2f, 0x00,
+		0xa4, 't', 'a', 'g', 's', 0x81,
+		0xa3, 'c', 'p', 'u', 0xa4, 'c', 'p', 'u', '0',
+		0xa6, 'f', 'i', 'e', 'l', 'd', 's', 0x84,
+		0xa5, 'c', 'o', 'u', 'n', 't', 0xd1, 0xfe, 0xd4,
+		0xa7, 'r', 'u', 'n', 'n', 'i', 'n', 'g', 0xc3,
+		0xa5, 's', 't', 'a', 't', 'e', 0xa2, 'o', 'k',
+		0xaa, 'u', 's', 'a', 'g', 'e', '_', 'i', 'd', 'l', 'e',
+		0xcb, 0x40, 0x56, 0xe0, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+	assert.Equal(t, exp, buf)
+}
+
+func TestEncodeInt(t *testing.T) {
+	tests := []struct {
+		value int64
+		exp   []byte
+	}{
+		{0, []byte{0x00}},
+		{127, []byte{0x7f}},
+		{128, []byte{0xcc, 0x80}},
+		{65536, []byte{0xce, 0x00, 0x01, 0x00, 0x00}},
+		{-1, []byte{0xff}},
+		{-32, []byte{0xe0}},
+		{-33, []byte{0xd0, 0xdf}},
+		{-2147483649, []byte{0xd3, 0xff, 0xff, 0xff, 0xff, 0x7f, 0xff, 0xff, 0xff}},
+	}
+	for _, tt := range tests {
+		e := &encoder{}
+		e.int(tt.value)
+		assert.Equal(t, tt.exp, e.Bytes(), "%d", tt.value)
+	}
+}
+
+func TestEncodeString(t *testing.T) {
+	e := &encoder{}
+	e.string(string(make([]byte, 40)))
+	assert.Equal(t, []byte{0xd9, 40}, e.Bytes()[:2])
+	assert.Len(t, e.Bytes(), 42)
+}
+
+func TestEncodeTimestamp(t *testing.T) {
+	e := &encoder{}
+	e.timestamp(time.Unix(1, 5))
+	assert.Equal(t, []byte{0xd7, 0xff, 0x00, 0x00, 0x00, 0x14, 0x00, 0x00, 0x00, 0x01}, e.Bytes())
+
+	e = &encoder{}
+	e.timestamp(time.Unix(-1, 0))
+	assert.Equal(t, []byte{0xc7, 12, 0xff,
+		0x00, 0x00, 0x00, 0x00,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, e.Bytes())
+}
@@ -0,0 +1,63 @@
+package opentelemetry
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Wire types of the protobuf encoding.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+// encoder writes the protobuf encoding of the fields of a message, the
+// strings and varints with their zero value are omitted as in proto3.
+type encoder struct {
+	bytes.Buffer
+}
+
+func (e *encoder) uvarint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	e.Write(buf[:n])
+}
+
+func (e *encoder) key(field int, wire int) {
+	e.uvarint(uint64(field)<<3 | uint64(wire))
+}
+
+func (e *encoder) varint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	e.key(field, wireVarint)
+	e.uvarint(v)
+}
+
+// fixed64 is always written, the values of a oneof are set even if zero.
+func (e *encoder) fixed64(field int, v uint64) {
+	e.key(field, wireFixed64)
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	e.Write(buf[:])
+}
+
+func (e *encoder) string(field int, s string) {
+	if s == "" {
+		return
+	}
+	e.key(field, wireBytes)
+	e.uvarint(uint64(len(s)))
+	e.WriteString(s)
+}
+
+// message writes the embedded message encoded by marshal, even if empty.
+func (e *encoder) message(field int, marshal func(*encoder)) {
+	m := &encoder{}
+	marshal(m)
+	e.key(field, wireBytes)
+	e.uvarint(uint64(m.Len()))
+	e.Write(m.Bytes())
+}
@@ -0,0 +1,185 @@
+package opentelemetry
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+)
+
+// The types of the OTLP metrics data model, version 1.0, tagged for the
+// OTLP/JSON encoding and marshaled to the protobuf encoding by hand.
+
+// AggregationTemporality of the sums.
+const (
+	AggregationTemporalityUnspecified = 0
+	AggregationTemporalityDelta       = 1
+	AggregationTemporalityCumulative  = 2
+)
+
+// ExportMetricsServiceRequest is the request of the OTLP metrics service,
+// the body of the OTLP/HTTP requests and the message of the OTLP/gRPC
+// Export call.
+type ExportMetricsServiceRequest struct {
+	ResourceMetrics []*ResourceMetrics `json:"resourceMetrics"`
+}
+
+type ResourceMetrics struct {
+	Resource     Resource        `json:"resource"`
+	ScopeMetrics []*ScopeMetrics `json:"scopeMetrics"`
+}
+
+type Resource struct {
+	Attributes []KeyValue `json:"attributes,omitempty"`
+}
+
+type ScopeMetrics struct {
+	Scope   InstrumentationScope `json:"scope"`
+	Metrics []*Metric            `json:"metrics"`
+}
+
+type InstrumentationScope struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+// Metric is a gauge or a sum, the other kinds of metrics are not made of
+// Telegraf metrics.
+type Metric struct {
+	Name  string `json:"name"`
+	Gauge *Gauge `json:"gauge,omitempty"`
+	Sum   *Sum   `json:"sum,omitempty"`
+}
+
+type Gauge struct {
+	DataPoints []*NumberDataPoint `json:"dataPoints"`
+}
+
+type Sum struct {
+	DataPoints             []*NumberDataPoint `json:"dataPoints"`
+	AggregationTemporality int                `json:"aggregationTemporality"`
+	IsMonotonic            bool               `json:"isMonotonic,omitempty"`
+}
+
+// NumberDataPoint has either an integer or a double value, AsInt or
+// AsDouble is set.
+type NumberDataPoint struct {
+	Attributes   []KeyValue `json:"attributes,omitempty"`
+	TimeUnixNano uint64     `json:"timeUnixNano,string"`
+	AsDouble     *Double    `json:"asDouble,omitempty"`
+	AsInt        *int64     `json:"asInt,omitempty"`
+}
+
+type KeyValue struct {
+	Key   string   `json:"key"`
+	Value AnyValue `json:"value"`
+}
+
+// AnyValue is a string value, the tags being the only attributes.
+type AnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+// Double is a float64 encoded as in the JSON mapping of protobuf, the NaN
+// and infinite values as strings.
+type Double float64
+
+func (d Double) MarshalJSON() ([]byte, error) {
+	f := float64(d)
+	switch {
+	case math.IsNaN(f):
+		return []byte(`"NaN"`), nil
+	case math.IsInf(f, 1):
+		return []byte(`"Infinity"`), nil
+	case math.IsInf(f, -1):
+		return []byte(`"-Infinity"`), nil
+	}
+	return json.Marshal(f)
+}
+
+// MarshalJSON is needed for the 64 bits integers, encoded as strings.
+func (p *NumberDataPoint) MarshalJSON() ([]byte, error) {
+	type point NumberDataPoint
+	if p.AsInt == nil {
+		return json.Marshal((*point)(p))
+	}
+	return json.Marshal(&struct {
+		*point
+		AsInt string `json:"asInt"`
+	}{(*point)(p), strconv.FormatInt(*p.AsInt, 10)})
+}
+
+// Marshal returns the protobuf encoding of the request.
+func (r *ExportMetricsServiceRequest) Marshal() []byte {
+	e := &encoder{}
+	for _, rm := range r.ResourceMetrics {
+		e.message(1, rm.marshal)
+	}
+	return e.Bytes()
+}
+
+func (rm *ResourceMetrics) marshal(e *encoder) {
+	e.message(1, rm.Resource.marshal)
+	for _, sm := range rm.ScopeMetrics {
+		e.message(2, sm.marshal)
+	}
+}
+
+func (r *Resource) marshal(e *encoder) {
+	for i := range r.Attributes {
+		e.message(1, r.Attributes[i].marshal)
+	}
+}
+
+func (sm *ScopeMetrics) marshal(e *encoder) {
+	e.message(1, sm.Scope.marshal)
+	for _, m := range sm.Metrics {
+		e.message(2, m.marshal)
+	}
+}
+
+func (s *InstrumentationScope) marshal(e *encoder) {
+	e.string(1, s.Name)
+	e.string(2, s.Version)
+}
+
+func (m *Metric) marshal(e *encoder) {
+	e.string(1, m.Name)
+	if m.Gauge != nil {
+		e.message(5, func(e *encoder) {
+			for _, p := range m.Gauge.DataPoints {
+				e.message(1, p.marshal)
+			}
+		})
+	}
+	if m.Sum != nil {
+		e.message(7, func(e *encoder) {
+			for _, p := range m.Sum.DataPoints {
+				e.message(1, p.marshal)
+			}
+			e.varint(2, uint64(m.Sum.AggregationTemporality))
+			if m.Sum.IsMonotonic {
+				e.varint(3, 1)
+			}
+		})
+	}
+}
+
+func (p *NumberDataPoint) marshal(e *encoder) {
+	e.fixed64(3, p.TimeUnixNano)
+	if p.AsDouble != nil {
+		e.fixed64(4, math.Float64bits(float64(*p.AsDouble)))
+	}
+	if p.AsInt != nil {
+		e.fixed64(6, uint64(*p.AsInt))
+	}
+	for i := range p.Attributes {
+		e.message(7, p.Attributes[i].marshal)
+	}
+}
+
+func (kv *KeyValue) marshal(e *encoder) {
+	e.string(1, kv.Key)
+	e.message(2, func(e *encoder) {
+		e.string(1, kv.Value.StringValue)
+	})
+}
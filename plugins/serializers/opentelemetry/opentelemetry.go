@@ -0,0 +1,184 @@
+package opentelemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+// ScopeName is the name of the instrumentation scope of the metrics.
+const ScopeName = "telegraf"
+
+// OpenTelemetrySerializer converts the metrics to OTLP metrics, encoded as
+// an ExportMetricsServiceRequest for the OTLP receivers of the OpenTelemetry
+// collectors.
+//
+// Each numeric field is a metric named after the measurement and the field,
+// as in the prometheus_client output, the counters being cumulative
+// monotonic sums and the other metrics gauges. The tags are the attributes
+// of the data points, but the ResourceTags which are the attributes of the
+// resources, ie "host".
+type OpenTelemetrySerializer struct {
+	// Encoding is "protobuf", the default, or "json".
+	Encoding     string
+	ResourceTags []string
+}
+
+func (s *OpenTelemetrySerializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	return s.Marshal(s.Request([]telegraf.Metric{metric}))
+}
+
+// Marshal encodes the request in the Encoding of the serializer, the outputs
+// sending the metrics of a batch in a single request use it with Request.
+func (s *OpenTelemetrySerializer) Marshal(r *ExportMetricsServiceRequest) ([]byte, error) {
+	switch s.Encoding {
+	case "", "protobuf":
+		return r.Marshal(), nil
+	case "json":
+		serialized, err := json.Marshal(r)
+		if err != nil {
+			return []byte{}, err
+		}
+		return append(serialized, '\n'), nil
+	}
+	return nil, fmt.Errorf("invalid encoding %q", s.Encoding)
+}
+
+// Request converts the metrics to an ExportMetricsServiceRequest, with a
+// ResourceMetrics by set of resource attributes. The metrics and the data
+// points are in the order of the Telegraf metrics.
+func (s *OpenTelemetrySerializer) Request(metrics []telegraf.Metric) *ExportMetricsServiceRequest {
+	r := &ExportMetricsServiceRequest{}
+	resources := make(map[string]*ScopeMetrics)
+	for _, metric := range metrics {
+		resource, attributes := s.attributes(metric.Tags())
+		key := resourceKey(resource)
+		sm, ok := resources[key]
+		if !ok {
+			sm = &ScopeMetrics{Scope: InstrumentationScope{Name: ScopeName}}
+			resources[key] = sm
+			r.ResourceMetrics = append(r.ResourceMetrics, &ResourceMetrics{
+				Resource:     Resource{Attributes: resource},
+				ScopeMetrics: []*ScopeMetrics{sm},
+			})
+		}
+		addMetric(sm, metric, attributes)
+	}
+	return r
+}
+
+// attributes splits the tags into the attributes of the resource and of the
+// data points, sorted by key.
+func (s *OpenTelemetrySerializer) attributes(tags map[string]string) ([]KeyValue, []KeyValue) {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var resource, attributes []KeyValue
+	for _, k := range keys {
+		kv := KeyValue{Key: k, Value: AnyValue{StringValue: tags[k]}}
+		if s.isResourceTag(k) {
+			resource = append(resource, kv)
+		} else {
+			attributes = append(attributes, kv)
+		}
+	}
+	return resource, attributes
+}
+
+func (s *OpenTelemetrySerializer) isResourceTag(key string) bool {
+	for _, tag := range s.ResourceTags {
+		if tag == key {
+			return true
+		}
+	}
+	return false
+}
+
+func resourceKey(attributes []KeyValue) string {
+	parts := make([]string, len(attributes))
+	for i, kv := range attributes {
+		parts[i] = kv.Key + "=" + kv.Value.StringValue
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// addMetric adds a data point by numeric field of the metric to the OTLP
+// metrics of the scope, the string fields are skipped.
+func addMetric(sm *ScopeMetrics, metric telegraf.Metric, attributes []KeyValue) {
+	fields := metric.Fields()
+	names := make([]string, 0, len(fields))
+	for k := range fields {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	for _, field := range names {
+		p := &NumberDataPoint{
+			Attributes:   attributes,
+			TimeUnixNano: uint64(metric.Time().UnixNano()),
+		}
+		switch v := fields[field].(type) {
+		case int64:
+			p.AsInt = &v
+		case uint64:
+			if v <= math.MaxInt64 {
+				i := int64(v)
+				p.AsInt = &i
+			} else {
+				d := Double(v)
+				p.AsDouble = &d
+			}
+		case float64:
+			d := Double(v)
+			p.AsDouble = &d
+		case bool:
+			var i int64
+			if v {
+				i = 1
+			}
+			p.AsInt = &i
+		default:
+			continue
+		}
+
+		name := metric.Name()
+		if field != "value" {
+			name += "_" + field
+		}
+		m := findMetric(sm, name, metric.Type())
+		if m.Sum != nil {
+			m.Sum.DataPoints = append(m.Sum.DataPoints, p)
+		} else {
+			m.Gauge.DataPoints = append(m.Gauge.DataPoints, p)
+		}
+	}
+}
+
+// findMetric returns the OTLP metric of the scope with the name and the kind
+// of the type, added if missing.
+func findMetric(sm *ScopeMetrics, name string, typ telegraf.ValueType) *Metric {
+	sum := typ == telegraf.Counter
+	for _, m := range sm.Metrics {
+		if m.Name == name && (m.Sum != nil) == sum {
+			return m
+		}
+	}
+	m := &Metric{Name: name}
+	if sum {
+		m.Sum = &Sum{
+			AggregationTemporality: AggregationTemporalityCumulative,
+			IsMonotonic:            true,
+		}
+	} else {
+		m.Gauge = &Gauge{}
+	}
+	sm.Metrics = append(sm.Metrics, m)
+	return m
+}
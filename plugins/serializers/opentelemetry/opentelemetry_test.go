@@ -0,0 +1,202 @@
+package opentelemetry
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+var ts = time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func testMetrics(t *testing.T) []telegraf.Metric {
+	cpu, err := metric.New("cpu",
+		map[string]string{"host": "server-1", "cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": 91.5, "state": "ok"},
+		ts)
+	require.NoError(t, err)
+	requests, err := metric.New("http",
+		map[string]string{"host": "server-1", "code": "200"},
+		map[string]interface{}{"value": int64(42)},
+		ts, telegraf.Counter)
+	require.NoError(t, err)
+	other, err := metric.New("cpu",
+		map[string]string{"host": "server-2", "cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": 80.0, "online": true},
+		ts)
+	require.NoError(t, err)
+	return []telegraf.Metric{cpu, requests, other}
+}
+
+func TestRequest(t *testing.T) {
+	s := OpenTelemetrySerializer{ResourceTags: []string{"host"}}
+	r := s.Request(testMetrics(t))
+	require.Len(t, r.ResourceMetrics, 2)
+
+	rm := r.ResourceMetrics[0]
+	assert.Equal(t, []KeyValue{{"host", AnyValue{"server-1"}}}, rm.Resource.Attributes)
+	require.Len(t, rm.ScopeMetrics, 1)
+	assert.Equal(t, ScopeName, rm.ScopeMetrics[0].Scope.Name)
+	metrics := rm.ScopeMetrics[0].Metrics
+	require.Len(t, metrics, 2)
+
+	// The string fields are skipped
+	assert.Equal(t, "cpu_usage_idle", metrics[0].Name)
+	require.NotNil(t, metrics[0].Gauge)
+	p := metrics[0].Gauge.DataPoints[0]
+	assert.Equal(t, []KeyValue{{"cpu", AnyValue{"cpu0"}}}, p.Attributes)
+	assert.Equal(t, uint64(ts.UnixNano()), p.TimeUnixNano)
+	assert.Equal(t, Double(91.5), *p.AsDouble)
+	assert.Nil(t, p.AsInt)
+
+	// The value fields are named after the measurement
+	assert.Equal(t, "http", metrics[1].Name)
+	require.NotNil(t, metrics[1].Sum)
+	assert.Equal(t, AggregationTemporalityCumulative, metrics[1].Sum.AggregationTemporality)
+	assert.True(t, metrics[1].Sum.IsMonotonic)
+	assert.Equal(t, int64(42), *metrics[1].Sum.DataPoints[0].AsInt)
+
+	rm = r.ResourceMetrics[1]
+	assert.Equal(t, []KeyValue{{"host", AnyValue{"server-2"}}}, rm.Resource.Attributes)
+	metrics = rm.ScopeMetrics[0].Metrics
+	require.Len(t, metrics, 2)
+	assert.Equal(t, "cpu_online", metrics[0].Name)
+	assert.Equal(t, int64(1), *metrics[0].Gauge.DataPoints[0].AsInt)
+}
+
+func TestRequestWithoutResourceTags(t *testing.T) {
+	s := OpenTelemetrySerializer{}
+	r := s.Request(testMetrics(t))
+	require.Len(t, r.ResourceMetrics, 1)
+	assert.Empty(t, r.ResourceMetrics[0].Resource.Attributes)
+
+	// The data points of both hosts are in the same metric
+	metrics := r.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	require.Len(t, metrics, 3)
+	assert.Equal(t, "cpu_usage_idle", metrics[0].Name)
+	require.Len(t, metrics[0].Gauge.DataPoints, 2)
+	assert.Equal(t, []KeyValue{
+		{"cpu", AnyValue{"cpu0"}},
+		{"host", AnyValue{"server-2"}},
+	}, metrics[0].Gauge.DataPoints[1].Attributes)
+}
+
+func TestSerializeJSON(t *testing.T) {
+	m, err := metric.New("http",
+		map[string]string{"host": "server-1", "code": "200"},
+		map[string]interface{}{"requests": int64(42), "latency": 0.25},
+		ts, telegraf.Counter)
+	require.NoError(t, err)
+
+	s := OpenTelemetrySerializer{Encoding: "json", ResourceTags: []string{"host"}}
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+	assert.Equal(t, `{"resourceMetrics":[{"resource":{"attributes":[{"key":"host","value":{"stringValue":"server-1"}}]},`+
+		`"scopeMetrics":[{"scope":{"name":"telegraf"},"metrics":[`+
+		`{"name":"http_latency","sum":{"dataPoints":[{"attributes":[{"key":"code","value":{"stringValue":"200"}}],`+
+		`"timeUnixNano":"1496318400000000000","asDouble":0.25}],"aggregationTemporality":2,"isMonotonic":true}},`+
+		`{"name":"http_requests","sum":{"dataPoints":[{"attributes":[{"key":"code","value":{"stringValue":"200"}}],`+
+		`"timeUnixNano":"1496318400000000000","asInt":"42"}],"aggregationTemporality":2,"isMonotonic":true}}]}]}]}`+"\n",
+		string(buf))
+
+	s.Encoding = "xml"
+	_, err = s.Serialize(m)
+	assert.Error(t, err)
+}
+
+func TestDoubleJSON(t *testing.T) {
+	for f, want := range map[float64]string{
+		1.5:          "1.5",
+		math.Inf(1):  `"Infinity"`,
+		math.Inf(-1): `"-Infinity"`,
+	} {
+		buf, err := Double(f).MarshalJSON()
+		require.NoError(t, err)
+		assert.Equal(t, want, string(buf))
+	}
+	buf, err := Double(math.NaN()).MarshalJSON()
+	require.NoError(t, err)
+	assert.Equal(t, `"NaN"`, string(buf))
+}
+
+// field is a field of a protobuf message, the value of the embedded messages
+// being their encoding.
+type field struct {
+	number int
+	value  interface{}
+}
+
+func decode(t *testing.T, buf []byte) []field {
+	var fields []field
+	for len(buf) > 0 {
+		key, n := binary.Uvarint(buf)
+		require.True(t, n > 0)
+		buf = buf[n:]
+		f := field{number: int(key >> 3)}
+		switch key & 7 {
+		case wireVarint:
+			v, n := binary.Uvarint(buf)
+			require.True(t, n > 0)
+			f.value, buf = v, buf[n:]
+		case wireFixed64:
+			require.True(t, len(buf) >= 8)
+			f.value, buf = binary.LittleEndian.Uint64(buf), buf[8:]
+		case wireBytes:
+			l, n := binary.Uvarint(buf)
+			require.True(t, n > 0 && len(buf) >= n+int(l))
+			f.value, buf = buf[n:n+int(l)], buf[n+int(l):]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// message returns the encoding of the only embedded message of the fields
+// with the number.
+func message(t *testing.T, fields []field, number int) []byte {
+	var found []byte
+	for _, f := range fields {
+		if f.number == number {
+			require.Nil(t, found, "field %d is repeated", number)
+			found = f.value.([]byte)
+		}
+	}
+	require.NotNil(t, found, "field %d is missing", number)
+	return found
+}
+
+func TestSerializeProtobuf(t *testing.T) {
+	m, err := metric.New("http",
+		map[string]string{"host": "server-1"},
+		map[string]interface{}{"requests": int64(-1)},
+		ts, telegraf.Counter)
+	require.NoError(t, err)
+
+	s := OpenTelemetrySerializer{ResourceTags: []string{"host"}}
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+
+	rm := decode(t, message(t, decode(t, buf), 1))
+	attr := decode(t, message(t, decode(t, message(t, rm, 1)), 1))
+	assert.Equal(t, []field{{1, []byte("host")}, {2, []byte("\n\bserver-1")}}, attr)
+
+	sm := decode(t, message(t, rm, 2))
+	assert.Equal(t, []field{{1, []byte("telegraf")}}, decode(t, message(t, sm, 1)))
+	metric := decode(t, message(t, sm, 2))
+	assert.Equal(t, field{1, []byte("http_requests")}, metric[0])
+	sum := decode(t, message(t, metric, 7))
+	assert.Equal(t, []field{{2, uint64(2)}, {3, uint64(1)}}, sum[1:])
+	point := decode(t, message(t, sum, 1))
+	assert.Equal(t, []field{
+		{3, uint64(ts.UnixNano())},
+		{6, uint64(math.MaxUint64)},
+	}, point)
+}
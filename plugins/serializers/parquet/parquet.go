@@ -0,0 +1,311 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Physical types
+const (
+	typeBoolean   int32 = 0
+	typeInt64     int32 = 2
+	typeDouble    int32 = 5
+	typeByteArray int32 = 6
+)
+
+// Converted types
+const (
+	convertedUTF8            int32 = 0
+	convertedTimestampMicros int32 = 10
+)
+
+// Encodings
+const (
+	encodingPlain int32 = 0
+	encodingRLE   int32 = 3
+)
+
+const magic = "PAR1"
+
+// ParquetSerializer encodes batches of metrics as Parquet files with a
+// single row group, each metric being a row. The schema is derived from the
+// metrics of the batch: the required "name" and "time" columns, then an
+// optional string column per tag key and an optional column per field, all
+// sorted by name.
+type ParquetSerializer struct{}
+
+// Serialize encodes a single metric as a Parquet file, the batches should
+// be serialized with SerializeBatch.
+func (s *ParquetSerializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	return s.SerializeBatch([]telegraf.Metric{metric})
+}
+
+// column is a column of the row group, with the values of each row, nil
+// when the metric has no such tag or field.
+type column struct {
+	name      string
+	typ       int32
+	converted int32
+	required  bool
+	values    []interface{}
+}
+
+func (s *ParquetSerializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	columns := makeColumns(metrics)
+
+	b := &bytes.Buffer{}
+	b.WriteString(magic)
+	var chunks tList
+	var total int64
+	for _, c := range columns {
+		offset := int64(b.Len())
+		if err := c.writePage(b); err != nil {
+			return nil, fmt.Errorf("column %s: %s", c.name, err)
+		}
+		size := int64(b.Len()) - offset
+		total += size
+		chunks = append(chunks, tStruct{
+			{2, offset},
+			{3, tStruct{
+				{1, c.typ},
+				{2, tList{encodingPlain, encodingRLE}},
+				{3, tList{c.name}},
+				{4, int32(0)}, // uncompressed
+				{5, int64(len(metrics))},
+				{6, size},
+				{7, size},
+				{9, offset},
+			}},
+		})
+	}
+
+	schema := tList{tStruct{
+		{4, "telegraf"},
+		{5, int32(len(columns))},
+	}}
+	for _, c := range columns {
+		var repetition int32 = 1 // optional
+		if c.required {
+			repetition = 0
+		}
+		element := tStruct{
+			{1, c.typ},
+			{3, repetition},
+			{4, c.name},
+		}
+		if c.converted >= 0 {
+			element = append(element, tField{6, c.converted})
+		}
+		schema = append(schema, element)
+	}
+
+	footer := tStruct{
+		{1, int32(1)},
+		{2, schema},
+		{3, int64(len(metrics))},
+		{4, tList{tStruct{
+			{1, chunks},
+			{2, total},
+			{3, int64(len(metrics))},
+		}}},
+		{6, "telegraf"},
+	}
+	start := b.Len()
+	footer.encode(b)
+	binary.Write(b, binary.LittleEndian, uint32(b.Len()-start))
+	b.WriteString(magic)
+	return b.Bytes(), nil
+}
+
+// makeColumns returns the columns of the metrics. The type of a field is
+// the type of its values, or double for the integer and float values, and
+// string for other mixed types.
+func makeColumns(metrics []telegraf.Metric) []*column {
+	names := &column{name: "name", typ: typeByteArray, converted: convertedUTF8, required: true}
+	times := &column{name: "time", typ: typeInt64, converted: convertedTimestampMicros, required: true}
+
+	tagKeys := make(map[string]bool)
+	fieldTypes := make(map[string]int32)
+	for _, m := range metrics {
+		for k := range m.Tags() {
+			tagKeys[k] = true
+		}
+		for k, v := range m.Fields() {
+			typ := typeOfValue(v)
+			if prev, ok := fieldTypes[k]; ok && prev != typ {
+				if (prev == typeInt64 || prev == typeDouble) && (typ == typeInt64 || typ == typeDouble) {
+					typ = typeDouble
+				} else {
+					typ = typeByteArray
+				}
+			}
+			fieldTypes[k] = typ
+		}
+	}
+
+	columns := []*column{names, times}
+	used := map[string]bool{"name": true, "time": true}
+	tags := make(map[string]*column)
+	for _, k := range sortedKeys(tagKeys) {
+		c := &column{name: unique(k, "tag", used), typ: typeByteArray, converted: convertedUTF8}
+		tags[k] = c
+		columns = append(columns, c)
+	}
+	fieldNames := make(map[string]bool)
+	for k := range fieldTypes {
+		fieldNames[k] = true
+	}
+	fields := make(map[string]*column)
+	for _, k := range sortedKeys(fieldNames) {
+		c := &column{name: unique(k, "field", used), typ: fieldTypes[k], converted: -1}
+		if c.typ == typeByteArray {
+			c.converted = convertedUTF8
+		}
+		fields[k] = c
+		columns = append(columns, c)
+	}
+
+	for _, m := range metrics {
+		names.values = append(names.values, m.Name())
+		times.values = append(times.values, m.UnixNano()/1000)
+		mtags, mfields := m.Tags(), m.Fields()
+		for k, c := range tags {
+			if v, ok := mtags[k]; ok {
+				c.values = append(c.values, v)
+			} else {
+				c.values = append(c.values, nil)
+			}
+		}
+		for k, c := range fields {
+			v, ok := mfields[k]
+			if !ok {
+				c.values = append(c.values, nil)
+				continue
+			}
+			c.values = append(c.values, convert(v, c.typ))
+		}
+	}
+	return columns
+}
+
+// unique returns the name, suffixed with the kind of the column if it is
+// already used, ie a field with the name of a tag.
+func unique(name, kind string, used map[string]bool) string {
+	if used[name] {
+		name += "_" + kind
+	}
+	used[name] = true
+	return name
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func typeOfValue(v interface{}) int32 {
+	switch v.(type) {
+	case int64, uint64:
+		return typeInt64
+	case float64:
+		return typeDouble
+	case bool:
+		return typeBoolean
+	}
+	return typeByteArray
+}
+
+func convert(v interface{}, typ int32) interface{} {
+	switch typ {
+	case typeDouble:
+		switch v := v.(type) {
+		case int64:
+			return float64(v)
+		case uint64:
+			return float64(v)
+		}
+	case typeInt64:
+		if u, ok := v.(uint64); ok {
+			return int64(u)
+		}
+	case typeByteArray:
+		if _, ok := v.(string); !ok {
+			return fmt.Sprint(v)
+		}
+	}
+	return v
+}
+
+// writePage writes the values of the column as a single data page: the
+// definition levels of the optional columns, then the PLAIN encoding of the
+// values that are set.
+func (c *column) writePage(b *bytes.Buffer) error {
+	data := &bytes.Buffer{}
+	if !c.required {
+		levels := &bytes.Buffer{}
+		// A bit-packed run of 1 bit levels, by groups of 8
+		groups := (len(c.values) + 7) / 8
+		varint(levels, uint64(groups)<<1|1)
+		packed := make([]byte, groups)
+		for i, v := range c.values {
+			if v != nil {
+				packed[i/8] |= 1 << uint(i%8)
+			}
+		}
+		levels.Write(packed)
+		binary.Write(data, binary.LittleEndian, uint32(levels.Len()))
+		data.Write(levels.Bytes())
+	}
+
+	var bits []byte
+	n := 0
+	for _, v := range c.values {
+		if v == nil {
+			continue
+		}
+		switch v := v.(type) {
+		case int64:
+			binary.Write(data, binary.LittleEndian, v)
+		case float64:
+			binary.Write(data, binary.LittleEndian, math.Float64bits(v))
+		case string:
+			binary.Write(data, binary.LittleEndian, uint32(len(v)))
+			data.WriteString(v)
+		case bool:
+			if n%8 == 0 {
+				bits = append(bits, 0)
+			}
+			if v {
+				bits[n/8] |= 1 << uint(n%8)
+			}
+			n++
+		default:
+			return fmt.Errorf("unsupported type %T", v)
+		}
+	}
+	data.Write(bits)
+
+	header := tStruct{
+		{1, int32(0)}, // data page
+		{2, int32(data.Len())},
+		{3, int32(data.Len())},
+		{5, tStruct{
+			{1, int32(len(c.values))},
+			{2, encodingPlain},
+			{3, encodingRLE},
+			{4, encodingRLE},
+		}},
+	}
+	header.encode(b)
+	b.Write(data.Bytes())
+	return nil
+}
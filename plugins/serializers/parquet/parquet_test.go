@@ -0,0 +1,199 @@
+package parquet
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// reader decodes the Thrift compact protocol into maps of the fields by id.
+type reader struct {
+	buf []byte
+	pos int
+}
+
+func (r *reader) uvarint() uint64 {
+	u, n := binary.Uvarint(r.buf[r.pos:])
+	r.pos += n
+	return u
+}
+
+func (r *reader) readStruct() map[int16]interface{} {
+	fields := make(map[int16]interface{})
+	var last int16
+	for {
+		h := r.buf[r.pos]
+		r.pos++
+		if h == 0 {
+			return fields
+		}
+		id := last + int16(h>>4)
+		if h>>4 == 0 {
+			u := r.uvarint()
+			id = int16(int64(u>>1) ^ -int64(u&1))
+		}
+		last = id
+		fields[id] = r.value(h & 0x0f)
+	}
+}
+
+func (r *reader) value(typ byte) interface{} {
+	switch typ {
+	case compactI32, compactI64:
+		u := r.uvarint()
+		return int64(u>>1) ^ -int64(u&1)
+	case compactBinary:
+		n := int(r.uvarint())
+		r.pos += n
+		return string(r.buf[r.pos-n : r.pos])
+	case compactList:
+		h := r.buf[r.pos]
+		r.pos++
+		n := int(h >> 4)
+		if n == 15 {
+			n = int(r.uvarint())
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			items[i] = r.value(h & 0x0f)
+		}
+		return items
+	case compactStruct:
+		return r.readStruct()
+	}
+	panic("unexpected type")
+}
+
+func footer(t *testing.T, buf []byte) map[int16]interface{} {
+	require.Equal(t, magic, string(buf[:4]))
+	require.Equal(t, magic, string(buf[len(buf)-4:]))
+	size := int(binary.LittleEndian.Uint32(buf[len(buf)-8:]))
+	r := &reader{buf: buf, pos: len(buf) - 8 - size}
+	return r.readStruct()
+}
+
+// page returns the definition levels and the values of a column chunk.
+func page(t *testing.T, buf []byte, chunk map[int16]interface{}, optional bool, rows int) ([]bool, []byte) {
+	meta := chunk[3].(map[int16]interface{})
+	r := &reader{buf: buf, pos: int(meta[9].(int64))}
+	header := r.readStruct()
+	size := int(header[2].(int64))
+	data := buf[r.pos : r.pos+size]
+	if !optional {
+		return nil, data
+	}
+
+	n := int(binary.LittleEndian.Uint32(data))
+	levels := &reader{buf: data[4 : 4+n]}
+	require.Equal(t, uint64(1), levels.uvarint()&1)
+	var defined []bool
+	for i := 0; i < rows; i++ {
+		defined = append(defined, levels.buf[levels.pos+i/8]&(1<<uint(i%8)) != 0)
+	}
+	return defined, data[4+n:]
+}
+
+func testMetrics(t *testing.T) []telegraf.Metric {
+	now := time.Unix(1496318400, 123456000)
+	m1, err := metric.New("inverter",
+		map[string]string{"serial": "1234567"},
+		map[string]interface{}{"power": 1530.5, "count": int64(3), "ok": true},
+		now)
+	require.NoError(t, err)
+	m2, err := metric.New("inverter",
+		map[string]string{"serial": "7654321", "name": "roof"},
+		map[string]interface{}{"count": 4.5, "status": "OK"},
+		now.Add(time.Second))
+	require.NoError(t, err)
+	m3, err := metric.New("meter",
+		map[string]string{},
+		map[string]interface{}{"ok": false, "status": int64(2)},
+		now.Add(2*time.Second))
+	require.NoError(t, err)
+	return []telegraf.Metric{m1, m2, m3}
+}
+
+func TestSerializeBatch(t *testing.T) {
+	s := &ParquetSerializer{}
+	buf, err := s.SerializeBatch(testMetrics(t))
+	require.NoError(t, err)
+
+	f := footer(t, buf)
+	assert.Equal(t, int64(1), f[1])
+	assert.Equal(t, int64(3), f[3])
+	assert.Equal(t, "telegraf", f[6])
+
+	schema := f[2].([]interface{})
+	var names []string
+	for _, e := range schema[1:] {
+		names = append(names, e.(map[int16]interface{})[4].(string))
+	}
+	assert.Equal(t, []string{
+		"name", "time", "name_tag", "serial", "count", "ok", "power", "status",
+	}, names)
+	assert.Equal(t, int64(len(names)), schema[0].(map[int16]interface{})[5])
+
+	types := map[string]int64{}
+	for _, e := range schema[1:] {
+		e := e.(map[int16]interface{})
+		types[e[4].(string)] = e[1].(int64)
+	}
+	assert.Equal(t, map[string]int64{
+		"name":     int64(typeByteArray),
+		"time":     int64(typeInt64),
+		"name_tag": int64(typeByteArray),
+		"serial":   int64(typeByteArray),
+		// mixed integers and floats
+		"count": int64(typeDouble),
+		"ok":    int64(typeBoolean),
+		"power": int64(typeDouble),
+		// mixed strings and integers
+		"status": int64(typeByteArray),
+	}, types)
+
+	chunks := f[4].([]interface{})[0].(map[int16]interface{})[1].([]interface{})
+	require.Len(t, chunks, len(names))
+	chunk := func(name string) map[int16]interface{} {
+		for i, n := range names {
+			if n == name {
+				return chunks[i].(map[int16]interface{})
+			}
+		}
+		return nil
+	}
+
+	_, data := page(t, buf, chunk("name"), false, 3)
+	assert.Equal(t, "\x08\x00\x00\x00inverter\x08\x00\x00\x00inverter\x05\x00\x00\x00meter", string(data))
+
+	_, data = page(t, buf, chunk("time"), false, 3)
+	assert.Equal(t, int64(1496318400123456), int64(binary.LittleEndian.Uint64(data)))
+	assert.Equal(t, int64(1496318402123456), int64(binary.LittleEndian.Uint64(data[16:])))
+
+	defined, data := page(t, buf, chunk("count"), true, 3)
+	assert.Equal(t, []bool{true, true, false}, defined)
+	require.Len(t, data, 16)
+	assert.Equal(t, 3.0, math.Float64frombits(binary.LittleEndian.Uint64(data)))
+	assert.Equal(t, 4.5, math.Float64frombits(binary.LittleEndian.Uint64(data[8:])))
+
+	defined, data = page(t, buf, chunk("ok"), true, 3)
+	assert.Equal(t, []bool{true, false, true}, defined)
+	assert.Equal(t, []byte{0x01}, data)
+
+	defined, data = page(t, buf, chunk("status"), true, 3)
+	assert.Equal(t, []bool{false, true, true}, defined)
+	assert.Equal(t, "\x02\x00\x00\x00OK\x01\x00\x00\x002", string(data))
+}
+
+func TestSerialize(t *testing.T) {
+	s := &ParquetSerializer{}
+	buf, err := s.Serialize(testMetrics(t)[0])
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), footer(t, buf)[3])
+}
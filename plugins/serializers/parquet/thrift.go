@@ -0,0 +1,96 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// The Parquet metadata is encoded with the Thrift compact protocol, a
+// struct is a list of fields by id, the values being int32, int64, string,
+// tStruct or tList.
+type tField struct {
+	id    int16
+	value interface{}
+}
+
+type tStruct []tField
+
+type tList []interface{}
+
+// Compact protocol types
+const (
+	compactI32    = 5
+	compactI64    = 6
+	compactBinary = 8
+	compactList   = 9
+	compactStruct = 12
+)
+
+func typeOf(v interface{}) byte {
+	switch v.(type) {
+	case int32:
+		return compactI32
+	case int64:
+		return compactI64
+	case string:
+		return compactBinary
+	case tList:
+		return compactList
+	case tStruct:
+		return compactStruct
+	}
+	panic("unsupported thrift type")
+}
+
+func varint(b *bytes.Buffer, u uint64) {
+	buf := make([]byte, binary.MaxVarintLen64)
+	b.Write(buf[:binary.PutUvarint(buf, u)])
+}
+
+func zigzag(i int64) uint64 {
+	return uint64((i << 1) ^ (i >> 63))
+}
+
+func (s tStruct) encode(b *bytes.Buffer) {
+	var last int16
+	for _, f := range s {
+		typ := typeOf(f.value)
+		if delta := f.id - last; delta > 0 && delta <= 15 {
+			b.WriteByte(byte(delta)<<4 | typ)
+		} else {
+			b.WriteByte(typ)
+			varint(b, zigzag(int64(f.id)))
+		}
+		last = f.id
+		encodeValue(b, f.value)
+	}
+	b.WriteByte(0)
+}
+
+func encodeValue(b *bytes.Buffer, v interface{}) {
+	switch v := v.(type) {
+	case int32:
+		varint(b, zigzag(int64(v)))
+	case int64:
+		varint(b, zigzag(v))
+	case string:
+		varint(b, uint64(len(v)))
+		b.WriteString(v)
+	case tStruct:
+		v.encode(b)
+	case tList:
+		var elem byte = compactI32
+		if len(v) > 0 {
+			elem = typeOf(v[0])
+		}
+		if len(v) < 15 {
+			b.WriteByte(byte(len(v))<<4 | elem)
+		} else {
+			b.WriteByte(0xf0 | elem)
+			varint(b, uint64(len(v)))
+		}
+		for _, item := range v {
+			encodeValue(b, item)
+		}
+	}
+}
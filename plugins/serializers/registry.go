@@ -6,9 +6,17 @@ import (
 
 	"github.com/influxdata/telegraf"
 
+	"github.com/influxdata/telegraf/plugins/serializers/cloudevents"
+	"github.com/influxdata/telegraf/plugins/serializers/csv"
 	"github.com/influxdata/telegraf/plugins/serializers/graphite"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 	"github.com/influxdata/telegraf/plugins/serializers/json"
+	"github.com/influxdata/telegraf/plugins/serializers/msgpack"
+	"github.com/influxdata/telegraf/plugins/serializers/opentelemetry"
+	"github.com/influxdata/telegraf/plugins/serializers/parquet"
+	"github.com/influxdata/telegraf/plugins/serializers/splunkmetric"
+	"github.com/influxdata/telegraf/plugins/serializers/template"
+	"github.com/influxdata/telegraf/plugins/serializers/wavefront"
 )
 
 // SerializerOutput is an interface for output plugins that are able to
@@ -27,21 +35,77 @@ type Serializer interface {
 	Serialize(metric telegraf.Metric) ([]byte, error)
 }
 
+// BatchSerializer is implemented by the serializers of the formats encoding
+// a batch of metrics as a whole, ie a Parquet file, that can not be
+// appended to. The outputs writing files write each batch to a new file.
+type BatchSerializer interface {
+	Serializer
+
+	// SerializeBatch turns a batch of metrics into a byte buffer.
+	SerializeBatch(metrics []telegraf.Metric) ([]byte, error)
+}
+
 // Config is a struct that covers the data types needed for all serializer types,
 // and can be used to instantiate _any_ of the serializers.
 type Config struct {
-	// Dataformat can be one of: influx, graphite, or json
+	// Dataformat can be one of: influx, graphite, json, msgpack, parquet,
+	// template, cloudevents, opentelemetry, splunkmetric, wavefront, or csv
 	DataFormat string
 
-	// Prefix to add to all measurements, only supports Graphite
+	// Prefix to add to all measurements, only supports Graphite and Wavefront
 	Prefix string
 
-	// Template for converting telegraf metrics into Graphite
-	// only supports Graphite
+	// Template for converting telegraf metrics into Graphite, or the Go
+	// template formatting the metrics of the template data format
 	Template string
 
+	// GraphiteTagSupport serializes the tags as Graphite 1.1 tags, and
+	// Templates are the Graphite templates of the measurements, as
+	// "<measurement filter> <template>"
+	GraphiteTagSupport bool
+	Templates          []string
+
 	// Timestamp units to use for JSON formatted output
 	TimestampUnits time.Duration
+
+	// JSONTimestampFormat formats the JSON timestamps, as "unix", "unix_ms",
+	// "unix_us", "unix_ns" or a Go reference time layout, overriding
+	// TimestampUnits
+	JSONTimestampFormat string
+
+	// JSONNestSeparator splits the JSON tag and field names into nested
+	// objects
+	JSONNestSeparator string
+
+	// CloudEventsSource and CloudEventsType are the source and type
+	// attributes of the events, CloudEventsBatch serializes the batches as
+	// arrays of events
+	CloudEventsSource string
+	CloudEventsType   string
+	CloudEventsBatch  bool
+
+	// OpenTelemetryEncoding is the OTLP encoding, "protobuf" or "json", and
+	// OpenTelemetryResourceTags the tags making the resource attributes
+	OpenTelemetryEncoding     string
+	OpenTelemetryResourceTags []string
+
+	// SplunkmetricIndex, SplunkmetricSource and SplunkmetricSourcetype are
+	// the metadata of the HTTP Event Collector events
+	SplunkmetricIndex      string
+	SplunkmetricSource     string
+	SplunkmetricSourcetype string
+
+	// WavefrontSourceOverride are the tags making the source of the
+	// Wavefront points, before the source and host tags
+	WavefrontSourceOverride []string
+
+	// CSVColumns are the columns of the CSV rows, CSVHeader writes the
+	// header rows, CSVTimestampFormat formats the timestamps and
+	// CSVSeparator is the field delimiter
+	CSVColumns         []string
+	CSVHeader          bool
+	CSVTimestampFormat string
+	CSVSeparator       string
 }
 
 // NewSerializer a Serializer interface based on the given config.
@@ -52,26 +116,126 @@ func NewSerializer(config *Config) (Serializer, error) {
 	case "influx":
 		serializer, err = NewInfluxSerializer()
 	case "graphite":
-		serializer, err = NewGraphiteSerializer(config.Prefix, config.Template)
+		serializer, err = NewGraphiteSerializer(config.Prefix, config.Template,
+			config.GraphiteTagSupport, config.Templates)
 	case "json":
-		serializer, err = NewJsonSerializer(config.TimestampUnits)
+		serializer, err = NewJsonSerializer(config.TimestampUnits,
+			config.JSONTimestampFormat, config.JSONNestSeparator)
+	case "msgpack":
+		serializer, err = NewMsgpackSerializer()
+	case "parquet":
+		serializer, err = NewParquetSerializer()
+	case "template":
+		serializer, err = NewTemplateSerializer(config.Template)
+	case "cloudevents":
+		serializer, err = NewCloudEventsSerializer(config.CloudEventsSource,
+			config.CloudEventsType, config.CloudEventsBatch)
+	case "opentelemetry":
+		serializer, err = NewOpenTelemetrySerializer(config.OpenTelemetryEncoding,
+			config.OpenTelemetryResourceTags)
+	case "wavefront":
+		serializer, err = NewWavefrontSerializer(config.Prefix,
+			config.WavefrontSourceOverride)
+	case "csv":
+		serializer, err = NewCSVSerializer(config)
+	case "splunkmetric":
+		serializer, err = NewSplunkmetricSerializer(config.SplunkmetricIndex,
+			config.SplunkmetricSource, config.SplunkmetricSourcetype)
 	default:
 		err = fmt.Errorf("Invalid data format: %s", config.DataFormat)
 	}
 	return serializer, err
 }
 
-func NewJsonSerializer(timestampUnits time.Duration) (Serializer, error) {
-	return &json.JsonSerializer{TimestampUnits: timestampUnits}, nil
+func NewJsonSerializer(
+	timestampUnits time.Duration,
+	timestampFormat string,
+	nestSeparator string,
+) (Serializer, error) {
+	return &json.JsonSerializer{
+		TimestampUnits:  timestampUnits,
+		TimestampFormat: timestampFormat,
+		NestSeparator:   nestSeparator,
+	}, nil
+}
+
+func NewMsgpackSerializer() (Serializer, error) {
+	return &msgpack.MsgpackSerializer{}, nil
+}
+
+func NewParquetSerializer() (Serializer, error) {
+	return &parquet.ParquetSerializer{}, nil
+}
+
+func NewTemplateSerializer(text string) (Serializer, error) {
+	s, err := template.NewTemplateSerializer(text)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func NewCloudEventsSerializer(source, typ string, batch bool) (Serializer, error) {
+	s := cloudevents.CloudEventsSerializer{
+		Source: source,
+		Type:   typ,
+	}
+	if batch {
+		return &cloudevents.CloudEventsBatchSerializer{CloudEventsSerializer: s}, nil
+	}
+	return &s, nil
+}
+
+func NewOpenTelemetrySerializer(encoding string, resourceTags []string) (Serializer, error) {
+	switch encoding {
+	case "", "protobuf", "json":
+	default:
+		return nil, fmt.Errorf("Invalid OpenTelemetry encoding: %s", encoding)
+	}
+	return &opentelemetry.OpenTelemetrySerializer{
+		Encoding:     encoding,
+		ResourceTags: resourceTags,
+	}, nil
+}
+
+func NewSplunkmetricSerializer(index, source, sourcetype string) (Serializer, error) {
+	return &splunkmetric.SplunkmetricSerializer{
+		Index:      index,
+		Source:     source,
+		Sourcetype: sourcetype,
+	}, nil
+}
+
+func NewWavefrontSerializer(prefix string, sourceOverride []string) (Serializer, error) {
+	return &wavefront.WavefrontSerializer{
+		Prefix:         prefix,
+		SourceOverride: sourceOverride,
+	}, nil
+}
+
+// NewCSVSerializer returns the CSV serializer of the config, the columns
+// being checked.
+func NewCSVSerializer(config *Config) (Serializer, error) {
+	s := &csv.CSVSerializer{
+		Columns:         config.CSVColumns,
+		Header:          config.CSVHeader,
+		TimestampFormat: config.CSVTimestampFormat,
+		Separator:       config.CSVSeparator,
+	}
+	if err := s.Check(); err != nil {
+		return nil, err
+	}
+	return s, nil
 }
 
 func NewInfluxSerializer() (Serializer, error) {
 	return &influx.InfluxSerializer{}, nil
 }
 
-func NewGraphiteSerializer(prefix, template string) (Serializer, error) {
-	return &graphite.GraphiteSerializer{
-		Prefix:   prefix,
-		Template: template,
-	}, nil
+func NewGraphiteSerializer(prefix, template string, tagSupport bool, templates []string) (Serializer, error) {
+	s, err := graphite.NewGraphiteSerializer(prefix, template, tagSupport, templates)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
 }
@@ -0,0 +1,81 @@
+package splunkmetric
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/influxdata/telegraf"
+)
+
+// event is an event of the Splunk HTTP Event Collector in the multi-metric
+// format, the measures being the "metric_name:<name>" fields and the other
+// fields the dimensions.
+type event struct {
+	Time       json.Number            `json:"time"`
+	Event      string                 `json:"event"`
+	Host       string                 `json:"host,omitempty"`
+	Index      string                 `json:"index,omitempty"`
+	Source     string                 `json:"source,omitempty"`
+	Sourcetype string                 `json:"sourcetype,omitempty"`
+	Fields     map[string]interface{} `json:"fields"`
+}
+
+// SplunkmetricSerializer serializes each metric as a HTTP Event Collector
+// metric event, its numeric fields being the measures named
+// "<measurement>.<field>" and its tags the dimensions. The "host" tag is the
+// host of the event.
+type SplunkmetricSerializer struct {
+	// Index, Source and Sourcetype are the metadata of the events, the
+	// defaults of the HEC token if empty.
+	Index      string
+	Source     string
+	Sourcetype string
+}
+
+func (s *SplunkmetricSerializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	e := event{
+		// The time is in seconds, with a millisecond precision.
+		Time:       json.Number(strconv.FormatFloat(float64(metric.Time().UnixNano()/1e6)/1e3, 'f', 3, 64)),
+		Event:      "metric",
+		Index:      s.Index,
+		Source:     s.Source,
+		Sourcetype: s.Sourcetype,
+		Fields:     make(map[string]interface{}),
+	}
+
+	for k, v := range metric.Tags() {
+		if k == "host" {
+			e.Host = v
+			continue
+		}
+		e.Fields[k] = v
+	}
+
+	measures := 0
+	for k, v := range metric.Fields() {
+		switch v := v.(type) {
+		case int64, uint64, float64:
+			e.Fields["metric_name:"+metric.Name()+"."+k] = v
+		case bool:
+			// The measures are numeric.
+			if v {
+				e.Fields["metric_name:"+metric.Name()+"."+k] = 1
+			} else {
+				e.Fields["metric_name:"+metric.Name()+"."+k] = 0
+			}
+		default:
+			continue
+		}
+		measures++
+	}
+	// A metric without numeric fields has no measure and is dropped.
+	if measures == 0 {
+		return []byte{}, nil
+	}
+
+	serialized, err := json.Marshal(e)
+	if err != nil {
+		return []byte{}, err
+	}
+	return append(serialized, '\n'), nil
+}
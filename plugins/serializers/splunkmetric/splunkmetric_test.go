@@ -0,0 +1,59 @@
+package splunkmetric
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/metric"
+)
+
+func TestSerializeMetric(t *testing.T) {
+	m, err := metric.New("cpu",
+		map[string]string{"host": "server-1", "cpu": "cpu0"},
+		map[string]interface{}{
+			"usage_idle": float64(91.5),
+			"processes":  int64(12),
+			"throttled":  true,
+			"state":      "ok",
+		},
+		time.Date(2017, 6, 1, 12, 0, 0, 123456789, time.UTC))
+	require.NoError(t, err)
+
+	s := SplunkmetricSerializer{Index: "metrics"}
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+	assert.Equal(t, byte('\n'), buf[len(buf)-1])
+	assert.Contains(t, string(buf), `"time":1496318400.123,`)
+
+	var e map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf, &e))
+	assert.Equal(t, map[string]interface{}{
+		"time":  1496318400.123,
+		"event": "metric",
+		"host":  "server-1",
+		"index": "metrics",
+		"fields": map[string]interface{}{
+			"cpu":                        "cpu0",
+			"metric_name:cpu.usage_idle": 91.5,
+			"metric_name:cpu.processes":  float64(12),
+			"metric_name:cpu.throttled":  float64(1),
+		},
+	}, e)
+}
+
+func TestSerializeMetricWithoutMeasure(t *testing.T) {
+	m, err := metric.New("log",
+		map[string]string{},
+		map[string]interface{}{"message": "started"},
+		time.Unix(1496318400, 0))
+	require.NoError(t, err)
+
+	s := SplunkmetricSerializer{}
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+	assert.Empty(t, buf)
+}
@@ -0,0 +1,51 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"text/template"
+
+	"github.com/influxdata/telegraf"
+)
+
+// funcs are the functions available to the templates in addition to the
+// builtin ones.
+var funcs = template.FuncMap{
+	// json encodes a value, ie {{json .Tags}}
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// TemplateSerializer formats each metric with a Go template, the metric
+// being its data: {{.Name}}, {{.Tags.host}}, {{.Fields.value}},
+// {{.Time.Unix}}, etc. A newline is added after each metric if the
+// template does not end with one.
+type TemplateSerializer struct {
+	template *template.Template
+}
+
+func NewTemplateSerializer(text string) (*TemplateSerializer, error) {
+	if text == "" {
+		return nil, fmt.Errorf("a template is required")
+	}
+	t, err := template.New("metric").Funcs(funcs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template, %s", err)
+	}
+	return &TemplateSerializer{template: t}, nil
+}
+
+func (s *TemplateSerializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	var b bytes.Buffer
+	if err := s.template.Execute(&b, metric); err != nil {
+		return nil, err
+	}
+	out := b.Bytes()
+	if len(out) == 0 || out[len(out)-1] != '\n' {
+		out = append(out, '\n')
+	}
+	return out, nil
+}
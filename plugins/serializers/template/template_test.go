@@ -0,0 +1,58 @@
+package template
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/metric"
+)
+
+func TestSerializeMetric(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	m, err := metric.New("cpu",
+		map[string]string{"cpu": "cpu0", "host": "localhost"},
+		map[string]interface{}{"usage_idle": float64(91.5), "usage_busy": float64(8.5)},
+		now)
+	require.NoError(t, err)
+
+	s, err := NewTemplateSerializer(`{{.Name}}.{{.Tags.cpu}} {{.Fields.usage_idle}} {{.Time.Unix}}`)
+	require.NoError(t, err)
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+	assert.Equal(t, "cpu.cpu0 91.5 1500000000\n", string(buf))
+
+	s, err = NewTemplateSerializer(`{"measurement":{{json .Name}},"tags":{{json .Tags}},"time":{{.UnixNano}}}` + "\n")
+	require.NoError(t, err)
+	buf, err = s.Serialize(m)
+	require.NoError(t, err)
+	assert.Equal(t, `{"measurement":"cpu","tags":{"cpu":"cpu0","host":"localhost"},"time":1500000000000000000}`+"\n", string(buf))
+
+	// The fields in order, one line each
+	s, err = NewTemplateSerializer(`{{range $k, $v := .Fields}}{{$.Name}}_{{$k}}={{$v}}
+{{end}}`)
+	require.NoError(t, err)
+	buf, err = s.Serialize(m)
+	require.NoError(t, err)
+	assert.Equal(t, "cpu_usage_busy=8.5\ncpu_usage_idle=91.5\n", string(buf))
+}
+
+func TestSerializeMissingTag(t *testing.T) {
+	m, err := metric.New("cpu", map[string]string{}, map[string]interface{}{"value": int64(1)}, time.Now())
+	require.NoError(t, err)
+
+	s, err := NewTemplateSerializer(`{{.Name}} host={{.Tags.host}} {{.Fields.value}}`)
+	require.NoError(t, err)
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+	assert.Equal(t, "cpu host= 1\n", string(buf))
+}
+
+func TestInvalidTemplate(t *testing.T) {
+	_, err := NewTemplateSerializer("")
+	assert.Error(t, err)
+	_, err = NewTemplateSerializer("{{.Name")
+	assert.Error(t, err)
+}
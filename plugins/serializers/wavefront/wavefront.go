@@ -0,0 +1,109 @@
+package wavefront
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+// DefaultSource is the source of the metrics without source tag.
+const DefaultSource = "telegraf"
+
+var (
+	// The metric names are made of letters, digits and "-_./,~", the
+	// other common characters are replaced.
+	metricNameChars = strings.NewReplacer(" ", "_", `"`, "-", "=", "-",
+		"#", "-", "@", "-", "*", "-", "(", "-", ")", "-", ":", "-", ";", "-")
+	tagValueChars = strings.NewReplacer(`"`, `\"`, "\n", " ")
+)
+
+// WavefrontSerializer serializes each numeric field as a Wavefront point, ie
+// "<prefix><measurement>.<field> <value> <timestamp> source=<source> <tags>",
+// the field being left out if it is "value". The boolean fields are 0 or 1
+// and the string fields are skipped. The timestamps are in seconds.
+type WavefrontSerializer struct {
+	Prefix string
+	// SourceOverride are the tags tried, in order, as the source of the
+	// points before the "source" and the "host" tags, DefaultSource being
+	// the source of the metrics without any of them.
+	SourceOverride []string
+}
+
+func (s *WavefrontSerializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	tags := metric.Tags()
+	source, sourceTag := s.source(tags)
+
+	var keys []string
+	for k := range tags {
+		if k != sourceTag {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var pointTags bytes.Buffer
+	fmt.Fprintf(&pointTags, "source=\"%s\"", tagValueChars.Replace(source))
+	for _, k := range keys {
+		if tags[k] == "" {
+			continue
+		}
+		fmt.Fprintf(&pointTags, " %s=\"%s\"", sanitizeTagKey(k), tagValueChars.Replace(tags[k]))
+	}
+
+	var out bytes.Buffer
+	timestamp := metric.UnixNano() / 1000000000
+	for fieldName, value := range metric.Fields() {
+		var v string
+		switch value := value.(type) {
+		case int64:
+			v = strconv.FormatInt(value, 10)
+		case uint64:
+			v = strconv.FormatUint(value, 10)
+		case float64:
+			v = strconv.FormatFloat(value, 'f', -1, 64)
+		case bool:
+			v = "0"
+			if value {
+				v = "1"
+			}
+		default:
+			continue
+		}
+
+		name := s.Prefix + metric.Name()
+		if fieldName != "value" {
+			name += "." + fieldName
+		}
+		fmt.Fprintf(&out, "%s %s %d %s\n",
+			metricNameChars.Replace(name), v, timestamp, pointTags.String())
+	}
+	return out.Bytes(), nil
+}
+
+// source returns the source of the points and the tag it is taken from.
+func (s *WavefrontSerializer) source(tags map[string]string) (string, string) {
+	candidates := append(append([]string{}, s.SourceOverride...), "source", "host")
+	for _, k := range candidates {
+		if v, ok := tags[k]; ok && v != "" {
+			return v, k
+		}
+	}
+	return DefaultSource, ""
+}
+
+// sanitizeTagKey replaces the characters other than letters, digits and
+// "-_." of the tag keys with "-".
+func sanitizeTagKey(k string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9',
+			r == '-', r == '_', r == '.':
+			return r
+		}
+		return '-'
+	}, k)
+}
@@ -0,0 +1,57 @@
+package wavefront
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/metric"
+)
+
+func TestSerializeMetric(t *testing.T) {
+	m, err := metric.New("cpu",
+		map[string]string{"host": "server-1", "cpu": "cpu0", "region name": `us "west"`},
+		map[string]interface{}{
+			"usage_idle": float64(91.5),
+			"value":      int64(12),
+			"throttled":  true,
+			"state":      "ok",
+		},
+		time.Unix(1496318400, 0))
+	require.NoError(t, err)
+
+	s := WavefrontSerializer{Prefix: "telegraf."}
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+	points := strings.Split(strings.TrimSpace(string(buf)), "\n")
+	sort.Strings(points)
+	tags := `source="server-1" cpu="cpu0" region-name="us \"west\""`
+	assert.Equal(t, []string{
+		"telegraf.cpu 12 1496318400 " + tags,
+		"telegraf.cpu.throttled 1 1496318400 " + tags,
+		"telegraf.cpu.usage_idle 91.5 1496318400 " + tags,
+	}, points)
+}
+
+func TestSerializeSource(t *testing.T) {
+	m, err := metric.New("disk used",
+		map[string]string{"host": "server-1", "node": "node-7"},
+		map[string]interface{}{"value": float64(1)},
+		time.Unix(1496318400, 0))
+	require.NoError(t, err)
+
+	s := WavefrontSerializer{SourceOverride: []string{"agent", "node"}}
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+	assert.Equal(t, "disk_used 1 1496318400 source=\"node-7\" host=\"server-1\"\n", string(buf))
+
+	m.RemoveTag("host")
+	m.RemoveTag("node")
+	buf, err = s.Serialize(m)
+	require.NoError(t, err)
+	assert.Equal(t, "disk_used 1 1496318400 source=\"telegraf\"\n", string(buf))
+}
@@ -0,0 +1,285 @@
+// Package shim runs a single input, processor or output plugin as a
+// standalone program communicating with Telegraf over stdin and stdout in
+// line protocol, so plugins can be built and distributed outside of Telegraf.
+//
+// An input shim is run by the execd input: it gathers on every line read
+// from stdin, or at the given interval, and writes the metrics to stdout.
+// A processor shim reads metrics from stdin and writes the processed metrics
+// to stdout. An output shim reads metrics from stdin and writes them to the
+// output, after every line or at the given interval.
+//
+// A program only needs to import the plugin and the shim:
+//
+//	func main() {
+//		s := shim.New()
+//		if err := s.LoadConfig("plugin.conf"); err != nil {
+//			log.Fatal(err)
+//		}
+//		if err := s.Run(0); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+package shim
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/agent"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+// Shim runs one plugin.
+type Shim struct {
+	input     *models.RunningInput
+	processor *models.RunningProcessor
+	output    *models.RunningOutput
+
+	stdin  io.Reader
+	stdout io.Writer
+}
+
+// New returns a shim reading from stdin and writing to stdout.
+func New() *Shim {
+	return &Shim{
+		stdin:  os.Stdin,
+		stdout: os.Stdout,
+	}
+}
+
+func (s *Shim) hasPlugin() bool {
+	return s.input != nil || s.processor != nil || s.output != nil
+}
+
+// AddInput sets the input to run.
+func (s *Shim) AddInput(input telegraf.Input) error {
+	if s.hasPlugin() {
+		return fmt.Errorf("only one plugin can be run by a shim")
+	}
+	s.input = models.NewRunningInput(input, &models.InputConfig{Name: "shim"})
+	return nil
+}
+
+// AddProcessor sets the processor to run.
+func (s *Shim) AddProcessor(processor telegraf.Processor) error {
+	if s.hasPlugin() {
+		return fmt.Errorf("only one plugin can be run by a shim")
+	}
+	s.processor = &models.RunningProcessor{
+		Name:      "shim",
+		Processor: processor,
+		Config:    &models.ProcessorConfig{Name: "shim"},
+	}
+	return nil
+}
+
+// AddOutput sets the output to run.
+func (s *Shim) AddOutput(output telegraf.Output) error {
+	if s.hasPlugin() {
+		return fmt.Errorf("only one plugin can be run by a shim")
+	}
+	s.output = models.NewRunningOutput("shim", output,
+		&models.OutputConfig{Name: "shim"}, 0, 0)
+	return nil
+}
+
+// LoadConfig loads the plugin to run from a configuration file in the
+// format of Telegraf, it must define exactly one plugin.
+func (s *Shim) LoadConfig(path string) error {
+	if s.hasPlugin() {
+		return fmt.Errorf("only one plugin can be run by a shim")
+	}
+
+	c := config.NewConfig()
+	if err := c.LoadConfig(path); err != nil {
+		return err
+	}
+	if n := len(c.Inputs) + len(c.Processors) + len(c.Outputs); n != 1 {
+		return fmt.Errorf("expected one plugin in %s, found %d", path, n)
+	}
+
+	switch {
+	case len(c.Inputs) == 1:
+		s.input = c.Inputs[0]
+	case len(c.Processors) == 1:
+		s.processor = c.Processors[0]
+	default:
+		s.output = c.Outputs[0]
+	}
+	return nil
+}
+
+// Run runs the plugin until stdin is closed. The interval is the gather
+// interval of an input or the flush interval of an output, when zero an
+// input gathers on every line read from stdin and an output writes every
+// metric read.
+func (s *Shim) Run(interval time.Duration) error {
+	switch {
+	case s.input != nil:
+		return s.runInput(interval)
+	case s.processor != nil:
+		return s.runProcessor()
+	case s.output != nil:
+		return s.runOutput(interval)
+	}
+	return fmt.Errorf("no plugin to run")
+}
+
+// readLines sends the lines read from r until it is closed.
+func readLines(r io.Reader) <-chan string {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		if err := scanner.Err(); err != nil {
+			log.Printf("E! Error reading stdin: %s\n", err)
+		}
+	}()
+	return lines
+}
+
+func newTicker(interval time.Duration) (<-chan time.Time, func()) {
+	if interval <= 0 {
+		return nil, func() {}
+	}
+	ticker := time.NewTicker(interval)
+	return ticker.C, ticker.Stop
+}
+
+func (s *Shim) write(m telegraf.Metric) error {
+	_, err := s.stdout.Write(m.Serialize())
+	return err
+}
+
+func (s *Shim) runInput(interval time.Duration) error {
+	metricC := make(chan telegraf.Metric, 100)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for m := range metricC {
+			if err := s.write(m); err != nil {
+				log.Printf("E! Error writing to stdout: %s\n", err)
+			}
+		}
+	}()
+
+	acc := agent.NewAccumulator(s.input, metricC)
+	acc.SetPrecision(time.Nanosecond, 0)
+
+	service, isService := s.input.Input.(telegraf.ServiceInput)
+	if isService {
+		if err := service.Start(acc); err != nil {
+			close(metricC)
+			return err
+		}
+	}
+
+	tick, stop := newTicker(interval)
+	defer stop()
+
+	lines := readLines(s.stdin)
+	for {
+		select {
+		case _, ok := <-lines:
+			if !ok {
+				// the metrics of a service input are written until it is
+				// stopped.
+				if isService {
+					service.Stop()
+				}
+				close(metricC)
+				wg.Wait()
+				return nil
+			}
+			if interval > 0 {
+				continue
+			}
+		case <-tick:
+		}
+
+		if err := s.input.Gather(acc); err != nil {
+			acc.AddError(err)
+		}
+	}
+}
+
+func (s *Shim) runProcessor() error {
+	parser, err := parsers.NewInfluxParser()
+	if err != nil {
+		return err
+	}
+
+	for line := range readLines(s.stdin) {
+		m, err := parser.ParseLine(line)
+		if err != nil {
+			log.Printf("E! Unable to parse %q: %s\n", line, err)
+			continue
+		}
+		for _, m := range s.processor.Apply(m) {
+			if err := s.write(m); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Shim) runOutput(interval time.Duration) error {
+	parser, err := parsers.NewInfluxParser()
+	if err != nil {
+		return err
+	}
+
+	if err := s.output.Output.Connect(); err != nil {
+		return err
+	}
+	defer s.output.Output.Close()
+
+	// flush writes the buffered metrics, they are retried on the next flush
+	// if the output fails.
+	flush := func() {
+		for s.output.BufferLen() > 0 {
+			if err := s.output.Write(); err != nil {
+				log.Printf("E! Error writing to output: %s\n", err)
+				return
+			}
+		}
+	}
+
+	tick, stop := newTicker(interval)
+	defer stop()
+
+	lines := readLines(s.stdin)
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				flush()
+				return nil
+			}
+			m, err := parser.ParseLine(line)
+			if err != nil {
+				log.Printf("E! Unable to parse %q: %s\n", line, err)
+				continue
+			}
+			s.output.AddMetric(m)
+			if interval <= 0 {
+				flush()
+			}
+		case <-tick:
+			flush()
+		}
+	}
+}
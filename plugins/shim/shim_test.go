@@ -0,0 +1,89 @@
+package shim
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countInput struct {
+	count int
+}
+
+func (c *countInput) Description() string  { return "" }
+func (c *countInput) SampleConfig() string { return "" }
+func (c *countInput) Gather(acc telegraf.Accumulator) error {
+	c.count++
+	acc.AddFields("count", map[string]interface{}{"value": c.count}, nil)
+	return nil
+}
+
+type renameProcessor struct{}
+
+func (r *renameProcessor) Description() string  { return "" }
+func (r *renameProcessor) SampleConfig() string { return "" }
+func (r *renameProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, m := range in {
+		m.SetName("renamed")
+	}
+	return in
+}
+
+type recordOutput struct {
+	metrics []telegraf.Metric
+}
+
+func (r *recordOutput) Connect() error       { return nil }
+func (r *recordOutput) Close() error         { return nil }
+func (r *recordOutput) Description() string  { return "" }
+func (r *recordOutput) SampleConfig() string { return "" }
+func (r *recordOutput) Write(metrics []telegraf.Metric) error {
+	r.metrics = append(r.metrics, metrics...)
+	return nil
+}
+
+func TestShimInput(t *testing.T) {
+	var stdout bytes.Buffer
+	s := New()
+	s.stdin = strings.NewReader("\n\n")
+	s.stdout = &stdout
+	require.NoError(t, s.AddInput(&countInput{}))
+	assert.Error(t, s.AddOutput(&recordOutput{}))
+
+	require.NoError(t, s.Run(0))
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.True(t, strings.HasPrefix(lines[0], "count value=1i "))
+	assert.True(t, strings.HasPrefix(lines[1], "count value=2i "))
+}
+
+func TestShimProcessor(t *testing.T) {
+	var stdout bytes.Buffer
+	s := New()
+	s.stdin = strings.NewReader("cpu value=1 1500000000000000000\ninvalid\n")
+	s.stdout = &stdout
+	require.NoError(t, s.AddProcessor(&renameProcessor{}))
+
+	require.NoError(t, s.Run(0))
+	assert.Equal(t, "renamed value=1 1500000000000000000\n", stdout.String())
+}
+
+func TestShimOutput(t *testing.T) {
+	output := &recordOutput{}
+	s := New()
+	s.stdin = strings.NewReader("cpu value=1 1500000000000000000\ncpu value=2 1500000000000000000\n")
+	require.NoError(t, s.AddOutput(output))
+
+	require.NoError(t, s.Run(0))
+	require.Len(t, output.metrics, 2)
+	assert.Equal(t, map[string]interface{}{"value": float64(2)}, output.metrics[1].Fields())
+}
+
+func TestShimNoPlugin(t *testing.T) {
+	assert.Error(t, New().Run(0))
+}
@@ -0,0 +1,75 @@
+package selfstat
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultDurationBounds are the upper bounds of the buckets of the duration
+// histograms of the agent.
+var DefaultDurationBounds = []time.Duration{
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+}
+
+// histogramStat counts the durations added to it in cumulative buckets, it
+// is reported as the <field>_count field and one <field>_le_<bound> field per
+// bucket, along with the total in the <field>_sum_ns field.
+type histogramStat struct {
+	stat
+
+	bounds  []time.Duration
+	buckets []Stat
+	sum     Stat
+
+	mu sync.Mutex
+}
+
+func newHistogramStat(
+	measurement string,
+	field string,
+	tags map[string]string,
+	bounds []time.Duration,
+) *histogramStat {
+	return &histogramStat{
+		stat: stat{
+			measurement: measurement,
+			field:       field + "_count",
+			tags:        tags,
+		},
+		bounds: bounds,
+	}
+}
+
+// Incr adds a duration in nanoseconds to the histogram.
+func (s *histogramStat) Incr(v int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stat.Incr(1)
+	s.sum.Incr(v)
+	for i, bound := range s.bounds {
+		if v <= bound.Nanoseconds() {
+			s.buckets[i].Incr(1)
+		}
+	}
+}
+
+// Set adds a duration in nanoseconds to the histogram.
+func (s *histogramStat) Set(v int64) {
+	s.Incr(v)
+}
+
+// boundName formats the bound for a field name, ie 500ms or 10s.
+func boundName(bound time.Duration) string {
+	if bound%time.Second == 0 {
+		return fmt.Sprintf("%ds", bound/time.Second)
+	}
+	return fmt.Sprintf("%dms", bound/time.Millisecond)
+}
@@ -87,6 +87,30 @@ func RegisterTiming(measurement, field string, tags map[string]string) Stat {
 	})
 }
 
+// RegisterHistogram registers a histogram of durations for the given
+// measurement, field, and tags in the selfstat registry. If given an identical
+// measurement, it will return the histogram that's already been registered.
+//
+// Each duration in nanoseconds passed to Incr() or Set() is counted in the
+// buckets with an upper bound greater or equal to it. The counts are
+// cumulative and never cleared, so the distribution over an interval is the
+// difference between two collections. Get() returns the number of durations
+// added.
+func RegisterHistogram(
+	measurement string,
+	field string,
+	tags map[string]string,
+	bounds []time.Duration,
+) Stat {
+	h := newHistogramStat("internal_"+measurement, field, tags, bounds)
+	h.sum = Register(measurement, field+"_sum_ns", tags)
+	for _, bound := range bounds {
+		h.buckets = append(h.buckets,
+			Register(measurement, field+"_le_"+boundName(bound), tags))
+	}
+	return registry.register(h)
+}
+
 // Metrics returns all registered stats as telegraf metrics.
 func Metrics() []telegraf.Metric {
 	registry.mu.Lock()
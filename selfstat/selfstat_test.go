@@ -3,6 +3,7 @@ package selfstat
 import (
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/influxdata/telegraf/testutil"
 
@@ -108,6 +109,36 @@ func TestRegisterTimingAndIncrAndSet(t *testing.T) {
 	assert.Equal(t, "internal_test", foo.Name())
 }
 
+func TestRegisterHistogramAndIncr(t *testing.T) {
+	testLock.Lock()
+	defer testCleanup()
+	bounds := []time.Duration{10 * time.Millisecond, time.Second}
+	h := RegisterHistogram("test", "test_time", map[string]string{"test": "foo"}, bounds)
+	h.Incr(int64(5 * time.Millisecond))
+	h.Incr(int64(500 * time.Millisecond))
+	h.Set(int64(2 * time.Second))
+	assert.Equal(t, int64(3), h.Get())
+	assert.Equal(t, "test_time_count", h.FieldName())
+
+	// make sure that the same field returns the same histogram
+	foo := RegisterHistogram("test", "test_time", map[string]string{"test": "foo"}, bounds)
+	foo.Incr(int64(time.Millisecond))
+
+	acc := testutil.Accumulator{}
+	acc.AddMetrics(Metrics())
+	acc.AssertContainsTaggedFields(t, "internal_test",
+		map[string]interface{}{
+			"test_time_count":   int64(4),
+			"test_time_le_10ms": int64(2),
+			"test_time_le_1s":   int64(3),
+			"test_time_sum_ns":  int64(2506 * time.Millisecond),
+		},
+		map[string]string{
+			"test": "foo",
+		},
+	)
+}
+
 func TestStatKeyConsistency(t *testing.T) {
 	s := &stat{
 		measurement: "internal_stat",
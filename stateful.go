@@ -0,0 +1,15 @@
+package telegraf
+
+// StatefulPlugin is implemented by plugins keeping a state across restarts
+// of the agent, ie the offsets of the data already read. The agent saves the
+// states to its statefile when stopping and restores them before starting
+// the plugins.
+type StatefulPlugin interface {
+	// GetState returns the state of the plugin, which must be serializable
+	// to JSON.
+	GetState() interface{}
+
+	// SetState restores the state, it is of the type of the values returned
+	// by GetState.
+	SetState(state interface{}) error
+}